@@ -0,0 +1,43 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// ConcurrencyLimitHandler serves at most maxConcurrent requests at once
+// through the wrapped handler, and rejects the others with a
+// `503 Service Unavailable` response and a `Retry-After` header.
+type ConcurrencyLimitHandler struct {
+	handler    http.Handler
+	semaphore  chan struct{}
+	retryAfter time.Duration
+}
+
+func NewConcurrencyLimitHandler(
+	handler http.Handler,
+	maxConcurrent int,
+	retryAfter time.Duration,
+) *ConcurrencyLimitHandler {
+	return &ConcurrencyLimitHandler{
+		handler:    handler,
+		semaphore:  make(chan struct{}, maxConcurrent),
+		retryAfter: retryAfter,
+	}
+}
+
+func (h *ConcurrencyLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	select {
+	case h.semaphore <- struct{}{}:
+		defer func() { <-h.semaphore }()
+		h.handler.ServeHTTP(w, r)
+	default:
+		log.Debugf("Rejecting request from `%s`: %d requests already in progress", r.RemoteAddr, cap(h.semaphore))
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(h.retryAfter.Seconds()))))
+		http.Error(w, "Too many concurrent requests", http.StatusServiceUnavailable)
+	}
+}
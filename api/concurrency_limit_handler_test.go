@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+var _ = Describe("ConcurrencyLimitHandler", func() {
+	var (
+		started  chan struct{}
+		release  chan struct{}
+		served   chan int
+		recorder *httptest.ResponseRecorder
+
+		concurrencyLimitHandler *ConcurrencyLimitHandler
+	)
+
+	BeforeEach(func() {
+		started = make(chan struct{}, 2)
+		release = make(chan struct{})
+		served = make(chan int, 2)
+		recorder = httptest.NewRecorder()
+
+		handlerStarted, handlerRelease := started, release
+		concurrencyLimitHandler = NewConcurrencyLimitHandler(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerStarted <- struct{}{}
+				<-handlerRelease
+			}),
+			1,
+			1500*time.Millisecond,
+		)
+	})
+
+	serveInBackground := func() {
+		handler, served := concurrencyLimitHandler, served
+		go func() {
+			backgroundRecorder := httptest.NewRecorder()
+			handler.ServeHTTP(backgroundRecorder, httptest.NewRequest("GET", "/metrics", nil))
+			served <- backgroundRecorder.Code
+		}()
+		Eventually(started).Should(Receive())
+	}
+
+	It("serves the requests within the limit", func() {
+		serveInBackground()
+		close(release)
+
+		Eventually(served).Should(Receive(Equal(http.StatusOK)))
+	})
+
+	Context("when the limit is reached", func() {
+		BeforeEach(func() {
+			serveInBackground()
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("rejects the requests beyond the limit", func() {
+			concurrencyLimitHandler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+			Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(recorder.Header().Get("Retry-After")).To(Equal("2"))
+			Expect(started).ToNot(Receive())
+		})
+	})
+
+	Context("when a request is over", func() {
+		BeforeEach(func() {
+			serveInBackground()
+			release <- struct{}{}
+			Eventually(served).Should(Receive())
+		})
+
+		AfterEach(func() {
+			close(release)
+		})
+
+		It("serves the next request", func() {
+			serveInBackground()
+		})
+	})
+})
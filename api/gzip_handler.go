@@ -0,0 +1,110 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GzipHandler compresses the responses of the wrapped handler with gzip when
+// the client accepts it.
+type GzipHandler struct {
+	handler http.Handler
+}
+
+func NewGzipHandler(handler http.Handler) *GzipHandler {
+	return &GzipHandler{handler: handler}
+}
+
+func (h *GzipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// Range requests address the uncompressed body.
+	if r.Method == "HEAD" || r.Header.Get("Range") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.handler.ServeHTTP(w, r)
+		return
+	}
+
+	gzipWriter := &gzipResponseWriter{ResponseWriter: w}
+	defer gzipWriter.Close()
+
+	h.handler.ServeHTTP(gzipWriter, r)
+}
+
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		if strings.TrimSpace(fields[0]) != "gzip" {
+			continue
+		}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gzipWriter  *gzip.Writer
+	wroteHeader bool
+	compress    bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	header := w.Header()
+	if header.Get("Content-Encoding") == "" && statusCode != http.StatusNoContent && statusCode != http.StatusNotModified {
+		w.compress = true
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		// The content type must be sniffed from the uncompressed body.
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.compress {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if w.gzipWriter == nil {
+		w.gzipWriter = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	return w.gzipWriter.Write(b)
+}
+
+func (w *gzipResponseWriter) Close() error {
+	if !w.compress {
+		return nil
+	}
+
+	// An empty body is still written as a valid gzip stream.
+	if w.gzipWriter == nil {
+		w.gzipWriter = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	return w.gzipWriter.Close()
+}
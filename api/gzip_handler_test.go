@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+var _ = Describe("GzipHandler", func() {
+	var (
+		method         string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		recorder       *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		method = "GET"
+		acceptEncoding = "gzip, deflate"
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "15")
+			w.Write([]byte(`{"fake":"body"}`))
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		request := httptest.NewRequest(method, "/sd", nil)
+		if acceptEncoding != "" {
+			request.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		NewGzipHandler(handler).ServeHTTP(recorder, request)
+	})
+
+	uncompressedBody := func() string {
+		gzipReader, err := gzip.NewReader(recorder.Body)
+		Expect(err).ToNot(HaveOccurred())
+		body, err := ioutil.ReadAll(gzipReader)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	It("compresses the response", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(recorder.Header().Get("Content-Length")).To(BeEmpty())
+		Expect(recorder.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(uncompressedBody()).To(Equal(`{"fake":"body"}`))
+	})
+
+	It("sniffs the content type from the uncompressed body", func() {
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+	})
+
+	Context("when the client does not accept gzip", func() {
+		BeforeEach(func() {
+			acceptEncoding = ""
+		})
+
+		It("does not compress the response", func() {
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(recorder.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+			Expect(recorder.Body.String()).To(Equal(`{"fake":"body"}`))
+		})
+	})
+
+	Context("when the client refuses gzip", func() {
+		BeforeEach(func() {
+			acceptEncoding = "gzip;q=0, identity"
+		})
+
+		It("does not compress the response", func() {
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(recorder.Body.String()).To(Equal(`{"fake":"body"}`))
+		})
+	})
+
+	Context("when the response is already encoded", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "identity")
+				w.Write([]byte(`{"fake":"body"}`))
+			}
+		})
+
+		It("does not compress the response again", func() {
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("identity"))
+			Expect(recorder.Body.String()).To(Equal(`{"fake":"body"}`))
+		})
+	})
+
+	Context("when the response has no body", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotModified)
+			}
+		})
+
+		It("does not compress the response", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotModified))
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(recorder.Body.Len()).To(BeZero())
+		})
+	})
+
+	Context("when the response body is empty", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+		})
+
+		It("writes an empty gzip stream", func() {
+			Expect(recorder.Header().Get("Content-Encoding")).To(Equal("gzip"))
+			Expect(uncompressedBody()).To(BeEmpty())
+		})
+	})
+
+	Context("when the method is HEAD", func() {
+		BeforeEach(func() {
+			method = "HEAD"
+		})
+
+		It("does not compress the response", func() {
+			Expect(recorder.Header().Get("Content-Encoding")).To(BeEmpty())
+		})
+	})
+})
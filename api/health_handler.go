@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HealthHandler answers `200 OK` when all its checks succeed, and
+// `503 Service Unavailable` with the checks errors otherwise, so it can be
+// used as a Kubernetes liveness or readiness probe.
+type HealthHandler struct {
+	checks []func() error
+}
+
+func NewHealthHandler(checks ...func() error) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	errs := []string{}
+	for _, check := range h.checks {
+		if err := check(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		http.Error(w, strings.Join(errs, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("OK\n"))
+}
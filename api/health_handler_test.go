@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+var _ = Describe("HealthHandler", func() {
+	var (
+		checks   []func() error
+		recorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		checks = []func() error{}
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		NewHealthHandler(checks...).ServeHTTP(recorder, httptest.NewRequest("GET", "/-/ready", nil))
+	})
+
+	It("answers OK", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(Equal("OK\n"))
+	})
+
+	Context("when the checks succeed", func() {
+		BeforeEach(func() {
+			checks = append(checks, func() error { return nil }, func() error { return nil })
+		})
+
+		It("answers OK", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when checks fail", func() {
+		BeforeEach(func() {
+			checks = append(checks,
+				func() error { return errors.New("fake-error-1") },
+				func() error { return nil },
+				func() error { return errors.New("fake-error-2") },
+			)
+		})
+
+		It("answers Service Unavailable with the checks errors", func() {
+			Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(recorder.Body.String()).To(Equal("fake-error-1\nfake-error-2\n"))
+		})
+	})
+})
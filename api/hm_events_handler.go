@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+type HMEventsReceiver interface {
+	HandleHMEvent(event deployments.HMEvent)
+}
+
+type HMEventsHandler struct {
+	receiver HMEventsReceiver
+}
+
+func NewHMEventsHandler(receiver HMEventsReceiver) *HMEventsHandler {
+	return &HMEventsHandler{receiver: receiver}
+}
+
+// ServeHTTP reads the BOSH Health Monitor events posted as a stream of JSON
+// objects (one per line, as written by the HM JSON plugin) and hands them to
+// the receiver as they arrive.
+func (h *HMEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	for {
+		var event deployments.HMEvent
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+			log.Errorf("Error reading Health Monitor event: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		log.Debugf("Received Health Monitor `%s` event for deployment `%s`", event.Kind, event.Deployment)
+		h.receiver.HandleHMEvent(event)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
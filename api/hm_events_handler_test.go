@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+type fakeHMEventsReceiver struct {
+	events []deployments.HMEvent
+}
+
+func (r *fakeHMEventsReceiver) HandleHMEvent(event deployments.HMEvent) {
+	r.events = append(r.events, event)
+}
+
+var _ = Describe("HMEventsHandler", func() {
+	var (
+		method   string
+		body     string
+		receiver *fakeHMEventsReceiver
+		recorder *httptest.ResponseRecorder
+
+		hmEventsHandler *HMEventsHandler
+	)
+
+	BeforeEach(func() {
+		method = "POST"
+		body = `{"kind":"alert","id":"1","severity":4,"title":"Begin update deployment","deployment":"fake-deployment-name"}
+{"kind":"heartbeat","id":"2","deployment":"fake-deployment-name","instance_id":"fake-instance-id","job_state":"failing"}
+`
+		receiver = &fakeHMEventsReceiver{}
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		hmEventsHandler = NewHMEventsHandler(receiver)
+		hmEventsHandler.ServeHTTP(recorder, httptest.NewRequest(method, "/api/v1/hm-events", strings.NewReader(body)))
+	})
+
+	It("hands the events to the receiver", func() {
+		Expect(recorder.Code).To(Equal(http.StatusNoContent))
+		Expect(receiver.events).To(Equal([]deployments.HMEvent{
+			{Kind: "alert", Deployment: "fake-deployment-name"},
+			{Kind: "heartbeat", Deployment: "fake-deployment-name", InstanceID: "fake-instance-id", JobState: "failing"},
+		}))
+	})
+
+	Context("when an event is not valid JSON", func() {
+		BeforeEach(func() {
+			body = `{"kind":"alert","deployment":"fake-deployment-name"}
+not json
+`
+		})
+
+		It("returns a bad request after handing the previous events", func() {
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			Expect(receiver.events).To(HaveLen(1))
+		})
+	})
+
+	Context("when the method is not POST", func() {
+		BeforeEach(func() {
+			method = "GET"
+		})
+
+		It("returns a method not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(recorder.Header().Get("Allow")).To(Equal("POST"))
+			Expect(receiver.events).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+type CollectorsStatusProvider interface {
+	CollectorsStatus() []CollectorStatus
+}
+
+// CollectorStatus describes an enabled collector and the time of its last
+// successful collection, zero if it never succeeded.
+type CollectorStatus struct {
+	Name                     string
+	LastSuccessfulCollection time.Time
+}
+
+// Setting is a filter configuration shown on the landing page.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Link is a link to an exporter endpoint shown on the landing page.
+type Link struct {
+	Name string
+	Path string
+}
+
+type landingPage struct {
+	Collectors []CollectorStatus
+	Filters    []Setting
+	Links      []Link
+}
+
+var landingPageTemplate = template.Must(template.New("landing").Parse(`<html>
+<head><title>BOSH Exporter</title></head>
+<body>
+<h1>BOSH Exporter</h1>
+<p>{{range $index, $link := .Links}}{{if $index}} | {{end}}<a href="{{$link.Path}}">{{$link.Name}}</a>{{end}}</p>
+<h2>Collectors</h2>
+<table>
+<tr><th>Collector</th><th>Last successful collection</th></tr>
+{{range .Collectors}}<tr><td>{{.Name}}</td><td>{{if .LastSuccessfulCollection.IsZero}}never{{else}}{{.LastSuccessfulCollection.UTC.Format "2006-01-02T15:04:05Z07:00"}}{{end}}</td></tr>
+{{end}}</table>
+<h2>Filters</h2>
+<table>
+{{range .Filters}}<tr><td>{{.Name}}</td><td>{{if .Value}}{{.Value}}{{else}}none{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type LandingPageHandler struct {
+	collectorsStatusProvider CollectorsStatusProvider
+	filters                  []Setting
+	links                    []Link
+}
+
+func NewLandingPageHandler(
+	collectorsStatusProvider CollectorsStatusProvider,
+	filters []Setting,
+	links []Link,
+) *LandingPageHandler {
+	return &LandingPageHandler{
+		collectorsStatusProvider: collectorsStatusProvider,
+		filters:                  filters,
+		links:                    links,
+	}
+}
+
+// ServeHTTP writes an HTML page listing the enabled collectors with the time
+// of their last successful collection, the filter configuration and links to
+// the exporter endpoints.
+func (h *LandingPageHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := landingPage{
+		Collectors: h.collectorsStatusProvider.CollectorsStatus(),
+		Filters:    h.filters,
+		Links:      h.links,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := landingPageTemplate.Execute(w, page); err != nil {
+		log.Errorf("Error writing landing page: %v", err)
+	}
+}
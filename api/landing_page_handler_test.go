@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+type fakeCollectorsStatusProvider struct {
+	collectorsStatus []CollectorStatus
+}
+
+func (p *fakeCollectorsStatusProvider) CollectorsStatus() []CollectorStatus {
+	return p.collectorsStatus
+}
+
+var _ = Describe("LandingPageHandler", func() {
+	var (
+		method   string
+		path     string
+		recorder *httptest.ResponseRecorder
+
+		landingPageHandler *LandingPageHandler
+	)
+
+	BeforeEach(func() {
+		method = "GET"
+		path = "/"
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		collectorsStatusProvider := &fakeCollectorsStatusProvider{
+			collectorsStatus: []CollectorStatus{
+				{Name: "Deployments", LastSuccessfulCollection: time.Date(2017, 5, 10, 12, 30, 0, 0, time.UTC)},
+				{Name: "Jobs"},
+			},
+		}
+		landingPageHandler = NewLandingPageHandler(
+			collectorsStatusProvider,
+			[]Setting{
+				{Name: "filter.deployments", Value: "fake-deployment-name"},
+				{Name: "filter.teams"},
+			},
+			[]Link{
+				{Name: "Metrics", Path: "/metrics"},
+				{Name: "Service Discovery", Path: "/sd"},
+			},
+		)
+		landingPageHandler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+	})
+
+	It("lists the collectors with their last successful collection", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("text/html; charset=utf-8"))
+		Expect(recorder.Body.String()).To(ContainSubstring("<tr><td>Deployments</td><td>2017-05-10T12:30:00Z</td></tr>"))
+		Expect(recorder.Body.String()).To(ContainSubstring("<tr><td>Jobs</td><td>never</td></tr>"))
+	})
+
+	It("lists the filters", func() {
+		Expect(recorder.Body.String()).To(ContainSubstring("<tr><td>filter.deployments</td><td>fake-deployment-name</td></tr>"))
+		Expect(recorder.Body.String()).To(ContainSubstring("<tr><td>filter.teams</td><td>none</td></tr>"))
+	})
+
+	It("links to the exporter endpoints", func() {
+		Expect(recorder.Body.String()).To(ContainSubstring(`<a href="/metrics">Metrics</a> | <a href="/sd">Service Discovery</a>`))
+	})
+
+	Context("when the path is not the root", func() {
+		BeforeEach(func() {
+			path = "/unknown"
+		})
+
+		It("returns not found", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("returns method not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(recorder.Header().Get("Allow")).To(Equal("GET, HEAD"))
+		})
+	})
+})
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+)
+
+// ScrapeTimeoutHeader is the header Prometheus sets to the scrape timeout.
+const ScrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// GathererFunc returns the gatherer of a scrape, bounded by the timeout
+// (unbounded if 0).
+type GathererFunc func(timeout time.Duration) prometheus.Gatherer
+
+// MetricsHandler exposes the metrics, bounding their collection by the scrape
+// timeout sent by Prometheus minus timeoutOffset (left to encode and send
+// the response).
+type MetricsHandler struct {
+	gatherer      GathererFunc
+	timeoutOffset time.Duration
+}
+
+func NewMetricsHandler(gatherer GathererFunc, timeoutOffset time.Duration) *MetricsHandler {
+	return &MetricsHandler{
+		gatherer:      gatherer,
+		timeoutOffset: timeoutOffset,
+	}
+}
+
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metricFamilies, err := h.gatherer(h.scrapeTimeout(r)).Gather()
+	if err != nil {
+		http.Error(w, "An error has occurred during metrics collection:\n\n"+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := expfmt.Negotiate(r.Header)
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, contentType)
+	for _, metricFamily := range metricFamilies {
+		if err := encoder.Encode(metricFamily); err != nil {
+			http.Error(w, "An error has occurred during metrics encoding:\n\n"+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", string(contentType))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+func (h *MetricsHandler) scrapeTimeout(r *http.Request) time.Duration {
+	header := r.Header.Get(ScrapeTimeoutHeader)
+	if header == "" {
+		return 0
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		log.Errorf("Invalid `%s` header `%s` from `%s`", ScrapeTimeoutHeader, header, r.RemoteAddr)
+		return 0
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > h.timeoutOffset {
+		timeout -= h.timeoutOffset
+	}
+
+	return timeout
+}
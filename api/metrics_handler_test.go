@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+var _ = Describe("MetricsHandler", func() {
+	var (
+		scrapeTimeout   string
+		gatherErr       error
+		gathererTimeout time.Duration
+		recorder        *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		scrapeTimeout = ""
+		gatherErr = nil
+		gathererTimeout = -1
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		gatherer := func(timeout time.Duration) prometheus.Gatherer {
+			gathererTimeout = timeout
+			return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return []*dto.MetricFamily{
+					{
+						Name: proto.String("bosh_last_scrape_error"),
+						Help: proto.String("Fake help."),
+						Type: dto.MetricType_GAUGE.Enum(),
+						Metric: []*dto.Metric{
+							{Gauge: &dto.Gauge{Value: proto.Float64(0)}},
+						},
+					},
+				}, gatherErr
+			})
+		}
+
+		request := httptest.NewRequest("GET", "/metrics", nil)
+		if scrapeTimeout != "" {
+			request.Header.Set(ScrapeTimeoutHeader, scrapeTimeout)
+		}
+		NewMetricsHandler(gatherer, 500*time.Millisecond).ServeHTTP(recorder, request)
+	})
+
+	It("writes the metrics using the text format", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(recorder.Body.String()).To(ContainSubstring("bosh_last_scrape_error 0\n"))
+	})
+
+	It("does not bound the collection", func() {
+		Expect(gathererTimeout).To(BeZero())
+	})
+
+	Context("when Prometheus sends the scrape timeout", func() {
+		BeforeEach(func() {
+			scrapeTimeout = "10.5"
+		})
+
+		It("bounds the collection by the scrape timeout minus the offset", func() {
+			Expect(gathererTimeout).To(Equal(10 * time.Second))
+		})
+	})
+
+	Context("when the scrape timeout is shorter than the offset", func() {
+		BeforeEach(func() {
+			scrapeTimeout = "0.2"
+		})
+
+		It("bounds the collection by the scrape timeout", func() {
+			Expect(gathererTimeout).To(Equal(200 * time.Millisecond))
+		})
+	})
+
+	Context("when the scrape timeout is not valid", func() {
+		BeforeEach(func() {
+			scrapeTimeout = "fake-timeout"
+		})
+
+		It("does not bound the collection", func() {
+			Expect(gathererTimeout).To(BeZero())
+		})
+	})
+
+	Context("when gathering the metrics fails", func() {
+		BeforeEach(func() {
+			gatherErr = errors.New("fake-error")
+		})
+
+		It("returns an internal server error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("fake-error"))
+		})
+	})
+})
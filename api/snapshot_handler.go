@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+type DeploymentsFetcher interface {
+	Deployments() ([]deployments.DeploymentInfo, error)
+}
+
+type Snapshot struct {
+	Environment string                       `json:"environment"`
+	BoshName    string                       `json:"bosh_name"`
+	BoshUUID    string                       `json:"bosh_uuid"`
+	FetchedAt   time.Time                    `json:"fetched_at"`
+	Deployments []deployments.DeploymentInfo `json:"deployments"`
+}
+
+type SnapshotHandler struct {
+	environment        string
+	boshName           string
+	boshUUID           string
+	deploymentsFetcher DeploymentsFetcher
+}
+
+func NewSnapshotHandler(
+	environment string,
+	boshName string,
+	boshUUID string,
+	deploymentsFetcher DeploymentsFetcher,
+) *SnapshotHandler {
+	return &SnapshotHandler{
+		environment:        environment,
+		boshName:           boshName,
+		boshUUID:           boshUUID,
+		deploymentsFetcher: deploymentsFetcher,
+	}
+}
+
+// ServeHTTP fetches the deployments from the BOSH Director and writes them,
+// with their instances and processes, as JSON.
+func (h *SnapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	deploymentsInfo, err := h.deploymentsFetcher.Deployments()
+	if err != nil {
+		log.Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deploymentsInfo == nil {
+		deploymentsInfo = []deployments.DeploymentInfo{}
+	}
+
+	snapshot := Snapshot{
+		Environment: h.environment,
+		BoshName:    h.boshName,
+		BoshUUID:    h.boshUUID,
+		FetchedAt:   time.Now().UTC(),
+		Deployments: deploymentsInfo,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(snapshot); err != nil {
+		log.Errorf("Error writing snapshot: %v", err)
+	}
+}
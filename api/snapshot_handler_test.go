@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/api"
+)
+
+type fakeDeploymentsFetcher struct {
+	deployments []deployments.DeploymentInfo
+	err         error
+}
+
+func (f *fakeDeploymentsFetcher) Deployments() ([]deployments.DeploymentInfo, error) {
+	return f.deployments, f.err
+}
+
+var _ = Describe("SnapshotHandler", func() {
+	var (
+		method             string
+		deploymentsFetcher *fakeDeploymentsFetcher
+		recorder           *httptest.ResponseRecorder
+
+		snapshotHandler *SnapshotHandler
+	)
+
+	BeforeEach(func() {
+		method = "GET"
+		deploymentsFetcher = &fakeDeploymentsFetcher{
+			deployments: []deployments.DeploymentInfo{
+				{
+					Name: "fake-deployment-name",
+					Instances: []deployments.Instance{
+						{
+							Name:    "fake-job-name",
+							ID:      "fake-job-id",
+							IPs:     []string{"1.2.3.4"},
+							Healthy: true,
+							Processes: []deployments.Process{
+								{Name: "fake-process-name", Healthy: true},
+							},
+						},
+					},
+				},
+			},
+		}
+		recorder = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		snapshotHandler = NewSnapshotHandler("test_environment", "test_bosh_name", "test_bosh_uuid", deploymentsFetcher)
+		snapshotHandler.ServeHTTP(recorder, httptest.NewRequest(method, "/api/v1/snapshot", nil))
+	})
+
+	It("returns the deployments as JSON", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var snapshot map[string]interface{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &snapshot)).To(Succeed())
+		Expect(snapshot).To(HaveKeyWithValue("environment", "test_environment"))
+		Expect(snapshot).To(HaveKeyWithValue("bosh_name", "test_bosh_name"))
+		Expect(snapshot).To(HaveKeyWithValue("bosh_uuid", "test_bosh_uuid"))
+		Expect(snapshot).To(HaveKey("fetched_at"))
+		Expect(snapshot["deployments"]).To(HaveLen(1))
+
+		deployment := snapshot["deployments"].([]interface{})[0].(map[string]interface{})
+		Expect(deployment).To(HaveKeyWithValue("name", "fake-deployment-name"))
+
+		instance := deployment["instances"].([]interface{})[0].(map[string]interface{})
+		Expect(instance).To(HaveKeyWithValue("name", "fake-job-name"))
+		Expect(instance).To(HaveKeyWithValue("id", "fake-job-id"))
+		Expect(instance).To(HaveKeyWithValue("ips", []interface{}{"1.2.3.4"}))
+		Expect(instance).To(HaveKeyWithValue("healthy", true))
+
+		process := instance["processes"].([]interface{})[0].(map[string]interface{})
+		Expect(process).To(HaveKeyWithValue("name", "fake-process-name"))
+	})
+
+	Context("when there are no deployments", func() {
+		BeforeEach(func() {
+			deploymentsFetcher.deployments = nil
+		})
+
+		It("returns an empty deployments list", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"deployments":[]`))
+		})
+	})
+
+	Context("when fetching the deployments fails", func() {
+		BeforeEach(func() {
+			deploymentsFetcher.err = errors.New("no deployments")
+		})
+
+		It("returns an internal server error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("no deployments"))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("returns a method not allowed error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// NewFileTokenFunc returns a TokenFunc reading a bearer token from a file on
+// every request, so a sidecar (i.e. of an OIDC proxy fronting the BOSH
+// Director) can rotate it without restarting the exporter.
+func NewFileTokenFunc(filename string) TokenFunc {
+	return func(retried bool) (string, error) {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Error reading bearer token file `%s`: %v", filename, err))
+		}
+
+		token := strings.TrimSpace(string(content))
+		if token == "" {
+			return "", errors.New(fmt.Sprintf("Error reading bearer token file `%s`: file is empty", filename))
+		}
+
+		return "Bearer " + token, nil
+	}
+}
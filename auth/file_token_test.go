@@ -0,0 +1,66 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/auth"
+)
+
+var _ = Describe("NewFileTokenFunc", func() {
+	var (
+		err       error
+		tokenFile *os.File
+		tokenFunc TokenFunc
+	)
+
+	BeforeEach(func() {
+		tokenFile, err = ioutil.TempFile("", "file_token_test_")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(tokenFile.Name(), []byte("fake-token\n"), 0600)).To(Succeed())
+
+		tokenFunc = NewFileTokenFunc(tokenFile.Name())
+	})
+
+	AfterEach(func() {
+		os.Remove(tokenFile.Name())
+	})
+
+	It("returns the bearer token read from the file", func() {
+		Expect(tokenFunc(false)).To(Equal("Bearer fake-token"))
+	})
+
+	It("reads the file again on every request", func() {
+		Expect(tokenFunc(false)).To(Equal("Bearer fake-token"))
+
+		Expect(ioutil.WriteFile(tokenFile.Name(), []byte("rotated-token"), 0600)).To(Succeed())
+		Expect(tokenFunc(false)).To(Equal("Bearer rotated-token"))
+	})
+
+	Context("when the file is empty", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(tokenFile.Name(), []byte("\n"), 0600)).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			_, err := tokenFunc(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("file is empty"))
+		})
+	})
+
+	Context("when the file does not exist", func() {
+		BeforeEach(func() {
+			os.Remove(tokenFile.Name())
+		})
+
+		It("returns an error", func() {
+			_, err := tokenFunc(false)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error reading bearer token file"))
+		})
+	})
+})
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/common/log"
+)
+
+// ReadSecretFile returns the secret stored in a file (i.e. a Kubernetes
+// Secret key mounted in the Pod), without its surrounding whitespaces.
+func ReadSecretFile(filename string) (string, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error reading secret file `%s`: %v", filename, err))
+	}
+
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return "", errors.New(fmt.Sprintf("Error reading secret file `%s`: file is empty", filename))
+	}
+
+	return secret, nil
+}
+
+// NewSecretFileTokenFunc returns a TokenFunc reading a secret from a file on
+// every request, and delegating to the TokenFunc built by newTokenFunc from
+// that secret. The TokenFunc is built again when the secret changes, so a
+// rotated secret is used without restarting the exporter.
+func NewSecretFileTokenFunc(filename string, newTokenFunc func(secret string) (TokenFunc, error)) TokenFunc {
+	var (
+		mutex     sync.Mutex
+		secret    string
+		tokenFunc TokenFunc
+	)
+
+	return func(retried bool) (string, error) {
+		currentSecret, err := ReadSecretFile(filename)
+		if err != nil {
+			return "", err
+		}
+
+		mutex.Lock()
+		if tokenFunc == nil || currentSecret != secret {
+			if tokenFunc != nil {
+				log.Infof("Secret file `%s` changed, using the new secret", filename)
+			}
+			tokenFunc, err = newTokenFunc(currentSecret)
+			if err != nil {
+				tokenFunc = nil
+				mutex.Unlock()
+				return "", err
+			}
+			secret = currentSecret
+		}
+		currentTokenFunc := tokenFunc
+		mutex.Unlock()
+
+		return currentTokenFunc(retried)
+	}
+}
+
+// NewBasicAuthTokenFunc returns a TokenFunc sending the username and
+// password as basic auth credentials.
+func NewBasicAuthTokenFunc(username string, password string) TokenFunc {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return func(retried bool) (string, error) {
+		return "Basic " + credentials, nil
+	}
+}
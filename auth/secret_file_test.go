@@ -0,0 +1,118 @@
+package auth_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/auth"
+)
+
+var _ = Describe("ReadSecretFile", func() {
+	var (
+		err        error
+		secretFile *os.File
+	)
+
+	BeforeEach(func() {
+		secretFile, err = ioutil.TempFile("", "secret_file_test_")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Remove(secretFile.Name())
+	})
+
+	It("returns the secret trimmed", func() {
+		Expect(ioutil.WriteFile(secretFile.Name(), []byte("fake-secret\n"), 0600)).To(Succeed())
+
+		Expect(ReadSecretFile(secretFile.Name())).To(Equal("fake-secret"))
+	})
+
+	It("returns an error if the file is empty", func() {
+		_, err = ReadSecretFile(secretFile.Name())
+		Expect(err).To(MatchError(ContainSubstring("file is empty")))
+	})
+
+	It("returns an error if the file does not exist", func() {
+		_, err = ReadSecretFile(secretFile.Name() + "-missing")
+		Expect(err).To(MatchError(ContainSubstring("Error reading secret file")))
+	})
+})
+
+var _ = Describe("NewSecretFileTokenFunc", func() {
+	var (
+		err        error
+		secretFile *os.File
+		secrets    []string
+		buildErr   error
+		tokenFunc  TokenFunc
+	)
+
+	BeforeEach(func() {
+		secretFile, err = ioutil.TempFile("", "secret_file_test_")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(secretFile.Name(), []byte("fake-secret\n"), 0600)).To(Succeed())
+
+		secrets = []string{}
+		buildErr = nil
+		tokenFunc = NewSecretFileTokenFunc(secretFile.Name(), func(secret string) (TokenFunc, error) {
+			if buildErr != nil {
+				return nil, buildErr
+			}
+			secrets = append(secrets, secret)
+			return func(retried bool) (string, error) {
+				return "bearer token-of-" + secret, nil
+			}, nil
+		})
+	})
+
+	AfterEach(func() {
+		os.Remove(secretFile.Name())
+	})
+
+	It("returns the token of the secret read from the file", func() {
+		Expect(tokenFunc(false)).To(Equal("bearer token-of-fake-secret"))
+	})
+
+	It("builds the token func once while the secret does not change", func() {
+		Expect(tokenFunc(false)).To(Equal("bearer token-of-fake-secret"))
+		Expect(tokenFunc(true)).To(Equal("bearer token-of-fake-secret"))
+
+		Expect(secrets).To(Equal([]string{"fake-secret"}))
+	})
+
+	It("builds the token func again when the secret changes", func() {
+		Expect(tokenFunc(false)).To(Equal("bearer token-of-fake-secret"))
+
+		Expect(ioutil.WriteFile(secretFile.Name(), []byte("rotated-secret"), 0600)).To(Succeed())
+		Expect(tokenFunc(false)).To(Equal("bearer token-of-rotated-secret"))
+
+		Expect(secrets).To(Equal([]string{"fake-secret", "rotated-secret"}))
+	})
+
+	It("returns an error if the file cannot be read", func() {
+		os.Remove(secretFile.Name())
+
+		_, err = tokenFunc(false)
+		Expect(err).To(MatchError(ContainSubstring("Error reading secret file")))
+	})
+
+	It("returns an error and retries if the token func cannot be built", func() {
+		buildErr = errors.New("fake-error")
+		_, err = tokenFunc(false)
+		Expect(err).To(MatchError("fake-error"))
+
+		buildErr = nil
+		Expect(tokenFunc(false)).To(Equal("bearer token-of-fake-secret"))
+	})
+})
+
+var _ = Describe("NewBasicAuthTokenFunc", func() {
+	It("returns the basic auth credentials", func() {
+		Expect(NewBasicAuthTokenFunc("fake-username", "fake-password")(false)).To(Equal("Basic ZmFrZS11c2VybmFtZTpmYWtlLXBhc3N3b3Jk"))
+	})
+})
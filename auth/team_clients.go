@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TeamClient is a UAA client whose scopes are restricted to a BOSH team.
+type TeamClient struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// LoadTeamClients reads the UAA team clients from a YAML file listing
+// `client_id` and `client_secret` pairs. It returns no clients if the
+// filename is empty.
+func LoadTeamClients(filename string) ([]TeamClient, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading UAA team clients file `%s`: %v", filename, err))
+	}
+
+	var teamClients []TeamClient
+	if err = yaml.Unmarshal(content, &teamClients); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing UAA team clients file `%s`: %v", filename, err))
+	}
+
+	for i, teamClient := range teamClients {
+		if teamClient.ClientID == "" || teamClient.ClientSecret == "" {
+			return nil, errors.New(fmt.Sprintf("Invalid UAA team client #%d at UAA team clients file `%s`: `client_id` and `client_secret` are required", i, filename))
+		}
+	}
+
+	return teamClients, nil
+}
@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/auth"
+)
+
+var _ = Describe("LoadTeamClients", func() {
+	var (
+		err         error
+		content     string
+		filename    string
+		teamClients []TeamClient
+	)
+
+	BeforeEach(func() {
+		content = `
+- client_id: team-a-client
+  client_secret: team-a-secret
+- client_id: team-b-client
+  client_secret: team-b-secret
+`
+	})
+
+	JustBeforeEach(func() {
+		tmpfile, tmpErr := ioutil.TempFile("", "team_clients_test_")
+		Expect(tmpErr).ToNot(HaveOccurred())
+		Expect(ioutil.WriteFile(tmpfile.Name(), []byte(content), 0600)).To(Succeed())
+		filename = tmpfile.Name()
+
+		teamClients, err = LoadTeamClients(filename)
+	})
+
+	AfterEach(func() {
+		os.Remove(filename)
+	})
+
+	It("returns the team clients", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(teamClients).To(Equal([]TeamClient{
+			{ClientID: "team-a-client", ClientSecret: "team-a-secret"},
+			{ClientID: "team-b-client", ClientSecret: "team-b-secret"},
+		}))
+	})
+
+	Context("when a client has no secret", func() {
+		BeforeEach(func() {
+			content = `
+- client_id: team-a-client
+`
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("`client_id` and `client_secret` are required"))
+		})
+	})
+
+	Context("when the file is not valid YAML", func() {
+		BeforeEach(func() {
+			content = "client_id: team-a-client"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error parsing UAA team clients file"))
+		})
+	})
+
+	It("returns no clients when the filename is empty", func() {
+		Expect(LoadTeamClients("")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/bosh-cli/uaa"
+	"github.com/prometheus/common/log"
+)
+
+// TokenFunc returns the authorization header value (i.e. `bearer <token>`)
+// of the requests to the BOSH Director, getting a new token when retried.
+type TokenFunc func(retried bool) (string, error)
+
+// TokenSession wraps a UAA TokenFunc, recording the expiry of the last token
+// and the number of failures getting a token, so credentials problems are
+// noticed before the tokens expire.
+type TokenSession struct {
+	tokenFunc TokenFunc
+	mutex     sync.Mutex
+	expiry    time.Time
+	failures  uint64
+}
+
+func NewTokenSession(tokenFunc TokenFunc) *TokenSession {
+	return &TokenSession{tokenFunc: tokenFunc}
+}
+
+func (s *TokenSession) TokenFunc(retried bool) (string, error) {
+	token, err := s.tokenFunc(retried)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err != nil {
+		s.failures++
+		return token, err
+	}
+
+	fields := strings.Fields(token)
+	if len(fields) == 0 {
+		return token, nil
+	}
+	tokenInfo, err := uaa.NewTokenInfoFromValue(fields[len(fields)-1])
+	if err != nil {
+		log.Debugf("Error reading UAA token expiry: %v", err)
+		return token, nil
+	}
+	s.expiry = time.Unix(int64(tokenInfo.ExpiredAt), 0)
+
+	return token, nil
+}
+
+// TokenExpiry returns the expiry of the last token, zero if no token was
+// got yet.
+func (s *TokenSession) TokenExpiry() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.expiry
+}
+
+// AuthFailures returns the number of failures getting a token.
+func (s *TokenSession) AuthFailures() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.failures
+}
+
+// TokenSessions reports the health of several token sessions: the earliest
+// token expiry and the total number of failures.
+type TokenSessions []*TokenSession
+
+func (s TokenSessions) TokenExpiry() time.Time {
+	var expiry time.Time
+	for _, tokenSession := range s {
+		tokenExpiry := tokenSession.TokenExpiry()
+		if !tokenExpiry.IsZero() && (expiry.IsZero() || tokenExpiry.Before(expiry)) {
+			expiry = tokenExpiry
+		}
+	}
+
+	return expiry
+}
+
+func (s TokenSessions) AuthFailures() uint64 {
+	var failures uint64
+	for _, tokenSession := range s {
+		failures += tokenSession.AuthFailures()
+	}
+
+	return failures
+}
@@ -0,0 +1,87 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/auth"
+)
+
+var _ = Describe("TokenSession", func() {
+	var (
+		token        string
+		tokenErr     error
+		retries      []bool
+		tokenSession *TokenSession
+	)
+
+	BeforeEach(func() {
+		token = "bearer fake-header." + base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1500000000}`)) + ".fake-signature"
+		tokenErr = nil
+		retries = []bool{}
+		tokenSession = NewTokenSession(func(retried bool) (string, error) {
+			retries = append(retries, retried)
+			return token, tokenErr
+		})
+	})
+
+	It("returns the token", func() {
+		Expect(tokenSession.TokenFunc(true)).To(Equal(token))
+		Expect(retries).To(Equal([]bool{true}))
+	})
+
+	It("records the token expiry", func() {
+		Expect(tokenSession.TokenExpiry().IsZero()).To(BeTrue())
+
+		_, err := tokenSession.TokenFunc(false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokenSession.TokenExpiry()).To(Equal(time.Unix(1500000000, 0)))
+		Expect(tokenSession.AuthFailures()).To(BeZero())
+	})
+
+	Context("when the token is not a JWT", func() {
+		BeforeEach(func() {
+			token = "bearer fake-token"
+		})
+
+		It("returns the token without expiry", func() {
+			Expect(tokenSession.TokenFunc(false)).To(Equal(token))
+			Expect(tokenSession.TokenExpiry().IsZero()).To(BeTrue())
+		})
+	})
+
+	Context("when there are several sessions", func() {
+		It("reports the earliest expiry and the total failures", func() {
+			_, err := tokenSession.TokenFunc(false)
+			Expect(err).ToNot(HaveOccurred())
+
+			failingSession := NewTokenSession(func(retried bool) (string, error) {
+				return "", errors.New("fake-error")
+			})
+			failingSession.TokenFunc(false)
+
+			tokenSessions := TokenSessions{NewTokenSession(nil), failingSession, tokenSession}
+			Expect(tokenSessions.TokenExpiry()).To(Equal(time.Unix(1500000000, 0)))
+			Expect(tokenSessions.AuthFailures()).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("when getting a token fails", func() {
+		BeforeEach(func() {
+			tokenErr = errors.New("fake-error")
+		})
+
+		It("counts the failures", func() {
+			_, err := tokenSession.TokenFunc(false)
+			Expect(err).To(MatchError("fake-error"))
+			_, err = tokenSession.TokenFunc(true)
+			Expect(err).To(HaveOccurred())
+
+			Expect(tokenSession.AuthFailures()).To(Equal(uint64(2)))
+		})
+	})
+})
@@ -1,24 +1,51 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry/bosh-cli/director"
 	"github.com/cloudfoundry/bosh-cli/uaa"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/cloudfoundry/bosh-utils/system"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/version"
 
+	"github.com/cloudfoundry-community/bosh_exporter/api"
+	"github.com/cloudfoundry-community/bosh_exporter/auth"
 	"github.com/cloudfoundry-community/bosh_exporter/collectors"
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
+	"github.com/cloudfoundry-community/bosh_exporter/httpcache"
+	"github.com/cloudfoundry-community/bosh_exporter/publishers"
+	"github.com/cloudfoundry-community/bosh_exporter/redirect"
+)
+
+const (
+	snapshotPath    = "/api/v1/snapshot"
+	hmEventsPath    = "/api/v1/hm-events"
+	sdPath          = "/sd"
+	healthyPath     = "/-/healthy"
+	readyPath       = "/-/ready"
+	validateCommand = "validate"
+
+	// scrapeRetryAfter is the delay after which rejected scrapes are retried.
+	scrapeRetryAfter = 5 * time.Second
 )
 
 var (
@@ -37,6 +64,11 @@ var (
 		"BOSH Password ($BOSH_EXPORTER_BOSH_PASSWORD).",
 	)
 
+	boshPasswordFile = flag.String(
+		"bosh.password-file", "",
+		"Path to a file containing the BOSH Password (i.e. a mounted Kubernetes Secret), overriding the BOSH Password flag ($BOSH_EXPORTER_BOSH_PASSWORD_FILE).",
+	)
+
 	boshUAAClientID = flag.String(
 		"bosh.uaa.client-id", "",
 		"BOSH UAA Client ID ($BOSH_EXPORTER_BOSH_UAA_CLIENT_ID).",
@@ -47,6 +79,21 @@ var (
 		"BOSH UAA Client Secret ($BOSH_EXPORTER_BOSH_UAA_CLIENT_SECRET).",
 	)
 
+	boshUAAClientSecretFile = flag.String(
+		"bosh.uaa.client-secret-file", "",
+		"Path to a file containing the BOSH UAA Client Secret (i.e. a mounted Kubernetes Secret), read again on every request and overriding the BOSH UAA Client Secret flag ($BOSH_EXPORTER_BOSH_UAA_CLIENT_SECRET_FILE).",
+	)
+
+	boshUAATeamClientsFile = flag.String(
+		"bosh.uaa.team-clients-file", "",
+		"Path to a YAML file listing additional BOSH UAA clients (`client_id` and `client_secret`), i.e. scoped to BOSH teams, whose deployments are merged ($BOSH_EXPORTER_BOSH_UAA_TEAM_CLIENTS_FILE).",
+	)
+
+	boshBearerTokenFile = flag.String(
+		"bosh.bearer-token-file", "",
+		"Path to a file containing a bearer token sent to the BOSH Director (i.e. fronted by an OIDC proxy), read again on every request. When set, the BOSH Username, Password and UAA Client flags are ignored ($BOSH_EXPORTER_BOSH_BEARER_TOKEN_FILE).",
+	)
+
 	boshLogLevel = flag.String(
 		"bosh.log-level", "ERROR",
 		"BOSH Log Level ($BOSH_EXPORTER_BOSH_LOG_LEVEL).",
@@ -57,19 +104,104 @@ var (
 		"BOSH CA Certificate file ($BOSH_EXPORTER_BOSH_CA_CERT_FILE).",
 	)
 
+	boshHTTPMaxIdleConns = flag.Int(
+		"bosh.http.max-idle-conns", 100,
+		"Maximum number of idle (keep-alive) connections to the BOSH Director across all hosts ($BOSH_EXPORTER_BOSH_HTTP_MAX_IDLE_CONNS).",
+	)
+
+	boshHTTPMaxIdleConnsPerHost = flag.Int(
+		"bosh.http.max-idle-conns-per-host", 10,
+		"Maximum number of idle (keep-alive) connections to keep per BOSH Director host ($BOSH_EXPORTER_BOSH_HTTP_MAX_IDLE_CONNS_PER_HOST).",
+	)
+
+	boshHTTPIdleConnTimeout = flag.Duration(
+		"bosh.http.idle-conn-timeout", 90*time.Second,
+		"Maximum amount of time an idle (keep-alive) connection to the BOSH Director will remain idle before closing itself ($BOSH_EXPORTER_BOSH_HTTP_IDLE_CONN_TIMEOUT).",
+	)
+
+	boshHTTPKeepAlive = flag.Duration(
+		"bosh.http.keep-alive", 30*time.Second,
+		"Keep-alive period for active TCP connections to the BOSH Director ($BOSH_EXPORTER_BOSH_HTTP_KEEP_ALIVE).",
+	)
+
+	boshHTTPDisableKeepAlives = flag.Bool(
+		"bosh.http.disable-keep-alives", false,
+		"Disable HTTP keep-alives and use a new connection for every BOSH Director request ($BOSH_EXPORTER_BOSH_HTTP_DISABLE_KEEP_ALIVES).",
+	)
+
+	boshHTTPConditionalRequests = flag.Bool(
+		"bosh.http.conditional-requests", false,
+		"Send conditional requests (If-None-Match and If-Modified-Since) for the BOSH Director deployments and configs endpoints, reusing the cached responses when they did not change ($BOSH_EXPORTER_BOSH_HTTP_CONDITIONAL_REQUESTS).",
+	)
+
+	boshDeploymentsRefreshInterval = flag.Duration(
+		"bosh.deployments-refresh-interval", 0,
+		"Interval at which the deployments list (with their teams, manifests, releases, stemcells, snapshots and tasks) is fetched from the BOSH Director. If 0, it is fetched on each scrape ($BOSH_EXPORTER_BOSH_DEPLOYMENTS_REFRESH_INTERVAL).",
+	)
+
+	boshInstancesRefreshInterval = flag.Duration(
+		"bosh.instances-refresh-interval", 0,
+		"Interval at which the deployments instances (with their vitals and processes) are fetched from the BOSH Director. If 0, they are fetched on each scrape ($BOSH_EXPORTER_BOSH_INSTANCES_REFRESH_INTERVAL).",
+	)
+
+	boshInstancesEndpoint = flag.String(
+		"bosh.instances-endpoint", deployments.InstancesEndpoint,
+		"BOSH Director endpoint the deployments instances are read from: instances or vms ($BOSH_EXPORTER_BOSH_INSTANCES_ENDPOINT).",
+	)
+
+	boshInstancesTimeout = flag.Duration(
+		"bosh.instances-timeout", 0,
+		"Time after which the deployments instances still being read in full format are read in short format, without their state, vitals and processes. If 0, the full format is always waited for ($BOSH_EXPORTER_BOSH_INSTANCES_TIMEOUT).",
+	)
+
+	boshRecentTasksLimit = flag.Int(
+		"bosh.recent-tasks-limit", deployments.DefaultRecentTasksLimit,
+		"Number of recent BOSH Director tasks read, besides the current ones, to find the deployments last tasks ($BOSH_EXPORTER_BOSH_RECENT_TASKS_LIMIT).",
+	)
+
+	boshHMEvents = flag.Bool(
+		"bosh.hm-events", false,
+		"Cache deployments between scrapes, and only refresh those changed according to the BOSH Health Monitor events received at the /api/v1/hm-events endpoint ($BOSH_EXPORTER_BOSH_HM_EVENTS).",
+	)
+
+	boshHMEventsFullRefreshInterval = flag.Duration(
+		"bosh.hm-events.full-refresh-interval", 10*time.Minute,
+		"Interval at which all cached deployments are refreshed when using BOSH Health Monitor events ($BOSH_EXPORTER_BOSH_HM_EVENTS_FULL_REFRESH_INTERVAL).",
+	)
+
 	filterDeployments = flag.String(
 		"filter.deployments", "",
-		"Comma separated deployments to filter ($BOSH_EXPORTER_FILTER_DEPLOYMENTS).",
+		"Comma separated deployments to filter. Each filter is a regular expression matching the whole deployment name, and filters prefixed with `!` exclude deployments ($BOSH_EXPORTER_FILTER_DEPLOYMENTS).",
+	)
+
+	filterTeams = flag.String(
+		"filter.teams", "",
+		"Comma separated BOSH teams to filter deployments ($BOSH_EXPORTER_FILTER_TEAMS).",
+	)
+
+	filterJobs = flag.String(
+		"filter.jobs", "",
+		"Comma separated jobs (instance groups) to filter. Each filter is a regular expression matching the whole job name, and filters prefixed with `!` exclude jobs ($BOSH_EXPORTER_FILTER_JOBS).",
 	)
 
 	filterAZs = flag.String(
 		"filter.azs", "",
-		"Comma separated AZs to filter ($BOSH_EXPORTER_FILTER_AZS).",
+		"Comma separated AZs to filter. Each filter is a regular expression matching the whole AZ name, and filters prefixed with `!` exclude AZs ($BOSH_EXPORTER_FILTER_AZS).",
 	)
 
 	filterCollectors = flag.String(
 		"filter.collectors", "",
-		"Comma separated collectors to filter (Deployments,Jobs,ServiceDiscovery) ($BOSH_EXPORTER_FILTER_COLLECTORS).",
+		"Comma separated collectors to filter (Backups,Deployments,Director,Exec,Jobs,Networks,ServiceDiscovery,Snapshots) ($BOSH_EXPORTER_FILTER_COLLECTORS).",
+	)
+
+	shardIndex = flag.Int(
+		"shard.index", 0,
+		"Index (starting at 0) of the shard of deployments collected by this exporter replica ($BOSH_EXPORTER_SHARD_INDEX).",
+	)
+
+	shardCount = flag.Int(
+		"shard.count", 1,
+		"Number of exporter replicas the deployments are partitioned across ($BOSH_EXPORTER_SHARD_COUNT).",
 	)
 
 	metricsNamespace = flag.String(
@@ -82,14 +214,334 @@ var (
 		"Environment label to be attached to metrics ($BOSH_EXPORTER_METRICS_ENVIRONMENT).",
 	)
 
+	metricsEnvironmentSource = flag.String(
+		"metrics.environment-source", "",
+		"BOSH Director info field to derive the environment label from, instead of the `metrics.environment` flag (one of `director-name`, `cpi`) ($BOSH_EXPORTER_METRICS_ENVIRONMENT_SOURCE).",
+	)
+
+	metricsEnvironmentNamesFile = flag.String(
+		"metrics.environment-names-file", "",
+		"Full path to a YAML file mapping the derived environment values to friendly names ($BOSH_EXPORTER_METRICS_ENVIRONMENT_NAMES_FILE).",
+	)
+
+	metricsSubsystems = flag.String(
+		"metrics.subsystems", "",
+		"Comma separated list of collector=subsystem pairs to prefix the collectors metrics with (one of `Deployments`, `Jobs`, `ServiceDiscovery`, `Exporter`) ($BOSH_EXPORTER_METRICS_SUBSYSTEMS).",
+	)
+
+	metricsLegacyNames = flag.Bool(
+		"metrics.legacy-names", false,
+		"Also emit the Deployments and Jobs metrics under the default `bosh` namespace, without subsystems, const labels and deployment labels ($BOSH_EXPORTER_METRICS_LEGACY_NAMES).",
+	)
+
+	metricsConstLabels = flag.String(
+		"metrics.const-labels", "",
+		"Comma separated list of key=value constant labels to be attached to all metrics ($BOSH_EXPORTER_METRICS_CONST_LABELS).",
+	)
+
+	metricsDeploymentLabelsFile = flag.String(
+		"metrics.deployment-labels-file", "",
+		"Full path to a YAML file mapping deployment names patterns to extra labels ($BOSH_EXPORTER_METRICS_DEPLOYMENT_LABELS_FILE).",
+	)
+
+	metricsServiceLabelsFile = flag.String(
+		"metrics.service-labels-file", "",
+		"Full path to a YAML file with rules mapping deployments and instance groups names regexes to logical labels, added to the metrics and Service Discovery target groups ($BOSH_EXPORTER_METRICS_SERVICE_LABELS_FILE).",
+	)
+
+	metricsStemcellsLifecycleFile = flag.String(
+		"metrics.stemcells-lifecycle-file", "",
+		"Full path to a YAML file mapping stemcells patterns to their creation and end of life dates ($BOSH_EXPORTER_METRICS_STEMCELLS_LIFECYCLE_FILE).",
+	)
+
+	metricsBackupsDirectory = flag.String(
+		"metrics.backups-directory", "",
+		"Full path to a directory containing BBR deployments backups, enables the Backups collector ($BOSH_EXPORTER_METRICS_BACKUPS_DIRECTORY).",
+	)
+
+	metricsExecCommands = flag.String(
+		"metrics.exec-commands", "",
+		"Comma separated external commands (with their arguments separated by spaces) run on each scrape with the deployments snapshot as JSON on stdin, and exposing Prometheus text format metrics on stdout. If set, the Exec collector is enabled ($BOSH_EXPORTER_METRICS_EXEC_COMMANDS).",
+	)
+
+	metricsExecTimeout = flag.Duration(
+		"metrics.exec-timeout", 30*time.Second,
+		"Maximum duration of each external command run by the Exec collector ($BOSH_EXPORTER_METRICS_EXEC_TIMEOUT).",
+	)
+
+	metricsTimestamps = flag.Bool(
+		"metrics.timestamps", false,
+		"Attach the time the data was fetched from the BOSH Director to the collectors metrics samples ($BOSH_EXPORTER_METRICS_TIMESTAMPS).",
+	)
+
+	metricsLabelsAllowlist = flag.String(
+		"metrics.labels-allowlist", "",
+		"Comma separated list of metric labels to keep, series sharing the remaining labels are summed ($BOSH_EXPORTER_METRICS_LABELS_ALLOWLIST).",
+	)
+
+	metricsMaxSeries = flag.Int(
+		"metrics.max-series", 0,
+		"Maximum number of series exported by each collector per scrape, 0 means unlimited ($BOSH_EXPORTER_METRICS_MAX_SERIES).",
+	)
+
+	metricsLabelValuesMaxLength = flag.Int(
+		"metrics.label-values-max-length", 0,
+		"Maximum length in bytes of the deployments, jobs and processes names label values, longer values are truncated, 0 means unlimited ($BOSH_EXPORTER_METRICS_LABEL_VALUES_MAX_LENGTH).",
+	)
+
+	metricsMaxScrapeSeries = flag.Int(
+		"metrics.max-scrape-series", 0,
+		"Maximum number of series exported by all collectors per scrape, beyond which the scrape is truncated, 0 means unlimited ($BOSH_EXPORTER_METRICS_MAX_SCRAPE_SERIES).",
+	)
+
 	sdFilename = flag.String(
 		"sd.filename", "bosh_target_groups.json",
-		"Full path to the Service Discovery output file ($BOSH_EXPORTER_SD_FILENAME).",
+		"Full path to the Service Discovery output file. If it contains a template (i.e. `{{.Deployment}}.json`), a file per deployment will be written ($BOSH_EXPORTER_SD_FILENAME).",
 	)
 
 	sdProcessesRegexp = flag.String(
 		"sd.processes_regexp", "",
-		"Regexp to filter Service Discovery processes names ($BOSH_EXPORTER_SD_PROCESSES_REGEXP).",
+		"Regexp to filter Service Discovery processes names. A regexp prefixed with `!` excludes processes ($BOSH_EXPORTER_SD_PROCESSES_REGEXP).",
+	)
+
+	sdDeploymentsProcessesFile = flag.String(
+		"sd.deployments_processes_file", "",
+		"Full path to a YAML file mapping deployments names regexps to the regexp filtering their Service Discovery processes names, overriding the `sd.processes_regexp` flag for those deployments ($BOSH_EXPORTER_SD_DEPLOYMENTS_PROCESSES_FILE).",
+	)
+
+	sdCIDRs = flag.String(
+		"sd.cidrs", "",
+		"Comma separated CIDRs used to select the Service Discovery target IPs when instances have multiple IPs ($BOSH_EXPORTER_SD_CIDRS).",
+	)
+
+	sdNetworks = flag.String(
+		"sd.networks", "",
+		"Comma separated networks names used to select the Service Discovery BOSH DNS names when instances have multiple networks, requires the `sd.dns_names` flag ($BOSH_EXPORTER_SD_NETWORKS).",
+	)
+
+	sdAllIPs = flag.Bool(
+		"sd.all_ips", false,
+		"Emit all selected instance IPs as Service Discovery targets instead of only the first one ($BOSH_EXPORTER_SD_ALL_IPS).",
+	)
+
+	sdProcessesPortsFile = flag.String(
+		"sd.processes_ports_file", "",
+		"Full path to a YAML file mapping Service Discovery processes names to ports ($BOSH_EXPORTER_SD_PROCESSES_PORTS_FILE).",
+	)
+
+	sdDNSNames = flag.Bool(
+		"sd.dns_names", false,
+		"Emit BOSH DNS names instead of IPs as Service Discovery targets ($BOSH_EXPORTER_SD_DNS_NAMES).",
+	)
+
+	sdRelabelConfigsFile = flag.String(
+		"sd.relabel_configs_file", "",
+		"Full path to a YAML file with Prometheus relabel configs applied to the Service Discovery target groups ($BOSH_EXPORTER_SD_RELABEL_CONFIGS_FILE).",
+	)
+
+	sdFormat = flag.String(
+		"sd.format", "",
+		"Service Discovery output file format (json, yaml, scrape-config). If empty, it is selected by the `sd.filename` extension ($BOSH_EXPORTER_SD_FORMAT).",
+	)
+
+	sdTemplateFile = flag.String(
+		"sd.template_file", "",
+		"Full path to a Go template file used to render the Service Discovery output file instead of JSON ($BOSH_EXPORTER_SD_TEMPLATE_FILE).",
+	)
+
+	sdRefreshInterval = flag.Duration(
+		"sd.refresh_interval", 0,
+		"Interval at which Service Discovery is refreshed in background, independently of Prometheus scrapes. If 0, it is refreshed on each scrape ($BOSH_EXPORTER_SD_REFRESH_INTERVAL).",
+	)
+
+	sdLeaderElectionConsulURL = flag.String(
+		"sd.leader_election.consul_url", "",
+		"Consul agent URL used to elect the exporter replica writing the Service Discovery targets, i.e. `http://127.0.0.1:8500`. If empty, all replicas write them ($BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_URL).",
+	)
+
+	sdLeaderElectionConsulToken = flag.String(
+		"sd.leader_election.consul_token", "",
+		"Consul ACL token used to elect the Service Discovery leader ($BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_TOKEN).",
+	)
+
+	sdLeaderElectionConsulKey = flag.String(
+		"sd.leader_election.consul_key", "",
+		"Consul KV key locked by the Service Discovery leader. If empty, `bosh_exporter/<bosh uuid>/service-discovery-leader` is used ($BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_KEY).",
+	)
+
+	sdLeaderElectionTTL = flag.Duration(
+		"sd.leader_election.ttl", 1*time.Minute,
+		"TTL of the Service Discovery leader Consul session, after which another replica can take over. Must be greater than the Service Discovery refresh interval ($BOSH_EXPORTER_SD_LEADER_ELECTION_TTL).",
+	)
+
+	sdConsulURL = flag.String(
+		"sd.consul.url", "",
+		"Consul agent URL where Service Discovery processes will be registered as services, i.e. `http://127.0.0.1:8500` ($BOSH_EXPORTER_SD_CONSUL_URL).",
+	)
+
+	sdConsulToken = flag.String(
+		"sd.consul.token", "",
+		"Consul ACL token used to register Service Discovery processes ($BOSH_EXPORTER_SD_CONSUL_TOKEN).",
+	)
+
+	sdEtcdURL = flag.String(
+		"sd.etcd.url", "",
+		"etcd v3 gRPC gateway URL where Service Discovery target groups will be published, i.e. `http://127.0.0.1:2379` ($BOSH_EXPORTER_SD_ETCD_URL).",
+	)
+
+	sdEtcdPrefix = flag.String(
+		"sd.etcd.prefix", "/bosh_exporter/",
+		"etcd key prefix under which Service Discovery target groups will be published, one key per deployment ($BOSH_EXPORTER_SD_ETCD_PREFIX).",
+	)
+
+	sdEtcdUsername = flag.String(
+		"sd.etcd.username", "",
+		"etcd Username ($BOSH_EXPORTER_SD_ETCD_USERNAME).",
+	)
+
+	sdEtcdPassword = flag.String(
+		"sd.etcd.password", "",
+		"etcd Password ($BOSH_EXPORTER_SD_ETCD_PASSWORD).",
+	)
+
+	sdS3Bucket = flag.String(
+		"sd.s3.bucket", "",
+		"S3 compatible bucket where the Service Discovery target groups will be uploaded on change ($BOSH_EXPORTER_SD_S3_BUCKET).",
+	)
+
+	sdS3Key = flag.String(
+		"sd.s3.key", "bosh_target_groups.json",
+		"S3 compatible object key of the uploaded Service Discovery target groups ($BOSH_EXPORTER_SD_S3_KEY).",
+	)
+
+	sdS3Endpoint = flag.String(
+		"sd.s3.endpoint", "https://s3.amazonaws.com",
+		"S3 compatible endpoint, i.e. `https://storage.googleapis.com` for GCS ($BOSH_EXPORTER_SD_S3_ENDPOINT).",
+	)
+
+	sdS3Region = flag.String(
+		"sd.s3.region", "us-east-1",
+		"S3 compatible bucket region ($BOSH_EXPORTER_SD_S3_REGION).",
+	)
+
+	sdS3AccessKeyID = flag.String(
+		"sd.s3.access_key_id", "",
+		"S3 compatible Access Key ID ($BOSH_EXPORTER_SD_S3_ACCESS_KEY_ID).",
+	)
+
+	sdS3SecretAccessKey = flag.String(
+		"sd.s3.secret_access_key", "",
+		"S3 compatible Secret Access Key ($BOSH_EXPORTER_SD_S3_SECRET_ACCESS_KEY).",
+	)
+
+	sdWebhookURLs = flag.String(
+		"sd.webhook.urls", "",
+		"Comma separated list of webhook URLs where the Service Discovery target groups added and removed are posted on change ($BOSH_EXPORTER_SD_WEBHOOK_URLS).",
+	)
+
+	alertmanagerURL = flag.String(
+		"alertmanager.url", "",
+		"Alertmanager URL where silences are created for the deployments while a deploy task is in progress ($BOSH_EXPORTER_ALERTMANAGER_URL).",
+	)
+
+	alertmanagerSilenceDuration = flag.Duration(
+		"alertmanager.silence-duration", 1*time.Hour,
+		"Duration of the deployments silences, extended while the deploy task is in progress ($BOSH_EXPORTER_ALERTMANAGER_SILENCE_DURATION).",
+	)
+
+	alertmanagerSilenceMatchers = flag.String(
+		"alertmanager.silence-matchers", "",
+		"Comma separated list of extra `name=value` matchers of the deployments silences ($BOSH_EXPORTER_ALERTMANAGER_SILENCE_MATCHERS).",
+	)
+
+	auditLogFile = flag.String(
+		"audit-log.file", "",
+		"JSON lines file where the deployments and instances changes observed between collections are appended ($BOSH_EXPORTER_AUDIT_LOG_FILE).",
+	)
+
+	remoteWriteURL = flag.String(
+		"remote-write.url", "",
+		"Prometheus remote write endpoint where the metrics will be pushed periodically ($BOSH_EXPORTER_REMOTE_WRITE_URL).",
+	)
+
+	remoteWriteInterval = flag.Duration(
+		"remote-write.interval", 1*time.Minute,
+		"Interval between pushes of the metrics to the remote write endpoint ($BOSH_EXPORTER_REMOTE_WRITE_INTERVAL).",
+	)
+
+	remoteWriteUsername = flag.String(
+		"remote-write.username", "",
+		"Remote write endpoint basic auth Username ($BOSH_EXPORTER_REMOTE_WRITE_USERNAME).",
+	)
+
+	remoteWritePassword = flag.String(
+		"remote-write.password", "",
+		"Remote write endpoint basic auth Password ($BOSH_EXPORTER_REMOTE_WRITE_PASSWORD).",
+	)
+
+	remoteWriteBearerToken = flag.String(
+		"remote-write.bearer-token", "",
+		"Remote write endpoint Bearer Token ($BOSH_EXPORTER_REMOTE_WRITE_BEARER_TOKEN).",
+	)
+
+	remoteWriteWALDirectory = flag.String(
+		"remote-write.wal-directory", "",
+		"Directory where the remote write requests are queued until the remote write endpoint accepts them ($BOSH_EXPORTER_REMOTE_WRITE_WAL_DIRECTORY).",
+	)
+
+	remoteWriteWALMaxSegments = flag.Int(
+		"remote-write.wal-max-segments", 1440,
+		"Maximum number of remote write requests queued in the WAL directory, the oldest ones being dropped beyond (0 for no limit) ($BOSH_EXPORTER_REMOTE_WRITE_WAL_MAX_SEGMENTS).",
+	)
+
+	bridgeAddress = flag.String(
+		"bridge.address", "",
+		"Address (host:port) of a Graphite or InfluxDB server where the metrics will be flushed periodically ($BOSH_EXPORTER_BRIDGE_ADDRESS).",
+	)
+
+	bridgeFormat = flag.String(
+		"bridge.format", publishers.GraphiteLineFormat,
+		"Line format of the flushed metrics, one of `graphite` (plaintext protocol) or `influx` (line protocol) ($BOSH_EXPORTER_BRIDGE_FORMAT).",
+	)
+
+	bridgeInterval = flag.Duration(
+		"bridge.interval", 1*time.Minute,
+		"Interval between flushes of the metrics to the bridge address ($BOSH_EXPORTER_BRIDGE_INTERVAL).",
+	)
+
+	once = flag.Bool(
+		"once", false,
+		"Perform a single collection, push the metrics to the Pushgateway and/or write them to the textfile and exit ($BOSH_EXPORTER_ONCE).",
+	)
+
+	pushGatewayURL = flag.String(
+		"push.gateway-url", "",
+		"Pushgateway URL where the metrics will be pushed in `once` mode ($BOSH_EXPORTER_PUSH_GATEWAY_URL).",
+	)
+
+	pushJob = flag.String(
+		"push.job", "bosh_exporter",
+		"Job name used as the Pushgateway grouping key ($BOSH_EXPORTER_PUSH_JOB).",
+	)
+
+	dryRun = flag.Bool(
+		"dry-run", false,
+		"Perform a single collection, print the metrics to stdout and exit without starting the HTTP server ($BOSH_EXPORTER_DRY_RUN).",
+	)
+
+	dashboardsOut = flag.String(
+		"out", "dashboards",
+		"Directory where the `dashboards` subcommand writes the Grafana dashboards ($BOSH_EXPORTER_DASHBOARDS_OUT).",
+	)
+
+	textfilePath = flag.String(
+		"textfile.path", "",
+		"Path of a file (i.e. in the node_exporter textfile collector directory) where the metrics will be written periodically instead of being served over HTTP ($BOSH_EXPORTER_TEXTFILE_PATH).",
+	)
+
+	textfileInterval = flag.Duration(
+		"textfile.interval", 1*time.Minute,
+		"Interval between writes of the metrics to the textfile ($BOSH_EXPORTER_TEXTFILE_INTERVAL).",
 	)
 
 	showVersion = flag.Bool(
@@ -107,6 +559,16 @@ var (
 		"Path under which to expose Prometheus metrics ($BOSH_EXPORTER_WEB_TELEMETRY_PATH).",
 	)
 
+	maxConcurrentScrapes = flag.Int(
+		"web.max-concurrent-scrapes", 0,
+		"Maximum number of concurrent requests to the metrics path, the requests beyond it being rejected with a 503 status code. If 0, requests are not limited ($BOSH_EXPORTER_WEB_MAX_CONCURRENT_SCRAPES).",
+	)
+
+	scrapeTimeoutOffset = flag.Duration(
+		"web.scrape-timeout-offset", 500*time.Millisecond,
+		"Duration subtracted from the scrape timeout sent by Prometheus to bound the metrics collection, left to encode and send the metrics ($BOSH_EXPORTER_WEB_SCRAPE_TIMEOUT_OFFSET).",
+	)
+
 	authUsername = flag.String(
 		"web.auth.username", "",
 		"Username for web interface basic auth ($BOSH_EXPORTER_WEB_AUTH_USERNAME).",
@@ -138,17 +600,104 @@ func overrideFlagsWithEnvVars() {
 	overrideWithEnvVar("BOSH_EXPORTER_BOSH_PASSWORD", boshPassword)
 	overrideWithEnvVar("BOSH_EXPORTER_BOSH_UAA_CLIENT_ID", boshUAAClientID)
 	overrideWithEnvVar("BOSH_EXPORTER_BOSH_UAA_CLIENT_SECRET", boshUAAClientSecret)
+	overrideWithEnvVar("BOSH_EXPORTER_BOSH_PASSWORD_FILE", boshPasswordFile)
+	overrideWithEnvVar("BOSH_EXPORTER_BOSH_UAA_CLIENT_SECRET_FILE", boshUAAClientSecretFile)
+	overrideWithEnvVar("BOSH_EXPORTER_BOSH_UAA_TEAM_CLIENTS_FILE", boshUAATeamClientsFile)
+	overrideWithEnvVar("BOSH_EXPORTER_BOSH_BEARER_TOKEN_FILE", boshBearerTokenFile)
 	overrideWithEnvVar("BOSH_EXPORTER_BOSH_LOG_LEVEL", boshLogLevel)
 	overrideWithEnvVar("BOSH_EXPORTER_BOSH_CA_CERT_FILE", boshCACertFile)
+	overrideWithEnvInt("BOSH_EXPORTER_BOSH_HTTP_MAX_IDLE_CONNS", boshHTTPMaxIdleConns)
+	overrideWithEnvInt("BOSH_EXPORTER_BOSH_HTTP_MAX_IDLE_CONNS_PER_HOST", boshHTTPMaxIdleConnsPerHost)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_HTTP_IDLE_CONN_TIMEOUT", boshHTTPIdleConnTimeout)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_HTTP_KEEP_ALIVE", boshHTTPKeepAlive)
+	overrideWithEnvBool("BOSH_EXPORTER_BOSH_HTTP_DISABLE_KEEP_ALIVES", boshHTTPDisableKeepAlives)
+	overrideWithEnvBool("BOSH_EXPORTER_BOSH_HTTP_CONDITIONAL_REQUESTS", boshHTTPConditionalRequests)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_DEPLOYMENTS_REFRESH_INTERVAL", boshDeploymentsRefreshInterval)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_INSTANCES_REFRESH_INTERVAL", boshInstancesRefreshInterval)
+	overrideWithEnvVar("BOSH_EXPORTER_BOSH_INSTANCES_ENDPOINT", boshInstancesEndpoint)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_INSTANCES_TIMEOUT", boshInstancesTimeout)
+	overrideWithEnvInt("BOSH_EXPORTER_BOSH_RECENT_TASKS_LIMIT", boshRecentTasksLimit)
+	overrideWithEnvBool("BOSH_EXPORTER_BOSH_HM_EVENTS", boshHMEvents)
+	overrideWithEnvDuration("BOSH_EXPORTER_BOSH_HM_EVENTS_FULL_REFRESH_INTERVAL", boshHMEventsFullRefreshInterval)
 	overrideWithEnvVar("BOSH_EXPORTER_FILTER_DEPLOYMENTS", filterDeployments)
+	overrideWithEnvVar("BOSH_EXPORTER_FILTER_TEAMS", filterTeams)
+	overrideWithEnvVar("BOSH_EXPORTER_FILTER_JOBS", filterJobs)
 	overrideWithEnvVar("BOSH_EXPORTER_FILTER_AZS", filterAZs)
 	overrideWithEnvVar("BOSH_EXPORTER_FILTER_COLLECTORS", filterCollectors)
+	overrideWithEnvInt("BOSH_EXPORTER_SHARD_INDEX", shardIndex)
+	overrideWithEnvInt("BOSH_EXPORTER_SHARD_COUNT", shardCount)
 	overrideWithEnvVar("BOSH_EXPORTER_METRICS_NAMESPACE", metricsNamespace)
 	overrideWithEnvVar("BOSH_EXPORTER_METRICS_ENVIRONMENT", metricsEnvironment)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_ENVIRONMENT_SOURCE", metricsEnvironmentSource)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_ENVIRONMENT_NAMES_FILE", metricsEnvironmentNamesFile)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_SUBSYSTEMS", metricsSubsystems)
+	overrideWithEnvBool("BOSH_EXPORTER_METRICS_LEGACY_NAMES", metricsLegacyNames)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_CONST_LABELS", metricsConstLabels)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_DEPLOYMENT_LABELS_FILE", metricsDeploymentLabelsFile)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_SERVICE_LABELS_FILE", metricsServiceLabelsFile)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_STEMCELLS_LIFECYCLE_FILE", metricsStemcellsLifecycleFile)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_BACKUPS_DIRECTORY", metricsBackupsDirectory)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_EXEC_COMMANDS", metricsExecCommands)
+	overrideWithEnvDuration("BOSH_EXPORTER_METRICS_EXEC_TIMEOUT", metricsExecTimeout)
+	overrideWithEnvBool("BOSH_EXPORTER_METRICS_TIMESTAMPS", metricsTimestamps)
+	overrideWithEnvVar("BOSH_EXPORTER_METRICS_LABELS_ALLOWLIST", metricsLabelsAllowlist)
+	overrideWithEnvInt("BOSH_EXPORTER_METRICS_MAX_SERIES", metricsMaxSeries)
+	overrideWithEnvInt("BOSH_EXPORTER_METRICS_LABEL_VALUES_MAX_LENGTH", metricsLabelValuesMaxLength)
+	overrideWithEnvInt("BOSH_EXPORTER_METRICS_MAX_SCRAPE_SERIES", metricsMaxScrapeSeries)
 	overrideWithEnvVar("BOSH_EXPORTER_SD_FILENAME", sdFilename)
 	overrideWithEnvVar("BOSH_EXPORTER_SD_PROCESSES_REGEXP", sdProcessesRegexp)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_DEPLOYMENTS_PROCESSES_FILE", sdDeploymentsProcessesFile)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_CIDRS", sdCIDRs)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_NETWORKS", sdNetworks)
+	overrideWithEnvBool("BOSH_EXPORTER_SD_ALL_IPS", sdAllIPs)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_PROCESSES_PORTS_FILE", sdProcessesPortsFile)
+	overrideWithEnvBool("BOSH_EXPORTER_SD_DNS_NAMES", sdDNSNames)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_RELABEL_CONFIGS_FILE", sdRelabelConfigsFile)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_FORMAT", sdFormat)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_TEMPLATE_FILE", sdTemplateFile)
+	overrideWithEnvDuration("BOSH_EXPORTER_SD_REFRESH_INTERVAL", sdRefreshInterval)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_URL", sdLeaderElectionConsulURL)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_TOKEN", sdLeaderElectionConsulToken)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_LEADER_ELECTION_CONSUL_KEY", sdLeaderElectionConsulKey)
+	overrideWithEnvDuration("BOSH_EXPORTER_SD_LEADER_ELECTION_TTL", sdLeaderElectionTTL)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_CONSUL_URL", sdConsulURL)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_CONSUL_TOKEN", sdConsulToken)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_ETCD_URL", sdEtcdURL)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_ETCD_PREFIX", sdEtcdPrefix)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_ETCD_USERNAME", sdEtcdUsername)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_ETCD_PASSWORD", sdEtcdPassword)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_BUCKET", sdS3Bucket)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_KEY", sdS3Key)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_ENDPOINT", sdS3Endpoint)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_REGION", sdS3Region)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_ACCESS_KEY_ID", sdS3AccessKeyID)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_S3_SECRET_ACCESS_KEY", sdS3SecretAccessKey)
+	overrideWithEnvVar("BOSH_EXPORTER_SD_WEBHOOK_URLS", sdWebhookURLs)
+	overrideWithEnvVar("BOSH_EXPORTER_ALERTMANAGER_URL", alertmanagerURL)
+	overrideWithEnvDuration("BOSH_EXPORTER_ALERTMANAGER_SILENCE_DURATION", alertmanagerSilenceDuration)
+	overrideWithEnvVar("BOSH_EXPORTER_ALERTMANAGER_SILENCE_MATCHERS", alertmanagerSilenceMatchers)
+	overrideWithEnvVar("BOSH_EXPORTER_AUDIT_LOG_FILE", auditLogFile)
+	overrideWithEnvVar("BOSH_EXPORTER_REMOTE_WRITE_URL", remoteWriteURL)
+	overrideWithEnvDuration("BOSH_EXPORTER_REMOTE_WRITE_INTERVAL", remoteWriteInterval)
+	overrideWithEnvVar("BOSH_EXPORTER_REMOTE_WRITE_USERNAME", remoteWriteUsername)
+	overrideWithEnvVar("BOSH_EXPORTER_REMOTE_WRITE_PASSWORD", remoteWritePassword)
+	overrideWithEnvVar("BOSH_EXPORTER_REMOTE_WRITE_BEARER_TOKEN", remoteWriteBearerToken)
+	overrideWithEnvVar("BOSH_EXPORTER_REMOTE_WRITE_WAL_DIRECTORY", remoteWriteWALDirectory)
+	overrideWithEnvInt("BOSH_EXPORTER_REMOTE_WRITE_WAL_MAX_SEGMENTS", remoteWriteWALMaxSegments)
+	overrideWithEnvVar("BOSH_EXPORTER_BRIDGE_ADDRESS", bridgeAddress)
+	overrideWithEnvVar("BOSH_EXPORTER_BRIDGE_FORMAT", bridgeFormat)
+	overrideWithEnvDuration("BOSH_EXPORTER_BRIDGE_INTERVAL", bridgeInterval)
+	overrideWithEnvBool("BOSH_EXPORTER_ONCE", once)
+	overrideWithEnvVar("BOSH_EXPORTER_PUSH_GATEWAY_URL", pushGatewayURL)
+	overrideWithEnvVar("BOSH_EXPORTER_PUSH_JOB", pushJob)
+	overrideWithEnvBool("BOSH_EXPORTER_DRY_RUN", dryRun)
+	overrideWithEnvVar("BOSH_EXPORTER_DASHBOARDS_OUT", dashboardsOut)
+	overrideWithEnvVar("BOSH_EXPORTER_TEXTFILE_PATH", textfilePath)
+	overrideWithEnvDuration("BOSH_EXPORTER_TEXTFILE_INTERVAL", textfileInterval)
 	overrideWithEnvVar("BOSH_EXPORTER_WEB_LISTEN_ADDRESS", listenAddress)
 	overrideWithEnvVar("BOSH_EXPORTER_WEB_TELEMETRY_PATH", metricsPath)
+	overrideWithEnvInt("BOSH_EXPORTER_WEB_MAX_CONCURRENT_SCRAPES", maxConcurrentScrapes)
+	overrideWithEnvDuration("BOSH_EXPORTER_WEB_SCRAPE_TIMEOUT_OFFSET", scrapeTimeoutOffset)
 	overrideWithEnvVar("BOSH_EXPORTER_WEB_AUTH_USERNAME", authUsername)
 	overrideWithEnvVar("BOSH_EXPORTER_WEB_AUTH_PASSWORD", authPassword)
 	overrideWithEnvVar("BOSH_EXPORTER_WEB_TLS_CERTFILE", tlsCertFile)
@@ -162,6 +711,39 @@ func overrideWithEnvVar(name string, value *string) {
 	}
 }
 
+func overrideWithEnvInt(name string, value *int) {
+	envValue := os.Getenv(name)
+	if envValue != "" {
+		intValue, err := strconv.Atoi(envValue)
+		if err != nil {
+			log.Fatalf("Invalid `%s` environment variable: %v", name, err)
+		}
+		*value = intValue
+	}
+}
+
+func overrideWithEnvBool(name string, value *bool) {
+	envValue := os.Getenv(name)
+	if envValue != "" {
+		boolValue, err := strconv.ParseBool(envValue)
+		if err != nil {
+			log.Fatalf("Invalid `%s` environment variable: %v", name, err)
+		}
+		*value = boolValue
+	}
+}
+
+func overrideWithEnvDuration(name string, value *time.Duration) {
+	envValue := os.Getenv(name)
+	if envValue != "" {
+		durationValue, err := time.ParseDuration(envValue)
+		if err != nil {
+			log.Fatalf("Invalid `%s` environment variable: %v", name, err)
+		}
+		*value = durationValue
+	}
+}
+
 type basicAuthHandler struct {
 	handler  http.HandlerFunc
 	username string
@@ -194,6 +776,76 @@ func prometheusHandler() http.Handler {
 	return handler
 }
 
+func apiHandler(handler http.Handler) http.Handler {
+	if *authUsername != "" && *authPassword != "" {
+		handler = &basicAuthHandler{
+			handler:  handler.ServeHTTP,
+			username: *authUsername,
+			password: *authPassword,
+		}
+	}
+
+	return handler
+}
+
+// loadTLSCertificate returns the leaf certificate served by the exporter.
+func loadTLSCertificate(certFile string, keyFile string) (*x509.Certificate, error) {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error loading TLS certificate: %v", err))
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing TLS certificate: %v", err))
+	}
+
+	return leaf, nil
+}
+
+func parseConstLabels(constLabels string) (prometheus.Labels, error) {
+	labels := prometheus.Labels{}
+	if constLabels == "" {
+		return labels, nil
+	}
+
+	for _, constLabel := range strings.Split(constLabels, ",") {
+		nameValue := strings.SplitN(strings.Trim(constLabel, " "), "=", 2)
+		if len(nameValue) != 2 {
+			return labels, errors.New(fmt.Sprintf("Const label `%s` is not a key=value pair", constLabel))
+		}
+
+		name := nameValue[0]
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, "__") || strings.HasPrefix(name, "bosh_") || name == "environment" {
+			return labels, errors.New(fmt.Sprintf("Const label name `%s` is not valid", name))
+		}
+		labels[name] = nameValue[1]
+	}
+
+	return labels, nil
+}
+
+func parseSilenceMatchers(silenceMatchers string) (map[string]string, error) {
+	matchers := map[string]string{}
+	if silenceMatchers == "" {
+		return matchers, nil
+	}
+
+	for _, silenceMatcher := range strings.Split(silenceMatchers, ",") {
+		nameValue := strings.SplitN(strings.Trim(silenceMatcher, " "), "=", 2)
+		if len(nameValue) != 2 {
+			return matchers, errors.New(fmt.Sprintf("Silence matcher `%s` is not a name=value pair", silenceMatcher))
+		}
+
+		if !model.LabelName(nameValue[0]).IsValid() {
+			return matchers, errors.New(fmt.Sprintf("Silence matcher name `%s` is not valid", nameValue[0]))
+		}
+		matchers[nameValue[0]] = nameValue[1]
+	}
+
+	return matchers, nil
+}
+
 func readCACert(CACertFile string, logger logger.Logger) (string, error) {
 	if CACertFile != "" {
 		fs := system.NewOsFileSystem(logger)
@@ -214,134 +866,379 @@ func readCACert(CACertFile string, logger logger.Logger) (string, error) {
 	return "", nil
 }
 
-func buildBOSHClient() (director.Director, error) {
+func buildBOSHClient() (director.Director, auth.TokenSessions, error) {
 	logLevel, err := logger.Levelify(*boshLogLevel)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	logger := logger.NewLogger(logLevel)
 
 	directorConfig, err := director.NewConfigFromURL(*boshURL)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	boshCACert, err := readCACert(*boshCACertFile, logger)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	directorConfig.CACert = boshCACert
 
-	anonymousDirector, err := director.NewFactory(logger).New(directorConfig, nil, nil)
+	if *boshBearerTokenFile != "" {
+		directorConfig.TokenFunc = auth.NewFileTokenFunc(*boshBearerTokenFile)
+		boshClient, err := newDirector(directorConfig, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		return boshClient, nil, nil
+	}
+
+	anonymousDirector, err := newDirector(directorConfig, logger)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	boshInfo, err := anonymousDirector.Info()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	teamClients, err := auth.LoadTeamClients(*boshUAATeamClientsFile)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	var tokenSessions auth.TokenSessions
 	if boshInfo.Auth.Type != "uaa" {
-		directorConfig.Client = *boshUsername
-		directorConfig.ClientSecret = *boshPassword
+		if len(teamClients) > 0 {
+			return nil, nil, errors.New("Flag `bosh.uaa.team-clients-file` requires a BOSH Director using UAA authentication")
+		}
+		if *boshPasswordFile != "" {
+			username := *boshUsername
+			directorConfig.TokenFunc = auth.NewSecretFileTokenFunc(*boshPasswordFile, func(password string) (auth.TokenFunc, error) {
+				return auth.NewBasicAuthTokenFunc(username, password), nil
+			})
+		} else {
+			directorConfig.Client = *boshUsername
+			directorConfig.ClientSecret = *boshPassword
+		}
 	} else {
 		uaaURL := boshInfo.Auth.Options["url"]
 		uaaURLStr, ok := uaaURL.(string)
 		if !ok {
-			return nil, errors.New(fmt.Sprintf("Expected UAA URL '%s' to be a string", uaaURL))
+			return nil, nil, errors.New(fmt.Sprintf("Expected UAA URL '%s' to be a string", uaaURL))
 		}
 
 		uaaConfig, err := uaa.NewConfigFromURL(uaaURLStr)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		uaaConfig.CACert = boshCACert
+		uaaFactory := uaa.NewFactory(logger)
 
-		if *boshUAAClientID != "" && *boshUAAClientSecret != "" {
-			uaaConfig.Client = *boshUAAClientID
-			uaaConfig.ClientSecret = *boshUAAClientSecret
-		} else {
-			uaaConfig.Client = "bosh_cli"
+		uaaClients := []auth.TeamClient{}
+		if *boshUAAClientID != "" && *boshUAAClientSecret != "" && *boshUAAClientSecretFile == "" {
+			uaaClients = append(uaaClients, auth.TeamClient{ClientID: *boshUAAClientID, ClientSecret: *boshUAAClientSecret})
 		}
-
-		uaaFactory := uaa.NewFactory(logger)
-		uaaClient, err := uaaFactory.New(uaaConfig)
-		if err != nil {
-			return nil, err
+		uaaClients = append(uaaClients, teamClients...)
+
+		tokenFuncs := []auth.TokenFunc{}
+		if *boshUAAClientID != "" && *boshUAAClientSecretFile != "" {
+			clientID := *boshUAAClientID
+			tokenFuncs = append(tokenFuncs, auth.NewSecretFileTokenFunc(*boshUAAClientSecretFile, func(clientSecret string) (auth.TokenFunc, error) {
+				return newUAAClientTokenFunc(uaaFactory, uaaConfig, auth.TeamClient{ClientID: clientID, ClientSecret: clientSecret})
+			}))
 		}
-
-		if *boshUAAClientID != "" && *boshUAAClientSecret != "" {
-			directorConfig.TokenFunc = uaa.NewClientTokenSession(uaaClient).TokenFunc
-		} else {
-			answers := []uaa.PromptAnswer{
-				uaa.PromptAnswer{
-					Key:   "username",
-					Value: *boshUsername,
-				},
-				uaa.PromptAnswer{
-					Key:   "password",
-					Value: *boshPassword,
-				},
+		for _, uaaClient := range uaaClients {
+			tokenFunc, err := newUAAClientTokenFunc(uaaFactory, uaaConfig, uaaClient)
+			if err != nil {
+				return nil, nil, err
+			}
+			tokenFuncs = append(tokenFuncs, tokenFunc)
+		}
+
+		if len(tokenFuncs) > 0 {
+			directors := []director.Director{}
+			for _, tokenFunc := range tokenFuncs {
+				boshClient, tokenSession, err := newUAAClientDirector(directorConfig, tokenFunc, logger)
+				if err != nil {
+					return nil, nil, err
+				}
+				directors = append(directors, boshClient)
+				tokenSessions = append(tokenSessions, tokenSession)
 			}
-			accessToken, err := uaaClient.OwnerPasswordCredentialsGrant(answers)
+
+			if len(directors) == 1 {
+				return directors[0], tokenSessions, nil
+			}
+			return deployments.NewMergedDirector(directors), tokenSessions, nil
+		}
+
+		uaaConfig.Client = "bosh_cli"
+		uaaClient, err := uaaFactory.New(uaaConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The password is only used to get the first refresh token.
+		password := *boshPassword
+		if *boshPasswordFile != "" {
+			password, err = auth.ReadSecretFile(*boshPasswordFile)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
+		}
 
-			origToken := uaaClient.NewStaleAccessToken(accessToken.RefreshToken().Value())
-			directorConfig.TokenFunc = uaa.NewAccessTokenSession(origToken).TokenFunc
+		answers := []uaa.PromptAnswer{
+			uaa.PromptAnswer{
+				Key:   "username",
+				Value: *boshUsername,
+			},
+			uaa.PromptAnswer{
+				Key:   "password",
+				Value: password,
+			},
 		}
+		accessToken, err := uaaClient.OwnerPasswordCredentialsGrant(answers)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		origToken := uaaClient.NewStaleAccessToken(accessToken.RefreshToken().Value())
+		tokenSession := auth.NewTokenSession(uaa.NewAccessTokenSession(origToken).TokenFunc)
+		tokenSessions = append(tokenSessions, tokenSession)
+		directorConfig.TokenFunc = tokenSession.TokenFunc
+	}
+
+	boshClient, err := newDirector(directorConfig, logger)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	boshFactory := director.NewFactory(logger)
-	boshClient, err := boshFactory.New(directorConfig, director.NewNoopTaskReporter(), director.NewNoopFileReporter())
+	return boshClient, tokenSessions, nil
+}
+
+func newUAAClientTokenFunc(
+	uaaFactory uaa.Factory,
+	uaaConfig uaa.Config,
+	uaaClient auth.TeamClient,
+) (auth.TokenFunc, error) {
+	uaaConfig.Client = uaaClient.ClientID
+	uaaConfig.ClientSecret = uaaClient.ClientSecret
+
+	client, err := uaaFactory.New(uaaConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	return boshClient, nil
+	return uaa.NewClientTokenSession(client).TokenFunc, nil
+}
+
+func newUAAClientDirector(
+	directorConfig director.Config,
+	tokenFunc auth.TokenFunc,
+	logger logger.Logger,
+) (director.Director, *auth.TokenSession, error) {
+	tokenSession := auth.NewTokenSession(tokenFunc)
+	directorConfig.TokenFunc = tokenSession.TokenFunc
+
+	boshClient, err := newDirector(directorConfig, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return boshClient, tokenSession, nil
+}
+
+func newDirectorHTTPClient(directorConfig director.Config) (*http.Client, error) {
+	certPool, err := directorConfig.CACertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: *boshHTTPKeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		Dial:                httpclient.SOCKS5DialFuncFromEnvironment(dialer.Dial),
+		TLSClientConfig:     &tls.Config{RootCAs: certPool},
+		TLSHandshakeTimeout: 30 * time.Second,
+		MaxIdleConns:        *boshHTTPMaxIdleConns,
+		MaxIdleConnsPerHost: *boshHTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     *boshHTTPIdleConnTimeout,
+		DisableKeepAlives:   *boshHTTPDisableKeepAlives,
+	}
+
+	// Redirects are followed by the transport, as the BOSH client redirects
+	// every request to the BOSH Director address.
+	var roundTripper http.RoundTripper = redirect.NewTransport(transport)
+	if *boshHTTPConditionalRequests {
+		roundTripper = httpcache.NewConditionalTransport(roundTripper, httpcache.DirectorPaths)
+	}
+
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+func newDirector(directorConfig director.Config, logger logger.Logger) (director.Director, error) {
+	rawClient, err := newDirectorHTTPClient(directorConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDirectorWithHTTPClient(directorConfig, rawClient, logger)
+}
+
+// runOnce gathers the metrics a single time and pushes them to the
+// Pushgateway and/or writes them to the textfile.
+func runOnce(gatherer prometheus.Gatherer) error {
+	metricFamilies, err := gatherer.Gather()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error gathering metrics: %v", err))
+	}
+
+	if *textfilePath != "" {
+		if err = publishers.NewTextfilePublisher(*textfilePath).Publish(metricFamilies, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if *pushGatewayURL != "" {
+		pushgatewayPublisher := publishers.NewPushgatewayPublisher(
+			*pushGatewayURL,
+			*pushJob,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+		if err = pushgatewayPublisher.Publish(metricFamilies, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runDryRun gathers the metrics a single time and prints them using the
+// Prometheus text exposition format. It fails if the scrape from BOSH failed.
+func runDryRun(out io.Writer, gatherer prometheus.Gatherer, lastScrapeErrorMetricName string) error {
+	metricFamilies, err := gatherer.Gather()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error gathering metrics: %v", err))
+	}
+
+	encoder := expfmt.NewEncoder(out, expfmt.FmtText)
+	scrapeError := false
+	for _, metricFamily := range metricFamilies {
+		if err = encoder.Encode(metricFamily); err != nil {
+			return errors.New(fmt.Sprintf("Error encoding metrics: %v", err))
+		}
+
+		if metricFamily.GetName() == lastScrapeErrorMetricName {
+			for _, metric := range metricFamily.Metric {
+				if metric.GetGauge().GetValue() != 0 {
+					scrapeError = true
+				}
+			}
+		}
+	}
+
+	if scrapeError {
+		return errors.New("Error scraping metrics from BOSH")
+	}
+
+	return nil
+}
+
+type metricsPublisher interface {
+	Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error
+}
+
+func pushMetrics(gatherer prometheus.Gatherer, publisher metricsPublisher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		metricFamilies, err := gatherer.Gather()
+		if err != nil {
+			log.Errorf("Error gathering metrics: %v", err)
+			if len(metricFamilies) == 0 {
+				continue
+			}
+		}
+
+		if err := publisher.Publish(metricFamilies, time.Now()); err != nil {
+			log.Error(err)
+		}
+	}
 }
 
 func main() {
-	flag.Parse()
+	command := ""
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == checkCommand || args[0] == validateCommand || args[0] == dashboardsCommand || args[0] == rulesCommand) {
+		command, args = args[0], args[1:]
+	}
+	flag.CommandLine.Parse(args)
 	overrideFlagsWithEnvVars()
+	overrideCollectorsFlagsWithEnvVars()
 
 	if *showVersion {
 		fmt.Fprintln(os.Stdout, version.Print("bosh_exporter"))
 		os.Exit(0)
 	}
 
-	log.Infoln("Starting bosh_exporter", version.Info())
-	log.Infoln("Build context", version.BuildContext())
+	if *once && *pushGatewayURL == "" && *textfilePath == "" {
+		log.Error("The `once` flag requires the `push.gateway-url` or the `textfile.path` flag")
+		os.Exit(1)
+	}
 
-	boshClient, err := buildBOSHClient()
-	if err != nil {
-		log.Errorf("Error creating BOSH Client: %s", err.Error())
+	if *sdNetworks != "" && !*sdDNSNames {
+		log.Error("The `sd.networks` flag requires the `sd.dns_names` flag")
 		os.Exit(1)
 	}
 
-	boshInfo, err := boshClient.Info()
-	if err != nil {
-		log.Errorf("Error reading BOSH Info: %s", err.Error())
+	if *maxConcurrentScrapes < 0 {
+		log.Error("The `web.max-concurrent-scrapes` flag must not be negative")
 		os.Exit(1)
 	}
-	log.Infof("Using BOSH Director `%s` (%s)", boshInfo.Name, boshInfo.UUID)
 
-	var deploymentsFilters []string
-	if *filterDeployments != "" {
-		deploymentsFilters = strings.Split(*filterDeployments, ",")
+	log.Infoln("Starting bosh_exporter", version.Info())
+	log.Infoln("Build context", version.BuildContext())
+
+	var teamsFilters []string
+	if *filterTeams != "" {
+		teamsFilters = strings.Split(*filterTeams, ",")
+	}
+	teamsFilter := filters.NewTeamsFilter(teamsFilters)
+
+	var jobsFilters []string
+	if *filterJobs != "" {
+		jobsFilters = strings.Split(*filterJobs, ",")
+	}
+	jobsFilter, err := filters.NewAnchoredRegexpFilter(jobsFilters)
+	if err != nil {
+		log.Errorf("Error processing Jobs Regexp: %v", err)
+		os.Exit(1)
 	}
-	deploymentsFilter := filters.NewDeploymentsFilter(deploymentsFilters, boshClient)
-	deploymentsFetcher := deployments.NewFetcher(*deploymentsFilter)
 
 	var azsFilters []string
 	if *filterAZs != "" {
 		azsFilters = strings.Split(*filterAZs, ",")
 	}
-	azsFilter := filters.NewAZsFilter(azsFilters)
+	azsFilter, err := filters.NewAZsFilter(azsFilters)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	shardFilter, err := filters.NewShardFilter(*shardIndex, *shardCount)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 
 	var collectorsFilters []string
 	if *filterCollectors != "" {
@@ -353,39 +1250,504 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = setCollectorsEnabled(collectorsRegistry); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
 	var processesFilters []string
 	if *sdProcessesRegexp != "" {
 		processesFilters = []string{*sdProcessesRegexp}
 	}
-	processesFilter, err := filters.NewRegexpFilter(processesFilters)
+	defaultProcessesFilter, err := filters.NewRegexpFilter(processesFilters)
 	if err != nil {
 		log.Errorf("Error processing Processes Regexp: %v", err)
 		os.Exit(1)
 	}
+	processesFilter, err := filters.LoadProcessesFilter(defaultProcessesFilter, *sdDeploymentsProcessesFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 
-	boshCollector := collectors.NewBoshCollector(
-		*metricsNamespace,
-		*metricsEnvironment,
+	var cidrsFilters []string
+	if *sdCIDRs != "" {
+		cidrsFilters = strings.Split(*sdCIDRs, ",")
+	}
+	cidrsFilter, err := filters.NewCIDRsFilter(cidrsFilters)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	var networksFilters []string
+	if *sdNetworks != "" {
+		networksFilters = strings.Split(*sdNetworks, ",")
+	}
+	networksFilter, err := filters.NewNetworksFilter(networksFilters)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	processesPorts, err := collectors.LoadProcessesPorts(*sdProcessesPortsFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	var subsystems []string
+	if *metricsSubsystems != "" {
+		subsystems = strings.Split(*metricsSubsystems, ",")
+	}
+	collectorsSubsystems, err := collectors.NewCollectorsSubsystems(subsystems)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	constLabels, err := parseConstLabels(*metricsConstLabels)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	environmentDeriver, err := collectors.NewEnvironmentDeriver(*metricsEnvironmentSource, *metricsEnvironmentNamesFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	deploymentLabels, err := collectors.LoadDeploymentLabels(*metricsDeploymentLabelsFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	serviceLabels, err := collectors.LoadServiceLabels(*metricsServiceLabelsFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	for _, labelName := range serviceLabels.LabelNames() {
+		for _, deploymentLabelName := range deploymentLabels.LabelNames() {
+			if labelName == deploymentLabelName {
+				log.Errorf("Label `%s` is set by both the deployment labels and the service labels files", labelName)
+				os.Exit(1)
+			}
+		}
+	}
+
+	stemcellsLifecycle, err := collectors.LoadStemcellsLifecycle(*metricsStemcellsLifecycleFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	var tlsCertificate *x509.Certificate
+	if *tlsCertFile != "" && *tlsKeyFile != "" {
+		tlsCertificate, err = loadTLSCertificate(*tlsCertFile, *tlsKeyFile)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	sdRelabelConfigs, err := collectors.LoadRelabelConfigs(*sdRelabelConfigsFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	if *boshRecentTasksLimit < 1 {
+		log.Errorf("BOSH recent tasks limit `%d` must be positive", *boshRecentTasksLimit)
+		os.Exit(1)
+	}
+
+	if *sdFormat != "" && *sdFormat != collectors.JSONFormat && *sdFormat != collectors.YAMLFormat && *sdFormat != collectors.ScrapeConfigFormat {
+		log.Errorf("Service Discovery format `%s` is not supported", *sdFormat)
+		os.Exit(1)
+	}
+
+	sdTemplate, err := collectors.LoadServiceDiscoveryTemplate(*sdTemplateFile)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	if *bridgeFormat != publishers.GraphiteLineFormat && *bridgeFormat != publishers.InfluxLineFormat {
+		log.Errorf("Bridge format `%s` is not supported", *bridgeFormat)
+		os.Exit(1)
+	}
+
+	if command == validateCommand {
+		fmt.Fprintln(os.Stdout, "Configuration is valid")
+		return
+	}
+
+	var execCommands []string
+	if *metricsExecCommands != "" {
+		execCommands = strings.Split(*metricsExecCommands, ",")
+	}
+
+	if command == dashboardsCommand || command == rulesCommand {
+		// Describing the metrics does not need the BOSH Director.
+		boshCollector := collectors.NewBoshCollector(
+			nil,
+			nil,
+			collectors.BoshCollectorOptions{
+				Namespace:            *metricsNamespace,
+				ConstLabels:          constLabels,
+				CollectorsSubsystems: collectorsSubsystems,
+				CollectorsRegistry:   collectorsRegistry,
+				CollectorsFilter:     collectorsFilter,
+				BackupsDirectory:     *metricsBackupsDirectory,
+				ExecCommands:         execCommands,
+			},
+		)
+		if command == dashboardsCommand {
+			err = runDashboards(os.Stdout, *dashboardsOut, *metricsNamespace, collectorsSubsystems, boshCollector)
+		} else {
+			err = runRules(os.Stdout, *metricsNamespace, collectorsSubsystems, constLabels, boshCollector)
+		}
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	boshClient, tokenSessions, err := buildBOSHClient()
+	if err != nil {
+		log.Errorf("Error creating BOSH Client: %s", err.Error())
+		os.Exit(1)
+	}
+
+	boshInfo, err := boshClient.Info()
+	if err != nil {
+		log.Errorf("Error reading BOSH Info: %s", err.Error())
+		os.Exit(1)
+	}
+	log.Infof("Using BOSH Director `%s` (%s)", boshInfo.Name, boshInfo.UUID)
+
+	environment := environmentDeriver.Environment(*metricsEnvironment, boshInfo)
+	if *metricsEnvironmentSource != "" {
+		log.Infof("Using environment `%s` derived from the BOSH Director `%s`", environment, *metricsEnvironmentSource)
+	}
+
+	var uaaTokenStatus collectors.UAATokenStatus
+	if len(tokenSessions) > 0 {
+		uaaTokenStatus = tokenSessions
+	}
+
+	directorCompatibility := deployments.NewDirectorCompatibility(boshInfo)
+	if directorCompatibility.Unsupported {
+		log.Warnf("BOSH Director version `%s` is not supported, the oldest supported version is `%d`", boshInfo.Version, deployments.MinSupportedDirectorVersion)
+	}
+
+	var deploymentsFilters []string
+	if *filterDeployments != "" {
+		deploymentsFilters = strings.Split(*filterDeployments, ",")
+	}
+	deploymentsFilter, err := filters.NewDeploymentsFilter(deploymentsFilters, boshClient)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+
+	deploymentsFetcher := deployments.NewFetcher(boshClient, *deploymentsFilter, teamsFilter, jobsFilter, azsFilter, shardFilter)
+	deploymentsFetcher.SetRefreshIntervals(*boshDeploymentsRefreshInterval, *boshInstancesRefreshInterval)
+	instancesBackend, err := deployments.NewInstancesBackend(*boshInstancesEndpoint)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	deploymentsFetcher.SetInstancesBackend(instancesBackend)
+	deploymentsFetcher.SetInstancesTimeout(*boshInstancesTimeout)
+	deploymentsFetcher.SetRecentTasksLimit(*boshRecentTasksLimit)
+	deploymentsFetcher.SetDirectorCompatibility(directorCompatibility)
+
+	var boshDeploymentsFetcher collectors.DeploymentsFetcher = deploymentsFetcher
+	var cachedFetcher *deployments.CachedFetcher
+	if *boshHMEvents {
+		cachedFetcher = deployments.NewCachedFetcher(deploymentsFetcher, *boshHMEventsFullRefreshInterval)
+		boshDeploymentsFetcher = cachedFetcher
+	}
+
+	sdPublishers := []collectors.ServiceDiscoveryPublisher{}
+	if *sdConsulURL != "" {
+		consulPublisher := publishers.NewConsulPublisher(
+			*sdConsulURL,
+			*sdConsulToken,
+			boshInfo.UUID,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+		sdPublishers = append(sdPublishers, consulPublisher)
+	}
+
+	if *sdEtcdURL != "" {
+		etcdPublisher := publishers.NewEtcdPublisher(
+			*sdEtcdURL,
+			*sdEtcdPrefix,
+			*sdEtcdUsername,
+			*sdEtcdPassword,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+		sdPublishers = append(sdPublishers, etcdPublisher)
+	}
+
+	if *sdS3Bucket != "" {
+		s3Publisher := publishers.NewS3Publisher(
+			*sdS3Endpoint,
+			*sdS3Region,
+			*sdS3Bucket,
+			*sdS3Key,
+			*sdS3AccessKeyID,
+			*sdS3SecretAccessKey,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+		sdPublishers = append(sdPublishers, s3Publisher)
+	}
+
+	if *sdWebhookURLs != "" {
+		for _, sdWebhookURL := range strings.Split(*sdWebhookURLs, ",") {
+			sdPublishers = append(sdPublishers, publishers.NewWebhookPublisher(strings.TrimSpace(sdWebhookURL), &http.Client{Timeout: 30 * time.Second}))
+		}
+	}
+
+	deploymentsObservers := []collectors.DeploymentsObserver{}
+	if *alertmanagerURL != "" {
+		silenceMatchers, err := parseSilenceMatchers(*alertmanagerSilenceMatchers)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+
+		alertmanagerSilencer := publishers.NewAlertmanagerSilencer(
+			*alertmanagerURL,
+			boshInfo.UUID,
+			silenceMatchers,
+			*alertmanagerSilenceDuration,
+			&http.Client{Timeout: 10 * time.Second},
+		)
+		deploymentsObservers = append(deploymentsObservers, alertmanagerSilencer)
+	}
+
+	if *auditLogFile != "" {
+		deploymentsObservers = append(deploymentsObservers, publishers.NewAuditLog(*auditLogFile))
+	}
+
+	var sdLeaderElector collectors.ServiceDiscoveryLeaderElector
+	if *sdLeaderElectionConsulURL != "" {
+		sdLeaderElectionKey := *sdLeaderElectionConsulKey
+		if sdLeaderElectionKey == "" {
+			sdLeaderElectionKey = "bosh_exporter/" + boshInfo.UUID + "/service-discovery-leader"
+		}
+		sdLeaderElector = publishers.NewConsulLeaderElector(
+			*sdLeaderElectionConsulURL,
+			*sdLeaderElectionConsulToken,
+			sdLeaderElectionKey,
+			*sdLeaderElectionTTL,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+	}
+
+	var labelsAllowlist []string
+	if *metricsLabelsAllowlist != "" {
+		labelsAllowlist = strings.Split(*metricsLabelsAllowlist, ",")
+	}
+	seriesGuard := collectors.NewSeriesGuard(
+		collectorsSubsystems.Namespace(*metricsNamespace, collectors.ExporterMetrics),
+		environment,
 		boshInfo.Name,
 		boshInfo.UUID,
-		*sdFilename,
-		deploymentsFetcher,
-		collectorsFilter,
-		azsFilter,
-		processesFilter,
-	)
-	prometheus.MustRegister(boshCollector)
-
-	http.Handle(*metricsPath, prometheus.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-             <head><title>BOSH Exporter</title></head>
-             <body>
-             <h1>BOSH Exporter</h1>
-             <p><a href='` + *metricsPath + `'>Metrics</a></p>
-             </body>
-             </html>`))
-	})
+		constLabels,
+		labelsAllowlist,
+		*metricsMaxSeries,
+	)
+
+	boshCollector := collectors.NewBoshCollector(
+		boshClient,
+		boshDeploymentsFetcher,
+		collectors.BoshCollectorOptions{
+			Namespace:                       *metricsNamespace,
+			Environment:                     environment,
+			BoshName:                        boshInfo.Name,
+			BoshUUID:                        boshInfo.UUID,
+			ConstLabels:                     constLabels,
+			CollectorsSubsystems:            collectorsSubsystems,
+			LegacyMetricsNames:              *metricsLegacyNames,
+			MetricsTimestamps:               *metricsTimestamps,
+			CollectorsRegistry:              collectorsRegistry,
+			CollectorsFilter:                collectorsFilter,
+			DeploymentLabels:                deploymentLabels,
+			ServiceLabels:                   serviceLabels,
+			StemcellsLifecycle:              stemcellsLifecycle,
+			BackupsDirectory:                *metricsBackupsDirectory,
+			ExecCommands:                    execCommands,
+			ExecTimeout:                     *metricsExecTimeout,
+			SeriesGuard:                     seriesGuard,
+			LabelSanitizer:                  collectors.NewLabelSanitizer(*metricsLabelValuesMaxLength),
+			MaxScrapeSeries:                 *metricsMaxScrapeSeries,
+			DirectorCompatibility:           directorCompatibility,
+			TLSCertificate:                  tlsCertificate,
+			UAATokenStatus:                  uaaTokenStatus,
+			DeploymentsObservers:            deploymentsObservers,
+			ServiceDiscoveryFilename:        *sdFilename,
+			ServiceDiscoveryProcessesFilter: processesFilter,
+			ServiceDiscoveryCIDRsFilter:     cidrsFilter,
+			ServiceDiscoveryNetworksFilter:  networksFilter,
+			ServiceDiscoveryAllIPs:          *sdAllIPs,
+			ServiceDiscoveryProcessesPorts:  processesPorts,
+			ServiceDiscoveryDNSNames:        *sdDNSNames,
+			ServiceDiscoveryRelabelConfigs:  sdRelabelConfigs,
+			ServiceDiscoveryFormat:          *sdFormat,
+			ServiceDiscoveryTemplate:        sdTemplate,
+			ServiceDiscoveryPublishers:      sdPublishers,
+			ServiceDiscoveryLeaderElector:   sdLeaderElector,
+			ServiceDiscoveryRefreshInterval: *sdRefreshInterval,
+		},
+	)
+	deploymentsFetcher.SetFetchObserver(boshCollector.ObserveDeploymentFetch)
+	deploymentsFetcher.SetFetchErrorObserver(boshCollector.ObserveDeploymentFetchError)
+	deploymentsDetails := boshCollector.DeploymentsDetails()
+	// The Alertmanager silencer finds the deployments being deployed from
+	// their tasks.
+	if *alertmanagerURL != "" {
+		deploymentsDetails.Tasks = true
+	}
+	deploymentsFetcher.SetDetails(deploymentsDetails)
+
+	// The BOSH collector has its own registry, so each scrape can be bounded
+	// by its own timeout.
+	boshRegistry := prometheus.NewRegistry()
+	boshRegistry.MustRegister(boshCollector)
+	metricsGatherer := prometheus.Gatherers{prometheus.DefaultGatherer, boshRegistry}
+
+	if command == checkCommand {
+		if err := runCheck(os.Stdout, boshClient, boshInfo, deploymentsFetcher, boshCollector); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *dryRun {
+		lastScrapeErrorMetricName := prometheus.BuildFQName(collectorsSubsystems.Namespace(*metricsNamespace, collectors.ExporterMetrics), "", "last_scrape_error")
+		if err := runDryRun(os.Stdout, metricsGatherer, lastScrapeErrorMetricName); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *once {
+		if err := runOnce(metricsGatherer); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	go boshCollector.RefreshServiceDiscovery(make(chan struct{}))
+
+	if *remoteWriteURL != "" {
+		remoteWritePublisher := publishers.NewRemoteWritePublisher(
+			*remoteWriteURL,
+			*remoteWriteUsername,
+			*remoteWritePassword,
+			*remoteWriteBearerToken,
+			&http.Client{Timeout: 30 * time.Second},
+		)
+		if *remoteWriteWALDirectory == "" {
+			go pushMetrics(metricsGatherer, remoteWritePublisher, *remoteWriteInterval)
+		} else {
+			remoteWriteWAL, err := publishers.NewRemoteWriteWAL(remoteWritePublisher, *remoteWriteWALDirectory, *remoteWriteWALMaxSegments)
+			if err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+			go pushMetrics(metricsGatherer, remoteWriteWAL, *remoteWriteInterval)
+		}
+	}
+
+	if *bridgeAddress != "" {
+		linePublisher, err := publishers.NewLinePublisher(*bridgeAddress, *bridgeFormat, 30*time.Second)
+		if err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+		go pushMetrics(metricsGatherer, linePublisher, *bridgeInterval)
+	}
+
+	if *textfilePath != "" {
+		log.Infoln("Writing metrics to", *textfilePath)
+		pushMetrics(metricsGatherer, publishers.NewTextfilePublisher(*textfilePath), *textfileInterval)
+		return
+	}
+
+	var metricsHandler http.Handler = prometheus.InstrumentHandler("prometheus", api.NewGzipHandler(api.NewMetricsHandler(
+		func(timeout time.Duration) prometheus.Gatherer {
+			if timeout == 0 {
+				return metricsGatherer
+			}
+			scrapeRegistry := prometheus.NewRegistry()
+			scrapeRegistry.MustRegister(boshCollector.WithScrapeTimeout(timeout))
+			return prometheus.Gatherers{prometheus.DefaultGatherer, scrapeRegistry}
+		},
+		*scrapeTimeoutOffset,
+	)))
+	if *maxConcurrentScrapes > 0 {
+		metricsHandler = api.NewConcurrencyLimitHandler(metricsHandler, *maxConcurrentScrapes, scrapeRetryAfter)
+	}
+	http.Handle(*metricsPath, metricsHandler)
+
+	// The exporter is not ready while the mounted credentials cannot be read,
+	// i.e. when the Kubernetes Secret has been deleted.
+	readinessChecks := []func() error{}
+	for _, filename := range []string{*boshPasswordFile, *boshUAAClientSecretFile, *boshBearerTokenFile} {
+		if filename == "" {
+			continue
+		}
+		secretFilename := filename
+		readinessChecks = append(readinessChecks, func() error {
+			_, err := auth.ReadSecretFile(secretFilename)
+			return err
+		})
+	}
+	http.Handle(healthyPath, api.NewHealthHandler())
+	http.Handle(readyPath, api.NewHealthHandler(readinessChecks...))
+	http.Handle(snapshotPath, apiHandler(api.NewGzipHandler(api.NewSnapshotHandler(environment, boshInfo.Name, boshInfo.UUID, boshDeploymentsFetcher))))
+	if cachedFetcher != nil {
+		http.Handle(hmEventsPath, apiHandler(api.NewHMEventsHandler(cachedFetcher)))
+	}
+	links := []api.Link{
+		{Name: "Metrics", Path: *metricsPath},
+		{Name: "Snapshot", Path: snapshotPath},
+	}
+	if *sdFilename != "" && !strings.Contains(*sdFilename, "{{") {
+		http.Handle(sdPath, apiHandler(api.NewGzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFile(w, r, *sdFilename)
+		}))))
+		links = append(links, api.Link{Name: "Service Discovery", Path: sdPath})
+	}
+	http.Handle("/", api.NewGzipHandler(api.NewLandingPageHandler(
+		boshCollector,
+		[]api.Setting{
+			{Name: "filter.deployments", Value: *filterDeployments},
+			{Name: "filter.teams", Value: *filterTeams},
+			{Name: "filter.jobs", Value: *filterJobs},
+			{Name: "filter.azs", Value: *filterAZs},
+			{Name: "filter.collectors", Value: *filterCollectors},
+			{Name: "shard", Value: fmt.Sprintf("%d of %d", *shardIndex, *shardCount)},
+			{Name: "sd.processes_regexp", Value: *sdProcessesRegexp},
+			{Name: "sd.deployments_processes_file", Value: *sdDeploymentsProcessesFile},
+			{Name: "sd.cidrs", Value: *sdCIDRs},
+			{Name: "sd.networks", Value: *sdNetworks},
+		},
+		links,
+	)))
 
 	if *tlsCertFile != "" && *tlsKeyFile != "" {
 		log.Infoln("Listening TLS on", *listenAddress)
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const checkCommand = "check"
+
+// runCheck connects to the BOSH Director, lists the visible deployments and
+// prints what the exporter would collect with the current flags.
+func runCheck(
+	out io.Writer,
+	boshClient director.Director,
+	boshInfo director.Info,
+	deploymentsFetcher *deployments.Fetcher,
+	boshCollector *collectors.BoshCollector,
+) error {
+	fmt.Fprintf(out, "BOSH Director: %s (%s), version %s\n", boshInfo.Name, boshInfo.UUID, boshInfo.Version)
+
+	authenticated, err := boshClient.IsAuthenticated()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error authenticating to the BOSH Director: %v", err))
+	}
+	if !authenticated {
+		return errors.New("Not authenticated to the BOSH Director, check the BOSH credentials flags")
+	}
+	fmt.Fprintf(out, "Authentication: %s\n", boshInfo.Auth.Type)
+
+	boshDeployments, err := boshClient.Deployments()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error listing BOSH Deployments: %v", err))
+	}
+	fmt.Fprintf(out, "Visible deployments (%d):\n", len(boshDeployments))
+	for _, deployment := range boshDeployments {
+		fmt.Fprintf(out, "  %s\n", deployment.Name())
+	}
+
+	deploymentsInfo, err := deploymentsFetcher.Deployments()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Filtered deployments (%d):\n", len(deploymentsInfo))
+	for _, deployment := range deploymentsInfo {
+		processes := 0
+		for _, instance := range deployment.Instances {
+			processes += len(instance.Processes)
+		}
+		fmt.Fprintf(out, "  %s: %d instances, %d processes\n", deployment.Name, len(deployment.Instances), processes)
+	}
+	fmt.Fprintf(out, "Filtered out instances: %d\n", deploymentsFetcher.FilteredInstances())
+
+	fmt.Fprintf(out, "Enabled collectors: %s\n", strings.Join(boshCollector.EnabledCollectors(), ", "))
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const (
+	backupDirectoryTimeLayout = "20060102T150405Z"
+	backupMetadataTimeLayout  = "2006/01/02 15:04:05 MST"
+	backupMetadataFilename    = "metadata"
+)
+
+// backupDirectoryRegexp matches the `<deployment>_<timestamp>` directories
+// created by `bbr deployment backup`.
+var backupDirectoryRegexp = regexp.MustCompile(`^(.+)_(\d{8}T\d{6}Z)$`)
+
+type BackupsCollector struct {
+	backupsDirectory                       string
+	deploymentLastBackupTimestampMetric    *prometheus.GaugeVec
+	lastBackupsScrapeTimestampMetric       prometheus.Gauge
+	lastBackupsScrapeDurationSecondsMetric prometheus.Gauge
+	descs                                  metricsDescs
+}
+
+type backupMetadata struct {
+	BackupActivity struct {
+		FinishTime string `yaml:"finish_time"`
+	} `yaml:"backup_activity"`
+}
+
+func NewBackupsCollector(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+	backupsDirectory string,
+) *BackupsCollector {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	deploymentLastBackupTimestampMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "last_backup_timestamp",
+			Help:        "Number of seconds since 1970 since the last BBR backup of the BOSH Deployment.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	lastBackupsScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_backups_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Backups metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastBackupsScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_backups_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Backups metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	collector := &BackupsCollector{
+		backupsDirectory:                       backupsDirectory,
+		deploymentLastBackupTimestampMetric:    deploymentLastBackupTimestampMetric,
+		lastBackupsScrapeTimestampMetric:       lastBackupsScrapeTimestampMetric,
+		lastBackupsScrapeDurationSecondsMetric: lastBackupsScrapeDurationSecondsMetric,
+		descs:                                  descs,
+	}
+	return collector
+}
+
+func (c *BackupsCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	var begun = time.Now()
+
+	lastBackups, err := c.readLastBackups()
+	if err != nil {
+		return err
+	}
+
+	c.deploymentLastBackupTimestampMetric.Reset()
+
+	for _, deployment := range deployments {
+		c.reportDeploymentLastBackupMetrics(deployment, lastBackups, ch)
+	}
+
+	c.deploymentLastBackupTimestampMetric.Collect(ch)
+
+	c.lastBackupsScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastBackupsScrapeTimestampMetric.Collect(ch)
+
+	c.lastBackupsScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.lastBackupsScrapeDurationSecondsMetric.Collect(ch)
+
+	return nil
+}
+
+func (c *BackupsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.deploymentLastBackupTimestampMetric.Describe(ch)
+	c.lastBackupsScrapeTimestampMetric.Describe(ch)
+	c.lastBackupsScrapeDurationSecondsMetric.Describe(ch)
+}
+
+func (c *BackupsCollector) reportDeploymentLastBackupMetrics(
+	deployment deployments.DeploymentInfo,
+	lastBackups map[string]time.Time,
+	ch chan<- prometheus.Metric,
+) {
+	lastBackup, ok := lastBackups[deployment.Name]
+	if !ok {
+		return
+	}
+
+	c.deploymentLastBackupTimestampMetric.WithLabelValues(deployment.Name).Set(float64(lastBackup.Unix()))
+}
+
+// readLastBackups returns the time of the last backup of each deployment
+// found in the backups directory.
+func (c *BackupsCollector) readLastBackups() (map[string]time.Time, error) {
+	lastBackups := map[string]time.Time{}
+
+	entries, err := ioutil.ReadDir(c.backupsDirectory)
+	if err != nil {
+		return lastBackups, errors.New(fmt.Sprintf("Error while reading Backups directory `%s`: %v", c.backupsDirectory, err))
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		matches := backupDirectoryRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+
+		backupTime, err := time.Parse(backupDirectoryTimeLayout, matches[2])
+		if err != nil {
+			log.Debugf("Ignoring backup directory `%s`: %v", entry.Name(), err)
+			continue
+		}
+
+		if finishTime, ok := readBackupFinishTime(filepath.Join(c.backupsDirectory, entry.Name())); ok {
+			backupTime = finishTime
+		}
+
+		if backupTime.After(lastBackups[matches[1]]) {
+			lastBackups[matches[1]] = backupTime
+		}
+	}
+
+	return lastBackups, nil
+}
+
+// readBackupFinishTime returns the finish time recorded in the backup
+// metadata file, if any.
+func readBackupFinishTime(backupDirectory string) (time.Time, bool) {
+	content, err := ioutil.ReadFile(filepath.Join(backupDirectory, backupMetadataFilename))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var metadata backupMetadata
+	if err = yaml.Unmarshal(content, &metadata); err != nil {
+		log.Debugf("Ignoring backup metadata `%s`: %v", backupDirectory, err)
+		return time.Time{}, false
+	}
+
+	finishTime, err := time.Parse(backupMetadataTimeLayout, metadata.BackupActivity.FinishTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return finishTime, true
+}
+
+func (c *BackupsCollector) descriptions() metricsDescs {
+	return c.descs
+}
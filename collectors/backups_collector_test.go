@@ -0,0 +1,224 @@
+package collectors_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("BackupsCollector", func() {
+	var (
+		err              error
+		namespace        string
+		environment      string
+		boshName         string
+		boshUUID         string
+		backupsDirectory string
+		backupsCollector *BackupsCollector
+
+		deploymentLastBackupTimestampMetric    *prometheus.GaugeVec
+		lastBackupsScrapeTimestampMetric       prometheus.Gauge
+		lastBackupsScrapeDurationSecondsMetric prometheus.Gauge
+
+		deploymentName      = "fake-deployment-name"
+		otherDeploymentName = "fake-other-deployment-name"
+		oldestBackupTime    = time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC)
+		latestBackupTime    = time.Date(2017, 12, 2, 10, 0, 0, 0, time.UTC)
+		finishedBackupTime  = time.Date(2017, 12, 2, 10, 15, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		environment = "test_environment"
+		boshName = "test_bosh_name"
+		boshUUID = "test_bosh_uuid"
+
+		backupsDirectory, err = ioutil.TempDir("", "backups_collector_test_")
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, backup := range []string{
+			deploymentName + "_" + oldestBackupTime.Format("20060102T150405Z"),
+			deploymentName + "_" + latestBackupTime.Format("20060102T150405Z"),
+			otherDeploymentName + "_" + oldestBackupTime.Format("20060102T150405Z"),
+			"not-a-backup",
+		} {
+			Expect(os.Mkdir(filepath.Join(backupsDirectory, backup), 0755)).To(Succeed())
+		}
+
+		deploymentLastBackupTimestampMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "last_backup_timestamp",
+				Help:      "Number of seconds since 1970 since the last BBR backup of the BOSH Deployment.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		lastBackupsScrapeTimestampMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_backups_scrape_timestamp",
+				Help:      "Number of seconds since 1970 since last scrape of Backups metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastBackupsScrapeDurationSecondsMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_backups_scrape_duration_seconds",
+				Help:      "Duration of the last scrape of Backups metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(backupsDirectory)
+	})
+
+	JustBeforeEach(func() {
+		backupsCollector = NewBackupsCollector(
+			namespace,
+			environment,
+			boshName,
+			boshUUID,
+			prometheus.Labels{},
+			backupsDirectory,
+		)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go backupsCollector.Describe(descriptions)
+		})
+
+		It("returns a deployment_last_backup_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentLastBackupTimestampMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a last_backups_scrape_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastBackupsScrapeTimestampMetric.Desc())))
+		})
+
+		It("returns a last_backups_scrape_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastBackupsScrapeDurationSecondsMetric.Desc())))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			deploymentsInfo []deployments.DeploymentInfo
+
+			metrics    chan prometheus.Metric
+			errMetrics chan error
+		)
+
+		BeforeEach(func() {
+			deploymentsInfo = []deployments.DeploymentInfo{
+				{Name: deploymentName},
+			}
+
+			metrics = make(chan prometheus.Metric)
+			errMetrics = make(chan error, 1)
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				if err := backupsCollector.Collect(deploymentsInfo, metrics); err != nil {
+					errMetrics <- err
+				}
+			}()
+		})
+
+		It("returns a deployment_last_backup_timestamp metric with the latest backup", func() {
+			deploymentLastBackupTimestampMetric.WithLabelValues(deploymentName).Set(float64(latestBackupTime.Unix()))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentLastBackupTimestampMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("does not return a deployment_last_backup_timestamp metric for filtered deployments", func() {
+			Eventually(metrics).Should(Receive())
+			Eventually(metrics).Should(Receive())
+			Eventually(metrics).Should(Receive())
+			Consistently(metrics).ShouldNot(Receive())
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the backup has a metadata file", func() {
+			BeforeEach(func() {
+				metadata := []byte("backup_activity:\n  start_time: 2017/12/02 10:00:00 UTC\n  finish_time: 2017/12/02 10:15:00 UTC\n")
+				backup := deploymentName + "_" + latestBackupTime.Format("20060102T150405Z")
+				Expect(ioutil.WriteFile(filepath.Join(backupsDirectory, backup, "metadata"), metadata, 0644)).To(Succeed())
+			})
+
+			It("returns a deployment_last_backup_timestamp metric with the backup finish time", func() {
+				deploymentLastBackupTimestampMetric.WithLabelValues(deploymentName).Set(float64(finishedBackupTime.Unix()))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentLastBackupTimestampMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the deployment has no backups", func() {
+			BeforeEach(func() {
+				deploymentsInfo = []deployments.DeploymentInfo{
+					{Name: "fake-not-backed-up-deployment-name"},
+				}
+			})
+
+			It("returns only a last_backups_scrape_timestamp & last_backups_scrape_duration_seconds metric", func() {
+				Eventually(metrics).Should(Receive())
+				Eventually(metrics).Should(Receive())
+				Consistently(metrics).ShouldNot(Receive())
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the backups directory does not exist", func() {
+			BeforeEach(func() {
+				os.RemoveAll(backupsDirectory)
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+	})
+})
@@ -1,140 +1,375 @@
 package collectors
 
 import (
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/cloudfoundry/bosh-cli/director"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/log"
 
+	"github.com/cloudfoundry-community/bosh_exporter/api"
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
 )
 
+const LegacyNamespace = "bosh"
+
 type BoshCollector struct {
-	enabledCollectors                   []Collector
-	deploymentsFetcher                  *deployments.Fetcher
+	enabledCollectors                   map[string]Collector
+	legacyCollectors                    map[string]Collector
+	serviceDiscoveryCollector           *ServiceDiscoveryCollector
+	serviceDiscoveryRefreshInterval     time.Duration
+	deploymentsFetcher                  DeploymentsFetcher
+	deploymentLabels                    *DeploymentLabels
+	serviceLabels                       *ServiceLabels
+	seriesGuard                         *SeriesGuard
+	labelSanitizer                      *LabelSanitizer
+	deploymentsObservers                []DeploymentsObserver
+	maxScrapeSeries                     int
+	seriesTracker                       *seriesTracker
+	metricsTimestamps                   bool
 	totalBoshScrapesMetric              prometheus.Counter
 	totalBoshScrapeErrorsMetric         prometheus.Counter
+	scrapeErrorsMetric                  *prometheus.CounterVec
 	lastBoshScrapeErrorMetric           prometheus.Gauge
+	lastBoshScrapePartialMetric         prometheus.Gauge
+	scrapeTruncatedMetric               prometheus.Gauge
 	lastBoshScrapeTimestampMetric       prometheus.Gauge
 	lastBoshScrapeDurationSecondsMetric prometheus.Gauge
+	filteredDeploymentsMetric           prometheus.CounterFunc
+	filteredInstancesMetric             prometheus.CounterFunc
+	filteredProcessesMetric             prometheus.CounterFunc
+	directorUnsupportedMetric           *prometheus.GaugeVec
+	tlsCertificateNotAfterMetric        prometheus.Gauge
+	uaaTokenExpiryMetric                prometheus.GaugeFunc
+	uaaAuthFailuresMetric               prometheus.CounterFunc
+	collectorDurationSecondsMetric      *prometheus.HistogramVec
+	collectorEnabledMetric              *prometheus.GaugeVec
+	deploymentFetchDurationMetric       *prometheus.HistogramVec
+	fetchedDeployments                  map[string]bool
+	fetchedDeploymentsMutex             *sync.Mutex
+	lastCollections                     map[string]time.Time
+	lastCollectionsMutex                *sync.Mutex
+	collection                          *collection
+	collectionMutex                     *sync.Mutex
+	descs                               metricsDescs
 }
 
+// NewBoshCollector returns a collector exposing the metrics of the
+// deployments returned by the deploymentsFetcher.
 func NewBoshCollector(
-	namespace string,
-	environment string,
-	boshName string,
-	boshUUID string,
-	serviceDiscoveryFilename string,
-	deploymentsFetcher *deployments.Fetcher,
-	collectorsFilter *filters.CollectorsFilter,
-	azsFilter *filters.AZsFilter,
-	processesFilter *filters.RegexpFilter,
+	boshClient director.Director,
+	deploymentsFetcher DeploymentsFetcher,
+	options BoshCollectorOptions,
 ) *BoshCollector {
-	enabledCollectors := []Collector{}
+	options = options.withDefaults()
+	namespace := options.Namespace
+	constLabels := options.ConstLabels
+	collectorsSubsystems := options.CollectorsSubsystems
+	collectorsFilter := options.CollectorsFilter
+	processesFilter := options.ServiceDiscoveryProcessesFilter
+	legacyMetricsNames := options.LegacyMetricsNames
 
-	if collectorsFilter.Enabled(filters.DeploymentsCollector) {
-		deploymentsCollector := NewDeploymentsCollector(namespace, environment, boshName, boshUUID)
-		enabledCollectors = append(enabledCollectors, deploymentsCollector)
-	}
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(options.Environment, options.BoshName, options.BoshUUID, constLabels)
+	exporterNamespace := collectorsSubsystems.Namespace(namespace, ExporterMetrics)
 
-	if collectorsFilter.Enabled(filters.JobsCollector) {
-		jobsCollector := NewJobsCollector(namespace, environment, boshName, boshUUID, azsFilter)
-		enabledCollectors = append(enabledCollectors, jobsCollector)
-	}
+	collectorEnabledMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "collector_enabled",
+			Help:        "Whether the collector is enabled (1 for enabled, 0 for disabled).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"collector"},
+	)
 
-	if collectorsFilter.Enabled(filters.ServiceDiscoveryCollector) {
-		serviceDiscoveryCollector := NewServiceDiscoveryCollector(
-			namespace,
-			environment,
-			boshName,
-			boshUUID,
-			serviceDiscoveryFilename,
-			azsFilter,
-			processesFilter,
-		)
-		enabledCollectors = append(enabledCollectors, serviceDiscoveryCollector)
+	enabledCollectors := map[string]Collector{}
+	legacyCollectors := map[string]Collector{}
+	var backgroundServiceDiscoveryCollector *ServiceDiscoveryCollector
+
+	for _, registered := range options.CollectorsRegistry.collectors {
+		collectorEnabledMetric.WithLabelValues(registered.name).Set(0)
+		if !registered.enabled || !collectorsFilter.Enabled(registered.name) {
+			continue
+		}
+
+		collectorNamespace := collectorsSubsystems.Namespace(namespace, registered.name)
+		collector := registered.factory(collectorNamespace, constLabels, boshClient, options)
+		if collector == nil {
+			continue
+		}
+		collectorEnabledMetric.WithLabelValues(registered.name).Set(1)
+
+		if serviceDiscoveryCollector, ok := collector.(*ServiceDiscoveryCollector); ok && options.ServiceDiscoveryRefreshInterval > 0 {
+			backgroundServiceDiscoveryCollector = serviceDiscoveryCollector
+			continue
+		}
+		enabledCollectors[registered.name] = collector
+
+		if registered.legacyMetrics && legacyMetricsNames && collectorNamespace != LegacyNamespace {
+			legacyCollectors[registered.name] = registered.factory(LegacyNamespace, prometheus.Labels{}, boshClient, options)
+		}
 	}
 
-	totalBoshScrapesMetric := prometheus.NewCounter(
+	totalBoshScrapesMetric := descs.newCounter(
 		prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "scrapes_total",
-			Help:      "Total number of times BOSH was scraped for metrics.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "scrapes_total",
+			Help:        "Total number of times BOSH was scraped for metrics.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
-	totalBoshScrapeErrorsMetric := prometheus.NewCounter(
+	totalBoshScrapeErrorsMetric := descs.newCounter(
 		prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "scrape_errors_total",
-			Help:      "Total number of times an error occured scraping BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "scrape_errors_total",
+			Help:        "Total number of times an error occured scraping BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	scrapeErrorsMetric := descs.newCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "scrape_errors_total",
+			Help:        "Total number of errors scraping BOSH, by collector and kind of error (auth, timeout, task, parse or other).",
+			ConstLabels: metricConstLabels,
 		},
+		[]string{"collector", "kind"},
 	)
 
-	lastBoshScrapeErrorMetric := prometheus.NewGauge(
+	lastBoshScrapeErrorMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_scrape_error",
-			Help:      "Whether the last scrape of metrics from BOSH resulted in an error (1 for error, 0 for success).",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "last_scrape_error",
+			Help:        "Whether the last scrape of metrics from BOSH resulted in an error (1 for error, 0 for success).",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
-	lastBoshScrapeTimestampMetric := prometheus.NewGauge(
+	scrapeTruncatedMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_scrape_timestamp",
-			Help:      "Number of seconds since 1970 since last scrape from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "truncated",
+			Help:        "Whether the last scrape of metrics from BOSH was truncated because it exceeded the maximum number of series per scrape (1 for truncated, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastBoshScrapePartialMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "last_scrape_partial",
+			Help:        "Whether the last scrape of metrics from BOSH was cut by the scrape timeout (1 for partial, 0 for complete).",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastBoshScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "last_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	filteredDeploymentsMetric := descs.newCounterFunc(
+		prometheus.CounterOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "filtered_deployments_total",
+			Help:        "Total number of BOSH deployments discarded by the deployments and teams filters.",
+			ConstLabels: metricConstLabels,
+		},
+		func() float64 { return float64(deploymentsFetcher.FilteredDeployments()) },
+	)
+
+	filteredInstancesMetric := descs.newCounterFunc(
+		prometheus.CounterOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "filtered_instances_total",
+			Help:        "Total number of BOSH instances discarded by the jobs and AZs filters.",
+			ConstLabels: metricConstLabels,
+		},
+		func() float64 { return float64(deploymentsFetcher.FilteredInstances()) },
+	)
+
+	filteredProcessesMetric := descs.newCounterFunc(
+		prometheus.CounterOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "filtered_processes_total",
+			Help:        "Total number of BOSH processes discarded by the Service Discovery processes filter.",
+			ConstLabels: metricConstLabels,
+		},
+		func() float64 { return float64(processesFilter.Filtered()) },
+	)
+
+	lastBoshScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "",
+			Name:        "last_scrape_duration_seconds",
+			Help:        "Duration of the last scrape from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
-	lastBoshScrapeDurationSecondsMetric := prometheus.NewGauge(
+	directorUnsupportedMetric := descs.newGaugeVec(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_scrape_duration_seconds",
-			Help:      "Duration of the last scrape from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "director_unsupported",
+			Help:        "Whether the BOSH Director version is not supported by the exporter (1 for unsupported, 0 for supported).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_director_version"},
+	)
+
+	directorVersion := options.DirectorCompatibility.Version
+	if directorVersion != "" {
+		directorUnsupported := 0
+		if options.DirectorCompatibility.Unsupported {
+			directorUnsupported = 1
+		}
+		directorUnsupportedMetric.WithLabelValues(directorVersion).Set(float64(directorUnsupported))
+	}
+
+	var tlsCertificateNotAfterMetric prometheus.Gauge
+	if options.TLSCertificate != nil {
+		tlsCertificateNotAfterMetric = descs.newGauge(
+			prometheus.GaugeOpts{
+				Namespace:   exporterNamespace,
+				Subsystem:   "exporter",
+				Name:        "tls_certificate_not_after",
+				Help:        "Number of seconds since 1970 since the TLS certificate served by the exporter expired or will expire.",
+				ConstLabels: metricConstLabels,
+			},
+		)
+		tlsCertificateNotAfterMetric.Set(float64(options.TLSCertificate.NotAfter.Unix()))
+	}
+
+	var uaaTokenExpiryMetric prometheus.GaugeFunc
+	var uaaAuthFailuresMetric prometheus.CounterFunc
+	if uaaTokenStatus := options.UAATokenStatus; uaaTokenStatus != nil {
+		uaaTokenExpiryMetric = descs.newGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   exporterNamespace,
+				Subsystem:   "exporter",
+				Name:        "uaa_token_expiry_timestamp_seconds",
+				Help:        "Number of seconds since 1970 since the UAA token used to authenticate to the BOSH Director expired or will expire.",
+				ConstLabels: metricConstLabels,
+			},
+			func() float64 {
+				if expiry := uaaTokenStatus.TokenExpiry(); !expiry.IsZero() {
+					return float64(expiry.Unix())
+				}
+				return 0
+			},
+		)
+
+		uaaAuthFailuresMetric = descs.newCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   exporterNamespace,
+				Subsystem:   "exporter",
+				Name:        "uaa_auth_failures_total",
+				Help:        "Total number of failures getting a UAA token to authenticate to the BOSH Director.",
+				ConstLabels: metricConstLabels,
 			},
+			func() float64 { return float64(uaaTokenStatus.AuthFailures()) },
+		)
+	}
+
+	collectorDurationSecondsMetric := descs.newHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "collector_duration_seconds",
+			Help:        "Duration of the collectors scrapes.",
+			Buckets:     prometheus.ExponentialBuckets(0.001, 4, 10),
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"collector"},
+	)
+
+	deploymentFetchDurationMetric := descs.newHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   exporterNamespace,
+			Subsystem:   "exporter",
+			Name:        "deployment_fetch_duration_seconds",
+			Help:        "Duration of the BOSH Deployments fetches from the BOSH Director.",
+			Buckets:     prometheus.ExponentialBuckets(0.1, 2, 12),
+			ConstLabels: metricConstLabels,
 		},
+		[]string{"bosh_deployment"},
 	)
 
+	seriesTracker := newSeriesTracker(exporterNamespace, metricConstLabels)
+
+	for _, collector := range enabledCollectors {
+		descs.merge(collector)
+	}
+	for _, collector := range legacyCollectors {
+		descs.merge(collector)
+	}
+	if backgroundServiceDiscoveryCollector != nil {
+		descs.merge(backgroundServiceDiscoveryCollector)
+	}
+	descs.merge(options.SeriesGuard)
+	descs.merge(seriesTracker)
+
 	return &BoshCollector{
 		enabledCollectors:                   enabledCollectors,
+		legacyCollectors:                    legacyCollectors,
+		serviceDiscoveryCollector:           backgroundServiceDiscoveryCollector,
+		serviceDiscoveryRefreshInterval:     options.ServiceDiscoveryRefreshInterval,
 		deploymentsFetcher:                  deploymentsFetcher,
+		deploymentLabels:                    options.DeploymentLabels,
+		serviceLabels:                       options.ServiceLabels,
+		seriesGuard:                         options.SeriesGuard,
+		labelSanitizer:                      options.LabelSanitizer,
+		deploymentsObservers:                options.DeploymentsObservers,
+		seriesTracker:                       seriesTracker,
+		metricsTimestamps:                   options.MetricsTimestamps,
 		totalBoshScrapesMetric:              totalBoshScrapesMetric,
 		totalBoshScrapeErrorsMetric:         totalBoshScrapeErrorsMetric,
+		scrapeErrorsMetric:                  scrapeErrorsMetric,
 		lastBoshScrapeErrorMetric:           lastBoshScrapeErrorMetric,
+		lastBoshScrapePartialMetric:         lastBoshScrapePartialMetric,
+		scrapeTruncatedMetric:               scrapeTruncatedMetric,
+		maxScrapeSeries:                     options.MaxScrapeSeries,
 		lastBoshScrapeTimestampMetric:       lastBoshScrapeTimestampMetric,
 		lastBoshScrapeDurationSecondsMetric: lastBoshScrapeDurationSecondsMetric,
+		filteredDeploymentsMetric:           filteredDeploymentsMetric,
+		filteredInstancesMetric:             filteredInstancesMetric,
+		filteredProcessesMetric:             filteredProcessesMetric,
+		directorUnsupportedMetric:           directorUnsupportedMetric,
+		tlsCertificateNotAfterMetric:        tlsCertificateNotAfterMetric,
+		uaaTokenExpiryMetric:                uaaTokenExpiryMetric,
+		uaaAuthFailuresMetric:               uaaAuthFailuresMetric,
+		collectorDurationSecondsMetric:      collectorDurationSecondsMetric,
+		collectorEnabledMetric:              collectorEnabledMetric,
+		deploymentFetchDurationMetric:       deploymentFetchDurationMetric,
+		fetchedDeployments:                  map[string]bool{},
+		fetchedDeploymentsMutex:             &sync.Mutex{},
+		lastCollections:                     map[string]time.Time{},
+		lastCollectionsMutex:                &sync.Mutex{},
+		collectionMutex:                     &sync.Mutex{},
+		descs:                               descs,
 	}
 }
 
@@ -148,73 +383,414 @@ func (c *BoshCollector) Describe(ch chan<- *prometheus.Desc) {
 			collector.Describe(ch)
 		}(collector, ch)
 	}
+
+	for _, collector := range c.legacyCollectors {
+		wg.Add(1)
+		go func(collector Collector, ch chan<- *prometheus.Desc) {
+			defer wg.Done()
+			collector.Describe(ch)
+		}(collector, ch)
+	}
 	wg.Wait()
 
+	if c.serviceDiscoveryCollector != nil {
+		c.serviceDiscoveryCollector.Describe(ch)
+	}
+
 	c.totalBoshScrapesMetric.Describe(ch)
 	c.totalBoshScrapeErrorsMetric.Describe(ch)
+	c.scrapeErrorsMetric.Describe(ch)
 	c.lastBoshScrapeErrorMetric.Describe(ch)
+	c.lastBoshScrapePartialMetric.Describe(ch)
+	c.scrapeTruncatedMetric.Describe(ch)
 	c.lastBoshScrapeTimestampMetric.Describe(ch)
 	c.lastBoshScrapeDurationSecondsMetric.Describe(ch)
+	c.filteredDeploymentsMetric.Describe(ch)
+	c.filteredInstancesMetric.Describe(ch)
+	c.filteredProcessesMetric.Describe(ch)
+	c.directorUnsupportedMetric.Describe(ch)
+	if c.tlsCertificateNotAfterMetric != nil {
+		c.tlsCertificateNotAfterMetric.Describe(ch)
+	}
+	if c.uaaTokenExpiryMetric != nil {
+		c.uaaTokenExpiryMetric.Describe(ch)
+		c.uaaAuthFailuresMetric.Describe(ch)
+	}
+	c.collectorDurationSecondsMetric.Describe(ch)
+	c.collectorEnabledMetric.Describe(ch)
+	c.deploymentFetchDurationMetric.Describe(ch)
+	c.seriesGuard.Describe(ch)
+	c.seriesTracker.Describe(ch)
 }
 
 func (c *BoshCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(0, ch)
+}
+
+// WithScrapeTimeout returns a collector bounding the scrape of the
+// BoshCollector by the timeout: the metrics collected before it elapses are
+// returned, and the `last_scrape_partial` metric is set.
+func (c *BoshCollector) WithScrapeTimeout(timeout time.Duration) prometheus.Collector {
+	return &boundedBoshCollector{BoshCollector: c, timeout: timeout}
+}
+
+type boundedBoshCollector struct {
+	*BoshCollector
+	timeout time.Duration
+}
+
+func (c *boundedBoshCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(c.timeout, ch)
+}
+
+func (c *BoshCollector) collect(timeout time.Duration, ch chan<- prometheus.Metric) {
 	var begun = time.Now()
 
 	scrapeError := 0
+	scrapePartial := 0
+	scrapeTruncated := 0
 	c.totalBoshScrapesMetric.Inc()
-	deployments, err := c.deploymentsFetcher.Deployments()
+
+	scrape := func(ch chan<- prometheus.Metric) error {
+		deployments, err := c.deploymentsFetcher.Deployments()
+		if err != nil {
+			c.scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, errorKind(err)).Inc()
+			return err
+		}
+
+		c.pruneFetchedDeployments(deployments)
+		deployments = c.labelSanitizer.SanitizeDeployments(deployments)
+		c.observeDeployments(deployments)
+		fetchedAt := time.Now()
+		collect := func(ch chan<- prometheus.Metric) error {
+			return c.seriesTracker.Track(func(ch chan<- prometheus.Metric) error {
+				return c.executeCollectors(deployments, ch)
+			}, ch)
+		}
+		if c.metricsTimestamps {
+			return collectWithTimestamp(fetchedAt, collect, ch)
+		}
+		return collect(ch)
+	}
+
+	bounded := func(ch chan<- prometheus.Metric) error {
+		collection := c.joinCollection(scrape)
+		defer c.leaveCollection(collection)
+
+		complete, err := collection.wait(timeout, ch)
+		if !complete {
+			log.Errorf("Scrape from BOSH is partial: timed out after %s", timeout)
+			scrapePartial = 1
+		}
+		return err
+	}
+
+	var err error
+	if c.maxScrapeSeries > 0 {
+		var dropped map[string]int
+		dropped, err = collectWithLimit(c.maxScrapeSeries, bounded, ch)
+		if len(dropped) > 0 {
+			log.Errorf("Scrape from BOSH is truncated: more than %d series, dropped series by metric: %v", c.maxScrapeSeries, dropped)
+			scrapeTruncated = 1
+		}
+	} else {
+		err = bounded(ch)
+	}
 	if err != nil {
 		log.Error(err)
 		scrapeError = 1
 		c.totalBoshScrapeErrorsMetric.Inc()
-	} else {
-		if err := c.executeCollectors(deployments, ch); err != nil {
-			log.Error(err)
-			scrapeError = 1
-			c.totalBoshScrapeErrorsMetric.Inc()
-		}
+	}
+
+	if c.serviceDiscoveryCollector != nil {
+		c.serviceDiscoveryCollector.CollectMetrics(ch)
 	}
 
 	c.totalBoshScrapesMetric.Collect(ch)
 
 	c.totalBoshScrapeErrorsMetric.Collect(ch)
+	c.scrapeErrorsMetric.Collect(ch)
 
 	c.lastBoshScrapeErrorMetric.Set(float64(scrapeError))
 	c.lastBoshScrapeErrorMetric.Collect(ch)
 
+	c.lastBoshScrapePartialMetric.Set(float64(scrapePartial))
+	c.lastBoshScrapePartialMetric.Collect(ch)
+
+	c.scrapeTruncatedMetric.Set(float64(scrapeTruncated))
+	c.scrapeTruncatedMetric.Collect(ch)
+
 	c.lastBoshScrapeTimestampMetric.Set(float64(time.Now().Unix()))
 	c.lastBoshScrapeTimestampMetric.Collect(ch)
 
 	c.lastBoshScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
 	c.lastBoshScrapeDurationSecondsMetric.Collect(ch)
+
+	c.filteredDeploymentsMetric.Collect(ch)
+	c.filteredInstancesMetric.Collect(ch)
+	c.filteredProcessesMetric.Collect(ch)
+	c.directorUnsupportedMetric.Collect(ch)
+	if c.tlsCertificateNotAfterMetric != nil {
+		c.tlsCertificateNotAfterMetric.Collect(ch)
+	}
+	if c.uaaTokenExpiryMetric != nil {
+		c.uaaTokenExpiryMetric.Collect(ch)
+		c.uaaAuthFailuresMetric.Collect(ch)
+	}
+	c.collectorDurationSecondsMetric.Collect(ch)
+	c.collectorEnabledMetric.Collect(ch)
+	c.deploymentFetchDurationMetric.Collect(ch)
+	c.seriesGuard.Collect(ch)
+	c.seriesTracker.Collect(ch)
+}
+
+// joinCollection returns the collection in progress, or starts one running
+// scrape, so scrapes never run the collectors concurrently: a scrape starting
+// while another one (or a timed out one) is collecting waits for it, and
+// returns its metrics.
+func (c *BoshCollector) joinCollection(scrape func(ch chan<- prometheus.Metric) error) *collection {
+	c.collectionMutex.Lock()
+	defer c.collectionMutex.Unlock()
+
+	if c.collection == nil {
+		collection := newCollection()
+		c.collection = collection
+		go collection.run(scrape, func() {
+			c.collectionMutex.Lock()
+			defer c.collectionMutex.Unlock()
+
+			collection.finished = true
+			c.releaseCollection(collection)
+		})
+	}
+	c.collection.waiters++
+
+	return c.collection
+}
+
+func (c *BoshCollector) leaveCollection(collection *collection) {
+	c.collectionMutex.Lock()
+	defer c.collectionMutex.Unlock()
+
+	collection.waiters--
+	c.releaseCollection(collection)
+}
+
+// releaseCollection lets the next scrape start a new collection once the
+// collection finished and its metrics were forwarded to all the scrapes
+// waiting for it, as they are still read while forwarded.
+func (c *BoshCollector) releaseCollection(collection *collection) {
+	if collection.finished && collection.waiters == 0 && c.collection == collection {
+		c.collection = nil
+	}
+}
+
+// ObserveDeploymentFetch records the duration of a deployment fetch. It is
+// meant to be set as the deployments.Fetcher FetchObserver.
+func (c *BoshCollector) ObserveDeploymentFetch(deploymentName string, duration time.Duration) {
+	c.fetchedDeploymentsMutex.Lock()
+	defer c.fetchedDeploymentsMutex.Unlock()
+
+	c.fetchedDeployments[deploymentName] = true
+	c.deploymentFetchDurationMetric.WithLabelValues(deploymentName).Observe(duration.Seconds())
+}
+
+// ObserveDeploymentFetchError counts an error reading a deployment detail. It
+// is meant to be set as the deployments.Fetcher FetchErrorObserver.
+func (c *BoshCollector) ObserveDeploymentFetchError(deploymentName string, err error) {
+	c.scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, errorKind(err)).Inc()
+}
+
+// pruneFetchedDeployments drops the fetch durations of the deployments no
+// longer returned by the deployments fetcher.
+func (c *BoshCollector) pruneFetchedDeployments(deploymentsInfo []deployments.DeploymentInfo) {
+	names := map[string]bool{}
+	for _, deploymentInfo := range deploymentsInfo {
+		names[deploymentInfo.Name] = true
+	}
+
+	c.fetchedDeploymentsMutex.Lock()
+	defer c.fetchedDeploymentsMutex.Unlock()
+
+	for name := range c.fetchedDeployments {
+		if !names[name] {
+			c.deploymentFetchDurationMetric.DeleteLabelValues(name)
+			delete(c.fetchedDeployments, name)
+		}
+	}
+}
+
+// EnabledCollectors returns the sorted names of the enabled collectors,
+// including the Service Discovery collector when refreshed in background.
+func (c *BoshCollector) EnabledCollectors() []string {
+	names := []string{}
+	for name := range c.enabledCollectors {
+		names = append(names, name)
+	}
+	if c.serviceDiscoveryCollector != nil {
+		names = append(names, filters.ServiceDiscoveryCollector)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DeploymentsDetails returns the deployments details read by the enabled
+// collectors, meant to be set as the deployments.Fetcher details. The
+// collectors unknown to the exporter (i.e. the Exec collector, or the ones
+// registered by embedding programs) may read any detail.
+func (c *BoshCollector) DeploymentsDetails() deployments.Details {
+	details := deployments.Details{}
+	for _, name := range c.EnabledCollectors() {
+		switch name {
+		case filters.DeploymentsCollector:
+			details.Teams = true
+			details.Releases = true
+			details.Stemcells = true
+			details.Tasks = true
+		case filters.JobsCollector:
+			details.Stemcells = true
+		case filters.SnapshotsCollector:
+			details.Snapshots = true
+		case filters.BackupsCollector, filters.DirectorCollector, filters.NetworksCollector, filters.ServiceDiscoveryCollector:
+		default:
+			return deployments.AllDetails
+		}
+	}
+
+	return details
+}
+
+// CollectorsStatus returns the status of the enabled collectors, sorted by
+// name.
+func (c *BoshCollector) CollectorsStatus() []api.CollectorStatus {
+	c.lastCollectionsMutex.Lock()
+	defer c.lastCollectionsMutex.Unlock()
+
+	collectorsStatus := []api.CollectorStatus{}
+	for _, name := range c.EnabledCollectors() {
+		collectorsStatus = append(collectorsStatus, api.CollectorStatus{
+			Name:                     name,
+			LastSuccessfulCollection: c.lastCollections[name],
+		})
+	}
+
+	return collectorsStatus
+}
+
+// observeDeployments notifies the observers of the fetched deployments. An
+// observer failing does not fail the scrape.
+func (c *BoshCollector) observeDeployments(deploymentsInfo []deployments.DeploymentInfo) {
+	for _, observer := range c.deploymentsObservers {
+		if err := observer.ObserveDeployments(deploymentsInfo); err != nil {
+			log.Errorf("Error observing deployments: %v", err)
+		}
+	}
+}
+
+func (c *BoshCollector) recordCollection(name string) {
+	c.lastCollectionsMutex.Lock()
+	defer c.lastCollectionsMutex.Unlock()
+
+	c.lastCollections[name] = time.Now()
+}
+
+func (c *BoshCollector) RefreshServiceDiscovery(stopCh <-chan struct{}) {
+	if c.serviceDiscoveryCollector == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.serviceDiscoveryRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		c.refreshServiceDiscovery()
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (c *BoshCollector) refreshServiceDiscovery() {
+	deployments, err := c.deploymentsFetcher.Deployments()
+	if err != nil {
+		log.Errorf("Error refreshing Service Discovery: %v", err)
+		return
+	}
+
+	if err = c.serviceDiscoveryCollector.Refresh(c.labelSanitizer.SanitizeDeployments(deployments)); err != nil {
+		log.Errorf("Error refreshing Service Discovery: %v", err)
+		return
+	}
+	c.recordCollection(filters.ServiceDiscoveryCollector)
 }
 
 func (c *BoshCollector) executeCollectors(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
 	var wg = &sync.WaitGroup{}
 
-	doneChannel := make(chan bool, 1)
 	errChannel := make(chan error, 1)
 
-	for _, collector := range c.enabledCollectors {
+	for name, collector := range c.enabledCollectors {
 		wg.Add(1)
-		go func(collector Collector) {
+		go func(name string, collector Collector) {
 			defer wg.Done()
-			if err := collector.Collect(deployments, ch); err != nil {
-				errChannel <- err
+			collect := func(ch chan<- prometheus.Metric) error {
+				return c.serviceLabels.Apply(func(ch chan<- prometheus.Metric) error {
+					return c.deploymentLabels.Apply(func(ch chan<- prometheus.Metric) error {
+						return describeMetrics(c.descs, func(ch chan<- prometheus.Metric) error {
+							return collector.Collect(deployments, ch)
+						}, ch)
+					}, ch)
+				}, ch)
+			}
+			begun := time.Now()
+			if err := c.seriesGuard.Guard(name, collect, ch); err != nil {
+				c.scrapeErrorsMetric.WithLabelValues(name, errorKind(err)).Inc()
+				select {
+				case errChannel <- err:
+				default:
+				}
+			} else {
+				c.recordCollection(name)
 			}
-		}(collector)
+			c.collectorDurationSecondsMetric.WithLabelValues(name).Observe(time.Since(begun).Seconds())
+		}(name, collector)
 	}
 
-	go func() {
-		wg.Wait()
-		close(doneChannel)
-	}()
+	for name, collector := range c.legacyCollectors {
+		wg.Add(1)
+		go func(name string, collector Collector) {
+			defer wg.Done()
+			collect := func(ch chan<- prometheus.Metric) error {
+				return describeMetrics(c.descs, func(ch chan<- prometheus.Metric) error {
+					return collector.Collect(deployments, ch)
+				}, ch)
+			}
+			if err := c.seriesGuard.Guard(name, collect, ch); err != nil {
+				c.scrapeErrorsMetric.WithLabelValues(name, errorKind(err)).Inc()
+				select {
+				case errChannel <- err:
+				default:
+				}
+			}
+		}(name, collector)
+	}
+
+	// All collectors are waited for, even on error, so none of them writes
+	// metrics once the scrape is over.
+	wg.Wait()
 
 	select {
-	case <-doneChannel:
 	case err := <-errChannel:
 		return err
+	default:
+		return nil
 	}
+}
 
-	return nil
+func (c *BoshCollector) descriptions() metricsDescs {
+	return c.descs
 }
@@ -0,0 +1,184 @@
+package collectors
+
+import (
+	"crypto/x509"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+// DeploymentsFetcher fetches the BOSH deployments to collect metrics from.
+// It is implemented by *deployments.Fetcher.
+type DeploymentsFetcher interface {
+	Deployments() ([]deployments.DeploymentInfo, error)
+	FilteredDeployments() uint64
+	FilteredInstances() uint64
+}
+
+// UAATokenStatus reports the health of the UAA authentication against the
+// BOSH Director. It is implemented by *auth.TokenSession.
+type UAATokenStatus interface {
+	TokenExpiry() time.Time
+	AuthFailures() uint64
+}
+
+// DeploymentsObserver is notified of the deployments fetched on each scrape.
+// It is implemented by *publishers.AlertmanagerSilencer and
+// *publishers.AuditLog.
+type DeploymentsObserver interface {
+	ObserveDeployments(deployments []deployments.DeploymentInfo) error
+}
+
+// BoshCollectorOptions configures a BoshCollector. The zero value is valid:
+// all collectors are enabled, nothing is filtered and the Service Discovery
+// targets are collected but not written anywhere.
+type BoshCollectorOptions struct {
+	// Namespace prefixes the metrics names.
+	Namespace string
+	// Environment, BoshName and BoshUUID are added as constant labels to
+	// all metrics.
+	Environment string
+	BoshName    string
+	BoshUUID    string
+	// ConstLabels are additional constant labels added to all metrics.
+	ConstLabels prometheus.Labels
+	// CollectorsSubsystems overrides the namespace of some collectors.
+	CollectorsSubsystems CollectorsSubsystems
+	// LegacyMetricsNames also exposes the metrics under the `bosh`
+	// namespace.
+	LegacyMetricsNames bool
+	// MetricsTimestamps stamps the metrics with the time the deployments
+	// were fetched.
+	MetricsTimestamps bool
+
+	// CollectorsRegistry holds the collectors to run. If nil, the built-in
+	// collectors are run.
+	CollectorsRegistry *CollectorsRegistry
+	// CollectorsFilter selects the enabled collectors. If nil, all
+	// collectors are enabled.
+	CollectorsFilter *filters.CollectorsFilter
+	// DeploymentLabels adds labels to the metrics of some deployments.
+	DeploymentLabels *DeploymentLabels
+	// ServiceLabels adds logical labels to the metrics of the deployments
+	// and instance groups matching its rules.
+	ServiceLabels *ServiceLabels
+	// StemcellsLifecycle sets the stemcells creation and EOL dates.
+	StemcellsLifecycle *StemcellsLifecycle
+	// BackupsDirectory enables the Backups collector.
+	BackupsDirectory string
+	// ExecCommands enables the Exec collector, running each external
+	// command on every scrape.
+	ExecCommands []string
+	// ExecTimeout is the maximum duration of each external command. If
+	// zero, commands are killed after 30 seconds.
+	ExecTimeout time.Duration
+	// SeriesGuard limits the series exposed by each collector. If nil,
+	// series are not limited.
+	SeriesGuard *SeriesGuard
+	// LabelSanitizer sanitizes the deployments names before the collectors
+	// use them as label values. If nil, invalid characters are replaced but
+	// values are not truncated.
+	LabelSanitizer *LabelSanitizer
+	// MaxScrapeSeries truncates the scrapes exposing more than this number
+	// of series. If zero, scrapes are not truncated.
+	MaxScrapeSeries int
+	// DirectorCompatibility adapts the collectors to the BOSH Director API
+	// features. Its zero value assumes a recent Director.
+	DirectorCompatibility deployments.DirectorCompatibility
+	// TLSCertificate is the certificate served by the exporter, whose expiry
+	// is exposed. If nil, the exporter does not serve TLS.
+	TLSCertificate *x509.Certificate
+	// UAATokenStatus exposes the UAA token expiry and authentication
+	// failures. If nil, the BOSH Director does not use UAA.
+	UAATokenStatus UAATokenStatus
+	// DeploymentsObservers are notified of the deployments fetched on each
+	// scrape.
+	DeploymentsObservers []DeploymentsObserver
+
+	// ServiceDiscoveryFilename is the file the target groups are written
+	// to. If empty, no file is written.
+	ServiceDiscoveryFilename string
+	// ServiceDiscoveryProcessesFilter selects the processes exposed as
+	// targets. If nil, all processes are exposed.
+	ServiceDiscoveryProcessesFilter *filters.ProcessesFilter
+	// ServiceDiscoveryCIDRsFilter selects the IPs exposed as targets. If
+	// nil, all IPs are exposed.
+	ServiceDiscoveryCIDRsFilter *filters.CIDRsFilter
+	// ServiceDiscoveryNetworksFilter selects the BOSH DNS names exposed as
+	// targets. If nil, the DNS names of all networks are exposed.
+	ServiceDiscoveryNetworksFilter  *filters.NetworksFilter
+	ServiceDiscoveryAllIPs          bool
+	ServiceDiscoveryProcessesPorts  ProcessesPorts
+	ServiceDiscoveryDNSNames        bool
+	ServiceDiscoveryRelabelConfigs  []RelabelConfig
+	ServiceDiscoveryFormat          string
+	ServiceDiscoveryTemplate        *template.Template
+	ServiceDiscoveryPublishers      []ServiceDiscoveryPublisher
+	ServiceDiscoveryLeaderElector   ServiceDiscoveryLeaderElector
+	ServiceDiscoveryRefreshInterval time.Duration
+}
+
+func (o BoshCollectorOptions) withDefaults() BoshCollectorOptions {
+	if o.CollectorsRegistry == nil {
+		o.CollectorsRegistry = NewCollectorsRegistry()
+	}
+
+	if o.CollectorsFilter == nil {
+		o.CollectorsFilter, _ = filters.NewCollectorsFilter([]string{})
+	}
+
+	if o.DeploymentLabels == nil {
+		o.DeploymentLabels, _ = LoadDeploymentLabels("")
+	}
+
+	if o.ServiceLabels == nil {
+		o.ServiceLabels, _ = LoadServiceLabels("")
+	}
+
+	if o.LabelSanitizer == nil {
+		o.LabelSanitizer = NewLabelSanitizer(0)
+	}
+
+	if o.StemcellsLifecycle == nil {
+		o.StemcellsLifecycle, _ = LoadStemcellsLifecycle("")
+	}
+
+	if o.ExecTimeout <= 0 {
+		o.ExecTimeout = 30 * time.Second
+	}
+
+	if o.SeriesGuard == nil {
+		o.SeriesGuard = NewSeriesGuard(
+			o.CollectorsSubsystems.Namespace(o.Namespace, ExporterMetrics),
+			o.Environment,
+			o.BoshName,
+			o.BoshUUID,
+			o.ConstLabels,
+			[]string{},
+			0,
+		)
+	}
+
+	if o.ServiceDiscoveryProcessesFilter == nil {
+		defaultProcessesFilter, _ := filters.NewRegexpFilter([]string{})
+		o.ServiceDiscoveryProcessesFilter, _ = filters.LoadProcessesFilter(defaultProcessesFilter, "")
+	}
+
+	if o.ServiceDiscoveryCIDRsFilter == nil {
+		o.ServiceDiscoveryCIDRsFilter, _ = filters.NewCIDRsFilter([]string{})
+	}
+
+	if o.ServiceDiscoveryNetworksFilter == nil {
+		o.ServiceDiscoveryNetworksFilter, _ = filters.NewNetworksFilter([]string{})
+	}
+
+	if o.ServiceDiscoveryProcessesPorts == nil {
+		o.ServiceDiscoveryProcessesPorts = ProcessesPorts{}
+	}
+
+	return o
+}
@@ -1,10 +1,14 @@
 package collectors_test
 
 import (
+	"crypto/x509"
 	"errors"
 	"flag"
 	"io/ioutil"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,8 +16,11 @@ import (
 	"github.com/cloudfoundry/bosh-cli/director"
 	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
+	"github.com/cloudfoundry-community/bosh_exporter/api"
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+	"github.com/cloudfoundry-community/bosh_exporter/fakes"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
 
 	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
@@ -33,20 +40,38 @@ var _ = Describe("BoshCollector", func() {
 		tmpfile                  *os.File
 		serviceDiscoveryFilename string
 
-		boshDeployments    []string
-		boshClient         *directorfakes.FakeDirector
-		deploymentsFilter  *filters.DeploymentsFilter
-		deploymentsFetcher *deployments.Fetcher
-		collectorsFilter   *filters.CollectorsFilter
-		azsFilter          *filters.AZsFilter
-		processesFilter    *filters.RegexpFilter
-		boshCollector      *BoshCollector
+		boshDeployments      []string
+		boshClient           *directorfakes.FakeDirector
+		deploymentsFilter    *filters.DeploymentsFilter
+		deploymentsFetcher   *deployments.Fetcher
+		collectorsRegistry   *CollectorsRegistry
+		collectorsFilter     *filters.CollectorsFilter
+		azsFilter            *filters.AZsFilter
+		processesFilter      *filters.ProcessesFilter
+		cidrsFilter          *filters.CIDRsFilter
+		deploymentLabels     *DeploymentLabels
+		stemcellsLifecycle   *StemcellsLifecycle
+		collectorsSubsystems CollectorsSubsystems
+		legacyMetricsNames   bool
+		metricsTimestamps    bool
+		maxScrapeSeries      int
+		boshCollector        *BoshCollector
+
+		serviceDiscoveryRefreshInterval time.Duration
 
 		totalBoshScrapesMetric              prometheus.Counter
 		totalBoshScrapeErrorsMetric         prometheus.Counter
+		scrapeErrorsMetric                  *prometheus.CounterVec
 		lastBoshScrapeErrorMetric           prometheus.Gauge
+		lastBoshScrapePartialMetric         prometheus.Gauge
+		scrapeTruncatedMetric               prometheus.Gauge
 		lastBoshScrapeTimestampMetric       prometheus.Gauge
 		lastBoshScrapeDurationSecondsMetric prometheus.Gauge
+		filteredDeploymentsMetric           prometheus.CounterFunc
+		filteredInstancesMetric             prometheus.CounterFunc
+		filteredProcessesMetric             prometheus.CounterFunc
+		seriesDroppedMetricDesc             *prometheus.Desc
+		seriesPrunedMetric                  prometheus.Counter
 	)
 
 	BeforeEach(func() {
@@ -60,13 +85,33 @@ var _ = Describe("BoshCollector", func() {
 
 		boshDeployments = []string{}
 		boshClient = &directorfakes.FakeDirector{}
-		deploymentsFilter = filters.NewDeploymentsFilter(boshDeployments, boshClient)
-		deploymentsFetcher = deployments.NewFetcher(*deploymentsFilter)
+		deploymentsFilter, err = filters.NewDeploymentsFilter(boshDeployments, boshClient)
+		Expect(err).ToNot(HaveOccurred())
+		jobsFilter, err := filters.NewRegexpFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		azsFilter, err = filters.NewAZsFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		shardFilter, err := filters.NewShardFilter(0, 1)
+		Expect(err).ToNot(HaveOccurred())
+		deploymentsFetcher = deployments.NewFetcher(boshClient, *deploymentsFilter, filters.NewTeamsFilter([]string{}), jobsFilter, azsFilter, shardFilter)
+		collectorsRegistry = NewCollectorsRegistry()
 		collectorsFilter, err = filters.NewCollectorsFilter([]string{})
 		Expect(err).ToNot(HaveOccurred())
-		azsFilter = filters.NewAZsFilter([]string{})
-		processesFilter, err = filters.NewRegexpFilter([]string{})
+		defaultProcessesFilter, err := filters.NewRegexpFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		processesFilter, err = filters.LoadProcessesFilter(defaultProcessesFilter, "")
 		Expect(err).ToNot(HaveOccurred())
+		cidrsFilter, err = filters.NewCIDRsFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		deploymentLabels, err = LoadDeploymentLabels("")
+		Expect(err).ToNot(HaveOccurred())
+		stemcellsLifecycle, err = LoadStemcellsLifecycle("")
+		Expect(err).ToNot(HaveOccurred())
+		collectorsSubsystems = CollectorsSubsystems{}
+		legacyMetricsNames = false
+		metricsTimestamps = false
+		maxScrapeSeries = 0
+		serviceDiscoveryRefreshInterval = 0
 
 		totalBoshScrapesMetric = prometheus.NewCounter(
 			prometheus.CounterOpts{
@@ -98,6 +143,21 @@ var _ = Describe("BoshCollector", func() {
 			},
 		)
 
+		scrapeErrorsMetric = prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "scrape_errors_total",
+				Help:      "Total number of errors scraping BOSH, by collector and kind of error (auth, timeout, task, parse or other).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"collector", "kind"},
+		)
+
 		lastBoshScrapeErrorMetric = prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -114,6 +174,38 @@ var _ = Describe("BoshCollector", func() {
 
 		lastBoshScrapeErrorMetric.Set(float64(0))
 
+		lastBoshScrapePartialMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_scrape_partial",
+				Help:      "Whether the last scrape of metrics from BOSH was cut by the scrape timeout (1 for partial, 0 for complete).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastBoshScrapePartialMetric.Set(float64(0))
+
+		scrapeTruncatedMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "truncated",
+				Help:      "Whether the last scrape of metrics from BOSH was truncated because it exceeded the maximum number of series per scrape (1 for truncated, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		scrapeTruncatedMetric.Set(float64(0))
+
 		lastBoshScrapeTimestampMetric = prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -141,6 +233,76 @@ var _ = Describe("BoshCollector", func() {
 				},
 			},
 		)
+
+		filteredDeploymentsMetric = prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "filtered_deployments_total",
+				Help:      "Total number of BOSH deployments discarded by the deployments and teams filters.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			func() float64 { return 0 },
+		)
+
+		filteredInstancesMetric = prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "filtered_instances_total",
+				Help:      "Total number of BOSH instances discarded by the jobs and AZs filters.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			func() float64 { return 0 },
+		)
+
+		filteredProcessesMetric = prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "filtered_processes_total",
+				Help:      "Total number of BOSH processes discarded by the Service Discovery processes filter.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			func() float64 { return 0 },
+		)
+
+		seriesDroppedMetricDesc = prometheus.NewDesc(
+			"test_exporter_exporter_series_dropped_total",
+			"Total number of series dropped because a collector exceeded the maximum number of series per scrape.",
+			[]string{"collector"},
+			prometheus.Labels{
+				"environment": environment,
+				"bosh_name":   boshName,
+				"bosh_uuid":   boshUUID,
+			},
+		)
+
+		seriesPrunedMetric = prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "exporter",
+				Name:      "series_pruned_total",
+				Help:      "Total number of series no longer exposed because their BOSH deployment, instance or process vanished.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
 	})
 
 	AfterEach(func() {
@@ -150,15 +312,28 @@ var _ = Describe("BoshCollector", func() {
 
 	JustBeforeEach(func() {
 		boshCollector = NewBoshCollector(
-			namespace,
-			environment,
-			boshName,
-			boshUUID,
-			serviceDiscoveryFilename,
+			boshClient,
 			deploymentsFetcher,
-			collectorsFilter,
-			azsFilter,
-			processesFilter,
+			BoshCollectorOptions{
+				Namespace:                       namespace,
+				Environment:                     environment,
+				BoshName:                        boshName,
+				BoshUUID:                        boshUUID,
+				ConstLabels:                     prometheus.Labels{},
+				CollectorsSubsystems:            collectorsSubsystems,
+				LegacyMetricsNames:              legacyMetricsNames,
+				MetricsTimestamps:               metricsTimestamps,
+				CollectorsRegistry:              collectorsRegistry,
+				CollectorsFilter:                collectorsFilter,
+				DeploymentLabels:                deploymentLabels,
+				StemcellsLifecycle:              stemcellsLifecycle,
+				SeriesGuard:                     NewSeriesGuard(namespace, environment, boshName, boshUUID, prometheus.Labels{}, []string{}, 0),
+				MaxScrapeSeries:                 maxScrapeSeries,
+				ServiceDiscoveryFilename:        serviceDiscoveryFilename,
+				ServiceDiscoveryProcessesFilter: processesFilter,
+				ServiceDiscoveryCIDRsFilter:     cidrsFilter,
+				ServiceDiscoveryRefreshInterval: serviceDiscoveryRefreshInterval,
+			},
 		)
 	})
 
@@ -187,6 +362,14 @@ var _ = Describe("BoshCollector", func() {
 			Eventually(descriptions).Should(Receive(Equal(lastBoshScrapeErrorMetric.Desc())))
 		})
 
+		It("returns a last_scrape_partial description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastBoshScrapePartialMetric.Desc())))
+		})
+
+		It("returns a exporter_truncated description", func() {
+			Eventually(descriptions).Should(Receive(Equal(scrapeTruncatedMetric.Desc())))
+		})
+
 		It("returns a last_scrape_timestamp metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(lastBoshScrapeTimestampMetric.Desc())))
 		})
@@ -194,6 +377,66 @@ var _ = Describe("BoshCollector", func() {
 		It("returns a last_scrape_duration_seconds metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(lastBoshScrapeDurationSecondsMetric.Desc())))
 		})
+
+		It("returns a exporter_filtered_deployments_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(filteredDeploymentsMetric.Desc())))
+		})
+
+		It("returns a exporter_filtered_instances_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(filteredInstancesMetric.Desc())))
+		})
+
+		It("returns a exporter_filtered_processes_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(filteredProcessesMetric.Desc())))
+		})
+
+		It("returns a exporter_series_dropped_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(seriesDroppedMetricDesc)))
+		})
+
+		It("returns a exporter_series_pruned_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(seriesPrunedMetric.Desc())))
+		})
+
+		It("returns a exporter_scrape_errors_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, OtherErrorKind).Desc())))
+		})
+
+		It("returns a exporter_collector_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(WithTransform(func(description *prometheus.Desc) string {
+				return description.String()
+			}, ContainSubstring(`fqName: "test_exporter_exporter_collector_duration_seconds"`))))
+		})
+
+		It("returns a exporter_deployment_fetch_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(WithTransform(func(description *prometheus.Desc) string {
+				return description.String()
+			}, ContainSubstring(`fqName: "test_exporter_exporter_deployment_fetch_duration_seconds"`))))
+		})
+
+		Context("when legacy metrics names are enabled", func() {
+			BeforeEach(func() {
+				legacyMetricsNames = true
+			})
+
+			It("returns a legacy job_healthy description", func() {
+				Eventually(descriptions).Should(Receive(WithTransform(func(description *prometheus.Desc) string {
+					return description.String()
+				}, ContainSubstring(`fqName: "bosh_job_healthy"`))))
+			})
+		})
+
+		Context("when there is an exporter subsystem", func() {
+			BeforeEach(func() {
+				collectorsSubsystems = CollectorsSubsystems{ExporterMetrics: "director"}
+			})
+
+			It("returns a director_scrapes_total description", func() {
+				Eventually(descriptions).Should(Receive(WithTransform(func(description *prometheus.Desc) string {
+					return description.String()
+				}, ContainSubstring(`fqName: "test_exporter_director_scrapes_total"`))))
+			})
+		})
 	})
 
 	Describe("Collect", func() {
@@ -221,6 +464,53 @@ var _ = Describe("BoshCollector", func() {
 			Eventually(metrics).Should(Receive(Equal(lastBoshScrapeErrorMetric)))
 		})
 
+		It("returns a last_scrape_partial metric", func() {
+			Eventually(metrics).Should(Receive(Equal(lastBoshScrapePartialMetric)))
+		})
+
+		It("returns a exporter_truncated metric", func() {
+			Eventually(metrics).Should(Receive(Equal(scrapeTruncatedMetric)))
+		})
+
+		Context("when the scrape exceeds the maximum number of series", func() {
+			BeforeEach(func() {
+				maxScrapeSeries = 2
+				scrapeTruncatedMetric.Set(float64(1))
+			})
+
+			It("returns a exporter_truncated metric", func() {
+				Eventually(metrics).Should(Receive(Equal(scrapeTruncatedMetric)))
+			})
+
+			It("still returns the exporter metrics", func() {
+				Eventually(metrics).Should(Receive(Equal(totalBoshScrapesMetric)))
+			})
+		})
+
+		It("does not attach timestamps to the collectors metrics", func() {
+			Eventually(metrics).Should(Receive(SatisfyAll(
+				WithTransform(func(m prometheus.Metric) string { return m.Desc().String() }, ContainSubstring("last_deployments_scrape_timestamp")),
+				WithTransform(metricTimestampMs, BeZero()),
+			)))
+		})
+
+		Context("when metrics timestamps are enabled", func() {
+			BeforeEach(func() {
+				metricsTimestamps = true
+			})
+
+			It("attaches the fetch timestamp to the collectors metrics", func() {
+				Eventually(metrics).Should(Receive(SatisfyAll(
+					WithTransform(func(m prometheus.Metric) string { return m.Desc().String() }, ContainSubstring("last_deployments_scrape_timestamp")),
+					WithTransform(metricTimestampMs, BeNumerically("~", time.Now().UnixNano()/int64(time.Millisecond), 5000)),
+				)))
+			})
+
+			It("does not attach timestamps to the exporter metrics", func() {
+				Eventually(metrics).Should(Receive(Equal(totalBoshScrapesMetric)))
+			})
+		})
+
 		Context("when it fails to get the deployment", func() {
 			BeforeEach(func() {
 				boshClient.DeploymentsReturns([]director.Deployment{}, errors.New("no deployments"))
@@ -236,6 +526,679 @@ var _ = Describe("BoshCollector", func() {
 			It("returns a last_scrape_error metric", func() {
 				Eventually(metrics).Should(Receive(Equal(lastBoshScrapeErrorMetric)))
 			})
+
+			It("returns a exporter_scrape_errors_total metric", func() {
+				scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, OtherErrorKind).Inc()
+				Eventually(metrics).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, OtherErrorKind))))
+			})
+
+			Context("because the credentials are invalid", func() {
+				BeforeEach(func() {
+					boshClient.DeploymentsReturns([]director.Deployment{}, errors.New("Director responded with non-successful status code '401' response 'Not authorized'"))
+				})
+
+				It("returns a auth exporter_scrape_errors_total metric", func() {
+					scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, AuthErrorKind).Inc()
+					Eventually(metrics).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, AuthErrorKind))))
+				})
+			})
+
+			Context("because the director is slow", func() {
+				BeforeEach(func() {
+					boshClient.DeploymentsReturns([]director.Deployment{}, errors.New("net/http: request canceled (Client.Timeout exceeded while awaiting headers)"))
+				})
+
+				It("returns a timeout exporter_scrape_errors_total metric", func() {
+					scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TimeoutErrorKind).Inc()
+					Eventually(metrics).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TimeoutErrorKind))))
+				})
+			})
+
+			Context("because a task failed", func() {
+				BeforeEach(func() {
+					boshClient.DeploymentsReturns([]director.Deployment{}, errors.New("Expected task '42' to succeed but was state is 'timeout'"))
+				})
+
+				It("returns a task exporter_scrape_errors_total metric", func() {
+					scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TaskErrorKind).Inc()
+					Eventually(metrics).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TaskErrorKind))))
+				})
+			})
+
+			Context("because a response cannot be parsed", func() {
+				BeforeEach(func() {
+					boshClient.DeploymentsReturns([]director.Deployment{}, errors.New("Unmarshaling Director response: invalid character 'x'"))
+				})
+
+				It("returns a parse exporter_scrape_errors_total metric", func() {
+					scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, ParseErrorKind).Inc()
+					Eventually(metrics).Should(Receive(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, ParseErrorKind))))
+				})
+			})
+		})
+
+		It("returns a exporter_series_pruned_total metric", func() {
+			Eventually(metrics).Should(Receive(Equal(seriesPrunedMetric)))
+		})
+
+		It("does not return a exporter_director_unsupported metric when the director version is unknown", func() {
+			Consistently(metrics).ShouldNot(Receive(WithTransform(
+				func(m prometheus.Metric) string { return m.Desc().String() },
+				ContainSubstring("director_unsupported"),
+			)))
+		})
+
+		Context("when the director version is not supported", func() {
+			JustBeforeEach(func() {
+				boshCollector = NewBoshCollector(boshClient, deploymentsFetcher, BoshCollectorOptions{
+					Namespace:             namespace,
+					Environment:           environment,
+					BoshName:              boshName,
+					BoshUUID:              boshUUID,
+					DirectorCompatibility: deployments.NewDirectorCompatibility(director.Info{Version: "1.3215.0 (00000000)"}),
+				})
+			})
+
+			It("returns a exporter_director_unsupported metric", func() {
+				metric := collectedMetric(boshCollector, prometheus.NewDesc(
+					"test_exporter_exporter_director_unsupported",
+					"Whether the BOSH Director version is not supported by the exporter (1 for unsupported, 0 for supported).",
+					[]string{"bosh_director_version"},
+					prometheus.Labels{"environment": environment, "bosh_name": boshName, "bosh_uuid": boshUUID},
+				))
+				Expect(metric).ToNot(BeNil())
+
+				m := &dto.Metric{}
+				Expect(metric.Write(m)).To(Succeed())
+				Expect(m.GetGauge().GetValue()).To(Equal(float64(1)))
+			})
+		})
+
+		It("does not return a exporter_tls_certificate_not_after metric when TLS is not served", func() {
+			Expect(collectMetrics(boshCollector)).ToNot(ContainElement(WithTransform(
+				func(m prometheus.Metric) string { return m.Desc().String() },
+				ContainSubstring("tls_certificate_not_after"),
+			)))
+		})
+
+		Context("when the exporter serves TLS", func() {
+			var notAfter time.Time
+
+			JustBeforeEach(func() {
+				notAfter = time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+				boshCollector = NewBoshCollector(boshClient, deploymentsFetcher, BoshCollectorOptions{
+					Namespace:      namespace,
+					Environment:    environment,
+					BoshName:       boshName,
+					BoshUUID:       boshUUID,
+					TLSCertificate: &x509.Certificate{NotAfter: notAfter},
+				})
+			})
+
+			It("returns a exporter_tls_certificate_not_after metric", func() {
+				metric := collectedMetric(boshCollector, prometheus.NewDesc(
+					"test_exporter_exporter_tls_certificate_not_after",
+					"Number of seconds since 1970 since the TLS certificate served by the exporter expired or will expire.",
+					nil,
+					prometheus.Labels{"environment": environment, "bosh_name": boshName, "bosh_uuid": boshUUID},
+				))
+				Expect(metric).ToNot(BeNil())
+
+				m := &dto.Metric{}
+				Expect(metric.Write(m)).To(Succeed())
+				Expect(m.GetGauge().GetValue()).To(Equal(float64(notAfter.Unix())))
+			})
+		})
+
+		Context("when the BOSH Director uses UAA", func() {
+			var uaaTokenStatus *fakeUAATokenStatus
+
+			JustBeforeEach(func() {
+				uaaTokenStatus = &fakeUAATokenStatus{expiry: time.Unix(1500000000, 0), failures: 3}
+				boshCollector = NewBoshCollector(boshClient, deploymentsFetcher, BoshCollectorOptions{
+					Namespace:      namespace,
+					Environment:    environment,
+					BoshName:       boshName,
+					BoshUUID:       boshUUID,
+					UAATokenStatus: uaaTokenStatus,
+				})
+			})
+
+			It("returns a exporter_uaa_token_expiry_timestamp_seconds metric", func() {
+				metric := collectedMetric(boshCollector, prometheus.NewDesc(
+					"test_exporter_exporter_uaa_token_expiry_timestamp_seconds",
+					"Number of seconds since 1970 since the UAA token used to authenticate to the BOSH Director expired or will expire.",
+					nil,
+					prometheus.Labels{"environment": environment, "bosh_name": boshName, "bosh_uuid": boshUUID},
+				))
+				Expect(metric).ToNot(BeNil())
+
+				m := &dto.Metric{}
+				Expect(metric.Write(m)).To(Succeed())
+				Expect(m.GetGauge().GetValue()).To(Equal(float64(1500000000)))
+			})
+
+			It("returns a exporter_uaa_auth_failures_total metric", func() {
+				metric := collectedMetric(boshCollector, prometheus.NewDesc(
+					"test_exporter_exporter_uaa_auth_failures_total",
+					"Total number of failures getting a UAA token to authenticate to the BOSH Director.",
+					nil,
+					prometheus.Labels{"environment": environment, "bosh_name": boshName, "bosh_uuid": boshUUID},
+				))
+				Expect(metric).ToNot(BeNil())
+				Expect(counterValue(metric)).To(Equal(float64(3)))
+			})
+		})
+
+		It("returns a exporter_collector_duration_seconds metric per collector", func() {
+			Eventually(metrics).Should(Receive(SatisfyAll(
+				WithTransform(func(m prometheus.Metric) string { return m.Desc().String() }, ContainSubstring("collector_duration_seconds")),
+				WithTransform(func(m prometheus.Metric) uint64 {
+					metric := &dto.Metric{}
+					Expect(m.Write(metric)).To(Succeed())
+					return metric.GetHistogram().GetSampleCount()
+				}, Equal(uint64(1))),
+			)))
+		})
+
+		Describe("exporter_collector_enabled metric", func() {
+			collectorsEnabled := func() map[string]float64 {
+				enabled := map[string]float64{}
+				for _, m := range collectMetrics(boshCollector) {
+					if !strings.Contains(m.Desc().String(), "exporter_collector_enabled") {
+						continue
+					}
+					metric := &dto.Metric{}
+					Expect(m.Write(metric)).To(Succeed())
+					for _, label := range metric.GetLabel() {
+						if label.GetName() == "collector" {
+							enabled[label.GetValue()] = metric.GetGauge().GetValue()
+						}
+					}
+				}
+				return enabled
+			}
+
+			It("returns whether each collector is enabled", func() {
+				Expect(collectorsEnabled()).To(Equal(map[string]float64{
+					filters.BackupsCollector:          0,
+					filters.DeploymentsCollector:      1,
+					filters.DirectorCollector:         1,
+					filters.ExecCollector:             0,
+					filters.JobsCollector:             1,
+					filters.NetworksCollector:         1,
+					filters.ServiceDiscoveryCollector: 1,
+					filters.SnapshotsCollector:        1,
+				}))
+			})
+
+			Context("when collectors are filtered", func() {
+				BeforeEach(func() {
+					collectorsFilter, err = filters.NewCollectorsFilter([]string{filters.JobsCollector})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("returns the filtered out collectors as disabled", func() {
+					Expect(collectorsEnabled()).To(HaveKeyWithValue(filters.JobsCollector, float64(1)))
+					Expect(collectorsEnabled()).To(HaveKeyWithValue(filters.DeploymentsCollector, float64(0)))
+				})
+			})
+		})
+
+		Context("when a deployment vanishes from BOSH", func() {
+			BeforeEach(func() {
+				boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0, fakes.Process("fake-process-name"))))
+				deploymentsFilter, err = filters.NewDeploymentsFilter([]string{}, boshClient)
+				Expect(err).ToNot(HaveOccurred())
+				deploymentsFetcher = deployments.NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil)
+			})
+
+			It("stops exposing its series and counts them as pruned", func() {
+				Eventually(metrics).Should(Receive(Equal(seriesPrunedMetric)))
+
+				Expect(collectedSeries(boshCollector, "fake-deployment-name")).ToNot(BeEmpty())
+				Expect(counterValue(collectedMetric(boshCollector, seriesPrunedMetric.Desc()))).To(BeZero())
+
+				boshClient.DeploymentsReturns([]director.Deployment{}, nil)
+				Expect(collectedSeries(boshCollector, "fake-deployment-name")).To(BeEmpty())
+				Expect(counterValue(collectedMetric(boshCollector, seriesPrunedMetric.Desc()))).To(BeNumerically(">", 0))
+			})
+		})
+
+		Context("when deployments observers are set", func() {
+			var deploymentsObserver *fakeDeploymentsObserver
+
+			BeforeEach(func() {
+				boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+				deploymentsFilter, err = filters.NewDeploymentsFilter([]string{}, boshClient)
+				Expect(err).ToNot(HaveOccurred())
+				deploymentsFetcher = deployments.NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil)
+			})
+
+			JustBeforeEach(func() {
+				deploymentsObserver = &fakeDeploymentsObserver{err: errors.New("fake-observer-error")}
+				boshCollector = NewBoshCollector(boshClient, deploymentsFetcher, BoshCollectorOptions{
+					Namespace:            namespace,
+					Environment:          environment,
+					BoshName:             boshName,
+					BoshUUID:             boshUUID,
+					DeploymentsObservers: []DeploymentsObserver{deploymentsObserver},
+				})
+			})
+
+			It("notifies them of the fetched deployments without failing the scrape", func() {
+				metric := collectedMetric(boshCollector, lastBoshScrapeErrorMetric.Desc())
+				Expect(metric).ToNot(BeNil())
+
+				m := &dto.Metric{}
+				Expect(metric.Write(m)).To(Succeed())
+				Expect(m.GetGauge().GetValue()).To(BeZero())
+				Expect(deploymentsObserver.observedDeploymentsNames()).To(ContainElement([]string{"fake-deployment-name"}))
+			})
+		})
+
+		Context("when Service Discovery is refreshed in background", func() {
+			BeforeEach(func() {
+				serviceDiscoveryRefreshInterval = time.Hour
+			})
+
+			It("does not write the target groups file on scrape", func() {
+				Eventually(metrics).Should(Receive(Equal(totalBoshScrapesMetric)))
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WithScrapeTimeout", func() {
+		var (
+			timeout                    time.Duration
+			slowDeployments            func(delay time.Duration) func() ([]director.Deployment, error)
+			concurrentDeploymentsCalls func() int
+		)
+
+		BeforeEach(func() {
+			timeout = time.Minute
+
+			var (
+				mutex       sync.Mutex
+				inFlight    int
+				maxInFlight int
+			)
+
+			slowDeployments = func(delay time.Duration) func() ([]director.Deployment, error) {
+				return func() ([]director.Deployment, error) {
+					mutex.Lock()
+					inFlight++
+					if inFlight > maxInFlight {
+						maxInFlight = inFlight
+					}
+					mutex.Unlock()
+
+					time.Sleep(delay)
+
+					mutex.Lock()
+					inFlight--
+					mutex.Unlock()
+					return []director.Deployment{}, nil
+				}
+			}
+
+			concurrentDeploymentsCalls = func() int {
+				mutex.Lock()
+				defer mutex.Unlock()
+				return maxInFlight
+			}
+		})
+
+		isDeploymentsScrapeTimestamp := WithTransform(
+			func(m prometheus.Metric) string { return m.Desc().String() },
+			ContainSubstring("last_deployments_scrape_timestamp"),
+		)
+
+		It("returns the metrics collected in time", func() {
+			metrics := collectMetrics(boshCollector.WithScrapeTimeout(timeout))
+			Expect(metrics).To(ContainElement(isDeploymentsScrapeTimestamp))
+			Expect(metrics).To(ContainElement(Equal(lastBoshScrapePartialMetric)))
+		})
+
+		Context("when the scrape times out", func() {
+			BeforeEach(func() {
+				timeout = 50 * time.Millisecond
+				boshClient.DeploymentsStub = slowDeployments(500 * time.Millisecond)
+
+				lastBoshScrapePartialMetric.Set(float64(1))
+			})
+
+			It("returns the metrics collected before the timeout with a last_scrape_partial metric", func() {
+				metrics := collectMetrics(boshCollector.WithScrapeTimeout(timeout))
+				Expect(metrics).ToNot(ContainElement(isDeploymentsScrapeTimestamp))
+				Expect(metrics).To(ContainElement(Equal(totalBoshScrapesMetric)))
+				Expect(metrics).To(ContainElement(Equal(lastBoshScrapePartialMetric)))
+			})
+
+			It("returns the metrics of the timed out collection on the next scrape instead of collecting concurrently", func() {
+				collectMetrics(boshCollector.WithScrapeTimeout(timeout))
+
+				metrics := collectMetrics(boshCollector.WithScrapeTimeout(time.Minute))
+				Expect(metrics).To(ContainElement(isDeploymentsScrapeTimestamp))
+				Expect(concurrentDeploymentsCalls()).To(Equal(1))
+			})
+		})
+
+		Context("when scrapes are concurrent", func() {
+			BeforeEach(func() {
+				boshClient.DeploymentsStub = slowDeployments(100 * time.Millisecond)
+			})
+
+			It("does not run the collectors concurrently", func() {
+				done := make(chan []prometheus.Metric)
+				go func() { done <- collectMetrics(boshCollector.WithScrapeTimeout(timeout)) }()
+				go func() { done <- collectMetrics(boshCollector) }()
+
+				Expect(<-done).To(ContainElement(isDeploymentsScrapeTimestamp))
+				Expect(<-done).To(ContainElement(isDeploymentsScrapeTimestamp))
+				Expect(concurrentDeploymentsCalls()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("ObserveDeploymentFetch", func() {
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+			deploymentsFilter, err = filters.NewDeploymentsFilter([]string{}, boshClient)
+			Expect(err).ToNot(HaveOccurred())
+			deploymentsFetcher = deployments.NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil)
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetFetchObserver(boshCollector.ObserveDeploymentFetch)
+		})
+
+		It("returns a exporter_deployment_fetch_duration_seconds metric per fetched deployment", func() {
+			Expect(collectedSeries(boshCollector, "fake-deployment-name")).To(ContainElement(WithTransform(
+				func(m prometheus.Metric) string { return m.Desc().String() },
+				ContainSubstring("deployment_fetch_duration_seconds"),
+			)))
+		})
+
+		It("drops the metric of the deployments no longer fetched", func() {
+			boshCollector.ObserveDeploymentFetch("deleted-deployment-name", time.Second)
+
+			Expect(collectedSeries(boshCollector, "deleted-deployment-name")).To(BeEmpty())
+		})
+	})
+
+	Describe("ObserveDeploymentFetchError", func() {
+		It("returns a exporter_scrape_errors_total metric", func() {
+			boshCollector.ObserveDeploymentFetchError("fake-deployment-name", errors.New("Error while reading Releases: timed out"))
+
+			scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TimeoutErrorKind).Inc()
+			Expect(collectMetrics(boshCollector)).To(ContainElement(Equal(scrapeErrorsMetric.WithLabelValues(FetcherErrorCollector, TimeoutErrorKind))))
+		})
+	})
+
+	Describe("DeploymentsDetails", func() {
+		It("returns the details read by the enabled collectors", func() {
+			Expect(boshCollector.DeploymentsDetails()).To(Equal(deployments.AllDetails))
+		})
+
+		Context("when the Deployments collector is not enabled", func() {
+			BeforeEach(func() {
+				collectorsFilter, err = filters.NewCollectorsFilter([]string{filters.JobsCollector})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns only the stemcells", func() {
+				Expect(boshCollector.DeploymentsDetails()).To(Equal(deployments.Details{Stemcells: true}))
+			})
+		})
+
+		Context("when a collector is registered", func() {
+			BeforeEach(func() {
+				err = collectorsRegistry.Register("Fake", func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+					return NewSnapshotsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels)
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns all the details", func() {
+				Expect(boshCollector.DeploymentsDetails()).To(Equal(deployments.AllDetails))
+			})
+		})
+	})
+
+	Describe("EnabledCollectors", func() {
+		It("returns the enabled collectors", func() {
+			Expect(boshCollector.EnabledCollectors()).To(Equal([]string{
+				filters.DeploymentsCollector,
+				filters.DirectorCollector,
+				filters.JobsCollector,
+				filters.NetworksCollector,
+				filters.ServiceDiscoveryCollector,
+				filters.SnapshotsCollector,
+			}))
+		})
+
+		Context("when collectors are filtered", func() {
+			BeforeEach(func() {
+				collectorsFilter, err = filters.NewCollectorsFilter([]string{filters.JobsCollector, filters.BackupsCollector})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns only the filtered collectors", func() {
+				Expect(boshCollector.EnabledCollectors()).To(Equal([]string{filters.JobsCollector}))
+			})
+		})
+
+		Context("when a collector is disabled", func() {
+			BeforeEach(func() {
+				err = collectorsRegistry.SetEnabled(filters.JobsCollector, false)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does not return the disabled collector", func() {
+				Expect(boshCollector.EnabledCollectors()).ToNot(ContainElement(filters.JobsCollector))
+				Expect(boshCollector.EnabledCollectors()).To(ContainElement(filters.DeploymentsCollector))
+			})
+		})
+
+		Context("when a collector is registered", func() {
+			var factoryNamespace string
+
+			BeforeEach(func() {
+				err = collectorsRegistry.Register("Fake", func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+					factoryNamespace = namespace
+					return NewSnapshotsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels)
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns the registered collector", func() {
+				Expect(boshCollector.EnabledCollectors()).To(ContainElement("Fake"))
+				Expect(factoryNamespace).To(Equal(namespace))
+			})
+		})
+
+		Context("when Service Discovery is refreshed in background", func() {
+			BeforeEach(func() {
+				serviceDiscoveryRefreshInterval = time.Hour
+			})
+
+			It("returns the ServiceDiscovery collector", func() {
+				Expect(boshCollector.EnabledCollectors()).To(ContainElement(filters.ServiceDiscoveryCollector))
+			})
+		})
+	})
+
+	Describe("CollectorsStatus", func() {
+		It("has no successful collection before the first scrape", func() {
+			collectorsStatus := boshCollector.CollectorsStatus()
+			Expect(collectorsStatus).To(HaveLen(len(boshCollector.EnabledCollectors())))
+			Expect(collectorsStatus[0].Name).To(Equal(filters.DeploymentsCollector))
+			Expect(collectorsStatus[0].LastSuccessfulCollection.IsZero()).To(BeTrue())
+		})
+
+		It("records the last successful collection of the collectors", func() {
+			collectMetrics(boshCollector)
+
+			Expect(boshCollector.CollectorsStatus()).To(ContainElement(SatisfyAll(
+				WithTransform(func(status api.CollectorStatus) string { return status.Name }, Equal(filters.JobsCollector)),
+				WithTransform(func(status api.CollectorStatus) time.Time { return status.LastSuccessfulCollection }, BeTemporally("~", time.Now(), time.Minute)),
+			)))
+		})
+	})
+
+	Context("when no options are set", func() {
+		JustBeforeEach(func() {
+			boshCollector = NewBoshCollector(boshClient, deploymentsFetcher, BoshCollectorOptions{})
+		})
+
+		It("enables all collectors but Backups", func() {
+			Expect(boshCollector.EnabledCollectors()).To(Equal([]string{
+				filters.DeploymentsCollector,
+				filters.DirectorCollector,
+				filters.JobsCollector,
+				filters.NetworksCollector,
+				filters.ServiceDiscoveryCollector,
+				filters.SnapshotsCollector,
+			}))
+		})
+
+		It("collects the metrics", func() {
+			metrics := make(chan prometheus.Metric)
+			go boshCollector.Collect(metrics)
+			Eventually(metrics).Should(Receive())
+		})
+	})
+
+	Describe("RefreshServiceDiscovery", func() {
+		var (
+			stopCh chan struct{}
+			doneCh chan struct{}
+		)
+
+		BeforeEach(func() {
+			serviceDiscoveryRefreshInterval = 10 * time.Millisecond
+			stopCh = make(chan struct{})
+			doneCh = make(chan struct{})
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				boshCollector.RefreshServiceDiscovery(stopCh)
+				close(doneCh)
+			}()
+		})
+
+		It("writes the target groups file periodically until stopped", func() {
+			Eventually(func() int { return boshClient.DeploymentsCallCount() }).Should(BeNumerically(">=", 2))
+			targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(targetGroups)).To(Equal("[]"))
+
+			close(stopCh)
+			Eventually(doneCh).Should(BeClosed())
+		})
+
+		Context("when Service Discovery is not refreshed in background", func() {
+			BeforeEach(func() {
+				serviceDiscoveryRefreshInterval = 0
+			})
+
+			It("returns immediately", func() {
+				Eventually(doneCh).Should(BeClosed())
+				Expect(boshClient.DeploymentsCallCount()).To(Equal(0))
+			})
 		})
 	})
 })
+
+type fakeDeploymentsObserver struct {
+	mutex    sync.Mutex
+	observed [][]string
+	err      error
+}
+
+func (o *fakeDeploymentsObserver) ObserveDeployments(deploymentsInfo []deployments.DeploymentInfo) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	names := []string{}
+	for _, deployment := range deploymentsInfo {
+		names = append(names, deployment.Name)
+	}
+	o.observed = append(o.observed, names)
+
+	return o.err
+}
+
+func (o *fakeDeploymentsObserver) observedDeploymentsNames() [][]string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.observed
+}
+
+type fakeUAATokenStatus struct {
+	expiry   time.Time
+	failures uint64
+}
+
+func (s *fakeUAATokenStatus) TokenExpiry() time.Time {
+	return s.expiry
+}
+
+func (s *fakeUAATokenStatus) AuthFailures() uint64 {
+	return s.failures
+}
+
+func collectMetrics(collector prometheus.Collector) []prometheus.Metric {
+	metricsCh := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	metrics := []prometheus.Metric{}
+	for metric := range metricsCh {
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+func collectedSeries(collector prometheus.Collector, deploymentName string) []prometheus.Metric {
+	series := []prometheus.Metric{}
+	for _, m := range collectMetrics(collector) {
+		metric := &dto.Metric{}
+		Expect(m.Write(metric)).To(Succeed())
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "bosh_deployment" && label.GetValue() == deploymentName {
+				series = append(series, m)
+			}
+		}
+	}
+	return series
+}
+
+func collectedMetric(collector prometheus.Collector, desc *prometheus.Desc) prometheus.Metric {
+	for _, m := range collectMetrics(collector) {
+		if m.Desc().String() == desc.String() {
+			return m
+		}
+	}
+	return nil
+}
+
+func counterValue(m prometheus.Metric) float64 {
+	metric := &dto.Metric{}
+	Expect(m.Write(metric)).To(Succeed())
+	return metric.GetCounter().GetValue()
+}
+
+func metricTimestampMs(m prometheus.Metric) int64 {
+	metric := &dto.Metric{}
+	Expect(m.Write(metric)).To(Succeed())
+	return metric.GetTimestampMs()
+}
@@ -10,3 +10,16 @@ type Collector interface {
 	Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error
 	Describe(ch chan<- *prometheus.Desc)
 }
+
+func newConstLabels(environment string, boshName string, boshUUID string, constLabels prometheus.Labels) prometheus.Labels {
+	metricConstLabels := prometheus.Labels{
+		"environment": environment,
+		"bosh_name":   boshName,
+		"bosh_uuid":   boshUUID,
+	}
+	for name, value := range constLabels {
+		metricConstLabels[name] = value
+	}
+
+	return metricConstLabels
+}
@@ -0,0 +1,162 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+// CollectorFactory builds a collector exposing its metrics under the given
+// namespace and constant labels. It returns nil if the collector is not
+// configured, i.e. the Backups collector without a backups directory.
+type CollectorFactory func(
+	namespace string,
+	constLabels prometheus.Labels,
+	boshClient director.Director,
+	options BoshCollectorOptions,
+) Collector
+
+// CollectorsRegistry holds the collectors a BoshCollector can run, and
+// whether they are enabled.
+type CollectorsRegistry struct {
+	collectors []*registeredCollector
+}
+
+type registeredCollector struct {
+	name          string
+	enabled       bool
+	legacyMetrics bool
+	factory       CollectorFactory
+}
+
+// NewCollectorsRegistry returns a registry holding the built-in collectors,
+// all of them enabled.
+func NewCollectorsRegistry() *CollectorsRegistry {
+	registry := &CollectorsRegistry{}
+
+	registry.register(filters.BackupsCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		if options.BackupsDirectory == "" {
+			return nil
+		}
+		return NewBackupsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels, options.BackupsDirectory)
+	})
+
+	registry.register(filters.DeploymentsCollector, true, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewDeploymentsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels, options.StemcellsLifecycle)
+	})
+
+	registry.register(filters.DirectorCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewDirectorCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels, boshClient, options.DirectorCompatibility)
+	})
+
+	registry.register(filters.ExecCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		if len(options.ExecCommands) == 0 {
+			return nil
+		}
+		return NewExecCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels, options.ExecCommands, options.ExecTimeout)
+	})
+
+	registry.register(filters.JobsCollector, true, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewJobsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels)
+	})
+
+	registry.register(filters.NetworksCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewNetworksCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels, boshClient)
+	})
+
+	registry.register(filters.ServiceDiscoveryCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewServiceDiscoveryCollector(
+			namespace,
+			options.Environment,
+			options.BoshName,
+			options.BoshUUID,
+			constLabels,
+			options.ServiceDiscoveryFilename,
+			options.ServiceDiscoveryProcessesFilter,
+			options.ServiceDiscoveryCIDRsFilter,
+			options.ServiceDiscoveryNetworksFilter,
+			options.ServiceDiscoveryAllIPs,
+			options.ServiceDiscoveryProcessesPorts,
+			options.ServiceDiscoveryDNSNames,
+			options.DeploymentLabels,
+			options.ServiceLabels,
+			options.ServiceDiscoveryRelabelConfigs,
+			options.ServiceDiscoveryFormat,
+			options.ServiceDiscoveryTemplate,
+			options.ServiceDiscoveryPublishers,
+			options.ServiceDiscoveryLeaderElector,
+		)
+	})
+
+	registry.register(filters.SnapshotsCollector, false, func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+		return NewSnapshotsCollector(namespace, options.Environment, options.BoshName, options.BoshUUID, constLabels)
+	})
+
+	return registry
+}
+
+// Register adds an enabled collector to the registry.
+func (r *CollectorsRegistry) Register(name string, factory CollectorFactory) error {
+	if name == "" {
+		return errors.New("Collector name cannot be empty")
+	}
+
+	if r.collector(name) != nil {
+		return errors.New(fmt.Sprintf("Collector `%s` is already registered", name))
+	}
+
+	r.register(name, false, factory)
+
+	return nil
+}
+
+// Names returns the names of the registered collectors, in registration
+// order.
+func (r *CollectorsRegistry) Names() []string {
+	names := []string{}
+	for _, collector := range r.collectors {
+		names = append(names, collector.name)
+	}
+
+	return names
+}
+
+// SetEnabled enables or disables a registered collector.
+func (r *CollectorsRegistry) SetEnabled(name string, enabled bool) error {
+	collector := r.collector(name)
+	if collector == nil {
+		return errors.New(fmt.Sprintf("Collector `%s` is not registered", name))
+	}
+	collector.enabled = enabled
+
+	return nil
+}
+
+// Enabled returns true if the collector is registered and enabled.
+func (r *CollectorsRegistry) Enabled(name string) bool {
+	collector := r.collector(name)
+	return collector != nil && collector.enabled
+}
+
+func (r *CollectorsRegistry) register(name string, legacyMetrics bool, factory CollectorFactory) {
+	r.collectors = append(r.collectors, &registeredCollector{
+		name:          name,
+		enabled:       true,
+		legacyMetrics: legacyMetrics,
+		factory:       factory,
+	})
+}
+
+func (r *CollectorsRegistry) collector(name string) *registeredCollector {
+	for _, collector := range r.collectors {
+		if collector.name == name {
+			return collector
+		}
+	}
+
+	return nil
+}
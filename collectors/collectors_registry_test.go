@@ -0,0 +1,84 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("CollectorsRegistry", func() {
+	var (
+		collectorsRegistry *CollectorsRegistry
+
+		fakeFactory = func(namespace string, constLabels prometheus.Labels, boshClient director.Director, options BoshCollectorOptions) Collector {
+			return nil
+		}
+	)
+
+	BeforeEach(func() {
+		collectorsRegistry = NewCollectorsRegistry()
+	})
+
+	Describe("Names", func() {
+		It("returns the built-in collectors", func() {
+			Expect(collectorsRegistry.Names()).To(Equal([]string{
+				filters.BackupsCollector,
+				filters.DeploymentsCollector,
+				filters.DirectorCollector,
+				filters.ExecCollector,
+				filters.JobsCollector,
+				filters.NetworksCollector,
+				filters.ServiceDiscoveryCollector,
+				filters.SnapshotsCollector,
+			}))
+		})
+	})
+
+	Describe("Register", func() {
+		It("registers an enabled collector", func() {
+			err := collectorsRegistry.Register("Fake", fakeFactory)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(collectorsRegistry.Names()).To(ContainElement("Fake"))
+			Expect(collectorsRegistry.Enabled("Fake")).To(BeTrue())
+		})
+
+		Context("when the collector is already registered", func() {
+			It("returns an error", func() {
+				err := collectorsRegistry.Register(filters.JobsCollector, fakeFactory)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Collector `Jobs` is already registered"))
+			})
+		})
+
+		Context("when the collector name is empty", func() {
+			It("returns an error", func() {
+				err := collectorsRegistry.Register("", fakeFactory)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("SetEnabled", func() {
+		It("disables a collector", func() {
+			err := collectorsRegistry.SetEnabled(filters.JobsCollector, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(collectorsRegistry.Enabled(filters.JobsCollector)).To(BeFalse())
+			Expect(collectorsRegistry.Enabled(filters.DeploymentsCollector)).To(BeTrue())
+		})
+
+		Context("when the collector is not registered", func() {
+			It("returns an error", func() {
+				err := collectorsRegistry.SetEnabled("Unknown", false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Collector `Unknown` is not registered"))
+				Expect(collectorsRegistry.Enabled("Unknown")).To(BeFalse())
+			})
+		})
+	})
+})
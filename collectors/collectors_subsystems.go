@@ -0,0 +1,53 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+const ExporterMetrics = "Exporter"
+
+type CollectorsSubsystems map[string]string
+
+func NewCollectorsSubsystems(subsystems []string) (CollectorsSubsystems, error) {
+	collectorsSubsystems := CollectorsSubsystems{}
+
+	for _, subsystem := range subsystems {
+		nameSubsystem := strings.SplitN(strings.Trim(subsystem, " "), "=", 2)
+		if len(nameSubsystem) != 2 {
+			return collectorsSubsystems, errors.New(fmt.Sprintf("Subsystem `%s` is not a collector=subsystem pair", subsystem))
+		}
+
+		switch nameSubsystem[0] {
+		case filters.BackupsCollector, filters.DeploymentsCollector, filters.DirectorCollector, filters.ExecCollector, filters.JobsCollector, filters.NetworksCollector, filters.ServiceDiscoveryCollector, filters.SnapshotsCollector, ExporterMetrics:
+		default:
+			return collectorsSubsystems, errors.New(fmt.Sprintf("Subsystem collector `%s` is not supported", nameSubsystem[0]))
+		}
+
+		if !model.IsValidMetricName(model.LabelValue(nameSubsystem[1])) || strings.Contains(nameSubsystem[1], ":") {
+			return collectorsSubsystems, errors.New(fmt.Sprintf("Subsystem `%s` for collector `%s` is not valid", nameSubsystem[1], nameSubsystem[0]))
+		}
+
+		collectorsSubsystems[nameSubsystem[0]] = nameSubsystem[1]
+	}
+
+	return collectorsSubsystems, nil
+}
+
+func (s CollectorsSubsystems) Namespace(namespace string, collectorName string) string {
+	subsystem := s[collectorName]
+	if subsystem == "" {
+		return namespace
+	}
+
+	if namespace == "" {
+		return subsystem
+	}
+
+	return namespace + "_" + subsystem
+}
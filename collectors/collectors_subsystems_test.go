@@ -0,0 +1,78 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("CollectorsSubsystems", func() {
+	var (
+		err                  error
+		subsystems           []string
+		collectorsSubsystems CollectorsSubsystems
+	)
+
+	BeforeEach(func() {
+		subsystems = []string{"Jobs=jobs", "Exporter=director"}
+	})
+
+	JustBeforeEach(func() {
+		collectorsSubsystems, err = NewCollectorsSubsystems(subsystems)
+	})
+
+	Describe("NewCollectorsSubsystems", func() {
+		It("returns the collectors subsystems", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(collectorsSubsystems).To(Equal(CollectorsSubsystems{"Jobs": "jobs", "Exporter": "director"}))
+		})
+
+		Context("when a subsystem is not a pair", func() {
+			BeforeEach(func() {
+				subsystems = []string{"Jobs"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Subsystem `Jobs` is not a collector=subsystem pair"))
+			})
+		})
+
+		Context("when a collector is not supported", func() {
+			BeforeEach(func() {
+				subsystems = []string{"Unknown=unknown"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Subsystem collector `Unknown` is not supported"))
+			})
+		})
+
+		Context("when a subsystem is not valid", func() {
+			BeforeEach(func() {
+				subsystems = []string{"Jobs=my-jobs"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Subsystem `my-jobs` for collector `Jobs` is not valid"))
+			})
+		})
+	})
+
+	Describe("Namespace", func() {
+		It("returns the namespace with the collector subsystem", func() {
+			Expect(collectorsSubsystems.Namespace("bosh", "Jobs")).To(Equal("bosh_jobs"))
+		})
+
+		It("returns the namespace when the collector has no subsystem", func() {
+			Expect(collectorsSubsystems.Namespace("bosh", "Deployments")).To(Equal("bosh"))
+		})
+
+		It("returns the subsystem when there is no namespace", func() {
+			Expect(collectorsSubsystems.Namespace("", "Jobs")).To(Equal("jobs"))
+		})
+	})
+})
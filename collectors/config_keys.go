@@ -0,0 +1,30 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// checkConfigKeys returns an error if the mapping contains a key that is not
+// one of the known keys, so typos in configuration files are not silently
+// ignored.
+func checkConfigKeys(mapping yaml.MapSlice, knownKeys ...string) error {
+	for _, item := range mapping {
+		key := fmt.Sprintf("%v", item.Key)
+
+		known := false
+		for _, knownKey := range knownKeys {
+			if key == knownKey {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return errors.New(fmt.Sprintf("key `%s` is not supported", key))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+const deploymentLabel = "bosh_deployment"
+
+type DeploymentLabels struct {
+	patterns   []deploymentLabelsPattern
+	labelNames []string
+}
+
+type deploymentLabelsPattern struct {
+	pattern string
+	labels  map[string]string
+}
+
+func LoadDeploymentLabels(filename string) (*DeploymentLabels, error) {
+	deploymentLabels := &DeploymentLabels{}
+
+	if filename == "" {
+		return deploymentLabels, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return deploymentLabels, errors.New(fmt.Sprintf("Error reading deployment labels file `%s`: %v", filename, err))
+	}
+
+	var mapping yaml.MapSlice
+	if err = yaml.Unmarshal(content, &mapping); err != nil {
+		return deploymentLabels, errors.New(fmt.Sprintf("Error parsing deployment labels file `%s`: %v", filename, err))
+	}
+
+	labelNames := map[string]bool{}
+	for _, item := range mapping {
+		pattern := fmt.Sprintf("%v", item.Key)
+		if _, err = path.Match(pattern, ""); err != nil {
+			return deploymentLabels, errors.New(fmt.Sprintf("Invalid deployment pattern `%s` at deployment labels file `%s`: %v", pattern, filename, err))
+		}
+
+		values, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return deploymentLabels, errors.New(fmt.Sprintf("Invalid labels for deployment pattern `%s` at deployment labels file `%s`", pattern, filename))
+		}
+
+		labels := map[string]string{}
+		for _, value := range values {
+			name := fmt.Sprintf("%v", value.Key)
+			if !validExtraLabelName(name) {
+				return deploymentLabels, errors.New(fmt.Sprintf("Invalid label name `%s` for deployment pattern `%s` at deployment labels file `%s`", name, pattern, filename))
+			}
+			labels[name] = fmt.Sprintf("%v", value.Value)
+			labelNames[name] = true
+		}
+
+		deploymentLabels.patterns = append(deploymentLabels.patterns, deploymentLabelsPattern{pattern: pattern, labels: labels})
+	}
+
+	for name := range labelNames {
+		deploymentLabels.labelNames = append(deploymentLabels.labelNames, name)
+	}
+	sort.Strings(deploymentLabels.labelNames)
+
+	return deploymentLabels, nil
+}
+
+// validExtraLabelName returns whether name can be used for labels added by
+// the operator, which must not collide with the exporter labels.
+func validExtraLabelName(name string) bool {
+	return model.LabelName(name).IsValid() && !strings.HasPrefix(name, "__") && !strings.HasPrefix(name, "bosh_") && name != "environment"
+}
+
+func (d *DeploymentLabels) LabelNames() []string {
+	return d.labelNames
+}
+
+func (d *DeploymentLabels) Labels(deployment string) map[string]string {
+	labels := map[string]string{}
+	for _, pattern := range d.patterns {
+		if matched, _ := path.Match(pattern.pattern, deployment); !matched {
+			continue
+		}
+
+		for name, value := range pattern.labels {
+			labels[name] = value
+		}
+	}
+
+	return labels
+}
+
+func (d *DeploymentLabels) Apply(collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	if len(d.labelNames) == 0 {
+		return collect(ch)
+	}
+
+	describer := newMetricDescriber()
+	return transformMetrics(collect, ch, func(metric prometheus.Metric) prometheus.Metric {
+		return addMetricLabels(describer, metric, deploymentLabel, d.labelNames, func(labelValues map[string]string) map[string]string {
+			return d.Labels(labelValues[deploymentLabel])
+		})
+	})
+}
@@ -0,0 +1,228 @@
+package collectors_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("DeploymentLabels", func() {
+	var (
+		err              error
+		tmpfile          *os.File
+		filename         string
+		content          string
+		deploymentLabels *DeploymentLabels
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "deployment_labels_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = "cf-*:\n  product: cf\n  tier: dev\ncf-prod:\n  tier: prod\n"
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		deploymentLabels, err = LoadDeploymentLabels(filename)
+	})
+
+	Describe("LoadDeploymentLabels", func() {
+		It("returns the deployment labels", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentLabels.LabelNames()).To(Equal([]string{"product", "tier"}))
+		})
+
+		Context("when there is no filename", func() {
+			BeforeEach(func() {
+				filename = ""
+			})
+
+			It("returns empty deployment labels", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentLabels.LabelNames()).To(BeEmpty())
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			BeforeEach(func() {
+				filename = "/fake-deployment-labels-file"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the file is not valid", func() {
+			BeforeEach(func() {
+				content = "cf-*: [product"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a pattern is not valid", func() {
+			BeforeEach(func() {
+				content = "cf-[:\n  product: cf\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a label name is not valid", func() {
+			BeforeEach(func() {
+				content = "cf-*:\n  bosh_deployment: cf\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Labels", func() {
+		It("returns the labels of all matching patterns", func() {
+			Expect(deploymentLabels.Labels("cf-prod")).To(Equal(map[string]string{"product": "cf", "tier": "prod"}))
+			Expect(deploymentLabels.Labels("cf-dev")).To(Equal(map[string]string{"product": "cf", "tier": "dev"}))
+			Expect(deploymentLabels.Labels("fake-deployment-name")).To(BeEmpty())
+		})
+	})
+
+	Describe("Apply", func() {
+		var (
+			collectErr  error
+			help        string
+			constLabels prometheus.Labels
+			metrics     []*dto.Metric
+		)
+
+		BeforeEach(func() {
+			collectErr = nil
+			help = "BOSH Job Healthy (1 for healthy, 0 for unhealthy)."
+			constLabels = nil
+		})
+
+		JustBeforeEach(func() {
+			jobHealthyMetric := prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace:   "test_exporter",
+					Subsystem:   "job",
+					Name:        "healthy",
+					Help:        help,
+					ConstLabels: constLabels,
+				},
+				[]string{"bosh_deployment", "bosh_job_name"},
+			)
+			jobHealthyMetric.WithLabelValues("cf-prod", "fake-job-name").Set(float64(1))
+
+			lastScrapeTimestampMetric := prometheus.NewGauge(
+				prometheus.GaugeOpts{
+					Namespace: "test_exporter",
+					Name:      "last_jobs_scrape_timestamp",
+					Help:      "Number of seconds since 1970 since last scrape of Job metrics from BOSH.",
+				},
+			)
+
+			ch := make(chan prometheus.Metric)
+			done := make(chan bool)
+			metrics = []*dto.Metric{}
+			go func() {
+				for metric := range ch {
+					var m dto.Metric
+					Expect(metric.Write(&m)).To(Succeed())
+					metrics = append(metrics, &m)
+				}
+				close(done)
+			}()
+
+			err = deploymentLabels.Apply(func(ch chan<- prometheus.Metric) error {
+				jobHealthyMetric.Collect(ch)
+				lastScrapeTimestampMetric.Collect(ch)
+				return collectErr
+			}, ch)
+			close(ch)
+			<-done
+		})
+
+		labels := func(m *dto.Metric) map[string]string {
+			labels := map[string]string{}
+			for _, labelPair := range m.Label {
+				labels[labelPair.GetName()] = labelPair.GetValue()
+			}
+
+			return labels
+		}
+
+		It("adds the deployment labels to metrics with a deployment label", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metrics).To(HaveLen(2))
+			Expect(labels(metrics[0])).To(Equal(map[string]string{
+				"bosh_deployment": "cf-prod",
+				"bosh_job_name":   "fake-job-name",
+				"product":         "cf",
+				"tier":            "prod",
+			}))
+			Expect(metrics[0].Gauge.GetValue()).To(Equal(float64(1)))
+		})
+
+		It("does not modify metrics without a deployment label", func() {
+			Expect(labels(metrics[1])).To(BeEmpty())
+		})
+
+		Context("when the metric help looks like a metric description", func() {
+			BeforeEach(func() {
+				help = `BOSH Job Healthy", constLabels: {}, variableLabels: [bosh_job_name]}`
+			})
+
+			It("adds the deployment labels", func() {
+				Expect(labels(metrics[0])).To(HaveKeyWithValue("product", "cf"))
+				Expect(labels(metrics[0])).To(HaveKeyWithValue("bosh_job_name", "fake-job-name"))
+			})
+		})
+
+		Context("when the metric has constant labels", func() {
+			BeforeEach(func() {
+				constLabels = prometheus.Labels{"environment": "test_environment"}
+			})
+
+			It("keeps them", func() {
+				Expect(labels(metrics[0])).To(Equal(map[string]string{
+					"bosh_deployment": "cf-prod",
+					"bosh_job_name":   "fake-job-name",
+					"environment":     "test_environment",
+					"product":         "cf",
+					"tier":            "prod",
+				}))
+			})
+		})
+
+		Context("when the collector returns an error", func() {
+			BeforeEach(func() {
+				collectErr = errors.New("no bueno")
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package collectors
 
 import (
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -8,11 +11,48 @@ import (
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 )
 
+var deploymentTasksDescriptions = []string{"create deployment", "delete deployment", "recreate", "restart", "start", "stop"}
+
+var activeTasksStates = []string{"queued", "processing", "cancelling"}
+
+var completedTasksStates = []string{"done", "error", "timeout", "cancelled"}
+
+var tasksTypes = []string{"create deployment", "delete deployment", "run errand", "recreate", "restart", "start", "stop", "scan and fix", "snapshot deployment", "fetch logs"}
+
 type DeploymentsCollector struct {
-	deploymentReleaseInfoMetric                *prometheus.GaugeVec
-	deploymentStemcellInfoMetric               *prometheus.GaugeVec
-	lastDeploymentsScrapeTimestampMetric       prometheus.Gauge
-	lastDeploymentsScrapeDurationSecondsMetric prometheus.Gauge
+	deploymentInfoMetric                         *prometheus.GaugeVec
+	deploymentReleaseInfoMetric                  *prometheus.GaugeVec
+	deploymentReleaseOutdatedMetric              *prometheus.GaugeVec
+	deploymentStemcellInfoMetric                 *prometheus.GaugeVec
+	deploymentStemcellCreatedAtMetric            *prometheus.GaugeVec
+	deploymentStemcellEOLAtMetric                *prometheus.GaugeVec
+	deploymentInstancesTotalMetric               *prometheus.GaugeVec
+	deploymentInstancesUnhealthyMetric           *prometheus.GaugeVec
+	deploymentProcessesUnhealthyMetric           *prometheus.GaugeVec
+	deploymentInstancesShortFormatMetric         *prometheus.GaugeVec
+	deploymentTaskInProgressMetric               *prometheus.GaugeVec
+	deploymentManifestSHA1Metric                 *prometheus.GaugeVec
+	deploymentManifestChangedAtMetric            *prometheus.GaugeVec
+	deploymentUpdateCanariesMetric               *prometheus.GaugeVec
+	deploymentUpdateMaxInFlightMetric            *prometheus.GaugeVec
+	deploymentUpdateCanaryWatchTimeSecondsMetric *prometheus.GaugeVec
+	deploymentUpdateWatchTimeSecondsMetric       *prometheus.GaugeVec
+	deploymentJobDesiredInstancesMetric          *prometheus.GaugeVec
+	deploymentJobActualInstancesMetric           *prometheus.GaugeVec
+	taskDurationSecondsMetric                    *prometheus.HistogramVec
+	lastDeploymentsScrapeTimestampMetric         prometheus.Gauge
+	lastDeploymentsScrapeDurationSecondsMetric   prometheus.Gauge
+	stemcellsLifecycle                           *StemcellsLifecycle
+	observedTasks                                map[int]bool
+	observedTasksMutex                           *sync.Mutex
+	observedManifests                            map[string]observedManifest
+	observedManifestsMutex                       *sync.Mutex
+	descs                                        metricsDescs
+}
+
+type observedManifest struct {
+	sha1      string
+	changedAt time.Time
 }
 
 func NewDeploymentsCollector(
@@ -20,70 +60,282 @@ func NewDeploymentsCollector(
 	environment string,
 	boshName string,
 	boshUUID string,
+	constLabels prometheus.Labels,
+	stemcellsLifecycle *StemcellsLifecycle,
 ) *DeploymentsCollector {
-	deploymentReleaseInfoMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "deployment",
-			Name:      "release_info",
-			Help:      "Labeled BOSH Deployment Release Info with a constant '1' value.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	deploymentInfoMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "info",
+			Help:        "Labeled BOSH Deployment Info with a constant '1' value.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_teams", "bosh_stemcells", "bosh_releases"},
+	)
+
+	deploymentReleaseInfoMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "release_info",
+			Help:        "Labeled BOSH Deployment Release Info with a constant '1' value.",
+			ConstLabels: metricConstLabels,
 		},
 		[]string{"bosh_deployment", "bosh_release_name", "bosh_release_version"},
 	)
 
-	deploymentStemcellInfoMetric := prometheus.NewGaugeVec(
+	deploymentReleaseOutdatedMetric := descs.newGaugeVec(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "deployment",
-			Name:      "stemcell_info",
-			Help:      "Labeled BOSH Deployment Stemcell Info with a constant '1' value.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "release_outdated",
+			Help:        "BOSH Deployment Release Outdated (1 if a newer release version is uploaded to the director, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_release_name"},
+	)
+
+	deploymentStemcellInfoMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "stemcell_info",
+			Help:        "Labeled BOSH Deployment Stemcell Info with a constant '1' value.",
+			ConstLabels: metricConstLabels,
 		},
 		[]string{"bosh_deployment", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name"},
 	)
 
-	lastDeploymentsScrapeTimestampMetric := prometheus.NewGauge(
+	deploymentStemcellCreatedAtMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "stemcell_created_at",
+			Help:        "Number of seconds since 1970 since the BOSH Deployment Stemcell was created.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name"},
+	)
+
+	deploymentStemcellEOLAtMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "stemcell_eol_at",
+			Help:        "Number of seconds since 1970 since the BOSH Deployment Stemcell reached or will reach its end of life.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name"},
+	)
+
+	deploymentInstancesTotalMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "instances_total",
+			Help:        "Number of BOSH Deployment Instances.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentInstancesUnhealthyMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "instances_unhealthy",
+			Help:        "Number of unhealthy BOSH Deployment Instances.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentProcessesUnhealthyMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "processes_unhealthy",
+			Help:        "Number of unhealthy BOSH Deployment Processes.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentInstancesShortFormatMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "instances_short_format",
+			Help:        "BOSH Deployment Instances Short Format (1 if the instances were read in short format, without their state, vitals and processes, because reading them in full format timed out, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentTaskInProgressMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "task_in_progress",
+			Help:        "BOSH Deployment Task in Progress (1 if a deploy, recreate, restart, start, stop or delete task is queued or processing, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentManifestSHA1Metric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "manifest_sha1",
+			Help:        "Labeled BOSH Deployment Manifest SHA1 with a constant '1' value.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_manifest_sha1"},
+	)
+
+	deploymentManifestChangedAtMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "manifest_changed_at",
+			Help:        "Number of seconds since 1970 since the BOSH Deployment Manifest was last seen changing.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment"},
+	)
+
+	deploymentUpdateCanariesMetric := descs.newGaugeVec(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_deployments_scrape_timestamp",
-			Help:      "Number of seconds since 1970 since last scrape of Deployments metrics from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "update_canaries",
+			Help:        "Number of canary instances of the BOSH Deployment Instance Group update.",
+			ConstLabels: metricConstLabels,
 		},
+		[]string{"bosh_deployment", "bosh_job_name"},
 	)
 
-	lastDeploymentsScrapeDurationSecondsMetric := prometheus.NewGauge(
+	deploymentUpdateMaxInFlightMetric := descs.newGaugeVec(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_deployments_scrape_duration_seconds",
-			Help:      "Duration of the last scrape of Deployments metrics from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "update_max_in_flight",
+			Help:        "Maximum number of non-canary instances of the BOSH Deployment Instance Group updated in parallel.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name"},
+	)
+
+	deploymentUpdateCanaryWatchTimeSecondsMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "update_canary_watch_time_seconds",
+			Help:        "Maximum time in seconds the BOSH Director waits for a canary instance of the BOSH Deployment Instance Group to become healthy.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name"},
+	)
+
+	deploymentUpdateWatchTimeSecondsMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "update_watch_time_seconds",
+			Help:        "Maximum time in seconds the BOSH Director waits for a non-canary instance of the BOSH Deployment Instance Group to become healthy.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name"},
+	)
+
+	deploymentJobDesiredInstancesMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "job_desired_instances",
+			Help:        "Number of instances of the BOSH Deployment Instance Group declared at the manifest.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name"},
+	)
+
+	deploymentJobActualInstancesMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "deployment",
+			Name:        "job_actual_instances",
+			Help:        "Number of instances of the BOSH Deployment Instance Group reported by BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name"},
+	)
+
+	taskDurationSecondsMetric := descs.newHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Subsystem:   "task",
+			Name:        "duration_seconds",
+			Help:        "Duration of the completed BOSH Deployment Tasks.",
+			Buckets:     prometheus.ExponentialBuckets(30, 2, 10),
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_task_type"},
+	)
+
+	lastDeploymentsScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_deployments_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Deployments metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastDeploymentsScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_deployments_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Deployments metrics from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
 	collector := &DeploymentsCollector{
-		deploymentReleaseInfoMetric:                deploymentReleaseInfoMetric,
-		deploymentStemcellInfoMetric:               deploymentStemcellInfoMetric,
-		lastDeploymentsScrapeTimestampMetric:       lastDeploymentsScrapeTimestampMetric,
-		lastDeploymentsScrapeDurationSecondsMetric: lastDeploymentsScrapeDurationSecondsMetric,
+		deploymentInfoMetric:                         deploymentInfoMetric,
+		deploymentReleaseInfoMetric:                  deploymentReleaseInfoMetric,
+		deploymentReleaseOutdatedMetric:              deploymentReleaseOutdatedMetric,
+		deploymentStemcellInfoMetric:                 deploymentStemcellInfoMetric,
+		deploymentStemcellCreatedAtMetric:            deploymentStemcellCreatedAtMetric,
+		deploymentStemcellEOLAtMetric:                deploymentStemcellEOLAtMetric,
+		deploymentInstancesTotalMetric:               deploymentInstancesTotalMetric,
+		deploymentInstancesUnhealthyMetric:           deploymentInstancesUnhealthyMetric,
+		deploymentProcessesUnhealthyMetric:           deploymentProcessesUnhealthyMetric,
+		deploymentInstancesShortFormatMetric:         deploymentInstancesShortFormatMetric,
+		deploymentTaskInProgressMetric:               deploymentTaskInProgressMetric,
+		deploymentManifestSHA1Metric:                 deploymentManifestSHA1Metric,
+		deploymentManifestChangedAtMetric:            deploymentManifestChangedAtMetric,
+		deploymentUpdateCanariesMetric:               deploymentUpdateCanariesMetric,
+		deploymentUpdateMaxInFlightMetric:            deploymentUpdateMaxInFlightMetric,
+		deploymentUpdateCanaryWatchTimeSecondsMetric: deploymentUpdateCanaryWatchTimeSecondsMetric,
+		deploymentUpdateWatchTimeSecondsMetric:       deploymentUpdateWatchTimeSecondsMetric,
+		deploymentJobDesiredInstancesMetric:          deploymentJobDesiredInstancesMetric,
+		deploymentJobActualInstancesMetric:           deploymentJobActualInstancesMetric,
+		taskDurationSecondsMetric:                    taskDurationSecondsMetric,
+		lastDeploymentsScrapeTimestampMetric:         lastDeploymentsScrapeTimestampMetric,
+		lastDeploymentsScrapeDurationSecondsMetric:   lastDeploymentsScrapeDurationSecondsMetric,
+		stemcellsLifecycle:                           stemcellsLifecycle,
+		observedTasks:                                map[int]bool{},
+		observedTasksMutex:                           &sync.Mutex{},
+		observedManifests:                            map[string]observedManifest{},
+		observedManifestsMutex:                       &sync.Mutex{},
+		descs:                                        descs,
 	}
 	return collector
 }
@@ -91,16 +343,59 @@ func NewDeploymentsCollector(
 func (c *DeploymentsCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
 	var begun = time.Now()
 
+	c.deploymentInfoMetric.Reset()
 	c.deploymentReleaseInfoMetric.Reset()
+	c.deploymentReleaseOutdatedMetric.Reset()
 	c.deploymentStemcellInfoMetric.Reset()
+	c.deploymentStemcellCreatedAtMetric.Reset()
+	c.deploymentStemcellEOLAtMetric.Reset()
+	c.deploymentInstancesTotalMetric.Reset()
+	c.deploymentInstancesUnhealthyMetric.Reset()
+	c.deploymentProcessesUnhealthyMetric.Reset()
+	c.deploymentInstancesShortFormatMetric.Reset()
+	c.deploymentTaskInProgressMetric.Reset()
+	c.deploymentManifestSHA1Metric.Reset()
+	c.deploymentManifestChangedAtMetric.Reset()
+	c.deploymentUpdateCanariesMetric.Reset()
+	c.deploymentUpdateMaxInFlightMetric.Reset()
+	c.deploymentUpdateCanaryWatchTimeSecondsMetric.Reset()
+	c.deploymentUpdateWatchTimeSecondsMetric.Reset()
+	c.deploymentJobDesiredInstancesMetric.Reset()
+	c.deploymentJobActualInstancesMetric.Reset()
+
+	c.reportTaskDurationMetrics(deployments, ch)
+	c.reportDeploymentManifestMetrics(deployments, ch)
 
 	for _, deployment := range deployments {
+		c.reportDeploymentInfoMetrics(deployment, ch)
 		c.reportDeploymentReleaseInfoMetrics(deployment, ch)
 		c.reportDeploymentStemcellInfoMetrics(deployment, ch)
+		c.reportDeploymentHealthMetrics(deployment, ch)
+		c.reportDeploymentTaskInProgressMetrics(deployment, ch)
+		c.reportDeploymentUpdateMetrics(deployment, ch)
+		c.reportDeploymentJobInstancesMetrics(deployment, ch)
 	}
 
+	c.deploymentInfoMetric.Collect(ch)
 	c.deploymentReleaseInfoMetric.Collect(ch)
+	c.deploymentReleaseOutdatedMetric.Collect(ch)
 	c.deploymentStemcellInfoMetric.Collect(ch)
+	c.deploymentStemcellCreatedAtMetric.Collect(ch)
+	c.deploymentStemcellEOLAtMetric.Collect(ch)
+	c.deploymentInstancesTotalMetric.Collect(ch)
+	c.deploymentInstancesUnhealthyMetric.Collect(ch)
+	c.deploymentProcessesUnhealthyMetric.Collect(ch)
+	c.deploymentInstancesShortFormatMetric.Collect(ch)
+	c.deploymentTaskInProgressMetric.Collect(ch)
+	c.deploymentManifestSHA1Metric.Collect(ch)
+	c.deploymentManifestChangedAtMetric.Collect(ch)
+	c.deploymentUpdateCanariesMetric.Collect(ch)
+	c.deploymentUpdateMaxInFlightMetric.Collect(ch)
+	c.deploymentUpdateCanaryWatchTimeSecondsMetric.Collect(ch)
+	c.deploymentUpdateWatchTimeSecondsMetric.Collect(ch)
+	c.deploymentJobDesiredInstancesMetric.Collect(ch)
+	c.deploymentJobActualInstancesMetric.Collect(ch)
+	c.taskDurationSecondsMetric.Collect(ch)
 
 	c.lastDeploymentsScrapeTimestampMetric.Set(float64(time.Now().Unix()))
 	c.lastDeploymentsScrapeTimestampMetric.Collect(ch)
@@ -112,12 +407,57 @@ func (c *DeploymentsCollector) Collect(deployments []deployments.DeploymentInfo,
 }
 
 func (c *DeploymentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.deploymentInfoMetric.Describe(ch)
 	c.deploymentReleaseInfoMetric.Describe(ch)
+	c.deploymentReleaseOutdatedMetric.Describe(ch)
 	c.deploymentStemcellInfoMetric.Describe(ch)
+	c.deploymentStemcellCreatedAtMetric.Describe(ch)
+	c.deploymentStemcellEOLAtMetric.Describe(ch)
+	c.deploymentInstancesTotalMetric.Describe(ch)
+	c.deploymentInstancesUnhealthyMetric.Describe(ch)
+	c.deploymentProcessesUnhealthyMetric.Describe(ch)
+	c.deploymentInstancesShortFormatMetric.Describe(ch)
+	c.deploymentTaskInProgressMetric.Describe(ch)
+	c.deploymentManifestSHA1Metric.Describe(ch)
+	c.deploymentManifestChangedAtMetric.Describe(ch)
+	c.deploymentUpdateCanariesMetric.Describe(ch)
+	c.deploymentUpdateMaxInFlightMetric.Describe(ch)
+	c.deploymentUpdateCanaryWatchTimeSecondsMetric.Describe(ch)
+	c.deploymentUpdateWatchTimeSecondsMetric.Describe(ch)
+	c.deploymentJobDesiredInstancesMetric.Describe(ch)
+	c.deploymentJobActualInstancesMetric.Describe(ch)
+	c.taskDurationSecondsMetric.Describe(ch)
 	c.lastDeploymentsScrapeTimestampMetric.Describe(ch)
 	c.lastDeploymentsScrapeDurationSecondsMetric.Describe(ch)
 }
 
+func (c *DeploymentsCollector) reportDeploymentInfoMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	teams := append([]string{}, deployment.Teams...)
+	sort.Strings(teams)
+
+	stemcells := []string{}
+	for _, stemcell := range deployment.Stemcells {
+		stemcells = append(stemcells, stemcell.OSName+"/"+stemcell.Version)
+	}
+	sort.Strings(stemcells)
+
+	releases := []string{}
+	for _, release := range deployment.Releases {
+		releases = append(releases, release.Name+"/"+release.Version)
+	}
+	sort.Strings(releases)
+
+	c.deploymentInfoMetric.WithLabelValues(
+		deployment.Name,
+		strings.Join(teams, ","),
+		strings.Join(stemcells, ","),
+		strings.Join(releases, ","),
+	).Set(float64(1))
+}
+
 func (c *DeploymentsCollector) reportDeploymentReleaseInfoMetrics(
 	deployment deployments.DeploymentInfo,
 	ch chan<- prometheus.Metric,
@@ -128,6 +468,15 @@ func (c *DeploymentsCollector) reportDeploymentReleaseInfoMetrics(
 			release.Name,
 			release.Version,
 		).Set(float64(1))
+
+		var outdated float64
+		if release.Outdated {
+			outdated = 1
+		}
+		c.deploymentReleaseOutdatedMetric.WithLabelValues(
+			deployment.Name,
+			release.Name,
+		).Set(outdated)
 	}
 }
 
@@ -142,5 +491,225 @@ func (c *DeploymentsCollector) reportDeploymentStemcellInfoMetrics(
 			stemcell.Version,
 			stemcell.OSName,
 		).Set(float64(1))
+
+		lifecycle := c.stemcellsLifecycle.Lifecycle(stemcell.OSName, stemcell.Version)
+		if !lifecycle.CreatedAt.IsZero() {
+			c.deploymentStemcellCreatedAtMetric.WithLabelValues(
+				deployment.Name,
+				stemcell.Name,
+				stemcell.Version,
+				stemcell.OSName,
+			).Set(float64(lifecycle.CreatedAt.Unix()))
+		}
+		if !lifecycle.EOL.IsZero() {
+			c.deploymentStemcellEOLAtMetric.WithLabelValues(
+				deployment.Name,
+				stemcell.Name,
+				stemcell.Version,
+				stemcell.OSName,
+			).Set(float64(lifecycle.EOL.Unix()))
+		}
+	}
+}
+
+func (c *DeploymentsCollector) reportDeploymentHealthMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	var instancesUnhealthy, processesUnhealthy float64
+	for _, instance := range deployment.Instances {
+		if !instance.Healthy {
+			instancesUnhealthy++
+		}
+
+		for _, process := range instance.Processes {
+			if !process.Healthy {
+				processesUnhealthy++
+			}
+		}
+	}
+
+	c.deploymentInstancesTotalMetric.WithLabelValues(deployment.Name).Set(float64(len(deployment.Instances)))
+
+	// The health of instances read in short format is unknown.
+	if deployment.InstancesShortFormat {
+		c.deploymentInstancesShortFormatMetric.WithLabelValues(deployment.Name).Set(1)
+		return
+	}
+	c.deploymentInstancesShortFormatMetric.WithLabelValues(deployment.Name).Set(0)
+	c.deploymentInstancesUnhealthyMetric.WithLabelValues(deployment.Name).Set(instancesUnhealthy)
+	c.deploymentProcessesUnhealthyMetric.WithLabelValues(deployment.Name).Set(processesUnhealthy)
+}
+
+func (c *DeploymentsCollector) reportDeploymentTaskInProgressMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	if deployment.UnavailableDetails.Tasks {
+		return
+	}
+
+	var inProgress float64
+	if _, ok := DeploymentTaskInProgress(deployment); ok {
+		inProgress = 1
+	}
+
+	c.deploymentTaskInProgressMetric.WithLabelValues(deployment.Name).Set(inProgress)
+}
+
+// DeploymentTaskInProgress returns the task deploying, deleting, starting,
+// stopping or restarting the deployment, if one is in progress.
+func DeploymentTaskInProgress(deployment deployments.DeploymentInfo) (deployments.Task, bool) {
+	for _, task := range deployment.Tasks {
+		if !containsString(activeTasksStates, task.State) {
+			continue
+		}
+
+		for _, description := range deploymentTasksDescriptions {
+			if strings.HasPrefix(task.Description, description) {
+				return task, true
+			}
+		}
+	}
+
+	return deployments.Task{}, false
+}
+
+func (c *DeploymentsCollector) reportDeploymentManifestMetrics(
+	deployments []deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	c.observedManifestsMutex.Lock()
+	defer c.observedManifestsMutex.Unlock()
+
+	now := time.Now()
+	observedManifests := map[string]observedManifest{}
+	for _, deployment := range deployments {
+		if deployment.ManifestSHA1 == "" {
+			continue
+		}
+
+		manifest, ok := c.observedManifests[deployment.Name]
+		if !ok || manifest.sha1 != deployment.ManifestSHA1 {
+			manifest = observedManifest{sha1: deployment.ManifestSHA1, changedAt: now}
+		}
+		observedManifests[deployment.Name] = manifest
+
+		c.deploymentManifestSHA1Metric.WithLabelValues(
+			deployment.Name,
+			manifest.sha1,
+		).Set(float64(1))
+
+		c.deploymentManifestChangedAtMetric.WithLabelValues(
+			deployment.Name,
+		).Set(float64(manifest.changedAt.Unix()))
 	}
+	c.observedManifests = observedManifests
+}
+
+func (c *DeploymentsCollector) reportDeploymentUpdateMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	for _, instanceGroup := range deployment.InstanceGroups {
+		c.deploymentUpdateCanariesMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroup.Name,
+		).Set(float64(instanceGroup.Update.Canaries))
+
+		c.deploymentUpdateMaxInFlightMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroup.Name,
+		).Set(float64(instanceGroup.Update.MaxInFlight))
+
+		c.deploymentUpdateCanaryWatchTimeSecondsMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroup.Name,
+		).Set(instanceGroup.Update.CanaryWatchTime.Seconds())
+
+		c.deploymentUpdateWatchTimeSecondsMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroup.Name,
+		).Set(instanceGroup.Update.UpdateWatchTime.Seconds())
+	}
+}
+
+func (c *DeploymentsCollector) reportDeploymentJobInstancesMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	actualInstances := map[string]int{}
+	for _, instanceGroup := range deployment.InstanceGroups {
+		c.deploymentJobDesiredInstancesMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroup.Name,
+		).Set(float64(instanceGroup.Instances))
+
+		actualInstances[instanceGroup.Name] = 0
+	}
+
+	// the actual instances are counted before the jobs and AZs filters, as
+	// the desired ones
+	for instanceGroupName, instances := range deployment.ActualInstances {
+		actualInstances[instanceGroupName] = instances
+	}
+
+	for instanceGroupName, instances := range actualInstances {
+		c.deploymentJobActualInstancesMetric.WithLabelValues(
+			deployment.Name,
+			instanceGroupName,
+		).Set(float64(instances))
+	}
+}
+
+func (c *DeploymentsCollector) reportTaskDurationMetrics(
+	deployments []deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	c.observedTasksMutex.Lock()
+	defer c.observedTasksMutex.Unlock()
+
+	observedTasks := map[int]bool{}
+	for _, deployment := range deployments {
+		for _, task := range deployment.Tasks {
+			if !containsString(completedTasksStates, task.State) {
+				continue
+			}
+
+			observedTasks[task.ID] = true
+			if c.observedTasks[task.ID] {
+				continue
+			}
+
+			c.taskDurationSecondsMetric.WithLabelValues(
+				deployment.Name,
+				taskType(task.Description),
+			).Observe(task.LastActivityAt.Sub(task.StartedAt).Seconds())
+		}
+	}
+	c.observedTasks = observedTasks
+}
+
+func taskType(description string) string {
+	for _, tasksType := range tasksTypes {
+		if strings.HasPrefix(description, tasksType) {
+			return strings.Replace(tasksType, " ", "_", -1)
+		}
+	}
+
+	return "other"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *DeploymentsCollector) descriptions() metricsDescs {
+	return c.descs
 }
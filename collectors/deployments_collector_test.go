@@ -1,10 +1,15 @@
 package collectors_test
 
 import (
+	"io/ioutil"
+	"os"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 
@@ -17,19 +22,42 @@ var _ = Describe("DeploymentsCollector", func() {
 		environment          string
 		boshName             string
 		boshUUID             string
+		constLabels          prometheus.Labels
+		stemcellsLifecycle   *StemcellsLifecycle
 		deploymentsCollector *DeploymentsCollector
 
-		deploymentReleaseInfoMetric                *prometheus.GaugeVec
-		deploymentStemcellInfoMetric               *prometheus.GaugeVec
-		lastDeploymentsScrapeTimestampMetric       prometheus.Gauge
-		lastDeploymentsScrapeDurationSecondsMetric prometheus.Gauge
+		deploymentInfoMetric                         *prometheus.GaugeVec
+		deploymentReleaseInfoMetric                  *prometheus.GaugeVec
+		deploymentReleaseOutdatedMetric              *prometheus.GaugeVec
+		deploymentStemcellInfoMetric                 *prometheus.GaugeVec
+		deploymentStemcellCreatedAtMetric            *prometheus.GaugeVec
+		deploymentStemcellEOLAtMetric                *prometheus.GaugeVec
+		deploymentInstancesTotalMetric               *prometheus.GaugeVec
+		deploymentInstancesUnhealthyMetric           *prometheus.GaugeVec
+		deploymentProcessesUnhealthyMetric           *prometheus.GaugeVec
+		deploymentInstancesShortFormatMetric         *prometheus.GaugeVec
+		deploymentTaskInProgressMetric               *prometheus.GaugeVec
+		deploymentManifestSHA1Metric                 *prometheus.GaugeVec
+		deploymentManifestChangedAtMetric            *prometheus.GaugeVec
+		deploymentUpdateCanariesMetric               *prometheus.GaugeVec
+		deploymentUpdateMaxInFlightMetric            *prometheus.GaugeVec
+		deploymentUpdateCanaryWatchTimeSecondsMetric *prometheus.GaugeVec
+		deploymentUpdateWatchTimeSecondsMetric       *prometheus.GaugeVec
+		deploymentJobDesiredInstancesMetric          *prometheus.GaugeVec
+		deploymentJobActualInstancesMetric           *prometheus.GaugeVec
+		taskDurationSecondsMetric                    *prometheus.HistogramVec
+		lastDeploymentsScrapeTimestampMetric         prometheus.Gauge
+		lastDeploymentsScrapeDurationSecondsMetric   prometheus.Gauge
 
 		deploymentName  = "fake-deployment-name"
+		deploymentTeam  = "fake-deployment-team"
 		releaseName     = "fake-release-name"
 		releaseVersion  = "1.2.3"
 		stemcellName    = "fake-stemcell-name"
 		stemcellVersion = "4.5.6"
 		stemcellOSName  = "fake-stemcell-os-name"
+		jobName         = "fake-job-name"
+		manifestSHA1    = "fake-manifest-sha1"
 	)
 
 	BeforeEach(func() {
@@ -37,6 +65,30 @@ var _ = Describe("DeploymentsCollector", func() {
 		environment = "test_environment"
 		boshName = "test_bosh_name"
 		boshUUID = "test_bosh_uuid"
+		constLabels = prometheus.Labels{}
+		stemcellsLifecycle = &StemcellsLifecycle{}
+
+		deploymentInfoMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "info",
+				Help:      "Labeled BOSH Deployment Info with a constant '1' value.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_teams", "bosh_stemcells", "bosh_releases"},
+		)
+
+		deploymentInfoMetric.WithLabelValues(
+			deploymentName,
+			deploymentTeam,
+			stemcellOSName+"/"+stemcellVersion,
+			releaseName+"/"+releaseVersion,
+		).Set(float64(1))
 
 		deploymentReleaseInfoMetric = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -59,6 +111,21 @@ var _ = Describe("DeploymentsCollector", func() {
 			releaseVersion,
 		).Set(float64(1))
 
+		deploymentReleaseOutdatedMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "release_outdated",
+				Help:      "BOSH Deployment Release Outdated (1 if a newer release version is uploaded to the director, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_release_name"},
+		)
+
 		deploymentStemcellInfoMetric = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -81,6 +148,247 @@ var _ = Describe("DeploymentsCollector", func() {
 			stemcellOSName,
 		).Set(float64(1))
 
+		deploymentStemcellCreatedAtMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "stemcell_created_at",
+				Help:      "Number of seconds since 1970 since the BOSH Deployment Stemcell was created.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name"},
+		)
+
+		deploymentStemcellEOLAtMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "stemcell_eol_at",
+				Help:      "Number of seconds since 1970 since the BOSH Deployment Stemcell reached or will reach its end of life.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name"},
+		)
+
+		deploymentInstancesTotalMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "instances_total",
+				Help:      "Number of BOSH Deployment Instances.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentInstancesUnhealthyMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "instances_unhealthy",
+				Help:      "Number of unhealthy BOSH Deployment Instances.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentProcessesUnhealthyMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "processes_unhealthy",
+				Help:      "Number of unhealthy BOSH Deployment Processes.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentInstancesShortFormatMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "instances_short_format",
+				Help:      "BOSH Deployment Instances Short Format (1 if the instances were read in short format, without their state, vitals and processes, because reading them in full format timed out, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentTaskInProgressMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "task_in_progress",
+				Help:      "BOSH Deployment Task in Progress (1 if a deploy, recreate, restart, start, stop or delete task is queued or processing, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentManifestSHA1Metric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "manifest_sha1",
+				Help:      "Labeled BOSH Deployment Manifest SHA1 with a constant '1' value.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_manifest_sha1"},
+		)
+
+		deploymentManifestChangedAtMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "manifest_changed_at",
+				Help:      "Number of seconds since 1970 since the BOSH Deployment Manifest was last seen changing.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment"},
+		)
+
+		deploymentUpdateCanariesMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "update_canaries",
+				Help:      "Number of canary instances of the BOSH Deployment Instance Group update.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		deploymentUpdateMaxInFlightMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "update_max_in_flight",
+				Help:      "Maximum number of non-canary instances of the BOSH Deployment Instance Group updated in parallel.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		deploymentUpdateCanaryWatchTimeSecondsMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "update_canary_watch_time_seconds",
+				Help:      "Maximum time in seconds the BOSH Director waits for a canary instance of the BOSH Deployment Instance Group to become healthy.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		deploymentUpdateWatchTimeSecondsMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "update_watch_time_seconds",
+				Help:      "Maximum time in seconds the BOSH Director waits for a non-canary instance of the BOSH Deployment Instance Group to become healthy.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		deploymentJobDesiredInstancesMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "job_desired_instances",
+				Help:      "Number of instances of the BOSH Deployment Instance Group declared at the manifest.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		deploymentJobActualInstancesMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "deployment",
+				Name:      "job_actual_instances",
+				Help:      "Number of instances of the BOSH Deployment Instance Group reported by BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name"},
+		)
+
+		taskDurationSecondsMetric = prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: "task",
+				Name:      "duration_seconds",
+				Help:      "Duration of the completed BOSH Deployment Tasks.",
+				Buckets:   prometheus.ExponentialBuckets(30, 2, 10),
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_task_type"},
+		)
+
 		lastDeploymentsScrapeTimestampMetric = prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -116,6 +424,8 @@ var _ = Describe("DeploymentsCollector", func() {
 			environment,
 			boshName,
 			boshUUID,
+			constLabels,
+			stemcellsLifecycle,
 		)
 	})
 
@@ -132,6 +442,15 @@ var _ = Describe("DeploymentsCollector", func() {
 			go deploymentsCollector.Describe(descriptions)
 		})
 
+		It("returns a deployment_info metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentInfoMetric.WithLabelValues(
+				deploymentName,
+				deploymentTeam,
+				stemcellOSName+"/"+stemcellVersion,
+				releaseName+"/"+releaseVersion,
+			).Desc())))
+		})
+
 		It("returns a deployment_release_info description", func() {
 			Eventually(descriptions).Should(Receive(Equal(deploymentReleaseInfoMetric.WithLabelValues(
 				deploymentName,
@@ -140,6 +459,13 @@ var _ = Describe("DeploymentsCollector", func() {
 			).Desc())))
 		})
 
+		It("returns a deployment_release_outdated metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentReleaseOutdatedMetric.WithLabelValues(
+				deploymentName,
+				releaseName,
+			).Desc())))
+		})
+
 		It("returns a deployment_stemcell_info metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(deploymentStemcellInfoMetric.WithLabelValues(
 				deploymentName,
@@ -149,6 +475,93 @@ var _ = Describe("DeploymentsCollector", func() {
 			).Desc())))
 		})
 
+		Context("when there are const labels", func() {
+			BeforeEach(func() {
+				constLabels = prometheus.Labels{"datacenter": "fake-datacenter"}
+			})
+
+			It("adds them to the metrics descriptions", func() {
+				var description *prometheus.Desc
+				Eventually(descriptions).Should(Receive(&description))
+				Expect(description.String()).To(ContainSubstring(`datacenter="fake-datacenter"`))
+				Expect(description.String()).To(ContainSubstring(`environment="test_environment"`))
+			})
+		})
+
+		It("returns a deployment_stemcell_created_at metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentStemcellCreatedAtMetric.WithLabelValues(
+				deploymentName,
+				stemcellName,
+				stemcellVersion,
+				stemcellOSName,
+			).Desc())))
+		})
+
+		It("returns a deployment_stemcell_eol_at metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentStemcellEOLAtMetric.WithLabelValues(
+				deploymentName,
+				stemcellName,
+				stemcellVersion,
+				stemcellOSName,
+			).Desc())))
+		})
+
+		It("returns a deployment_instances_total metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentInstancesTotalMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_instances_unhealthy metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentInstancesUnhealthyMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_processes_unhealthy metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentProcessesUnhealthyMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_instances_short_format metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentInstancesShortFormatMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_task_in_progress metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentTaskInProgressMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_manifest_sha1 metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentManifestSHA1Metric.WithLabelValues(deploymentName, manifestSHA1).Desc())))
+		})
+
+		It("returns a deployment_manifest_changed_at metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentManifestChangedAtMetric.WithLabelValues(deploymentName).Desc())))
+		})
+
+		It("returns a deployment_update_canaries metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentUpdateCanariesMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a deployment_update_max_in_flight metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentUpdateMaxInFlightMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a deployment_update_canary_watch_time_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentUpdateCanaryWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a deployment_update_watch_time_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentUpdateWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a deployment_job_desired_instances metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentJobDesiredInstancesMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a deployment_job_actual_instances metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, jobName).Desc())))
+		})
+
+		It("returns a task_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(taskDurationSecondsMetric.WithLabelValues(deploymentName, "create_deployment").Desc())))
+		})
+
 		It("returns a last_deployments_scrape_timestamp metric description", func() {
 			Eventually(descriptions).Should(Receive(Equal(lastDeploymentsScrapeTimestampMetric.Desc())))
 		})
@@ -183,9 +596,41 @@ var _ = Describe("DeploymentsCollector", func() {
 
 		BeforeEach(func() {
 			deploymentInfo = deployments.DeploymentInfo{
-				Name:      deploymentName,
-				Releases:  releases,
-				Stemcells: stemcells,
+				Name:         deploymentName,
+				Teams:        []string{deploymentTeam},
+				ManifestSHA1: manifestSHA1,
+				InstanceGroups: []deployments.InstanceGroup{
+					{
+						Name:      jobName,
+						Instances: 6,
+						Update: deployments.Update{
+							Canaries:        2,
+							MaxInFlight:     3,
+							CanaryWatchTime: 30 * time.Second,
+							UpdateWatchTime: 60 * time.Second,
+						},
+					},
+				},
+				Instances: []deployments.Instance{
+					{
+						Name:    jobName,
+						Healthy: true,
+						Processes: []deployments.Process{
+							{Healthy: true},
+							{Healthy: false},
+						},
+					},
+					{
+						Name:    jobName,
+						Healthy: false,
+						Processes: []deployments.Process{
+							{Healthy: false},
+						},
+					},
+				},
+				ActualInstances: map[string]int{jobName: 2},
+				Releases:        releases,
+				Stemcells:       stemcells,
 			}
 			deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
 
@@ -201,6 +646,16 @@ var _ = Describe("DeploymentsCollector", func() {
 			}()
 		})
 
+		It("returns a deployment_info metric", func() {
+			Eventually(metrics).Should(Receive(Equal(deploymentInfoMetric.WithLabelValues(
+				deploymentName,
+				deploymentTeam,
+				stemcellOSName+"/"+stemcellVersion,
+				releaseName+"/"+releaseVersion,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
 		It("returns a deployment_release_info metric", func() {
 			Eventually(metrics).Should(Receive(Equal(deploymentReleaseInfoMetric.WithLabelValues(
 				deploymentName,
@@ -210,6 +665,35 @@ var _ = Describe("DeploymentsCollector", func() {
 			Consistently(errMetrics).ShouldNot(Receive())
 		})
 
+		It("returns a deployment_release_outdated metric", func() {
+			deploymentReleaseOutdatedMetric.WithLabelValues(deploymentName, releaseName).Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentReleaseOutdatedMetric.WithLabelValues(
+				deploymentName,
+				releaseName,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when a newer release version is uploaded", func() {
+			BeforeEach(func() {
+				deploymentInfo.Releases = []deployments.Release{
+					{Name: releaseName, Version: releaseVersion, LatestVersion: "1.3.0", Outdated: true},
+				}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns an outdated deployment_release_outdated metric", func() {
+				deploymentReleaseOutdatedMetric.WithLabelValues(deploymentName, releaseName).Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentReleaseOutdatedMetric.WithLabelValues(
+					deploymentName,
+					releaseName,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
 		It("returns a deployment_stemcell_info metric", func() {
 			Eventually(metrics).Should(Receive(Equal(deploymentStemcellInfoMetric.WithLabelValues(
 				deploymentName,
@@ -220,6 +704,347 @@ var _ = Describe("DeploymentsCollector", func() {
 			Consistently(errMetrics).ShouldNot(Receive())
 		})
 
+		It("does not return a deployment_stemcell_created_at metric", func() {
+			Consistently(metrics).ShouldNot(Receive(Equal(deploymentStemcellCreatedAtMetric.WithLabelValues(
+				deploymentName,
+				stemcellName,
+				stemcellVersion,
+				stemcellOSName,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when there is a stemcells lifecycle", func() {
+			var (
+				err                    error
+				stemcellsLifecycleFile *os.File
+			)
+
+			BeforeEach(func() {
+				stemcellsLifecycleFile, err = ioutil.TempFile("", "deployments_collector_test_")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = stemcellsLifecycleFile.WriteString(stemcellOSName + "/*:\n  created_at: 2020-06-09T17:40:00Z\n  eol: 2021-04-30\n")
+				Expect(err).ToNot(HaveOccurred())
+				stemcellsLifecycle, err = LoadStemcellsLifecycle(stemcellsLifecycleFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				err = os.Remove(stemcellsLifecycleFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns a deployment_stemcell_created_at metric", func() {
+				deploymentStemcellCreatedAtMetric.WithLabelValues(
+					deploymentName,
+					stemcellName,
+					stemcellVersion,
+					stemcellOSName,
+				).Set(float64(1591724400))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentStemcellCreatedAtMetric.WithLabelValues(
+					deploymentName,
+					stemcellName,
+					stemcellVersion,
+					stemcellOSName,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("returns a deployment_stemcell_eol_at metric", func() {
+				deploymentStemcellEOLAtMetric.WithLabelValues(
+					deploymentName,
+					stemcellName,
+					stemcellVersion,
+					stemcellOSName,
+				).Set(float64(1619740800))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentStemcellEOLAtMetric.WithLabelValues(
+					deploymentName,
+					stemcellName,
+					stemcellVersion,
+					stemcellOSName,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a deployment_instances_total metric", func() {
+			deploymentInstancesTotalMetric.WithLabelValues(deploymentName).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentInstancesTotalMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_instances_unhealthy metric", func() {
+			deploymentInstancesUnhealthyMetric.WithLabelValues(deploymentName).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentInstancesUnhealthyMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_processes_unhealthy metric", func() {
+			deploymentProcessesUnhealthyMetric.WithLabelValues(deploymentName).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentProcessesUnhealthyMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_instances_short_format metric", func() {
+			deploymentInstancesShortFormatMetric.WithLabelValues(deploymentName).Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentInstancesShortFormatMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the instances are read in short format", func() {
+			BeforeEach(func() {
+				deploymentsInfo[0].InstancesShortFormat = true
+			})
+
+			It("returns a deployment_instances_short_format metric", func() {
+				deploymentInstancesShortFormatMetric.WithLabelValues(deploymentName).Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentInstancesShortFormatMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("returns a deployment_instances_total metric", func() {
+				deploymentInstancesTotalMetric.WithLabelValues(deploymentName).Set(float64(2))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentInstancesTotalMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("does not return the deployment unhealthy instances and processes metrics", func() {
+				Consistently(metrics).ShouldNot(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, ContainSubstring("_unhealthy"))))
+			})
+		})
+
+		It("returns a deployment_manifest_sha1 metric", func() {
+			deploymentManifestSHA1Metric.WithLabelValues(deploymentName, manifestSHA1).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentManifestSHA1Metric.WithLabelValues(deploymentName, manifestSHA1))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_manifest_changed_at metric with the time the manifest was first seen", func() {
+			changedAt := func(m prometheus.Metric) float64 {
+				metric := &dto.Metric{}
+				Expect(m.Write(metric)).To(Succeed())
+				return metric.GetGauge().GetValue()
+			}
+
+			Eventually(metrics).Should(Receive(SatisfyAll(
+				WithTransform(func(m prometheus.Metric) string { return m.Desc().String() }, ContainSubstring("deployment_manifest_changed_at")),
+				WithTransform(changedAt, BeNumerically("~", time.Now().Unix(), 5)),
+			)))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the deployment manifest changes", func() {
+			var newManifestSHA1 = "fake-new-manifest-sha1"
+
+			It("returns a deployment_manifest_sha1 metric with the new manifest SHA1", func() {
+				Eventually(metrics).Should(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, ContainSubstring("last_deployments_scrape_duration_seconds"))))
+
+				deploymentInfo.ManifestSHA1 = newManifestSHA1
+				go func() {
+					if err := deploymentsCollector.Collect([]deployments.DeploymentInfo{deploymentInfo}, metrics); err != nil {
+						errMetrics <- err
+					}
+				}()
+
+				deploymentManifestSHA1Metric.WithLabelValues(deploymentName, newManifestSHA1).Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentManifestSHA1Metric.WithLabelValues(deploymentName, newManifestSHA1))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the deployment manifest SHA1 is unknown", func() {
+			BeforeEach(func() {
+				deploymentInfo.ManifestSHA1 = ""
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("does not return a deployment_manifest_sha1 metric", func() {
+				Consistently(metrics).ShouldNot(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, ContainSubstring("deployment_manifest_sha1"))))
+			})
+		})
+
+		It("returns a deployment_update_canaries metric", func() {
+			deploymentUpdateCanariesMetric.WithLabelValues(deploymentName, jobName).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentUpdateCanariesMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_update_max_in_flight metric", func() {
+			deploymentUpdateMaxInFlightMetric.WithLabelValues(deploymentName, jobName).Set(float64(3))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentUpdateMaxInFlightMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_update_canary_watch_time_seconds metric", func() {
+			deploymentUpdateCanaryWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName).Set(float64(30))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentUpdateCanaryWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_update_watch_time_seconds metric", func() {
+			deploymentUpdateWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName).Set(float64(60))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentUpdateWatchTimeSecondsMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_job_desired_instances metric", func() {
+			deploymentJobDesiredInstancesMetric.WithLabelValues(deploymentName, jobName).Set(float64(6))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentJobDesiredInstancesMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a deployment_job_actual_instances metric", func() {
+			deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, jobName).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, jobName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when instances are discarded by the jobs and AZs filters", func() {
+			BeforeEach(func() {
+				deploymentInfo.ActualInstances = map[string]int{jobName: 6}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns a deployment_job_actual_instances metric counting them", func() {
+				deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, jobName).Set(float64(6))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, jobName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when an instance group has no instances", func() {
+			BeforeEach(func() {
+				deploymentInfo.InstanceGroups = append(deploymentInfo.InstanceGroups, deployments.InstanceGroup{Name: "fake-missing-job-name", Instances: 1})
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns an empty deployment_job_actual_instances metric", func() {
+				deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, "fake-missing-job-name").Set(float64(0))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentJobActualInstancesMetric.WithLabelValues(deploymentName, "fake-missing-job-name"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a deployment_task_in_progress metric", func() {
+			deploymentTaskInProgressMetric.WithLabelValues(deploymentName).Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(deploymentTaskInProgressMetric.WithLabelValues(deploymentName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when a deploy task is in progress", func() {
+			BeforeEach(func() {
+				deploymentInfo.Tasks = []deployments.Task{
+					{ID: 42, Description: "create deployment", State: "processing"},
+				}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns an in progress deployment_task_in_progress metric", func() {
+				deploymentTaskInProgressMetric.WithLabelValues(deploymentName).Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentTaskInProgressMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the tasks could not be read", func() {
+			BeforeEach(func() {
+				deploymentInfo.UnavailableDetails.Tasks = true
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("does not return a deployment_task_in_progress metric", func() {
+				Consistently(metrics).ShouldNot(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, ContainSubstring("deployment_task_in_progress"))))
+			})
+		})
+
+		Context("when a deploy task is completed", func() {
+			var (
+				startedAt = time.Unix(1500000000, 0)
+			)
+
+			BeforeEach(func() {
+				deploymentInfo.Tasks = []deployments.Task{
+					{ID: 41, Description: "create deployment", State: "done", StartedAt: startedAt, LastActivityAt: startedAt.Add(90 * time.Second)},
+				}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns a not in progress deployment_task_in_progress metric", func() {
+				deploymentTaskInProgressMetric.WithLabelValues(deploymentName).Set(float64(0))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentTaskInProgressMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("returns a task_duration_seconds metric", func() {
+				taskDurationSecondsMetric.WithLabelValues(deploymentName, "create_deployment").Observe(float64(90))
+
+				Eventually(metrics).Should(Receive(Equal(taskDurationSecondsMetric.WithLabelValues(deploymentName, "create_deployment"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("observes the task only once", func() {
+				Eventually(metrics).Should(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, ContainSubstring("last_deployments_scrape_duration_seconds"))))
+
+				go func() {
+					if err := deploymentsCollector.Collect(deploymentsInfo, metrics); err != nil {
+						errMetrics <- err
+					}
+				}()
+
+				taskDurationSecondsMetric.WithLabelValues(deploymentName, "create_deployment").Observe(float64(90))
+
+				Eventually(metrics).Should(Receive(Equal(taskDurationSecondsMetric.WithLabelValues(deploymentName, "create_deployment"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when another task is in progress", func() {
+			BeforeEach(func() {
+				deploymentInfo.Tasks = []deployments.Task{
+					{ID: 42, Description: "run errand smoke-tests", State: "processing"},
+				}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns a not in progress deployment_task_in_progress metric", func() {
+				deploymentTaskInProgressMetric.WithLabelValues(deploymentName).Set(float64(0))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentTaskInProgressMetric.WithLabelValues(deploymentName))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
 				deploymentsInfo = []deployments.DeploymentInfo{}
@@ -239,6 +1064,18 @@ var _ = Describe("DeploymentsCollector", func() {
 				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
 			})
 
+			It("returns a deployment_info metric without releases", func() {
+				deploymentInfoMetric.WithLabelValues(deploymentName, deploymentTeam, stemcellOSName+"/"+stemcellVersion, "").Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(deploymentInfoMetric.WithLabelValues(
+					deploymentName,
+					deploymentTeam,
+					stemcellOSName+"/"+stemcellVersion,
+					"",
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
 			It("should not return a deployment_release_info metric", func() {
 				Consistently(metrics).ShouldNot(Receive(Equal(deploymentReleaseInfoMetric.WithLabelValues(
 					deploymentName,
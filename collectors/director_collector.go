@@ -0,0 +1,222 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+// noCPIConfigErr is the error returned by the BOSH client when no CPI config
+// has been uploaded to the director (ie single CPI directors).
+const noCPIConfigErr = "No CPI config"
+
+type DirectorCollector struct {
+	boshClient                              director.Director
+	directorCompatibility                   deployments.DirectorCompatibility
+	directorFeatureEnabledMetric            *prometheus.GaugeVec
+	directorCPIInfoMetric                   *prometheus.GaugeVec
+	directorAZCPIMetric                     *prometheus.GaugeVec
+	lastDirectorScrapeTimestampMetric       prometheus.Gauge
+	lastDirectorScrapeDurationSecondsMetric prometheus.Gauge
+	descs                                   metricsDescs
+}
+
+type cpiConfigManifest struct {
+	CPIs []cpiConfigCPI `yaml:"cpis"`
+}
+
+type cpiConfigCPI struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+}
+
+func NewDirectorCollector(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+	boshClient director.Director,
+	directorCompatibility deployments.DirectorCompatibility,
+) *DirectorCollector {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	directorFeatureEnabledMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "director",
+			Name:        "feature_enabled",
+			Help:        "BOSH Director Feature Enabled (1 for enabled, 0 for disabled).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_director_feature"},
+	)
+
+	directorCPIInfoMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "director",
+			Name:        "cpi_info",
+			Help:        "BOSH Director CPI Info with a constant '1' value.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_cpi_name", "bosh_cpi_type", "bosh_cpi_source"},
+	)
+
+	directorAZCPIMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "director",
+			Name:        "az_cpi",
+			Help:        "BOSH Director AZ CPI (1 if the AZ CPI is configured on the director, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_az", "bosh_cpi_name"},
+	)
+
+	lastDirectorScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_director_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Director metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastDirectorScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_director_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Director metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	collector := &DirectorCollector{
+		boshClient:                              boshClient,
+		directorCompatibility:                   directorCompatibility,
+		directorFeatureEnabledMetric:            directorFeatureEnabledMetric,
+		directorCPIInfoMetric:                   directorCPIInfoMetric,
+		directorAZCPIMetric:                     directorAZCPIMetric,
+		lastDirectorScrapeTimestampMetric:       lastDirectorScrapeTimestampMetric,
+		lastDirectorScrapeDurationSecondsMetric: lastDirectorScrapeDurationSecondsMetric,
+		descs:                                   descs,
+	}
+	return collector
+}
+
+func (c *DirectorCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	var begun = time.Now()
+
+	info, err := c.boshClient.Info()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error while reading Director Info: %v", err))
+	}
+
+	var cpiConfig cpiConfigManifest
+	if !c.directorCompatibility.NoCPIConfigs {
+		latestCPIConfig, err := c.boshClient.LatestCPIConfig()
+		if err != nil {
+			if err.Error() != noCPIConfigErr {
+				return errors.New(fmt.Sprintf("Error while reading CPI Config: %v", err))
+			}
+		} else if err = yaml.Unmarshal([]byte(latestCPIConfig.Properties), &cpiConfig); err != nil {
+			return errors.New(fmt.Sprintf("Error while parsing CPI Config: %v", err))
+		}
+	}
+
+	latestCloudConfig, err := c.boshClient.LatestCloudConfig()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error while reading Cloud Config: %v", err))
+	}
+
+	var cloudConfig cloudConfigManifest
+	if err = yaml.Unmarshal([]byte(latestCloudConfig.Properties), &cloudConfig); err != nil {
+		return errors.New(fmt.Sprintf("Error while parsing Cloud Config: %v", err))
+	}
+
+	c.directorFeatureEnabledMetric.Reset()
+	c.directorCPIInfoMetric.Reset()
+	c.directorAZCPIMetric.Reset()
+
+	c.reportDirectorFeatureMetrics(info, ch)
+	c.reportDirectorCPIMetrics(info, cpiConfig, cloudConfig, ch)
+
+	c.directorFeatureEnabledMetric.Collect(ch)
+	c.directorCPIInfoMetric.Collect(ch)
+	c.directorAZCPIMetric.Collect(ch)
+
+	c.lastDirectorScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastDirectorScrapeTimestampMetric.Collect(ch)
+
+	c.lastDirectorScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.lastDirectorScrapeDurationSecondsMetric.Collect(ch)
+
+	return nil
+}
+
+func (c *DirectorCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.directorFeatureEnabledMetric.Describe(ch)
+	c.directorCPIInfoMetric.Describe(ch)
+	c.directorAZCPIMetric.Describe(ch)
+	c.lastDirectorScrapeTimestampMetric.Describe(ch)
+	c.lastDirectorScrapeDurationSecondsMetric.Describe(ch)
+}
+
+func (c *DirectorCollector) reportDirectorFeatureMetrics(
+	info director.Info,
+	ch chan<- prometheus.Metric,
+) {
+	for feature, enabled := range info.Features {
+		var value float64
+		if enabled {
+			value = 1
+		}
+		c.directorFeatureEnabledMetric.WithLabelValues(feature).Set(value)
+	}
+}
+
+func (c *DirectorCollector) reportDirectorCPIMetrics(
+	info director.Info,
+	cpiConfig cpiConfigManifest,
+	cloudConfig cloudConfigManifest,
+	ch chan<- prometheus.Metric,
+) {
+	configuredCPIs := map[string]bool{}
+
+	if info.CPI != "" {
+		c.directorCPIInfoMetric.WithLabelValues(info.CPI, "", "info").Set(float64(1))
+		configuredCPIs[info.CPI] = true
+	}
+
+	for _, cpi := range cpiConfig.CPIs {
+		c.directorCPIInfoMetric.WithLabelValues(cpi.Name, cpi.Type, "cpi_config").Set(float64(1))
+		configuredCPIs[cpi.Name] = true
+	}
+
+	for _, az := range cloudConfig.AZs {
+		cpiName := az.CPI
+		if cpiName == "" {
+			cpiName = info.CPI
+		}
+
+		var value float64
+		if configuredCPIs[cpiName] {
+			value = 1
+		}
+		c.directorAZCPIMetric.WithLabelValues(az.Name, cpiName).Set(value)
+	}
+}
+
+func (c *DirectorCollector) descriptions() metricsDescs {
+	return c.descs
+}
@@ -0,0 +1,321 @@
+package collectors_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("DirectorCollector", func() {
+	var (
+		namespace             string
+		environment           string
+		boshName              string
+		boshUUID              string
+		boshClient            *directorfakes.FakeDirector
+		directorCompatibility deployments.DirectorCompatibility
+		directorCollector     *DirectorCollector
+
+		directorFeatureEnabledMetric            *prometheus.GaugeVec
+		directorCPIInfoMetric                   *prometheus.GaugeVec
+		directorAZCPIMetric                     *prometheus.GaugeVec
+		lastDirectorScrapeTimestampMetric       prometheus.Gauge
+		lastDirectorScrapeDurationSecondsMetric prometheus.Gauge
+
+		enabledFeature  = "local_dns"
+		disabledFeature = "snapshots"
+		defaultCPIName  = "fake-default-cpi"
+		cpiName         = "fake-cpi-name"
+		cpiType         = "fake-cpi-type"
+		azName          = "z1"
+		defaultAZName   = "z2"
+		driftedAZName   = "z3"
+		driftedCPIName  = "fake-unknown-cpi"
+		cpiConfig       = `---
+cpis:
+- name: fake-cpi-name
+  type: fake-cpi-type
+`
+		cloudConfig = `---
+azs:
+- name: z1
+  cpi: fake-cpi-name
+- name: z2
+- name: z3
+  cpi: fake-unknown-cpi
+`
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		environment = "test_environment"
+		boshName = "test_bosh_name"
+		boshUUID = "test_bosh_uuid"
+		boshClient = &directorfakes.FakeDirector{}
+		directorCompatibility = deployments.DirectorCompatibility{}
+		boshClient.InfoReturns(director.Info{
+			Features: map[string]bool{enabledFeature: true, disabledFeature: false},
+			CPI:      defaultCPIName,
+		}, nil)
+		boshClient.LatestCPIConfigReturns(director.CPIConfig{Properties: cpiConfig}, nil)
+		boshClient.LatestCloudConfigReturns(director.CloudConfig{Properties: cloudConfig}, nil)
+
+		directorFeatureEnabledMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "director",
+				Name:      "feature_enabled",
+				Help:      "BOSH Director Feature Enabled (1 for enabled, 0 for disabled).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_director_feature"},
+		)
+
+		directorCPIInfoMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "director",
+				Name:      "cpi_info",
+				Help:      "BOSH Director CPI Info with a constant '1' value.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_cpi_name", "bosh_cpi_type", "bosh_cpi_source"},
+		)
+
+		directorAZCPIMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "director",
+				Name:      "az_cpi",
+				Help:      "BOSH Director AZ CPI (1 if the AZ CPI is configured on the director, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_az", "bosh_cpi_name"},
+		)
+
+		lastDirectorScrapeTimestampMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_director_scrape_timestamp",
+				Help:      "Number of seconds since 1970 since last scrape of Director metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastDirectorScrapeDurationSecondsMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_director_scrape_duration_seconds",
+				Help:      "Duration of the last scrape of Director metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+	})
+
+	JustBeforeEach(func() {
+		directorCollector = NewDirectorCollector(
+			namespace,
+			environment,
+			boshName,
+			boshUUID,
+			prometheus.Labels{},
+			boshClient,
+			directorCompatibility,
+		)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go directorCollector.Describe(descriptions)
+		})
+
+		It("returns a director_feature_enabled metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(directorFeatureEnabledMetric.WithLabelValues(enabledFeature).Desc())))
+		})
+
+		It("returns a director_cpi_info metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(directorCPIInfoMetric.WithLabelValues(cpiName, cpiType, "cpi_config").Desc())))
+		})
+
+		It("returns a director_az_cpi metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(directorAZCPIMetric.WithLabelValues(azName, cpiName).Desc())))
+		})
+
+		It("returns a last_director_scrape_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastDirectorScrapeTimestampMetric.Desc())))
+		})
+
+		It("returns a last_director_scrape_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastDirectorScrapeDurationSecondsMetric.Desc())))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			metrics    chan prometheus.Metric
+			errMetrics chan error
+		)
+
+		BeforeEach(func() {
+			metrics = make(chan prometheus.Metric)
+			errMetrics = make(chan error, 1)
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				if err := directorCollector.Collect([]deployments.DeploymentInfo{}, metrics); err != nil {
+					errMetrics <- err
+				}
+			}()
+		})
+
+		It("returns an enabled director_feature_enabled metric", func() {
+			directorFeatureEnabledMetric.WithLabelValues(enabledFeature).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(directorFeatureEnabledMetric.WithLabelValues(enabledFeature))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a disabled director_feature_enabled metric", func() {
+			directorFeatureEnabledMetric.WithLabelValues(disabledFeature).Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(directorFeatureEnabledMetric.WithLabelValues(disabledFeature))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a director_cpi_info metric for the director default CPI", func() {
+			directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a director_cpi_info metric for the CPI config CPIs", func() {
+			directorCPIInfoMetric.WithLabelValues(cpiName, cpiType, "cpi_config").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(directorCPIInfoMetric.WithLabelValues(cpiName, cpiType, "cpi_config"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a director_az_cpi metric for an AZ with a configured CPI", func() {
+			directorAZCPIMetric.WithLabelValues(azName, cpiName).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(directorAZCPIMetric.WithLabelValues(azName, cpiName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a director_az_cpi metric with the director default CPI for an AZ without CPI", func() {
+			directorAZCPIMetric.WithLabelValues(defaultAZName, defaultCPIName).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(directorAZCPIMetric.WithLabelValues(defaultAZName, defaultCPIName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a director_az_cpi metric for an AZ with an unknown CPI", func() {
+			directorAZCPIMetric.WithLabelValues(driftedAZName, driftedCPIName).Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(directorAZCPIMetric.WithLabelValues(driftedAZName, driftedCPIName))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when there is no CPI config", func() {
+			BeforeEach(func() {
+				boshClient.LatestCPIConfigReturns(director.CPIConfig{}, errors.New("No CPI config"))
+			})
+
+			It("returns a director_cpi_info metric for the director default CPI", func() {
+				directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info").Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the director has no CPI configs", func() {
+			BeforeEach(func() {
+				directorCompatibility = deployments.DirectorCompatibility{NoCPIConfigs: true}
+				boshClient.LatestCPIConfigReturns(director.CPIConfig{}, errors.New("Director responded with non-successful status code '404'"))
+			})
+
+			It("does not read the CPI config", func() {
+				directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info").Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(directorCPIInfoMetric.WithLabelValues(defaultCPIName, "", "info"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+				Expect(boshClient.LatestCPIConfigCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when it fails to get the CPI config", func() {
+			BeforeEach(func() {
+				boshClient.LatestCPIConfigReturns(director.CPIConfig{}, errors.New("no cpi config"))
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+
+		Context("when it fails to get the cloud config", func() {
+			BeforeEach(func() {
+				boshClient.LatestCloudConfigReturns(director.CloudConfig{}, errors.New("no cloud config"))
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+
+		Context("when it fails to get the director info", func() {
+			BeforeEach(func() {
+				boshClient.InfoReturns(director.Info{}, errors.New("no info"))
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,8 @@
+// Package collectors implements the Prometheus collectors exposing the BOSH
+// metrics and Service Discovery targets.
+//
+// BoshCollector is the entry point: it fetches the deployments once per
+// scrape using a DeploymentsFetcher and runs the enabled collectors against
+// them. It can be embedded in other exporters by registering it on their own
+// Prometheus registry.
+package collectors
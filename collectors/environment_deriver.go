@@ -0,0 +1,69 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DirectorNameEnvironmentSource = "director-name"
+	CPIEnvironmentSource          = "cpi"
+)
+
+// EnvironmentDeriver derives the `environment` label from the BOSH Director
+// info, mapping the derived values to friendly names.
+type EnvironmentDeriver struct {
+	source string
+	names  map[string]string
+}
+
+func NewEnvironmentDeriver(source string, namesFilename string) (*EnvironmentDeriver, error) {
+	environmentDeriver := &EnvironmentDeriver{source: source, names: map[string]string{}}
+
+	switch source {
+	case "", DirectorNameEnvironmentSource, CPIEnvironmentSource:
+	default:
+		return environmentDeriver, errors.New(fmt.Sprintf("Environment source `%s` is not supported", source))
+	}
+
+	if namesFilename == "" {
+		return environmentDeriver, nil
+	}
+
+	content, err := ioutil.ReadFile(namesFilename)
+	if err != nil {
+		return environmentDeriver, errors.New(fmt.Sprintf("Error reading environment names file `%s`: %v", namesFilename, err))
+	}
+
+	if err = yaml.Unmarshal(content, &environmentDeriver.names); err != nil {
+		return environmentDeriver, errors.New(fmt.Sprintf("Error parsing environment names file `%s`: %v", namesFilename, err))
+	}
+
+	return environmentDeriver, nil
+}
+
+// Environment returns the environment derived from the BOSH Director info,
+// or the given environment if there is no source or the source is empty.
+func (d *EnvironmentDeriver) Environment(environment string, boshInfo director.Info) string {
+	var derived string
+	switch d.source {
+	case DirectorNameEnvironmentSource:
+		derived = boshInfo.Name
+	case CPIEnvironmentSource:
+		derived = boshInfo.CPI
+	}
+
+	if derived == "" {
+		return environment
+	}
+
+	if name, ok := d.names[derived]; ok {
+		return name
+	}
+
+	return derived
+}
@@ -0,0 +1,118 @@
+package collectors_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("EnvironmentDeriver", func() {
+	var (
+		err                error
+		tmpfile            *os.File
+		source             string
+		filename           string
+		content            string
+		boshInfo           director.Info
+		environmentDeriver *EnvironmentDeriver
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "environment_deriver_test_")
+		Expect(err).ToNot(HaveOccurred())
+		source = DirectorNameEnvironmentSource
+		filename = tmpfile.Name()
+		content = "bosh-prod-eu: production\nvsphere_cpi: datacenter-1\n"
+		boshInfo = director.Info{Name: "bosh-prod-eu", CPI: "vsphere_cpi"}
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		environmentDeriver, err = NewEnvironmentDeriver(source, filename)
+	})
+
+	It("maps the BOSH Director name to its friendly name", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("production"))
+	})
+
+	Context("when the derived value has no friendly name", func() {
+		BeforeEach(func() {
+			boshInfo.Name = "bosh-dev"
+		})
+
+		It("returns the derived value", func() {
+			Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("bosh-dev"))
+		})
+	})
+
+	Context("when the source is the CPI", func() {
+		BeforeEach(func() {
+			source = CPIEnvironmentSource
+		})
+
+		It("maps the CPI to its friendly name", func() {
+			Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("datacenter-1"))
+		})
+
+		It("returns the given environment when the CPI is unknown", func() {
+			boshInfo.CPI = ""
+			Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("fake-environment"))
+		})
+	})
+
+	Context("when there is no source", func() {
+		BeforeEach(func() {
+			source = ""
+		})
+
+		It("returns the given environment", func() {
+			Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("fake-environment"))
+		})
+	})
+
+	Context("when the source is not supported", func() {
+		BeforeEach(func() {
+			source = "fake-source"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Environment source `fake-source` is not supported"))
+		})
+	})
+
+	Context("when there is no filename", func() {
+		BeforeEach(func() {
+			filename = ""
+		})
+
+		It("returns the derived value", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(environmentDeriver.Environment("fake-environment", boshInfo)).To(Equal("bosh-prod-eu"))
+		})
+	})
+
+	Context("when the file is not valid", func() {
+		BeforeEach(func() {
+			content = "- production"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error parsing environment names file"))
+		})
+	})
+})
@@ -0,0 +1,254 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/log"
+
+	"github.com/cloudfoundry-community/bosh_exporter/api"
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+type ExecCollector struct {
+	environment                         string
+	boshName                            string
+	boshUUID                            string
+	metricConstLabels                   prometheus.Labels
+	commands                            []string
+	timeout                             time.Duration
+	execCommandSuccessMetric            *prometheus.GaugeVec
+	lastExecScrapeTimestampMetric       prometheus.Gauge
+	lastExecScrapeDurationSecondsMetric prometheus.Gauge
+	descs                               metricsDescs
+}
+
+func NewExecCollector(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+	commands []string,
+	timeout time.Duration,
+) *ExecCollector {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	execCommandSuccessMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "exec",
+			Name:        "command_success",
+			Help:        "Whether the last run of the external command succeeded (1 for success, 0 for error).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"command"},
+	)
+
+	lastExecScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_exec_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of external commands metrics.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastExecScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_exec_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of external commands metrics.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	collector := &ExecCollector{
+		environment:                         environment,
+		boshName:                            boshName,
+		boshUUID:                            boshUUID,
+		metricConstLabels:                   metricConstLabels,
+		commands:                            commands,
+		timeout:                             timeout,
+		execCommandSuccessMetric:            execCommandSuccessMetric,
+		lastExecScrapeTimestampMetric:       lastExecScrapeTimestampMetric,
+		lastExecScrapeDurationSecondsMetric: lastExecScrapeDurationSecondsMetric,
+		descs:                               descs,
+	}
+	return collector
+}
+
+// Collect runs each external command with the deployments snapshot (in the
+// Snapshot API format) as JSON on its stdin, and exposes the metrics it
+// writes to its stdout using the Prometheus text format. A failing command
+// is logged and reported at the exec_command_success metric without failing
+// the whole scrape.
+func (c *ExecCollector) Collect(deploymentsInfo []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	var begun = time.Now()
+
+	if deploymentsInfo == nil {
+		deploymentsInfo = []deployments.DeploymentInfo{}
+	}
+	snapshot, err := json.Marshal(api.Snapshot{
+		Environment: c.environment,
+		BoshName:    c.boshName,
+		BoshUUID:    c.boshUUID,
+		FetchedAt:   begun.UTC(),
+		Deployments: deploymentsInfo,
+	})
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding deployments snapshot: %v", err))
+	}
+
+	c.execCommandSuccessMetric.Reset()
+
+	for _, command := range c.commands {
+		metricFamilies, err := c.run(command, snapshot)
+		if err != nil {
+			log.Error(err)
+			c.execCommandSuccessMetric.WithLabelValues(command).Set(0)
+			continue
+		}
+
+		c.execCommandSuccessMetric.WithLabelValues(command).Set(1)
+		c.reportMetricFamilies(command, metricFamilies, ch)
+	}
+
+	c.execCommandSuccessMetric.Collect(ch)
+
+	c.lastExecScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastExecScrapeTimestampMetric.Collect(ch)
+
+	c.lastExecScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.lastExecScrapeDurationSecondsMetric.Collect(ch)
+
+	return nil
+}
+
+func (c *ExecCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.execCommandSuccessMetric.Describe(ch)
+	c.lastExecScrapeTimestampMetric.Describe(ch)
+	c.lastExecScrapeDurationSecondsMetric.Describe(ch)
+}
+
+func (c *ExecCollector) run(command string, snapshot []byte) (map[string]*dto.MetricFamily, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("External command cannot be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(snapshot)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, errors.New(fmt.Sprintf("External command `%s` timed out after %s", command, c.timeout))
+		}
+		return nil, errors.New(fmt.Sprintf("Error running external command `%s`: %v: %s", command, err, strings.TrimSpace(stderr.String())))
+	}
+
+	var parser expfmt.TextParser
+	metricFamilies, err := parser.TextToMetricFamilies(&stdout)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing external command `%s` metrics: %v", command, err))
+	}
+
+	return metricFamilies, nil
+}
+
+func (c *ExecCollector) reportMetricFamilies(command string, metricFamilies map[string]*dto.MetricFamily, ch chan<- prometheus.Metric) {
+	for _, metricFamily := range metricFamilies {
+		help := metricFamily.GetHelp()
+		if help == "" {
+			help = fmt.Sprintf("Metric exposed by the `%s` external command.", command)
+		}
+
+		for _, metric := range metricFamily.Metric {
+			m, err := c.constMetric(metricFamily, help, metric)
+			if err != nil {
+				log.Errorf("Error exposing metric `%s` of external command `%s`: %v", metricFamily.GetName(), command, err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+func (c *ExecCollector) constMetric(metricFamily *dto.MetricFamily, help string, metric *dto.Metric) (prometheus.Metric, error) {
+	labels := map[string]string{}
+	for _, label := range metric.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+
+	constLabels := prometheus.Labels{}
+	for name, value := range c.metricConstLabels {
+		if _, ok := labels[name]; !ok {
+			constLabels[name] = value
+		}
+	}
+
+	labelNames := []string{}
+	for name := range labels {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	labelValues := []string{}
+	for _, name := range labelNames {
+		labelValues = append(labelValues, labels[name])
+	}
+
+	desc := metricDesc{fqName: metricFamily.GetName(), help: help, variableLabels: labelNames, constLabels: constLabels}
+	m, err := newConstMetric(prometheus.NewDesc(desc.fqName, desc.help, desc.variableLabels, desc.constLabels), metricFamily.GetType(), metric, labelValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return describedMetric{Metric: m, desc: desc}, nil
+}
+
+func newConstMetric(desc *prometheus.Desc, metricType dto.MetricType, metric *dto.Metric, labelValues []string) (prometheus.Metric, error) {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return prometheus.NewConstMetric(desc, prometheus.CounterValue, metric.Counter.GetValue(), labelValues...)
+	case dto.MetricType_GAUGE:
+		return prometheus.NewConstMetric(desc, prometheus.GaugeValue, metric.Gauge.GetValue(), labelValues...)
+	case dto.MetricType_SUMMARY:
+		quantiles := map[float64]float64{}
+		for _, quantile := range metric.Summary.Quantile {
+			quantiles[quantile.GetQuantile()] = quantile.GetValue()
+		}
+		return prometheus.NewConstSummary(desc, metric.Summary.GetSampleCount(), metric.Summary.GetSampleSum(), quantiles, labelValues...)
+	case dto.MetricType_HISTOGRAM:
+		buckets := map[float64]uint64{}
+		for _, bucket := range metric.Histogram.Bucket {
+			buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+		}
+		return prometheus.NewConstHistogram(desc, metric.Histogram.GetSampleCount(), metric.Histogram.GetSampleSum(), buckets, labelValues...)
+	default:
+		return prometheus.NewConstMetric(desc, prometheus.UntypedValue, metric.Untyped.GetValue(), labelValues...)
+	}
+}
+
+func (c *ExecCollector) descriptions() metricsDescs {
+	return c.descs
+}
@@ -0,0 +1,277 @@
+package collectors_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/cloudfoundry-community/bosh_exporter/api"
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+const fakeExecCommandScript = `#!/bin/sh
+cat > "$1"
+echo '# HELP fake_site_metric Fake site metric.'
+echo '# TYPE fake_site_metric gauge'
+echo 'fake_site_metric{site="fake-site"} 42'
+echo 'fake_untyped_metric{bosh_name="fake-override"} 1'
+`
+
+var _ = Describe("ExecCollector", func() {
+	var (
+		err           error
+		namespace     string
+		environment   string
+		boshName      string
+		boshUUID      string
+		tmpDir        string
+		commandPath   string
+		stdinPath     string
+		commands      []string
+		timeout       time.Duration
+		execCollector *ExecCollector
+
+		execCommandSuccessMetric            *prometheus.GaugeVec
+		lastExecScrapeTimestampMetric       prometheus.Gauge
+		lastExecScrapeDurationSecondsMetric prometheus.Gauge
+
+		deploymentName = "fake-deployment-name"
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		environment = "test_environment"
+		boshName = "test_bosh_name"
+		boshUUID = "test_bosh_uuid"
+
+		tmpDir, err = ioutil.TempDir("", "exec_collector_test_")
+		Expect(err).ToNot(HaveOccurred())
+		commandPath = filepath.Join(tmpDir, "fake-command")
+		Expect(ioutil.WriteFile(commandPath, []byte(fakeExecCommandScript), 0755)).To(Succeed())
+		stdinPath = filepath.Join(tmpDir, "stdin")
+		commands = []string{commandPath + " " + stdinPath}
+		timeout = 5 * time.Second
+
+		execCommandSuccessMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "exec",
+				Name:      "command_success",
+				Help:      "Whether the last run of the external command succeeded (1 for success, 0 for error).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"command"},
+		)
+
+		lastExecScrapeTimestampMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_exec_scrape_timestamp",
+				Help:      "Number of seconds since 1970 since last scrape of external commands metrics.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastExecScrapeDurationSecondsMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_exec_scrape_duration_seconds",
+				Help:      "Duration of the last scrape of external commands metrics.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	JustBeforeEach(func() {
+		execCollector = NewExecCollector(
+			namespace,
+			environment,
+			boshName,
+			boshUUID,
+			prometheus.Labels{},
+			commands,
+			timeout,
+		)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go execCollector.Describe(descriptions)
+		})
+
+		It("returns a exec_command_success metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(execCommandSuccessMetric.WithLabelValues(commands[0]).Desc())))
+		})
+
+		It("returns a last_exec_scrape_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastExecScrapeTimestampMetric.Desc())))
+		})
+
+		It("returns a last_exec_scrape_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastExecScrapeDurationSecondsMetric.Desc())))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			deploymentsInfo []deployments.DeploymentInfo
+			collectErr      error
+			metrics         map[string]*dto.Metric
+		)
+
+		BeforeEach(func() {
+			deploymentsInfo = []deployments.DeploymentInfo{
+				{Name: deploymentName},
+			}
+		})
+
+		JustBeforeEach(func() {
+			metricsCh := make(chan prometheus.Metric)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- execCollector.Collect(deploymentsInfo, metricsCh)
+				close(metricsCh)
+			}()
+
+			metrics = map[string]*dto.Metric{}
+			for metric := range metricsCh {
+				out := &dto.Metric{}
+				Expect(metric.Write(out)).To(Succeed())
+				metrics[metric.Desc().String()] = out
+			}
+			collectErr = <-errCh
+		})
+
+		findMetric := func(fqName string) *dto.Metric {
+			for desc, metric := range metrics {
+				if strings.Contains(desc, `fqName: "`+fqName+`"`) {
+					return metric
+				}
+			}
+			return nil
+		}
+
+		labelsOf := func(metric *dto.Metric) map[string]string {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			return labels
+		}
+
+		It("writes the deployments snapshot to the command stdin", func() {
+			Expect(collectErr).ToNot(HaveOccurred())
+			content, err := ioutil.ReadFile(stdinPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			var snapshot api.Snapshot
+			Expect(json.Unmarshal(content, &snapshot)).To(Succeed())
+			Expect(snapshot.Environment).To(Equal(environment))
+			Expect(snapshot.BoshName).To(Equal(boshName))
+			Expect(snapshot.BoshUUID).To(Equal(boshUUID))
+			Expect(snapshot.Deployments).To(HaveLen(1))
+			Expect(snapshot.Deployments[0].Name).To(Equal(deploymentName))
+		})
+
+		It("returns the command metrics with the exporter labels", func() {
+			metric := findMetric("fake_site_metric")
+			Expect(metric).ToNot(BeNil())
+			Expect(metric.GetGauge().GetValue()).To(Equal(float64(42)))
+			Expect(labelsOf(metric)).To(Equal(map[string]string{
+				"environment": environment,
+				"bosh_name":   boshName,
+				"bosh_uuid":   boshUUID,
+				"site":        "fake-site",
+			}))
+		})
+
+		It("keeps the labels set by the command", func() {
+			metric := findMetric("fake_untyped_metric")
+			Expect(metric).ToNot(BeNil())
+			Expect(metric.GetUntyped().GetValue()).To(Equal(float64(1)))
+			Expect(labelsOf(metric)["bosh_name"]).To(Equal("fake-override"))
+		})
+
+		It("returns a successful exec_command_success metric", func() {
+			metric := findMetric(namespace + "_exec_command_success")
+			Expect(metric).ToNot(BeNil())
+			Expect(metric.GetGauge().GetValue()).To(Equal(float64(1)))
+		})
+
+		Context("when the command fails", func() {
+			BeforeEach(func() {
+				commands = []string{filepath.Join(tmpDir, "unknown-command")}
+			})
+
+			It("returns a failed exec_command_success metric without error", func() {
+				Expect(collectErr).ToNot(HaveOccurred())
+				metric := findMetric(namespace + "_exec_command_success")
+				Expect(metric).ToNot(BeNil())
+				Expect(metric.GetGauge().GetValue()).To(Equal(float64(0)))
+				Expect(findMetric("fake_site_metric")).To(BeNil())
+			})
+		})
+
+		Context("when the command output is not valid", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(commandPath, []byte("#!/bin/sh\necho 'not valid metrics'\n"), 0755)).To(Succeed())
+			})
+
+			It("returns a failed exec_command_success metric", func() {
+				metric := findMetric(namespace + "_exec_command_success")
+				Expect(metric).ToNot(BeNil())
+				Expect(metric.GetGauge().GetValue()).To(Equal(float64(0)))
+			})
+		})
+
+		Context("when the command times out", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(commandPath, []byte("#!/bin/sh\nexec sleep 10\n"), 0755)).To(Succeed())
+				timeout = 100 * time.Millisecond
+			})
+
+			It("returns a failed exec_command_success metric", func() {
+				metric := findMetric(namespace + "_exec_command_success")
+				Expect(metric).ToNot(BeNil())
+				Expect(metric.GetGauge().GetValue()).To(Equal(float64(0)))
+			})
+		})
+	})
+})
@@ -0,0 +1,10 @@
+package collectors
+
+import (
+	"time"
+)
+
+// SetNow replaces the clock used to timestamp the observed state changes.
+func (c *JobsCollector) SetNow(now func() time.Time) {
+	c.now = now
+}
@@ -1,43 +1,81 @@
 package collectors
 
 import (
-	"errors"
-	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
-	"github.com/cloudfoundry-community/bosh_exporter/filters"
 )
 
+const jobUnresponsiveAgentState = "unresponsive_agent"
+
+var jobStates = []string{"running", "failing", jobUnresponsiveAgentState, "stopped"}
+
+var jobProcessStates = []string{"running", "starting", "unmonitored", "failing"}
+
+var jobLabelNames = []string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"}
+
+var jobVMInfoLabelNames = []string{"bosh_vm_type", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name", "bosh_os_flavor"}
+
+const (
+	linuxOSFlavor   = "linux"
+	windowsOSFlavor = "windows"
+)
+
+var jobProcessLabelNames = append(append([]string{}, jobLabelNames...), "bosh_job_process_name")
+
+// JobsCollector exposes the instances and processes metrics as constant
+// metrics built from descriptors created once, so a scrape does not rebuild
+// (and hash) a gauge per series.
 type JobsCollector struct {
-	azsFilter                           *filters.AZsFilter
-	jobHealthyMetric                    *prometheus.GaugeVec
-	jobLoadAvg01Metric                  *prometheus.GaugeVec
-	jobLoadAvg05Metric                  *prometheus.GaugeVec
-	jobLoadAvg15Metric                  *prometheus.GaugeVec
-	jobCPUSysMetric                     *prometheus.GaugeVec
-	jobCPUUserMetric                    *prometheus.GaugeVec
-	jobCPUWaitMetric                    *prometheus.GaugeVec
-	jobMemKBMetric                      *prometheus.GaugeVec
-	jobMemPercentMetric                 *prometheus.GaugeVec
-	jobSwapKBMetric                     *prometheus.GaugeVec
-	jobSwapPercentMetric                *prometheus.GaugeVec
-	jobSystemDiskInodePercentMetric     *prometheus.GaugeVec
-	jobSystemDiskPercentMetric          *prometheus.GaugeVec
-	jobEphemeralDiskInodePercentMetric  *prometheus.GaugeVec
-	jobEphemeralDiskPercentMetric       *prometheus.GaugeVec
-	jobPersistentDiskInodePercentMetric *prometheus.GaugeVec
-	jobPersistentDiskPercentMetric      *prometheus.GaugeVec
-	jobProcessHealthyMetric             *prometheus.GaugeVec
-	jobProcessUptimeMetric              *prometheus.GaugeVec
-	jobProcessCPUTotalMetric            *prometheus.GaugeVec
-	jobProcessMemKBMetric               *prometheus.GaugeVec
-	jobProcessMemPercentMetric          *prometheus.GaugeVec
+	jobHealthyDesc                      *prometheus.Desc
+	jobStateDesc                        *prometheus.Desc
+	jobUnresponsiveAgentDesc            *prometheus.Desc
+	jobLastStateChangeTimestampDesc     *prometheus.Desc
+	jobLoadAvg01Desc                    *prometheus.Desc
+	jobLoadAvg05Desc                    *prometheus.Desc
+	jobLoadAvg15Desc                    *prometheus.Desc
+	jobCPUSysDesc                       *prometheus.Desc
+	jobCPUUserDesc                      *prometheus.Desc
+	jobCPUWaitDesc                      *prometheus.Desc
+	jobMemKBDesc                        *prometheus.Desc
+	jobMemPercentDesc                   *prometheus.Desc
+	jobSwapKBDesc                       *prometheus.Desc
+	jobSwapPercentDesc                  *prometheus.Desc
+	jobSystemDiskInodePercentDesc       *prometheus.Desc
+	jobSystemDiskPercentDesc            *prometheus.Desc
+	jobEphemeralDiskInodePercentDesc    *prometheus.Desc
+	jobEphemeralDiskPercentDesc         *prometheus.Desc
+	jobPersistentDiskInodePercentDesc   *prometheus.Desc
+	jobPersistentDiskPercentDesc        *prometheus.Desc
+	jobPersistentDiskAttachedDesc       *prometheus.Desc
+	jobPersistentDiskInfoDesc           *prometheus.Desc
+	jobVMInfoDesc                       *prometheus.Desc
+	jobProcessHealthyDesc               *prometheus.Desc
+	jobProcessStateDesc                 *prometheus.Desc
+	jobProcessUptimeDesc                *prometheus.Desc
+	jobProcessCPUTotalDesc              *prometheus.Desc
+	jobProcessMemKBDesc                 *prometheus.Desc
+	jobProcessMemPercentDesc            *prometheus.Desc
 	lastJobsScrapeTimestampMetric       prometheus.Gauge
 	lastJobsScrapeDurationSecondsMetric prometheus.Gauge
+
+	now               func() time.Time
+	stateChangesMutex sync.Mutex
+	stateChanges      map[string]jobStateChange
+	descs             metricsDescs
+}
+
+// jobStateChange is the last state of an instance observed by the exporter,
+// and when the exporter observed it changing.
+type jobStateChange struct {
+	state     string
+	changedAt time.Time
 }
 
 func NewJobsCollector(
@@ -45,449 +83,141 @@ func NewJobsCollector(
 	environment string,
 	boshName string,
 	boshUUID string,
-	azsFilter *filters.AZsFilter,
+	constLabels prometheus.Labels,
 ) *JobsCollector {
-	jobHealthyMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "healthy",
-			Help:      "BOSH Job Healthy (1 for healthy, 0 for unhealthy).",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobLoadAvg01Metric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "load_avg01",
-			Help:      "BOSH Job Load avg01.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobLoadAvg05Metric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "load_avg05",
-			Help:      "BOSH Job Load avg05.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobLoadAvg15Metric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "load_avg15",
-			Help:      "BOSH Job Load avg15.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobCPUSysMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "cpu_sys",
-			Help:      "BOSH Job CPU System.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobCPUUserMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "cpu_user",
-			Help:      "BOSH Job CPU User.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobCPUWaitMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "cpu_wait",
-			Help:      "BOSH Job CPU Wait.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobMemKBMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "mem_kb",
-			Help:      "BOSH Job Memory KB.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobMemPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "mem_percent",
-			Help:      "BOSH Job Memory Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
 
-	jobSwapKBMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "swap_kb",
-			Help:      "BOSH Job Swap KB.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobSwapPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "swap_percent",
-			Help:      "BOSH Job Swap Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobSystemDiskInodePercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "system_disk_inode_percent",
-			Help:      "BOSH Job System Disk Inode Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobSystemDiskPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "system_disk_percent",
-			Help:      "BOSH Job System Disk Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobEphemeralDiskInodePercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "ephemeral_disk_inode_percent",
-			Help:      "BOSH Job Ephemeral Disk Inode Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobEphemeralDiskPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "ephemeral_disk_percent",
-			Help:      "BOSH Job Ephemeral Disk Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobPersistentDiskInodePercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "persistent_disk_inode_percent",
-			Help:      "BOSH Job Persistent Disk Inode Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobPersistentDiskPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job",
-			Name:      "persistent_disk_percent",
-			Help:      "BOSH Job Persistent Disk Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
-	)
-
-	jobProcessHealthyMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job_process",
-			Name:      "healthy",
-			Help:      "BOSH Job Process Healthy (1 for healthy, 0 for unhealthy).",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
-	)
-
-	jobProcessUptimeMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job_process",
-			Name:      "uptime_seconds",
-			Help:      "BOSH Job Process Uptime in seconds.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
-	)
-
-	jobProcessCPUTotalMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job_process",
-			Name:      "cpu_total",
-			Help:      "BOSH Job Process CPU Total.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
-	)
-
-	jobProcessMemKBMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job_process",
-			Name:      "mem_kb",
-			Help:      "BOSH Job Process Memory KB.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
-	)
+	jobDesc := func(name string, help string) *prometheus.Desc {
+		return descs.newDesc(prometheus.BuildFQName(namespace, "job", name), help, jobLabelNames, metricConstLabels)
+	}
 
-	jobProcessMemPercentMetric := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "job_process",
-			Name:      "mem_percent",
-			Help:      "BOSH Job Process Memory Percent.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
-		},
-		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
-	)
+	jobProcessDesc := func(name string, help string) *prometheus.Desc {
+		return descs.newDesc(prometheus.BuildFQName(namespace, "job_process", name), help, jobProcessLabelNames, metricConstLabels)
+	}
 
-	lastJobsScrapeTimestampMetric := prometheus.NewGauge(
+	lastJobsScrapeTimestampMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_jobs_scrape_timestamp",
-			Help:      "Number of seconds since 1970 since last scrape of Job metrics from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_jobs_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Job metrics from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
-	lastJobsScrapeDurationSecondsMetric := prometheus.NewGauge(
+	lastJobsScrapeDurationSecondsMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_jobs_scrape_duration_seconds",
-			Help:      "Duration of the last scrape of Job metrics from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_jobs_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Job metrics from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
 	collector := &JobsCollector{
-		azsFilter:                           azsFilter,
-		jobHealthyMetric:                    jobHealthyMetric,
-		jobLoadAvg01Metric:                  jobLoadAvg01Metric,
-		jobLoadAvg05Metric:                  jobLoadAvg05Metric,
-		jobLoadAvg15Metric:                  jobLoadAvg15Metric,
-		jobCPUSysMetric:                     jobCPUSysMetric,
-		jobCPUUserMetric:                    jobCPUUserMetric,
-		jobCPUWaitMetric:                    jobCPUWaitMetric,
-		jobMemKBMetric:                      jobMemKBMetric,
-		jobMemPercentMetric:                 jobMemPercentMetric,
-		jobSwapKBMetric:                     jobSwapKBMetric,
-		jobSwapPercentMetric:                jobSwapPercentMetric,
-		jobSystemDiskInodePercentMetric:     jobSystemDiskInodePercentMetric,
-		jobSystemDiskPercentMetric:          jobSystemDiskPercentMetric,
-		jobEphemeralDiskInodePercentMetric:  jobEphemeralDiskInodePercentMetric,
-		jobEphemeralDiskPercentMetric:       jobEphemeralDiskPercentMetric,
-		jobPersistentDiskInodePercentMetric: jobPersistentDiskInodePercentMetric,
-		jobPersistentDiskPercentMetric:      jobPersistentDiskPercentMetric,
-		jobProcessHealthyMetric:             jobProcessHealthyMetric,
-		jobProcessUptimeMetric:              jobProcessUptimeMetric,
-		jobProcessCPUTotalMetric:            jobProcessCPUTotalMetric,
-		jobProcessMemKBMetric:               jobProcessMemKBMetric,
-		jobProcessMemPercentMetric:          jobProcessMemPercentMetric,
+		jobHealthyDesc: jobDesc("healthy", "BOSH Job Healthy (1 for healthy, 0 for unhealthy)."),
+		jobStateDesc: descs.newDesc(
+			prometheus.BuildFQName(namespace, "job", "state"),
+			"BOSH Job State (1 for the current state, 0 for the other states).",
+			append(append([]string{}, jobLabelNames...), "state"),
+			metricConstLabels,
+		),
+		jobUnresponsiveAgentDesc:          jobDesc("unresponsive_agent", "BOSH Job Unresponsive Agent (1 if the BOSH Agent is unresponsive, 0 otherwise)."),
+		jobLastStateChangeTimestampDesc:   jobDesc("last_state_change_timestamp", "Number of seconds since 1970 since the exporter observed the BOSH Job State changing."),
+		jobLoadAvg01Desc:                  jobDesc("load_avg01", "BOSH Job Load avg01."),
+		jobLoadAvg05Desc:                  jobDesc("load_avg05", "BOSH Job Load avg05."),
+		jobLoadAvg15Desc:                  jobDesc("load_avg15", "BOSH Job Load avg15."),
+		jobCPUSysDesc:                     jobDesc("cpu_sys", "BOSH Job CPU System."),
+		jobCPUUserDesc:                    jobDesc("cpu_user", "BOSH Job CPU User."),
+		jobCPUWaitDesc:                    jobDesc("cpu_wait", "BOSH Job CPU Wait."),
+		jobMemKBDesc:                      jobDesc("mem_kb", "BOSH Job Memory KB."),
+		jobMemPercentDesc:                 jobDesc("mem_percent", "BOSH Job Memory Percent."),
+		jobSwapKBDesc:                     jobDesc("swap_kb", "BOSH Job Swap KB."),
+		jobSwapPercentDesc:                jobDesc("swap_percent", "BOSH Job Swap Percent."),
+		jobSystemDiskInodePercentDesc:     jobDesc("system_disk_inode_percent", "BOSH Job System Disk Inode Percent."),
+		jobSystemDiskPercentDesc:          jobDesc("system_disk_percent", "BOSH Job System Disk Percent."),
+		jobEphemeralDiskInodePercentDesc:  jobDesc("ephemeral_disk_inode_percent", "BOSH Job Ephemeral Disk Inode Percent."),
+		jobEphemeralDiskPercentDesc:       jobDesc("ephemeral_disk_percent", "BOSH Job Ephemeral Disk Percent."),
+		jobPersistentDiskInodePercentDesc: jobDesc("persistent_disk_inode_percent", "BOSH Job Persistent Disk Inode Percent."),
+		jobPersistentDiskPercentDesc:      jobDesc("persistent_disk_percent", "BOSH Job Persistent Disk Percent."),
+		jobPersistentDiskAttachedDesc:     jobDesc("persistent_disk_attached", "BOSH Job Persistent Disk Attached (1 if a persistent disk is attached, 0 otherwise)."),
+		jobPersistentDiskInfoDesc: descs.newDesc(
+			prometheus.BuildFQName(namespace, "job", "persistent_disk_info"),
+			"BOSH Job Persistent Disk Info (always 1), labeled by the disk CID.",
+			append(append([]string{}, jobLabelNames...), "bosh_persistent_disk_cid"),
+			metricConstLabels,
+		),
+		jobVMInfoDesc: descs.newDesc(
+			prometheus.BuildFQName(namespace, "job", "vm_info"),
+			"BOSH Job VM Info (always 1), labeled by the VM type, the stemcell and the OS flavor (linux or windows).",
+			append(append([]string{}, jobLabelNames...), jobVMInfoLabelNames...),
+			metricConstLabels,
+		),
+		jobProcessHealthyDesc: jobProcessDesc("healthy", "BOSH Job Process Healthy (1 for healthy, 0 for unhealthy)."),
+		jobProcessStateDesc: descs.newDesc(
+			prometheus.BuildFQName(namespace, "job_process", "state"),
+			"BOSH Job Process State (1 for the current state, 0 for the other states).",
+			append(append([]string{}, jobProcessLabelNames...), "state"),
+			metricConstLabels,
+		),
+		jobProcessUptimeDesc:                jobProcessDesc("uptime_seconds", "BOSH Job Process Uptime in seconds."),
+		jobProcessCPUTotalDesc:              jobProcessDesc("cpu_total", "BOSH Job Process CPU Total."),
+		jobProcessMemKBDesc:                 jobProcessDesc("mem_kb", "BOSH Job Process Memory KB."),
+		jobProcessMemPercentDesc:            jobProcessDesc("mem_percent", "BOSH Job Process Memory Percent."),
 		lastJobsScrapeTimestampMetric:       lastJobsScrapeTimestampMetric,
 		lastJobsScrapeDurationSecondsMetric: lastJobsScrapeDurationSecondsMetric,
+		now:                                 time.Now,
+		stateChanges:                        map[string]jobStateChange{},
+		descs:                               descs,
 	}
 	return collector
 }
 
 func (c *JobsCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
-	var err error
 	var begun = time.Now()
 
-	c.jobHealthyMetric.Reset()
-	c.jobLoadAvg01Metric.Reset()
-	c.jobLoadAvg05Metric.Reset()
-	c.jobLoadAvg15Metric.Reset()
-	c.jobCPUSysMetric.Reset()
-	c.jobCPUUserMetric.Reset()
-	c.jobCPUWaitMetric.Reset()
-	c.jobMemKBMetric.Reset()
-	c.jobMemPercentMetric.Reset()
-	c.jobSwapKBMetric.Reset()
-	c.jobSwapPercentMetric.Reset()
-	c.jobSystemDiskInodePercentMetric.Reset()
-	c.jobSystemDiskPercentMetric.Reset()
-	c.jobEphemeralDiskInodePercentMetric.Reset()
-	c.jobEphemeralDiskPercentMetric.Reset()
-	c.jobPersistentDiskInodePercentMetric.Reset()
-	c.jobPersistentDiskPercentMetric.Reset()
-	c.jobProcessHealthyMetric.Reset()
-	c.jobProcessUptimeMetric.Reset()
-	c.jobProcessCPUTotalMetric.Reset()
-	c.jobProcessMemKBMetric.Reset()
-	c.jobProcessMemPercentMetric.Reset()
+	// Label values are built in a single slice, large enough for the job
+	// VM info metric labels, and reused for every series: constant metrics
+	// copy them into their own label pairs.
+	labelValues := make([]string, 0, len(jobLabelNames)+len(jobVMInfoLabelNames))
+
+	stateChanges := c.observeStateChanges(deployments)
 
 	for _, deployment := range deployments {
-		err = c.reportJobMetrics(deployment, ch)
-	}
+		stemcells := instanceGroupsStemcells(deployment)
+		for _, instance := range deployment.Instances {
+			jobIP := ""
+			if len(instance.IPs) > 0 {
+				jobIP = instance.IPs[0]
+			}
+			labelValues = append(labelValues[:0], deployment.Name, instance.Name, instance.ID, instance.Index, instance.AZ, jobIP)
+
+			stemcell := stemcells[instance.Name]
+			if deployment.InstancesShortFormat {
+				// Instances read in short format have no state, vitals nor
+				// processes: only the VM info is exposed.
+				c.reportJobVMInfoMetric(ch, instance, stemcell, osFlavor(stemcell), labelValues)
+				continue
+			}
+
+			instanceOSFlavor := osFlavor(stemcell)
+			if instanceOSFlavor == "" {
+				instanceOSFlavor = vitalsOSFlavor(instance.Vitals)
+			}
+			c.reportJobMetrics(ch, instance, instanceOSFlavor, labelValues)
 
-	c.jobHealthyMetric.Collect(ch)
-	c.jobLoadAvg01Metric.Collect(ch)
-	c.jobLoadAvg05Metric.Collect(ch)
-	c.jobLoadAvg15Metric.Collect(ch)
-	c.jobCPUSysMetric.Collect(ch)
-	c.jobCPUUserMetric.Collect(ch)
-	c.jobCPUWaitMetric.Collect(ch)
-	c.jobMemKBMetric.Collect(ch)
-	c.jobMemPercentMetric.Collect(ch)
-	c.jobSwapKBMetric.Collect(ch)
-	c.jobSwapPercentMetric.Collect(ch)
-	c.jobSystemDiskInodePercentMetric.Collect(ch)
-	c.jobSystemDiskPercentMetric.Collect(ch)
-	c.jobEphemeralDiskInodePercentMetric.Collect(ch)
-	c.jobEphemeralDiskPercentMetric.Collect(ch)
-	c.jobPersistentDiskInodePercentMetric.Collect(ch)
-	c.jobPersistentDiskPercentMetric.Collect(ch)
-	c.jobProcessHealthyMetric.Collect(ch)
-	c.jobProcessUptimeMetric.Collect(ch)
-	c.jobProcessCPUTotalMetric.Collect(ch)
-	c.jobProcessMemKBMetric.Collect(ch)
-	c.jobProcessMemPercentMetric.Collect(ch)
+			changedAt := stateChanges[jobStateChangeKey(deployment.Name, instance)].changedAt
+			ch <- prometheus.MustNewConstMetric(c.jobLastStateChangeTimestampDesc, prometheus.GaugeValue, float64(changedAt.UnixNano())/1e9, labelValues...)
+
+			c.reportJobVMInfoMetric(ch, instance, stemcell, instanceOSFlavor, labelValues)
+
+			for _, process := range instance.Processes {
+				c.reportJobProcessMetrics(ch, process, append(labelValues[:len(jobLabelNames)], process.Name))
+			}
+		}
+	}
 
 	c.lastJobsScrapeTimestampMetric.Set(float64(time.Now().Unix()))
 	c.lastJobsScrapeTimestampMetric.Collect(ch)
@@ -495,582 +225,305 @@ func (c *JobsCollector) Collect(deployments []deployments.DeploymentInfo, ch cha
 	c.lastJobsScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
 	c.lastJobsScrapeDurationSecondsMetric.Collect(ch)
 
-	return err
+	return nil
 }
 
 func (c *JobsCollector) Describe(ch chan<- *prometheus.Desc) {
-	c.jobHealthyMetric.Describe(ch)
-	c.jobLoadAvg01Metric.Describe(ch)
-	c.jobLoadAvg05Metric.Describe(ch)
-	c.jobLoadAvg15Metric.Describe(ch)
-	c.jobCPUSysMetric.Describe(ch)
-	c.jobCPUUserMetric.Describe(ch)
-	c.jobCPUWaitMetric.Describe(ch)
-	c.jobMemKBMetric.Describe(ch)
-	c.jobMemPercentMetric.Describe(ch)
-	c.jobSwapKBMetric.Describe(ch)
-	c.jobSwapPercentMetric.Describe(ch)
-	c.jobSystemDiskInodePercentMetric.Describe(ch)
-	c.jobSystemDiskPercentMetric.Describe(ch)
-	c.jobEphemeralDiskInodePercentMetric.Describe(ch)
-	c.jobEphemeralDiskPercentMetric.Describe(ch)
-	c.jobPersistentDiskInodePercentMetric.Describe(ch)
-	c.jobPersistentDiskPercentMetric.Describe(ch)
-	c.jobProcessHealthyMetric.Describe(ch)
-	c.jobProcessUptimeMetric.Describe(ch)
-	c.jobProcessCPUTotalMetric.Describe(ch)
-	c.jobProcessMemKBMetric.Describe(ch)
-	c.jobProcessMemPercentMetric.Describe(ch)
+	ch <- c.jobHealthyDesc
+	ch <- c.jobStateDesc
+	ch <- c.jobUnresponsiveAgentDesc
+	ch <- c.jobLastStateChangeTimestampDesc
+	ch <- c.jobLoadAvg01Desc
+	ch <- c.jobLoadAvg05Desc
+	ch <- c.jobLoadAvg15Desc
+	ch <- c.jobCPUSysDesc
+	ch <- c.jobCPUUserDesc
+	ch <- c.jobCPUWaitDesc
+	ch <- c.jobMemKBDesc
+	ch <- c.jobMemPercentDesc
+	ch <- c.jobSwapKBDesc
+	ch <- c.jobSwapPercentDesc
+	ch <- c.jobSystemDiskInodePercentDesc
+	ch <- c.jobSystemDiskPercentDesc
+	ch <- c.jobEphemeralDiskInodePercentDesc
+	ch <- c.jobEphemeralDiskPercentDesc
+	ch <- c.jobPersistentDiskInodePercentDesc
+	ch <- c.jobPersistentDiskPercentDesc
+	ch <- c.jobPersistentDiskAttachedDesc
+	ch <- c.jobPersistentDiskInfoDesc
+	ch <- c.jobVMInfoDesc
+	ch <- c.jobProcessHealthyDesc
+	ch <- c.jobProcessStateDesc
+	ch <- c.jobProcessUptimeDesc
+	ch <- c.jobProcessCPUTotalDesc
+	ch <- c.jobProcessMemKBDesc
+	ch <- c.jobProcessMemPercentDesc
 	c.lastJobsScrapeTimestampMetric.Describe(ch)
 	c.lastJobsScrapeDurationSecondsMetric.Describe(ch)
 }
 
-func (c *JobsCollector) reportJobMetrics(deployment deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
-	var err error
+// reportJobMetrics exposes the instance vitals. Windows agents report zeros
+// for the load averages, the CPU wait and the inodes usage, which Windows
+// does not measure, so those are not exposed for Windows instances.
+func (c *JobsCollector) reportJobMetrics(
+	ch chan<- prometheus.Metric,
+	instance deployments.Instance,
+	osFlavor string,
+	labelValues []string,
+) {
+	windows := osFlavor == windowsOSFlavor
 
-	for _, instance := range deployment.Instances {
-		if !c.azsFilter.Enabled(instance.AZ) {
-			continue
-		}
+	var healthyMetric float64
+	if instance.Healthy {
+		healthyMetric = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.jobHealthyDesc, prometheus.GaugeValue, healthyMetric, labelValues...)
 
-		deploymentName := deployment.Name
-		jobName := instance.Name
-		jobID := instance.ID
-		jobIndex := instance.Index
-		jobAZ := instance.AZ
-		jobIP := ""
-		if len(instance.IPs) > 0 {
-			jobIP = instance.IPs[0]
-		}
+	c.reportStateMetrics(ch, c.jobStateDesc, instance.State, jobStates, labelValues)
 
-		err = c.jobHealthyMetrics(ch, instance.Healthy, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobLoadAvgMetrics(ch, instance.Vitals.Load, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobCPUMetrics(ch, instance.Vitals.CPU, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobMemMetrics(ch, instance.Vitals.Mem, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobSwapMetrics(ch, instance.Vitals.Swap, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobSystemDiskMetrics(ch, instance.Vitals.SystemDisk, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobEphemeralDiskMetrics(ch, instance.Vitals.EphemeralDisk, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-		err = c.jobPersistentDiskMetrics(ch, instance.Vitals.PersistentDisk, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP)
-
-		for _, process := range instance.Processes {
-			jobProcessName := process.Name
-
-			err = c.jobProcessHealthyMetrics(ch, process.Healthy, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName)
-			err = c.jobProcessUptimeMetrics(ch, process.Uptime, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName)
-			err = c.jobProcessCPUMetrics(ch, process.CPU, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName)
-			err = c.jobProcessMemMetrics(ch, process.Mem, deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName)
-		}
+	var unresponsiveAgentMetric float64
+	if currentState, _ := stateset(instance.State, jobStates); currentState == jobUnresponsiveAgentState {
+		unresponsiveAgentMetric = 1
 	}
+	ch <- prometheus.MustNewConstMetric(c.jobUnresponsiveAgentDesc, prometheus.GaugeValue, unresponsiveAgentMetric, labelValues...)
 
-	return err
-}
+	if len(instance.Vitals.Load) == 3 && !windows {
+		c.reportVitalMetric(ch, c.jobLoadAvg01Desc, "Load avg01", instance.Vitals.Load[0], labelValues)
+		c.reportVitalMetric(ch, c.jobLoadAvg05Desc, "Load avg05", instance.Vitals.Load[1], labelValues)
+		c.reportVitalMetric(ch, c.jobLoadAvg15Desc, "Load avg15", instance.Vitals.Load[2], labelValues)
+	}
 
-func (c *JobsCollector) jobHealthyMetrics(
-	ch chan<- prometheus.Metric,
-	healthy bool,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var healthyMetric float64
-	if healthy {
-		healthyMetric = 1
+	c.reportVitalMetric(ch, c.jobCPUSysDesc, "CPU Sys", instance.Vitals.CPU.Sys, labelValues)
+	c.reportVitalMetric(ch, c.jobCPUUserDesc, "CPU User", instance.Vitals.CPU.User, labelValues)
+	if !windows {
+		c.reportVitalMetric(ch, c.jobCPUWaitDesc, "CPU Wait", instance.Vitals.CPU.Wait, labelValues)
+	}
+	c.reportVitalMetric(ch, c.jobMemKBDesc, "Mem KB", instance.Vitals.Mem.KB, labelValues)
+	c.reportVitalMetric(ch, c.jobMemPercentDesc, "Mem Percent", instance.Vitals.Mem.Percent, labelValues)
+	c.reportVitalMetric(ch, c.jobSwapKBDesc, "Swap KB", instance.Vitals.Swap.KB, labelValues)
+	c.reportVitalMetric(ch, c.jobSwapPercentDesc, "Swap Percent", instance.Vitals.Swap.Percent, labelValues)
+	c.reportVitalMetric(ch, c.jobSystemDiskPercentDesc, "System Disk Percent", instance.Vitals.SystemDisk.Percent, labelValues)
+	c.reportVitalMetric(ch, c.jobEphemeralDiskPercentDesc, "Ephemeral Disk Percent", instance.Vitals.EphemeralDisk.Percent, labelValues)
+	c.reportVitalMetric(ch, c.jobPersistentDiskPercentDesc, "Persistent Disk Percent", instance.Vitals.PersistentDisk.Percent, labelValues)
+	if !windows {
+		c.reportVitalMetric(ch, c.jobSystemDiskInodePercentDesc, "System Disk Inode Percent", instance.Vitals.SystemDisk.InodePercent, labelValues)
+		c.reportVitalMetric(ch, c.jobEphemeralDiskInodePercentDesc, "Ephemeral Disk Inode Percent", instance.Vitals.EphemeralDisk.InodePercent, labelValues)
+		c.reportVitalMetric(ch, c.jobPersistentDiskInodePercentDesc, "Persistent Disk Inode Percent", instance.Vitals.PersistentDisk.InodePercent, labelValues)
 	}
 
-	c.jobHealthyMetric.WithLabelValues(
-		deploymentName,
-		jobName,
-		jobID,
-		jobIndex,
-		jobAZ,
-		jobIP,
-	).Set(healthyMetric)
+	var persistentDiskAttachedMetric float64
+	if len(instance.DiskCIDs) > 0 {
+		persistentDiskAttachedMetric = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.jobPersistentDiskAttachedDesc, prometheus.GaugeValue, persistentDiskAttachedMetric, labelValues...)
 
-	return nil
+	for _, diskCID := range instance.DiskCIDs {
+		ch <- prometheus.MustNewConstMetric(c.jobPersistentDiskInfoDesc, prometheus.GaugeValue, 1, append(labelValues, diskCID)...)
+	}
 }
 
-func (c *JobsCollector) jobLoadAvgMetrics(
-	ch chan<- prometheus.Metric,
-	loadAvg []string,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if len(loadAvg) == 3 {
-		if loadAvg[0] != "" {
-			loadAvg01, err := strconv.ParseFloat(loadAvg[0], 64)
-			if err != nil {
-				err = errors.New(fmt.Sprintf("Error while converting Load avg01 metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-			} else {
-				c.jobLoadAvg01Metric.WithLabelValues(
-					deploymentName,
-					jobName,
-					jobID,
-					jobIndex,
-					jobAZ,
-					jobIP,
-				).Set(float64(loadAvg01))
-			}
-		}
-
-		if loadAvg[1] != "" {
-			loadAvg05, err := strconv.ParseFloat(loadAvg[1], 64)
-			if err != nil {
-				err = errors.New(fmt.Sprintf("Error while converting Load avg05 metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-			} else {
-				c.jobLoadAvg05Metric.WithLabelValues(
-					deploymentName,
-					jobName,
-					jobID,
-					jobIndex,
-					jobAZ,
-					jobIP,
-				).Set(float64(loadAvg05))
-
+// observeStateChanges records the states of the instances and returns their
+// last state change, which is the first time the exporter observes them or
+// the time it observes a new state. Only the instances of this scrape are
+// kept, so vanished instances do not accumulate. The lock is not held while
+// metrics are sent, as an abandoned collection may block sending forever.
+func (c *JobsCollector) observeStateChanges(deploymentsInfo []deployments.DeploymentInfo) map[string]jobStateChange {
+	observedAt := c.now()
+
+	c.stateChangesMutex.Lock()
+	defer c.stateChangesMutex.Unlock()
+
+	stateChanges := make(map[string]jobStateChange, len(c.stateChanges))
+	for _, deployment := range deploymentsInfo {
+		// The state of instances read in short format is unknown, so their
+		// last observed state changes are kept.
+		if deployment.InstancesShortFormat {
+			for key, stateChange := range c.stateChanges {
+				if strings.HasPrefix(key, deployment.Name+"/") {
+					stateChanges[key] = stateChange
+				}
 			}
+			continue
 		}
 
-		if loadAvg[2] != "" {
-			loadAvg15, err := strconv.ParseFloat(loadAvg[2], 64)
-			if err != nil {
-				err = errors.New(fmt.Sprintf("Error while converting Load avg15 metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-			} else {
-				c.jobLoadAvg15Metric.WithLabelValues(
-					deploymentName,
-					jobName,
-					jobID,
-					jobIndex,
-					jobAZ,
-					jobIP,
-				).Set(float64(loadAvg15))
+		for _, instance := range deployment.Instances {
+			key := jobStateChangeKey(deployment.Name, instance)
+			stateChange, ok := c.stateChanges[key]
+			if !ok || stateChange.state != instance.State {
+				stateChange = jobStateChange{state: instance.State, changedAt: observedAt}
 			}
+			stateChanges[key] = stateChange
 		}
 	}
+	c.stateChanges = stateChanges
 
-	return err
+	return stateChanges
 }
 
-func (c *JobsCollector) jobCPUMetrics(
-	ch chan<- prometheus.Metric,
-	cpu deployments.CPU,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if cpu.Sys != "" {
-		cpuSys, err := strconv.ParseFloat(cpu.Sys, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting CPU Sys metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobCPUSysMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(cpuSys)
-		}
-	}
+func jobStateChangeKey(deploymentName string, instance deployments.Instance) string {
+	return strings.Join([]string{deploymentName, instance.Name, instance.ID, instance.Index}, "/")
+}
 
-	if cpu.User != "" {
-		cpuUser, err := strconv.ParseFloat(cpu.User, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting CPU User metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobCPUUserMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(cpuUser)
-		}
+func instanceGroupsStemcells(deployment deployments.DeploymentInfo) map[string]deployments.Stemcell {
+	stemcells := map[string]deployments.Stemcell{}
+	for _, instanceGroup := range deployment.InstanceGroups {
+		stemcells[instanceGroup.Name] = instanceGroup.Stemcell
 	}
+	return stemcells
+}
 
-	if cpu.Wait != "" {
-		cpuWait, err := strconv.ParseFloat(cpu.Wait, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting CPU Wait metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobCPUWaitMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(cpuWait)
-		}
+// osFlavor returns the OS flavor of the stemcell, i.e. `windows` for the
+// `windows2019` OS, or an empty string if the stemcell is unknown.
+func osFlavor(stemcell deployments.Stemcell) string {
+	switch {
+	case stemcell.OSName == "":
+		return ""
+	case strings.HasPrefix(strings.ToLower(stemcell.OSName), windowsOSFlavor):
+		return windowsOSFlavor
+	default:
+		return linuxOSFlavor
 	}
-
-	return err
 }
 
-func (c *JobsCollector) jobMemMetrics(
-	ch chan<- prometheus.Metric,
-	mem deployments.Mem,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if mem.KB != "" {
-		memKB, err := strconv.ParseFloat(mem.KB, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Mem KB metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobMemKBMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(memKB)
-		}
+// vitalsOSFlavor guesses the OS flavor of an instance whose stemcell is
+// unknown from the shape of its vitals: Windows agents report zeros (or
+// nothing) for the load averages, the CPU wait and the system disk inodes
+// usage, which are never all zero on Linux. It returns an empty string if the
+// agent did not report any vitals.
+func vitalsOSFlavor(vitals deployments.Vitals) string {
+	if vitals.CPU.Sys == "" && vitals.Mem.KB == "" && vitals.SystemDisk.Percent == "" {
+		return ""
 	}
 
-	if mem.Percent != "" {
-		memPercent, err := strconv.ParseFloat(mem.Percent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Mem Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobMemPercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(memPercent)
+	for _, load := range vitals.Load {
+		if !zeroVital(load) {
+			return linuxOSFlavor
 		}
 	}
 
-	return err
-}
-
-func (c *JobsCollector) jobSwapMetrics(
-	ch chan<- prometheus.Metric,
-	swap deployments.Mem,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if swap.KB != "" {
-		swapKB, err := strconv.ParseFloat(swap.KB, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Swap KB metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobSwapKBMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(swapKB)
-		}
+	if !zeroVital(vitals.CPU.Wait) || !zeroVital(vitals.SystemDisk.InodePercent) {
+		return linuxOSFlavor
 	}
 
-	if swap.Percent != "" {
-		swapPercent, err := strconv.ParseFloat(swap.Percent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Swap Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobSwapPercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(swapPercent)
-		}
+	return windowsOSFlavor
+}
+
+func zeroVital(value string) bool {
+	if value == "" {
+		return true
 	}
 
-	return err
+	floatValue, err := strconv.ParseFloat(value, 64)
+	return err == nil && floatValue == 0
 }
 
-func (c *JobsCollector) jobSystemDiskMetrics(
+func (c *JobsCollector) reportJobVMInfoMetric(
 	ch chan<- prometheus.Metric,
-	systemDisk deployments.Disk,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if systemDisk.InodePercent != "" {
-		systemDiskInodePercent, err := strconv.ParseFloat(systemDisk.InodePercent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting System Disk Inode Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobSystemDiskInodePercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(systemDiskInodePercent)
-		}
-	}
-
-	if systemDisk.Percent != "" {
-		systemDiskPercent, err := strconv.ParseFloat(systemDisk.Percent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting System Disk Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobSystemDiskPercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(systemDiskPercent)
-		}
+	instance deployments.Instance,
+	stemcell deployments.Stemcell,
+	osFlavor string,
+	labelValues []string,
+) {
+	// v1 manifests place instances in resource pools instead of VM types
+	vmType := instance.VMType
+	if vmType == "" {
+		vmType = instance.ResourcePool
 	}
 
-	return err
+	ch <- prometheus.MustNewConstMetric(
+		c.jobVMInfoDesc,
+		prometheus.GaugeValue,
+		1,
+		append(labelValues, vmType, stemcell.Name, stemcell.Version, stemcell.OSName, osFlavor)...,
+	)
 }
 
-func (c *JobsCollector) jobEphemeralDiskMetrics(
+func (c *JobsCollector) reportJobProcessMetrics(
 	ch chan<- prometheus.Metric,
-	ephemeralDisk deployments.Disk,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if ephemeralDisk.InodePercent != "" {
-		ephemeralDiskInodePercent, err := strconv.ParseFloat(ephemeralDisk.InodePercent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Ephemeral Disk Inode Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobEphemeralDiskInodePercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(ephemeralDiskInodePercent)
-		}
+	process deployments.Process,
+	labelValues []string,
+) {
+	var healthyMetric float64
+	if process.Healthy {
+		healthyMetric = 1
 	}
+	ch <- prometheus.MustNewConstMetric(c.jobProcessHealthyDesc, prometheus.GaugeValue, healthyMetric, labelValues...)
 
-	if ephemeralDisk.Percent != "" {
-		ephemeralDiskPercent, err := strconv.ParseFloat(ephemeralDisk.Percent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Ephemeral Disk Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobEphemeralDiskPercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(ephemeralDiskPercent)
-		}
-	}
+	c.reportStateMetrics(ch, c.jobProcessStateDesc, process.State, jobProcessStates, labelValues)
 
-	return err
-}
+	if process.Uptime != nil {
+		ch <- prometheus.MustNewConstMetric(c.jobProcessUptimeDesc, prometheus.GaugeValue, float64(*process.Uptime), labelValues...)
+	}
 
-func (c *JobsCollector) jobPersistentDiskMetrics(
-	ch chan<- prometheus.Metric,
-	persistentDisk deployments.Disk,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-) error {
-	var err error
-
-	if persistentDisk.InodePercent != "" {
-		persistentDiskInodePercent, err := strconv.ParseFloat(persistentDisk.InodePercent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Persistent Disk Inode Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobPersistentDiskInodePercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(persistentDiskInodePercent)
-		}
+	if process.CPU.Total != nil {
+		ch <- prometheus.MustNewConstMetric(c.jobProcessCPUTotalDesc, prometheus.GaugeValue, *process.CPU.Total, labelValues...)
 	}
 
-	if persistentDisk.Percent != "" {
-		persistentDiskPercent, err := strconv.ParseFloat(persistentDisk.Percent, 64)
-		if err != nil {
-			err = errors.New(fmt.Sprintf("Error while converting Persistent Disk Percent metric for deployment `%s` and job `%s`: %v", deploymentName, jobName, err))
-		} else {
-			c.jobPersistentDiskPercentMetric.WithLabelValues(
-				deploymentName,
-				jobName,
-				jobID,
-				jobIndex,
-				jobAZ,
-				jobIP,
-			).Set(persistentDiskPercent)
-		}
+	if process.Mem.KB != nil {
+		ch <- prometheus.MustNewConstMetric(c.jobProcessMemKBDesc, prometheus.GaugeValue, float64(*process.Mem.KB), labelValues...)
 	}
 
-	return err
+	if process.Mem.Percent != nil {
+		ch <- prometheus.MustNewConstMetric(c.jobProcessMemPercentDesc, prometheus.GaugeValue, *process.Mem.Percent, labelValues...)
+	}
 }
 
-func (c *JobsCollector) jobProcessHealthyMetrics(
+func (c *JobsCollector) reportStateMetrics(
 	ch chan<- prometheus.Metric,
-	healthy bool,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-	jobProcessName string,
-) error {
-	var healthyMetric float64
-	if healthy {
-		healthyMetric = 1
-	}
-
-	c.jobProcessHealthyMetric.WithLabelValues(
-		deploymentName,
-		jobName,
-		jobID,
-		jobIndex,
-		jobAZ,
-		jobIP,
-		jobProcessName,
-	).Set(healthyMetric)
+	desc *prometheus.Desc,
+	state string,
+	knownStates []string,
+	labelValues []string,
+) {
+	currentState, states := stateset(state, knownStates)
+	for _, knownState := range states {
+		var stateMetric float64
+		if knownState == currentState {
+			stateMetric = 1
+		}
 
-	return nil
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, stateMetric, append(labelValues, knownState)...)
+	}
 }
 
-func (c *JobsCollector) jobProcessUptimeMetrics(
+// reportVitalMetric exposes a vital reported by the BOSH Director as a
+// string. Empty values are not exposed, and values that cannot be converted
+// are logged and skipped.
+func (c *JobsCollector) reportVitalMetric(
 	ch chan<- prometheus.Metric,
-	uptime *uint64,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-	jobProcessName string,
-) error {
-	if uptime != nil {
-		c.jobProcessUptimeMetric.WithLabelValues(
-			deploymentName,
-			jobName,
-			jobID,
-			jobIndex,
-			jobAZ,
-			jobIP,
-			jobProcessName,
-		).Set(float64(*uptime))
+	desc *prometheus.Desc,
+	vitalName string,
+	value string,
+	labelValues []string,
+) {
+	if value == "" {
+		return
 	}
 
-	return nil
-}
-
-func (c *JobsCollector) jobProcessCPUMetrics(
-	ch chan<- prometheus.Metric,
-	cpu deployments.CPU,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-	jobProcessName string,
-) error {
-	if cpu.Total != nil {
-		c.jobProcessCPUTotalMetric.WithLabelValues(
-			deploymentName,
-			jobName,
-			jobID,
-			jobIndex,
-			jobAZ,
-			jobIP,
-			jobProcessName,
-		).Set(float64(*cpu.Total))
+	vital, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Debugf("Error while converting %s metric for deployment `%s` and job `%s`: %v", vitalName, labelValues[0], labelValues[1], err)
+		return
 	}
 
-	return nil
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, vital, labelValues...)
 }
 
-func (c *JobsCollector) jobProcessMemMetrics(
-	ch chan<- prometheus.Metric,
-	mem deployments.MemInt,
-	deploymentName string,
-	jobName string,
-	jobID string,
-	jobIndex string,
-	jobAZ string,
-	jobIP string,
-	jobProcessName string,
-) error {
-	if mem.KB != nil {
-		c.jobProcessMemKBMetric.WithLabelValues(
-			deploymentName,
-			jobName,
-			jobID,
-			jobIndex,
-			jobAZ,
-			jobIP,
-			jobProcessName,
-		).Set(float64(*mem.KB))
+func stateset(state string, knownStates []string) (string, []string) {
+	currentState := strings.Replace(strings.ToLower(strings.TrimSpace(state)), " ", "_", -1)
+	if currentState == "" {
+		return currentState, knownStates
 	}
 
-	if mem.Percent != nil {
-		c.jobProcessMemPercentMetric.WithLabelValues(
-			deploymentName,
-			jobName,
-			jobID,
-			jobIndex,
-			jobAZ,
-			jobIP,
-			jobProcessName,
-		).Set(*mem.Percent)
+	for _, knownState := range knownStates {
+		if knownState == currentState {
+			return currentState, knownStates
+		}
 	}
 
-	return nil
+	return currentState, append([]string{currentState}, knownStates...)
+}
+
+func (c *JobsCollector) descriptions() metricsDescs {
+	return c.descs
 }
@@ -2,28 +2,44 @@ package collectors_test
 
 import (
 	"strconv"
+	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
-	"github.com/cloudfoundry-community/bosh_exporter/filters"
+	"github.com/cloudfoundry-community/bosh_exporter/fakes"
 
 	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
 )
 
+// constGauge returns the constant metric holding the value of a gauge vector
+// child, as exposed by the JobsCollector.
+func constGauge(gaugeVec *prometheus.GaugeVec, labelValues ...string) prometheus.Metric {
+	gauge := gaugeVec.WithLabelValues(labelValues...)
+
+	metric := &dto.Metric{}
+	gauge.Write(metric)
+
+	return prometheus.MustNewConstMetric(gauge.Desc(), prometheus.GaugeValue, metric.GetGauge().GetValue(), labelValues...)
+}
+
 var _ = Describe("JobsCollector", func() {
 	var (
 		namespace     string
 		environment   string
 		boshName      string
 		boshUUID      string
-		azsFilter     *filters.AZsFilter
 		jobsCollector *JobsCollector
 
 		jobHealthyMetric                    *prometheus.GaugeVec
+		jobStateMetric                      *prometheus.GaugeVec
+		jobUnresponsiveAgentMetric          *prometheus.GaugeVec
+		jobLastStateChangeTimestampMetric   *prometheus.GaugeVec
 		jobLoadAvg01Metric                  *prometheus.GaugeVec
 		jobLoadAvg05Metric                  *prometheus.GaugeVec
 		jobLoadAvg15Metric                  *prometheus.GaugeVec
@@ -40,7 +56,11 @@ var _ = Describe("JobsCollector", func() {
 		jobEphemeralDiskPercentMetric       *prometheus.GaugeVec
 		jobPersistentDiskInodePercentMetric *prometheus.GaugeVec
 		jobPersistentDiskPercentMetric      *prometheus.GaugeVec
+		jobPersistentDiskAttachedMetric     *prometheus.GaugeVec
+		jobPersistentDiskInfoMetric         *prometheus.GaugeVec
+		jobVMInfoMetric                     *prometheus.GaugeVec
 		jobProcessHealthyMetric             *prometheus.GaugeVec
+		jobProcessStateMetric               *prometheus.GaugeVec
 		jobProcessUptimeMetric              *prometheus.GaugeVec
 		jobProcessCPUTotalMetric            *prometheus.GaugeVec
 		jobProcessMemKBMetric               *prometheus.GaugeVec
@@ -55,6 +75,7 @@ var _ = Describe("JobsCollector", func() {
 		jobIP                         = "1.2.3.4"
 		jobAZ                         = "fake-job-az"
 		jobHealthy                    = true
+		jobState                      = "unresponsive agent"
 		jobCPUSys                     = float64(0.5)
 		jobCPUUser                    = float64(1.0)
 		jobCPUWait                    = float64(1.5)
@@ -71,9 +92,16 @@ var _ = Describe("JobsCollector", func() {
 		jobEphemeralDiskPercent       = 40
 		jobPersistentDiskInodePercent = 50
 		jobPersistentDiskPercent      = 60
+		jobPersistentDiskCID          = "fake-disk-cid"
+		jobVMType                     = "fake-vm-type"
+		jobResourcePool               = "fake-resource-pool"
+		jobStemcellName               = "fake-stemcell-name"
+		jobStemcellVersion            = "1.2"
+		jobStemcellOSName             = "fake-stemcell-os-name"
 		jobProcessName                = "fake-process-name"
 		jobProcessUptime              = uint64(3600)
 		jobProcessHealthy             = true
+		jobProcessState               = "starting"
 		jobProcessCPUTotal            = float64(0.5)
 		jobProcessMemKB               = uint64(2000)
 		jobProcessMemPercent          = float64(20)
@@ -84,7 +112,6 @@ var _ = Describe("JobsCollector", func() {
 		environment = "test_environment"
 		boshName = "test_bosh_name"
 		boshUUID = "test_bosh_uuid"
-		azsFilter = filters.NewAZsFilter([]string{})
 
 		jobHealthyMetric = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -110,6 +137,60 @@ var _ = Describe("JobsCollector", func() {
 			jobIP,
 		).Set(float64(1))
 
+		jobStateMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "state",
+				Help:      "BOSH Job State (1 for the current state, 0 for the other states).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "state"},
+		)
+
+		jobUnresponsiveAgentMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "unresponsive_agent",
+				Help:      "BOSH Job Unresponsive Agent (1 if the BOSH Agent is unresponsive, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
+		)
+
+		jobUnresponsiveAgentMetric.WithLabelValues(
+			deploymentName,
+			jobName,
+			jobID,
+			jobIndex,
+			jobAZ,
+			jobIP,
+		).Set(float64(1))
+
+		jobLastStateChangeTimestampMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "last_state_change_timestamp",
+				Help:      "Number of seconds since 1970 since the exporter observed the BOSH Job State changing.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
+		)
+
 		jobLoadAvg01Metric = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -494,6 +575,84 @@ var _ = Describe("JobsCollector", func() {
 			jobIP,
 		).Set(float64(jobPersistentDiskPercent))
 
+		jobPersistentDiskAttachedMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "persistent_disk_attached",
+				Help:      "BOSH Job Persistent Disk Attached (1 if a persistent disk is attached, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip"},
+		)
+
+		jobPersistentDiskAttachedMetric.WithLabelValues(
+			deploymentName,
+			jobName,
+			jobID,
+			jobIndex,
+			jobAZ,
+			jobIP,
+		).Set(float64(1))
+
+		jobPersistentDiskInfoMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "persistent_disk_info",
+				Help:      "BOSH Job Persistent Disk Info (always 1), labeled by the disk CID.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_persistent_disk_cid"},
+		)
+
+		jobPersistentDiskInfoMetric.WithLabelValues(
+			deploymentName,
+			jobName,
+			jobID,
+			jobIndex,
+			jobAZ,
+			jobIP,
+			jobPersistentDiskCID,
+		).Set(float64(1))
+
+		jobVMInfoMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "vm_info",
+				Help:      "BOSH Job VM Info (always 1), labeled by the VM type, the stemcell and the OS flavor (linux or windows).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_vm_type", "bosh_stemcell_name", "bosh_stemcell_version", "bosh_stemcell_os_name", "bosh_os_flavor"},
+		)
+
+		jobVMInfoMetric.WithLabelValues(
+			deploymentName,
+			jobName,
+			jobID,
+			jobIndex,
+			jobAZ,
+			jobIP,
+			jobVMType,
+			jobStemcellName,
+			jobStemcellVersion,
+			jobStemcellOSName,
+			"linux",
+		).Set(float64(1))
+
 		jobProcessHealthyMetric = prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -509,6 +668,21 @@ var _ = Describe("JobsCollector", func() {
 			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name"},
 		)
 
+		jobProcessStateMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job_process",
+				Name:      "state",
+				Help:      "BOSH Job Process State (1 for the current state, 0 for the other states).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_id", "bosh_job_index", "bosh_job_az", "bosh_job_ip", "bosh_job_process_name", "state"},
+		)
+
 		jobProcessHealthyMetric.WithLabelValues(
 			deploymentName,
 			jobName,
@@ -649,7 +823,7 @@ var _ = Describe("JobsCollector", func() {
 	})
 
 	JustBeforeEach(func() {
-		jobsCollector = NewJobsCollector(namespace, environment, boshName, boshUUID, azsFilter)
+		jobsCollector = NewJobsCollector(namespace, environment, boshName, boshUUID, prometheus.Labels{})
 	})
 
 	Describe("Describe", func() {
@@ -666,91 +840,111 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_healthy metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobHealthyDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobHealthyMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
+		})
+
+		It("returns a job_unresponsive_agent metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobUnresponsiveAgentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
+		})
+
+		It("returns a job_last_state_change_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobLastStateChangeTimestampMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_load_avg01 metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobLoadAvg01Desc)))
+			Eventually(descriptions).Should(Receive(Equal(jobLoadAvg01Metric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_load_avg05 metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobLoadAvg05Desc)))
+			Eventually(descriptions).Should(Receive(Equal(jobLoadAvg05Metric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_load_avg15 metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobLoadAvg15Desc)))
+			Eventually(descriptions).Should(Receive(Equal(jobLoadAvg15Metric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_cpu_sys metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobCPUSysDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobCPUSysMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_cpu_user metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobCPUUserDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobCPUUserMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_cpu_wait metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobCPUWaitDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobCPUWaitMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_mem_kb metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobMemKBDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobMemKBMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_mem_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobMemPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobMemPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_swap_kb metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobSwapKBDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobSwapKBMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_swap_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobSwapPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobSwapPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_system_disk_inode_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobSystemDiskInodePercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobSystemDiskInodePercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_system_disk_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobSystemDiskPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobSystemDiskPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_ephemeral_disk_inode_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobEphemeralDiskInodePercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobEphemeralDiskInodePercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_ephemeral_disk_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobEphemeralDiskPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobEphemeralDiskPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_persistent_disk_inode_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskInodePercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskInodePercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
 		})
 
 		It("returns a job_persistent_disk_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
+		})
+
+		It("returns a job_persistent_disk_attached metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskAttachedMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc())))
+		})
+
+		It("returns a job_persistent_disk_info metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobPersistentDiskInfoMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobPersistentDiskCID).Desc())))
+		})
+
+		It("returns a job_vm_info metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobVMInfoMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, jobStemcellName, jobStemcellVersion, jobStemcellOSName, "linux").Desc())))
 		})
 
 		It("returns a job_process_healthy metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobProcessHealthyDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobProcessHealthyMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName).Desc())))
 		})
 
 		It("returns a job_process_uptime_seconds metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobProcessUptimeDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobProcessUptimeMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName).Desc())))
 		})
 
 		It("returns a job_process_cpu_total metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobProcessCPUTotalDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobProcessCPUTotalMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName).Desc())))
 		})
 
 		It("returns a job_process_mem_kb metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobProcessMemKBDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobProcessMemKBMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName).Desc())))
 		})
 
 		It("returns a job_process_mem_percent metric description", func() {
-			//Eventually(descriptions).Should(Receive(Equal(jobProcessMemPercentDesc)))
+			Eventually(descriptions).Should(Receive(Equal(jobProcessMemPercentMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName).Desc())))
 		})
 
 		It("returns a last_jobs_scrape_timestamp metric description", func() {
@@ -780,6 +974,7 @@ var _ = Describe("JobsCollector", func() {
 					Name:    jobProcessName,
 					Uptime:  &jobProcessUptime,
 					Healthy: jobProcessHealthy,
+					State:   jobProcessState,
 					CPU:     deployments.CPU{Total: &jobProcessCPUTotal},
 					Mem:     deployments.MemInt{KB: &jobProcessMemKB, Percent: &jobProcessMemPercent},
 				},
@@ -825,14 +1020,24 @@ var _ = Describe("JobsCollector", func() {
 					Index:     jobIndex,
 					IPs:       []string{jobIP},
 					AZ:        jobAZ,
+					VMType:    jobVMType,
 					Healthy:   jobHealthy,
+					State:     jobState,
+					DiskCIDs:  []string{jobPersistentDiskCID},
 					Vitals:    vitals,
 					Processes: processes,
 				},
 			}
 
 			deploymentInfo = deployments.DeploymentInfo{
-				Name:      deploymentName,
+				Name: deploymentName,
+				InstanceGroups: []deployments.InstanceGroup{
+					{
+						Name:      jobName,
+						Instances: 1,
+						Stemcell:  deployments.Stemcell{Name: jobStemcellName, Version: jobStemcellVersion, OSName: jobStemcellOSName},
+					},
+				},
 				Instances: instances,
 			}
 
@@ -851,7 +1056,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_process_healthy metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobHealthyMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobHealthyMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -877,7 +1082,99 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("returns a job_process_healthy metric", func() {
-				Eventually(metrics).Should(Receive(Equal(jobHealthyMetric.WithLabelValues(
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobHealthyMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a job_state metric for the current state", func() {
+			jobStateMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, "unresponsive_agent").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobStateMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				"unresponsive_agent",
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_state metric for the other states", func() {
+			jobStateMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, "stopped").Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobStateMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				"stopped",
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the state is not a known state", func() {
+			BeforeEach(func() {
+				instances[0].State = "starting"
+				deploymentInfo.Instances = instances
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("returns a job_state metric for the current state", func() {
+				jobStateMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, "starting").Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobStateMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					"starting",
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a job_unresponsive_agent metric", func() {
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobUnresponsiveAgentMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the agent is responsive", func() {
+			BeforeEach(func() {
+				instances[0].State = "failing"
+
+				jobUnresponsiveAgentMetric.WithLabelValues(
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				).Set(float64(0))
+			})
+
+			It("returns a job_unresponsive_agent metric", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobUnresponsiveAgentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -890,7 +1187,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_load_avg01 metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobLoadAvg01Metric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobLoadAvg01Metric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -902,7 +1199,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_load_avg05 metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobLoadAvg05Metric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobLoadAvg05Metric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -914,7 +1211,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_load_avg15 metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobLoadAvg15Metric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobLoadAvg15Metric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -931,7 +1228,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return any job_load_avg metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobLoadAvg01Metric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobLoadAvg01Metric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -939,7 +1236,7 @@ var _ = Describe("JobsCollector", func() {
 					jobAZ,
 					jobIP,
 				))))
-				Consistently(metrics).ShouldNot(Receive(Equal(jobLoadAvg05Metric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobLoadAvg05Metric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -947,7 +1244,7 @@ var _ = Describe("JobsCollector", func() {
 					jobAZ,
 					jobIP,
 				))))
-				Consistently(metrics).ShouldNot(Receive(Equal(jobLoadAvg15Metric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobLoadAvg15Metric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -960,7 +1257,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_cpu_sys metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobCPUSysMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobCPUSysMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -980,7 +1277,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_cpu_sys metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobCPUSysMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobCPUSysMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -993,7 +1290,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_cpu_user metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobCPUUserMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobCPUUserMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1013,7 +1310,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_cpu_user metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobCPUUserMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobCPUUserMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1026,7 +1323,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_cpu_wait metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobCPUWaitMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobCPUWaitMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1046,7 +1343,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_cpu_wait metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobCPUWaitMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobCPUWaitMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1059,7 +1356,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_mem_kb metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobMemKBMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobMemKBMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1078,7 +1375,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_mem_kb metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobMemKBMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobMemKBMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1091,7 +1388,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_mem_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobMemPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobMemPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1110,7 +1407,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_mem_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobMemPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobMemPercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1123,7 +1420,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_swap_kb metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobSwapKBMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobSwapKBMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1142,7 +1439,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_swap_kb metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobSwapKBMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobSwapKBMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1155,7 +1452,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_swap_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobSwapPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobSwapPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1174,7 +1471,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_swap_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobSwapPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobSwapPercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1187,7 +1484,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_system_disk_inode_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobSystemDiskInodePercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobSystemDiskInodePercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1206,7 +1503,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_system_disk_inode_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobSystemDiskInodePercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobSystemDiskInodePercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1219,7 +1516,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_system_disk_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobSystemDiskPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobSystemDiskPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1238,7 +1535,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_system_disk_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobSystemDiskPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobSystemDiskPercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1251,7 +1548,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_ephemeral_disk_inode_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobEphemeralDiskInodePercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobEphemeralDiskInodePercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1270,7 +1567,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_ephemeral_disk_inode_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobEphemeralDiskInodePercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobEphemeralDiskInodePercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1283,7 +1580,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_ephemeral_disk_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobEphemeralDiskPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobEphemeralDiskPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1302,7 +1599,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_Ephemeral_disk_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobEphemeralDiskPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobEphemeralDiskPercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1315,7 +1612,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_persistent_disk_inode_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobPersistentDiskInodePercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobPersistentDiskInodePercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1334,7 +1631,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_persistent_disk_inode_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobPersistentDiskInodePercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobPersistentDiskInodePercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1347,7 +1644,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_persistent_disk_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobPersistentDiskPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobPersistentDiskPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1366,20 +1663,336 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_persistent_disk_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobPersistentDiskPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobPersistentDiskPercentMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a job_persistent_disk_attached metric", func() {
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobPersistentDiskAttachedMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_persistent_disk_info metric", func() {
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobPersistentDiskInfoMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				jobPersistentDiskCID,
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_vm_info metric", func() {
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				jobVMType,
+				jobStemcellName,
+				jobStemcellVersion,
+				jobStemcellOSName,
+				"linux",
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when the instance uses a resource pool", func() {
+			BeforeEach(func() {
+				instances[0].VMType = ""
+				instances[0].ResourcePool = jobResourcePool
+
+				jobVMInfoMetric.WithLabelValues(
 					deploymentName,
 					jobName,
 					jobID,
 					jobIndex,
 					jobAZ,
 					jobIP,
+					jobResourcePool,
+					jobStemcellName,
+					jobStemcellVersion,
+					jobStemcellOSName,
+					"linux",
+				).Set(float64(1))
+			})
+
+			It("returns a job_vm_info metric labeled by the resource pool", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					jobResourcePool,
+					jobStemcellName,
+					jobStemcellVersion,
+					jobStemcellOSName,
+					"linux",
 				))))
 				Consistently(errMetrics).ShouldNot(Receive())
 			})
 		})
 
+		Context("when the instance group stemcell is unknown", func() {
+			BeforeEach(func() {
+				deploymentsInfo[0].InstanceGroups = []deployments.InstanceGroup{}
+
+				jobVMInfoMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "linux").Set(float64(1))
+			})
+
+			It("returns a job_vm_info metric with empty stemcell labels and the OS flavor of the vitals", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+					deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "linux",
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			Context("and the instance reports Windows vitals", func() {
+				BeforeEach(func() {
+					instances[0].Vitals.Load = []string{"0.00", "0.00", "0.00"}
+					instances[0].Vitals.CPU.Wait = "0.0"
+					instances[0].Vitals.SystemDisk.InodePercent = "0"
+					instances[0].Vitals.EphemeralDisk.InodePercent = "0"
+					instances[0].Vitals.PersistentDisk.InodePercent = ""
+
+					jobVMInfoMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "windows").Set(float64(1))
+				})
+
+				It("returns a job_vm_info metric labeled by the windows OS flavor", func() {
+					Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+						deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "windows",
+					))))
+					Consistently(errMetrics).ShouldNot(Receive())
+				})
+
+				It("does not return the vitals Windows does not report", func() {
+					Consistently(metrics).ShouldNot(Receive(WithTransform(func(m prometheus.Metric) string {
+						return m.Desc().String()
+					}, Or(
+						ContainSubstring("job_load_avg"),
+						ContainSubstring("job_cpu_wait"),
+						ContainSubstring("disk_inode_percent"),
+					))))
+				})
+			})
+
+			Context("and the instances are read in short format", func() {
+				BeforeEach(func() {
+					deploymentsInfo[0].InstancesShortFormat = true
+
+					jobVMInfoMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "").Set(float64(1))
+				})
+
+				It("returns a job_vm_info metric with an empty OS flavor", func() {
+					Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+						deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobVMType, "", "", "", "",
+					))))
+					Consistently(errMetrics).ShouldNot(Receive())
+				})
+			})
+		})
+
+		Context("when the instance runs a Windows stemcell", func() {
+			BeforeEach(func() {
+				deploymentsInfo[0].InstanceGroups[0].Stemcell.OSName = "windows2019"
+
+				jobVMInfoMetric.WithLabelValues(
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					jobVMType,
+					jobStemcellName,
+					jobStemcellVersion,
+					"windows2019",
+					"windows",
+				).Set(float64(1))
+			})
+
+			It("returns a job_vm_info metric labeled by the windows OS flavor", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					jobVMType,
+					jobStemcellName,
+					jobStemcellVersion,
+					"windows2019",
+					"windows",
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("returns a job_mem_percent metric", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobMemPercentMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("does not return the vitals Windows does not report", func() {
+				for _, metric := range []*prometheus.GaugeVec{
+					jobLoadAvg01Metric,
+					jobLoadAvg05Metric,
+					jobLoadAvg15Metric,
+					jobCPUWaitMetric,
+					jobSystemDiskInodePercentMetric,
+					jobEphemeralDiskInodePercentMetric,
+					jobPersistentDiskInodePercentMetric,
+				} {
+					Consistently(metrics).ShouldNot(Receive(Equal(constGauge(metric,
+						deploymentName,
+						jobName,
+						jobID,
+						jobIndex,
+						jobAZ,
+						jobIP,
+					))))
+				}
+			})
+		})
+
+		Context("when the instances are read in short format", func() {
+			BeforeEach(func() {
+				deploymentsInfo[0].InstancesShortFormat = true
+			})
+
+			It("returns a job_vm_info metric", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobVMInfoMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					jobVMType,
+					jobStemcellName,
+					jobStemcellVersion,
+					jobStemcellOSName,
+					"linux",
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("does not return the state, vitals and processes metrics", func() {
+				Consistently(metrics).ShouldNot(Receive(WithTransform(func(m prometheus.Metric) string {
+					return m.Desc().String()
+				}, Or(
+					ContainSubstring("job_healthy"),
+					ContainSubstring("job_state"),
+					ContainSubstring("job_cpu_"),
+					ContainSubstring("job_process_"),
+					ContainSubstring("job_last_state_change_timestamp"),
+				))))
+			})
+		})
+
+		Context("when there is no persistent disk attached", func() {
+			BeforeEach(func() {
+				instances[0].DiskCIDs = []string{}
+
+				jobPersistentDiskAttachedMetric.WithLabelValues(
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				).Set(float64(0))
+			})
+
+			It("returns a job_persistent_disk_attached metric", func() {
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobPersistentDiskAttachedMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("does not return a job_persistent_disk_info metric", func() {
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobPersistentDiskInfoMetric,
+					deploymentName,
+					jobName,
+					jobID,
+					jobIndex,
+					jobAZ,
+					jobIP,
+					jobPersistentDiskCID,
+				))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		It("returns a job_process_state metric for the current state", func() {
+			jobProcessStateMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName, "starting").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessStateMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				jobProcessName,
+				"starting",
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_process_state metric for the other states", func() {
+			jobProcessStateMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP, jobProcessName, "unmonitored").Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessStateMetric,
+				deploymentName,
+				jobName,
+				jobID,
+				jobIndex,
+				jobAZ,
+				jobIP,
+				jobProcessName,
+				"unmonitored",
+			))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
 		It("returns a healthy job_process_healthy metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobProcessHealthyMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessHealthyMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1407,7 +2020,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("returns an unhealthy job_process_healthy metric", func() {
-				Eventually(metrics).Should(Receive(Equal(jobProcessHealthyMetric.WithLabelValues(
+				Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessHealthyMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1421,7 +2034,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_process_uptime_seconds metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobProcessUptimeMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessUptimeMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1439,7 +2052,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_process_uptime_seconds metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobProcessUptimeMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobProcessUptimeMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1453,7 +2066,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_process_cpu_total metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobProcessCPUTotalMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessCPUTotalMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1471,7 +2084,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_process_cpu_total metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobProcessCPUTotalMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobProcessCPUTotalMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1485,7 +2098,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_process_mem_kb metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobProcessMemKBMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessMemKBMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1503,7 +2116,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_process_mem_kb metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobProcessMemKBMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobProcessMemKBMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1517,7 +2130,7 @@ var _ = Describe("JobsCollector", func() {
 		})
 
 		It("returns a job_process_mem_percent metric", func() {
-			Eventually(metrics).Should(Receive(Equal(jobProcessMemPercentMetric.WithLabelValues(
+			Eventually(metrics).Should(Receive(Equal(constGauge(jobProcessMemPercentMetric,
 				deploymentName,
 				jobName,
 				jobID,
@@ -1535,7 +2148,7 @@ var _ = Describe("JobsCollector", func() {
 			})
 
 			It("does not return a job_process_mem_percent metric", func() {
-				Consistently(metrics).ShouldNot(Receive(Equal(jobProcessMemPercentMetric.WithLabelValues(
+				Consistently(metrics).ShouldNot(Receive(Equal(constGauge(jobProcessMemPercentMetric,
 					deploymentName,
 					jobName,
 					jobID,
@@ -1575,4 +2188,164 @@ var _ = Describe("JobsCollector", func() {
 			})
 		})
 	})
+
+	Describe("state changes", func() {
+		var (
+			now             time.Time
+			deploymentsInfo []deployments.DeploymentInfo
+		)
+
+		collect := func() chan prometheus.Metric {
+			metrics := make(chan prometheus.Metric, 100)
+			Expect(jobsCollector.Collect(deploymentsInfo, metrics)).To(Succeed())
+			close(metrics)
+			return metrics
+		}
+
+		lastStateChangeTimestamp := func() float64 {
+			metrics := collect()
+			desc := jobLastStateChangeTimestampMetric.WithLabelValues(deploymentName, jobName, jobID, jobIndex, jobAZ, jobIP).Desc()
+			for m := range metrics {
+				if m.Desc().String() == desc.String() {
+					metric := &dto.Metric{}
+					Expect(m.Write(metric)).To(Succeed())
+					return metric.GetGauge().GetValue()
+				}
+			}
+			Fail("no job_last_state_change_timestamp metric")
+			return 0
+		}
+
+		BeforeEach(func() {
+			now = time.Unix(1500000000, 0)
+			deploymentsInfo = []deployments.DeploymentInfo{
+				fakes.DeploymentInfo(deploymentName, fakes.Instance(jobName, 0)),
+			}
+		})
+
+		JustBeforeEach(func() {
+			jobsCollector.SetNow(func() time.Time { return now })
+		})
+
+		It("returns the time the instance is first observed", func() {
+			Expect(lastStateChangeTimestamp()).To(Equal(float64(1500000000)))
+		})
+
+		It("keeps the timestamp while the state does not change", func() {
+			lastStateChangeTimestamp()
+			now = now.Add(time.Minute)
+			Expect(lastStateChangeTimestamp()).To(Equal(float64(1500000000)))
+		})
+
+		It("updates the timestamp when the state changes", func() {
+			lastStateChangeTimestamp()
+			now = now.Add(time.Minute)
+			deploymentsInfo[0].Instances[0].State = "failing"
+			Expect(lastStateChangeTimestamp()).To(Equal(float64(1500000060)))
+		})
+
+		It("forgets the instances no longer reported", func() {
+			lastStateChangeTimestamp()
+			now = now.Add(time.Minute)
+			instances := deploymentsInfo[0].Instances
+			deploymentsInfo[0].Instances = []deployments.Instance{}
+			collect()
+			deploymentsInfo[0].Instances = instances
+			Expect(lastStateChangeTimestamp()).To(Equal(float64(1500000060)))
+		})
+
+		It("does not block other collections while an abandoned one is sending", func() {
+			go jobsCollector.Collect(deploymentsInfo, make(chan prometheus.Metric))
+
+			done := make(chan bool)
+			go func() {
+				collect()
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+	})
 })
+
+// BenchmarkJobsCollectorCollect measures the allocations of a Jobs collection
+// over 3000 instances (50 deployments of 60 instances running 5 processes).
+func BenchmarkJobsCollectorCollect(b *testing.B) {
+	uptime := uint64(3600)
+	cpuTotal := 0.5
+	memKB := uint64(1024)
+	memPercent := 1.5
+
+	processes := []deployments.Process{}
+	for i := 0; i < 5; i++ {
+		processes = append(processes, deployments.Process{
+			Name:    "fake-process-name-" + strconv.Itoa(i),
+			Uptime:  &uptime,
+			Healthy: true,
+			State:   "running",
+			CPU:     deployments.CPU{Total: &cpuTotal},
+			Mem:     deployments.MemInt{KB: &memKB, Percent: &memPercent},
+		})
+	}
+
+	vitals := deployments.Vitals{
+		CPU:            deployments.CPU{Sys: "1.5", User: "2.5", Wait: "0.5"},
+		Mem:            deployments.Mem{KB: "2048", Percent: "25"},
+		Swap:           deployments.Mem{KB: "512", Percent: "5"},
+		Load:           []string{"0.25", "0.50", "0.75"},
+		SystemDisk:     deployments.Disk{InodePercent: "30", Percent: "40"},
+		EphemeralDisk:  deployments.Disk{InodePercent: "10", Percent: "20"},
+		PersistentDisk: deployments.Disk{InodePercent: "5", Percent: "15"},
+	}
+
+	deploymentsInfo := []deployments.DeploymentInfo{}
+	for d := 0; d < 50; d++ {
+		deploymentInfo := deployments.DeploymentInfo{
+			Name: "fake-deployment-name-" + strconv.Itoa(d),
+			InstanceGroups: []deployments.InstanceGroup{
+				{
+					Name:      "fake-job-name",
+					Instances: 60,
+					Stemcell:  deployments.Stemcell{Name: "fake-stemcell-name", Version: "1.0", OSName: "ubuntu-jammy"},
+				},
+			},
+		}
+		for i := 0; i < 60; i++ {
+			deploymentInfo.Instances = append(deploymentInfo.Instances, deployments.Instance{
+				Name:      "fake-job-name",
+				ID:        "fake-job-id-" + strconv.Itoa(i),
+				Index:     strconv.Itoa(i),
+				IPs:       []string{"10.0." + strconv.Itoa(d) + "." + strconv.Itoa(i)},
+				AZ:        "z1",
+				VMType:    "fake-vm-type",
+				Healthy:   true,
+				State:     "running",
+				DiskCIDs:  []string{"fake-disk-cid-" + strconv.Itoa(i)},
+				Vitals:    vitals,
+				Processes: processes,
+			})
+		}
+		deploymentsInfo = append(deploymentsInfo, deploymentInfo)
+	}
+
+	jobsCollector := NewJobsCollector("test_exporter", "test_environment", "test_bosh_name", "test_bosh_uuid", prometheus.Labels{})
+
+	metrics := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range metrics {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := jobsCollector.Collect(deploymentsInfo, metrics); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	close(metrics)
+	<-done
+}
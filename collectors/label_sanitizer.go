@@ -0,0 +1,122 @@
+package collectors
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+// labelReplacementChar replaces the invalid UTF-8 sequences and control
+// characters of label values.
+const labelReplacementChar = '_'
+
+// LabelSanitizer makes the deployments, jobs and processes names safe to
+// use as label values: invalid UTF-8 sequences and control characters, that
+// would produce an exposition Prometheus fails to parse, are replaced, and
+// values are truncated to a maximum length.
+type LabelSanitizer struct {
+	maxLength int
+}
+
+// NewLabelSanitizer returns a sanitizer truncating the label values to
+// maxLength bytes (not truncating them if 0).
+func NewLabelSanitizer(maxLength int) *LabelSanitizer {
+	return &LabelSanitizer{maxLength: maxLength}
+}
+
+// Sanitize returns the value safe to use as a label value.
+func (s *LabelSanitizer) Sanitize(value string) string {
+	if s.valid(value) {
+		return value
+	}
+
+	sanitized := make([]byte, 0, len(value))
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		if (r == utf8.RuneError && size == 1) || unicode.IsControl(r) {
+			r = labelReplacementChar
+		}
+		if s.maxLength > 0 && len(sanitized)+utf8.RuneLen(r) > s.maxLength {
+			break
+		}
+		sanitized = append(sanitized, string(r)...)
+		i += size
+	}
+
+	return string(sanitized)
+}
+
+func (s *LabelSanitizer) valid(value string) bool {
+	if s.maxLength > 0 && len(value) > s.maxLength {
+		return false
+	}
+	for _, r := range value {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SanitizeDeployments returns a copy of the deployments with their names,
+// and the names of their instance groups, instances, processes, releases
+// and stemcells, sanitized. Deployments are shared with the deployments
+// cache, so they are copied rather than updated.
+func (s *LabelSanitizer) SanitizeDeployments(deploymentsInfo []deployments.DeploymentInfo) []deployments.DeploymentInfo {
+	sanitized := make([]deployments.DeploymentInfo, 0, len(deploymentsInfo))
+	for _, deployment := range deploymentsInfo {
+		sanitized = append(sanitized, s.sanitizeDeployment(deployment))
+	}
+	return sanitized
+}
+
+func (s *LabelSanitizer) sanitizeDeployment(deployment deployments.DeploymentInfo) deployments.DeploymentInfo {
+	deployment.Name = s.Sanitize(deployment.Name)
+
+	teams := make([]string, 0, len(deployment.Teams))
+	for _, team := range deployment.Teams {
+		teams = append(teams, s.Sanitize(team))
+	}
+	deployment.Teams = teams
+
+	instanceGroups := make([]deployments.InstanceGroup, 0, len(deployment.InstanceGroups))
+	for _, instanceGroup := range deployment.InstanceGroups {
+		instanceGroup.Name = s.Sanitize(instanceGroup.Name)
+		instanceGroups = append(instanceGroups, instanceGroup)
+	}
+	deployment.InstanceGroups = instanceGroups
+
+	instances := make([]deployments.Instance, 0, len(deployment.Instances))
+	for _, instance := range deployment.Instances {
+		instance.Name = s.Sanitize(instance.Name)
+		instance.AZ = s.Sanitize(instance.AZ)
+
+		processes := make([]deployments.Process, 0, len(instance.Processes))
+		for _, process := range instance.Processes {
+			process.Name = s.Sanitize(process.Name)
+			processes = append(processes, process)
+		}
+		instance.Processes = processes
+
+		instances = append(instances, instance)
+	}
+	deployment.Instances = instances
+
+	releases := make([]deployments.Release, 0, len(deployment.Releases))
+	for _, release := range deployment.Releases {
+		release.Name = s.Sanitize(release.Name)
+		releases = append(releases, release)
+	}
+	deployment.Releases = releases
+
+	stemcells := make([]deployments.Stemcell, 0, len(deployment.Stemcells))
+	for _, stemcell := range deployment.Stemcells {
+		stemcell.Name = s.Sanitize(stemcell.Name)
+		stemcell.OSName = s.Sanitize(stemcell.OSName)
+		stemcells = append(stemcells, stemcell)
+	}
+	deployment.Stemcells = stemcells
+
+	return deployment
+}
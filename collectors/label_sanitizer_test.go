@@ -0,0 +1,86 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+	"github.com/cloudfoundry-community/bosh_exporter/fakes"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("LabelSanitizer", func() {
+	var (
+		maxLength      int
+		labelSanitizer *LabelSanitizer
+	)
+
+	BeforeEach(func() {
+		maxLength = 0
+	})
+
+	JustBeforeEach(func() {
+		labelSanitizer = NewLabelSanitizer(maxLength)
+	})
+
+	Describe("Sanitize", func() {
+		It("keeps valid values", func() {
+			Expect(labelSanitizer.Sanitize("fake-deployment-name")).To(Equal("fake-deployment-name"))
+			Expect(labelSanitizer.Sanitize("déploiement-été")).To(Equal("déploiement-été"))
+			Expect(labelSanitizer.Sanitize("")).To(Equal(""))
+		})
+
+		It("replaces invalid UTF-8 sequences", func() {
+			Expect(labelSanitizer.Sanitize("fake-\xff\xfe-name")).To(Equal("fake-__-name"))
+		})
+
+		It("replaces control characters", func() {
+			Expect(labelSanitizer.Sanitize("fake\x00deployment\tname\n")).To(Equal("fake_deployment_name_"))
+		})
+
+		Context("when values are capped", func() {
+			BeforeEach(func() {
+				maxLength = 10
+			})
+
+			It("truncates longer values", func() {
+				Expect(labelSanitizer.Sanitize("fake-deployment-name")).To(Equal("fake-deplo"))
+			})
+
+			It("does not split multi-byte characters", func() {
+				Expect(labelSanitizer.Sanitize("fake-étéété")).To(Equal("fake-été"))
+			})
+
+			It("keeps shorter values", func() {
+				Expect(labelSanitizer.Sanitize("fake-name")).To(Equal("fake-name"))
+			})
+		})
+	})
+
+	Describe("SanitizeDeployments", func() {
+		var deploymentsInfo []deployments.DeploymentInfo
+
+		BeforeEach(func() {
+			deploymentsInfo = []deployments.DeploymentInfo{
+				fakes.DeploymentInfo("fake-\xffdeployment", fakes.Instance("fake-\x00job", 0, fakes.Process("fake-\nprocess"))),
+			}
+		})
+
+		It("sanitizes the deployments, jobs and processes names", func() {
+			sanitized := labelSanitizer.SanitizeDeployments(deploymentsInfo)
+			Expect(sanitized).To(HaveLen(1))
+			Expect(sanitized[0].Name).To(Equal("fake-_deployment"))
+			Expect(sanitized[0].InstanceGroups[0].Name).To(Equal("fake-_job"))
+			Expect(sanitized[0].Instances[0].Name).To(Equal("fake-_job"))
+			Expect(sanitized[0].Instances[0].Processes[0].Name).To(Equal("fake-_process"))
+		})
+
+		It("does not update the given deployments", func() {
+			labelSanitizer.SanitizeDeployments(deploymentsInfo)
+			Expect(deploymentsInfo[0].Name).To(Equal("fake-\xffdeployment"))
+			Expect(deploymentsInfo[0].Instances[0].Name).To(Equal("fake-\x00job"))
+			Expect(deploymentsInfo[0].Instances[0].Processes[0].Name).To(Equal("fake-\nprocess"))
+		})
+	})
+})
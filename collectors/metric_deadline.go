@@ -0,0 +1,83 @@
+package collectors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collection is a run of the collectors shared by the scrapes waiting for
+// it: collectors mutate their metrics, so they can not run concurrently, and
+// a scrape timing out leaves the collection running in background.
+type collection struct {
+	mutex   sync.Mutex
+	metrics []prometheus.Metric
+	err     error
+	done    chan struct{}
+
+	// waiters is the number of scrapes waiting for the collection, and
+	// finished whether collect returned, both guarded by the BoshCollector
+	// collectionMutex.
+	waiters  int
+	finished bool
+}
+
+func newCollection() *collection {
+	return &collection{done: make(chan struct{})}
+}
+
+// run runs collect, keeping the metrics it emits, then calls finished before
+// releasing the waiting scrapes.
+func (c *collection) run(collect func(ch chan<- prometheus.Metric) error, finished func()) {
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	for metric := range metricsCh {
+		c.mutex.Lock()
+		c.metrics = append(c.metrics, metric)
+		c.mutex.Unlock()
+	}
+
+	err := <-errCh
+	c.mutex.Lock()
+	c.err = err
+	c.mutex.Unlock()
+
+	finished()
+	close(c.done)
+}
+
+// wait forwards the metrics of the collection to ch once it completes,
+// returning whether it completed before the timeout (if any) elapsed. Once
+// the timeout elapsed, the metrics collected so far are forwarded.
+func (c *collection) wait(timeout time.Duration, ch chan<- prometheus.Metric) (bool, error) {
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	complete := true
+	select {
+	case <-c.done:
+	case <-timeoutCh:
+		complete = false
+	}
+
+	c.mutex.Lock()
+	metrics := c.metrics[:len(c.metrics):len(c.metrics)]
+	err := c.err
+	c.mutex.Unlock()
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+
+	return complete, err
+}
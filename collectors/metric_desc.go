@@ -0,0 +1,322 @@
+package collectors
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// metricDesc is the name, help and labels of a metric description, which
+// prometheus.Desc does not expose.
+type metricDesc struct {
+	fqName         string
+	help           string
+	variableLabels []string
+	constLabels    prometheus.Labels
+}
+
+// metricsDescs indexes by prometheus.Desc the descriptions of the metrics
+// created by a collector constructor, through its newGaugeVec, newGauge, ...
+// and newDesc methods. Descriptions created on every collection are carried
+// by the metrics themselves (see describedMetric) instead.
+type metricsDescs map[*prometheus.Desc]metricDesc
+
+// describedCollector is a collector indexing the descriptions of its metrics.
+type describedCollector interface {
+	descriptions() metricsDescs
+}
+
+func (d metricsDescs) index(collector prometheus.Collector, opts prometheus.Opts, labelNames []string) {
+	descCh := make(chan *prometheus.Desc, 1)
+	collector.Describe(descCh)
+	d[<-descCh] = metricDesc{
+		fqName:         prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		help:           opts.Help,
+		variableLabels: labelNames,
+		constLabels:    opts.ConstLabels,
+	}
+}
+
+// merge adds the descriptions indexed by the collector, if any.
+func (d metricsDescs) merge(collector interface{}) {
+	described, ok := collector.(describedCollector)
+	if !ok {
+		return
+	}
+
+	for desc, metricDesc := range described.descriptions() {
+		d[desc] = metricDesc
+	}
+}
+
+func (d metricsDescs) newDesc(fqName string, help string, variableLabels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	desc := prometheus.NewDesc(fqName, help, variableLabels, constLabels)
+	d[desc] = metricDesc{fqName: fqName, help: help, variableLabels: variableLabels, constLabels: constLabels}
+	return desc
+}
+
+func (d metricsDescs) newGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	gauge := prometheus.NewGauge(opts)
+	d.index(gauge, prometheus.Opts(opts), nil)
+	return gauge
+}
+
+func (d metricsDescs) newGaugeVec(opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
+	gaugeVec := prometheus.NewGaugeVec(opts, labelNames)
+	d.index(gaugeVec, prometheus.Opts(opts), labelNames)
+	return gaugeVec
+}
+
+func (d metricsDescs) newGaugeFunc(opts prometheus.GaugeOpts, function func() float64) prometheus.GaugeFunc {
+	gaugeFunc := prometheus.NewGaugeFunc(opts, function)
+	d.index(gaugeFunc, prometheus.Opts(opts), nil)
+	return gaugeFunc
+}
+
+func (d metricsDescs) newCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	counter := prometheus.NewCounter(opts)
+	d.index(counter, prometheus.Opts(opts), nil)
+	return counter
+}
+
+func (d metricsDescs) newCounterVec(opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
+	counterVec := prometheus.NewCounterVec(opts, labelNames)
+	d.index(counterVec, prometheus.Opts(opts), labelNames)
+	return counterVec
+}
+
+func (d metricsDescs) newCounterFunc(opts prometheus.CounterOpts, function func() float64) prometheus.CounterFunc {
+	counterFunc := prometheus.NewCounterFunc(opts, function)
+	d.index(counterFunc, prometheus.Opts(opts), nil)
+	return counterFunc
+}
+
+func (d metricsDescs) newHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *prometheus.HistogramVec {
+	histogramVec := prometheus.NewHistogramVec(opts, labelNames)
+	d.index(histogramVec, prometheus.Opts{
+		Namespace:   opts.Namespace,
+		Subsystem:   opts.Subsystem,
+		Name:        opts.Name,
+		Help:        opts.Help,
+		ConstLabels: opts.ConstLabels,
+	}, labelNames)
+	return histogramVec
+}
+
+// describeMetrics forwards the metrics emitted by collect to ch, carrying the
+// descriptions indexed by descs.
+func describeMetrics(descs metricsDescs, collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	return transformMetrics(collect, ch, func(metric prometheus.Metric) prometheus.Metric {
+		if desc, ok := descs[metric.Desc()]; ok {
+			return describedMetric{Metric: metric, desc: desc}
+		}
+		return metric
+	})
+}
+
+// describedMetric is a metric carrying its description.
+type describedMetric struct {
+	prometheus.Metric
+	desc metricDesc
+}
+
+// metricDescriber returns the description of metrics: the one they carry
+// (see describeMetrics), or else the one gathered into a registry (i.e. for
+// the metrics of the collectors registered by embedding programs), whose
+// constant and variable labels are not told apart. Gathered descriptions are cached, so a describer is meant to last
+// for a single collection.
+type metricDescriber struct {
+	gathered map[*prometheus.Desc]*metricDesc
+}
+
+func newMetricDescriber() *metricDescriber {
+	return &metricDescriber{gathered: map[*prometheus.Desc]*metricDesc{}}
+}
+
+func (d *metricDescriber) describe(metric prometheus.Metric) (metricDesc, bool) {
+	if described, ok := metric.(describedMetric); ok {
+		return described.desc, true
+	}
+
+	desc, ok := d.gathered[metric.Desc()]
+	if !ok {
+		desc = gatherMetricDesc(metric)
+		d.gathered[metric.Desc()] = desc
+	}
+	if desc == nil {
+		return metricDesc{}, false
+	}
+
+	return *desc, true
+}
+
+// singleMetricCollector collects a single metric.
+type singleMetricCollector struct {
+	metric prometheus.Metric
+}
+
+func (c singleMetricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.metric.Desc()
+}
+
+func (c singleMetricCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- c.metric
+}
+
+func gatherMetricDesc(metric prometheus.Metric) *metricDesc {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(singleMetricCollector{metric: metric}); err != nil {
+		return nil
+	}
+
+	metricFamilies, err := registry.Gather()
+	if err != nil || len(metricFamilies) != 1 || len(metricFamilies[0].Metric) != 1 {
+		return nil
+	}
+
+	labelNames := []string{}
+	for _, labelPair := range metricFamilies[0].Metric[0].Label {
+		labelNames = append(labelNames, labelPair.GetName())
+	}
+
+	return &metricDesc{
+		fqName:         metricFamilies[0].GetName(),
+		help:           metricFamilies[0].GetHelp(),
+		variableLabels: labelNames,
+		constLabels:    prometheus.Labels{},
+	}
+}
+
+// DescribedMetricsNames returns the sorted names of the metrics described by
+// the collector. Only the metrics of the collectors of this package are
+// named.
+func DescribedMetricsNames(collector prometheus.Collector) []string {
+	descs := metricsDescs{}
+	descs.merge(collector)
+
+	descCh := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(descCh)
+		close(descCh)
+	}()
+
+	isDescribed := map[string]bool{}
+	for desc := range descCh {
+		if indexed, ok := descs[desc]; ok {
+			isDescribed[indexed.fqName] = true
+		}
+	}
+
+	names := []string{}
+	for name := range isDescribed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func metricValue(m *dto.Metric) (prometheus.ValueType, float64, bool) {
+	switch {
+	case m.Gauge != nil:
+		return prometheus.GaugeValue, m.Gauge.GetValue(), true
+	case m.Counter != nil:
+		return prometheus.CounterValue, m.Counter.GetValue(), true
+	case m.Untyped != nil:
+		return prometheus.UntypedValue, m.Untyped.GetValue(), true
+	}
+
+	return 0, 0, false
+}
+
+func metricLabelValues(m *dto.Metric, variableLabels []string) []string {
+	labels := map[string]string{}
+	for _, labelPair := range m.Label {
+		labels[labelPair.GetName()] = labelPair.GetValue()
+	}
+
+	labelValues := []string{}
+	for _, label := range variableLabels {
+		labelValues = append(labelValues, labels[label])
+	}
+
+	return labelValues
+}
+
+// transformMetrics forwards the metrics emitted by collect to ch, passing
+// each one through transform.
+func transformMetrics(collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric, transform func(metric prometheus.Metric) prometheus.Metric) error {
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	for metric := range metricsCh {
+		ch <- transform(metric)
+	}
+
+	return <-errCh
+}
+
+// addMetricLabels adds the labelNames labels to a metric with a
+// requiredLabel label, using the values returned by labelsFunc for the
+// metric label values. Other metrics are returned unmodified.
+func addMetricLabels(describer *metricDescriber, metric prometheus.Metric, requiredLabel string, labelNames []string, labelsFunc func(labelValues map[string]string) map[string]string) prometheus.Metric {
+	desc, ok := describer.describe(metric)
+	if !ok {
+		return metric
+	}
+
+	hasRequiredLabel := false
+	for _, label := range desc.variableLabels {
+		if label == requiredLabel {
+			hasRequiredLabel = true
+			break
+		}
+	}
+	if !hasRequiredLabel {
+		return metric
+	}
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		return metric
+	}
+
+	valueType, value, ok := metricValue(&m)
+	if !ok {
+		return metric
+	}
+
+	values := metricLabelValues(&m, desc.variableLabels)
+	labelValues := map[string]string{}
+	for i, label := range desc.variableLabels {
+		labelValues[label] = values[i]
+	}
+
+	extraLabels := labelsFunc(labelValues)
+	for _, name := range labelNames {
+		values = append(values, extraLabels[name])
+	}
+
+	labeledDesc := metricDesc{
+		fqName:         desc.fqName,
+		help:           desc.help,
+		variableLabels: append(append([]string{}, desc.variableLabels...), labelNames...),
+		constLabels:    desc.constLabels,
+	}
+	labeledMetric, err := prometheus.NewConstMetric(
+		prometheus.NewDesc(labeledDesc.fqName, labeledDesc.help, labeledDesc.variableLabels, labeledDesc.constLabels),
+		valueType,
+		value,
+		values...,
+	)
+	if err != nil {
+		return metric
+	}
+
+	return describedMetric{Metric: labeledMetric, desc: labeledDesc}
+}
@@ -0,0 +1,36 @@
+package collectors
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectWithLimit forwards at most limit of the metrics emitted by collect
+// to ch, returning the number of metrics discarded beyond the limit by
+// metric name.
+func collectWithLimit(limit int, collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) (map[string]int, error) {
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	describer := newMetricDescriber()
+	dropped := map[string]int{}
+	series := 0
+	for metric := range metricsCh {
+		if series < limit {
+			series++
+			ch <- metric
+			continue
+		}
+
+		fqName := metric.Desc().String()
+		if desc, ok := describer.describe(metric); ok {
+			fqName = desc.fqName
+		}
+		dropped[fqName]++
+	}
+
+	return dropped, <-errCh
+}
@@ -0,0 +1,41 @@
+package collectors
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type timestampedMetric struct {
+	prometheus.Metric
+	timestampMs int64
+}
+
+func (m timestampedMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+
+	out.TimestampMs = &m.timestampMs
+	return nil
+}
+
+// collectWithTimestamp attaches the given timestamp to all the metrics
+// emitted by collect.
+func collectWithTimestamp(timestamp time.Time, collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	timestampMs := timestamp.UnixNano() / int64(time.Millisecond)
+
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	for metric := range metricsCh {
+		ch <- timestampedMetric{Metric: metric, timestampMs: timestampMs}
+	}
+
+	return <-errCh
+}
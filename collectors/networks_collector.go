@@ -0,0 +1,469 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+type NetworksCollector struct {
+	boshClient                              director.Director
+	networkIPsUsedMetric                    *prometheus.GaugeVec
+	networkIPsFreeMetric                    *prometheus.GaugeVec
+	cloudConfigDefinitionUsedMetric         *prometheus.GaugeVec
+	cloudConfigUnusedDefinitionsMetric      *prometheus.GaugeVec
+	lastNetworksScrapeTimestampMetric       prometheus.Gauge
+	lastNetworksScrapeDurationSecondsMetric prometheus.Gauge
+	descs                                   metricsDescs
+}
+
+// Types of the cloud config definitions whose usage by deployments is
+// reported.
+const (
+	cloudConfigVMTypeDefinition   = "vm_type"
+	cloudConfigNetworkDefinition  = "network"
+	cloudConfigDiskTypeDefinition = "disk_type"
+)
+
+type cloudConfigManifest struct {
+	AZs         []cloudConfigAZ         `yaml:"azs"`
+	Networks    []cloudConfigNetwork    `yaml:"networks"`
+	VMTypes     []cloudConfigDefinition `yaml:"vm_types"`
+	DiskTypes   []cloudConfigDefinition `yaml:"disk_types"`
+	Compilation cloudConfigCompilation  `yaml:"compilation"`
+}
+
+type cloudConfigDefinition struct {
+	Name string `yaml:"name"`
+}
+
+type cloudConfigCompilation struct {
+	VMType  string `yaml:"vm_type"`
+	Network string `yaml:"network"`
+}
+
+type cloudConfigAZ struct {
+	Name string `yaml:"name"`
+	CPI  string `yaml:"cpi"`
+}
+
+type cloudConfigNetwork struct {
+	Name    string              `yaml:"name"`
+	Type    string              `yaml:"type"`
+	Subnets []cloudConfigSubnet `yaml:"subnets"`
+}
+
+type cloudConfigSubnet struct {
+	Range    string   `yaml:"range"`
+	Gateway  string   `yaml:"gateway"`
+	Reserved []string `yaml:"reserved"`
+}
+
+// cloudConfigUsageManifest is the part of a deployment manifest referring to
+// cloud config definitions.
+type cloudConfigUsageManifest struct {
+	InstanceGroups []cloudConfigUsageInstanceGroup `yaml:"instance_groups"`
+	Jobs           []cloudConfigUsageInstanceGroup `yaml:"jobs"`
+}
+
+type cloudConfigUsageInstanceGroup struct {
+	VMType             string                  `yaml:"vm_type"`
+	PersistentDiskType string                  `yaml:"persistent_disk_type"`
+	Networks           []cloudConfigDefinition `yaml:"networks"`
+}
+
+func NewNetworksCollector(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+	boshClient director.Director,
+) *NetworksCollector {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	networkIPsUsedMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "network",
+			Name:        "ips_used",
+			Help:        "Number of IPs of the BOSH Network Subnet allocated to instances.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_network_name", "bosh_network_subnet"},
+	)
+
+	networkIPsFreeMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "network",
+			Name:        "ips_free",
+			Help:        "Number of IPs of the BOSH Network Subnet available to instances.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_network_name", "bosh_network_subnet"},
+	)
+
+	cloudConfigDefinitionUsedMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "cloud_config",
+			Name:        "definition_used",
+			Help:        "BOSH Cloud Config definition usage (1 if used by a deployment, 0 otherwise).",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_cloud_config_type", "bosh_cloud_config_name"},
+	)
+
+	cloudConfigUnusedDefinitionsMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "cloud_config",
+			Name:        "unused_definitions",
+			Help:        "Number of BOSH Cloud Config definitions not used by any deployment.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_cloud_config_type"},
+	)
+
+	lastNetworksScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_networks_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Networks metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastNetworksScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_networks_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Networks metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	collector := &NetworksCollector{
+		boshClient:                              boshClient,
+		networkIPsUsedMetric:                    networkIPsUsedMetric,
+		networkIPsFreeMetric:                    networkIPsFreeMetric,
+		cloudConfigDefinitionUsedMetric:         cloudConfigDefinitionUsedMetric,
+		cloudConfigUnusedDefinitionsMetric:      cloudConfigUnusedDefinitionsMetric,
+		lastNetworksScrapeTimestampMetric:       lastNetworksScrapeTimestampMetric,
+		lastNetworksScrapeDurationSecondsMetric: lastNetworksScrapeDurationSecondsMetric,
+		descs:                                   descs,
+	}
+	return collector
+}
+
+func (c *NetworksCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	var begun = time.Now()
+
+	cloudConfig, err := c.boshClient.LatestCloudConfig()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error while reading Cloud Config: %v", err))
+	}
+
+	var manifest cloudConfigManifest
+	if err = yaml.Unmarshal([]byte(cloudConfig.Properties), &manifest); err != nil {
+		return errors.New(fmt.Sprintf("Error while parsing Cloud Config: %v", err))
+	}
+
+	usedIPs := map[string]net.IP{}
+	for _, deployment := range deployments {
+		for _, instance := range deployment.Instances {
+			for _, instanceIP := range instance.IPs {
+				if ip := net.ParseIP(instanceIP); ip != nil {
+					usedIPs[ip.String()] = ip
+				}
+			}
+		}
+	}
+
+	c.networkIPsUsedMetric.Reset()
+	c.networkIPsFreeMetric.Reset()
+
+	for _, network := range manifest.Networks {
+		if network.Type != "" && network.Type != "manual" {
+			continue
+		}
+
+		for _, subnet := range network.Subnets {
+			c.reportSubnetMetrics(network.Name, subnet, usedIPs)
+		}
+	}
+
+	discardedManifests, err := c.discardedDeploymentsManifests(deployments)
+	if err != nil {
+		return err
+	}
+
+	used, err := cloudConfigUsedDefinitions(manifest, deployments, discardedManifests)
+	if err != nil {
+		return err
+	}
+
+	c.cloudConfigDefinitionUsedMetric.Reset()
+	c.cloudConfigUnusedDefinitionsMetric.Reset()
+
+	networkNames := []string{}
+	for _, network := range manifest.Networks {
+		networkNames = append(networkNames, network.Name)
+	}
+	c.reportCloudConfigDefinitionsMetrics(cloudConfigVMTypeDefinition, definitionNames(manifest.VMTypes), used[cloudConfigVMTypeDefinition])
+	c.reportCloudConfigDefinitionsMetrics(cloudConfigNetworkDefinition, networkNames, used[cloudConfigNetworkDefinition])
+	c.reportCloudConfigDefinitionsMetrics(cloudConfigDiskTypeDefinition, definitionNames(manifest.DiskTypes), used[cloudConfigDiskTypeDefinition])
+
+	c.networkIPsUsedMetric.Collect(ch)
+	c.networkIPsFreeMetric.Collect(ch)
+	c.cloudConfigDefinitionUsedMetric.Collect(ch)
+	c.cloudConfigUnusedDefinitionsMetric.Collect(ch)
+
+	c.lastNetworksScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastNetworksScrapeTimestampMetric.Collect(ch)
+
+	c.lastNetworksScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.lastNetworksScrapeDurationSecondsMetric.Collect(ch)
+
+	return nil
+}
+
+func (c *NetworksCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.networkIPsUsedMetric.Describe(ch)
+	c.networkIPsFreeMetric.Describe(ch)
+	c.cloudConfigDefinitionUsedMetric.Describe(ch)
+	c.cloudConfigUnusedDefinitionsMetric.Describe(ch)
+	c.lastNetworksScrapeTimestampMetric.Describe(ch)
+	c.lastNetworksScrapeDurationSecondsMetric.Describe(ch)
+}
+
+// reportCloudConfigDefinitionsMetrics reports the usage of the cloud config
+// definitions of a type. Types without any definition are not reported.
+func (c *NetworksCollector) reportCloudConfigDefinitionsMetrics(
+	definitionType string,
+	names []string,
+	used map[string]bool,
+) {
+	if len(names) == 0 {
+		return
+	}
+
+	var unused float64
+	for _, name := range names {
+		var usedMetric float64
+		if used[name] {
+			usedMetric = 1
+		} else {
+			unused++
+		}
+		c.cloudConfigDefinitionUsedMetric.WithLabelValues(definitionType, name).Set(usedMetric)
+	}
+	c.cloudConfigUnusedDefinitionsMetric.WithLabelValues(definitionType).Set(unused)
+}
+
+// discardedDeploymentsManifests returns the manifests of the deployments
+// discarded by the deployments, teams and shard filters, as they still use
+// the cloud config definitions.
+func (c *NetworksCollector) discardedDeploymentsManifests(deploymentsInfo []deployments.DeploymentInfo) ([]string, error) {
+	collected := map[string]bool{}
+	for _, deployment := range deploymentsInfo {
+		collected[deployment.Name] = true
+	}
+
+	boshDeployments, err := c.boshClient.Deployments()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error while reading Deployments: %v", err))
+	}
+
+	manifests := []string{}
+	for _, deployment := range boshDeployments {
+		if collected[deployment.Name()] {
+			continue
+		}
+
+		manifest, err := deployment.Manifest()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error while reading Manifest for deployment `%s`: %v", deployment.Name(), err))
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// cloudConfigUsedDefinitions returns the names of the cloud config
+// definitions used by the deployment manifests (including the ones of the
+// discarded deployments) and the compilation workers, by definition type.
+func cloudConfigUsedDefinitions(manifest cloudConfigManifest, deployments []deployments.DeploymentInfo, discardedManifests []string) (map[string]map[string]bool, error) {
+	used := map[string]map[string]bool{
+		cloudConfigVMTypeDefinition:   {manifest.Compilation.VMType: true},
+		cloudConfigNetworkDefinition:  {manifest.Compilation.Network: true},
+		cloudConfigDiskTypeDefinition: {},
+	}
+
+	for _, deployment := range deployments {
+		for _, instanceGroup := range deployment.InstanceGroups {
+			used[cloudConfigVMTypeDefinition][instanceGroup.VMType] = true
+			used[cloudConfigDiskTypeDefinition][instanceGroup.PersistentDiskType] = true
+			for _, network := range instanceGroup.Networks {
+				used[cloudConfigNetworkDefinition][network] = true
+			}
+		}
+
+		// the director also reports the VM type each instance was last
+		// deployed with
+		for _, instance := range deployment.Instances {
+			used[cloudConfigVMTypeDefinition][instance.VMType] = true
+		}
+	}
+
+	for _, content := range discardedManifests {
+		var m cloudConfigUsageManifest
+		if err := yaml.Unmarshal([]byte(content), &m); err != nil {
+			return used, errors.New(fmt.Sprintf("Error while parsing Manifest of a discarded deployment: %v", err))
+		}
+
+		// v1 manifests use `jobs` instead of `instance_groups`
+		for _, instanceGroup := range append(m.InstanceGroups, m.Jobs...) {
+			used[cloudConfigVMTypeDefinition][instanceGroup.VMType] = true
+			used[cloudConfigDiskTypeDefinition][instanceGroup.PersistentDiskType] = true
+			for _, network := range instanceGroup.Networks {
+				used[cloudConfigNetworkDefinition][network.Name] = true
+			}
+		}
+	}
+
+	return used, nil
+}
+
+func definitionNames(definitions []cloudConfigDefinition) []string {
+	names := []string{}
+	for _, definition := range definitions {
+		names = append(names, definition.Name)
+	}
+	return names
+}
+
+func (c *NetworksCollector) reportSubnetMetrics(
+	networkName string,
+	subnet cloudConfigSubnet,
+	usedIPs map[string]net.IP,
+) {
+	_, subnetRange, err := net.ParseCIDR(subnet.Range)
+	if err != nil {
+		return
+	}
+
+	first, last := subnetBoundaries(subnetRange)
+	excluded := []net.IP{}
+	if subnetRange.IP.To4() != nil {
+		excluded = append(excluded, intToIP(first, len(subnetRange.IP)), intToIP(last, len(subnetRange.IP)))
+	}
+	if gateway := net.ParseIP(subnet.Gateway); gateway != nil {
+		excluded = append(excluded, gateway)
+	}
+
+	excludedIPs := map[string]bool{}
+	for _, ip := range excluded {
+		if subnetRange.Contains(ip) {
+			excludedIPs[ip.String()] = true
+		}
+	}
+
+	reserved := big.NewInt(0)
+	for _, reservedRange := range subnet.Reserved {
+		reservedFirst, reservedLast, ok := parseIPRange(reservedRange, subnetRange)
+		if !ok {
+			continue
+		}
+		reserved.Add(reserved, new(big.Int).Add(new(big.Int).Sub(reservedLast, reservedFirst), big.NewInt(1)))
+
+		for ip := range excludedIPs {
+			ipInt := ipToInt(net.ParseIP(ip))
+			if ipInt.Cmp(reservedFirst) >= 0 && ipInt.Cmp(reservedLast) <= 0 {
+				delete(excludedIPs, ip)
+			}
+		}
+	}
+
+	used := 0
+	for _, ip := range usedIPs {
+		if subnetRange.Contains(ip) {
+			used++
+		}
+	}
+
+	free := new(big.Int).Add(new(big.Int).Sub(last, first), big.NewInt(1))
+	free.Sub(free, reserved)
+	free.Sub(free, big.NewInt(int64(len(excludedIPs)+used)))
+	if free.Sign() < 0 {
+		free.SetInt64(0)
+	}
+	freeIPs, _ := new(big.Float).SetInt(free).Float64()
+
+	c.networkIPsUsedMetric.WithLabelValues(networkName, subnetRange.String()).Set(float64(used))
+	c.networkIPsFreeMetric.WithLabelValues(networkName, subnetRange.String()).Set(freeIPs)
+}
+
+func subnetBoundaries(subnetRange *net.IPNet) (*big.Int, *big.Int) {
+	first := ipToInt(subnetRange.IP)
+
+	ones, bits := subnetRange.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	last := new(big.Int).Sub(new(big.Int).Add(first, size), big.NewInt(1))
+
+	return first, last
+}
+
+func parseIPRange(ipRange string, subnetRange *net.IPNet) (*big.Int, *big.Int, bool) {
+	boundaries := strings.SplitN(ipRange, "-", 2)
+
+	firstIP := net.ParseIP(strings.TrimSpace(boundaries[0]))
+	lastIP := firstIP
+	if len(boundaries) == 2 {
+		lastIP = net.ParseIP(strings.TrimSpace(boundaries[1]))
+	}
+	if firstIP == nil || lastIP == nil || !subnetRange.Contains(firstIP) || !subnetRange.Contains(lastIP) {
+		return nil, nil, false
+	}
+
+	first, last := ipToInt(firstIP), ipToInt(lastIP)
+	if first.Cmp(last) > 0 {
+		return nil, nil, false
+	}
+
+	return first, last, true
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	if ip4 := ip.To4(); ip4 != nil {
+		return new(big.Int).SetBytes(ip4)
+	}
+
+	return new(big.Int).SetBytes(ip.To16())
+}
+
+func intToIP(ipInt *big.Int, length int) net.IP {
+	ip := make(net.IP, length)
+	ipBytes := ipInt.Bytes()
+	copy(ip[length-len(ipBytes):], ipBytes)
+
+	return ip
+}
+
+func (c *NetworksCollector) descriptions() metricsDescs {
+	return c.descs
+}
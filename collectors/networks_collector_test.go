@@ -0,0 +1,385 @@
+package collectors_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("NetworksCollector", func() {
+	var (
+		namespace         string
+		environment       string
+		boshName          string
+		boshUUID          string
+		boshClient        *directorfakes.FakeDirector
+		networksCollector *NetworksCollector
+
+		networkIPsUsedMetric                    *prometheus.GaugeVec
+		networkIPsFreeMetric                    *prometheus.GaugeVec
+		cloudConfigDefinitionUsedMetric         *prometheus.GaugeVec
+		cloudConfigUnusedDefinitionsMetric      *prometheus.GaugeVec
+		lastNetworksScrapeTimestampMetric       prometheus.Gauge
+		lastNetworksScrapeDurationSecondsMetric prometheus.Gauge
+
+		networkName   = "fake-network-name"
+		networkSubnet = "10.0.0.0/24"
+		cloudConfig   = `---
+networks:
+- name: fake-network-name
+  type: manual
+  subnets:
+  - range: 10.0.0.0/24
+    gateway: 10.0.0.1
+    reserved:
+    - 10.0.0.2-10.0.0.10
+    - 10.0.0.255
+- name: fake-dynamic-network-name
+  type: dynamic
+- name: fake-unused-network-name
+  type: dynamic
+vm_types:
+- name: fake-vm-type
+- name: fake-compilation-vm-type
+- name: fake-unused-vm-type
+disk_types:
+- name: fake-disk-type
+compilation:
+  vm_type: fake-compilation-vm-type
+  network: fake-dynamic-network-name
+`
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		environment = "test_environment"
+		boshName = "test_bosh_name"
+		boshUUID = "test_bosh_uuid"
+		boshClient = &directorfakes.FakeDirector{}
+		boshClient.LatestCloudConfigReturns(director.CloudConfig{Properties: cloudConfig}, nil)
+
+		networkIPsUsedMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "network",
+				Name:      "ips_used",
+				Help:      "Number of IPs of the BOSH Network Subnet allocated to instances.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_network_name", "bosh_network_subnet"},
+		)
+
+		networkIPsFreeMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "network",
+				Name:      "ips_free",
+				Help:      "Number of IPs of the BOSH Network Subnet available to instances.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_network_name", "bosh_network_subnet"},
+		)
+
+		cloudConfigDefinitionUsedMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "cloud_config",
+				Name:      "definition_used",
+				Help:      "BOSH Cloud Config definition usage (1 if used by a deployment, 0 otherwise).",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_cloud_config_type", "bosh_cloud_config_name"},
+		)
+
+		cloudConfigUnusedDefinitionsMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "cloud_config",
+				Name:      "unused_definitions",
+				Help:      "Number of BOSH Cloud Config definitions not used by any deployment.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_cloud_config_type"},
+		)
+
+		lastNetworksScrapeTimestampMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_networks_scrape_timestamp",
+				Help:      "Number of seconds since 1970 since last scrape of Networks metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastNetworksScrapeDurationSecondsMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_networks_scrape_duration_seconds",
+				Help:      "Duration of the last scrape of Networks metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+	})
+
+	JustBeforeEach(func() {
+		networksCollector = NewNetworksCollector(
+			namespace,
+			environment,
+			boshName,
+			boshUUID,
+			prometheus.Labels{},
+			boshClient,
+		)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go networksCollector.Describe(descriptions)
+		})
+
+		It("returns a network_ips_used metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(networkIPsUsedMetric.WithLabelValues(networkName, networkSubnet).Desc())))
+		})
+
+		It("returns a network_ips_free metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(networkIPsFreeMetric.WithLabelValues(networkName, networkSubnet).Desc())))
+		})
+
+		It("returns a cloud_config_definition_used metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-vm-type").Desc())))
+		})
+
+		It("returns a cloud_config_unused_definitions metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(cloudConfigUnusedDefinitionsMetric.WithLabelValues("vm_type").Desc())))
+		})
+
+		It("returns a last_networks_scrape_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastNetworksScrapeTimestampMetric.Desc())))
+		})
+
+		It("returns a last_networks_scrape_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastNetworksScrapeDurationSecondsMetric.Desc())))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			deploymentsInfo []deployments.DeploymentInfo
+
+			metrics    chan prometheus.Metric
+			errMetrics chan error
+		)
+
+		BeforeEach(func() {
+			deploymentsInfo = []deployments.DeploymentInfo{
+				{
+					Name: "fake-deployment-name",
+					InstanceGroups: []deployments.InstanceGroup{
+						{
+							Name:     "fake-job-name",
+							Networks: []string{networkName},
+						},
+						{
+							Name:               "fake-other-job-name",
+							VMType:             "fake-vm-type",
+							PersistentDiskType: "fake-disk-type",
+							Networks:           []string{networkName},
+						},
+					},
+					Instances: []deployments.Instance{
+						{IPs: []string{"10.0.0.11"}},
+						{IPs: []string{"10.0.0.12", "192.168.0.1"}},
+					},
+				},
+			}
+
+			metrics = make(chan prometheus.Metric)
+			errMetrics = make(chan error, 1)
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				if err := networksCollector.Collect(deploymentsInfo, metrics); err != nil {
+					errMetrics <- err
+				}
+			}()
+		})
+
+		It("returns a network_ips_used metric", func() {
+			networkIPsUsedMetric.WithLabelValues(networkName, networkSubnet).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(networkIPsUsedMetric.WithLabelValues(networkName, networkSubnet))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a network_ips_free metric", func() {
+			networkIPsFreeMetric.WithLabelValues(networkName, networkSubnet).Set(float64(242))
+
+			Eventually(metrics).Should(Receive(Equal(networkIPsFreeMetric.WithLabelValues(networkName, networkSubnet))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a cloud_config_definition_used metric for the definitions used by the deployments", func() {
+			cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-vm-type").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-vm-type"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a cloud_config_definition_used metric for the definitions used by the compilation workers", func() {
+			cloudConfigDefinitionUsedMetric.WithLabelValues("network", "fake-dynamic-network-name").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(cloudConfigDefinitionUsedMetric.WithLabelValues("network", "fake-dynamic-network-name"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a cloud_config_definition_used metric for the unused definitions", func() {
+			cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-unused-vm-type").Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-unused-vm-type"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a cloud_config_unused_definitions metric", func() {
+			cloudConfigUnusedDefinitionsMetric.WithLabelValues("network").Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(cloudConfigUnusedDefinitionsMetric.WithLabelValues("network"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a cloud_config_unused_definitions metric when all definitions are used", func() {
+			cloudConfigUnusedDefinitionsMetric.WithLabelValues("disk_type").Set(float64(0))
+
+			Eventually(metrics).Should(Receive(Equal(cloudConfigUnusedDefinitionsMetric.WithLabelValues("disk_type"))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when there are no manual networks", func() {
+			BeforeEach(func() {
+				boshClient.LatestCloudConfigReturns(director.CloudConfig{Properties: "networks: []"}, nil)
+			})
+
+			It("returns only a last_networks_scrape_timestamp & last_networks_scrape_duration_seconds metric", func() {
+				Eventually(metrics).Should(Receive())
+				Eventually(metrics).Should(Receive())
+				Consistently(metrics).ShouldNot(Receive())
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
+		Context("when a deployment is discarded by the filters", func() {
+			var (
+				collectedDeployment *directorfakes.FakeDeployment
+				discardedDeployment *directorfakes.FakeDeployment
+			)
+
+			BeforeEach(func() {
+				collectedDeployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return "fake-deployment-name" },
+				}
+				discardedDeployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return "fake-discarded-deployment-name" },
+					ManifestStub: func() (string, error) {
+						return "instance_groups:\n- name: fake-job-name\n  vm_type: fake-unused-vm-type\n  networks:\n  - name: fake-unused-network-name\n", nil
+					},
+				}
+				boshClient.DeploymentsReturns([]director.Deployment{collectedDeployment, discardedDeployment}, nil)
+			})
+
+			It("returns a cloud_config_definition_used metric for the definitions used by the discarded deployment", func() {
+				cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-unused-vm-type").Set(float64(1))
+
+				Eventually(metrics).Should(Receive(Equal(cloudConfigDefinitionUsedMetric.WithLabelValues("vm_type", "fake-unused-vm-type"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("returns a cloud_config_unused_definitions metric not counting the definitions used by the discarded deployment", func() {
+				cloudConfigUnusedDefinitionsMetric.WithLabelValues("network").Set(float64(0))
+
+				Eventually(metrics).Should(Receive(Equal(cloudConfigUnusedDefinitionsMetric.WithLabelValues("network"))))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+
+			It("does not read the manifest of the collected deployment", func() {
+				Eventually(metrics).Should(Receive())
+				Expect(collectedDeployment.ManifestCallCount()).To(BeZero())
+			})
+
+			Context("and it fails to get its manifest", func() {
+				BeforeEach(func() {
+					discardedDeployment.ManifestStub = func() (string, error) { return "", errors.New("no manifest") }
+				})
+
+				It("returns an error", func() {
+					Consistently(metrics).ShouldNot(Receive())
+					Eventually(errMetrics).Should(Receive())
+				})
+			})
+		})
+
+		Context("when it fails to get the deployments", func() {
+			BeforeEach(func() {
+				boshClient.DeploymentsReturns(nil, errors.New("no deployments"))
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+
+		Context("when it fails to get the cloud config", func() {
+			BeforeEach(func() {
+				boshClient.LatestCloudConfigReturns(director.CloudConfig{}, errors.New("no cloud config"))
+			})
+
+			It("returns an error", func() {
+				Consistently(metrics).ShouldNot(Receive())
+				Eventually(errMetrics).Should(Receive())
+			})
+		})
+	})
+})
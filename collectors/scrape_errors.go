@@ -0,0 +1,42 @@
+package collectors
+
+import (
+	"regexp"
+)
+
+const (
+	AuthErrorKind    = "auth"
+	TimeoutErrorKind = "timeout"
+	TaskErrorKind    = "task"
+	ParseErrorKind   = "parse"
+	OtherErrorKind   = "other"
+
+	// FetcherErrorCollector labels the errors fetching the deployments,
+	// shared by all collectors.
+	FetcherErrorCollector = "fetcher"
+)
+
+// Errors are wrapped as strings all along the BOSH client and the exporter,
+// so they are classified after their message.
+var errorKinds = []struct {
+	kind   string
+	regexp *regexp.Regexp
+}{
+	{AuthErrorKind, regexp.MustCompile(`(?i)status code '40[13]'|unauthorized|forbidden|invalid_token|invalid_client|bad credentials|requesting token|refreshing token`)},
+	{TaskErrorKind, regexp.MustCompile(`(?i)task '\d+'`)},
+	{TimeoutErrorKind, regexp.MustCompile(`(?i)timeout|timed out|deadline exceeded`)},
+	{ParseErrorKind, regexp.MustCompile(`(?i)unmarshal|parsing|yaml:|invalid character|unexpected end of json`)},
+}
+
+// errorKind classifies a scrape error as an authentication failure, a
+// timeout, a BOSH task failure or a parse error.
+func errorKind(err error) string {
+	message := err.Error()
+	for _, errorKind := range errorKinds {
+		if errorKind.regexp.MatchString(message) {
+			return errorKind.kind
+		}
+	}
+
+	return OtherErrorKind
+}
@@ -0,0 +1,213 @@
+package collectors
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type SeriesGuard struct {
+	labelsAllowlist     map[string]bool
+	constLabelNames     map[string]bool
+	maxSeries           int
+	seriesDroppedMetric *prometheus.CounterVec
+	descs               metricsDescs
+}
+
+type aggregatedSeries struct {
+	desc        metricDesc
+	valueType   prometheus.ValueType
+	value       float64
+	labelValues []string
+}
+
+// key identifies the series sharing the allowed labels.
+func (s *aggregatedSeries) key() string {
+	constLabels := []string{}
+	for name, value := range s.desc.constLabels {
+		constLabels = append(constLabels, name+"="+value)
+	}
+	sort.Strings(constLabels)
+
+	return strings.Join([]string{
+		s.desc.fqName,
+		strings.Join(constLabels, ","),
+		strings.Join(s.desc.variableLabels, ","),
+		strings.Join(s.labelValues, "\xff"),
+	}, "\xff")
+}
+
+func NewSeriesGuard(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+	labelsAllowlist []string,
+	maxSeries int,
+) *SeriesGuard {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	var allowlist map[string]bool
+	if len(labelsAllowlist) > 0 {
+		allowlist = map[string]bool{}
+		for _, label := range labelsAllowlist {
+			allowlist[strings.Trim(label, " ")] = true
+		}
+	}
+
+	constLabelNames := map[string]bool{}
+	for name := range metricConstLabels {
+		constLabelNames[name] = true
+	}
+
+	seriesDroppedMetric := descs.newCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "exporter",
+			Name:        "series_dropped_total",
+			Help:        "Total number of series dropped because a collector exceeded the maximum number of series per scrape.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"collector"},
+	)
+
+	return &SeriesGuard{
+		labelsAllowlist:     allowlist,
+		constLabelNames:     constLabelNames,
+		maxSeries:           maxSeries,
+		seriesDroppedMetric: seriesDroppedMetric,
+		descs:               descs,
+	}
+}
+
+func (g *SeriesGuard) Describe(ch chan<- *prometheus.Desc) {
+	g.seriesDroppedMetric.Describe(ch)
+}
+
+func (g *SeriesGuard) Collect(ch chan<- prometheus.Metric) {
+	g.seriesDroppedMetric.Collect(ch)
+}
+
+func (g *SeriesGuard) Guard(collectorName string, collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	if g.labelsAllowlist == nil && g.maxSeries <= 0 {
+		return collect(ch)
+	}
+
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	series := 0
+	emit := func(metric prometheus.Metric) {
+		if g.maxSeries > 0 && series >= g.maxSeries {
+			g.seriesDroppedMetric.WithLabelValues(collectorName).Inc()
+			return
+		}
+		series++
+		ch <- metric
+	}
+
+	describer := newMetricDescriber()
+	aggregatedKeys := []string{}
+	aggregated := map[string]*aggregatedSeries{}
+	for metric := range metricsCh {
+		aggregation, err := g.aggregation(describer, metric)
+		if err != nil || aggregation == nil {
+			emit(metric)
+			continue
+		}
+
+		key := aggregation.key()
+		if existing, ok := aggregated[key]; ok {
+			existing.value += aggregation.value
+			continue
+		}
+		aggregatedKeys = append(aggregatedKeys, key)
+		aggregated[key] = aggregation
+	}
+
+	for _, key := range aggregatedKeys {
+		aggregation := aggregated[key]
+		desc := prometheus.NewDesc(aggregation.desc.fqName, aggregation.desc.help, aggregation.desc.variableLabels, aggregation.desc.constLabels)
+		metric, err := prometheus.NewConstMetric(desc, aggregation.valueType, aggregation.value, aggregation.labelValues...)
+		if err != nil {
+			continue
+		}
+		emit(describedMetric{Metric: metric, desc: aggregation.desc})
+	}
+
+	return <-errCh
+}
+
+// allowed returns whether a label is kept: the exporter constant labels are
+// always kept, as the labels of the metrics not described by the collectors
+// constructors are not told apart from the variable ones.
+func (g *SeriesGuard) allowed(label string) bool {
+	return g.labelsAllowlist[label] || g.constLabelNames[label]
+}
+
+func (g *SeriesGuard) aggregation(describer *metricDescriber, metric prometheus.Metric) (*aggregatedSeries, error) {
+	if g.labelsAllowlist == nil {
+		return nil, nil
+	}
+
+	desc, ok := describer.describe(metric)
+	if !ok {
+		return nil, nil
+	}
+
+	allowed := true
+	for _, label := range desc.variableLabels {
+		if !g.allowed(label) {
+			allowed = false
+			break
+		}
+	}
+	if allowed {
+		return nil, nil
+	}
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		return nil, err
+	}
+
+	valueType, value, ok := metricValue(&m)
+	if !ok {
+		return nil, nil
+	}
+
+	labelValues := metricLabelValues(&m, desc.variableLabels)
+
+	allowedLabels := []string{}
+	allowedLabelValues := []string{}
+	for i, label := range desc.variableLabels {
+		if g.allowed(label) {
+			allowedLabels = append(allowedLabels, label)
+			allowedLabelValues = append(allowedLabelValues, labelValues[i])
+		}
+	}
+
+	return &aggregatedSeries{
+		desc: metricDesc{
+			fqName:         desc.fqName,
+			help:           desc.help,
+			variableLabels: allowedLabels,
+			constLabels:    desc.constLabels,
+		},
+		valueType:   valueType,
+		value:       value,
+		labelValues: allowedLabelValues,
+	}, nil
+}
+
+func (g *SeriesGuard) descriptions() metricsDescs {
+	return g.descs
+}
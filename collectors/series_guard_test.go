@@ -0,0 +1,150 @@
+package collectors_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("SeriesGuard", func() {
+	var (
+		err             error
+		labelsAllowlist []string
+		maxSeries       int
+		collectErr      error
+		seriesGuard     *SeriesGuard
+		guardedMetrics  []prometheus.Metric
+
+		jobHealthyMetric *prometheus.GaugeVec
+	)
+
+	BeforeEach(func() {
+		labelsAllowlist = []string{}
+		maxSeries = 0
+		collectErr = nil
+
+		jobHealthyMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "test_exporter",
+				Subsystem: "job",
+				Name:      "healthy",
+				Help:      "BOSH Job Healthy (1 for healthy, 0 for unhealthy).",
+				ConstLabels: prometheus.Labels{
+					"environment": "test_environment",
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+		)
+		jobHealthyMetric.WithLabelValues("fake-deployment-name", "fake-job-name", "0").Set(float64(1))
+		jobHealthyMetric.WithLabelValues("fake-deployment-name", "fake-job-name", "1").Set(float64(1))
+		jobHealthyMetric.WithLabelValues("fake-deployment-name", "fake-other-job-name", "0").Set(float64(0))
+	})
+
+	JustBeforeEach(func() {
+		seriesGuard = NewSeriesGuard("test_exporter", "test_environment", "test_bosh_name", "test_bosh_uuid", prometheus.Labels{}, labelsAllowlist, maxSeries)
+
+		metrics := make(chan prometheus.Metric)
+		done := make(chan bool)
+		guardedMetrics = []prometheus.Metric{}
+		go func() {
+			for metric := range metrics {
+				guardedMetrics = append(guardedMetrics, metric)
+			}
+			close(done)
+		}()
+
+		collect := func(ch chan<- prometheus.Metric) error {
+			jobHealthyMetric.Collect(ch)
+			return collectErr
+		}
+		err = seriesGuard.Guard("Jobs", collect, metrics)
+		close(metrics)
+		<-done
+	})
+
+	seriesValues := func(metrics []prometheus.Metric) map[string]float64 {
+		values := map[string]float64{}
+		for _, metric := range metrics {
+			var m dto.Metric
+			Expect(metric.Write(&m)).To(Succeed())
+
+			key := ""
+			for _, labelPair := range m.Label {
+				key += labelPair.GetName() + "=" + labelPair.GetValue() + ","
+			}
+			values[key] = m.Gauge.GetValue()
+		}
+
+		return values
+	}
+
+	droppedSeries := func() float64 {
+		metrics := make(chan prometheus.Metric, 10)
+		seriesGuard.Collect(metrics)
+		close(metrics)
+
+		dropped := float64(0)
+		for metric := range metrics {
+			var m dto.Metric
+			Expect(metric.Write(&m)).To(Succeed())
+			dropped += m.Counter.GetValue()
+		}
+
+		return dropped
+	}
+
+	It("forwards all series", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(guardedMetrics).To(HaveLen(3))
+		Expect(droppedSeries()).To(Equal(float64(0)))
+	})
+
+	Context("when the collector returns an error", func() {
+		BeforeEach(func() {
+			collectErr = errors.New("no bueno")
+			maxSeries = 10
+		})
+
+		It("returns the error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(guardedMetrics).To(HaveLen(3))
+		})
+	})
+
+	Context("when a labels allowlist is set", func() {
+		BeforeEach(func() {
+			labelsAllowlist = []string{"bosh_deployment", "bosh_job_name"}
+		})
+
+		It("sums the series sharing the allowed labels", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(seriesValues(guardedMetrics)).To(Equal(map[string]float64{
+				"bosh_deployment=fake-deployment-name,bosh_job_name=fake-job-name,environment=test_environment,":       float64(2),
+				"bosh_deployment=fake-deployment-name,bosh_job_name=fake-other-job-name,environment=test_environment,": float64(0),
+			}))
+		})
+
+		It("keeps the metric name and help", func() {
+			Expect(guardedMetrics[0].Desc().String()).To(ContainSubstring(`fqName: "test_exporter_job_healthy"`))
+			Expect(guardedMetrics[0].Desc().String()).To(ContainSubstring(`help: "BOSH Job Healthy (1 for healthy, 0 for unhealthy)."`))
+		})
+	})
+
+	Context("when a maximum number of series is set", func() {
+		BeforeEach(func() {
+			maxSeries = 2
+		})
+
+		It("drops the series beyond the threshold", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(guardedMetrics).To(HaveLen(2))
+			Expect(droppedSeries()).To(Equal(float64(1)))
+		})
+	})
+})
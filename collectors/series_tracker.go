@@ -0,0 +1,101 @@
+package collectors
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// seriesTracker remembers the series exposed by the last successful scrape,
+// counting the ones no longer exposed by the next one (i.e. because their
+// deployment, instance or process vanished from BOSH).
+type seriesTracker struct {
+	mutex              sync.Mutex
+	series             map[uint64]bool
+	seriesPrunedMetric prometheus.Counter
+	descs              metricsDescs
+}
+
+func newSeriesTracker(namespace string, metricConstLabels prometheus.Labels) *seriesTracker {
+	descs := metricsDescs{}
+
+	seriesPrunedMetric := descs.newCounter(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "exporter",
+			Name:        "series_pruned_total",
+			Help:        "Total number of series no longer exposed because their BOSH deployment, instance or process vanished.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	return &seriesTracker{seriesPrunedMetric: seriesPrunedMetric, descs: descs}
+}
+
+func (t *seriesTracker) Describe(ch chan<- *prometheus.Desc) {
+	t.seriesPrunedMetric.Describe(ch)
+}
+
+func (t *seriesTracker) Collect(ch chan<- prometheus.Metric) {
+	t.seriesPrunedMetric.Collect(ch)
+}
+
+// Track forwards the collected metrics to ch. Series are only compared with
+// the previous scrape when collect succeeds, as a failed scrape may have
+// not collected all of them.
+func (t *seriesTracker) Track(collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	metricsCh := make(chan prometheus.Metric)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- collect(metricsCh)
+		close(metricsCh)
+	}()
+
+	series := map[uint64]bool{}
+	for metric := range metricsCh {
+		series[seriesKey(metric)] = true
+		ch <- metric
+	}
+
+	err := <-errCh
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	pruned := 0
+	for key := range t.series {
+		if !series[key] {
+			pruned++
+		}
+	}
+	t.seriesPrunedMetric.Add(float64(pruned))
+	t.series = series
+
+	return nil
+}
+
+func seriesKey(metric prometheus.Metric) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(metric.Desc().String()))
+
+	var m dto.Metric
+	if err := metric.Write(&m); err == nil {
+		for _, label := range m.GetLabel() {
+			hash.Write([]byte{0})
+			hash.Write([]byte(label.GetName()))
+			hash.Write([]byte{0})
+			hash.Write([]byte(label.GetValue()))
+		}
+	}
+
+	return hash.Sum64()
+}
+
+func (t *seriesTracker) descriptions() metricsDescs {
+	return t.descs
+}
@@ -1,24 +1,49 @@
 package collectors
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
 
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
 )
 
 const (
+	JSONFormat = "json"
+	YAMLFormat = "yaml"
+)
+
+const (
+	boshDeploymentLabel     = model.MetaLabelPrefix + "bosh_deployment"
+	boshJobNameLabel        = model.MetaLabelPrefix + "bosh_job_name"
+	boshJobIDLabel          = model.MetaLabelPrefix + "bosh_job_id"
+	boshJobIndexLabel       = model.MetaLabelPrefix + "bosh_job_index"
+	boshJobAZLabel          = model.MetaLabelPrefix + "bosh_job_az"
+	boshJobIPLabel          = model.MetaLabelPrefix + "bosh_job_ip"
+	boshJobIPIndexLabel     = model.MetaLabelPrefix + "bosh_job_ip_index"
 	boshJobProcessNameLabel = model.MetaLabelPrefix + "bosh_job_process_name"
+	boshVMTypeLabel         = model.MetaLabelPrefix + "bosh_vm_type"
 )
 
 type ProcessesDetails map[string][]ProcessDetails
@@ -31,22 +56,50 @@ type ProcessDetails struct {
 	JobIndex       string
 	JobAZ          string
 	JobIP          string
+	JobIPIndex     int
+	JobDNS         string
+	JobVMType      string
 }
 
 type TargetGroups []TargetGroup
 
 type TargetGroup struct {
-	Targets []string       `json:"targets"`
-	Labels  model.LabelSet `json:"labels,omitempty"`
+	Targets []string       `json:"targets" yaml:"targets"`
+	Labels  model.LabelSet `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+type ServiceDiscoveryFilenameData struct {
+	Deployment string
+}
+
+type ServiceDiscoveryPublisher interface {
+	Publish(targetGroups TargetGroups) error
+}
+
+type ServiceDiscoveryLeaderElector interface {
+	IsLeader() (bool, error)
 }
 
 type ServiceDiscoveryCollector struct {
 	serviceDiscoveryFilename                        string
-	azsFilter                                       *filters.AZsFilter
-	processesFilter                                 *filters.RegexpFilter
+	processesFilter                                 *filters.ProcessesFilter
+	cidrsFilter                                     *filters.CIDRsFilter
+	networksFilter                                  *filters.NetworksFilter
+	allIPs                                          bool
+	processesPorts                                  ProcessesPorts
+	dnsNames                                        bool
+	deploymentLabels                                *DeploymentLabels
+	serviceLabels                                   *ServiceLabels
+	relabelConfigs                                  []RelabelConfig
+	outputFormat                                    string
+	outputTemplate                                  *template.Template
+	publishers                                      []ServiceDiscoveryPublisher
+	leaderElector                                   ServiceDiscoveryLeaderElector
+	deploymentsFilenames                            map[string]bool
 	lastServiceDiscoveryScrapeTimestampMetric       prometheus.Gauge
 	lastServiceDiscoveryScrapeDurationSecondsMetric prometheus.Gauge
 	mu                                              *sync.Mutex
+	descs                                           metricsDescs
 }
 
 func NewServiceDiscoveryCollector(
@@ -54,53 +107,149 @@ func NewServiceDiscoveryCollector(
 	environment string,
 	boshName string,
 	boshUUID string,
+	constLabels prometheus.Labels,
 	serviceDiscoveryFilename string,
-	azsFilter *filters.AZsFilter,
-	processesFilter *filters.RegexpFilter,
+	processesFilter *filters.ProcessesFilter,
+	cidrsFilter *filters.CIDRsFilter,
+	networksFilter *filters.NetworksFilter,
+	allIPs bool,
+	processesPorts ProcessesPorts,
+	dnsNames bool,
+	deploymentLabels *DeploymentLabels,
+	serviceLabels *ServiceLabels,
+	relabelConfigs []RelabelConfig,
+	outputFormat string,
+	outputTemplate *template.Template,
+	publishers []ServiceDiscoveryPublisher,
+	leaderElector ServiceDiscoveryLeaderElector,
 ) *ServiceDiscoveryCollector {
-	lastServiceDiscoveryScrapeTimestampMetric := prometheus.NewGauge(
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	lastServiceDiscoveryScrapeTimestampMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_service_discovery_scrape_timestamp",
-			Help:      "Number of seconds since 1970 since last scrape of Service Discovery from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_service_discovery_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Service Discovery from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
-	lastServiceDiscoveryScrapeDurationSecondsMetric := prometheus.NewGauge(
+	lastServiceDiscoveryScrapeDurationSecondsMetric := descs.newGauge(
 		prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: "",
-			Name:      "last_service_discovery_scrape_duration_seconds",
-			Help:      "Duration of the last scrape of Service Discovery from BOSH.",
-			ConstLabels: prometheus.Labels{
-				"environment": environment,
-				"bosh_name":   boshName,
-				"bosh_uuid":   boshUUID,
-			},
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_service_discovery_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Service Discovery from BOSH.",
+			ConstLabels: metricConstLabels,
 		},
 	)
 
 	collector := &ServiceDiscoveryCollector{
-		serviceDiscoveryFilename:                        serviceDiscoveryFilename,
-		azsFilter:                                       azsFilter,
-		processesFilter:                                 processesFilter,
+		serviceDiscoveryFilename: serviceDiscoveryFilename,
+		processesFilter:          processesFilter,
+		cidrsFilter:              cidrsFilter,
+		networksFilter:           networksFilter,
+		allIPs:                   allIPs,
+		processesPorts:           processesPorts,
+		dnsNames:                 dnsNames,
+		deploymentLabels:         deploymentLabels,
+		serviceLabels:            serviceLabels,
+		relabelConfigs:           relabelConfigs,
+		outputFormat:             outputFormat,
+		outputTemplate:           outputTemplate,
+		publishers:               publishers,
+		leaderElector:            leaderElector,
 		lastServiceDiscoveryScrapeTimestampMetric:       lastServiceDiscoveryScrapeTimestampMetric,
 		lastServiceDiscoveryScrapeDurationSecondsMetric: lastServiceDiscoveryScrapeDurationSecondsMetric,
-		mu: &sync.Mutex{},
+		mu:    &sync.Mutex{},
+		descs: descs,
 	}
 	return collector
 }
 
 func (c *ServiceDiscoveryCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	err := c.Refresh(deployments)
+	c.CollectMetrics(ch)
+
+	return err
+}
+
+func (c *ServiceDiscoveryCollector) Refresh(deployments []deployments.DeploymentInfo) error {
 	var begun = time.Now()
 
+	c.mu.Lock()
+	err := c.publishLeaderTargetGroups(deployments)
+	c.mu.Unlock()
+
+	c.lastServiceDiscoveryScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+
+	return err
+}
+
+func (c *ServiceDiscoveryCollector) CollectMetrics(ch chan<- prometheus.Metric) {
+	c.lastServiceDiscoveryScrapeTimestampMetric.Collect(ch)
+	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Collect(ch)
+}
+
+func (c *ServiceDiscoveryCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.lastServiceDiscoveryScrapeTimestampMetric.Describe(ch)
+	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Describe(ch)
+}
+
+func (c *ServiceDiscoveryCollector) perDeploymentFiles() bool {
+	return strings.Contains(c.serviceDiscoveryFilename, "{{")
+}
+
+// publishLeaderTargetGroups publishes the target groups only if this replica
+// is the leader, or if there is no leader election.
+func (c *ServiceDiscoveryCollector) publishLeaderTargetGroups(deploymentsInfo []deployments.DeploymentInfo) error {
+	if c.leaderElector != nil {
+		leader, err := c.leaderElector.IsLeader()
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error while electing the Service Discovery leader: %v", err))
+		}
+		if !leader {
+			log.Debugf("Not the Service Discovery leader, skipping the target groups publication")
+			return nil
+		}
+	}
+
+	return c.publishTargetGroups(deploymentsInfo)
+}
+
+func (c *ServiceDiscoveryCollector) publishTargetGroups(deploymentsInfo []deployments.DeploymentInfo) error {
+	if c.serviceDiscoveryFilename != "" {
+		var err error
+		if c.perDeploymentFiles() {
+			err = c.writeDeploymentsTargetGroupsToFiles(deploymentsInfo)
+		} else {
+			err = c.writeTargetGroupsToFile(c.serviceDiscoveryFilename, c.getProcessesDetails(deploymentsInfo))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(c.publishers) == 0 {
+		return nil
+	}
+
+	targetGroups := c.createTargetGroups(c.getProcessesDetails(deploymentsInfo))
+	for _, publisher := range c.publishers {
+		if err := publisher.Publish(targetGroups); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ServiceDiscoveryCollector) getProcessesDetails(deployments []deployments.DeploymentInfo) ProcessesDetails {
 	processesDetails := make(ProcessesDetails)
+
 	for _, deployment := range deployments {
 		processes := c.getDeploymentProcesses(deployment)
 		for _, process := range processes {
@@ -108,34 +257,95 @@ func (c *ServiceDiscoveryCollector) Collect(deployments []deployments.Deployment
 		}
 	}
 
-	targetGroups := c.createTargetGroups(processesDetails)
+	return processesDetails
+}
+
+func (c *ServiceDiscoveryCollector) writeDeploymentsTargetGroupsToFiles(deploymentsInfo []deployments.DeploymentInfo) error {
+	filenameTemplate, err := template.New("filename").Parse(c.serviceDiscoveryFilename)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error parsing Service Discovery filename template: %v", err))
+	}
 
-	err := c.writeTargetGroupsToFile(targetGroups)
+	deploymentsFilenames := map[string]bool{}
+	for _, deployment := range deploymentsInfo {
+		var filename bytes.Buffer
+		if err = filenameTemplate.Execute(&filename, ServiceDiscoveryFilenameData{Deployment: deployment.Name}); err != nil {
+			return errors.New(fmt.Sprintf("Error rendering Service Discovery filename for deployment `%s`: %v", deployment.Name, err))
+		}
 
-	c.lastServiceDiscoveryScrapeTimestampMetric.Set(float64(time.Now().Unix()))
-	c.lastServiceDiscoveryScrapeTimestampMetric.Collect(ch)
+		if err = c.writeTargetGroupsToFile(filename.String(), c.getProcessesDetails([]deployments.DeploymentInfo{deployment})); err != nil {
+			return err
+		}
+		deploymentsFilenames[filename.String()] = true
+	}
 
-	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
-	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Collect(ch)
+	// Files written by a previous run are only known from the filesystem.
+	if c.deploymentsFilenames == nil {
+		if c.deploymentsFilenames, err = c.existingDeploymentsFilenames(filenameTemplate); err != nil {
+			return err
+		}
+	}
 
-	return err
+	for filename := range c.deploymentsFilenames {
+		if !deploymentsFilenames[filename] {
+			if err = os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return errors.New(fmt.Sprintf("Error removing stale Service Discovery file `%s`: %v", filename, err))
+			}
+		}
+	}
+	c.deploymentsFilenames = deploymentsFilenames
+
+	return nil
 }
 
-func (c *ServiceDiscoveryCollector) Describe(ch chan<- *prometheus.Desc) {
-	c.lastServiceDiscoveryScrapeTimestampMetric.Describe(ch)
-	c.lastServiceDiscoveryScrapeDurationSecondsMetric.Describe(ch)
+// existingDeploymentsFilenames returns the files matching the Service
+// Discovery filename template, whatever the deployment.
+func (c *ServiceDiscoveryCollector) existingDeploymentsFilenames(filenameTemplate *template.Template) (map[string]bool, error) {
+	var pattern bytes.Buffer
+	if err := filenameTemplate.Execute(&pattern, ServiceDiscoveryFilenameData{Deployment: "*"}); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error rendering Service Discovery filename pattern: %v", err))
+	}
+
+	filenames, err := filepath.Glob(pattern.String())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error listing existing Service Discovery files `%s`: %v", pattern.String(), err))
+	}
+
+	existingFilenames := map[string]bool{}
+	for _, filename := range filenames {
+		existingFilenames[filename] = true
+	}
+
+	return existingFilenames, nil
 }
 
 func (c *ServiceDiscoveryCollector) getDeploymentProcesses(deployment deployments.DeploymentInfo) []ProcessDetails {
 	processesDetails := []ProcessDetails{}
 
 	for _, instance := range deployment.Instances {
-		if len(instance.IPs) == 0 || !c.azsFilter.Enabled(instance.AZ) {
+		ips := c.cidrsFilter.Select(instance.IPs)
+		if len(ips) == 0 {
 			continue
 		}
 
+		dnsNames := []string{}
+		if c.dnsNames {
+			instanceDNSNames := c.instanceDNSNames(instance)
+			dnsNames = c.networksFilter.Select(instanceDNSNames)
+			if len(instanceDNSNames) > 0 && len(dnsNames) == 0 {
+				continue
+			}
+		}
+
+		if !c.allIPs {
+			ips = ips[:1]
+			if len(dnsNames) > 0 {
+				dnsNames = dnsNames[:1]
+			}
+		}
+
 		for _, process := range instance.Processes {
-			if !c.processesFilter.Enabled(process.Name) {
+			if !c.processesFilter.Enabled(deployment.Name, process.Name) {
 				continue
 			}
 
@@ -146,50 +356,150 @@ func (c *ServiceDiscoveryCollector) getDeploymentProcesses(deployment deployment
 				JobID:          instance.ID,
 				JobIndex:       instance.Index,
 				JobAZ:          instance.AZ,
-				JobIP:          instance.IPs[0],
+				JobVMType:      instance.VMType,
 			}
 
-			processesDetails = append(processesDetails, processDetails)
+			if len(dnsNames) > 0 {
+				processDetails.JobIP = ips[0]
+				processDetails.JobIPIndex = ipIndex(instance.IPs, ips[0])
+				for _, dnsName := range dnsNames {
+					processDetails.JobDNS = dnsName
+					processesDetails = append(processesDetails, processDetails)
+				}
+				continue
+			}
+
+			for _, ip := range ips {
+				processDetails.JobIP = ip
+				processDetails.JobIPIndex = ipIndex(instance.IPs, ip)
+				processesDetails = append(processesDetails, processDetails)
+			}
 		}
 	}
 
 	return processesDetails
 }
 
+func (c *ServiceDiscoveryCollector) instanceDNSNames(instance deployments.Instance) []string {
+	dnsNames := []string{}
+
+	for _, dnsName := range instance.DNS {
+		if strings.HasPrefix(dnsName, instance.ID+".") {
+			dnsNames = append(dnsNames, dnsName)
+		}
+	}
+
+	return dnsNames
+}
+
 func (c *ServiceDiscoveryCollector) createTargetGroups(processesDetails ProcessesDetails) TargetGroups {
 	targetGroups := TargetGroups{}
 
-	for name, details := range processesDetails {
-		targets := []string{}
-		for _, processDetails := range details {
-			targets = append(targets, processDetails.JobIP)
+	c.eachTargetGroup(processesDetails, func(targetGroup TargetGroup) error {
+		targetGroups = append(targetGroups, targetGroup)
+		return nil
+	})
+
+	return targetGroups
+}
+
+// eachTargetGroup calls fn with each (relabeled) target group, one at a
+// time, so they can be written without holding all of them in memory.
+func (c *ServiceDiscoveryCollector) eachTargetGroup(processesDetails ProcessesDetails, fn func(targetGroup TargetGroup) error) error {
+	names := []string{}
+	for name := range processesDetails {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, processDetails := range processesDetails[name] {
+			target := processDetails.JobIP
+			if processDetails.JobDNS != "" {
+				target = processDetails.JobDNS
+			}
+
+			targetGroup := TargetGroup{
+				Targets: []string{targetAddress(target, c.processesPorts[name])},
+				Labels: model.LabelSet{
+					model.LabelName(boshDeploymentLabel):     model.LabelValue(processDetails.DeploymentName),
+					model.LabelName(boshJobNameLabel):        model.LabelValue(processDetails.JobName),
+					model.LabelName(boshJobIDLabel):          model.LabelValue(processDetails.JobID),
+					model.LabelName(boshJobIndexLabel):       model.LabelValue(processDetails.JobIndex),
+					model.LabelName(boshJobAZLabel):          model.LabelValue(processDetails.JobAZ),
+					model.LabelName(boshJobIPLabel):          model.LabelValue(processDetails.JobIP),
+					model.LabelName(boshJobIPIndexLabel):     model.LabelValue(strconv.Itoa(processDetails.JobIPIndex)),
+					model.LabelName(boshJobProcessNameLabel): model.LabelValue(name),
+					model.LabelName(boshVMTypeLabel):         model.LabelValue(processDetails.JobVMType),
+				},
+			}
+			for labelName, labelValue := range c.deploymentLabels.Labels(processDetails.DeploymentName) {
+				targetGroup.Labels[model.LabelName(labelName)] = model.LabelValue(labelValue)
+			}
+			for labelName, labelValue := range c.serviceLabels.Labels(processDetails.DeploymentName, processDetails.JobName) {
+				targetGroup.Labels[model.LabelName(labelName)] = model.LabelValue(labelValue)
+			}
+
+			for _, relabeledTargetGroup := range RelabelTargetGroups(c.relabelConfigs, TargetGroups{targetGroup}) {
+				if err := fn(relabeledTargetGroup); err != nil {
+					return err
+				}
+			}
 		}
+	}
 
-		targetGroup := TargetGroup{
-			Targets: targets,
-			Labels: model.LabelSet{
-				model.LabelName(boshJobProcessNameLabel): model.LabelValue(name),
-			},
+	return nil
+}
+
+func ipIndex(ips []string, ip string) int {
+	for i, instanceIP := range ips {
+		if instanceIP == ip {
+			return i
 		}
-		targetGroups = append(targetGroups, targetGroup)
 	}
 
-	return targetGroups
+	return 0
 }
 
-func (c *ServiceDiscoveryCollector) writeTargetGroupsToFile(targetGroups TargetGroups) error {
-	targetGroupsJSON, err := json.Marshal(targetGroups)
-	if err != nil {
-		return errors.New(fmt.Sprintf("Error while marshalling TargetGroups: %v", err))
+func targetAddress(ip string, port int) string {
+	if port > 0 {
+		return net.JoinHostPort(ip, strconv.Itoa(port))
+	}
+
+	parsedIP := net.ParseIP(ip)
+	if parsedIP != nil && parsedIP.To4() == nil {
+		return "[" + ip + "]"
+	}
+
+	return ip
+}
+
+// writeTargetGroupsToFile renders the target groups to a temp file, which
+// atomically replaces the Service Discovery file unless its content did not
+// change.
+func (c *ServiceDiscoveryCollector) writeTargetGroupsToFile(filename string, processesDetails ProcessesDetails) error {
+	dir, name := path.Split(filename)
+	if dir == "" {
+		dir = "."
 	}
 
-	dir, name := path.Split(c.serviceDiscoveryFilename)
 	f, err := ioutil.TempFile(dir, name)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Error creating temp file: %v", err))
 	}
 
-	_, err = f.Write(targetGroupsJSON)
+	contentHash := sha256.New()
+	w := bufio.NewWriter(io.MultiWriter(f, contentHash))
+	err = c.renderTargetGroups(w, filename, processesDetails)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil && bytes.Equal(fileSHA256(filename), contentHash.Sum(nil)) {
+		f.Close()
+		os.Remove(f.Name())
+		return nil
+	}
+
 	if err == nil {
 		err = f.Sync()
 	}
@@ -200,12 +510,117 @@ func (c *ServiceDiscoveryCollector) writeTargetGroupsToFile(targetGroups TargetG
 		err = permErr
 	}
 	if err == nil {
-		err = os.Rename(f.Name(), c.serviceDiscoveryFilename)
+		err = os.Rename(f.Name(), filename)
 	}
 
 	if err != nil {
 		os.Remove(f.Name())
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// renderTargetGroups writes the target groups using the output template or
+// file format. JSON target groups are encoded one at a time, so the memory
+// used does not grow with the number of targets.
+func (c *ServiceDiscoveryCollector) renderTargetGroups(w io.Writer, filename string, processesDetails ProcessesDetails) error {
+	if c.outputTemplate != nil {
+		if err := c.outputTemplate.Execute(w, c.createTargetGroups(processesDetails)); err != nil {
+			return errors.New(fmt.Sprintf("Error while rendering TargetGroups template: %v", err))
+		}
+		return nil
+	}
+
+	if c.fileFormat(filename) == ScrapeConfigFormat {
+		scrapeConfigYAML, err := yaml.Marshal(NewScrapeConfig(filename, c.createTargetGroups(processesDetails)))
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error while marshalling TargetGroups to a ScrapeConfig: %v", err))
+		}
+		_, err = w.Write(scrapeConfigYAML)
+		return err
+	}
+
+	if c.fileFormat(filename) == YAMLFormat {
+		targetGroupsYAML, err := yaml.Marshal(c.createTargetGroups(processesDetails))
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error while marshalling TargetGroups to YAML: %v", err))
+		}
+		_, err = w.Write(targetGroupsYAML)
+		return err
+	}
+
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err := c.eachTargetGroup(processesDetails, func(targetGroup TargetGroup) error {
+		targetGroupJSON, err := json.Marshal(targetGroup)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error while marshalling TargetGroups: %v", err))
+		}
+
+		if !first {
+			if _, err = io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		_, err = w.Write(targetGroupJSON)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]")
+	return err
+}
+
+func fileSHA256(filename string) []byte {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	contentHash := sha256.New()
+	if _, err = io.Copy(contentHash, f); err != nil {
+		return nil
+	}
+
+	return contentHash.Sum(nil)
+}
+
+func (c *ServiceDiscoveryCollector) fileFormat(filename string) string {
+	if c.outputFormat != "" {
+		return c.outputFormat
+	}
+
+	switch path.Ext(filename) {
+	case ".yml", ".yaml":
+		return YAMLFormat
+	default:
+		return JSONFormat
+	}
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
 	}
 
 	return err
 }
+
+func (c *ServiceDiscoveryCollector) descriptions() metricsDescs {
+	return c.descs
+}
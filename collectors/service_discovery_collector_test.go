@@ -1,13 +1,21 @@
 package collectors_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 
 	"github.com/cloudfoundry-community/bosh_exporter/deployments"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
@@ -24,8 +32,19 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 		boshUUID                  string
 		tmpfile                   *os.File
 		serviceDiscoveryFilename  string
-		azsFilter                 *filters.AZsFilter
-		processesFilter           *filters.RegexpFilter
+		processesFilter           *filters.ProcessesFilter
+		cidrsFilter               *filters.CIDRsFilter
+		networksFilter            *filters.NetworksFilter
+		allIPs                    bool
+		processesPorts            ProcessesPorts
+		dnsNames                  bool
+		deploymentLabels          *DeploymentLabels
+		serviceLabels             *ServiceLabels
+		relabelConfigs            []RelabelConfig
+		outputFormat              string
+		outputTemplate            *template.Template
+		publishers                []ServiceDiscoveryPublisher
+		leaderElector             ServiceDiscoveryLeaderElector
 		serviceDiscoveryCollector *ServiceDiscoveryCollector
 
 		lastServiceDiscoveryScrapeTimestampMetric       prometheus.Gauge
@@ -40,8 +59,26 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 		tmpfile, err = ioutil.TempFile("", "service_discovery_collector_test_")
 		Expect(err).ToNot(HaveOccurred())
 		serviceDiscoveryFilename = tmpfile.Name()
-		azsFilter = filters.NewAZsFilter([]string{})
-		processesFilter, err = filters.NewRegexpFilter([]string{})
+		defaultProcessesFilter, err := filters.NewRegexpFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		processesFilter, err = filters.LoadProcessesFilter(defaultProcessesFilter, "")
+		Expect(err).ToNot(HaveOccurred())
+		cidrsFilter, err = filters.NewCIDRsFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		networksFilter, err = filters.NewNetworksFilter([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		allIPs = false
+		processesPorts = ProcessesPorts{}
+		dnsNames = false
+		deploymentLabels, err = LoadDeploymentLabels("")
+		Expect(err).ToNot(HaveOccurred())
+		serviceLabels, err = LoadServiceLabels("")
+		Expect(err).ToNot(HaveOccurred())
+		relabelConfigs = []RelabelConfig{}
+		outputFormat = ""
+		outputTemplate = nil
+		publishers = []ServiceDiscoveryPublisher{}
+		leaderElector = nil
 
 		lastServiceDiscoveryScrapeTimestampMetric = prometheus.NewGauge(
 			prometheus.GaugeOpts{
@@ -83,9 +120,21 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 			environment,
 			boshName,
 			boshUUID,
+			prometheus.Labels{},
 			serviceDiscoveryFilename,
-			azsFilter,
 			processesFilter,
+			cidrsFilter,
+			networksFilter,
+			allIPs,
+			processesPorts,
+			dnsNames,
+			deploymentLabels,
+			serviceLabels,
+			relabelConfigs,
+			outputFormat,
+			outputTemplate,
+			publishers,
+			leaderElector,
 		)
 	})
 
@@ -119,8 +168,10 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 			jobIndex            = "0"
 			jobAZ               = "fake-job-az"
 			jobIP               = "1.2.3.4"
+			jobDNS              = "fake-job-id.fake-job-name.fake-network.fake-deployment-name.bosh"
+			jobVMType           = "fake-vm-type"
 			jobProcessName      = "fake-process-name"
-			targetGroupsContent = "[{\"targets\":[\"1.2.3.4\"],\"labels\":{\"__meta_bosh_job_process_name\":\"fake-process-name\"}}]"
+			targetGroupsContent = "[{\"targets\":[\"1.2.3.4\"],\"labels\":{\"__meta_bosh_deployment\":\"fake-deployment-name\",\"__meta_bosh_job_az\":\"fake-job-az\",\"__meta_bosh_job_id\":\"fake-job-id\",\"__meta_bosh_job_index\":\"0\",\"__meta_bosh_job_ip\":\"1.2.3.4\",\"__meta_bosh_job_ip_index\":\"0\",\"__meta_bosh_job_name\":\"fake-job-name\",\"__meta_bosh_job_process_name\":\"fake-process-name\",\"__meta_bosh_vm_type\":\"fake-vm-type\"}}]"
 
 			processes       []deployments.Process
 			instances       []deployments.Instance
@@ -129,6 +180,13 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 
 			metrics    chan prometheus.Metric
 			errMetrics chan error
+
+			targetGroupContent = func(target string, ip string, ipIndex string) string {
+				return fmt.Sprintf(
+					"{\"targets\":[\"%s\"],\"labels\":{\"__meta_bosh_deployment\":\"%s\",\"__meta_bosh_job_az\":\"%s\",\"__meta_bosh_job_id\":\"%s\",\"__meta_bosh_job_index\":\"%s\",\"__meta_bosh_job_ip\":\"%s\",\"__meta_bosh_job_ip_index\":\"%s\",\"__meta_bosh_job_name\":\"%s\",\"__meta_bosh_job_process_name\":\"%s\",\"__meta_bosh_vm_type\":\"%s\"}}",
+					target, deploymentName, jobAZ, jobID, jobIndex, ip, ipIndex, jobName, jobProcessName, jobVMType,
+				)
+			}
 		)
 
 		BeforeEach(func() {
@@ -144,7 +202,9 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 					ID:        jobID,
 					Index:     jobIndex,
 					IPs:       []string{jobIP},
+					DNS:       []string{"0.fake-job-name.fake-network.fake-deployment-name.bosh", jobDNS},
 					AZ:        jobAZ,
+					VMType:    jobVMType,
 					Processes: processes,
 				},
 			}
@@ -182,6 +242,29 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 			Consistently(errMetrics).ShouldNot(Receive())
 		})
 
+		Context("when the target groups file content has not changed", func() {
+			var (
+				modTime time.Time
+			)
+
+			BeforeEach(func() {
+				err = ioutil.WriteFile(serviceDiscoveryFilename, []byte(targetGroupsContent), 0644)
+				Expect(err).ToNot(HaveOccurred())
+
+				modTime = time.Now().Add(-time.Hour).Truncate(time.Second)
+				err = os.Chtimes(serviceDiscoveryFilename, modTime, modTime)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does not rewrite the target groups file", func() {
+				Eventually(metrics).Should(Receive())
+				fileInfo, err := os.Stat(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fileInfo.ModTime()).To(Equal(modTime))
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
 				deploymentsInfo = []deployments.DeploymentInfo{}
@@ -244,6 +327,589 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 			})
 		})
 
+		Context("when instance has multiple IPs", func() {
+			BeforeEach(func() {
+				deploymentInfo.Instances[0].IPs = []string{jobIP, "10.0.0.1"}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("writes a target groups file with the first IP", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(targetGroupsContent))
+			})
+
+			Context("and there is a CIDR filter", func() {
+				BeforeEach(func() {
+					cidrsFilter, err = filters.NewCIDRsFilter([]string{"10.0.0.0/8"})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("writes a target groups file with the matching IP", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent("10.0.0.1", "10.0.0.1", "1") + "]"))
+				})
+			})
+
+			Context("and all IPs are requested", func() {
+				BeforeEach(func() {
+					allIPs = true
+				})
+
+				It("writes a target groups file with all IPs", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent(jobIP, jobIP, "0") + "," + targetGroupContent("10.0.0.1", "10.0.0.1", "1") + "]"))
+				})
+			})
+		})
+
+		Context("when instance has an IPv6 IP", func() {
+			BeforeEach(func() {
+				deploymentInfo.Instances[0].IPs = []string{"fd00::1"}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("writes a target groups file with a bracketed target", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal("[" + targetGroupContent("[fd00::1]", "fd00::1", "0") + "]"))
+			})
+
+			Context("and it is a dual-stack instance", func() {
+				BeforeEach(func() {
+					deploymentInfo.Instances[0].IPs = []string{jobIP, "fd00::1"}
+					deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+					allIPs = true
+				})
+
+				It("writes a target groups file with both IPv4 and IPv6 targets", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent(jobIP, jobIP, "0") + "," + targetGroupContent("[fd00::1]", "fd00::1", "1") + "]"))
+				})
+			})
+
+			Context("and there is an IPv6 CIDR filter", func() {
+				BeforeEach(func() {
+					deploymentInfo.Instances[0].IPs = []string{jobIP, "fd00::1"}
+					deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+					cidrsFilter, err = filters.NewCIDRsFilter([]string{"fd00::/8"})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("writes a target groups file with the IPv6 target", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent("[fd00::1]", "fd00::1", "1") + "]"))
+				})
+			})
+		})
+
+		Context("when there are many processes", func() {
+			BeforeEach(func() {
+				instances[0].Processes = []deployments.Process{}
+				for i := 0; i < 100; i++ {
+					instances[0].Processes = append(instances[0].Processes, deployments.Process{Name: fmt.Sprintf("fake-process-name-%03d", i)})
+				}
+				deploymentInfo.Instances = instances
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+			})
+
+			It("writes a JSON target groups file with a target group per process", func() {
+				Eventually(metrics).Should(Receive())
+				content, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+
+				var targetGroups TargetGroups
+				err = json.Unmarshal(content, &targetGroups)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(targetGroups).To(HaveLen(100))
+				Expect(string(targetGroups[0].Labels["__meta_bosh_job_process_name"])).To(Equal("fake-process-name-000"))
+				Expect(string(targetGroups[99].Labels["__meta_bosh_job_process_name"])).To(Equal("fake-process-name-099"))
+
+				expectedContent, err := json.Marshal(targetGroups)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(content)).To(Equal(string(expectedContent)))
+			})
+		})
+
+		Context("when there is a port for the process", func() {
+			BeforeEach(func() {
+				processesPorts = ProcessesPorts{jobProcessName: 9100}
+			})
+
+			It("writes a target groups file with ip:port targets", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal("[" + targetGroupContent("1.2.3.4:9100", jobIP, "0") + "]"))
+			})
+
+			Context("and instance has an IPv6 IP", func() {
+				BeforeEach(func() {
+					deploymentInfo.Instances[0].IPs = []string{"fd00::1"}
+					deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+				})
+
+				It("writes a target groups file with bracketed ip:port targets", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent("[fd00::1]:9100", "fd00::1", "0") + "]"))
+				})
+			})
+		})
+
+		Context("when DNS names are requested", func() {
+			BeforeEach(func() {
+				dnsNames = true
+			})
+
+			It("writes a target groups file with DNS names targets", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal("[" + targetGroupContent(jobDNS, jobIP, "0") + "]"))
+			})
+
+			Context("and DNS names are selected by network", func() {
+				BeforeEach(func() {
+					deploymentInfo.Instances[0].DNS = []string{
+						"fake-job-id.fake-job-name.fake-other-network.fake-deployment-name.bosh",
+						jobDNS,
+					}
+					deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+					networksFilter, err = filters.NewNetworksFilter([]string{"fake-network"})
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("writes a target groups file with the DNS names targets of the network", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal("[" + targetGroupContent(jobDNS, jobIP, "0") + "]"))
+				})
+
+				Context("and instance has no DNS names on the network", func() {
+					BeforeEach(func() {
+						networksFilter, err = filters.NewNetworksFilter([]string{"fake-unknown-network"})
+						Expect(err).ToNot(HaveOccurred())
+					})
+
+					It("writes an empty target groups file", func() {
+						Eventually(metrics).Should(Receive())
+						targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(string(targetGroups)).To(Equal("[]"))
+					})
+				})
+			})
+
+			Context("and instance has no DNS names", func() {
+				BeforeEach(func() {
+					deploymentInfo.Instances[0].DNS = []string{}
+					deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo}
+				})
+
+				It("writes a target groups file with IP targets", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal(targetGroupsContent))
+				})
+			})
+		})
+
+		Context("when Service Discovery filename is a template", func() {
+			var (
+				tmpdir string
+			)
+
+			BeforeEach(func() {
+				tmpdir, err = ioutil.TempDir("", "service_discovery_collector_test_")
+				Expect(err).ToNot(HaveOccurred())
+				serviceDiscoveryFilename = tmpdir + "/{{.Deployment}}.json"
+
+				otherDeploymentInfo := deployments.DeploymentInfo{
+					Name:      "fake-other-deployment-name",
+					Instances: []deployments.Instance{},
+				}
+				deploymentsInfo = []deployments.DeploymentInfo{deploymentInfo, otherDeploymentInfo}
+			})
+
+			AfterEach(func() {
+				serviceDiscoveryFilename = tmpfile.Name()
+				err = os.RemoveAll(tmpdir)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("writes a target groups file per deployment", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(tmpdir + "/" + deploymentName + ".json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(targetGroupsContent))
+
+				targetGroups, err = ioutil.ReadFile(tmpdir + "/fake-other-deployment-name.json")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal("[]"))
+			})
+
+			Context("and files were left by a previous run", func() {
+				BeforeEach(func() {
+					err = ioutil.WriteFile(tmpdir+"/fake-stale-deployment-name.json", []byte("[]"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+					err = ioutil.WriteFile(tmpdir+"/fake-other-file.yml", []byte("[]"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("removes the files matching the template of unknown deployments", func() {
+					Eventually(metrics).Should(Receive())
+					Consistently(errMetrics).ShouldNot(Receive())
+
+					_, err = os.Stat(tmpdir + "/" + deploymentName + ".json")
+					Expect(err).ToNot(HaveOccurred())
+					_, err = os.Stat(tmpdir + "/fake-stale-deployment-name.json")
+					Expect(os.IsNotExist(err)).To(BeTrue())
+					_, err = os.Stat(tmpdir + "/fake-other-file.yml")
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+
+			It("removes the target groups file of deployments that no longer exist", func() {
+				Eventually(metrics).Should(Receive())
+				Eventually(metrics).Should(Receive())
+				Consistently(errMetrics).ShouldNot(Receive())
+
+				err = serviceDiscoveryCollector.Collect([]deployments.DeploymentInfo{deploymentInfo}, make(chan prometheus.Metric, 2))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = os.Stat(tmpdir + "/" + deploymentName + ".json")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = os.Stat(tmpdir + "/fake-other-deployment-name.json")
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("when there are deployment labels", func() {
+			var (
+				deploymentLabelsFile *os.File
+			)
+
+			BeforeEach(func() {
+				deploymentLabelsFile, err = ioutil.TempFile("", "service_discovery_collector_test_")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = deploymentLabelsFile.WriteString("fake-deployment-*:\n  tier: prod\n")
+				Expect(err).ToNot(HaveOccurred())
+
+				deploymentLabels, err = LoadDeploymentLabels(deploymentLabelsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				err = os.Remove(deploymentLabelsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("adds them to the target groups labels", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(ContainSubstring("\"tier\":\"prod\""))
+			})
+		})
+
+		Context("when there are service labels", func() {
+			var (
+				serviceLabelsFile *os.File
+			)
+
+			BeforeEach(func() {
+				serviceLabelsFile, err = ioutil.TempFile("", "service_discovery_collector_test_")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = serviceLabelsFile.WriteString("- deployment: fake-(?P<service>.*)-name\n  instance_group: fake-(?P<component>.*)-name\n  labels:\n    service: $service\n    component: $component\n")
+				Expect(err).ToNot(HaveOccurred())
+
+				serviceLabels, err = LoadServiceLabels(serviceLabelsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				err = os.Remove(serviceLabelsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("adds them to the target groups labels", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(ContainSubstring("\"component\":\"job\""))
+				Expect(string(targetGroups)).To(ContainSubstring("\"service\":\"deployment\""))
+			})
+		})
+
+		Context("when there are relabel configs", func() {
+			var (
+				relabelConfigsFile *os.File
+			)
+
+			BeforeEach(func() {
+				relabelConfigsFile, err = ioutil.TempFile("", "service_discovery_collector_test_")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = relabelConfigsFile.WriteString("- source_labels: [__meta_bosh_job_process_name]\n  regex: fake-process-name\n  action: drop\n")
+				Expect(err).ToNot(HaveOccurred())
+
+				relabelConfigs, err = LoadRelabelConfigs(relabelConfigsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				err = os.Remove(relabelConfigsFile.Name())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("applies them to the target groups", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal("[]"))
+			})
+		})
+
+		Context("when the output format is YAML", func() {
+			var (
+				targetGroupsYAMLContent = `- targets:
+  - 1.2.3.4
+  labels:
+    __meta_bosh_deployment: fake-deployment-name
+    __meta_bosh_job_az: fake-job-az
+    __meta_bosh_job_id: fake-job-id
+    __meta_bosh_job_index: "0"
+    __meta_bosh_job_ip: 1.2.3.4
+    __meta_bosh_job_ip_index: "0"
+    __meta_bosh_job_name: fake-job-name
+    __meta_bosh_job_process_name: fake-process-name
+    __meta_bosh_vm_type: fake-vm-type
+`
+			)
+
+			BeforeEach(func() {
+				outputFormat = YAMLFormat
+			})
+
+			It("writes a YAML target groups file", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(targetGroupsYAMLContent))
+			})
+
+			Context("and it is selected by the file extension", func() {
+				var (
+					tmpdir string
+				)
+
+				BeforeEach(func() {
+					relabelConfigs = []RelabelConfig{}
+					outputFormat = ""
+					tmpdir, err = ioutil.TempDir("", "service_discovery_collector_test_")
+					Expect(err).ToNot(HaveOccurred())
+					serviceDiscoveryFilename = tmpdir + "/bosh_target_groups.yml"
+				})
+
+				AfterEach(func() {
+					serviceDiscoveryFilename = tmpfile.Name()
+					err = os.RemoveAll(tmpdir)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("writes a YAML target groups file", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(tmpdir + "/bosh_target_groups.yml")
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(Equal(targetGroupsYAMLContent))
+				})
+			})
+		})
+
+		Context("when the output format is a ScrapeConfig", func() {
+			BeforeEach(func() {
+				outputFormat = ScrapeConfigFormat
+			})
+
+			It("writes a Prometheus Operator ScrapeConfig resource", func() {
+				Eventually(metrics).Should(Receive())
+				scrapeConfig, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(scrapeConfig)).To(Equal(`apiVersion: monitoring.coreos.com/v1alpha1
+kind: ScrapeConfig
+metadata:
+  name: ` + strings.Replace(path.Base(serviceDiscoveryFilename), "_", "-", -1) + `
+  labels:
+    app.kubernetes.io/managed-by: bosh_exporter
+spec:
+  staticConfigs:
+  - targets:
+    - 1.2.3.4
+    labels:
+      __meta_bosh_deployment: fake-deployment-name
+      __meta_bosh_job_az: fake-job-az
+      __meta_bosh_job_id: fake-job-id
+      __meta_bosh_job_index: "0"
+      __meta_bosh_job_ip: 1.2.3.4
+      __meta_bosh_job_ip_index: "0"
+      __meta_bosh_job_name: fake-job-name
+      __meta_bosh_job_process_name: fake-process-name
+      __meta_bosh_vm_type: fake-vm-type
+`))
+			})
+		})
+
+		Context("when there is an output template", func() {
+			BeforeEach(func() {
+				outputTemplate = template.Must(template.New("output").Funcs(template.FuncMap{
+					"label": func(targetGroup TargetGroup, name string) string {
+						return string(targetGroup.Labels[model.LabelName(model.MetaLabelPrefix+name)])
+					},
+				}).Parse("{{range .}}{{label . \"bosh_job_name\"}} {{index .Targets 0}}\n{{end}}"))
+			})
+
+			It("writes the rendered template", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(jobName + " " + jobIP + "\n"))
+			})
+
+			Context("and the template fails to render", func() {
+				BeforeEach(func() {
+					outputTemplate = template.Must(template.New("output").Parse("{{index .Targets 0}}"))
+				})
+
+				It("returns an error", func() {
+					Eventually(metrics).Should(Receive())
+					Eventually(metrics).Should(Receive())
+					Eventually(errMetrics).Should(Receive())
+				})
+			})
+		})
+
+		Context("when there is a Service Discovery publisher", func() {
+			var (
+				publisher *fakePublisher
+			)
+
+			BeforeEach(func() {
+				publisher = &fakePublisher{}
+				publishers = []ServiceDiscoveryPublisher{publisher}
+			})
+
+			It("publishes the target groups", func() {
+				Eventually(metrics).Should(Receive())
+				Expect(publisher.targetGroups).To(HaveLen(1))
+				Expect(publisher.targetGroups[0].Targets).To(Equal([]string{jobIP}))
+				Expect(publisher.targetGroups[0].Labels[model.LabelName("__meta_bosh_job_process_name")]).To(Equal(model.LabelValue(jobProcessName)))
+			})
+
+			It("writes a target groups file", func() {
+				Eventually(metrics).Should(Receive())
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(targetGroupsContent))
+			})
+
+			Context("and the publisher fails", func() {
+				BeforeEach(func() {
+					publisher.err = errors.New("fake-publish-error")
+				})
+
+				It("returns an error", func() {
+					Eventually(metrics).Should(Receive())
+					Eventually(metrics).Should(Receive())
+					Eventually(errMetrics).Should(Receive())
+				})
+			})
+
+			Context("and Service Discovery filename is empty", func() {
+				BeforeEach(func() {
+					serviceDiscoveryFilename = ""
+				})
+
+				AfterEach(func() {
+					serviceDiscoveryFilename = tmpfile.Name()
+				})
+
+				It("publishes the target groups", func() {
+					Eventually(metrics).Should(Receive())
+					Expect(publisher.targetGroups).To(HaveLen(1))
+				})
+
+				It("does not write a target groups file", func() {
+					Eventually(metrics).Should(Receive())
+					targetGroups, err := ioutil.ReadFile(tmpfile.Name())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when there is a leader elector", func() {
+			var (
+				publisher   *fakePublisher
+				fakeElector *fakeLeaderElector
+			)
+
+			BeforeEach(func() {
+				publisher = &fakePublisher{}
+				publishers = []ServiceDiscoveryPublisher{publisher}
+				fakeElector = &fakeLeaderElector{leader: true}
+				leaderElector = fakeElector
+			})
+
+			It("publishes the target groups", func() {
+				Eventually(metrics).Should(Receive())
+				Expect(publisher.targetGroups).To(HaveLen(1))
+				targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(targetGroups)).To(Equal(targetGroupsContent))
+			})
+
+			Context("and this replica is not the leader", func() {
+				BeforeEach(func() {
+					fakeElector.leader = false
+				})
+
+				It("does not publish the target groups", func() {
+					Eventually(metrics).Should(Receive())
+					Expect(publisher.targetGroups).To(BeNil())
+					targetGroups, err := ioutil.ReadFile(serviceDiscoveryFilename)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(targetGroups)).To(BeEmpty())
+					Consistently(errMetrics).ShouldNot(Receive())
+				})
+			})
+
+			Context("and the election fails", func() {
+				BeforeEach(func() {
+					fakeElector.err = errors.New("fake-election-error")
+				})
+
+				It("returns an error", func() {
+					Eventually(metrics).Should(Receive())
+					Eventually(metrics).Should(Receive())
+					Eventually(errMetrics).Should(Receive())
+					Expect(publisher.targetGroups).To(BeNil())
+				})
+			})
+		})
+
 		Context("when there are no processes", func() {
 			BeforeEach(func() {
 				deploymentInfo.Instances[0].Processes = []deployments.Process{}
@@ -266,3 +932,22 @@ var _ = Describe("ServiceDiscoveryCollector", func() {
 		})
 	})
 })
+
+type fakeLeaderElector struct {
+	leader bool
+	err    error
+}
+
+func (e *fakeLeaderElector) IsLeader() (bool, error) {
+	return e.leader && e.err == nil, e.err
+}
+
+type fakePublisher struct {
+	targetGroups TargetGroups
+	err          error
+}
+
+func (p *fakePublisher) Publish(targetGroups TargetGroups) error {
+	p.targetGroups = targetGroups
+	return p.err
+}
@@ -0,0 +1,36 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ProcessesPorts map[string]int
+
+func LoadProcessesPorts(filename string) (ProcessesPorts, error) {
+	processesPorts := ProcessesPorts{}
+
+	if filename == "" {
+		return processesPorts, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return processesPorts, errors.New(fmt.Sprintf("Error reading processes ports file `%s`: %v", filename, err))
+	}
+
+	if err = yaml.Unmarshal(content, &processesPorts); err != nil {
+		return processesPorts, errors.New(fmt.Sprintf("Error parsing processes ports file `%s`: %v", filename, err))
+	}
+
+	for process, port := range processesPorts {
+		if port <= 0 || port > 65535 {
+			return processesPorts, errors.New(fmt.Sprintf("Invalid port `%d` for process `%s` at processes ports file `%s`", port, process, filename))
+		}
+	}
+
+	return processesPorts, nil
+}
@@ -0,0 +1,88 @@
+package collectors_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("ProcessesPorts", func() {
+	var (
+		err            error
+		tmpfile        *os.File
+		filename       string
+		content        string
+		processesPorts ProcessesPorts
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "service_discovery_ports_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = "node_exporter: 9100\nbosh_exporter: 9190\n"
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		processesPorts, err = LoadProcessesPorts(filename)
+	})
+
+	Describe("LoadProcessesPorts", func() {
+		It("returns the processes ports", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processesPorts).To(Equal(ProcessesPorts{"node_exporter": 9100, "bosh_exporter": 9190}))
+		})
+
+		Context("when there is no filename", func() {
+			BeforeEach(func() {
+				filename = ""
+			})
+
+			It("returns an empty processes ports", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(processesPorts).To(BeEmpty())
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			BeforeEach(func() {
+				filename = "/fake-processes-ports-file"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the file is not valid", func() {
+			BeforeEach(func() {
+				content = "node_exporter: [9100"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a port is not valid", func() {
+			BeforeEach(func() {
+				content = "node_exporter: 91000\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid port `91000` for process `node_exporter`"))
+			})
+		})
+	})
+})
@@ -0,0 +1,219 @@
+package collectors
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	RelabelReplace   = "replace"
+	RelabelKeep      = "keep"
+	RelabelDrop      = "drop"
+	RelabelHashMod   = "hashmod"
+	RelabelLabelMap  = "labelmap"
+	RelabelLabelDrop = "labeldrop"
+	RelabelLabelKeep = "labelkeep"
+)
+
+var relabelConfigKeys = []string{"source_labels", "separator", "regex", "modulus", "target_label", "replacement", "action"}
+
+type RelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    *string  `yaml:"separator"`
+	Regex        *string  `yaml:"regex"`
+	Modulus      uint64   `yaml:"modulus"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  *string  `yaml:"replacement"`
+	Action       string   `yaml:"action"`
+
+	regexp *regexp.Regexp
+}
+
+func LoadRelabelConfigs(filename string) ([]RelabelConfig, error) {
+	relabelConfigs := []RelabelConfig{}
+
+	if filename == "" {
+		return relabelConfigs, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return relabelConfigs, errors.New(fmt.Sprintf("Error reading relabel configs file `%s`: %v", filename, err))
+	}
+
+	if err = yaml.Unmarshal(content, &relabelConfigs); err != nil {
+		return relabelConfigs, errors.New(fmt.Sprintf("Error parsing relabel configs file `%s`: %v", filename, err))
+	}
+
+	var mappings []yaml.MapSlice
+	if err = yaml.Unmarshal(content, &mappings); err != nil {
+		return relabelConfigs, errors.New(fmt.Sprintf("Error parsing relabel configs file `%s`: %v", filename, err))
+	}
+
+	for i := range relabelConfigs {
+		if err = checkConfigKeys(mappings[i], relabelConfigKeys...); err != nil {
+			return relabelConfigs, errors.New(fmt.Sprintf("Invalid relabel config #%d at relabel configs file `%s`: %v", i+1, filename, err))
+		}
+
+		if err = relabelConfigs[i].init(); err != nil {
+			return relabelConfigs, errors.New(fmt.Sprintf("Invalid relabel config #%d at relabel configs file `%s`: %v", i+1, filename, err))
+		}
+	}
+
+	return relabelConfigs, nil
+}
+
+func (r *RelabelConfig) init() error {
+	if r.Separator == nil {
+		separator := ";"
+		r.Separator = &separator
+	}
+
+	if r.Regex == nil {
+		regex := "(.*)"
+		r.Regex = &regex
+	}
+
+	if r.Replacement == nil {
+		replacement := "$1"
+		r.Replacement = &replacement
+	}
+
+	if r.Action == "" {
+		r.Action = RelabelReplace
+	}
+	r.Action = strings.ToLower(r.Action)
+
+	compiledRegexp, err := regexp.Compile("^(?:" + *r.Regex + ")$")
+	if err != nil {
+		return errors.New(fmt.Sprintf("regex `%s` is not valid: %v", *r.Regex, err))
+	}
+	r.regexp = compiledRegexp
+
+	switch r.Action {
+	case RelabelReplace, RelabelHashMod:
+		if r.TargetLabel == "" {
+			return errors.New(fmt.Sprintf("`target_label` is required for `%s` action", r.Action))
+		}
+		if r.Action == RelabelHashMod && r.Modulus == 0 {
+			return errors.New("`modulus` is required for `hashmod` action")
+		}
+	case RelabelKeep, RelabelDrop, RelabelLabelMap, RelabelLabelDrop, RelabelLabelKeep:
+	default:
+		return errors.New(fmt.Sprintf("action `%s` is not supported", r.Action))
+	}
+
+	return nil
+}
+
+func RelabelTargetGroups(relabelConfigs []RelabelConfig, targetGroups TargetGroups) TargetGroups {
+	if len(relabelConfigs) == 0 {
+		return targetGroups
+	}
+
+	relabeledTargetGroups := TargetGroups{}
+	for _, targetGroup := range targetGroups {
+		for _, target := range targetGroup.Targets {
+			labels := model.LabelSet{}
+			for name, value := range targetGroup.Labels {
+				labels[name] = value
+			}
+			labels[model.AddressLabel] = model.LabelValue(target)
+
+			labels = relabel(relabelConfigs, labels)
+			if labels == nil || labels[model.AddressLabel] == "" {
+				continue
+			}
+
+			address := string(labels[model.AddressLabel])
+			delete(labels, model.AddressLabel)
+			relabeledTargetGroups = append(relabeledTargetGroups, TargetGroup{
+				Targets: []string{address},
+				Labels:  labels,
+			})
+		}
+	}
+
+	return relabeledTargetGroups
+}
+
+func relabel(relabelConfigs []RelabelConfig, labels model.LabelSet) model.LabelSet {
+	for _, relabelConfig := range relabelConfigs {
+		values := []string{}
+		for _, sourceLabel := range relabelConfig.SourceLabels {
+			values = append(values, string(labels[model.LabelName(sourceLabel)]))
+		}
+		value := strings.Join(values, *relabelConfig.Separator)
+
+		switch relabelConfig.Action {
+		case RelabelKeep:
+			if !relabelConfig.regexp.MatchString(value) {
+				return nil
+			}
+		case RelabelDrop:
+			if relabelConfig.regexp.MatchString(value) {
+				return nil
+			}
+		case RelabelReplace:
+			indexes := relabelConfig.regexp.FindStringSubmatchIndex(value)
+			if indexes == nil {
+				continue
+			}
+			targetLabel := string(relabelConfig.regexp.ExpandString(nil, relabelConfig.TargetLabel, value, indexes))
+			if !model.LabelName(targetLabel).IsValid() {
+				continue
+			}
+			replacement := relabelConfig.regexp.ExpandString(nil, *relabelConfig.Replacement, value, indexes)
+			if len(replacement) == 0 {
+				delete(labels, model.LabelName(targetLabel))
+				continue
+			}
+			labels[model.LabelName(targetLabel)] = model.LabelValue(replacement)
+		case RelabelHashMod:
+			hash := md5.Sum([]byte(value))
+			modulus := binary.BigEndian.Uint64(hash[8:]) % relabelConfig.Modulus
+			labels[model.LabelName(relabelConfig.TargetLabel)] = model.LabelValue(fmt.Sprintf("%d", modulus))
+		case RelabelLabelMap:
+			names := sortedLabelNames(labels)
+			for _, name := range names {
+				if relabelConfig.regexp.MatchString(string(name)) {
+					newName := relabelConfig.regexp.ReplaceAllString(string(name), *relabelConfig.Replacement)
+					labels[model.LabelName(newName)] = labels[name]
+				}
+			}
+		case RelabelLabelDrop:
+			for name := range labels {
+				if relabelConfig.regexp.MatchString(string(name)) {
+					delete(labels, name)
+				}
+			}
+		case RelabelLabelKeep:
+			for name := range labels {
+				if name != model.AddressLabel && !relabelConfig.regexp.MatchString(string(name)) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+
+	return labels
+}
+
+func sortedLabelNames(labels model.LabelSet) []model.LabelName {
+	names := []model.LabelName{}
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	return names
+}
@@ -0,0 +1,218 @@
+package collectors_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("RelabelConfigs", func() {
+	var (
+		err            error
+		tmpfile        *os.File
+		filename       string
+		content        string
+		relabelConfigs []RelabelConfig
+		targetGroups   TargetGroups
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "service_discovery_relabel_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = ""
+		targetGroups = TargetGroups{
+			{
+				Targets: []string{"1.2.3.4"},
+				Labels: model.LabelSet{
+					model.LabelName("__meta_bosh_job_name"):         model.LabelValue("fake-job-name"),
+					model.LabelName("__meta_bosh_job_process_name"): model.LabelValue("node_exporter"),
+				},
+			},
+			{
+				Targets: []string{"5.6.7.8"},
+				Labels: model.LabelSet{
+					model.LabelName("__meta_bosh_job_name"):         model.LabelValue("fake-job-name"),
+					model.LabelName("__meta_bosh_job_process_name"): model.LabelValue("fake-process-name"),
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		relabelConfigs, err = LoadRelabelConfigs(filename)
+	})
+
+	Context("when filename is empty", func() {
+		BeforeEach(func() {
+			filename = ""
+		})
+
+		It("returns no relabel configs", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(relabelConfigs).To(BeEmpty())
+		})
+
+		It("does not modify the target groups", func() {
+			Expect(RelabelTargetGroups(relabelConfigs, targetGroups)).To(Equal(targetGroups))
+		})
+	})
+
+	Context("when file does not exist", func() {
+		BeforeEach(func() {
+			filename = tmpfile.Name() + "_missing"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error reading relabel configs file"))
+		})
+	})
+
+	Context("when file is not valid YAML", func() {
+		BeforeEach(func() {
+			content = "not: [valid"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error parsing relabel configs file"))
+		})
+	})
+
+	Context("when regex is not valid", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__meta_bosh_job_name]\n  regex: '('\n  action: keep\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid relabel config #1"))
+		})
+	})
+
+	Context("when a key is not supported", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__meta_bosh_job_name]\n  regexp: 'fake-job-name'\n  action: keep\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("key `regexp` is not supported"))
+		})
+	})
+
+	Context("when action is not supported", func() {
+		BeforeEach(func() {
+			content = "- action: fake-action\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("action `fake-action` is not supported"))
+		})
+	})
+
+	Context("when target_label is missing for a replace action", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__meta_bosh_job_name]\n"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("`target_label` is required"))
+		})
+	})
+
+	Context("when there is a keep action", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__meta_bosh_job_process_name]\n  regex: node_exporter\n  action: keep\n"
+		})
+
+		It("keeps only the matching targets", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(RelabelTargetGroups(relabelConfigs, targetGroups)).To(Equal(TargetGroups{targetGroups[0]}))
+		})
+	})
+
+	Context("when there is a drop action", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__meta_bosh_job_process_name]\n  regex: node_.*\n  action: drop\n"
+		})
+
+		It("drops the matching targets", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(RelabelTargetGroups(relabelConfigs, targetGroups)).To(Equal(TargetGroups{targetGroups[1]}))
+		})
+	})
+
+	Context("when there is a replace action on the address", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__address__, __meta_bosh_job_process_name]\n  regex: '(.*);node_exporter'\n  target_label: __address__\n  replacement: '${1}:9100'\n- target_label: team\n  replacement: fake-team\n"
+		})
+
+		It("rewrites the targets and adds the static labels", func() {
+			Expect(err).ToNot(HaveOccurred())
+			relabeledTargetGroups := RelabelTargetGroups(relabelConfigs, targetGroups)
+			Expect(relabeledTargetGroups).To(HaveLen(2))
+			Expect(relabeledTargetGroups[0].Targets).To(Equal([]string{"1.2.3.4:9100"}))
+			Expect(relabeledTargetGroups[0].Labels).To(HaveKeyWithValue(model.LabelName("team"), model.LabelValue("fake-team")))
+			Expect(relabeledTargetGroups[0].Labels).ToNot(HaveKey(model.LabelName("__address__")))
+			Expect(relabeledTargetGroups[1].Targets).To(Equal([]string{"5.6.7.8"}))
+		})
+	})
+
+	Context("when there are labelmap and labeldrop actions", func() {
+		BeforeEach(func() {
+			content = "- regex: __meta_bosh_(job_name)\n  action: labelmap\n- regex: __meta_bosh_.*\n  action: labeldrop\n"
+		})
+
+		It("renames the labels", func() {
+			Expect(err).ToNot(HaveOccurred())
+			relabeledTargetGroups := RelabelTargetGroups(relabelConfigs, targetGroups)
+			Expect(relabeledTargetGroups[0].Labels).To(Equal(model.LabelSet{
+				model.LabelName("job_name"): model.LabelValue("fake-job-name"),
+			}))
+		})
+	})
+
+	Context("when there is a labelkeep action", func() {
+		BeforeEach(func() {
+			content = "- regex: __meta_bosh_job_name\n  action: labelkeep\n"
+		})
+
+		It("keeps only the matching labels and the target", func() {
+			Expect(err).ToNot(HaveOccurred())
+			relabeledTargetGroups := RelabelTargetGroups(relabelConfigs, targetGroups)
+			Expect(relabeledTargetGroups[0].Targets).To(Equal([]string{"1.2.3.4"}))
+			Expect(relabeledTargetGroups[0].Labels).To(Equal(model.LabelSet{
+				model.LabelName("__meta_bosh_job_name"): model.LabelValue("fake-job-name"),
+			}))
+		})
+	})
+
+	Context("when there is a hashmod action", func() {
+		BeforeEach(func() {
+			content = "- source_labels: [__address__]\n  modulus: 1\n  target_label: shard\n  action: hashmod\n"
+		})
+
+		It("sets the target label to the hash modulus", func() {
+			Expect(err).ToNot(HaveOccurred())
+			relabeledTargetGroups := RelabelTargetGroups(relabelConfigs, targetGroups)
+			Expect(relabeledTargetGroups[0].Labels).To(HaveKeyWithValue(model.LabelName("shard"), model.LabelValue("0")))
+		})
+	})
+})
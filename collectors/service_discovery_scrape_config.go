@@ -0,0 +1,62 @@
+package collectors
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ScrapeConfigFormat writes the target groups as a Prometheus Operator
+// ScrapeConfig resource, to be applied to the Kubernetes cluster running
+// Prometheus.
+const ScrapeConfigFormat = "scrape-config"
+
+var scrapeConfigNameInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type ScrapeConfig struct {
+	APIVersion string               `yaml:"apiVersion"`
+	Kind       string               `yaml:"kind"`
+	Metadata   ScrapeConfigMetadata `yaml:"metadata"`
+	Spec       ScrapeConfigSpec     `yaml:"spec"`
+}
+
+type ScrapeConfigMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type ScrapeConfigSpec struct {
+	StaticConfigs TargetGroups `yaml:"staticConfigs"`
+}
+
+// NewScrapeConfig returns a ScrapeConfig resource for the target groups,
+// named after the Service Discovery file name, so each per deployment file
+// gets its own resource.
+func NewScrapeConfig(filename string, targetGroups TargetGroups) ScrapeConfig {
+	return ScrapeConfig{
+		APIVersion: "monitoring.coreos.com/v1alpha1",
+		Kind:       "ScrapeConfig",
+		Metadata: ScrapeConfigMetadata{
+			Name:   scrapeConfigName(filename),
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "bosh_exporter"},
+		},
+		Spec: ScrapeConfigSpec{StaticConfigs: targetGroups},
+	}
+}
+
+// scrapeConfigName converts the file name, without its extension, to a
+// Kubernetes resource name, i.e. `/sd/bosh_target_groups.yml` to
+// `bosh-target-groups`.
+func scrapeConfigName(filename string) string {
+	name := path.Base(filename)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name = strings.Trim(scrapeConfigNameInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		return "bosh-exporter"
+	}
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+
+	return name
+}
@@ -0,0 +1,32 @@
+package collectors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("NewScrapeConfig", func() {
+	targetGroups := TargetGroups{{Targets: []string{"1.2.3.4"}}}
+
+	It("returns a ScrapeConfig resource with the target groups as static configs", func() {
+		scrapeConfig := NewScrapeConfig("/sd/bosh_target_groups.yml", targetGroups)
+
+		Expect(scrapeConfig.APIVersion).To(Equal("monitoring.coreos.com/v1alpha1"))
+		Expect(scrapeConfig.Kind).To(Equal("ScrapeConfig"))
+		Expect(scrapeConfig.Spec.StaticConfigs).To(Equal(targetGroups))
+	})
+
+	It("names the resource after the file name", func() {
+		Expect(NewScrapeConfig("/sd/bosh_target_groups.yml", targetGroups).Metadata.Name).To(Equal("bosh-target-groups"))
+	})
+
+	It("converts the file name to a Kubernetes resource name", func() {
+		Expect(NewScrapeConfig("/sd/_CF.Deployment__2.yml", targetGroups).Metadata.Name).To(Equal("cf-deployment-2"))
+	})
+
+	It("uses a default name if the file name has no valid characters", func() {
+		Expect(NewScrapeConfig("/sd/__.yml", targetGroups).Metadata.Name).To(Equal("bosh-exporter"))
+	})
+})
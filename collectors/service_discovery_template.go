@@ -0,0 +1,45 @@
+package collectors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/common/model"
+)
+
+var serviceDiscoveryTemplateFuncs = template.FuncMap{
+	"label": func(targetGroup TargetGroup, name string) string {
+		if value, ok := targetGroup.Labels[model.LabelName(name)]; ok {
+			return string(value)
+		}
+		return string(targetGroup.Labels[model.LabelName(model.MetaLabelPrefix+name)])
+	},
+	"json": func(value interface{}) (string, error) {
+		content, err := json.Marshal(value)
+		return string(content), err
+	},
+	"join": strings.Join,
+}
+
+func LoadServiceDiscoveryTemplate(filename string) (*template.Template, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading Service Discovery template file `%s`: %v", filename, err))
+	}
+
+	serviceDiscoveryTemplate, err := template.New(path.Base(filename)).Funcs(serviceDiscoveryTemplateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error parsing Service Discovery template file `%s`: %v", filename, err))
+	}
+
+	return serviceDiscoveryTemplate, nil
+}
@@ -0,0 +1,95 @@
+package collectors_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"text/template"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("LoadServiceDiscoveryTemplate", func() {
+	var (
+		err             error
+		tmpfile         *os.File
+		filename        string
+		templateContent string
+		outputTemplate  *template.Template
+		targetGroups    TargetGroups
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "service_discovery_template_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		templateContent = "{{range .}}{{label . \"bosh_job_name\"}} {{label . \"__meta_bosh_job_ip\"}} {{join .Targets \",\"}} {{json .Labels}}\n{{end}}"
+		targetGroups = TargetGroups{
+			{
+				Targets: []string{"1.2.3.4"},
+				Labels: model.LabelSet{
+					model.LabelName("__meta_bosh_job_name"): model.LabelValue("fake-job-name"),
+					model.LabelName("__meta_bosh_job_ip"):   model.LabelValue("1.2.3.4"),
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(templateContent), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		outputTemplate, err = LoadServiceDiscoveryTemplate(filename)
+	})
+
+	It("loads the template with the Service Discovery functions", func() {
+		Expect(err).ToNot(HaveOccurred())
+
+		var content bytes.Buffer
+		err = outputTemplate.Execute(&content, targetGroups)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(content.String()).To(Equal("fake-job-name 1.2.3.4 1.2.3.4 {\"__meta_bosh_job_ip\":\"1.2.3.4\",\"__meta_bosh_job_name\":\"fake-job-name\"}\n"))
+	})
+
+	Context("when filename is empty", func() {
+		BeforeEach(func() {
+			filename = ""
+		})
+
+		It("returns no template", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outputTemplate).To(BeNil())
+		})
+	})
+
+	Context("when file does not exist", func() {
+		BeforeEach(func() {
+			filename = tmpfile.Name() + "_missing"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error reading Service Discovery template file"))
+		})
+	})
+
+	Context("when the template is not valid", func() {
+		BeforeEach(func() {
+			templateContent = "{{range .}"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Error parsing Service Discovery template file"))
+		})
+	})
+})
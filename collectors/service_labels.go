@@ -0,0 +1,143 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+const instanceGroupLabel = "bosh_job_name"
+
+// ServiceLabels maps deployments and instance groups to logical labels (i.e.
+// `service` or `component`) using rules matching their names with regular
+// expressions. Labels values may reference the regular expressions named
+// captures as `$name` or `${name}`. The first matching rule applies.
+type ServiceLabels struct {
+	rules      []serviceLabelsRule
+	labelNames []string
+}
+
+type serviceLabelsRule struct {
+	Deployment    string            `yaml:"deployment"`
+	InstanceGroup string            `yaml:"instance_group"`
+	Labels        map[string]string `yaml:"labels"`
+
+	deploymentRegexp    *regexp.Regexp
+	instanceGroupRegexp *regexp.Regexp
+}
+
+func LoadServiceLabels(filename string) (*ServiceLabels, error) {
+	serviceLabels := &ServiceLabels{}
+
+	if filename == "" {
+		return serviceLabels, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return serviceLabels, errors.New(fmt.Sprintf("Error reading service labels file `%s`: %v", filename, err))
+	}
+
+	var rules []serviceLabelsRule
+	if err = yaml.Unmarshal(content, &rules); err != nil {
+		return serviceLabels, errors.New(fmt.Sprintf("Error parsing service labels file `%s`: %v", filename, err))
+	}
+
+	labelNames := map[string]bool{}
+	for i, rule := range rules {
+		if rule.deploymentRegexp, err = anchoredRegexp(rule.Deployment); err != nil {
+			return serviceLabels, errors.New(fmt.Sprintf("Invalid deployment regex `%s` for rule #%d at service labels file `%s`: %v", rule.Deployment, i, filename, err))
+		}
+
+		if rule.instanceGroupRegexp, err = anchoredRegexp(rule.InstanceGroup); err != nil {
+			return serviceLabels, errors.New(fmt.Sprintf("Invalid instance group regex `%s` for rule #%d at service labels file `%s`: %v", rule.InstanceGroup, i, filename, err))
+		}
+
+		if len(rule.Labels) == 0 {
+			return serviceLabels, errors.New(fmt.Sprintf("Missing labels for rule #%d at service labels file `%s`", i, filename))
+		}
+
+		for name := range rule.Labels {
+			if !validExtraLabelName(name) {
+				return serviceLabels, errors.New(fmt.Sprintf("Invalid label name `%s` for rule #%d at service labels file `%s`", name, i, filename))
+			}
+			labelNames[name] = true
+		}
+
+		serviceLabels.rules = append(serviceLabels.rules, rule)
+	}
+
+	for name := range labelNames {
+		serviceLabels.labelNames = append(serviceLabels.labelNames, name)
+	}
+	sort.Strings(serviceLabels.labelNames)
+
+	return serviceLabels, nil
+}
+
+func anchoredRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		expr = ".*"
+	}
+
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+func (s *ServiceLabels) LabelNames() []string {
+	return s.labelNames
+}
+
+func (s *ServiceLabels) Labels(deployment string, instanceGroup string) map[string]string {
+	labels := map[string]string{}
+	for _, rule := range s.rules {
+		deploymentMatch := rule.deploymentRegexp.FindStringSubmatch(deployment)
+		if deploymentMatch == nil {
+			continue
+		}
+
+		instanceGroupMatch := rule.instanceGroupRegexp.FindStringSubmatch(instanceGroup)
+		if instanceGroupMatch == nil {
+			continue
+		}
+
+		captures := map[string]string{}
+		addCaptures(captures, rule.deploymentRegexp, deploymentMatch)
+		addCaptures(captures, rule.instanceGroupRegexp, instanceGroupMatch)
+
+		for name, value := range rule.Labels {
+			labels[name] = os.Expand(value, func(capture string) string {
+				return captures[capture]
+			})
+		}
+		break
+	}
+
+	return labels
+}
+
+func addCaptures(captures map[string]string, re *regexp.Regexp, match []string) {
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			captures[name] = match[i]
+		}
+	}
+}
+
+func (s *ServiceLabels) Apply(collect func(ch chan<- prometheus.Metric) error, ch chan<- prometheus.Metric) error {
+	if len(s.labelNames) == 0 {
+		return collect(ch)
+	}
+
+	describer := newMetricDescriber()
+	return transformMetrics(collect, ch, func(metric prometheus.Metric) prometheus.Metric {
+		return addMetricLabels(describer, metric, deploymentLabel, s.labelNames, func(labelValues map[string]string) map[string]string {
+			return s.Labels(labelValues[deploymentLabel], labelValues[instanceGroupLabel])
+		})
+	})
+}
@@ -0,0 +1,225 @@
+package collectors_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("ServiceLabels", func() {
+	var (
+		err           error
+		tmpfile       *os.File
+		filename      string
+		content       string
+		serviceLabels *ServiceLabels
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "service_labels_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = `
+- deployment: cf-(?P<env>.*)
+  instance_group: (?P<component>router|api)
+  labels:
+    service: cf
+    component: $component
+    env: ${env}
+- deployment: cf-(?P<env>.*)
+  labels:
+    service: cf
+    env: ${env}
+`
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		serviceLabels, err = LoadServiceLabels(filename)
+	})
+
+	Describe("LoadServiceLabels", func() {
+		It("returns the service labels", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serviceLabels.LabelNames()).To(Equal([]string{"component", "env", "service"}))
+		})
+
+		Context("when there is no filename", func() {
+			BeforeEach(func() {
+				filename = ""
+			})
+
+			It("returns empty service labels", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(serviceLabels.LabelNames()).To(BeEmpty())
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			BeforeEach(func() {
+				filename = "/fake-service-labels-file"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the file is not valid", func() {
+			BeforeEach(func() {
+				content = "cf-*: [service"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a regex is not valid", func() {
+			BeforeEach(func() {
+				content = "- deployment: cf-(\n  labels:\n    service: cf\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid deployment regex"))
+			})
+		})
+
+		Context("when a rule has no labels", func() {
+			BeforeEach(func() {
+				content = "- deployment: cf-.*\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Missing labels"))
+			})
+		})
+
+		Context("when a label name is not valid", func() {
+			BeforeEach(func() {
+				content = "- deployment: cf-.*\n  labels:\n    bosh_deployment: cf\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid label name `bosh_deployment`"))
+			})
+		})
+	})
+
+	Describe("Labels", func() {
+		It("returns the labels of the first matching rule", func() {
+			Expect(serviceLabels.Labels("cf-prod", "router")).To(Equal(map[string]string{"service": "cf", "component": "router", "env": "prod"}))
+			Expect(serviceLabels.Labels("cf-prod", "diego-cell")).To(Equal(map[string]string{"service": "cf", "env": "prod"}))
+			Expect(serviceLabels.Labels("cf-dev", "")).To(Equal(map[string]string{"service": "cf", "env": "dev"}))
+		})
+
+		It("anchors the regexes", func() {
+			Expect(serviceLabels.Labels("my-cf-prod", "router")).To(BeEmpty())
+		})
+	})
+
+	Describe("Apply", func() {
+		var (
+			collectErr error
+			metrics    []*dto.Metric
+		)
+
+		BeforeEach(func() {
+			collectErr = nil
+		})
+
+		JustBeforeEach(func() {
+			jobHealthyMetric := prometheus.NewGaugeVec(
+				prometheus.GaugeOpts{
+					Namespace: "test_exporter",
+					Subsystem: "job",
+					Name:      "healthy",
+					Help:      "BOSH Job Healthy (1 for healthy, 0 for unhealthy).",
+				},
+				[]string{"bosh_deployment", "bosh_job_name"},
+			)
+			jobHealthyMetric.WithLabelValues("cf-prod", "router").Set(float64(1))
+
+			lastScrapeTimestampMetric := prometheus.NewGauge(
+				prometheus.GaugeOpts{
+					Namespace: "test_exporter",
+					Name:      "last_jobs_scrape_timestamp",
+					Help:      "Number of seconds since 1970 since last scrape of Job metrics from BOSH.",
+				},
+			)
+
+			ch := make(chan prometheus.Metric)
+			done := make(chan bool)
+			metrics = []*dto.Metric{}
+			go func() {
+				for metric := range ch {
+					var m dto.Metric
+					Expect(metric.Write(&m)).To(Succeed())
+					metrics = append(metrics, &m)
+				}
+				close(done)
+			}()
+
+			err = serviceLabels.Apply(func(ch chan<- prometheus.Metric) error {
+				jobHealthyMetric.Collect(ch)
+				lastScrapeTimestampMetric.Collect(ch)
+				return collectErr
+			}, ch)
+			close(ch)
+			<-done
+		})
+
+		labels := func(m *dto.Metric) map[string]string {
+			labels := map[string]string{}
+			for _, labelPair := range m.Label {
+				labels[labelPair.GetName()] = labelPair.GetValue()
+			}
+
+			return labels
+		}
+
+		It("adds the service labels to metrics with a deployment label", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metrics).To(HaveLen(2))
+			Expect(labels(metrics[0])).To(Equal(map[string]string{
+				"bosh_deployment": "cf-prod",
+				"bosh_job_name":   "router",
+				"component":       "router",
+				"env":             "prod",
+				"service":         "cf",
+			}))
+			Expect(metrics[0].Gauge.GetValue()).To(Equal(float64(1)))
+		})
+
+		It("does not modify metrics without a deployment label", func() {
+			Expect(labels(metrics[1])).To(BeEmpty())
+		})
+
+		Context("when the collector returns an error", func() {
+			BeforeEach(func() {
+				collectErr = errors.New("no bueno")
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package collectors
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+type SnapshotsCollector struct {
+	jobSnapshotsMetric                       *prometheus.GaugeVec
+	jobSnapshotOldestCreatedAtMetric         *prometheus.GaugeVec
+	jobSnapshotLatestCreatedAtMetric         *prometheus.GaugeVec
+	lastSnapshotsScrapeTimestampMetric       prometheus.Gauge
+	lastSnapshotsScrapeDurationSecondsMetric prometheus.Gauge
+	descs                                    metricsDescs
+}
+
+type jobSnapshots struct {
+	deploymentName string
+	jobName        string
+	jobIndex       string
+	count          int
+	oldest         time.Time
+	latest         time.Time
+}
+
+func NewSnapshotsCollector(
+	namespace string,
+	environment string,
+	boshName string,
+	boshUUID string,
+	constLabels prometheus.Labels,
+) *SnapshotsCollector {
+	descs := metricsDescs{}
+	metricConstLabels := newConstLabels(environment, boshName, boshUUID, constLabels)
+
+	jobSnapshotsMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "job",
+			Name:        "snapshots",
+			Help:        "Number of BOSH Job Disk Snapshots.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+	)
+
+	jobSnapshotOldestCreatedAtMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "job",
+			Name:        "snapshot_oldest_created_at",
+			Help:        "Number of seconds since 1970 since the oldest BOSH Job Disk Snapshot was created.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+	)
+
+	jobSnapshotLatestCreatedAtMetric := descs.newGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "job",
+			Name:        "snapshot_latest_created_at",
+			Help:        "Number of seconds since 1970 since the latest BOSH Job Disk Snapshot was created.",
+			ConstLabels: metricConstLabels,
+		},
+		[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+	)
+
+	lastSnapshotsScrapeTimestampMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_snapshots_scrape_timestamp",
+			Help:        "Number of seconds since 1970 since last scrape of Snapshots metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	lastSnapshotsScrapeDurationSecondsMetric := descs.newGauge(
+		prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   "",
+			Name:        "last_snapshots_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of Snapshots metrics from BOSH.",
+			ConstLabels: metricConstLabels,
+		},
+	)
+
+	collector := &SnapshotsCollector{
+		jobSnapshotsMetric:                       jobSnapshotsMetric,
+		jobSnapshotOldestCreatedAtMetric:         jobSnapshotOldestCreatedAtMetric,
+		jobSnapshotLatestCreatedAtMetric:         jobSnapshotLatestCreatedAtMetric,
+		lastSnapshotsScrapeTimestampMetric:       lastSnapshotsScrapeTimestampMetric,
+		lastSnapshotsScrapeDurationSecondsMetric: lastSnapshotsScrapeDurationSecondsMetric,
+		descs:                                    descs,
+	}
+	return collector
+}
+
+func (c *SnapshotsCollector) Collect(deployments []deployments.DeploymentInfo, ch chan<- prometheus.Metric) error {
+	var begun = time.Now()
+
+	c.jobSnapshotsMetric.Reset()
+	c.jobSnapshotOldestCreatedAtMetric.Reset()
+	c.jobSnapshotLatestCreatedAtMetric.Reset()
+
+	for _, deployment := range deployments {
+		c.reportJobSnapshotsMetrics(deployment, ch)
+	}
+
+	c.jobSnapshotsMetric.Collect(ch)
+	c.jobSnapshotOldestCreatedAtMetric.Collect(ch)
+	c.jobSnapshotLatestCreatedAtMetric.Collect(ch)
+
+	c.lastSnapshotsScrapeTimestampMetric.Set(float64(time.Now().Unix()))
+	c.lastSnapshotsScrapeTimestampMetric.Collect(ch)
+
+	c.lastSnapshotsScrapeDurationSecondsMetric.Set(time.Since(begun).Seconds())
+	c.lastSnapshotsScrapeDurationSecondsMetric.Collect(ch)
+
+	return nil
+}
+
+func (c *SnapshotsCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.jobSnapshotsMetric.Describe(ch)
+	c.jobSnapshotOldestCreatedAtMetric.Describe(ch)
+	c.jobSnapshotLatestCreatedAtMetric.Describe(ch)
+	c.lastSnapshotsScrapeTimestampMetric.Describe(ch)
+	c.lastSnapshotsScrapeDurationSecondsMetric.Describe(ch)
+}
+
+func (c *SnapshotsCollector) reportJobSnapshotsMetrics(
+	deployment deployments.DeploymentInfo,
+	ch chan<- prometheus.Metric,
+) {
+	jobsSnapshots := map[string]*jobSnapshots{}
+	for _, snapshot := range deployment.Snapshots {
+		key := snapshot.JobName + "/" + snapshot.JobIndex
+		snapshots, ok := jobsSnapshots[key]
+		if !ok {
+			snapshots = &jobSnapshots{
+				deploymentName: deployment.Name,
+				jobName:        snapshot.JobName,
+				jobIndex:       snapshot.JobIndex,
+				oldest:         snapshot.CreatedAt,
+				latest:         snapshot.CreatedAt,
+			}
+			jobsSnapshots[key] = snapshots
+		}
+
+		snapshots.count++
+		if snapshot.CreatedAt.Before(snapshots.oldest) {
+			snapshots.oldest = snapshot.CreatedAt
+		}
+		if snapshot.CreatedAt.After(snapshots.latest) {
+			snapshots.latest = snapshot.CreatedAt
+		}
+	}
+
+	for _, snapshots := range jobsSnapshots {
+		c.jobSnapshotsMetric.WithLabelValues(
+			snapshots.deploymentName,
+			snapshots.jobName,
+			snapshots.jobIndex,
+		).Set(float64(snapshots.count))
+
+		c.jobSnapshotOldestCreatedAtMetric.WithLabelValues(
+			snapshots.deploymentName,
+			snapshots.jobName,
+			snapshots.jobIndex,
+		).Set(float64(snapshots.oldest.Unix()))
+
+		c.jobSnapshotLatestCreatedAtMetric.WithLabelValues(
+			snapshots.deploymentName,
+			snapshots.jobName,
+			snapshots.jobIndex,
+		).Set(float64(snapshots.latest.Unix()))
+	}
+}
+
+func (c *SnapshotsCollector) descriptions() metricsDescs {
+	return c.descs
+}
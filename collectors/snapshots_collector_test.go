@@ -0,0 +1,236 @@
+package collectors_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("SnapshotsCollector", func() {
+	var (
+		namespace          string
+		environment        string
+		boshName           string
+		boshUUID           string
+		snapshotsCollector *SnapshotsCollector
+
+		jobSnapshotsMetric                       *prometheus.GaugeVec
+		jobSnapshotOldestCreatedAtMetric         *prometheus.GaugeVec
+		jobSnapshotLatestCreatedAtMetric         *prometheus.GaugeVec
+		lastSnapshotsScrapeTimestampMetric       prometheus.Gauge
+		lastSnapshotsScrapeDurationSecondsMetric prometheus.Gauge
+
+		deploymentName      = "fake-deployment-name"
+		jobName             = "fake-job-name"
+		jobIndex            = "0"
+		oldestSnapshotTime  = time.Unix(1500000000, 0)
+		latestSnapshotTime  = time.Unix(1500086400, 0)
+		otherJobIndex       = "1"
+		otherJobSnapshotCID = "fake-other-snapshot-cid"
+	)
+
+	BeforeEach(func() {
+		namespace = "test_exporter"
+		environment = "test_environment"
+		boshName = "test_bosh_name"
+		boshUUID = "test_bosh_uuid"
+
+		jobSnapshotsMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "snapshots",
+				Help:      "Number of BOSH Job Disk Snapshots.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+		)
+
+		jobSnapshotOldestCreatedAtMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "snapshot_oldest_created_at",
+				Help:      "Number of seconds since 1970 since the oldest BOSH Job Disk Snapshot was created.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+		)
+
+		jobSnapshotLatestCreatedAtMetric = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "job",
+				Name:      "snapshot_latest_created_at",
+				Help:      "Number of seconds since 1970 since the latest BOSH Job Disk Snapshot was created.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+			[]string{"bosh_deployment", "bosh_job_name", "bosh_job_index"},
+		)
+
+		lastSnapshotsScrapeTimestampMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_snapshots_scrape_timestamp",
+				Help:      "Number of seconds since 1970 since last scrape of Snapshots metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+
+		lastSnapshotsScrapeDurationSecondsMetric = prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "",
+				Name:      "last_snapshots_scrape_duration_seconds",
+				Help:      "Duration of the last scrape of Snapshots metrics from BOSH.",
+				ConstLabels: prometheus.Labels{
+					"environment": environment,
+					"bosh_name":   boshName,
+					"bosh_uuid":   boshUUID,
+				},
+			},
+		)
+	})
+
+	JustBeforeEach(func() {
+		snapshotsCollector = NewSnapshotsCollector(
+			namespace,
+			environment,
+			boshName,
+			boshUUID,
+			prometheus.Labels{},
+		)
+	})
+
+	Describe("Describe", func() {
+		var (
+			descriptions chan *prometheus.Desc
+		)
+
+		BeforeEach(func() {
+			descriptions = make(chan *prometheus.Desc)
+		})
+
+		JustBeforeEach(func() {
+			go snapshotsCollector.Describe(descriptions)
+		})
+
+		It("returns a job_snapshots metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobSnapshotsMetric.WithLabelValues(deploymentName, jobName, jobIndex).Desc())))
+		})
+
+		It("returns a job_snapshot_oldest_created_at metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobSnapshotOldestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex).Desc())))
+		})
+
+		It("returns a job_snapshot_latest_created_at metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(jobSnapshotLatestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex).Desc())))
+		})
+
+		It("returns a last_snapshots_scrape_timestamp metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastSnapshotsScrapeTimestampMetric.Desc())))
+		})
+
+		It("returns a last_snapshots_scrape_duration_seconds metric description", func() {
+			Eventually(descriptions).Should(Receive(Equal(lastSnapshotsScrapeDurationSecondsMetric.Desc())))
+		})
+	})
+
+	Describe("Collect", func() {
+		var (
+			deploymentsInfo []deployments.DeploymentInfo
+
+			metrics    chan prometheus.Metric
+			errMetrics chan error
+		)
+
+		BeforeEach(func() {
+			deploymentsInfo = []deployments.DeploymentInfo{
+				{
+					Name: deploymentName,
+					Snapshots: []deployments.Snapshot{
+						{JobName: jobName, JobIndex: jobIndex, CID: "fake-latest-snapshot-cid", CreatedAt: latestSnapshotTime},
+						{JobName: jobName, JobIndex: jobIndex, CID: "fake-oldest-snapshot-cid", CreatedAt: oldestSnapshotTime},
+						{JobName: jobName, JobIndex: otherJobIndex, CID: otherJobSnapshotCID, CreatedAt: latestSnapshotTime},
+					},
+				},
+			}
+
+			metrics = make(chan prometheus.Metric)
+			errMetrics = make(chan error, 1)
+		})
+
+		JustBeforeEach(func() {
+			go func() {
+				if err := snapshotsCollector.Collect(deploymentsInfo, metrics); err != nil {
+					errMetrics <- err
+				}
+			}()
+		})
+
+		It("returns a job_snapshots metric", func() {
+			jobSnapshotsMetric.WithLabelValues(deploymentName, jobName, jobIndex).Set(float64(2))
+
+			Eventually(metrics).Should(Receive(Equal(jobSnapshotsMetric.WithLabelValues(deploymentName, jobName, jobIndex))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_snapshots metric for each instance", func() {
+			jobSnapshotsMetric.WithLabelValues(deploymentName, jobName, otherJobIndex).Set(float64(1))
+
+			Eventually(metrics).Should(Receive(Equal(jobSnapshotsMetric.WithLabelValues(deploymentName, jobName, otherJobIndex))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_snapshot_oldest_created_at metric", func() {
+			jobSnapshotOldestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex).Set(float64(oldestSnapshotTime.Unix()))
+
+			Eventually(metrics).Should(Receive(Equal(jobSnapshotOldestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		It("returns a job_snapshot_latest_created_at metric", func() {
+			jobSnapshotLatestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex).Set(float64(latestSnapshotTime.Unix()))
+
+			Eventually(metrics).Should(Receive(Equal(jobSnapshotLatestCreatedAtMetric.WithLabelValues(deploymentName, jobName, jobIndex))))
+			Consistently(errMetrics).ShouldNot(Receive())
+		})
+
+		Context("when there are no snapshots", func() {
+			BeforeEach(func() {
+				deploymentsInfo[0].Snapshots = []deployments.Snapshot{}
+			})
+
+			It("returns only a last_snapshots_scrape_timestamp & last_snapshots_scrape_duration_seconds metric", func() {
+				Eventually(metrics).Should(Receive())
+				Eventually(metrics).Should(Receive())
+				Consistently(metrics).ShouldNot(Receive())
+				Consistently(errMetrics).ShouldNot(Receive())
+			})
+		})
+	})
+})
@@ -0,0 +1,119 @@
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var stemcellLifecycleTimeLayouts = []string{time.RFC3339, "2006-01-02"}
+
+type StemcellsLifecycle struct {
+	patterns []stemcellLifecyclePattern
+}
+
+type StemcellLifecycle struct {
+	CreatedAt time.Time
+	EOL       time.Time
+}
+
+type stemcellLifecyclePattern struct {
+	pattern   string
+	lifecycle StemcellLifecycle
+}
+
+type stemcellLifecycleConfig struct {
+	CreatedAt string `yaml:"created_at"`
+	EOL       string `yaml:"eol"`
+}
+
+func LoadStemcellsLifecycle(filename string) (*StemcellsLifecycle, error) {
+	stemcellsLifecycle := &StemcellsLifecycle{}
+
+	if filename == "" {
+		return stemcellsLifecycle, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return stemcellsLifecycle, errors.New(fmt.Sprintf("Error reading stemcells lifecycle file `%s`: %v", filename, err))
+	}
+
+	var mapping yaml.MapSlice
+	if err = yaml.Unmarshal(content, &mapping); err != nil {
+		return stemcellsLifecycle, errors.New(fmt.Sprintf("Error parsing stemcells lifecycle file `%s`: %v", filename, err))
+	}
+
+	for _, item := range mapping {
+		pattern := fmt.Sprintf("%v", item.Key)
+		if _, err = path.Match(pattern, ""); err != nil {
+			return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+		}
+
+		if values, ok := item.Value.(yaml.MapSlice); ok {
+			if err = checkConfigKeys(values, "created_at", "eol"); err != nil {
+				return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid lifecycle for stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+			}
+		}
+
+		var value []byte
+		if value, err = yaml.Marshal(item.Value); err != nil {
+			return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid lifecycle for stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+		}
+
+		var config stemcellLifecycleConfig
+		if err = yaml.Unmarshal(value, &config); err != nil {
+			return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid lifecycle for stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+		}
+
+		lifecycle := StemcellLifecycle{}
+		if lifecycle.CreatedAt, err = parseStemcellLifecycleTime(config.CreatedAt); err != nil {
+			return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid `created_at` for stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+		}
+		if lifecycle.EOL, err = parseStemcellLifecycleTime(config.EOL); err != nil {
+			return stemcellsLifecycle, errors.New(fmt.Sprintf("Invalid `eol` for stemcell pattern `%s` at stemcells lifecycle file `%s`: %v", pattern, filename, err))
+		}
+
+		stemcellsLifecycle.patterns = append(stemcellsLifecycle.patterns, stemcellLifecyclePattern{pattern: pattern, lifecycle: lifecycle})
+	}
+
+	return stemcellsLifecycle, nil
+}
+
+func (s *StemcellsLifecycle) Lifecycle(osName string, version string) StemcellLifecycle {
+	lifecycle := StemcellLifecycle{}
+	for _, pattern := range s.patterns {
+		if matched, _ := path.Match(pattern.pattern, osName+"/"+version); !matched {
+			continue
+		}
+
+		if !pattern.lifecycle.CreatedAt.IsZero() {
+			lifecycle.CreatedAt = pattern.lifecycle.CreatedAt
+		}
+		if !pattern.lifecycle.EOL.IsZero() {
+			lifecycle.EOL = pattern.lifecycle.EOL
+		}
+	}
+
+	return lifecycle
+}
+
+func parseStemcellLifecycleTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	var err error
+	for _, layout := range stemcellLifecycleTimeLayouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, errors.New(fmt.Sprintf("`%s` is not a RFC3339 timestamp or a YYYY-MM-DD date", value))
+}
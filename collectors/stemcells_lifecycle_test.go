@@ -0,0 +1,121 @@
+package collectors_test
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var _ = Describe("StemcellsLifecycle", func() {
+	var (
+		err                error
+		tmpfile            *os.File
+		filename           string
+		content            string
+		stemcellsLifecycle *StemcellsLifecycle
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "stemcells_lifecycle_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = "ubuntu-xenial/*:\n  eol: 2021-04-30\nubuntu-xenial/621.74:\n  created_at: 2020-06-09T17:40:00Z\n"
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		stemcellsLifecycle, err = LoadStemcellsLifecycle(filename)
+	})
+
+	Describe("LoadStemcellsLifecycle", func() {
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when there is no filename", func() {
+			BeforeEach(func() {
+				filename = ""
+			})
+
+			It("returns an empty stemcells lifecycle", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stemcellsLifecycle.Lifecycle("ubuntu-xenial", "621.74")).To(Equal(StemcellLifecycle{}))
+			})
+		})
+
+		Context("when the file does not exist", func() {
+			BeforeEach(func() {
+				filename = "/fake-stemcells-lifecycle-file"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the file is not valid", func() {
+			BeforeEach(func() {
+				content = "ubuntu-xenial/*: [eol"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a pattern is not valid", func() {
+			BeforeEach(func() {
+				content = "ubuntu-[:\n  eol: 2021-04-30\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when a key is not supported", func() {
+			BeforeEach(func() {
+				content = "ubuntu-xenial/*:\n  end_of_life: 2021-04-30\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("key `end_of_life` is not supported"))
+			})
+		})
+
+		Context("when a date is not valid", func() {
+			BeforeEach(func() {
+				content = "ubuntu-xenial/*:\n  eol: next year\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Lifecycle", func() {
+		It("returns the lifecycle of all matching patterns", func() {
+			Expect(stemcellsLifecycle.Lifecycle("ubuntu-xenial", "621.74")).To(Equal(StemcellLifecycle{
+				CreatedAt: time.Date(2020, 6, 9, 17, 40, 0, 0, time.UTC),
+				EOL:       time.Date(2021, 4, 30, 0, 0, 0, 0, time.UTC),
+			}))
+			Expect(stemcellsLifecycle.Lifecycle("ubuntu-xenial", "621.75")).To(Equal(StemcellLifecycle{
+				EOL: time.Date(2021, 4, 30, 0, 0, 0, 0, time.UTC),
+			}))
+			Expect(stemcellsLifecycle.Lifecycle("ubuntu-bionic", "1.10")).To(Equal(StemcellLifecycle{}))
+		})
+	})
+})
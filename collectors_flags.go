@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+var (
+	collectorsRegistry = collectors.NewCollectorsRegistry()
+
+	collectorsEnabled = collectorsFlags(collectorsRegistry)
+)
+
+// collectorsFlags defines a `collector.<name>` flag enabling each registered
+// collector, and returns the flags values by collector name.
+func collectorsFlags(registry *collectors.CollectorsRegistry) map[string]*bool {
+	enabled := map[string]*bool{}
+	for _, name := range registry.Names() {
+		enabled[name] = flag.Bool(
+			"collector."+collectorFlagName(name), true,
+			fmt.Sprintf("Enable the %s collector ($%s).", name, collectorEnvVarName(name)),
+		)
+	}
+
+	return enabled
+}
+
+func overrideCollectorsFlagsWithEnvVars() {
+	for name, enabled := range collectorsEnabled {
+		overrideWithEnvBool(collectorEnvVarName(name), enabled)
+	}
+}
+
+func setCollectorsEnabled(registry *collectors.CollectorsRegistry) error {
+	for name, enabled := range collectorsEnabled {
+		if err := registry.SetEnabled(name, *enabled); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collectorFlagName converts a collector name to kebab case, i.e.
+// `ServiceDiscovery` to `service-discovery`.
+func collectorFlagName(name string) string {
+	var flagName []rune
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			flagName = append(flagName, '-')
+		}
+		flagName = append(flagName, unicode.ToLower(r))
+	}
+
+	return string(flagName)
+}
+
+func collectorEnvVarName(name string) string {
+	return "BOSH_EXPORTER_COLLECTOR_" + strings.ToUpper(strings.Replace(collectorFlagName(name), "-", "_", -1))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/dashboards"
+)
+
+const dashboardsCommand = "dashboards"
+
+// runDashboards writes to the out directory the Grafana dashboards of the
+// metrics described by the BOSH collector, and prints their filenames.
+func runDashboards(
+	out io.Writer,
+	outDirectory string,
+	namespace string,
+	collectorsSubsystems collectors.CollectorsSubsystems,
+	boshCollector *collectors.BoshCollector,
+) error {
+	generator := dashboards.NewGenerator(
+		namespace,
+		collectorsSubsystems,
+		boshCollector.EnabledCollectors(),
+		collectors.DescribedMetricsNames(boshCollector),
+	)
+
+	grafanaDashboards := generator.Dashboards()
+	if err := dashboards.Write(outDirectory, grafanaDashboards); err != nil {
+		return err
+	}
+
+	for _, dashboard := range grafanaDashboards {
+		fmt.Fprintf(out, "%s: %s (%d panels)\n", filepath.Join(outDirectory, dashboard.UID+".json"), dashboard.Title, len(dashboard.Panels))
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package dashboards
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+const (
+	statPanel       = "stat"
+	timeseriesPanel = "timeseries"
+
+	dashboardsWidth = 24
+)
+
+var datasource = Datasource{Type: "prometheus", UID: "${datasource}"}
+
+// metric is a metric exposed by a collector.
+type metric struct {
+	collector string
+	subsystem string
+	name      string
+}
+
+type panelDefinition struct {
+	title  string
+	kind   string
+	unit   string
+	metric metric
+	// expr is the query format, where %[1]s is the metric name and %[2]s the
+	// dashboard labels selector.
+	expr   string
+	legend string
+}
+
+type dashboardDefinition struct {
+	uid   string
+	title string
+	// collector is the collector required to render the dashboard, if any.
+	collector string
+	// variables are the labels the dashboard is filtered by, whose values
+	// are read from variablesMetric.
+	variables       []string
+	variablesMetric metric
+	panels          []panelDefinition
+}
+
+var dashboardsDefinitions = []dashboardDefinition{
+	{
+		uid:             "bosh-overview",
+		title:           "BOSH Overview",
+		variables:       []string{"environment", "bosh_name"},
+		variablesMetric: metric{collectors.ExporterMetrics, "", "last_scrape_timestamp"},
+		panels: []panelDefinition{
+			{title: "Deployments", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "info"}, expr: "count(%[1]s{%[2]s})"},
+			{title: "Instances", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "instances_total"}, expr: "sum(%[1]s{%[2]s})"},
+			{title: "Unhealthy instances", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "instances_unhealthy"}, expr: "sum(%[1]s{%[2]s})"},
+			{title: "Tasks in progress", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "task_in_progress"}, expr: "sum(%[1]s{%[2]s})"},
+			{title: "Unhealthy jobs by deployment", kind: timeseriesPanel, metric: metric{filters.JobsCollector, "job", "healthy"}, expr: "count by (bosh_deployment) (%[1]s{%[2]s} == 0)", legend: "{{bosh_deployment}}"},
+			{title: "Outdated releases by deployment", kind: timeseriesPanel, metric: metric{filters.DeploymentsCollector, "deployment", "release_outdated"}, expr: "sum by (bosh_deployment) (%[1]s{%[2]s})", legend: "{{bosh_deployment}}"},
+			{title: "Network IPs free", kind: timeseriesPanel, metric: metric{filters.NetworksCollector, "network", "ips_free"}, expr: "sum by (bosh_network_name) (%[1]s{%[2]s})", legend: "{{bosh_network_name}}"},
+			{title: "Last scrape error", kind: statPanel, metric: metric{collectors.ExporterMetrics, "", "last_scrape_error"}, expr: "max(%[1]s{%[2]s})"},
+			{title: "Scrape duration", kind: timeseriesPanel, unit: "s", metric: metric{collectors.ExporterMetrics, "", "last_scrape_duration_seconds"}, expr: "max by (bosh_name) (%[1]s{%[2]s})", legend: "{{bosh_name}}"},
+			{title: "Scrape errors by collector", kind: timeseriesPanel, metric: metric{collectors.ExporterMetrics, "exporter", "scrape_errors_total"}, expr: "sum by (collector, kind) (rate(%[1]s{%[2]s}[5m]))", legend: "{{collector}} {{kind}}"},
+		},
+	},
+	{
+		uid:             "bosh-deployment",
+		title:           "BOSH Deployment",
+		collector:       filters.JobsCollector,
+		variables:       []string{"environment", "bosh_name", "bosh_deployment"},
+		variablesMetric: metric{filters.JobsCollector, "job", "healthy"},
+		panels: []panelDefinition{
+			{title: "Unhealthy jobs", kind: statPanel, metric: metric{filters.JobsCollector, "job", "healthy"}, expr: "count(%[1]s{%[2]s} == 0) or vector(0)"},
+			{title: "Unhealthy processes", kind: statPanel, metric: metric{filters.JobsCollector, "job_process", "healthy"}, expr: "count(%[1]s{%[2]s} == 0) or vector(0)"},
+			{title: "Desired instances", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "job_desired_instances"}, expr: "sum(%[1]s{%[2]s})"},
+			{title: "Task in progress", kind: statPanel, metric: metric{filters.DeploymentsCollector, "deployment", "task_in_progress"}, expr: "max(%[1]s{%[2]s})"},
+			{title: "CPU user", kind: timeseriesPanel, unit: "percent", metric: metric{filters.JobsCollector, "job", "cpu_user"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "Memory", kind: timeseriesPanel, unit: "percent", metric: metric{filters.JobsCollector, "job", "mem_percent"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "Load average", kind: timeseriesPanel, metric: metric{filters.JobsCollector, "job", "load_avg01"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "System disk", kind: timeseriesPanel, unit: "percent", metric: metric{filters.JobsCollector, "job", "system_disk_percent"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "Ephemeral disk", kind: timeseriesPanel, unit: "percent", metric: metric{filters.JobsCollector, "job", "ephemeral_disk_percent"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "Persistent disk", kind: timeseriesPanel, unit: "percent", metric: metric{filters.JobsCollector, "job", "persistent_disk_percent"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}}"},
+			{title: "Actual instances by job", kind: timeseriesPanel, metric: metric{filters.DeploymentsCollector, "deployment", "job_actual_instances"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}"},
+			{title: "Processes uptime", kind: timeseriesPanel, unit: "s", metric: metric{filters.JobsCollector, "job_process", "uptime_seconds"}, expr: "%[1]s{%[2]s}", legend: "{{bosh_job_name}}/{{bosh_job_index}} {{bosh_job_process_name}}"},
+		},
+	},
+	{
+		uid:             "bosh-service-discovery",
+		title:           "BOSH Service Discovery",
+		collector:       filters.ServiceDiscoveryCollector,
+		variables:       []string{"environment", "bosh_name"},
+		variablesMetric: metric{filters.ServiceDiscoveryCollector, "", "last_service_discovery_scrape_timestamp"},
+		panels: []panelDefinition{
+			{title: "Last refresh age", kind: statPanel, unit: "s", metric: metric{filters.ServiceDiscoveryCollector, "", "last_service_discovery_scrape_timestamp"}, expr: "time() - max(%[1]s{%[2]s})"},
+			{title: "Refresh duration", kind: timeseriesPanel, unit: "s", metric: metric{filters.ServiceDiscoveryCollector, "", "last_service_discovery_scrape_duration_seconds"}, expr: "max by (bosh_name) (%[1]s{%[2]s})", legend: "{{bosh_name}}"},
+			{title: "Collection duration (p99)", kind: timeseriesPanel, unit: "s", metric: metric{collectors.ExporterMetrics, "exporter", "collector_duration_seconds"}, expr: "histogram_quantile(0.99, sum by (bosh_name, le) (rate(%[1]s_bucket{collector=\"ServiceDiscovery\",%[2]s}[5m])))", legend: "{{bosh_name}}"},
+			{title: "Collection errors", kind: timeseriesPanel, metric: metric{collectors.ExporterMetrics, "exporter", "scrape_errors_total"}, expr: "sum by (kind) (rate(%[1]s{collector=\"ServiceDiscovery\",%[2]s}[5m]))", legend: "{{kind}}"},
+		},
+	},
+}
+
+// Generator renders the dashboards of the metrics exposed by the enabled
+// collectors.
+type Generator struct {
+	namespace         string
+	subsystems        collectors.CollectorsSubsystems
+	enabledCollectors map[string]bool
+	metricsNames      map[string]bool
+}
+
+// NewGenerator returns a generator for the metrics named metricsNames,
+// exposed under the namespace and subsystems by the enabled collectors.
+func NewGenerator(
+	namespace string,
+	subsystems collectors.CollectorsSubsystems,
+	enabledCollectors []string,
+	metricsNames []string,
+) *Generator {
+	generator := &Generator{
+		namespace:         namespace,
+		subsystems:        subsystems,
+		enabledCollectors: map[string]bool{},
+		metricsNames:      map[string]bool{},
+	}
+	for _, name := range enabledCollectors {
+		generator.enabledCollectors[name] = true
+	}
+	for _, name := range metricsNames {
+		generator.metricsNames[name] = true
+	}
+
+	return generator
+}
+
+// Dashboards returns the dashboards of the enabled collectors. Panels of
+// metrics not exposed are left out, so the dashboards never query metrics
+// that do not exist.
+func (g *Generator) Dashboards() []Dashboard {
+	dashboards := []Dashboard{}
+	for _, definition := range dashboardsDefinitions {
+		if definition.collector != "" && !g.enabledCollectors[definition.collector] {
+			continue
+		}
+
+		variablesMetricName, ok := g.metricName(definition.variablesMetric)
+		if !ok {
+			continue
+		}
+
+		dashboard := g.dashboard(definition, variablesMetricName)
+		if len(dashboard.Panels) == 0 {
+			continue
+		}
+		dashboards = append(dashboards, dashboard)
+	}
+
+	return dashboards
+}
+
+func (g *Generator) dashboard(definition dashboardDefinition, variablesMetricName string) Dashboard {
+	variables := []Variable{{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"}}
+	selector := ""
+	for _, label := range definition.variables {
+		variables = append(variables, Variable{
+			Name:       label,
+			Label:      label,
+			Type:       "query",
+			Query:      fmt.Sprintf("label_values(%s, %s)", variablesMetricName, label),
+			Datasource: &datasource,
+			Refresh:    2,
+			IncludeAll: true,
+			Multi:      true,
+			AllValue:   ".*",
+		})
+		if selector != "" {
+			selector += ","
+		}
+		selector += fmt.Sprintf("%s=~\"$%s\"", label, label)
+	}
+
+	panels := []Panel{}
+	x, y, rowHeight := 0, 0, 0
+	for _, definition := range definition.panels {
+		metricName, ok := g.metricName(definition.metric)
+		if !ok {
+			continue
+		}
+
+		width, height := 12, 8
+		if definition.kind == statPanel {
+			width, height = 6, 4
+		}
+		if x+width > dashboardsWidth {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+
+		panels = append(panels, Panel{
+			ID:          len(panels) + 1,
+			Type:        definition.kind,
+			Title:       definition.title,
+			Datasource:  datasource,
+			GridPos:     GridPos{H: height, W: width, X: x, Y: y},
+			FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: definition.unit}},
+			Targets: []Target{{
+				RefID:        "A",
+				Datasource:   datasource,
+				Expr:         fmt.Sprintf(definition.expr, metricName, selector),
+				LegendFormat: definition.legend,
+			}},
+		})
+
+		x += width
+		if height > rowHeight {
+			rowHeight = height
+		}
+	}
+
+	return Dashboard{
+		UID:           definition.uid,
+		Title:         definition.title,
+		Tags:          []string{"bosh", "bosh_exporter"},
+		Timezone:      "browser",
+		SchemaVersion: 36,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Templating:    Templating{List: variables},
+		Panels:        panels,
+	}
+}
+
+// metricName returns the name of the metric under the configured namespace
+// and subsystems, and whether it is exposed.
+func (g *Generator) metricName(m metric) (string, bool) {
+	name := prometheus.BuildFQName(g.subsystems.Namespace(g.namespace, m.collector), m.subsystem, m.name)
+	return name, g.metricsNames[name]
+}
+
+// Write writes each dashboard to a `<uid>.json` file in the directory,
+// created if needed.
+func Write(directory string, dashboards []Dashboard) error {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return errors.New(fmt.Sprintf("Error creating dashboards directory `%s`: %v", directory, err))
+	}
+
+	for _, dashboard := range dashboards {
+		content, err := json.MarshalIndent(dashboard, "", "  ")
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error marshalling dashboard `%s`: %v", dashboard.UID, err))
+		}
+
+		filename := filepath.Join(directory, dashboard.UID+".json")
+		if err = ioutil.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+			return errors.New(fmt.Sprintf("Error writing dashboard `%s`: %v", filename, err))
+		}
+	}
+
+	return nil
+}
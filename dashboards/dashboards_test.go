@@ -0,0 +1,164 @@
+package dashboards_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/dashboards"
+)
+
+var _ = Describe("Generator", func() {
+	var (
+		namespace            string
+		collectorsSubsystems collectors.CollectorsSubsystems
+		collectorsRegistry   *collectors.CollectorsRegistry
+
+		dashboards []Dashboard
+	)
+
+	dashboardsUIDs := func() []string {
+		uids := []string{}
+		for _, dashboard := range dashboards {
+			uids = append(uids, dashboard.UID)
+		}
+		return uids
+	}
+
+	dashboardPanels := func(uid string) map[string]string {
+		panels := map[string]string{}
+		for _, dashboard := range dashboards {
+			if dashboard.UID != uid {
+				continue
+			}
+			for _, panel := range dashboard.Panels {
+				panels[panel.Title] = panel.Targets[0].Expr
+			}
+		}
+		return panels
+	}
+
+	BeforeEach(func() {
+		namespace = "test_namespace"
+		collectorsSubsystems = collectors.CollectorsSubsystems{}
+		collectorsRegistry = collectors.NewCollectorsRegistry()
+	})
+
+	JustBeforeEach(func() {
+		boshCollector := collectors.NewBoshCollector(
+			nil,
+			nil,
+			collectors.BoshCollectorOptions{
+				Namespace:            namespace,
+				CollectorsSubsystems: collectorsSubsystems,
+				CollectorsRegistry:   collectorsRegistry,
+			},
+		)
+
+		generator := NewGenerator(namespace, collectorsSubsystems, boshCollector.EnabledCollectors(), collectors.DescribedMetricsNames(boshCollector))
+		dashboards = generator.Dashboards()
+	})
+
+	It("renders the dashboards", func() {
+		Expect(dashboardsUIDs()).To(Equal([]string{"bosh-overview", "bosh-deployment", "bosh-service-discovery"}))
+	})
+
+	It("renders a panel for each dashboard metric", func() {
+		Expect(dashboardPanels("bosh-overview")).To(HaveLen(10))
+		Expect(dashboardPanels("bosh-deployment")).To(HaveLen(12))
+		Expect(dashboardPanels("bosh-service-discovery")).To(HaveLen(4))
+	})
+
+	It("queries the metrics names filtered by the dashboard variables", func() {
+		Expect(dashboardPanels("bosh-deployment")).To(HaveKeyWithValue(
+			"Unhealthy jobs",
+			`count(test_namespace_job_healthy{environment=~"$environment",bosh_name=~"$bosh_name",bosh_deployment=~"$bosh_deployment"} == 0) or vector(0)`,
+		))
+	})
+
+	It("reads the dashboard variables values from the metrics", func() {
+		Expect(dashboards[1].Templating.List).To(ContainElement(Variable{
+			Name:       "bosh_deployment",
+			Label:      "bosh_deployment",
+			Type:       "query",
+			Query:      "label_values(test_namespace_job_healthy, bosh_deployment)",
+			Datasource: &Datasource{Type: "prometheus", UID: "${datasource}"},
+			Refresh:    2,
+			IncludeAll: true,
+			Multi:      true,
+			AllValue:   ".*",
+		}))
+	})
+
+	Context("when collectors have subsystems", func() {
+		BeforeEach(func() {
+			collectorsSubsystems = collectors.CollectorsSubsystems{filters.JobsCollector: "jobs", collectors.ExporterMetrics: "director"}
+		})
+
+		It("queries the metrics with their subsystem", func() {
+			Expect(dashboardPanels("bosh-deployment")).To(HaveKeyWithValue(
+				"CPU user",
+				`test_namespace_jobs_job_cpu_user{environment=~"$environment",bosh_name=~"$bosh_name",bosh_deployment=~"$bosh_deployment"}`,
+			))
+			Expect(dashboardPanels("bosh-overview")).To(HaveKeyWithValue(
+				"Last scrape error",
+				`max(test_namespace_director_last_scrape_error{environment=~"$environment",bosh_name=~"$bosh_name"})`,
+			))
+		})
+	})
+
+	Context("when a collector is disabled", func() {
+		BeforeEach(func() {
+			Expect(collectorsRegistry.SetEnabled(filters.JobsCollector, false)).To(Succeed())
+		})
+
+		It("does not render the dashboards requiring it", func() {
+			Expect(dashboardsUIDs()).To(Equal([]string{"bosh-overview", "bosh-service-discovery"}))
+		})
+
+		It("does not render the panels of its metrics", func() {
+			Expect(dashboardPanels("bosh-overview")).ToNot(HaveKey("Unhealthy jobs by deployment"))
+			Expect(dashboardPanels("bosh-overview")).To(HaveKey("Unhealthy instances"))
+		})
+	})
+})
+
+var _ = Describe("Write", func() {
+	var (
+		err       error
+		directory string
+	)
+
+	BeforeEach(func() {
+		directory, err = ioutil.TempDir("", "dashboards")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(directory)
+	})
+
+	It("writes each dashboard to a JSON file", func() {
+		Expect(Write(filepath.Join(directory, "out"), []Dashboard{{UID: "fake-dashboard", Title: "Fake Dashboard"}})).To(Succeed())
+
+		content, err := ioutil.ReadFile(filepath.Join(directory, "out", "fake-dashboard.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		dashboard := Dashboard{}
+		Expect(json.Unmarshal(content, &dashboard)).To(Succeed())
+		Expect(dashboard.Title).To(Equal("Fake Dashboard"))
+	})
+
+	It("returns an error when the directory cannot be created", func() {
+		Expect(ioutil.WriteFile(filepath.Join(directory, "file"), []byte{}, 0644)).To(Succeed())
+
+		Expect(Write(filepath.Join(directory, "file", "out"), []Dashboard{})).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,3 @@
+// Package dashboards renders Grafana dashboards for the metrics exposed by
+// the exporter.
+package dashboards
@@ -0,0 +1,151 @@
+package deployments
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+// HMEvent is a BOSH Health Monitor event, as written by the HM JSON plugin.
+type HMEvent struct {
+	Kind       string `json:"kind"`
+	Deployment string `json:"deployment"`
+	InstanceID string `json:"instance_id"`
+	JobState   string `json:"job_state"`
+}
+
+// CachedFetcher caches the deployments of a Fetcher between calls. Only the
+// deployments invalidated by BOSH Health Monitor events are fetched again,
+// all of them being fetched again at the full refresh interval.
+type CachedFetcher struct {
+	fetcher             *Fetcher
+	fullRefreshInterval time.Duration
+	mutex               sync.Mutex
+	deploymentsInfo     map[string]DeploymentInfo
+	excludedDeployments map[string]bool
+	invalidDeployments  map[string]bool
+	refreshedAt         time.Time
+}
+
+func NewCachedFetcher(fetcher *Fetcher, fullRefreshInterval time.Duration) *CachedFetcher {
+	return &CachedFetcher{
+		fetcher:             fetcher,
+		fullRefreshInterval: fullRefreshInterval,
+		excludedDeployments: map[string]bool{},
+		invalidDeployments:  map[string]bool{},
+	}
+}
+
+func (c *CachedFetcher) FilteredDeployments() uint64 {
+	return c.fetcher.FilteredDeployments()
+}
+
+func (c *CachedFetcher) FilteredInstances() uint64 {
+	return c.fetcher.FilteredInstances()
+}
+
+// Deployments returns the cached deployments, fetching again the
+// invalidated ones. If one of them cannot be fetched (i.e. it has been
+// deleted), all deployments are fetched again.
+func (c *CachedFetcher) Deployments() ([]DeploymentInfo, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.deploymentsInfo == nil || time.Since(c.refreshedAt) >= c.fullRefreshInterval {
+		return c.refreshAll()
+	}
+
+	for name := range c.invalidDeployments {
+		log.Debugf("Refreshing invalidated deployment `%s`...", name)
+		deploymentInfo, err := c.fetcher.Deployment(name)
+		if err != nil {
+			log.Errorf("Error refreshing deployment `%s`, refreshing all deployments: %v", name, err)
+			return c.refreshAll()
+		}
+
+		if deploymentInfo == nil {
+			delete(c.deploymentsInfo, name)
+			c.excludedDeployments[name] = true
+		} else {
+			c.deploymentsInfo[name] = *deploymentInfo
+		}
+		delete(c.invalidDeployments, name)
+	}
+
+	return c.cachedDeployments(), nil
+}
+
+// Invalidate marks a deployment to be fetched again on the next call.
+func (c *CachedFetcher) Invalidate(deploymentName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.invalidDeployments[deploymentName] = true
+}
+
+// HandleHMEvent invalidates the deployment of an alert, or the deployment of
+// a heartbeat reporting a state different from the cached instance one (or
+// coming from a deployment not seen yet).
+func (c *CachedFetcher) HandleHMEvent(event HMEvent) {
+	if event.Deployment == "" {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch event.Kind {
+	case "alert":
+		c.invalidDeployments[event.Deployment] = true
+	case "heartbeat":
+		if c.excludedDeployments[event.Deployment] {
+			return
+		}
+
+		deploymentInfo, ok := c.deploymentsInfo[event.Deployment]
+		if !ok {
+			c.invalidDeployments[event.Deployment] = true
+			return
+		}
+
+		for _, instance := range deploymentInfo.Instances {
+			if instance.ID == event.InstanceID && instance.State != event.JobState {
+				c.invalidDeployments[event.Deployment] = true
+				return
+			}
+		}
+	}
+}
+
+func (c *CachedFetcher) refreshAll() ([]DeploymentInfo, error) {
+	log.Debugf("Refreshing all deployments...")
+	c.fetcher.expireCache()
+	deploymentsInfo, err := c.fetcher.Deployments()
+	if err != nil {
+		return deploymentsInfo, err
+	}
+
+	c.deploymentsInfo = map[string]DeploymentInfo{}
+	for _, deploymentInfo := range deploymentsInfo {
+		c.deploymentsInfo[deploymentInfo.Name] = deploymentInfo
+	}
+	c.excludedDeployments = map[string]bool{}
+	c.invalidDeployments = map[string]bool{}
+	c.refreshedAt = time.Now()
+
+	return c.cachedDeployments(), nil
+}
+
+func (c *CachedFetcher) cachedDeployments() []DeploymentInfo {
+	deploymentsInfo := []DeploymentInfo{}
+	for _, deploymentInfo := range c.deploymentsInfo {
+		deploymentsInfo = append(deploymentsInfo, deploymentInfo)
+	}
+	sort.Slice(deploymentsInfo, func(i, j int) bool {
+		return deploymentsInfo[i].Name < deploymentsInfo[j].Name
+	})
+
+	return deploymentsInfo
+}
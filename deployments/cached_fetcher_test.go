@@ -0,0 +1,151 @@
+package deployments_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+
+	"github.com/cloudfoundry-community/bosh_exporter/fakes"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+var _ = Describe("CachedFetcher", func() {
+	var (
+		fullRefreshInterval time.Duration
+		refreshInterval     time.Duration
+		boshClient          *directorfakes.FakeDirector
+		updatedBoshClient   *directorfakes.FakeDirector
+		deploymentInfo      DeploymentInfo
+		updatedInfo         DeploymentInfo
+		cachedFetcher       *CachedFetcher
+	)
+
+	BeforeEach(func() {
+		fullRefreshInterval = time.Hour
+		refreshInterval = 0
+		deploymentInfo = fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0))
+		updatedInfo = fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0))
+		updatedInfo.Instances[0].State = "failing"
+		updatedInfo.Instances[0].Healthy = false
+		boshClient = fakes.NewFakeDirector(deploymentInfo)
+		updatedBoshClient = fakes.NewFakeDirector(updatedInfo)
+	})
+
+	JustBeforeEach(func() {
+		deploymentsFilter, err := filters.NewDeploymentsFilter([]string{}, boshClient)
+		Expect(err).ToNot(HaveOccurred())
+		deploymentsFetcher := NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil)
+		deploymentsFetcher.SetRefreshIntervals(refreshInterval, refreshInterval)
+		cachedFetcher = NewCachedFetcher(deploymentsFetcher, fullRefreshInterval)
+
+		_, err = cachedFetcher.Deployments()
+		Expect(err).ToNot(HaveOccurred())
+		boshClient.FindDeploymentStub = updatedBoshClient.FindDeployment
+	})
+
+	fetchedStates := func() []string {
+		deploymentsInfo, err := cachedFetcher.Deployments()
+		Expect(err).ToNot(HaveOccurred())
+
+		states := []string{}
+		for _, deploymentInfo := range deploymentsInfo {
+			for _, instance := range deploymentInfo.Instances {
+				states = append(states, deploymentInfo.Name+"/"+instance.State)
+			}
+		}
+		return states
+	}
+
+	It("caches the deployments", func() {
+		Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/running"}))
+		Expect(boshClient.DeploymentsCallCount()).To(Equal(1))
+		Expect(boshClient.FindDeploymentCallCount()).To(Equal(0))
+	})
+
+	Context("when an alert is received", func() {
+		It("refreshes the alert deployment", func() {
+			cachedFetcher.HandleHMEvent(HMEvent{Kind: "alert", Deployment: "fake-deployment-name"})
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/failing"}))
+			Expect(boshClient.DeploymentsCallCount()).To(Equal(1))
+			Expect(boshClient.FindDeploymentCallCount()).To(Equal(1))
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/failing"}))
+			Expect(boshClient.FindDeploymentCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when a heartbeat is received", func() {
+		It("does not refresh the deployment if the instance state did not change", func() {
+			cachedFetcher.HandleHMEvent(HMEvent{Kind: "heartbeat", Deployment: "fake-deployment-name", InstanceID: deploymentInfo.Instances[0].ID, JobState: "running"})
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/running"}))
+			Expect(boshClient.FindDeploymentCallCount()).To(Equal(0))
+		})
+
+		It("refreshes the deployment if the instance state changed", func() {
+			cachedFetcher.HandleHMEvent(HMEvent{Kind: "heartbeat", Deployment: "fake-deployment-name", InstanceID: deploymentInfo.Instances[0].ID, JobState: "failing"})
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/failing"}))
+			Expect(boshClient.FindDeploymentCallCount()).To(Equal(1))
+		})
+
+		It("fetches the deployment if it was not seen yet", func() {
+			newBoshClient := fakes.NewFakeDirector(fakes.DeploymentInfo("new-deployment-name", fakes.Instance("fake-job-name", 0)))
+			boshClient.FindDeploymentStub = newBoshClient.FindDeployment
+
+			cachedFetcher.HandleHMEvent(HMEvent{Kind: "heartbeat", Deployment: "new-deployment-name", InstanceID: "fake-instance-id", JobState: "running"})
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/running", "new-deployment-name/running"}))
+		})
+	})
+
+	Context("when an invalidated deployment cannot be fetched", func() {
+		It("refreshes all deployments", func() {
+			boshClient.FindDeploymentStub = func(string) (director.Deployment, error) {
+				return nil, errors.New("no deployment")
+			}
+
+			cachedFetcher.Invalidate("deleted-deployment-name")
+
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/running"}))
+			Expect(boshClient.DeploymentsCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when a deployment is deleted", func() {
+		BeforeEach(func() {
+			refreshInterval = time.Hour
+		})
+
+		It("does not keep the deployments cached by the fetcher", func() {
+			boshClient.DeploymentsReturns([]director.Deployment{}, nil)
+			boshClient.FindDeploymentStub = func(string) (director.Deployment, error) {
+				return nil, errors.New("no deployment")
+			}
+
+			cachedFetcher.HandleHMEvent(HMEvent{Kind: "alert", Deployment: "fake-deployment-name"})
+
+			Expect(fetchedStates()).To(BeEmpty())
+			Expect(boshClient.DeploymentsCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when the full refresh interval is elapsed", func() {
+		BeforeEach(func() {
+			fullRefreshInterval = 0
+		})
+
+		It("refreshes all deployments", func() {
+			Expect(fetchedStates()).To(Equal([]string{"fake-deployment-name/running"}))
+			Expect(boshClient.DeploymentsCallCount()).To(Equal(2))
+		})
+	})
+})
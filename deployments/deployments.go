@@ -1,76 +1,156 @@
 package deployments
 
+import (
+	"time"
+)
+
 type DeploymentInfo struct {
-	Name      string
-	Instances []Instance
-	Releases  []Release
-	Stemcells []Stemcell
+	Name           string          `json:"name"`
+	Teams          []string        `json:"teams"`
+	ManifestSHA1   string          `json:"manifest_sha1"`
+	InstanceGroups []InstanceGroup `json:"instance_groups"`
+	Instances      []Instance      `json:"instances"`
+	Releases       []Release       `json:"releases"`
+	Stemcells      []Stemcell      `json:"stemcells"`
+	Tasks          []Task          `json:"tasks"`
+	Snapshots      []Snapshot      `json:"snapshots"`
+	// ActualInstances is the number of instances with a VM of every
+	// instance group, including the instances discarded by the jobs and AZs
+	// filters.
+	ActualInstances map[string]int `json:"actual_instances"`
+	// InstancesShortFormat is set when the instances were read in short
+	// format, without their state, vitals and processes, because reading
+	// them in full format timed out.
+	InstancesShortFormat bool `json:"instances_short_format"`
+	// UnavailableDetails are the details not read, because they are not
+	// needed by the enabled collectors or because reading them failed.
+	// They are then empty.
+	UnavailableDetails Details `json:"unavailable_details"`
+}
+
+// Details selects the deployments details read besides their manifest and
+// instances, each costing BOSH Director API calls.
+type Details struct {
+	// Teams are the teams the deployments belong to.
+	Teams bool `json:"teams"`
+	// Releases are the deployments releases, flagged as outdated against
+	// the latest versions uploaded to the Director.
+	Releases bool `json:"releases"`
+	// Stemcells are the deployments stemcells, which the instance groups
+	// stemcells are resolved to.
+	Stemcells bool `json:"stemcells"`
+	// Snapshots are the deployments instances persistent disks snapshots.
+	Snapshots bool `json:"snapshots"`
+	// Tasks are the current tasks and the recent ones of the deployments.
+	Tasks bool `json:"tasks"`
+}
+
+// AllDetails selects every deployment detail.
+var AllDetails = Details{Teams: true, Releases: true, Stemcells: true, Snapshots: true, Tasks: true}
+
+type InstanceGroup struct {
+	Name               string   `json:"name"`
+	Instances          int      `json:"instances"`
+	Update             Update   `json:"update"`
+	Stemcell           Stemcell `json:"stemcell"`
+	VMType             string   `json:"vm_type"`
+	PersistentDiskType string   `json:"persistent_disk_type"`
+	Networks           []string `json:"networks"`
+}
+
+type Update struct {
+	Canaries        int           `json:"canaries"`
+	MaxInFlight     int           `json:"max_in_flight"`
+	CanaryWatchTime time.Duration `json:"canary_watch_time"`
+	UpdateWatchTime time.Duration `json:"update_watch_time"`
 }
 
 type Instance struct {
-	AgentID            string
-	Name               string
-	ID                 string
-	Index              string
-	Bootstrap          bool
-	IPs                []string
-	AZ                 string
-	VMType             string
-	ResourcePool       string
-	ResurrectionPaused bool
-	Healthy            bool
-	Processes          []Process
-	Vitals             Vitals
+	AgentID            string    `json:"agent_id"`
+	Name               string    `json:"name"`
+	ID                 string    `json:"id"`
+	Index              string    `json:"index"`
+	Bootstrap          bool      `json:"bootstrap"`
+	IPs                []string  `json:"ips"`
+	DNS                []string  `json:"dns"`
+	AZ                 string    `json:"az"`
+	VMType             string    `json:"vm_type"`
+	ResourcePool       string    `json:"resource_pool"`
+	ResurrectionPaused bool      `json:"resurrection_paused"`
+	DiskCIDs           []string  `json:"disk_cids"`
+	Healthy            bool      `json:"healthy"`
+	State              string    `json:"state"`
+	Processes          []Process `json:"processes"`
+	Vitals             Vitals    `json:"vitals"`
 }
 
 type Process struct {
-	Name    string
-	Uptime  *uint64
-	Healthy bool
-	CPU     CPU
-	Mem     MemInt
+	Name    string  `json:"name"`
+	Uptime  *uint64 `json:"uptime"`
+	Healthy bool    `json:"healthy"`
+	State   string  `json:"state"`
+	CPU     CPU     `json:"cpu"`
+	Mem     MemInt  `json:"mem"`
 }
 
 type Vitals struct {
-	CPU            CPU
-	Mem            Mem
-	Swap           Mem
-	Uptime         *uint64
-	Load           []string
-	SystemDisk     Disk
-	EphemeralDisk  Disk
-	PersistentDisk Disk
+	CPU            CPU      `json:"cpu"`
+	Mem            Mem      `json:"mem"`
+	Swap           Mem      `json:"swap"`
+	Uptime         *uint64  `json:"uptime"`
+	Load           []string `json:"load"`
+	SystemDisk     Disk     `json:"system_disk"`
+	EphemeralDisk  Disk     `json:"ephemeral_disk"`
+	PersistentDisk Disk     `json:"persistent_disk"`
 }
 
 type CPU struct {
-	Total *float64
-	Sys   string
-	User  string
-	Wait  string
+	Total *float64 `json:"total"`
+	Sys   string   `json:"sys"`
+	User  string   `json:"user"`
+	Wait  string   `json:"wait"`
 }
 
 type Mem struct {
-	KB      string
-	Percent string
+	KB      string `json:"kb"`
+	Percent string `json:"percent"`
 }
 
 type MemInt struct {
-	KB      *uint64
-	Percent *float64
+	KB      *uint64  `json:"kb"`
+	Percent *float64 `json:"percent"`
 }
 
 type Disk struct {
-	InodePercent string
-	Percent      string
+	InodePercent string `json:"inode_percent"`
+	Percent      string `json:"percent"`
 }
 
 type Release struct {
-	Name    string
-	Version string
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	LatestVersion string `json:"latest_version"`
+	Outdated      bool   `json:"outdated"`
 }
 
 type Stemcell struct {
-	Name    string
-	Version string
-	OSName  string
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	OSName  string `json:"os_name"`
+}
+
+type Task struct {
+	ID             int       `json:"id"`
+	Description    string    `json:"description"`
+	State          string    `json:"state"`
+	StartedAt      time.Time `json:"started_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+type Snapshot struct {
+	JobName   string    `json:"job_name"`
+	JobIndex  string    `json:"job_index"`
+	CID       string    `json:"cid"`
+	CreatedAt time.Time `json:"created_at"`
+	Clean     bool      `json:"clean"`
 }
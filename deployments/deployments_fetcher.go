@@ -1,102 +1,749 @@
 package deployments
 
 import (
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cppforlife/go-semi-semantic/version"
 	"github.com/prometheus/common/log"
 
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
 )
 
+// DefaultRecentTasksLimit is the default number of recent tasks read.
+const DefaultRecentTasksLimit = 100
+
 type Fetcher struct {
+	boshClient        director.Director
 	deploymentsFilter filters.DeploymentsFilter
+	teamsFilter       *filters.TeamsFilter
+	jobsFilter        *filters.RegexpFilter
+	azsFilter         *filters.AZsFilter
+	shardFilter       *filters.ShardFilter
+
+	deploymentsRefreshInterval time.Duration
+	instancesRefreshInterval   time.Duration
+	cacheMutex                 sync.Mutex
+	cachedDeployments          []cachedDeployment
+	deploymentsFetchedAt       time.Time
+	cachedInstances            map[string]cachedInstances
+	instancesReads             map[string]*instancesRead
+	parsedManifests            map[string]parsedManifest
+	fetchObserver              FetchObserver
+	instancesBackend           InstancesBackend
+	instancesTimeout           time.Duration
+	details                    Details
+	recentTasksLimit           int
+	fetchErrorObserver         FetchErrorObserver
+}
+
+// FetchObserver is notified of the duration of every deployment fetched from
+// the BOSH Director.
+type FetchObserver func(deploymentName string, duration time.Duration)
+
+// FetchErrorObserver is notified of every error reading a deployment detail,
+// which is then unavailable rather than failing the fetch. The deployment
+// name is empty for the details read for all deployments at once.
+type FetchErrorObserver func(deploymentName string, err error)
+
+type cachedDeployment struct {
+	deployment     director.Deployment
+	deploymentInfo DeploymentInfo
+}
+
+type parsedManifest struct {
+	sha1           string
+	instanceGroups []InstanceGroup
+}
+
+type cachedInstances struct {
+	instances []Instance
+	fetchedAt time.Time
+}
+
+// NewFetcher returns a Fetcher of the BOSH deployments selected by the
+// filters. A nil teams, jobs, AZs or shard filter does not filter anything.
+func NewFetcher(
+	boshClient director.Director,
+	deploymentsFilter filters.DeploymentsFilter,
+	teamsFilter *filters.TeamsFilter,
+	jobsFilter *filters.RegexpFilter,
+	azsFilter *filters.AZsFilter,
+	shardFilter *filters.ShardFilter,
+) *Fetcher {
+	if teamsFilter == nil {
+		teamsFilter = filters.NewTeamsFilter([]string{})
+	}
+	if jobsFilter == nil {
+		jobsFilter, _ = filters.NewRegexpFilter([]string{})
+	}
+	if azsFilter == nil {
+		azsFilter, _ = filters.NewAZsFilter([]string{})
+	}
+	if shardFilter == nil {
+		shardFilter, _ = filters.NewShardFilter(0, 1)
+	}
+
+	return &Fetcher{
+		boshClient:        boshClient,
+		deploymentsFilter: deploymentsFilter,
+		teamsFilter:       teamsFilter,
+		jobsFilter:        jobsFilter,
+		azsFilter:         azsFilter,
+		shardFilter:       shardFilter,
+		cachedInstances:   map[string]cachedInstances{},
+		instancesReads:    map[string]*instancesRead{},
+		parsedManifests:   map[string]parsedManifest{},
+		instancesBackend:  InstancesEndpointBackend,
+		details:           AllDetails,
+		recentTasksLimit:  DefaultRecentTasksLimit,
+	}
+}
+
+// SetRefreshIntervals caches the deployments list (with their teams,
+// manifests, releases, stemcells, snapshots and tasks) and their instances
+// (with their vitals and processes) between calls, fetching them again once
+// their interval is elapsed. A 0 interval fetches them on every call.
+func (f *Fetcher) SetRefreshIntervals(deploymentsRefreshInterval time.Duration, instancesRefreshInterval time.Duration) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.deploymentsRefreshInterval = deploymentsRefreshInterval
+	f.instancesRefreshInterval = instancesRefreshInterval
+	f.cachedDeployments = nil
+	f.cachedInstances = map[string]cachedInstances{}
+}
+
+// SetFetchObserver sets the observer notified of every deployment fetch.
+func (f *Fetcher) SetFetchObserver(observer FetchObserver) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.fetchObserver = observer
+}
+
+// SetFetchErrorObserver sets the observer notified of every error reading a
+// deployment detail.
+func (f *Fetcher) SetFetchErrorObserver(observer FetchErrorObserver) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.fetchErrorObserver = observer
+}
+
+// SetDetails selects the deployments details read, i.e. the ones needed by
+// the enabled collectors. By default, all details are read.
+func (f *Fetcher) SetDetails(details Details) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.details = details
+	f.cachedDeployments = nil
+}
+
+// SetRecentTasksLimit sets the number of recent tasks read, besides the
+// current ones, to find the deployments last tasks.
+func (f *Fetcher) SetRecentTasksLimit(limit int) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.recentTasksLimit = limit
+}
+
+// SetInstancesBackend sets the backend reading the deployments instances. By
+// default, they are read from the instances endpoint.
+func (f *Fetcher) SetInstancesBackend(backend InstancesBackend) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.instancesBackend = backend
+}
+
+// SetInstancesTimeout sets the time after which the instances of a deployment
+// still being read in full format are read again in short format. A 0 timeout
+// waits for the full format.
+func (f *Fetcher) SetInstancesTimeout(timeout time.Duration) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.instancesTimeout = timeout
 }
 
-func NewFetcher(deploymentsFilter filters.DeploymentsFilter) *Fetcher {
-	return &Fetcher{deploymentsFilter: deploymentsFilter}
+// SetDirectorCompatibility adapts the requests to the Director API features,
+// reading the instances from the VMs endpoint of old Directors.
+func (f *Fetcher) SetDirectorCompatibility(compatibility DirectorCompatibility) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	if compatibility.VMsEndpoint {
+		f.instancesBackend = VMsEndpointBackend
+	}
+}
+
+func (f *Fetcher) FilteredDeployments() uint64 {
+	return f.deploymentsFilter.Filtered() + f.teamsFilter.Filtered() + f.shardFilter.Filtered()
+}
+
+func (f *Fetcher) FilteredInstances() uint64 {
+	return f.jobsFilter.Filtered() + f.azsFilter.Filtered()
 }
 
 func (f *Fetcher) Deployments() ([]DeploymentInfo, error) {
+	if cachedDeployments, ok := f.deploymentsFromCache(); ok {
+		deploymentsInfo, err := f.refreshDeploymentsInstances(cachedDeployments)
+		if err == nil {
+			return deploymentsInfo, nil
+		}
+
+		// A cached deployment may have been deleted since the deployments
+		// list was fetched, so it is fetched again to not keep exposing it.
+		log.Errorf("Error refreshing cached deployments instances, fetching all deployments: %v", err)
+		f.expireCache()
+	}
+
 	var deploymentsInfo = []DeploymentInfo{}
+	var fetchedDeployments = []cachedDeployment{}
 	var mutex = &sync.Mutex{}
-	var wg = &sync.WaitGroup{}
+	var group = &errGroup{}
 
 	deployments, err := f.deploymentsFilter.GetDeployments()
 	if err != nil {
 		return deploymentsInfo, err
 	}
 
-	doneChannel := make(chan bool, 1)
-	errChannel := make(chan error, 1)
+	latestReleaseVersions := f.latestReleaseVersions()
+
+	tasks := f.deploymentsTasks()
+
 	for _, deployment := range deployments {
-		wg.Add(1)
-		go func(deployment director.Deployment) {
-			defer wg.Done()
-			deploymentInfo, err := f.fetchDeploymentInfo(deployment)
+		deployment := deployment
+		group.Go(func() error {
+			if !f.shardFilter.Enabled(deployment.Name()) {
+				return nil
+			}
+
+			enabled, err := f.teamsFilter.Enabled(deployment)
 			if err != nil {
-				errChannel <- err
-				return
+				return err
+			}
+			if !enabled {
+				return nil
 			}
 
+			deploymentInfo, err := f.fetchDeploymentInfo(deployment, latestReleaseVersions)
+			if err != nil {
+				return err
+			}
+			setDeploymentTasks(deploymentInfo, tasks)
+
 			mutex.Lock()
 			deploymentsInfo = append(deploymentsInfo, *deploymentInfo)
+			fetchedDeployments = append(fetchedDeployments, cachedDeployment{deployment: deployment, deploymentInfo: *deploymentInfo})
 			mutex.Unlock()
-		}(deployment)
+			return nil
+		})
 	}
 
-	go func() {
-		wg.Wait()
-		close(doneChannel)
-	}()
+	if err := group.Wait(); err != nil {
+		return []DeploymentInfo{}, err
+	}
 
-	select {
-	case <-doneChannel:
-	case err := <-errChannel:
-		return deploymentsInfo, err
+	f.cacheDeployments(fetchedDeployments)
+
+	return deploymentsInfo, nil
+}
+
+// Deployment fetches a single deployment. It returns nil if the deployment
+// is filtered out.
+func (f *Fetcher) Deployment(name string) (*DeploymentInfo, error) {
+	if !f.deploymentsFilter.Enabled(name) || !f.shardFilter.Enabled(name) {
+		return nil, nil
+	}
+
+	deployment, err := f.boshClient.FindDeployment(name)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error while reading deployment `%s`: %v", name, err))
+	}
+
+	enabled, err := f.teamsFilter.Enabled(deployment)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	latestReleaseVersions := f.latestReleaseVersions()
+
+	tasks := f.deploymentsTasks()
+
+	f.cacheMutex.Lock()
+	delete(f.cachedInstances, name)
+	f.cacheMutex.Unlock()
+
+	deploymentInfo, err := f.fetchDeploymentInfo(deployment, latestReleaseVersions)
+	if err != nil {
+		return nil, err
+	}
+	setDeploymentTasks(deploymentInfo, tasks)
+
+	return deploymentInfo, nil
+}
+
+func (f *Fetcher) deploymentsFromCache() ([]cachedDeployment, bool) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	if f.deploymentsRefreshInterval <= 0 || f.cachedDeployments == nil || time.Since(f.deploymentsFetchedAt) >= f.deploymentsRefreshInterval {
+		return nil, false
+	}
+
+	return f.cachedDeployments, true
+}
+
+func (f *Fetcher) expireCache() {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	f.cachedDeployments = nil
+	f.cachedInstances = map[string]cachedInstances{}
+}
+
+func (f *Fetcher) cacheDeployments(deployments []cachedDeployment) {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	deploymentsNames := map[string]bool{}
+	for _, deployment := range deployments {
+		deploymentsNames[deployment.deploymentInfo.Name] = true
+	}
+	for name := range f.cachedInstances {
+		if !deploymentsNames[name] {
+			delete(f.cachedInstances, name)
+		}
+	}
+	for name := range f.parsedManifests {
+		if !deploymentsNames[name] {
+			delete(f.parsedManifests, name)
+		}
+	}
+
+	if f.deploymentsRefreshInterval <= 0 {
+		return
+	}
+	f.cachedDeployments = deployments
+	f.deploymentsFetchedAt = time.Now()
+}
+
+func (f *Fetcher) refreshDeploymentsInstances(cachedDeployments []cachedDeployment) ([]DeploymentInfo, error) {
+	var deploymentsInfo = []DeploymentInfo{}
+	var mutex = &sync.Mutex{}
+	var group = &errGroup{}
+
+	for _, cached := range cachedDeployments {
+		cached := cached
+		group.Go(func() error {
+			deploymentInfo := cached.deploymentInfo
+			instances, shortFormat, err := f.fetchDeploymentInstances(cached.deployment)
+			if err != nil {
+				return err
+			}
+			f.setDeploymentInstances(&deploymentInfo, instances, shortFormat)
+
+			mutex.Lock()
+			deploymentsInfo = append(deploymentsInfo, deploymentInfo)
+			mutex.Unlock()
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return []DeploymentInfo{}, err
 	}
 
 	return deploymentsInfo, nil
 }
 
-func (f *Fetcher) fetchDeploymentInfo(deployment director.Deployment) (*DeploymentInfo, error) {
-	deploymentInfo := &DeploymentInfo{
-		Name: deployment.Name(),
+func (f *Fetcher) fetchedDetails() Details {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	return f.details
+}
+
+// observeFetchError logs an error reading a deployment detail, and notifies
+// the fetch error observer.
+func (f *Fetcher) observeFetchError(deploymentName string, err error) {
+	log.Error(err)
+
+	f.cacheMutex.Lock()
+	fetchErrorObserver := f.fetchErrorObserver
+	f.cacheMutex.Unlock()
+	if fetchErrorObserver != nil {
+		fetchErrorObserver(deploymentName, err)
+	}
+}
+
+// latestReleaseVersions returns the latest version of every release, or nil
+// if the releases are not read or reading them failed.
+func (f *Fetcher) latestReleaseVersions() map[string]version.Version {
+	if !f.fetchedDetails().Releases {
+		return nil
+	}
+
+	latestReleaseVersions, err := f.fetchLatestReleaseVersions()
+	if err != nil {
+		f.observeFetchError("", err)
+		return nil
+	}
+
+	return latestReleaseVersions
+}
+
+func (f *Fetcher) fetchLatestReleaseVersions() (map[string]version.Version, error) {
+	latestReleaseVersions := map[string]version.Version{}
+
+	log.Debugf("Reading Releases:")
+	releases, err := f.boshClient.Releases()
+	if err != nil {
+		return latestReleaseVersions, errors.New(fmt.Sprintf("Error while reading Releases: %v", err))
+	}
+
+	for _, release := range releases {
+		latestVersion, ok := latestReleaseVersions[release.Name()]
+		if !ok || release.Version().IsGt(latestVersion) {
+			latestReleaseVersions[release.Name()] = release.Version()
+		}
+	}
+
+	return latestReleaseVersions, nil
+}
+
+// deploymentsTasks returns the tasks of every deployment, or nil if the
+// tasks are not read or reading them failed.
+func (f *Fetcher) deploymentsTasks() map[string][]Task {
+	if !f.fetchedDetails().Tasks {
+		return nil
+	}
+
+	tasks, err := f.fetchTasks()
+	if err != nil {
+		f.observeFetchError("", err)
+		return nil
+	}
+
+	return tasks
+}
+
+func setDeploymentTasks(deploymentInfo *DeploymentInfo, tasks map[string][]Task) {
+	if tasks == nil {
+		deploymentInfo.UnavailableDetails.Tasks = true
+		return
+	}
+	deploymentInfo.Tasks = tasks[deploymentInfo.Name]
+}
+
+func (f *Fetcher) fetchTasks() (map[string][]Task, error) {
+	deploymentsTasks := map[string][]Task{}
+
+	log.Debugf("Reading current Tasks:")
+	currentTasks, err := f.boshClient.CurrentTasks(director.TasksFilter{All: true})
+	if err != nil {
+		return deploymentsTasks, errors.New(fmt.Sprintf("Error while reading current Tasks: %v", err))
 	}
 
-	instances, err := f.fetchDeploymentInstances(deployment)
+	log.Debugf("Reading recent Tasks:")
+	f.cacheMutex.Lock()
+	recentTasksLimit := f.recentTasksLimit
+	f.cacheMutex.Unlock()
+	recentTasks, err := f.boshClient.RecentTasks(recentTasksLimit, director.TasksFilter{All: true})
+	if err != nil {
+		return deploymentsTasks, errors.New(fmt.Sprintf("Error while reading recent Tasks: %v", err))
+	}
+
+	tasksIDs := map[int]bool{}
+	for _, task := range append(currentTasks, recentTasks...) {
+		if task.DeploymentName() == "" || tasksIDs[task.ID()] {
+			continue
+		}
+		tasksIDs[task.ID()] = true
+
+		deploymentTask := Task{
+			ID:             task.ID(),
+			Description:    task.Description(),
+			State:          task.State(),
+			StartedAt:      task.StartedAt(),
+			LastActivityAt: task.LastActivityAt(),
+		}
+		deploymentsTasks[task.DeploymentName()] = append(deploymentsTasks[task.DeploymentName()], deploymentTask)
+	}
+
+	return deploymentsTasks, nil
+}
+
+func (f *Fetcher) fetchDeploymentInfo(deployment director.Deployment, latestReleaseVersions map[string]version.Version) (*DeploymentInfo, error) {
+	begun := time.Now()
+	deploymentInfo, err := f.readDeploymentInfo(deployment, latestReleaseVersions)
 	if err != nil {
 		return deploymentInfo, err
 	}
-	deploymentInfo.Instances = instances
 
-	releases, err := f.fetchDeploymentReleases(deployment)
+	f.cacheMutex.Lock()
+	fetchObserver := f.fetchObserver
+	f.cacheMutex.Unlock()
+	if fetchObserver != nil {
+		fetchObserver(deploymentInfo.Name, time.Since(begun))
+	}
+
+	return deploymentInfo, nil
+}
+
+func (f *Fetcher) readDeploymentInfo(deployment director.Deployment, latestReleaseVersions map[string]version.Version) (*DeploymentInfo, error) {
+	deploymentInfo := &DeploymentInfo{
+		Name:      deployment.Name(),
+		Teams:     []string{},
+		Releases:  []Release{},
+		Stemcells: []Stemcell{},
+		Snapshots: []Snapshot{},
+	}
+	details := f.fetchedDetails()
+
+	deploymentInfo.UnavailableDetails.Teams = !f.readDetail(deployment.Name(), details.Teams, func() (err error) {
+		deploymentInfo.Teams, err = f.fetchDeploymentTeams(deployment)
+		return err
+	})
+
+	manifest, err := f.fetchDeploymentManifest(deployment)
 	if err != nil {
 		return deploymentInfo, err
 	}
-	deploymentInfo.Releases = releases
+	deploymentInfo.ManifestSHA1 = fmt.Sprintf("%x", sha1.Sum([]byte(manifest)))
 
-	stemcells, err := f.fetchDeploymentStemcells(deployment)
+	instanceGroups, err := f.parseDeploymentManifest(deployment.Name(), deploymentInfo.ManifestSHA1, manifest)
+	if err != nil {
+		return deploymentInfo, errors.New(fmt.Sprintf("Error while parsing Manifest for deployment `%s`: %v", deployment.Name(), err))
+	}
+	deploymentInfo.InstanceGroups = instanceGroups
+
+	instances, shortFormat, err := f.fetchDeploymentInstances(deployment)
 	if err != nil {
 		return deploymentInfo, err
 	}
-	deploymentInfo.Stemcells = stemcells
+	f.setDeploymentInstances(deploymentInfo, instances, shortFormat)
+
+	deploymentInfo.UnavailableDetails.Releases = !f.readDetail(deployment.Name(), latestReleaseVersions != nil, func() (err error) {
+		deploymentInfo.Releases, err = f.fetchDeploymentReleases(deployment, latestReleaseVersions)
+		return err
+	})
+
+	deploymentInfo.UnavailableDetails.Stemcells = !f.readDetail(deployment.Name(), details.Stemcells, func() (err error) {
+		deploymentInfo.Stemcells, err = f.fetchDeploymentStemcells(deployment)
+		return err
+	})
+	deploymentInfo.InstanceGroups = resolveInstanceGroupsStemcells(instanceGroups, deploymentInfo.Stemcells)
+
+	deploymentInfo.UnavailableDetails.Snapshots = !f.readDetail(deployment.Name(), details.Snapshots, func() (err error) {
+		deploymentInfo.Snapshots, err = f.fetchDeploymentSnapshots(deployment)
+		return err
+	})
 
 	return deploymentInfo, nil
 }
 
-func (f *Fetcher) fetchDeploymentInstances(deployment director.Deployment) ([]Instance, error) {
-	deploymentInstances := []Instance{}
+// readDetail reads a deployment detail if it is selected. It returns false
+// if the detail is not selected or reading it failed, the error being
+// notified rather than failing the deployment fetch.
+func (f *Fetcher) readDetail(deploymentName string, selected bool, read func() error) bool {
+	if !selected {
+		return false
+	}
+
+	if err := read(); err != nil {
+		f.observeFetchError(deploymentName, err)
+		return false
+	}
+
+	return true
+}
+
+func (f *Fetcher) fetchDeploymentTeams(deployment director.Deployment) ([]string, error) {
+	deploymentTeams := []string{}
+
+	log.Debugf("Reading Teams for deployment `%s`:", deployment.Name())
+	teams, err := deployment.Teams()
+	if err != nil {
+		return deploymentTeams, errors.New(fmt.Sprintf("Error while reading Teams for deployment `%s`: %v", deployment.Name(), err))
+	}
+
+	deploymentTeams = append(deploymentTeams, teams...)
+
+	return deploymentTeams, nil
+}
+
+// parseDeploymentManifest parses the instance groups of a manifest, reusing
+// the ones parsed last time when the manifest did not change.
+func (f *Fetcher) parseDeploymentManifest(deploymentName string, manifestSHA1 string, manifest string) ([]InstanceGroup, error) {
+	f.cacheMutex.Lock()
+	parsed, ok := f.parsedManifests[deploymentName]
+	f.cacheMutex.Unlock()
+	if ok && parsed.sha1 == manifestSHA1 {
+		return parsed.instanceGroups, nil
+	}
+
+	instanceGroups, err := parseManifestInstanceGroups(deploymentName, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	f.cacheMutex.Lock()
+	f.parsedManifests[deploymentName] = parsedManifest{sha1: manifestSHA1, instanceGroups: instanceGroups}
+	f.cacheMutex.Unlock()
+
+	return instanceGroups, nil
+}
+
+func (f *Fetcher) fetchDeploymentManifest(deployment director.Deployment) (string, error) {
+	log.Debugf("Reading Manifest for deployment `%s`:", deployment.Name())
+	manifest, err := deployment.Manifest()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error while reading Manifest for deployment `%s`: %v", deployment.Name(), err))
+	}
+
+	return manifest, nil
+}
+
+// setDeploymentInstances sets the instances of the deployment not discarded
+// by the jobs and AZs filters, and the number of instances of every instance
+// group regardless of these filters.
+func (f *Fetcher) setDeploymentInstances(deploymentInfo *DeploymentInfo, instances []Instance, shortFormat bool) {
+	deploymentInfo.Instances = []Instance{}
+	deploymentInfo.ActualInstances = map[string]int{}
+	deploymentInfo.InstancesShortFormat = shortFormat
+
+	for _, instance := range instances {
+		deploymentInfo.ActualInstances[instance.Name]++
+
+		if !f.jobsFilter.Enabled(instance.Name) {
+			continue
+		}
+
+		if !f.azsFilter.Enabled(instance.AZ) {
+			continue
+		}
+
+		deploymentInfo.Instances = append(deploymentInfo.Instances, instance)
+	}
+}
+
+// fetchDeploymentInstances returns the instances of the deployment, before
+// the jobs and AZs filters, and whether they were read in short format. Instances read in short format are
+// not cached, so the full format is tried again on the next fetch.
+func (f *Fetcher) fetchDeploymentInstances(deployment director.Deployment) ([]Instance, bool, error) {
+	f.cacheMutex.Lock()
+	cached, ok := f.cachedInstances[deployment.Name()]
+	instancesRefreshInterval := f.instancesRefreshInterval
+	f.cacheMutex.Unlock()
+
+	if ok && time.Since(cached.fetchedAt) < instancesRefreshInterval {
+		return cached.instances, false, nil
+	}
+
+	instances, shortFormat, err := f.readDeploymentInstances(deployment)
+	if err != nil {
+		return instances, shortFormat, err
+	}
+
+	if instancesRefreshInterval > 0 && !shortFormat {
+		f.cacheMutex.Lock()
+		f.cachedInstances[deployment.Name()] = cachedInstances{instances: instances, fetchedAt: time.Now()}
+		f.cacheMutex.Unlock()
+	}
+
+	return instances, shortFormat, nil
+}
+
+// instancesRead is a read of the instances of a deployment in full format,
+// which is closed once the result is set.
+type instancesRead struct {
+	done      chan struct{}
+	instances []director.VMInfo
+	err       error
+}
+
+func (f *Fetcher) readDeploymentInstances(deployment director.Deployment) ([]Instance, bool, error) {
+	f.cacheMutex.Lock()
+	instancesBackend := f.instancesBackend
+	instancesTimeout := f.instancesTimeout
+	f.cacheMutex.Unlock()
 
 	log.Debugf("Reading Instances for deployment `%s`:", deployment.Name())
-	instances, err := deployment.InstanceInfos()
+	if instancesTimeout <= 0 {
+		instances, err := instancesBackend.Instances(deployment)
+		if err != nil {
+			return []Instance{}, false, errors.New(fmt.Sprintf("Error while reading Instances for deployment `%s`: %v", deployment.Name(), err))
+		}
+		return f.deploymentInstances(deployment.Name(), instances), false, nil
+	}
+
+	read := f.startInstancesRead(deployment, instancesBackend)
+
+	timer := time.NewTimer(instancesTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-read.done:
+		f.cacheMutex.Lock()
+		if f.instancesReads[deployment.Name()] == read {
+			delete(f.instancesReads, deployment.Name())
+		}
+		f.cacheMutex.Unlock()
+
+		if read.err != nil {
+			return []Instance{}, false, errors.New(fmt.Sprintf("Error while reading Instances for deployment `%s`: %v", deployment.Name(), read.err))
+		}
+		return f.deploymentInstances(deployment.Name(), read.instances), false, nil
+	case <-timer.C:
+	}
+
+	log.Warnf("Reading Instances for deployment `%s` in full format did not complete within %s, reading them in short format", deployment.Name(), instancesTimeout)
+	instances, err := ShortFormatInstancesBackend.Instances(deployment)
 	if err != nil {
-		return deploymentInstances, errors.New(fmt.Sprintf("Error while reading Instances for deployment `%s`: %v", deployment.Name(), err))
+		return []Instance{}, true, errors.New(fmt.Sprintf("Error while reading Instances in short format for deployment `%s`: %v", deployment.Name(), err))
 	}
 
+	return f.deploymentInstances(deployment.Name(), instances), true, nil
+}
+
+// startInstancesRead starts reading the instances of the deployment in full
+// format, unless a read is already in flight. The full format task can not be
+// cancelled: a read timing out is left running, and the next fetches wait for
+// it and use its result instead of piling up tasks on the BOSH Director.
+func (f *Fetcher) startInstancesRead(deployment director.Deployment, instancesBackend InstancesBackend) *instancesRead {
+	f.cacheMutex.Lock()
+	defer f.cacheMutex.Unlock()
+
+	if read, ok := f.instancesReads[deployment.Name()]; ok {
+		return read
+	}
+
+	read := &instancesRead{done: make(chan struct{})}
+	f.instancesReads[deployment.Name()] = read
+	go func() {
+		read.instances, read.err = instancesBackend.Instances(deployment)
+		close(read.done)
+	}()
+
+	return read
+}
+
+func (f *Fetcher) deploymentInstances(deploymentName string, instances []director.VMInfo) []Instance {
+	deploymentInstances := []Instance{}
+
 	for _, instance := range instances {
 		if instance.VMID == "" {
 			continue
@@ -108,11 +755,14 @@ func (f *Fetcher) fetchDeploymentInstances(deployment director.Deployment) ([]In
 			ID:                 instance.ID,
 			Bootstrap:          instance.Bootstrap,
 			IPs:                instance.IPs,
+			DNS:                instance.DNS,
 			AZ:                 instance.AZ,
 			VMType:             instance.VMType,
 			ResourcePool:       instance.ResourcePool,
 			ResurrectionPaused: instance.ResurrectionPaused,
+			DiskCIDs:           diskCIDs(instance),
 			Healthy:            instance.IsRunning(),
+			State:              instance.ProcessState,
 			Vitals: Vitals{
 				CPU: CPU{
 					Sys:  instance.Vitals.CPU.Sys,
@@ -154,6 +804,7 @@ func (f *Fetcher) fetchDeploymentInstances(deployment director.Deployment) ([]In
 				Name:    process.Name,
 				Uptime:  process.Uptime.Seconds,
 				Healthy: process.IsRunning(),
+				State:   process.State,
 				CPU: CPU{
 					Total: process.CPU.Total,
 				},
@@ -169,10 +820,45 @@ func (f *Fetcher) fetchDeploymentInstances(deployment director.Deployment) ([]In
 		deploymentInstances = append(deploymentInstances, deploymentInstance)
 	}
 
-	return deploymentInstances, nil
+	return dedupeInstances(deploymentName, deploymentInstances)
 }
 
-func (f *Fetcher) fetchDeploymentReleases(deployment director.Deployment) ([]Release, error) {
+// dedupeInstances keeps a single instance per instance group and ID (or
+// index when the director does not report IDs): while an instance is being
+// recreated, the director can briefly report both its old and new VMs, whose
+// series would be duplicated. The healthy VM is kept over the other one.
+func dedupeInstances(deploymentName string, instances []Instance) []Instance {
+	dedupedInstances := []Instance{}
+	positions := map[string]int{}
+
+	for _, instance := range instances {
+		id := instance.ID
+		if id == "" {
+			id = instance.Index
+		}
+		if id == "" {
+			dedupedInstances = append(dedupedInstances, instance)
+			continue
+		}
+
+		key := instance.Name + "/" + id
+		position, ok := positions[key]
+		if !ok {
+			positions[key] = len(dedupedInstances)
+			dedupedInstances = append(dedupedInstances, instance)
+			continue
+		}
+
+		log.Debugf("Instance `%s` of deployment `%s` is reported twice, keeping a single one", key, deploymentName)
+		if !dedupedInstances[position].Healthy && instance.Healthy {
+			dedupedInstances[position] = instance
+		}
+	}
+
+	return dedupedInstances
+}
+
+func (f *Fetcher) fetchDeploymentReleases(deployment director.Deployment, latestReleaseVersions map[string]version.Version) ([]Release, error) {
 	deploymentReleases := []Release{}
 
 	log.Debugf("Reading Releases for deployment `%s`:", deployment.Name())
@@ -183,8 +869,13 @@ func (f *Fetcher) fetchDeploymentReleases(deployment director.Deployment) ([]Rel
 
 	for _, release := range releases {
 		deploymentRelease := Release{
-			Name:    release.Name(),
-			Version: release.Version().AsString(),
+			Name:          release.Name(),
+			Version:       release.Version().AsString(),
+			LatestVersion: release.Version().AsString(),
+		}
+		if latestVersion, ok := latestReleaseVersions[release.Name()]; ok && latestVersion.IsGt(release.Version()) {
+			deploymentRelease.LatestVersion = latestVersion.AsString()
+			deploymentRelease.Outdated = true
 		}
 		deploymentReleases = append(deploymentReleases, deploymentRelease)
 	}
@@ -192,6 +883,44 @@ func (f *Fetcher) fetchDeploymentReleases(deployment director.Deployment) ([]Rel
 	return deploymentReleases, nil
 }
 
+// resolveInstanceGroupsStemcells replaces the stemcell requested by each
+// instance group manifest (by name or os, and possibly `latest` version) with
+// the deployment stemcell it resolves to. Deployments using a single stemcell
+// resolve all their instance groups to it. The parsed instance groups are
+// cached, so they are copied rather than updated.
+func resolveInstanceGroupsStemcells(instanceGroups []InstanceGroup, stemcells []Stemcell) []InstanceGroup {
+	resolvedInstanceGroups := make([]InstanceGroup, 0, len(instanceGroups))
+	for _, instanceGroup := range instanceGroups {
+		instanceGroup.Stemcell = resolveStemcell(instanceGroup.Stemcell, stemcells)
+		resolvedInstanceGroups = append(resolvedInstanceGroups, instanceGroup)
+	}
+	return resolvedInstanceGroups
+}
+
+func resolveStemcell(requested Stemcell, stemcells []Stemcell) Stemcell {
+	if len(stemcells) == 1 {
+		return stemcells[0]
+	}
+	if requested.Name == "" && requested.OSName == "" {
+		return Stemcell{}
+	}
+
+	for _, stemcell := range stemcells {
+		if requested.Name != "" && requested.Name != stemcell.Name {
+			continue
+		}
+		if requested.OSName != "" && requested.OSName != stemcell.OSName {
+			continue
+		}
+		if requested.Version != "" && requested.Version != "latest" && requested.Version != stemcell.Version {
+			continue
+		}
+		return stemcell
+	}
+
+	return Stemcell{}
+}
+
 func (f *Fetcher) fetchDeploymentStemcells(deployment director.Deployment) ([]Stemcell, error) {
 	deploymentStemcells := []Stemcell{}
 
@@ -212,3 +941,42 @@ func (f *Fetcher) fetchDeploymentStemcells(deployment director.Deployment) ([]St
 
 	return deploymentStemcells, nil
 }
+
+func (f *Fetcher) fetchDeploymentSnapshots(deployment director.Deployment) ([]Snapshot, error) {
+	deploymentSnapshots := []Snapshot{}
+
+	log.Debugf("Reading Snapshots for deployment `%s`:", deployment.Name())
+	snapshots, err := deployment.Snapshots()
+	if err != nil {
+		return deploymentSnapshots, errors.New(fmt.Sprintf("Error while reading Snapshots for deployment `%s`: %v", deployment.Name(), err))
+	}
+
+	for _, snapshot := range snapshots {
+		deploymentSnapshot := Snapshot{
+			JobName:   snapshot.Job,
+			CID:       snapshot.CID,
+			CreatedAt: snapshot.CreatedAt,
+			Clean:     snapshot.Clean,
+		}
+		if snapshot.Index != nil {
+			deploymentSnapshot.JobIndex = strconv.Itoa(*snapshot.Index)
+		}
+		deploymentSnapshots = append(deploymentSnapshots, deploymentSnapshot)
+	}
+
+	return deploymentSnapshots, nil
+}
+
+// diskCIDs returns the persistent disks attached to an instance: older BOSH
+// Directors only report a single `disk_cid`.
+func diskCIDs(instance director.VMInfo) []string {
+	if len(instance.DiskIDs) > 0 {
+		return instance.DiskIDs
+	}
+
+	if instance.DiskID != "" {
+		return []string{instance.DiskID}
+	}
+
+	return []string{}
+}
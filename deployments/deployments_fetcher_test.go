@@ -1,9 +1,12 @@
 package deployments_test
 
 import (
+	"crypto/sha1"
 	"errors"
 	"flag"
+	"fmt"
 	"strconv"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -12,6 +15,7 @@ import (
 	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
 	"github.com/cppforlife/go-semi-semantic/version"
 
+	"github.com/cloudfoundry-community/bosh_exporter/fakes"
 	"github.com/cloudfoundry-community/bosh_exporter/filters"
 
 	. "github.com/cloudfoundry-community/bosh_exporter/deployments"
@@ -27,33 +31,76 @@ var _ = Describe("Fetcher", func() {
 		boshDeployments    []string
 		boshClient         *directorfakes.FakeDirector
 		deploymentsFilter  *filters.DeploymentsFilter
+		teamsFilters       []string
+		jobsFilters        []string
+		azsFilters         []string
+		shardIndex         int
+		shardCount         int
+		jobsFilter         *filters.RegexpFilter
 		deploymentsFetcher *Fetcher
 	)
 
 	BeforeEach(func() {
 		boshDeployments = []string{}
+		teamsFilters = []string{}
+		jobsFilters = []string{}
+		azsFilters = []string{}
+		shardIndex = 0
+		shardCount = 1
 		boshClient = &directorfakes.FakeDirector{}
 	})
 
 	JustBeforeEach(func() {
-		deploymentsFilter = filters.NewDeploymentsFilter(boshDeployments, boshClient)
-		deploymentsFetcher = NewFetcher(*deploymentsFilter)
+		deploymentsFilter, err = filters.NewDeploymentsFilter(boshDeployments, boshClient)
+		Expect(err).ToNot(HaveOccurred())
+		jobsFilter, err = filters.NewRegexpFilter(jobsFilters)
+		Expect(err).ToNot(HaveOccurred())
+		azsFilter, err := filters.NewAZsFilter(azsFilters)
+		Expect(err).ToNot(HaveOccurred())
+		shardFilter, err := filters.NewShardFilter(shardIndex, shardCount)
+		Expect(err).ToNot(HaveOccurred())
+		deploymentsFetcher = NewFetcher(boshClient, *deploymentsFilter, filters.NewTeamsFilter(teamsFilters), jobsFilter, azsFilter, shardFilter)
 	})
 
 	Describe("Deployments", func() {
 		var (
-			deploymentName                = "fake-deployment-name"
+			deploymentName     = "fake-deployment-name"
+			deploymentTeam     = "fake-deployment-team"
+			deploymentManifest = `---
+update:
+  canaries: 1
+  max_in_flight: 50%
+  canary_watch_time: 1000-30000
+  update_watch_time: 5000
+instance_groups:
+- name: fake-job-name
+  instances: 4
+  stemcell: default
+  vm_type: fake-vm-type
+  persistent_disk_type: fake-disk-type
+  networks:
+  - name: fake-network
+  - name: fake-other-network
+- name: fake-other-job-name
+  instances: 2
+  update:
+    canaries: 2
+    max_in_flight: 10%
+    update_watch_time: 1000-60000
+`
 			agentID                       = "fake-agent-id"
 			jobName                       = "fake-job-name"
 			jobID                         = "fake-job-id"
 			jobIndex                      = 0
 			jobBootstrap                  = true
 			jobIP                         = "1.2.3.4"
+			jobDNS                        = "fake-job-id.fake-job-name.fake-network.fake-deployment-name.bosh"
 			jobAZ                         = "fake-job-az"
 			jobVMType                     = "fake-job-vm-type"
 			jobResourcePool               = "fake-job-resource-pool"
 			jobResurrectionPause          = true
 			jobVMID                       = "fake-job-vmid"
+			jobDiskCID                    = "fake-job-disk-cid"
 			processState                  = "running"
 			jobUptimeSeconds              = uint64(3600)
 			jobLoadAvg01                  = float64(0.01)
@@ -80,19 +127,32 @@ var _ = Describe("Fetcher", func() {
 			jobProcessMemPercent          = float64(20)
 			releaseName                   = "fake-release-name"
 			releaseVersion                = "1.2.3"
+			latestReleaseVersion          = "1.3.0"
+			taskID                        = 42
+			taskDescription               = "create deployment"
+			taskState                     = "processing"
+			taskStartedAt                 = time.Unix(1500000000, 0)
+			taskLastActivityAt            = time.Unix(1500000600, 0)
+			recentTaskState               = "done"
+			snapshotCID                   = "fake-snapshot-cid"
+			snapshotCreatedAt             = time.Unix(1500000000, 0)
 			stemcellName                  = "fake-stemcell-name"
 			stemcellVersion               = "4.5.6"
 			stemcellOSName                = "fake-stemcell-os-name"
 
-			processes   []director.VMInfoProcess
-			vitals      director.VMInfoVitals
-			instances   []director.VMInfo
-			release     director.Release
-			releases    []director.Release
-			stemcell    director.Stemcell
-			stemcells   []director.Stemcell
-			deployments []director.Deployment
-			deployment  director.Deployment
+			processes     []director.VMInfoProcess
+			vitals        director.VMInfoVitals
+			instances     []director.VMInfo
+			release       director.Release
+			releases      []director.Release
+			latestRelease director.Release
+			tasks         []director.Task
+			recentTasks   []director.Task
+			snapshots     []director.Snapshot
+			stemcell      director.Stemcell
+			stemcells     []director.Stemcell
+			deployments   []director.Deployment
+			deployment    director.Deployment
 
 			deploymentsInfo         []DeploymentInfo
 			expectedDeploymentsInfo []DeploymentInfo
@@ -156,11 +216,13 @@ var _ = Describe("Fetcher", func() {
 					Bootstrap:          jobBootstrap,
 					ProcessState:       processState,
 					IPs:                []string{jobIP},
+					DNS:                []string{jobDNS},
 					AZ:                 jobAZ,
 					VMType:             jobVMType,
 					ResourcePool:       jobResourcePool,
 					ResurrectionPaused: jobResurrectionPause,
 					VMID:               jobVMID,
+					DiskID:             jobDiskCID,
 					Vitals:             vitals,
 					Processes:          processes,
 				},
@@ -172,6 +234,42 @@ var _ = Describe("Fetcher", func() {
 			}
 			releases = []director.Release{release}
 
+			latestRelease = &directorfakes.FakeRelease{
+				NameStub:    func() string { return releaseName },
+				VersionStub: func() version.Version { return version.MustNewVersionFromString(latestReleaseVersion) },
+			}
+			boshClient.ReleasesReturns([]director.Release{release, latestRelease}, nil)
+
+			tasks = []director.Task{
+				&directorfakes.FakeTask{
+					IDStub:             func() int { return taskID },
+					DescriptionStub:    func() string { return taskDescription },
+					StateStub:          func() string { return taskState },
+					StartedAtStub:      func() time.Time { return taskStartedAt },
+					LastActivityAtStub: func() time.Time { return taskLastActivityAt },
+					DeploymentNameStub: func() string { return deploymentName },
+				},
+				&directorfakes.FakeTask{
+					IDStub:          func() int { return taskID + 1 },
+					DescriptionStub: func() string { return "create release" },
+					StateStub:       func() string { return taskState },
+				},
+			}
+			boshClient.CurrentTasksReturns(tasks, nil)
+
+			recentTasks = []director.Task{
+				&directorfakes.FakeTask{
+					IDStub:             func() int { return taskID - 1 },
+					DescriptionStub:    func() string { return taskDescription },
+					StateStub:          func() string { return recentTaskState },
+					StartedAtStub:      func() time.Time { return taskStartedAt },
+					LastActivityAtStub: func() time.Time { return taskLastActivityAt },
+					DeploymentNameStub: func() string { return deploymentName },
+				},
+				tasks[0],
+			}
+			boshClient.RecentTasksReturns(recentTasks, nil)
+
 			stemcell = &directorfakes.FakeStemcell{
 				NameStub:    func() string { return stemcellName },
 				VersionStub: func() version.Version { return version.MustNewVersionFromString(stemcellVersion) },
@@ -179,11 +277,18 @@ var _ = Describe("Fetcher", func() {
 			}
 			stemcells = []director.Stemcell{stemcell}
 
+			snapshots = []director.Snapshot{
+				director.Snapshot{Job: jobName, Index: &jobIndex, CID: snapshotCID, CreatedAt: snapshotCreatedAt, Clean: true},
+			}
+
 			deployment = &directorfakes.FakeDeployment{
 				NameStub:          func() string { return deploymentName },
+				TeamsStub:         func() ([]string, error) { return []string{deploymentTeam}, nil },
+				ManifestStub:      func() (string, error) { return deploymentManifest, nil },
 				InstanceInfosStub: func() ([]director.VMInfo, error) { return instances, nil },
 				ReleasesStub:      func() ([]director.Release, error) { return releases, nil },
 				StemcellsStub:     func() ([]director.Stemcell, error) { return stemcells, nil },
+				SnapshotsStub:     func() ([]director.Snapshot, error) { return snapshots, nil },
 			}
 
 			deployments = []director.Deployment{deployment}
@@ -191,7 +296,37 @@ var _ = Describe("Fetcher", func() {
 
 			expectedDeploymentsInfo = []DeploymentInfo{
 				DeploymentInfo{
-					Name: deploymentName,
+					Name:         deploymentName,
+					Teams:        []string{deploymentTeam},
+					ManifestSHA1: fmt.Sprintf("%x", sha1.Sum([]byte(deploymentManifest))),
+					InstanceGroups: []InstanceGroup{
+						InstanceGroup{
+							Name:      jobName,
+							Instances: 4,
+							Update: Update{
+								Canaries:        1,
+								MaxInFlight:     2,
+								CanaryWatchTime: 30 * time.Second,
+								UpdateWatchTime: 5 * time.Second,
+							},
+							Stemcell:           Stemcell{Name: stemcellName, Version: stemcellVersion, OSName: stemcellOSName},
+							VMType:             "fake-vm-type",
+							PersistentDiskType: "fake-disk-type",
+							Networks:           []string{"fake-network", "fake-other-network"},
+						},
+						InstanceGroup{
+							Name:      "fake-other-job-name",
+							Instances: 2,
+							Update: Update{
+								Canaries:        2,
+								MaxInFlight:     1,
+								CanaryWatchTime: 30 * time.Second,
+								UpdateWatchTime: 60 * time.Second,
+							},
+							Stemcell: Stemcell{Name: stemcellName, Version: stemcellVersion, OSName: stemcellOSName},
+							Networks: []string{},
+						},
+					},
 					Instances: []Instance{
 						Instance{
 							AgentID:            agentID,
@@ -200,16 +335,20 @@ var _ = Describe("Fetcher", func() {
 							Index:              strconv.Itoa(int(jobIndex)),
 							Bootstrap:          jobBootstrap,
 							IPs:                []string{jobIP},
+							DNS:                []string{jobDNS},
 							AZ:                 jobAZ,
 							VMType:             jobVMType,
 							ResourcePool:       jobResourcePool,
 							ResurrectionPaused: jobResurrectionPause,
+							DiskCIDs:           []string{jobDiskCID},
 							Healthy:            true,
+							State:              processState,
 							Processes: []Process{
 								Process{
 									Name:    jobProcessName,
 									Uptime:  &jobProcessUptimeSeconds,
 									Healthy: true,
+									State:   jobProcessState,
 									CPU:     CPU{Total: &jobProcessCPUTotal},
 									Mem:     MemInt{KB: &jobProcessMemKB, Percent: &jobProcessMemPercent},
 								},
@@ -249,12 +388,20 @@ var _ = Describe("Fetcher", func() {
 							},
 						},
 					},
+					ActualInstances: map[string]int{jobName: 1},
 					Releases: []Release{
-						Release{Name: releaseName, Version: releaseVersion},
+						Release{Name: releaseName, Version: releaseVersion, LatestVersion: latestReleaseVersion, Outdated: true},
 					},
 					Stemcells: []Stemcell{
 						Stemcell{Name: stemcellName, Version: stemcellVersion, OSName: stemcellOSName},
 					},
+					Tasks: []Task{
+						Task{ID: taskID, Description: taskDescription, State: taskState, StartedAt: taskStartedAt, LastActivityAt: taskLastActivityAt},
+						Task{ID: taskID - 1, Description: taskDescription, State: recentTaskState, StartedAt: taskStartedAt, LastActivityAt: taskLastActivityAt},
+					},
+					Snapshots: []Snapshot{
+						Snapshot{JobName: jobName, JobIndex: strconv.Itoa(int(jobIndex)), CID: snapshotCID, CreatedAt: snapshotCreatedAt, Clean: true},
+					},
 				},
 			}
 		})
@@ -286,6 +433,165 @@ var _ = Describe("Fetcher", func() {
 			})
 		})
 
+		Context("when an instance is reported twice while being recreated", func() {
+			BeforeEach(func() {
+				oldInstance := instances[0]
+				oldInstance.VMID = "fake-old-job-vmid"
+				oldInstance.ProcessState = "unresponsive agent"
+				instances = []director.VMInfo{oldInstance, instances[0]}
+			})
+
+			It("returns a single instance, keeping the healthy one", func() {
+				Expect(deploymentsInfo[0].Instances).To(Equal(expectedDeploymentsInfo[0].Instances))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when deployment does not belong to a filtered team", func() {
+			BeforeEach(func() {
+				teamsFilters = []string{"fake-team"}
+			})
+
+			It("does not return the deployment", func() {
+				Expect(deploymentsInfo).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("counts the filtered deployment", func() {
+				Expect(deploymentsFetcher.FilteredDeployments()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when deployment belongs to another shard", func() {
+			BeforeEach(func() {
+				shardIndex = 1
+				shardCount = 2
+			})
+
+			It("does not return the deployment", func() {
+				Expect(deploymentsInfo).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("counts the filtered deployment", func() {
+				Expect(deploymentsFetcher.FilteredDeployments()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when deployment belongs to the shard", func() {
+			BeforeEach(func() {
+				shardIndex = 0
+				shardCount = 2
+			})
+
+			It("returns the deployment", func() {
+				Expect(deploymentsInfo).To(Equal(expectedDeploymentsInfo))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when instance job is filtered", func() {
+			BeforeEach(func() {
+				jobsFilters = []string{"!^" + jobName + "$"}
+			})
+
+			It("does not return the instance", func() {
+				Expect(deploymentsInfo[0].Instances).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("counts the instance in the actual instances", func() {
+				Expect(deploymentsInfo[0].ActualInstances).To(Equal(map[string]int{jobName: 1}))
+			})
+
+			It("counts the filtered instance", func() {
+				Expect(deploymentsFetcher.FilteredInstances()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when instance AZ is filtered", func() {
+			BeforeEach(func() {
+				azsFilters = []string{"!" + jobAZ}
+			})
+
+			It("does not return the instance", func() {
+				Expect(deploymentsInfo[0].Instances).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("counts the instance in the actual instances", func() {
+				Expect(deploymentsInfo[0].ActualInstances).To(Equal(map[string]int{jobName: 1}))
+			})
+
+			It("counts the filtered instance", func() {
+				Expect(deploymentsFetcher.FilteredInstances()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when the deployment uses the latest release version", func() {
+			BeforeEach(func() {
+				boshClient.ReleasesReturns([]director.Release{release}, nil)
+			})
+
+			It("does not return the release as outdated", func() {
+				Expect(deploymentsInfo[0].Releases).To(Equal([]Release{
+					Release{Name: releaseName, Version: releaseVersion, LatestVersion: releaseVersion, Outdated: false},
+				}))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when it fails to get the director releases", func() {
+			BeforeEach(func() {
+				boshClient.ReleasesReturns(nil, errors.New("no releases"))
+			})
+
+			It("returns the deployments without their releases", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Releases).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Releases: true}))
+			})
+		})
+
+		Context("when there are no tasks", func() {
+			BeforeEach(func() {
+				boshClient.CurrentTasksReturns([]director.Task{}, nil)
+				boshClient.RecentTasksReturns([]director.Task{}, nil)
+			})
+
+			It("does not return tasks", func() {
+				Expect(deploymentsInfo[0].Tasks).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when it fails to get the current tasks", func() {
+			BeforeEach(func() {
+				boshClient.CurrentTasksReturns(nil, errors.New("no tasks"))
+			})
+
+			It("returns the deployments without their tasks", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Tasks).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Tasks: true}))
+			})
+		})
+
+		Context("when it fails to get the recent tasks", func() {
+			BeforeEach(func() {
+				boshClient.RecentTasksReturns(nil, errors.New("no tasks"))
+			})
+
+			It("returns the deployments without their tasks", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Tasks).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Tasks: true}))
+			})
+		})
+
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
 				boshClient.DeploymentsReturns([]director.Deployment{}, nil)
@@ -308,6 +614,146 @@ var _ = Describe("Fetcher", func() {
 			})
 		})
 
+		Context("when there are no teams", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:          func() string { return deploymentName },
+					InstanceInfosStub: func() ([]director.VMInfo, error) { return instances, nil },
+					ReleasesStub:      func() ([]director.Release, error) { return releases, nil },
+					StemcellsStub:     func() ([]director.Stemcell, error) { return stemcells, nil },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("does not return teams", func() {
+				Expect(deploymentsInfo[0].Teams).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when it fails to get the deployment teams", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:  func() string { return deploymentName },
+					TeamsStub: func() ([]string, error) { return nil, errors.New("no teams") },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("returns the deployments without their teams", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Teams).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Teams: true}))
+			})
+		})
+
+		Context("when the manifest has no instance groups", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:          func() string { return deploymentName },
+					InstanceInfosStub: func() ([]director.VMInfo, error) { return instances, nil },
+					ReleasesStub:      func() ([]director.Release, error) { return releases, nil },
+					StemcellsStub:     func() ([]director.Stemcell, error) { return stemcells, nil },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("does not return instance groups", func() {
+				Expect(deploymentsInfo[0].InstanceGroups).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the manifest is a v1 manifest", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return deploymentName },
+					ManifestStub: func() (string, error) {
+						return "jobs:\n- name: fake-job-name\n  instances: 1\n  update:\n    canaries: 1\n", nil
+					},
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("returns the jobs as instance groups", func() {
+				Expect(deploymentsInfo[0].InstanceGroups).To(Equal([]InstanceGroup{
+					InstanceGroup{Name: jobName, Instances: 1, Update: Update{Canaries: 1, MaxInFlight: 1}, Networks: []string{}},
+				}))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when it fails to get the deployment manifest", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:     func() string { return deploymentName },
+					ManifestStub: func() (string, error) { return "", errors.New("no manifest") },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("does not return deployments", func() {
+				Expect(deploymentsInfo).To(BeEmpty())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the deployment manifest has invalid update settings", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return deploymentName },
+					ManifestStub: func() (string, error) {
+						return "update:\n  max_in_flight: many\ninstance_groups:\n- name: fake-job-name\n", nil
+					},
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("skips the instance groups", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].InstanceGroups).To(BeEmpty())
+			})
+		})
+
+		Context("when the deployment manifest has variables", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return deploymentName },
+					ManifestStub: func() (string, error) {
+						return `instance_groups:
+- name: fake-job-name
+  instances: ((fake_job_instances))
+- name: fake-canaries-job-name
+  instances: 2
+  update:
+    canaries: ((fake_canaries))
+- name: fake-other-job-name
+  instances: "3"
+  update:
+    canaries: 1
+`, nil
+					},
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("skips the instance groups depending on a variable", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo[0].InstanceGroups).To(Equal([]InstanceGroup{
+					InstanceGroup{Name: "fake-other-job-name", Instances: 3, Update: Update{Canaries: 1, MaxInFlight: 1}, Networks: []string{}},
+				}))
+			})
+		})
+
 		Context("when there are no instances", func() {
 			BeforeEach(func() {
 				deployment = &directorfakes.FakeDeployment{
@@ -368,9 +814,65 @@ var _ = Describe("Fetcher", func() {
 				boshClient.DeploymentsReturns(deployments, nil)
 			})
 
-			It("does not return deployments", func() {
-				Expect(deploymentsInfo).To(BeEmpty())
-				Expect(err).To(HaveOccurred())
+			It("returns the deployments without their releases", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Releases).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Releases: true}))
+			})
+		})
+
+		Context("when the deployment uses several stemcells", func() {
+			BeforeEach(func() {
+				fakeStemcell := func(name string, stemcellVersion string, osName string) director.Stemcell {
+					return &directorfakes.FakeStemcell{
+						NameStub:    func() string { return name },
+						VersionStub: func() version.Version { return version.MustNewVersionFromString(stemcellVersion) },
+						OSNameStub:  func() string { return osName },
+					}
+				}
+
+				deployment = &directorfakes.FakeDeployment{
+					NameStub: func() string { return deploymentName },
+					ManifestStub: func() (string, error) {
+						return `---
+stemcells:
+- alias: xenial
+  os: ubuntu-xenial
+  version: latest
+- alias: windows
+  name: bosh-fake-windows-stemcell
+  version: "1.2"
+instance_groups:
+- name: fake-job-name
+  instances: 1
+  stemcell: xenial
+- name: fake-windows-job-name
+  instances: 1
+  stemcell: windows
+- name: fake-other-job-name
+  instances: 1
+  stemcell: unknown
+`, nil
+					},
+					StemcellsStub: func() ([]director.Stemcell, error) {
+						return []director.Stemcell{
+							fakeStemcell("bosh-fake-windows-stemcell", "1.1", "windows2016"),
+							fakeStemcell("bosh-fake-windows-stemcell", "1.2", "windows2016"),
+							fakeStemcell("bosh-fake-xenial-stemcell", "3.4", "ubuntu-xenial"),
+						}, nil
+					},
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("returns the stemcell of each instance group", func() {
+				Expect(deploymentsInfo[0].InstanceGroups).To(HaveLen(3))
+				Expect(deploymentsInfo[0].InstanceGroups[0].Stemcell).To(Equal(Stemcell{Name: "bosh-fake-xenial-stemcell", Version: "3.4", OSName: "ubuntu-xenial"}))
+				Expect(deploymentsInfo[0].InstanceGroups[1].Stemcell).To(Equal(Stemcell{Name: "bosh-fake-windows-stemcell", Version: "1.2", OSName: "windows2016"}))
+				Expect(deploymentsInfo[0].InstanceGroups[2].Stemcell).To(Equal(Stemcell{}))
+				Expect(err).ToNot(HaveOccurred())
 			})
 		})
 
@@ -401,9 +903,419 @@ var _ = Describe("Fetcher", func() {
 				boshClient.DeploymentsReturns(deployments, nil)
 			})
 
-			It("does not return deployments", func() {
-				Expect(deploymentsInfo).To(BeEmpty())
-				Expect(err).To(HaveOccurred())
+			It("returns the deployments without their stemcells", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Stemcells).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Stemcells: true}))
+			})
+		})
+
+		Context("when there are no snapshots", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:          func() string { return deploymentName },
+					InstanceInfosStub: func() ([]director.VMInfo, error) { return instances, nil },
+					ReleasesStub:      func() ([]director.Release, error) { return releases, nil },
+					StemcellsStub:     func() ([]director.Stemcell, error) { return stemcells, nil },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("does not return snapshots", func() {
+				Expect(deploymentsInfo[0].Snapshots).To(BeEmpty())
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when it fails to get the deployment snapshots", func() {
+			BeforeEach(func() {
+				deployment = &directorfakes.FakeDeployment{
+					NameStub:      func() string { return deploymentName },
+					SnapshotsStub: func() ([]director.Snapshot, error) { return nil, errors.New("no snapshots") },
+				}
+				deployments = []director.Deployment{deployment}
+				boshClient.DeploymentsReturns(deployments, nil)
+			})
+
+			It("returns the deployments without their snapshots", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Snapshots).To(BeEmpty())
+				Expect(deploymentsInfo[0].UnavailableDetails).To(Equal(Details{Snapshots: true}))
+			})
+		})
+	})
+
+	Context("when the optional filters are nil", func() {
+		JustBeforeEach(func() {
+			deploymentsFetcher = NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil)
+		})
+
+		It("does not filter anything", func() {
+			Expect(deploymentsFetcher.FilteredDeployments()).To(BeZero())
+			Expect(deploymentsFetcher.FilteredInstances()).To(BeZero())
+		})
+
+		It("fetches the deployments", func() {
+			_, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("SetRefreshIntervals", func() {
+		var (
+			deploymentsRefreshInterval time.Duration
+			instancesRefreshInterval   time.Duration
+			fakeDeployment             *directorfakes.FakeDeployment
+		)
+
+		BeforeEach(func() {
+			deploymentsRefreshInterval = time.Hour
+			instancesRefreshInterval = time.Hour
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+			boshDeployments, _ := boshClient.Deployments()
+			fakeDeployment = boshDeployments[0].(*directorfakes.FakeDeployment)
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetRefreshIntervals(deploymentsRefreshInterval, instancesRefreshInterval)
+
+			for i := 0; i < 2; i++ {
+				deploymentsInfo, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo).To(HaveLen(1))
+				Expect(deploymentsInfo[0].Instances).To(HaveLen(1))
+			}
+		})
+
+		It("caches the deployments and their instances", func() {
+			Expect(boshClient.DeploymentsCallCount()).To(Equal(2))
+			Expect(fakeDeployment.ManifestCallCount()).To(Equal(1))
+			Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(1))
+		})
+
+		Context("when the deployments refresh interval is 0", func() {
+			BeforeEach(func() {
+				deploymentsRefreshInterval = 0
+			})
+
+			It("fetches the deployments on every call but caches their instances", func() {
+				Expect(boshClient.DeploymentsCallCount()).To(Equal(3))
+				Expect(fakeDeployment.ManifestCallCount()).To(Equal(2))
+				Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(1))
+			})
+
+			It("parses the manifest again when it changed", func() {
+				fakeDeployment.ManifestStub = func() (string, error) {
+					return "instance_groups:\n- name: new-fake-job-name\n  instances: 2\n", nil
+				}
+
+				deploymentsInfo, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo[0].InstanceGroups).To(HaveLen(1))
+				Expect(deploymentsInfo[0].InstanceGroups[0].Name).To(Equal("new-fake-job-name"))
+				Expect(deploymentsInfo[0].InstanceGroups[0].Instances).To(Equal(2))
+			})
+		})
+
+		Context("when the instances refresh interval is 0", func() {
+			BeforeEach(func() {
+				instancesRefreshInterval = 0
+			})
+
+			It("caches the deployments but fetches their instances on every call", func() {
+				Expect(boshClient.DeploymentsCallCount()).To(Equal(2))
+				Expect(fakeDeployment.ManifestCallCount()).To(Equal(1))
+				Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(2))
+			})
+
+			Context("and a cached deployment has been deleted", func() {
+				It("fetches the deployments again", func() {
+					boshClient.DeploymentsReturns([]director.Deployment{}, nil)
+					fakeDeployment.InstanceInfosStub = func() ([]director.VMInfo, error) {
+						return nil, errors.New("Deployment 'fake-deployment-name' doesn't exist")
+					}
+
+					deploymentsInfo, err := deploymentsFetcher.Deployments()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(deploymentsInfo).To(BeEmpty())
+					Expect(boshClient.DeploymentsCallCount()).To(Equal(3))
+				})
+			})
+		})
+
+		Context("when a single deployment is fetched", func() {
+			It("fetches its instances again", func() {
+				_, err := deploymentsFetcher.Deployment("fake-deployment-name")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("SetFetchObserver", func() {
+		var (
+			observedDeployments []string
+		)
+
+		BeforeEach(func() {
+			observedDeployments = []string{}
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetFetchObserver(func(deploymentName string, duration time.Duration) {
+				Expect(duration).To(BeNumerically(">", 0))
+				observedDeployments = append(observedDeployments, deploymentName)
+			})
+		})
+
+		It("observes the fetched deployments", func() {
+			_, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(observedDeployments).To(Equal([]string{"fake-deployment-name"}))
+
+			_, err = deploymentsFetcher.Deployment("fake-deployment-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(observedDeployments).To(Equal([]string{"fake-deployment-name", "fake-deployment-name"}))
+		})
+	})
+
+	Describe("SetDirectorCompatibility", func() {
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetDirectorCompatibility(NewDirectorCompatibility(director.Info{Version: "257.3.0 (00000000)"}))
+		})
+
+		It("reads the instances from the VMs endpoint of old directors", func() {
+			boshDeployments, _ := boshClient.Deployments()
+			fakeDeployment := boshDeployments[0].(*directorfakes.FakeDeployment)
+
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].Instances).To(HaveLen(1))
+			Expect(fakeDeployment.VMInfosCallCount()).To(Equal(1))
+			Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("SetInstancesBackend", func() {
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetInstancesBackend(InstancesBackendFunc(func(deployment director.Deployment) ([]director.VMInfo, error) {
+				return []director.VMInfo{
+					director.VMInfo{JobName: "backend-job-name", ID: "backend-instance-id", VMID: "backend-vm-cid"},
+				}, nil
+			}))
+		})
+
+		It("reads the instances from the backend", func() {
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].Instances).To(HaveLen(1))
+			Expect(deploymentsInfo[0].Instances[0].Name).To(Equal("backend-job-name"))
+		})
+	})
+
+	Describe("SetDetails", func() {
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetDetails(Details{})
+		})
+
+		It("does not read the releases", func() {
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].Releases).To(BeEmpty())
+			Expect(deploymentsInfo[0].UnavailableDetails.Releases).To(BeTrue())
+			Expect(boshClient.ReleasesCallCount()).To(BeZero())
+		})
+
+		It("does not read the tasks", func() {
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].UnavailableDetails.Tasks).To(BeTrue())
+			Expect(boshClient.CurrentTasksCallCount()).To(BeZero())
+			Expect(boshClient.RecentTasksCallCount()).To(BeZero())
+		})
+
+		It("does not read the teams, the stemcells and the snapshots", func() {
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].UnavailableDetails.Teams).To(BeTrue())
+			Expect(deploymentsInfo[0].UnavailableDetails.Stemcells).To(BeTrue())
+			Expect(deploymentsInfo[0].UnavailableDetails.Snapshots).To(BeTrue())
+
+			deployment, err := boshClient.FindDeployment("fake-deployment-name")
+			Expect(err).ToNot(HaveOccurred())
+			fakeDeployment := deployment.(*directorfakes.FakeDeployment)
+			Expect(fakeDeployment.TeamsCallCount()).To(BeZero())
+			Expect(fakeDeployment.StemcellsCallCount()).To(BeZero())
+			Expect(fakeDeployment.SnapshotsCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("SetRecentTasksLimit", func() {
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetRecentTasksLimit(10)
+		})
+
+		It("reads the given number of recent tasks", func() {
+			_, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(boshClient.RecentTasksCallCount()).To(Equal(1))
+			limit, _ := boshClient.RecentTasksArgsForCall(0)
+			Expect(limit).To(Equal(10))
+		})
+	})
+
+	Describe("SetFetchErrorObserver", func() {
+		var (
+			observedDeploymentsNames []string
+			observedErrors           []error
+		)
+
+		BeforeEach(func() {
+			observedDeploymentsNames = []string{}
+			observedErrors = []error{}
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+			boshClient.ReleasesReturns(nil, errors.New("no releases"))
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetFetchErrorObserver(func(deploymentName string, err error) {
+				observedDeploymentsNames = append(observedDeploymentsNames, deploymentName)
+				observedErrors = append(observedErrors, err)
+			})
+		})
+
+		It("notifies the errors reading the deployments details", func() {
+			_, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(observedDeploymentsNames).To(Equal([]string{""}))
+			Expect(observedErrors).To(HaveLen(1))
+			Expect(observedErrors[0].Error()).To(ContainSubstring("Error while reading Releases: no releases"))
+		})
+	})
+
+	Describe("SetInstancesTimeout", func() {
+		var (
+			fakeDeployment *directorfakes.FakeDeployment
+			unblock        chan bool
+		)
+
+		BeforeEach(func() {
+			boshClient = fakes.NewFakeDirector(fakes.DeploymentInfo("fake-deployment-name", fakes.Instance("fake-job-name", 0)))
+			boshDeployments, _ := boshClient.Deployments()
+			fakeDeployment = boshDeployments[0].(*directorfakes.FakeDeployment)
+			fakeDeployment.InstancesStub = func() ([]director.Instance, error) {
+				return []director.Instance{
+					director.Instance{ID: "short-instance-id", Group: "short-job-name", VMID: "short-vm-cid", AZ: "short-az", IPs: []string{"1.2.3.4"}},
+				}, nil
+			}
+			unblock = make(chan bool)
+		})
+
+		JustBeforeEach(func() {
+			deploymentsFetcher.SetInstancesTimeout(50 * time.Millisecond)
+		})
+
+		AfterEach(func() {
+			close(unblock)
+		})
+
+		It("reads the instances in full format when they are read in time", func() {
+			deploymentsInfo, err := deploymentsFetcher.Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo[0].InstancesShortFormat).To(BeFalse())
+			Expect(deploymentsInfo[0].Instances[0].Name).To(Equal("fake-job-name"))
+			Expect(fakeDeployment.InstancesCallCount()).To(BeZero())
+		})
+
+		Context("when reading the instances in full format times out", func() {
+			BeforeEach(func() {
+				instanceInfos := fakeDeployment.InstanceInfosStub
+				unblock := unblock
+				fakeDeployment.InstanceInfosStub = func() ([]director.VMInfo, error) {
+					<-unblock
+					return instanceInfos()
+				}
+			})
+
+			It("reads the instances in short format", func() {
+				deploymentsInfo, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo[0].InstancesShortFormat).To(BeTrue())
+				Expect(deploymentsInfo[0].Instances).To(Equal([]Instance{
+					Instance{
+						Name:      "short-job-name",
+						ID:        "short-instance-id",
+						IPs:       []string{"1.2.3.4"},
+						AZ:        "short-az",
+						DiskCIDs:  []string{},
+						Processes: []Process{},
+					},
+				}))
+			})
+
+			It("does not cache the instances read in short format", func() {
+				deploymentsFetcher.SetRefreshIntervals(time.Hour, time.Hour)
+
+				_, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeDeployment.InstancesCallCount()).To(Equal(2))
+			})
+
+			It("does not read the instances in full format again while the timed out read is in flight", func() {
+				_, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(1))
+			})
+
+			It("uses the result of the timed out read once it completes", func() {
+				_, err := deploymentsFetcher.Deployments()
+				Expect(err).ToNot(HaveOccurred())
+
+				unblock <- true
+				Eventually(func() bool {
+					deploymentsInfo, err := deploymentsFetcher.Deployments()
+					Expect(err).ToNot(HaveOccurred())
+					return deploymentsInfo[0].InstancesShortFormat
+				}).Should(BeFalse())
+				Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(1))
+			})
+
+			Context("and reading them in short format fails", func() {
+				BeforeEach(func() {
+					fakeDeployment.InstancesStub = func() ([]director.Instance, error) {
+						return nil, errors.New("no instances")
+					}
+				})
+
+				It("returns an error", func() {
+					_, err := deploymentsFetcher.Deployments()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Error while reading Instances in short format for deployment `fake-deployment-name`"))
+				})
 			})
 		})
 	})
@@ -0,0 +1,73 @@
+package deployments
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+)
+
+const (
+	// MinSupportedDirectorVersion is the first BOSH Director version with
+	// cloud configs, required by the exporter.
+	MinSupportedDirectorVersion = 255
+
+	// instancesEndpointDirectorVersion is the first BOSH Director version
+	// exposing the deployments instances endpoint.
+	instancesEndpointDirectorVersion = 258
+
+	// cpiConfigsDirectorVersion is the first BOSH Director version with CPI
+	// configs.
+	cpiConfigsDirectorVersion = 261
+)
+
+// DirectorCompatibility describes the BOSH Director API features available
+// for a Director version. Its zero value describes a recent Director.
+type DirectorCompatibility struct {
+	Version string
+	// Unsupported is set for Directors older than MinSupportedDirectorVersion.
+	Unsupported bool
+	// VMsEndpoint is set for Directors without the instances endpoint, whose
+	// instances are read from the VMs endpoint instead.
+	VMsEndpoint bool
+	// NoCPIConfigs is set for Directors without CPI configs.
+	NoCPIConfigs bool
+}
+
+// NewDirectorCompatibility detects the features available from the Director
+// version (i.e. `262.3.0 (00000269)`, or `1.3262.0.0 (00000269)` for the old
+// versioning scheme). Unknown versions are assumed to be recent.
+func NewDirectorCompatibility(info director.Info) DirectorCompatibility {
+	compatibility := DirectorCompatibility{Version: info.Version}
+
+	major, ok := directorMajorVersion(info.Version)
+	if !ok {
+		return compatibility
+	}
+
+	compatibility.Unsupported = major < MinSupportedDirectorVersion
+	compatibility.VMsEndpoint = major < instancesEndpointDirectorVersion
+	compatibility.NoCPIConfigs = major < cpiConfigsDirectorVersion
+
+	return compatibility
+}
+
+func directorMajorVersion(version string) (int, bool) {
+	version = strings.SplitN(strings.TrimSpace(version), " ", 2)[0]
+	parts := strings.Split(version, ".")
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+
+	if major == 1 && len(parts) > 1 {
+		minor, err := strconv.Atoi(parts[1])
+		if err != nil || minor < 3000 {
+			return 0, false
+		}
+		return minor - 3000, true
+	}
+
+	return major, true
+}
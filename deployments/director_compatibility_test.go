@@ -0,0 +1,47 @@
+package deployments_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+var _ = Describe("DirectorCompatibility", func() {
+	compatibility := func(version string) DirectorCompatibility {
+		return NewDirectorCompatibility(director.Info{Version: version})
+	}
+
+	It("supports recent directors", func() {
+		Expect(compatibility("270.2.0 (00000000)")).To(Equal(DirectorCompatibility{Version: "270.2.0 (00000000)"}))
+	})
+
+	It("does not read CPI configs from directors without them", func() {
+		Expect(compatibility("260.0.0 (00000000)")).To(Equal(DirectorCompatibility{
+			Version:      "260.0.0 (00000000)",
+			NoCPIConfigs: true,
+		}))
+	})
+
+	It("reads the instances from the VMs endpoint of directors without the instances endpoint", func() {
+		Expect(compatibility("257.3.0 (00000000)")).To(Equal(DirectorCompatibility{
+			Version:      "257.3.0 (00000000)",
+			VMsEndpoint:  true,
+			NoCPIConfigs: true,
+		}))
+	})
+
+	It("does not support directors without cloud configs", func() {
+		Expect(compatibility("1.3215.0 (00000000)").Unsupported).To(BeTrue())
+	})
+
+	It("understands the old versioning scheme", func() {
+		Expect(compatibility("1.3262.0.0 (00000000)")).To(Equal(DirectorCompatibility{Version: "1.3262.0.0 (00000000)"}))
+	})
+
+	It("assumes unknown versions are recent", func() {
+		Expect(compatibility("fake-version")).To(Equal(DirectorCompatibility{Version: "fake-version"}))
+	})
+})
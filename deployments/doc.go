@@ -0,0 +1,3 @@
+// Package deployments fetches the BOSH deployments, instances, releases,
+// stemcells and tasks collected by the exporter.
+package deployments
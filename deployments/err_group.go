@@ -0,0 +1,35 @@
+package deployments
+
+import (
+	"sync"
+)
+
+// errGroup runs functions concurrently and waits for all of them to return,
+// keeping the first error, like golang.org/x/sync/errgroup: no function is
+// left blocked reporting its error once another one failed.
+type errGroup struct {
+	wg    sync.WaitGroup
+	mutex sync.Mutex
+	err   error
+}
+
+func (g *errGroup) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		if err := f(); err != nil {
+			g.mutex.Lock()
+			if g.err == nil {
+				g.err = err
+			}
+			g.mutex.Unlock()
+		}
+	}()
+}
+
+// Wait waits for all the functions to return, and returns the first error.
+func (g *errGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
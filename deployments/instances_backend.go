@@ -0,0 +1,75 @@
+package deployments
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+)
+
+const (
+	InstancesEndpoint = "instances"
+	VMsEndpoint       = "vms"
+)
+
+// InstancesBackend reads the instances of a BOSH deployment, with their
+// vitals and processes.
+type InstancesBackend interface {
+	Instances(deployment director.Deployment) ([]director.VMInfo, error)
+}
+
+// InstancesBackendFunc adapts a function to an InstancesBackend.
+type InstancesBackendFunc func(deployment director.Deployment) ([]director.VMInfo, error)
+
+func (f InstancesBackendFunc) Instances(deployment director.Deployment) ([]director.VMInfo, error) {
+	return f(deployment)
+}
+
+// InstancesEndpointBackend reads the instances from the
+// `/deployments/<name>/instances?format=full` endpoint.
+var InstancesEndpointBackend InstancesBackend = InstancesBackendFunc(func(deployment director.Deployment) ([]director.VMInfo, error) {
+	return deployment.InstanceInfos()
+})
+
+// VMsEndpointBackend reads the instances from the
+// `/deployments/<name>/vms?format=full` endpoint.
+var VMsEndpointBackend InstancesBackend = InstancesBackendFunc(func(deployment director.Deployment) ([]director.VMInfo, error) {
+	return deployment.VMInfos()
+})
+
+// ShortFormatInstancesBackend reads the instances from the
+// `/deployments/<name>/instances` endpoint, which does not run a task: the
+// instances have no state, vitals nor processes.
+var ShortFormatInstancesBackend InstancesBackend = InstancesBackendFunc(func(deployment director.Deployment) ([]director.VMInfo, error) {
+	instances, err := deployment.Instances()
+	if err != nil {
+		return nil, err
+	}
+
+	vmInfos := []director.VMInfo{}
+	for _, instance := range instances {
+		vmInfos = append(vmInfos, director.VMInfo{
+			AgentID: instance.AgentID,
+			VMID:    instance.VMID,
+			ID:      instance.ID,
+			JobName: instance.Group,
+			AZ:      instance.AZ,
+			IPs:     instance.IPs,
+		})
+	}
+
+	return vmInfos, nil
+})
+
+// NewInstancesBackend returns the backend reading the instances from the
+// `instances` or `vms` endpoint.
+func NewInstancesBackend(endpoint string) (InstancesBackend, error) {
+	switch endpoint {
+	case InstancesEndpoint:
+		return InstancesEndpointBackend, nil
+	case VMsEndpoint:
+		return VMsEndpointBackend, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Instances endpoint `%s` is not supported, it must be `%s` or `%s`", endpoint, InstancesEndpoint, VMsEndpoint))
+}
@@ -0,0 +1,48 @@
+package deployments_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+var _ = Describe("InstancesBackend", func() {
+	var (
+		fakeDeployment *directorfakes.FakeDeployment
+	)
+
+	BeforeEach(func() {
+		fakeDeployment = &directorfakes.FakeDeployment{}
+	})
+
+	It("reads the instances from the instances endpoint", func() {
+		backend, err := NewInstancesBackend("instances")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = backend.Instances(fakeDeployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(1))
+		Expect(fakeDeployment.VMInfosCallCount()).To(Equal(0))
+	})
+
+	It("reads the instances from the VMs endpoint", func() {
+		backend, err := NewInstancesBackend("vms")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = backend.Instances(fakeDeployment)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeDeployment.InstanceInfosCallCount()).To(Equal(0))
+		Expect(fakeDeployment.VMInfosCallCount()).To(Equal(1))
+	})
+
+	Context("when the endpoint is not supported", func() {
+		It("returns an error", func() {
+			_, err := NewInstancesBackend("fake-endpoint")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("`fake-endpoint` is not supported"))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+package deployments
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"gopkg.in/yaml.v2"
+)
+
+type manifest struct {
+	Update         manifestUpdate          `yaml:"update"`
+	Stemcells      []manifestStemcell      `yaml:"stemcells"`
+	InstanceGroups []manifestInstanceGroup `yaml:"instance_groups"`
+	Jobs           []manifestInstanceGroup `yaml:"jobs"`
+}
+
+type manifestInstanceGroup struct {
+	Name               string            `yaml:"name"`
+	Instances          interface{}       `yaml:"instances"`
+	Stemcell           string            `yaml:"stemcell"`
+	VMType             string            `yaml:"vm_type"`
+	PersistentDiskType string            `yaml:"persistent_disk_type"`
+	Networks           []manifestNetwork `yaml:"networks"`
+	Update             manifestUpdate    `yaml:"update"`
+}
+
+type manifestNetwork struct {
+	Name string `yaml:"name"`
+}
+
+type manifestStemcell struct {
+	Alias   string `yaml:"alias"`
+	OS      string `yaml:"os"`
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+type manifestUpdate struct {
+	Canaries        interface{} `yaml:"canaries"`
+	MaxInFlight     interface{} `yaml:"max_in_flight"`
+	CanaryWatchTime interface{} `yaml:"canary_watch_time"`
+	UpdateWatchTime interface{} `yaml:"update_watch_time"`
+}
+
+// parseManifestInstanceGroups returns the instance groups of a deployment
+// manifest with their update settings, the instance group `update` block
+// overriding the deployment one, the stemcell their `stemcell` alias refers
+// to and the cloud config definitions they use. Instance groups whose
+// instances or update settings are not numbers (i.e. `((variables))` only
+// resolved by the Director at deploy time) are unknown: they are logged and
+// skipped.
+func parseManifestInstanceGroups(deploymentName string, content string) ([]InstanceGroup, error) {
+	instanceGroups := []InstanceGroup{}
+
+	var m manifest
+	if err := yaml.Unmarshal([]byte(content), &m); err != nil {
+		return instanceGroups, err
+	}
+
+	// v1 manifests use `jobs` instead of `instance_groups`
+	manifestInstanceGroups := m.InstanceGroups
+	if len(manifestInstanceGroups) == 0 {
+		manifestInstanceGroups = m.Jobs
+	}
+
+	for _, manifestInstanceGroup := range manifestInstanceGroups {
+		instances, err := manifestInstances(manifestInstanceGroup.Instances)
+		if err != nil {
+			log.Warnf("Skipping instance group `%s` of deployment `%s`: invalid `instances`: %v", manifestInstanceGroup.Name, deploymentName, err)
+			continue
+		}
+
+		update, err := mergeManifestUpdate(m.Update, manifestInstanceGroup.Update).settings(instances)
+		if err != nil {
+			log.Warnf("Skipping instance group `%s` of deployment `%s`: %v", manifestInstanceGroup.Name, deploymentName, err)
+			continue
+		}
+
+		networks := []string{}
+		for _, network := range manifestInstanceGroup.Networks {
+			networks = append(networks, network.Name)
+		}
+
+		instanceGroups = append(instanceGroups, InstanceGroup{
+			Name:               manifestInstanceGroup.Name,
+			Instances:          instances,
+			Update:             update,
+			Stemcell:           m.stemcell(manifestInstanceGroup.Stemcell),
+			VMType:             manifestInstanceGroup.VMType,
+			PersistentDiskType: manifestInstanceGroup.PersistentDiskType,
+			Networks:           networks,
+		})
+	}
+
+	return instanceGroups, nil
+}
+
+func (m manifest) stemcell(alias string) Stemcell {
+	for _, stemcell := range m.Stemcells {
+		if stemcell.Alias == alias {
+			return Stemcell{Name: stemcell.Name, Version: stemcell.Version, OSName: stemcell.OS}
+		}
+	}
+	return Stemcell{}
+}
+
+func mergeManifestUpdate(deploymentUpdate manifestUpdate, instanceGroupUpdate manifestUpdate) manifestUpdate {
+	update := deploymentUpdate
+	if instanceGroupUpdate.Canaries != nil {
+		update.Canaries = instanceGroupUpdate.Canaries
+	}
+	if instanceGroupUpdate.MaxInFlight != nil {
+		update.MaxInFlight = instanceGroupUpdate.MaxInFlight
+	}
+	if instanceGroupUpdate.CanaryWatchTime != nil {
+		update.CanaryWatchTime = instanceGroupUpdate.CanaryWatchTime
+	}
+	if instanceGroupUpdate.UpdateWatchTime != nil {
+		update.UpdateWatchTime = instanceGroupUpdate.UpdateWatchTime
+	}
+	return update
+}
+
+func (u manifestUpdate) settings(instances int) (Update, error) {
+	var err error
+	update := Update{}
+
+	if update.Canaries, err = manifestCount(u.Canaries, instances); err != nil {
+		return update, errors.New(fmt.Sprintf("invalid `canaries`: %v", err))
+	}
+
+	if update.MaxInFlight, err = manifestCount(u.MaxInFlight, instances); err != nil {
+		return update, errors.New(fmt.Sprintf("invalid `max_in_flight`: %v", err))
+	}
+	// BOSH always updates at least one instance at a time
+	if update.MaxInFlight < 1 {
+		update.MaxInFlight = 1
+	}
+
+	if update.CanaryWatchTime, err = manifestWatchTime(u.CanaryWatchTime); err != nil {
+		return update, errors.New(fmt.Sprintf("invalid `canary_watch_time`: %v", err))
+	}
+
+	if update.UpdateWatchTime, err = manifestWatchTime(u.UpdateWatchTime); err != nil {
+		return update, errors.New(fmt.Sprintf("invalid `update_watch_time`: %v", err))
+	}
+
+	return update, nil
+}
+
+// manifestInstances resolves an `instances` value, an integer.
+func manifestInstances(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	default:
+		return 0, errors.New(fmt.Sprintf("unsupported value `%v`", value))
+	}
+}
+
+// manifestCount resolves a `canaries` or `max_in_flight` value, either an
+// integer or a percentage of the instance group instances.
+func manifestCount(value interface{}, instances int) (int, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if strings.HasSuffix(v, "%") {
+			percent, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+			if err != nil {
+				return 0, err
+			}
+			count := percent * instances / 100
+			if count > instances {
+				count = instances
+			}
+			return count, nil
+		}
+		return strconv.Atoi(v)
+	default:
+		return 0, errors.New(fmt.Sprintf("unsupported value `%v`", value))
+	}
+}
+
+// manifestWatchTime resolves a watch time value, either a number of
+// milliseconds or a `min-max` range of milliseconds, to its upper bound.
+func manifestWatchTime(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return time.Duration(v) * time.Millisecond, nil
+	case string:
+		bounds := strings.Split(v, "-")
+		milliseconds, err := strconv.Atoi(strings.TrimSpace(bounds[len(bounds)-1]))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(milliseconds) * time.Millisecond, nil
+	default:
+		return 0, errors.New(fmt.Sprintf("unsupported value `%v`", value))
+	}
+}
@@ -0,0 +1,118 @@
+package deployments
+
+import (
+	"github.com/cloudfoundry/bosh-cli/director"
+)
+
+// MergedDirector merges the views of several clients of the same BOSH
+// Director, each one authenticated with credentials scoped to a BOSH team:
+// deployments, releases and tasks are merged, and each deployment is read
+// through the client it was listed by. Other requests go through the first
+// client.
+type MergedDirector struct {
+	director.Director
+	directors []director.Director
+}
+
+func NewMergedDirector(directors []director.Director) *MergedDirector {
+	return &MergedDirector{
+		Director:  directors[0],
+		directors: directors,
+	}
+}
+
+func (d *MergedDirector) Deployments() ([]director.Deployment, error) {
+	deployments := []director.Deployment{}
+	names := map[string]bool{}
+
+	for _, boshClient := range d.directors {
+		clientDeployments, err := boshClient.Deployments()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, deployment := range clientDeployments {
+			if names[deployment.Name()] {
+				continue
+			}
+			names[deployment.Name()] = true
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return deployments, nil
+}
+
+// FindDeployment returns the deployment through the first client listing
+// it, or through the first client if none does.
+func (d *MergedDirector) FindDeployment(name string) (director.Deployment, error) {
+	deployments, err := d.Deployments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, deployment := range deployments {
+		if deployment.Name() == name {
+			return deployment, nil
+		}
+	}
+
+	return d.Director.FindDeployment(name)
+}
+
+func (d *MergedDirector) Releases() ([]director.Release, error) {
+	releases := []director.Release{}
+	versions := map[string]bool{}
+
+	for _, boshClient := range d.directors {
+		clientReleases, err := boshClient.Releases()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, release := range clientReleases {
+			key := release.Name() + "/" + release.Version().String()
+			if versions[key] {
+				continue
+			}
+			versions[key] = true
+			releases = append(releases, release)
+		}
+	}
+
+	return releases, nil
+}
+
+func (d *MergedDirector) CurrentTasks(filter director.TasksFilter) ([]director.Task, error) {
+	return d.mergeTasks(func(boshClient director.Director) ([]director.Task, error) {
+		return boshClient.CurrentTasks(filter)
+	})
+}
+
+func (d *MergedDirector) RecentTasks(limit int, filter director.TasksFilter) ([]director.Task, error) {
+	return d.mergeTasks(func(boshClient director.Director) ([]director.Task, error) {
+		return boshClient.RecentTasks(limit, filter)
+	})
+}
+
+func (d *MergedDirector) mergeTasks(tasksFunc func(boshClient director.Director) ([]director.Task, error)) ([]director.Task, error) {
+	tasks := []director.Task{}
+	ids := map[int]bool{}
+
+	for _, boshClient := range d.directors {
+		clientTasks, err := tasksFunc(boshClient)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, task := range clientTasks {
+			if ids[task.ID()] {
+				continue
+			}
+			ids[task.ID()] = true
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,118 @@
+package deployments_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	"github.com/cppforlife/go-semi-semantic/version"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+var _ = Describe("MergedDirector", func() {
+	var (
+		teamADirector  *directorfakes.FakeDirector
+		teamBDirector  *directorfakes.FakeDirector
+		mergedDirector *MergedDirector
+	)
+
+	fakeDeployment := func(name string) *directorfakes.FakeDeployment {
+		return &directorfakes.FakeDeployment{NameStub: func() string { return name }}
+	}
+
+	fakeRelease := func(name string, releaseVersion string) *directorfakes.FakeRelease {
+		return &directorfakes.FakeRelease{
+			NameStub:    func() string { return name },
+			VersionStub: func() version.Version { return version.MustNewVersionFromString(releaseVersion) },
+		}
+	}
+
+	fakeTask := func(id int) *directorfakes.FakeTask {
+		return &directorfakes.FakeTask{IDStub: func() int { return id }}
+	}
+
+	names := func(deployments []director.Deployment) []string {
+		deploymentsNames := []string{}
+		for _, deployment := range deployments {
+			deploymentsNames = append(deploymentsNames, deployment.Name())
+		}
+		return deploymentsNames
+	}
+
+	BeforeEach(func() {
+		teamADirector = &directorfakes.FakeDirector{}
+		teamADirector.DeploymentsReturns([]director.Deployment{fakeDeployment("team-a-deployment"), fakeDeployment("shared-deployment")}, nil)
+		teamBDirector = &directorfakes.FakeDirector{}
+		teamBDirector.DeploymentsReturns([]director.Deployment{fakeDeployment("shared-deployment"), fakeDeployment("team-b-deployment")}, nil)
+
+		mergedDirector = NewMergedDirector([]director.Director{teamADirector, teamBDirector})
+	})
+
+	It("merges the deployments of all clients", func() {
+		deployments, err := mergedDirector.Deployments()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(deployments)).To(Equal([]string{"team-a-deployment", "shared-deployment", "team-b-deployment"}))
+	})
+
+	It("returns an error when a client fails listing its deployments", func() {
+		teamBDirector.DeploymentsReturns(nil, errors.New("fake-error"))
+
+		_, err := mergedDirector.Deployments()
+		Expect(err).To(MatchError("fake-error"))
+	})
+
+	It("finds a deployment through the client listing it", func() {
+		deployment, err := mergedDirector.FindDeployment("team-b-deployment")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Name()).To(Equal("team-b-deployment"))
+		Expect(teamADirector.FindDeploymentCallCount()).To(Equal(0))
+	})
+
+	It("finds an unlisted deployment through the first client", func() {
+		teamADirector.FindDeploymentReturns(fakeDeployment("unknown-deployment"), nil)
+
+		deployment, err := mergedDirector.FindDeployment("unknown-deployment")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deployment.Name()).To(Equal("unknown-deployment"))
+		Expect(teamADirector.FindDeploymentArgsForCall(0)).To(Equal("unknown-deployment"))
+	})
+
+	It("merges the releases of all clients", func() {
+		teamADirector.ReleasesReturns([]director.Release{fakeRelease("fake-release", "1"), fakeRelease("fake-release", "2")}, nil)
+		teamBDirector.ReleasesReturns([]director.Release{fakeRelease("fake-release", "2"), fakeRelease("other-release", "1")}, nil)
+
+		releases, err := mergedDirector.Releases()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(releases).To(HaveLen(3))
+	})
+
+	It("merges the tasks of all clients", func() {
+		teamADirector.CurrentTasksReturns([]director.Task{fakeTask(1), fakeTask(2)}, nil)
+		teamBDirector.CurrentTasksReturns([]director.Task{fakeTask(2), fakeTask(3)}, nil)
+		teamADirector.RecentTasksReturns([]director.Task{fakeTask(4)}, nil)
+		teamBDirector.RecentTasksReturns([]director.Task{fakeTask(5)}, nil)
+
+		currentTasks, err := mergedDirector.CurrentTasks(director.TasksFilter{All: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(currentTasks).To(HaveLen(3))
+
+		recentTasks, err := mergedDirector.RecentTasks(10, director.TasksFilter{All: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recentTasks).To(HaveLen(2))
+		limit, _ := teamBDirector.RecentTasksArgsForCall(0)
+		Expect(limit).To(Equal(10))
+	})
+
+	It("sends other requests through the first client", func() {
+		teamADirector.InfoReturns(director.Info{Name: "fake-director"}, nil)
+
+		info, err := mergedDirector.Info()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Name).To(Equal("fake-director"))
+		Expect(teamBDirector.InfoCallCount()).To(Equal(0))
+	})
+})
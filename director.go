@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	"github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// newDirectorWithHTTPClient returns a BOSH Director client sending its
+// requests through rawClient, with the same authentication, retries and
+// logging as the clients returned by director.Factory, which always creates
+// its own transport (with keep-alives disabled).
+func newDirectorWithHTTPClient(directorConfig director.Config, rawClient *http.Client, logger logger.Logger) (director.Director, error) {
+	if err := directorConfig.Validate(); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error validating BOSH Director connection config: %v", err))
+	}
+
+	directorAddress := net.JoinHostPort(directorConfig.Host, fmt.Sprintf("%d", directorConfig.Port))
+	authAdjustment := director.NewAuthRequestAdjustment(
+		directorConfig.TokenFunc, directorConfig.Client, directorConfig.ClientSecret)
+	rawClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > 10 {
+			return errors.New("Too many redirects")
+		}
+
+		// Redirected requests are not retried, so this is the last chance to
+		// adjust the auth token.
+		if err := authAdjustment.Adjust(req, true); err != nil {
+			return err
+		}
+
+		req.URL.Host = directorAddress
+		req.Header.Del("Referer")
+
+		return nil
+	}
+
+	retryClient := boshhttp.NewNetworkSafeRetryClient(rawClient, 5, 500*time.Millisecond, logger)
+	authedClient := director.NewAdjustableClient(retryClient, authAdjustment)
+	httpClient := httpclient.NewHTTPClientOpts(authedClient, logger, httpclient.Opts{NoRedactUrlQuery: true})
+
+	endpoint := url.URL{Scheme: "https", Host: directorAddress}
+	client := director.NewClient(
+		endpoint.String(),
+		httpClient,
+		director.NewNoopTaskReporter(),
+		director.NewNoopFileReporter(),
+		logger,
+	)
+
+	return wrapDirectorClient(client)
+}
+
+// wrapDirectorClient returns the director.DirectorImpl of the client. Its only
+// field is unexported, so it is set through reflection, once its layout is
+// checked: a bosh-cli upgrade changing it fails at startup instead of
+// corrupting memory.
+func wrapDirectorClient(client director.Client) (director.Director, error) {
+	directorImpl := director.DirectorImpl{}
+
+	value := reflect.ValueOf(&directorImpl).Elem()
+	if value.NumField() != 1 || value.Field(0).Type() != reflect.TypeOf(client) {
+		return nil, errors.New("Error building BOSH Director client: unsupported bosh-cli director.DirectorImpl layout")
+	}
+
+	field := value.Field(0)
+	reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.ValueOf(client))
+
+	return directorImpl, nil
+}
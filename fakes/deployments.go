@@ -0,0 +1,127 @@
+// Package fakes provides test doubles for code embedding the exporter
+// collectors: canned deployments builders, an in-memory BOSH director and a
+// deployments fetcher.
+package fakes
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const (
+	fakeWatchTime = 30 * time.Second
+)
+
+// DeploymentInfo returns a deployment with the given instances, one instance
+// group per instance job name, a release and a stemcell.
+func DeploymentInfo(name string, instances ...deployments.Instance) deployments.DeploymentInfo {
+	if instances == nil {
+		instances = []deployments.Instance{}
+	}
+
+	stemcell := deployments.Stemcell{Name: "bosh-fake-stemcell", Version: "1.0", OSName: "ubuntu-xenial"}
+
+	instanceGroups := []deployments.InstanceGroup{}
+	instanceGroupsIndexes := map[string]int{}
+	actualInstances := map[string]int{}
+	for _, instance := range instances {
+		actualInstances[instance.Name]++
+
+		index, ok := instanceGroupsIndexes[instance.Name]
+		if !ok {
+			index = len(instanceGroups)
+			instanceGroupsIndexes[instance.Name] = index
+			instanceGroups = append(instanceGroups, deployments.InstanceGroup{
+				Name: instance.Name,
+				Update: deployments.Update{
+					Canaries:        1,
+					MaxInFlight:     1,
+					CanaryWatchTime: fakeWatchTime,
+					UpdateWatchTime: fakeWatchTime,
+				},
+				Stemcell: stemcell,
+				Networks: []string{},
+			})
+		}
+		instanceGroups[index].Instances++
+	}
+
+	return deployments.DeploymentInfo{
+		Name:            name,
+		Teams:           []string{},
+		InstanceGroups:  instanceGroups,
+		Instances:       instances,
+		ActualInstances: actualInstances,
+		Releases: []deployments.Release{
+			{Name: "fake-release", Version: "1.0.0", LatestVersion: "1.0.0"},
+		},
+		Stemcells: []deployments.Stemcell{stemcell},
+		Snapshots: []deployments.Snapshot{},
+	}
+}
+
+// Instance returns a running instance of the job with the given processes.
+// The instance is healthy if all its processes are.
+func Instance(jobName string, index int, processes ...deployments.Process) deployments.Instance {
+	if processes == nil {
+		processes = []deployments.Process{}
+	}
+
+	healthy := true
+	for _, process := range processes {
+		if !process.Healthy {
+			healthy = false
+		}
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(jobName))
+	uptime := uint64(3600)
+
+	return deployments.Instance{
+		AgentID:   fmt.Sprintf("fake-agent-id-%s-%d", jobName, index),
+		Name:      jobName,
+		ID:        fmt.Sprintf("fake-id-%s-%d", jobName, index),
+		Index:     strconv.Itoa(index),
+		Bootstrap: index == 0,
+		IPs:       []string{fmt.Sprintf("10.0.%d.%d", hash.Sum32()%250, index+1)},
+		DNS:       []string{},
+		AZ:        "z1",
+		VMType:    "default",
+		DiskCIDs:  []string{},
+		Healthy:   healthy,
+		State:     "running",
+		Processes: processes,
+		Vitals: deployments.Vitals{
+			CPU:            deployments.CPU{Sys: "1.0", User: "2.0", Wait: "0.5"},
+			Mem:            deployments.Mem{KB: "1048576", Percent: "25"},
+			Swap:           deployments.Mem{KB: "0", Percent: "0"},
+			Uptime:         &uptime,
+			Load:           []string{"0.10", "0.20", "0.30"},
+			SystemDisk:     deployments.Disk{InodePercent: "10", Percent: "20"},
+			EphemeralDisk:  deployments.Disk{InodePercent: "5", Percent: "10"},
+			PersistentDisk: deployments.Disk{},
+		},
+	}
+}
+
+// Process returns a running process.
+func Process(name string) deployments.Process {
+	uptime := uint64(3600)
+	cpuTotal := 0.5
+	memKB := uint64(102400)
+	memPercent := 2.5
+
+	return deployments.Process{
+		Name:    name,
+		Uptime:  &uptime,
+		Healthy: true,
+		State:   "running",
+		CPU:     deployments.CPU{Total: &cpuTotal},
+		Mem:     deployments.MemInt{KB: &memKB, Percent: &memPercent},
+	}
+}
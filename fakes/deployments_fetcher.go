@@ -0,0 +1,78 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+// FakeDeploymentsFetcher is an in-memory collectors.DeploymentsFetcher.
+type FakeDeploymentsFetcher struct {
+	mutex               sync.Mutex
+	deploymentsInfo     []deployments.DeploymentInfo
+	err                 error
+	filteredDeployments uint64
+	filteredInstances   uint64
+	deploymentsCalls    int
+}
+
+// NewFakeDeploymentsFetcher returns a fetcher returning the given
+// deployments.
+func NewFakeDeploymentsFetcher(deploymentsInfo ...deployments.DeploymentInfo) *FakeDeploymentsFetcher {
+	fetcher := &FakeDeploymentsFetcher{}
+	fetcher.DeploymentsReturns(deploymentsInfo, nil)
+
+	return fetcher
+}
+
+func (f *FakeDeploymentsFetcher) Deployments() ([]deployments.DeploymentInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.deploymentsCalls++
+
+	return f.deploymentsInfo, f.err
+}
+
+func (f *FakeDeploymentsFetcher) FilteredDeployments() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.filteredDeployments
+}
+
+func (f *FakeDeploymentsFetcher) FilteredInstances() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.filteredInstances
+}
+
+// DeploymentsReturns sets the result of the next Deployments calls.
+func (f *FakeDeploymentsFetcher) DeploymentsReturns(deploymentsInfo []deployments.DeploymentInfo, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if deploymentsInfo == nil {
+		deploymentsInfo = []deployments.DeploymentInfo{}
+	}
+	f.deploymentsInfo = deploymentsInfo
+	f.err = err
+}
+
+// FilteredReturns sets the number of filtered deployments and instances.
+func (f *FakeDeploymentsFetcher) FilteredReturns(filteredDeployments uint64, filteredInstances uint64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.filteredDeployments = filteredDeployments
+	f.filteredInstances = filteredInstances
+}
+
+// DeploymentsCallCount returns the number of Deployments calls.
+func (f *FakeDeploymentsFetcher) DeploymentsCallCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.deploymentsCalls
+}
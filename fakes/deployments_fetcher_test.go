@@ -0,0 +1,49 @@
+package fakes_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/fakes"
+)
+
+var _ = Describe("FakeDeploymentsFetcher", func() {
+	var (
+		deploymentsFetcher *FakeDeploymentsFetcher
+	)
+
+	BeforeEach(func() {
+		deploymentsFetcher = NewFakeDeploymentsFetcher(DeploymentInfo("fake-deployment-name"))
+	})
+
+	It("implements collectors.DeploymentsFetcher", func() {
+		var _ collectors.DeploymentsFetcher = deploymentsFetcher
+	})
+
+	It("returns the deployments", func() {
+		deploymentsInfo, err := deploymentsFetcher.Deployments()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deploymentsInfo).To(Equal([]deployments.DeploymentInfo{DeploymentInfo("fake-deployment-name")}))
+		Expect(deploymentsFetcher.DeploymentsCallCount()).To(Equal(1))
+	})
+
+	It("returns the configured error", func() {
+		deploymentsFetcher.DeploymentsReturns(nil, errors.New("no deployments"))
+
+		deploymentsInfo, err := deploymentsFetcher.Deployments()
+		Expect(err).To(HaveOccurred())
+		Expect(deploymentsInfo).To(BeEmpty())
+	})
+
+	It("returns the filtered counts", func() {
+		deploymentsFetcher.FilteredReturns(2, 3)
+
+		Expect(deploymentsFetcher.FilteredDeployments()).To(Equal(uint64(2)))
+		Expect(deploymentsFetcher.FilteredInstances()).To(Equal(uint64(3)))
+	})
+})
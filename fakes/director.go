@@ -0,0 +1,221 @@
+package fakes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+	"github.com/cppforlife/go-semi-semantic/version"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const (
+	FakeDirectorName = "fake-bosh-name"
+	FakeDirectorUUID = "fake-bosh-uuid"
+)
+
+type fakeManifest struct {
+	Name           string                  `yaml:"name"`
+	InstanceGroups []fakeManifestInstances `yaml:"instance_groups"`
+}
+
+type fakeManifestInstances struct {
+	Name      string             `yaml:"name"`
+	Instances int                `yaml:"instances"`
+	Update    fakeManifestUpdate `yaml:"update"`
+}
+
+type fakeManifestUpdate struct {
+	Canaries        int `yaml:"canaries"`
+	MaxInFlight     int `yaml:"max_in_flight"`
+	CanaryWatchTime int `yaml:"canary_watch_time"`
+	UpdateWatchTime int `yaml:"update_watch_time"`
+}
+
+// NewFakeDirector returns an in-memory BOSH director serving the given
+// deployments, so a deployments.Fetcher built on top of it returns them back
+// (with the ManifestSHA1 of the generated manifest). Releases and stemcells
+// versions must be valid BOSH versions.
+func NewFakeDirector(deploymentsInfo ...deployments.DeploymentInfo) *directorfakes.FakeDirector {
+	boshClient := &directorfakes.FakeDirector{}
+
+	boshClient.InfoReturns(director.Info{
+		Name:    FakeDirectorName,
+		UUID:    FakeDirectorUUID,
+		Version: "262.0.0 (00000000)",
+	}, nil)
+	boshClient.IsAuthenticatedReturns(true, nil)
+
+	boshDeployments := []director.Deployment{}
+	boshReleases := []director.Release{}
+	boshTasks := []director.Task{}
+	for _, deploymentInfo := range deploymentsInfo {
+		boshDeployments = append(boshDeployments, newFakeDeployment(deploymentInfo))
+
+		for _, release := range deploymentInfo.Releases {
+			boshReleases = append(boshReleases, newFakeRelease(release.Name, release.Version))
+			if release.Outdated && release.LatestVersion != release.Version {
+				boshReleases = append(boshReleases, newFakeRelease(release.Name, release.LatestVersion))
+			}
+		}
+
+		for _, task := range deploymentInfo.Tasks {
+			boshTasks = append(boshTasks, newFakeTask(deploymentInfo.Name, task))
+		}
+	}
+
+	boshClient.DeploymentsReturns(boshDeployments, nil)
+	boshClient.FindDeploymentStub = func(name string) (director.Deployment, error) {
+		for _, deployment := range boshDeployments {
+			if deployment.Name() == name {
+				return deployment, nil
+			}
+		}
+		return nil, errors.New(fmt.Sprintf("Deployment `%s` not found", name))
+	}
+	boshClient.ReleasesReturns(boshReleases, nil)
+	boshClient.CurrentTasksReturns(boshTasks, nil)
+	boshClient.RecentTasksReturns([]director.Task{}, nil)
+
+	return boshClient
+}
+
+func newFakeDeployment(deploymentInfo deployments.DeploymentInfo) *directorfakes.FakeDeployment {
+	manifest := fakeManifest{Name: deploymentInfo.Name}
+	for _, instanceGroup := range deploymentInfo.InstanceGroups {
+		manifest.InstanceGroups = append(manifest.InstanceGroups, fakeManifestInstances{
+			Name:      instanceGroup.Name,
+			Instances: instanceGroup.Instances,
+			Update: fakeManifestUpdate{
+				Canaries:        instanceGroup.Update.Canaries,
+				MaxInFlight:     instanceGroup.Update.MaxInFlight,
+				CanaryWatchTime: int(instanceGroup.Update.CanaryWatchTime / time.Millisecond),
+				UpdateWatchTime: int(instanceGroup.Update.UpdateWatchTime / time.Millisecond),
+			},
+		})
+	}
+	manifestContent, _ := yaml.Marshal(manifest)
+
+	vmInfos := []director.VMInfo{}
+	for _, instance := range deploymentInfo.Instances {
+		vmInfos = append(vmInfos, newFakeVMInfo(instance))
+	}
+
+	releases := []director.Release{}
+	for _, release := range deploymentInfo.Releases {
+		releases = append(releases, newFakeRelease(release.Name, release.Version))
+	}
+
+	stemcells := []director.Stemcell{}
+	for _, stemcell := range deploymentInfo.Stemcells {
+		stemcells = append(stemcells, newFakeStemcell(stemcell))
+	}
+
+	snapshots := []director.Snapshot{}
+	for _, snapshot := range deploymentInfo.Snapshots {
+		boshSnapshot := director.Snapshot{
+			Job:       snapshot.JobName,
+			CID:       snapshot.CID,
+			CreatedAt: snapshot.CreatedAt,
+			Clean:     snapshot.Clean,
+		}
+		if index, err := strconv.Atoi(snapshot.JobIndex); err == nil {
+			boshSnapshot.Index = &index
+		}
+		snapshots = append(snapshots, boshSnapshot)
+	}
+
+	teams := deploymentInfo.Teams
+
+	return &directorfakes.FakeDeployment{
+		NameStub:          func() string { return deploymentInfo.Name },
+		TeamsStub:         func() ([]string, error) { return teams, nil },
+		ManifestStub:      func() (string, error) { return string(manifestContent), nil },
+		InstanceInfosStub: func() ([]director.VMInfo, error) { return vmInfos, nil },
+		VMInfosStub:       func() ([]director.VMInfo, error) { return vmInfos, nil },
+		ReleasesStub:      func() ([]director.Release, error) { return releases, nil },
+		StemcellsStub:     func() ([]director.Stemcell, error) { return stemcells, nil },
+		SnapshotsStub:     func() ([]director.Snapshot, error) { return snapshots, nil },
+	}
+}
+
+func newFakeVMInfo(instance deployments.Instance) director.VMInfo {
+	vmInfo := director.VMInfo{
+		AgentID:            instance.AgentID,
+		JobName:            instance.Name,
+		ID:                 instance.ID,
+		ProcessState:       instance.State,
+		Bootstrap:          instance.Bootstrap,
+		IPs:                instance.IPs,
+		DNS:                instance.DNS,
+		AZ:                 instance.AZ,
+		VMID:               "fake-vm-cid-" + instance.ID,
+		VMType:             instance.VMType,
+		ResourcePool:       instance.ResourcePool,
+		ResurrectionPaused: instance.ResurrectionPaused,
+		DiskIDs:            instance.DiskCIDs,
+		Vitals: director.VMInfoVitals{
+			CPU: director.VMInfoVitalsCPU{
+				Sys:  instance.Vitals.CPU.Sys,
+				User: instance.Vitals.CPU.User,
+				Wait: instance.Vitals.CPU.Wait,
+			},
+			Mem:    director.VMInfoVitalsMemSize{KB: instance.Vitals.Mem.KB, Percent: instance.Vitals.Mem.Percent},
+			Swap:   director.VMInfoVitalsMemSize{KB: instance.Vitals.Swap.KB, Percent: instance.Vitals.Swap.Percent},
+			Uptime: director.VMInfoVitalsUptime{Seconds: instance.Vitals.Uptime},
+			Load:   instance.Vitals.Load,
+			Disk: map[string]director.VMInfoVitalsDiskSize{
+				"system":     {InodePercent: instance.Vitals.SystemDisk.InodePercent, Percent: instance.Vitals.SystemDisk.Percent},
+				"ephemeral":  {InodePercent: instance.Vitals.EphemeralDisk.InodePercent, Percent: instance.Vitals.EphemeralDisk.Percent},
+				"persistent": {InodePercent: instance.Vitals.PersistentDisk.InodePercent, Percent: instance.Vitals.PersistentDisk.Percent},
+			},
+		},
+	}
+
+	if index, err := strconv.Atoi(instance.Index); err == nil {
+		vmInfo.Index = &index
+	}
+
+	for _, process := range instance.Processes {
+		vmInfo.Processes = append(vmInfo.Processes, director.VMInfoProcess{
+			Name:   process.Name,
+			State:  process.State,
+			CPU:    director.VMInfoVitalsCPU{Total: process.CPU.Total},
+			Mem:    director.VMInfoVitalsMemIntSize{KB: process.Mem.KB, Percent: process.Mem.Percent},
+			Uptime: director.VMInfoVitalsUptime{Seconds: process.Uptime},
+		})
+	}
+
+	return vmInfo
+}
+
+func newFakeRelease(name string, releaseVersion string) *directorfakes.FakeRelease {
+	return &directorfakes.FakeRelease{
+		NameStub:    func() string { return name },
+		VersionStub: func() version.Version { return version.MustNewVersionFromString(releaseVersion) },
+	}
+}
+
+func newFakeStemcell(stemcell deployments.Stemcell) *directorfakes.FakeStemcell {
+	return &directorfakes.FakeStemcell{
+		NameStub:    func() string { return stemcell.Name },
+		VersionStub: func() version.Version { return version.MustNewVersionFromString(stemcell.Version) },
+		OSNameStub:  func() string { return stemcell.OSName },
+	}
+}
+
+func newFakeTask(deploymentName string, task deployments.Task) *directorfakes.FakeTask {
+	return &directorfakes.FakeTask{
+		IDStub:             func() int { return task.ID },
+		DescriptionStub:    func() string { return task.Description },
+		StateStub:          func() string { return task.State },
+		StartedAtStub:      func() time.Time { return task.StartedAt },
+		LastActivityAtStub: func() time.Time { return task.LastActivityAt },
+		DeploymentNameStub: func() string { return deploymentName },
+	}
+}
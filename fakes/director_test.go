@@ -0,0 +1,102 @@
+package fakes_test
+
+import (
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/fakes"
+)
+
+func init() {
+	flag.Set("log.level", "fatal")
+}
+
+var _ = Describe("FakeDirector", func() {
+	var (
+		deploymentInfo deployments.DeploymentInfo
+	)
+
+	BeforeEach(func() {
+		unhealthyProcess := Process("fake-process-2")
+		unhealthyProcess.Healthy = false
+		unhealthyProcess.State = "failing"
+
+		deploymentInfo = DeploymentInfo(
+			"fake-deployment-name",
+			Instance("fake-job-1", 0, Process("fake-process-1")),
+			Instance("fake-job-1", 1, Process("fake-process-1")),
+			Instance("fake-job-2", 0, unhealthyProcess),
+		)
+	})
+
+	Describe("DeploymentInfo", func() {
+		It("returns one instance group per job", func() {
+			Expect(deploymentInfo.InstanceGroups).To(HaveLen(2))
+			Expect(deploymentInfo.InstanceGroups[0].Name).To(Equal("fake-job-1"))
+			Expect(deploymentInfo.InstanceGroups[0].Instances).To(Equal(2))
+			Expect(deploymentInfo.InstanceGroups[1].Name).To(Equal("fake-job-2"))
+			Expect(deploymentInfo.InstanceGroups[1].Instances).To(Equal(1))
+		})
+
+		It("returns unhealthy instances with unhealthy processes", func() {
+			Expect(deploymentInfo.Instances[0].Healthy).To(BeTrue())
+			Expect(deploymentInfo.Instances[2].Healthy).To(BeFalse())
+		})
+	})
+
+	Describe("NewFakeDirector", func() {
+		It("returns the director info", func() {
+			info, err := NewFakeDirector().Info()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Name).To(Equal(FakeDirectorName))
+			Expect(info.UUID).To(Equal(FakeDirectorUUID))
+		})
+
+		It("finds deployments by name", func() {
+			boshClient := NewFakeDirector(deploymentInfo)
+
+			deployment, err := boshClient.FindDeployment("fake-deployment-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deployment.Name()).To(Equal("fake-deployment-name"))
+
+			_, err = boshClient.FindDeployment("unknown-deployment")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("serves the deployments back through a deployments fetcher", func() {
+			boshClient := NewFakeDirector(deploymentInfo)
+			deploymentsFilter, err := filters.NewDeploymentsFilter([]string{}, boshClient)
+			Expect(err).ToNot(HaveOccurred())
+
+			deploymentsInfo, err := deployments.NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil).Deployments()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(deploymentsInfo).To(HaveLen(1))
+
+			Expect(deploymentsInfo[0].ManifestSHA1).ToNot(BeEmpty())
+			deploymentInfo.ManifestSHA1 = deploymentsInfo[0].ManifestSHA1
+			Expect(deploymentsInfo[0]).To(Equal(deploymentInfo))
+		})
+
+		Context("when a release is outdated", func() {
+			BeforeEach(func() {
+				deploymentInfo.Releases[0].LatestVersion = "2.0.0"
+				deploymentInfo.Releases[0].Outdated = true
+			})
+
+			It("serves the latest release version", func() {
+				boshClient := NewFakeDirector(deploymentInfo)
+				deploymentsFilter, err := filters.NewDeploymentsFilter([]string{}, boshClient)
+				Expect(err).ToNot(HaveOccurred())
+
+				deploymentsInfo, err := deployments.NewFetcher(boshClient, *deploymentsFilter, nil, nil, nil, nil).Deployments()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deploymentsInfo[0].Releases).To(Equal(deploymentInfo.Releases))
+			})
+		})
+	})
+})
@@ -1,27 +1,67 @@
 package filters
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
 type AZsFilter struct {
-	azsEnabled map[string]bool
+	filtered       uint64
+	includeRegexps []*regexp.Regexp
+	excludeRegexps []*regexp.Regexp
 }
 
-func NewAZsFilter(filters []string) *AZsFilter {
-	azsEnabled := make(map[string]bool)
+func NewAZsFilter(filters []string) (*AZsFilter, error) {
+	var includeRegexps, excludeRegexps []*regexp.Regexp
+
+	for _, filter := range filters {
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(filter, "!") + ")$")
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("AZs filter `%s` is not a valid regular expression: %v", filter, err))
+		}
 
-	for _, az := range filters {
-		azsEnabled[az] = true
+		if strings.HasPrefix(filter, "!") {
+			excludeRegexps = append(excludeRegexps, re)
+		} else {
+			includeRegexps = append(includeRegexps, re)
+		}
 	}
 
-	return &AZsFilter{azsEnabled: azsEnabled}
+	return &AZsFilter{includeRegexps: includeRegexps, excludeRegexps: excludeRegexps}, nil
 }
 
 func (f *AZsFilter) Enabled(az string) bool {
-	if len(f.azsEnabled) == 0 {
+	if f.enabled(az) {
 		return true
 	}
 
-	if f.azsEnabled[az] {
+	atomic.AddUint64(&f.filtered, 1)
+	return false
+}
+
+func (f *AZsFilter) Filtered() uint64 {
+	return atomic.LoadUint64(&f.filtered)
+}
+
+func (f *AZsFilter) enabled(az string) bool {
+	for _, re := range f.excludeRegexps {
+		if re.MatchString(az) {
+			return false
+		}
+	}
+
+	if len(f.includeRegexps) == 0 {
 		return true
 	}
 
+	for _, re := range f.includeRegexps {
+		if re.MatchString(az) {
+			return true
+		}
+	}
+
 	return false
 }
@@ -9,6 +9,7 @@ import (
 
 var _ = Describe("AZsFilter", func() {
 	var (
+		err       error
 		filter    []string
 		azsFilter *AZsFilter
 	)
@@ -18,7 +19,26 @@ var _ = Describe("AZsFilter", func() {
 	})
 
 	JustBeforeEach(func() {
-		azsFilter = NewAZsFilter(filter)
+		azsFilter, err = NewAZsFilter(filter)
+	})
+
+	Describe("New", func() {
+		Context("when filters compile", func() {
+			It("does not return an error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when filters does not compile", func() {
+			BeforeEach(func() {
+				filter = []string{"!fake-az-(1"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("AZs filter `!fake-az-(1` is not a valid regular expression"))
+			})
+		})
 	})
 
 	Describe("Enabled", func() {
@@ -32,6 +52,40 @@ var _ = Describe("AZsFilter", func() {
 			It("returns false", func() {
 				Expect(azsFilter.Enabled("fake-az-2")).To(BeFalse())
 			})
+
+			It("counts the filtered az", func() {
+				azsFilter.Enabled("fake-az-1")
+				azsFilter.Enabled("fake-az-2")
+				Expect(azsFilter.Filtered()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when az partially matches a filter", func() {
+			It("returns false", func() {
+				Expect(azsFilter.Enabled("fake-az-10")).To(BeFalse())
+			})
+		})
+
+		Context("when there are regular expression filters", func() {
+			BeforeEach(func() {
+				filter = []string{"fake-az-[12]"}
+			})
+
+			It("returns true when az matches", func() {
+				Expect(azsFilter.Enabled("fake-az-2")).To(BeTrue())
+				Expect(azsFilter.Enabled("fake-az-3")).To(BeFalse())
+			})
+		})
+
+		Context("when there are exclusion filters", func() {
+			BeforeEach(func() {
+				filter = []string{"!z-maintenance"}
+			})
+
+			It("returns false only when az is excluded", func() {
+				Expect(azsFilter.Enabled("z-maintenance")).To(BeFalse())
+				Expect(azsFilter.Enabled("z1")).To(BeTrue())
+			})
 		})
 
 		Context("when there is no filter", func() {
@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+type CIDRsFilter struct {
+	cidrs []*net.IPNet
+}
+
+func NewCIDRsFilter(filters []string) (*CIDRsFilter, error) {
+	cidrs := []*net.IPNet{}
+
+	for _, filter := range filters {
+		_, cidr, err := net.ParseCIDR(filter)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("CIDR filter `%s` is not valid: %v", filter, err))
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	return &CIDRsFilter{cidrs: cidrs}, nil
+}
+
+func (f *CIDRsFilter) Select(ips []string) []string {
+	if len(f.cidrs) == 0 {
+		return ips
+	}
+
+	selectedIPs := []string{}
+	for _, ip := range ips {
+		parsedIP := net.ParseIP(ip)
+		if parsedIP == nil {
+			continue
+		}
+
+		for _, cidr := range f.cidrs {
+			if cidr.Contains(parsedIP) {
+				selectedIPs = append(selectedIPs, ip)
+				break
+			}
+		}
+	}
+
+	return selectedIPs
+}
@@ -0,0 +1,72 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+var _ = Describe("CIDRsFilter", func() {
+	var (
+		err     error
+		filters []string
+
+		cidrsFilter *CIDRsFilter
+	)
+
+	JustBeforeEach(func() {
+		cidrsFilter, err = NewCIDRsFilter(filters)
+	})
+
+	Describe("New", func() {
+		Context("when filters are valid CIDRs", func() {
+			BeforeEach(func() {
+				filters = []string{"10.0.0.0/16", "192.168.1.0/24"}
+			})
+
+			It("does not return an error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when filters are not valid CIDRs", func() {
+			BeforeEach(func() {
+				filters = []string{"10.0.0.0/16", "fake-cidr"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("CIDR filter `fake-cidr` is not valid: invalid CIDR address: fake-cidr"))
+			})
+		})
+	})
+
+	Describe("Select", func() {
+		BeforeEach(func() {
+			filters = []string{"10.0.0.0/16", "192.168.1.0/24"}
+		})
+
+		Context("when there are matching IPs", func() {
+			It("returns only the matching IPs", func() {
+				Expect(cidrsFilter.Select([]string{"172.16.0.1", "192.168.1.10", "10.0.1.2"})).To(Equal([]string{"192.168.1.10", "10.0.1.2"}))
+			})
+		})
+
+		Context("when there are no matching IPs", func() {
+			It("returns an empty list", func() {
+				Expect(cidrsFilter.Select([]string{"172.16.0.1"})).To(BeEmpty())
+			})
+		})
+
+		Context("when there are no filters", func() {
+			BeforeEach(func() {
+				filters = []string{}
+			})
+
+			It("returns all IPs", func() {
+				Expect(cidrsFilter.Select([]string{"172.16.0.1", "10.0.1.2"})).To(Equal([]string{"172.16.0.1", "10.0.1.2"}))
+			})
+		})
+	})
+})
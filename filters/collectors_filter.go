@@ -6,9 +6,14 @@ import (
 )
 
 const (
+	BackupsCollector          = "Backups"
 	DeploymentsCollector      = "Deployments"
+	DirectorCollector         = "Director"
+	ExecCollector             = "Exec"
 	JobsCollector             = "Jobs"
+	NetworksCollector         = "Networks"
 	ServiceDiscoveryCollector = "ServiceDiscovery"
+	SnapshotsCollector        = "Snapshots"
 )
 
 type CollectorsFilter struct {
@@ -20,12 +25,22 @@ func NewCollectorsFilter(filters []string) (*CollectorsFilter, error) {
 
 	for _, collectorName := range filters {
 		switch collectorName {
+		case BackupsCollector:
+			collectorsEnabled[BackupsCollector] = true
 		case DeploymentsCollector:
 			collectorsEnabled[DeploymentsCollector] = true
+		case DirectorCollector:
+			collectorsEnabled[DirectorCollector] = true
+		case ExecCollector:
+			collectorsEnabled[ExecCollector] = true
 		case JobsCollector:
 			collectorsEnabled[JobsCollector] = true
+		case NetworksCollector:
+			collectorsEnabled[NetworksCollector] = true
 		case ServiceDiscoveryCollector:
 			collectorsEnabled[ServiceDiscoveryCollector] = true
+		case SnapshotsCollector:
+			collectorsEnabled[SnapshotsCollector] = true
 		default:
 			return &CollectorsFilter{}, errors.New(fmt.Sprintf("Collector filter `%s` is not supported", collectorName))
 		}
@@ -3,25 +3,61 @@ package filters
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
 
 	"github.com/cloudfoundry/bosh-cli/director"
 	"github.com/prometheus/common/log"
 )
 
 type DeploymentsFilter struct {
-	filters    []string
-	boshClient director.Director
+	filtered       *uint64
+	filters        []string
+	includeRegexps []*regexp.Regexp
+	excludeRegexps []*regexp.Regexp
+	onlyExactNames bool
+	boshClient     director.Director
 }
 
-func NewDeploymentsFilter(filters []string, boshClient director.Director) *DeploymentsFilter {
-	return &DeploymentsFilter{filters: filters, boshClient: boshClient}
+func NewDeploymentsFilter(filters []string, boshClient director.Director) (*DeploymentsFilter, error) {
+	var includeRegexps, excludeRegexps []*regexp.Regexp
+
+	onlyExactNames := true
+	for _, filter := range filters {
+		exclude := strings.HasPrefix(filter, "!")
+		expression := strings.TrimPrefix(filter, "!")
+
+		re, err := regexp.Compile("^(?:" + expression + ")$")
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Deployments filter `%s` is not a valid regular expression: %v", filter, err))
+		}
+
+		if exclude {
+			excludeRegexps = append(excludeRegexps, re)
+		} else {
+			includeRegexps = append(includeRegexps, re)
+		}
+
+		if exclude || regexp.QuoteMeta(expression) != expression {
+			onlyExactNames = false
+		}
+	}
+
+	return &DeploymentsFilter{
+		filtered:       new(uint64),
+		filters:        filters,
+		includeRegexps: includeRegexps,
+		excludeRegexps: excludeRegexps,
+		onlyExactNames: onlyExactNames,
+		boshClient:     boshClient,
+	}, nil
 }
 
 func (f *DeploymentsFilter) GetDeployments() ([]director.Deployment, error) {
-	var err error
 	var deployments []director.Deployment
 
-	if len(f.filters) > 0 {
+	if len(f.filters) > 0 && f.onlyExactNames {
 		log.Debugf("Filtering deployments by `%v`...", f.filters)
 		for _, deploymentName := range f.filters {
 			deployment, err := f.boshClient.FindDeployment(deploymentName)
@@ -30,13 +66,52 @@ func (f *DeploymentsFilter) GetDeployments() ([]director.Deployment, error) {
 			}
 			deployments = append(deployments, deployment)
 		}
-	} else {
-		log.Debugf("Reading deployments...")
-		deployments, err = f.boshClient.Deployments()
-		if err != nil {
-			return deployments, errors.New(fmt.Sprintf("Error while reading deployments: %v", err))
+
+		return deployments, nil
+	}
+
+	log.Debugf("Reading deployments...")
+	allDeployments, err := f.boshClient.Deployments()
+	if err != nil {
+		return deployments, errors.New(fmt.Sprintf("Error while reading deployments: %v", err))
+	}
+
+	if len(f.filters) == 0 {
+		return allDeployments, nil
+	}
+
+	log.Debugf("Filtering deployments by `%v`...", f.filters)
+	deployments = []director.Deployment{}
+	for _, deployment := range allDeployments {
+		if f.Enabled(deployment.Name()) {
+			deployments = append(deployments, deployment)
 		}
 	}
+	atomic.AddUint64(f.filtered, uint64(len(allDeployments)-len(deployments)))
 
 	return deployments, nil
 }
+
+func (f *DeploymentsFilter) Filtered() uint64 {
+	return atomic.LoadUint64(f.filtered)
+}
+
+func (f *DeploymentsFilter) Enabled(deploymentName string) bool {
+	for _, re := range f.excludeRegexps {
+		if re.MatchString(deploymentName) {
+			return false
+		}
+	}
+
+	if len(f.includeRegexps) == 0 {
+		return true
+	}
+
+	for _, re := range f.includeRegexps {
+		if re.MatchString(deploymentName) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -44,11 +44,12 @@ var _ = Describe("DeploymentsFilter", func() {
 			deployment2 = &directorfakes.FakeDeployment{
 				NameStub: func() string { return "fake-deployment-name-2" },
 			}
-			allDeployments = []director.Deployment{}
+			allDeployments = []director.Deployment{deployment1, deployment2}
 		})
 
 		JustBeforeEach(func() {
-			deploymentsFilter = NewDeploymentsFilter(filters, boshClient)
+			deploymentsFilter, err = NewDeploymentsFilter(filters, boshClient)
+			Expect(err).ToNot(HaveOccurred())
 			deployments, err = deploymentsFilter.GetDeployments()
 		})
 
@@ -109,5 +110,79 @@ var _ = Describe("DeploymentsFilter", func() {
 				})
 			})
 		})
+
+		Context("when there are regular expression filters", func() {
+			BeforeEach(func() {
+				filters = []string{"fake-deployment-.*-1"}
+				boshClient.DeploymentsReturns(allDeployments, nil)
+			})
+
+			It("returns the matching deployments", func() {
+				Expect(boshClient.FindDeploymentCallCount()).To(Equal(0))
+				Expect(deployments).To(Equal([]director.Deployment{deployment1}))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			Context("and it fails to get the deployments", func() {
+				BeforeEach(func() {
+					boshClient.DeploymentsReturns(nil, errors.New("no deployments"))
+				})
+
+				It("does not return any deployment", func() {
+					Expect(deployments).To(BeEmpty())
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context("when there are exclusion filters", func() {
+			BeforeEach(func() {
+				filters = []string{"!fake-deployment-name-1"}
+				boshClient.DeploymentsReturns(allDeployments, nil)
+			})
+
+			It("returns the non excluded deployments", func() {
+				Expect(deployments).To(Equal([]director.Deployment{deployment2}))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("counts the filtered deployments", func() {
+				Expect(deploymentsFilter.Filtered()).To(Equal(uint64(1)))
+			})
+
+			Context("and inclusion filters", func() {
+				BeforeEach(func() {
+					filters = []string{"fake-deployment-.*", "!.*-2"}
+				})
+
+				It("returns the included and non excluded deployments", func() {
+					Expect(deployments).To(Equal([]director.Deployment{deployment1}))
+					Expect(err).ToNot(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	Describe("NewDeploymentsFilter", func() {
+		Context("when a filter is not a valid regular expression", func() {
+			It("returns an error", func() {
+				_, err = NewDeploymentsFilter([]string{"!fake-deployment-(.*"}, &directorfakes.FakeDirector{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Deployments filter `!fake-deployment-(.*` is not a valid regular expression"))
+			})
+		})
+	})
+
+	Describe("Enabled", func() {
+		BeforeEach(func() {
+			deploymentsFilter, err = NewDeploymentsFilter([]string{"cf", "!test-.*"}, &directorfakes.FakeDirector{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("matches the whole deployment name", func() {
+			Expect(deploymentsFilter.Enabled("cf")).To(BeTrue())
+			Expect(deploymentsFilter.Enabled("cf-mysql")).To(BeFalse())
+			Expect(deploymentsFilter.Enabled("test-cf")).To(BeFalse())
+		})
 	})
 })
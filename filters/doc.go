@@ -0,0 +1,3 @@
+// Package filters selects the BOSH deployments, instances, processes and
+// collectors handled by the exporter.
+package filters
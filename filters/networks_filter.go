@@ -0,0 +1,55 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var invalidDNSCharacters = regexp.MustCompile("[^a-z0-9-]")
+
+// NetworksFilter selects BOSH DNS names
+// (`<id>.<instance-group>.<network>.<deployment>.bosh`) by network name.
+// Network names are canonicalized as BOSH does in DNS names, so a
+// `default_net` network matches the `default-net` segment.
+type NetworksFilter struct {
+	networks map[string]bool
+}
+
+func NewNetworksFilter(filters []string) (*NetworksFilter, error) {
+	networks := map[string]bool{}
+
+	for _, filter := range filters {
+		if filter == "" || strings.Contains(filter, ".") {
+			return nil, errors.New(fmt.Sprintf("Network filter `%s` is not a valid network name", filter))
+		}
+		networks[canonicalizeDNSSegment(filter)] = true
+	}
+
+	return &NetworksFilter{networks: networks}, nil
+}
+
+func (f *NetworksFilter) Select(dnsNames []string) []string {
+	if len(f.networks) == 0 {
+		return dnsNames
+	}
+
+	selectedDNSNames := []string{}
+	for _, dnsName := range dnsNames {
+		segments := strings.Split(dnsName, ".")
+		if len(segments) < 5 {
+			continue
+		}
+
+		if f.networks[strings.ToLower(segments[2])] {
+			selectedDNSNames = append(selectedDNSNames, dnsName)
+		}
+	}
+
+	return selectedDNSNames
+}
+
+func canonicalizeDNSSegment(segment string) string {
+	return invalidDNSCharacters.ReplaceAllString(strings.Replace(strings.ToLower(segment), "_", "-", -1), "")
+}
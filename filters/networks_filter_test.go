@@ -0,0 +1,81 @@
+package filters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+var _ = Describe("NetworksFilter", func() {
+	var (
+		err     error
+		filters []string
+
+		networksFilter *NetworksFilter
+
+		dnsNames = []string{
+			"fake-id.fake-job.fake-network.fake-deployment.bosh",
+			"fake-id.fake-job.fake-other-network.fake-deployment.bosh",
+			"fake-id.fake-job.default-net.fake-deployment.bosh",
+		}
+	)
+
+	JustBeforeEach(func() {
+		networksFilter, err = NewNetworksFilter(filters)
+	})
+
+	Describe("New", func() {
+		Context("when filters are valid network names", func() {
+			BeforeEach(func() {
+				filters = []string{"fake-network", "default_net"}
+			})
+
+			It("does not return an error", func() {
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when filters are not valid network names", func() {
+			BeforeEach(func() {
+				filters = []string{"fake-network", "fake.network"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Network filter `fake.network` is not a valid network name"))
+			})
+		})
+	})
+
+	Describe("Select", func() {
+		BeforeEach(func() {
+			filters = []string{"fake-network", "Default_Net"}
+		})
+
+		Context("when there are DNS names on the networks", func() {
+			It("returns only the DNS names on the networks", func() {
+				Expect(networksFilter.Select(dnsNames)).To(Equal([]string{
+					"fake-id.fake-job.fake-network.fake-deployment.bosh",
+					"fake-id.fake-job.default-net.fake-deployment.bosh",
+				}))
+			})
+		})
+
+		Context("when there are no DNS names on the networks", func() {
+			It("returns an empty list", func() {
+				Expect(networksFilter.Select([]string{"fake-id.fake-job.fake-other-network.fake-deployment.bosh", "fake-network"})).To(BeEmpty())
+			})
+		})
+
+		Context("when there are no filters", func() {
+			BeforeEach(func() {
+				filters = []string{}
+			})
+
+			It("returns all DNS names", func() {
+				Expect(networksFilter.Select(dnsNames)).To(Equal(dnsNames))
+			})
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ProcessesFilter selects processes using the first rule whose deployment
+// regexp matches their deployment name, or the default filter when no rule
+// matches.
+type ProcessesFilter struct {
+	filtered      uint64
+	defaultFilter *RegexpFilter
+	rules         []processesRule
+}
+
+type processesRule struct {
+	deployment *regexp.Regexp
+	processes  *RegexpFilter
+}
+
+// LoadProcessesFilter reads the rules from a YAML file mapping deployment
+// names regexps to processes names regexps. It returns a filter using only
+// the default filter if the filename is empty.
+func LoadProcessesFilter(defaultFilter *RegexpFilter, filename string) (*ProcessesFilter, error) {
+	processesFilter := &ProcessesFilter{defaultFilter: defaultFilter}
+
+	if filename == "" {
+		return processesFilter, nil
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return processesFilter, errors.New(fmt.Sprintf("Error reading deployments processes file `%s`: %v", filename, err))
+	}
+
+	var mapping yaml.MapSlice
+	if err = yaml.Unmarshal(content, &mapping); err != nil {
+		return processesFilter, errors.New(fmt.Sprintf("Error parsing deployments processes file `%s`: %v", filename, err))
+	}
+
+	for _, item := range mapping {
+		deployment := fmt.Sprintf("%v", item.Key)
+		deploymentRegexp, err := regexp.Compile("^(?:" + deployment + ")$")
+		if err != nil {
+			return processesFilter, errors.New(fmt.Sprintf("Invalid deployment regexp `%s` at deployments processes file `%s`: %v", deployment, filename, err))
+		}
+
+		processes, ok := item.Value.(string)
+		if !ok {
+			return processesFilter, errors.New(fmt.Sprintf("Invalid processes regexp for deployment regexp `%s` at deployments processes file `%s`", deployment, filename))
+		}
+
+		processesRegexpFilter, err := NewRegexpFilter([]string{processes})
+		if err != nil {
+			return processesFilter, errors.New(fmt.Sprintf("Invalid processes regexp `%s` for deployment regexp `%s` at deployments processes file `%s`: %v", processes, deployment, filename, err))
+		}
+
+		processesFilter.rules = append(processesFilter.rules, processesRule{deployment: deploymentRegexp, processes: processesRegexpFilter})
+	}
+
+	return processesFilter, nil
+}
+
+func (f *ProcessesFilter) Enabled(deployment string, process string) bool {
+	for _, rule := range f.rules {
+		if !rule.deployment.MatchString(deployment) {
+			continue
+		}
+
+		if rule.processes.Enabled(process) {
+			return true
+		}
+
+		atomic.AddUint64(&f.filtered, 1)
+		return false
+	}
+
+	return f.defaultFilter.Enabled(process)
+}
+
+func (f *ProcessesFilter) Filtered() uint64 {
+	return atomic.LoadUint64(&f.filtered) + f.defaultFilter.Filtered()
+}
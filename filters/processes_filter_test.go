@@ -0,0 +1,126 @@
+package filters_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+var _ = Describe("ProcessesFilter", func() {
+	var (
+		err             error
+		tmpfile         *os.File
+		filename        string
+		content         string
+		defaultFilter   *RegexpFilter
+		processesFilter *ProcessesFilter
+	)
+
+	BeforeEach(func() {
+		tmpfile, err = ioutil.TempFile("", "processes_filter_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = tmpfile.Name()
+		content = "cf: gorouter\nconcourse-.*: .*\nredis: \"!redis\"\n"
+		defaultFilter, err = NewRegexpFilter([]string{"node_exporter"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		err = os.Remove(tmpfile.Name())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		err = ioutil.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		processesFilter, err = LoadProcessesFilter(defaultFilter, filename)
+	})
+
+	Describe("LoadProcessesFilter", func() {
+		It("does not return an error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Context("when the file is not valid", func() {
+			BeforeEach(func() {
+				content = "- cf"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Error parsing deployments processes file"))
+			})
+		})
+
+		Context("when a deployment regexp does not compile", func() {
+			BeforeEach(func() {
+				content = "cf-(: gorouter\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid deployment regexp `cf-(`"))
+			})
+		})
+
+		Context("when a processes regexp does not compile", func() {
+			BeforeEach(func() {
+				content = "cf: gorouter-(\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid processes regexp `gorouter-(`"))
+			})
+		})
+
+		Context("when a processes regexp is not a string", func() {
+			BeforeEach(func() {
+				content = "cf: [gorouter]\n"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid processes regexp for deployment regexp `cf`"))
+			})
+		})
+	})
+
+	Describe("Enabled", func() {
+		It("applies the rule of the deployment", func() {
+			Expect(processesFilter.Enabled("cf", "gorouter")).To(BeTrue())
+			Expect(processesFilter.Enabled("cf", "node_exporter")).To(BeFalse())
+			Expect(processesFilter.Enabled("concourse-main", "atc")).To(BeTrue())
+			Expect(processesFilter.Enabled("redis", "redis")).To(BeFalse())
+			Expect(processesFilter.Enabled("redis", "node_exporter")).To(BeTrue())
+		})
+
+		It("anchors the deployment regexps", func() {
+			Expect(processesFilter.Enabled("cf-redis", "gorouter")).To(BeFalse())
+			Expect(processesFilter.Enabled("cf-redis", "node_exporter")).To(BeTrue())
+		})
+
+		It("counts the filtered processes", func() {
+			processesFilter.Enabled("cf", "node_exporter")
+			processesFilter.Enabled("mysql", "mysqld")
+			processesFilter.Enabled("mysql", "node_exporter")
+			Expect(processesFilter.Filtered()).To(Equal(uint64(2)))
+		})
+
+		Context("when there is no filename", func() {
+			BeforeEach(func() {
+				filename = ""
+			})
+
+			It("applies the default filter", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(processesFilter.Enabled("cf", "gorouter")).To(BeFalse())
+				Expect(processesFilter.Enabled("cf", "node_exporter")).To(BeTrue())
+			})
+		})
+	})
+})
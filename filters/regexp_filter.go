@@ -2,27 +2,71 @@ package filters
 
 import (
 	"regexp"
+	"strings"
+	"sync/atomic"
 )
 
 type RegexpFilter struct {
-	reFilters []*regexp.Regexp
+	filtered         uint64
+	reFilters        []*regexp.Regexp
+	reExcludeFilters []*regexp.Regexp
 }
 
 func NewRegexpFilter(filters []string) (*RegexpFilter, error) {
 	reFilters := []*regexp.Regexp{}
+	reExcludeFilters := []*regexp.Regexp{}
 
 	for _, filter := range filters {
-		re, err := regexp.Compile(filter)
+		re, err := regexp.Compile(strings.TrimPrefix(filter, "!"))
 		if err != nil {
 			return nil, err
 		}
-		reFilters = append(reFilters, re)
+
+		if strings.HasPrefix(filter, "!") {
+			reExcludeFilters = append(reExcludeFilters, re)
+		} else {
+			reFilters = append(reFilters, re)
+		}
+	}
+
+	return &RegexpFilter{reFilters: reFilters, reExcludeFilters: reExcludeFilters}, nil
+}
+
+// NewAnchoredRegexpFilter returns a RegexpFilter whose regexps match the
+// whole expression, as the deployments and AZs filters do.
+func NewAnchoredRegexpFilter(filters []string) (*RegexpFilter, error) {
+	anchoredFilters := []string{}
+	for _, filter := range filters {
+		prefix := ""
+		if strings.HasPrefix(filter, "!") {
+			prefix = "!"
+		}
+		anchoredFilters = append(anchoredFilters, prefix+"^(?:"+strings.TrimPrefix(filter, "!")+")$")
 	}
 
-	return &RegexpFilter{reFilters: reFilters}, nil
+	return NewRegexpFilter(anchoredFilters)
 }
 
 func (f *RegexpFilter) Enabled(expr string) bool {
+	if f.enabled(expr) {
+		return true
+	}
+
+	atomic.AddUint64(&f.filtered, 1)
+	return false
+}
+
+func (f *RegexpFilter) Filtered() uint64 {
+	return atomic.LoadUint64(&f.filtered)
+}
+
+func (f *RegexpFilter) enabled(expr string) bool {
+	for _, re := range f.reExcludeFilters {
+		if re.MatchString(expr) {
+			return false
+		}
+	}
+
 	if len(f.reFilters) == 0 {
 		return true
 	}
@@ -57,6 +57,12 @@ var _ = Describe("RegexpFilter", func() {
 			It("returns false", func() {
 				Expect(regexpFilter.Enabled("deployments_exporter")).To(BeFalse())
 			})
+
+			It("counts the filtered expression", func() {
+				regexpFilter.Enabled("deployments_collector")
+				regexpFilter.Enabled("deployments_exporter")
+				Expect(regexpFilter.Filtered()).To(Equal(uint64(1)))
+			})
 		})
 
 		Context("when there are no filters", func() {
@@ -68,5 +74,72 @@ var _ = Describe("RegexpFilter", func() {
 				Expect(regexpFilter.Enabled("deployments_exporter")).To(BeTrue())
 			})
 		})
+
+		Context("when there are exclusion filters", func() {
+			BeforeEach(func() {
+				filters = []string{"!^smoke-tests$", "!^compilation-"}
+			})
+
+			It("returns false when there is a match", func() {
+				Expect(regexpFilter.Enabled("smoke-tests")).To(BeFalse())
+				Expect(regexpFilter.Enabled("compilation-1234")).To(BeFalse())
+			})
+
+			It("returns true when there is not a match", func() {
+				Expect(regexpFilter.Enabled("router")).To(BeTrue())
+			})
+
+			Context("and inclusion filters", func() {
+				BeforeEach(func() {
+					filters = []string{"_collector$", "!^jobs_"}
+				})
+
+				It("returns true only when the inclusion filters match and the exclusion filters do not", func() {
+					Expect(regexpFilter.Enabled("deployments_collector")).To(BeTrue())
+					Expect(regexpFilter.Enabled("jobs_collector")).To(BeFalse())
+					Expect(regexpFilter.Enabled("deployments_exporter")).To(BeFalse())
+				})
+			})
+		})
+	})
+
+	Describe("NewAnchored", func() {
+		JustBeforeEach(func() {
+			regexpFilter, err = NewAnchoredRegexpFilter(filters)
+		})
+
+		Context("when filters does not compile", func() {
+			BeforeEach(func() {
+				filters = []string{"router("}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when there are inclusion filters", func() {
+			BeforeEach(func() {
+				filters = []string{"router", "diego-.*"}
+			})
+
+			It("matches the whole expression", func() {
+				Expect(regexpFilter.Enabled("router")).To(BeTrue())
+				Expect(regexpFilter.Enabled("diego-cell")).To(BeTrue())
+				Expect(regexpFilter.Enabled("tcp-router")).To(BeFalse())
+				Expect(regexpFilter.Enabled("router-canary")).To(BeFalse())
+			})
+		})
+
+		Context("when there are exclusion filters", func() {
+			BeforeEach(func() {
+				filters = []string{"!smoke-tests"}
+			})
+
+			It("excludes only the whole expression", func() {
+				Expect(regexpFilter.Enabled("smoke-tests")).To(BeFalse())
+				Expect(regexpFilter.Enabled("smoke-tests-windows")).To(BeTrue())
+			})
+		})
 	})
 })
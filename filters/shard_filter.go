@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+)
+
+type ShardFilter struct {
+	filtered uint64
+	index    uint32
+	count    uint32
+}
+
+func NewShardFilter(index int, count int) (*ShardFilter, error) {
+	if count < 1 {
+		return nil, errors.New(fmt.Sprintf("Shard count `%d` must be greater than 0", count))
+	}
+
+	if index < 0 || index >= count {
+		return nil, errors.New(fmt.Sprintf("Shard index `%d` must be between 0 and %d", index, count-1))
+	}
+
+	return &ShardFilter{index: uint32(index), count: uint32(count)}, nil
+}
+
+// Enabled returns true if the deployment belongs to the shard. Deployments are
+// partitioned by the FNV-1a hash of their name, so each deployment belongs to
+// exactly one shard.
+func (f *ShardFilter) Enabled(deploymentName string) bool {
+	if f.count == 1 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(deploymentName))
+	if hash.Sum32()%f.count == f.index {
+		return true
+	}
+
+	atomic.AddUint64(&f.filtered, 1)
+	return false
+}
+
+func (f *ShardFilter) Filtered() uint64 {
+	return atomic.LoadUint64(&f.filtered)
+}
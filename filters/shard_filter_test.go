@@ -0,0 +1,109 @@
+package filters_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+var _ = Describe("ShardFilter", func() {
+	var (
+		err         error
+		index       int
+		count       int
+		shardFilter *ShardFilter
+	)
+
+	BeforeEach(func() {
+		index = 0
+		count = 1
+	})
+
+	JustBeforeEach(func() {
+		shardFilter, err = NewShardFilter(index, count)
+	})
+
+	Describe("New", func() {
+		Context("when the count is not valid", func() {
+			BeforeEach(func() {
+				count = 0
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Shard count `0` must be greater than 0"))
+			})
+		})
+
+		Context("when the index is not valid", func() {
+			BeforeEach(func() {
+				index = 3
+				count = 3
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Shard index `3` must be between 0 and 2"))
+			})
+		})
+	})
+
+	Describe("Enabled", func() {
+		Context("when there is a single shard", func() {
+			It("returns true", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(shardFilter.Enabled("fake-deployment-name")).To(BeTrue())
+				Expect(shardFilter.Filtered()).To(Equal(uint64(0)))
+			})
+		})
+
+		Context("when there are several shards", func() {
+			BeforeEach(func() {
+				count = 3
+			})
+
+			It("assigns each deployment to exactly one shard", func() {
+				shardFilters := []*ShardFilter{}
+				for i := 0; i < count; i++ {
+					shardFilter, err := NewShardFilter(i, count)
+					Expect(err).ToNot(HaveOccurred())
+					shardFilters = append(shardFilters, shardFilter)
+				}
+
+				shards := map[int]int{}
+				for d := 0; d < 30; d++ {
+					deploymentName := fmt.Sprintf("fake-deployment-name-%d", d)
+					enabled := 0
+					for i, shardFilter := range shardFilters {
+						if shardFilter.Enabled(deploymentName) {
+							enabled++
+							shards[i]++
+						}
+					}
+					Expect(enabled).To(Equal(1))
+				}
+				Expect(shards).To(HaveLen(count))
+			})
+
+			It("is deterministic", func() {
+				otherShardFilter, err := NewShardFilter(index, count)
+				Expect(err).ToNot(HaveOccurred())
+				for d := 0; d < 10; d++ {
+					deploymentName := fmt.Sprintf("fake-deployment-name-%d", d)
+					Expect(otherShardFilter.Enabled(deploymentName)).To(Equal(shardFilter.Enabled(deploymentName)))
+				}
+			})
+
+			It("counts the filtered deployments", func() {
+				for d := 0; d < 30; d++ {
+					shardFilter.Enabled(fmt.Sprintf("fake-deployment-name-%d", d))
+				}
+				Expect(shardFilter.Filtered()).To(BeNumerically(">", 0))
+				Expect(shardFilter.Filtered()).To(BeNumerically("<", 30))
+			})
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cloudfoundry/bosh-cli/director"
+)
+
+type TeamsFilter struct {
+	filtered     uint64
+	teamsEnabled map[string]bool
+}
+
+func NewTeamsFilter(filters []string) *TeamsFilter {
+	teamsEnabled := make(map[string]bool)
+
+	for _, team := range filters {
+		teamsEnabled[team] = true
+	}
+
+	return &TeamsFilter{teamsEnabled: teamsEnabled}
+}
+
+func (f *TeamsFilter) Enabled(deployment director.Deployment) (bool, error) {
+	if len(f.teamsEnabled) == 0 {
+		return true, nil
+	}
+
+	teams, err := deployment.Teams()
+	if err != nil {
+		return false, errors.New(fmt.Sprintf("Error while reading Teams for deployment `%s`: %v", deployment.Name(), err))
+	}
+
+	for _, team := range teams {
+		if f.teamsEnabled[team] {
+			return true, nil
+		}
+	}
+
+	atomic.AddUint64(&f.filtered, 1)
+	return false, nil
+}
+
+func (f *TeamsFilter) Filtered() uint64 {
+	return atomic.LoadUint64(&f.filtered)
+}
@@ -0,0 +1,85 @@
+package filters_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-cli/director/directorfakes"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+var _ = Describe("TeamsFilter", func() {
+	var (
+		err         error
+		filter      []string
+		deployment  *directorfakes.FakeDeployment
+		teamsFilter *TeamsFilter
+		enabled     bool
+	)
+
+	BeforeEach(func() {
+		filter = []string{"fake-team-1", "fake-team-3"}
+		deployment = &directorfakes.FakeDeployment{
+			NameStub: func() string { return "fake-deployment-name" },
+		}
+	})
+
+	JustBeforeEach(func() {
+		teamsFilter = NewTeamsFilter(filter)
+		enabled, err = teamsFilter.Enabled(deployment)
+	})
+
+	Describe("Enabled", func() {
+		Context("when deployment belongs to an enabled team", func() {
+			BeforeEach(func() {
+				deployment.TeamsReturns([]string{"fake-team-2", "fake-team-3"}, nil)
+			})
+
+			It("returns true", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(enabled).To(BeTrue())
+			})
+		})
+
+		Context("when deployment does not belong to an enabled team", func() {
+			BeforeEach(func() {
+				deployment.TeamsReturns([]string{"fake-team-2"}, nil)
+			})
+
+			It("returns false", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(enabled).To(BeFalse())
+			})
+
+			It("counts the filtered deployment", func() {
+				Expect(teamsFilter.Filtered()).To(Equal(uint64(1)))
+			})
+		})
+
+		Context("when it fails to get the deployment teams", func() {
+			BeforeEach(func() {
+				deployment.TeamsReturns(nil, errors.New("no teams"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(enabled).To(BeFalse())
+			})
+		})
+
+		Context("when there are no filters", func() {
+			BeforeEach(func() {
+				filter = []string{}
+			})
+
+			It("returns true without reading the deployment teams", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(enabled).To(BeTrue())
+				Expect(deployment.TeamsCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
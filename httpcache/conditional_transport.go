@@ -0,0 +1,149 @@
+package httpcache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/common/log"
+)
+
+// DirectorPaths matches the BOSH Director endpoints returning the
+// deployments and configs, the ones worth revalidating instead of fetching.
+var DirectorPaths = regexp.MustCompile(`^/(deployments(/[^/]+)?|configs|cloud_configs|runtime_configs|cpi_configs)$`)
+
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+}
+
+// ConditionalTransport is an http.RoundTripper sending conditional GET
+// requests (If-None-Match and If-Modified-Since) for the paths it caches,
+// and replaying the cached body when the server answers 304 Not Modified.
+// Responses without an ETag nor a Last-Modified header are not cached, so
+// servers not supporting conditional requests are not affected.
+type ConditionalTransport struct {
+	transport http.RoundTripper
+	paths     *regexp.Regexp
+	mutex     sync.Mutex
+	responses map[string]cachedResponse
+}
+
+func NewConditionalTransport(transport http.RoundTripper, paths *regexp.Regexp) *ConditionalTransport {
+	return &ConditionalTransport{
+		transport: transport,
+		paths:     paths,
+		responses: map[string]cachedResponse{},
+	}
+}
+
+func (t *ConditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || !t.paths.MatchString(req.URL.Path) {
+		return t.transport.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached, ok := t.cachedResponse(key)
+	if ok {
+		req = cloneRequest(req)
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && ok {
+		log.Debugf("Reusing cached response for `%s`", req.URL.Path)
+		resp.Body.Close()
+		return cached.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		t.forget(key)
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.mutex.Lock()
+	t.responses[key] = cachedResponse{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header,
+		body:         body,
+	}
+	t.mutex.Unlock()
+
+	return resp, nil
+}
+
+func (t *ConditionalTransport) cachedResponse(key string) (cachedResponse, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	cached, ok := t.responses[key]
+	return cached, ok
+}
+
+func (t *ConditionalTransport) forget(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.responses, key)
+}
+
+func (c cachedResponse) response(req *http.Request) *http.Response {
+	header := http.Header{}
+	for name, values := range c.header {
+		header[name] = values
+	}
+	header.Set("Content-Length", strconv.Itoa(len(c.body)))
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(c.body)),
+		ContentLength: int64(len(c.body)),
+		Request:       req,
+	}
+}
+
+// cloneRequest returns a shallow copy of the request with its own headers,
+// as a RoundTripper must not modify the request it is given.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = http.Header{}
+	for name, values := range req.Header {
+		clone.Header[name] = values
+	}
+
+	return clone
+}
@@ -0,0 +1,102 @@
+package httpcache_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/httpcache"
+)
+
+type directorRequest struct {
+	path        string
+	ifNoneMatch string
+}
+
+var _ = Describe("ConditionalTransport", func() {
+	var (
+		mutex          sync.Mutex
+		requests       []directorRequest
+		etag           string
+		directorServer *httptest.Server
+		httpClient     *http.Client
+	)
+
+	BeforeEach(func() {
+		requests = []directorRequest{}
+		etag = `"fake-etag"`
+		directorServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			requests = append(requests, directorRequest{path: r.URL.Path, ifNoneMatch: r.Header.Get("If-None-Match")})
+			mutex.Unlock()
+
+			if etag != "" {
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", etag)
+			}
+			w.Write([]byte("fake-body " + r.URL.Path))
+		}))
+		httpClient = &http.Client{Transport: NewConditionalTransport(http.DefaultTransport, DirectorPaths)}
+	})
+
+	AfterEach(func() {
+		directorServer.Close()
+	})
+
+	get := func(path string) string {
+		resp, err := httpClient.Get(directorServer.URL + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	It("revalidates cached responses and reuses their body", func() {
+		Expect(get("/deployments")).To(Equal("fake-body /deployments"))
+		Expect(get("/deployments")).To(Equal("fake-body /deployments"))
+
+		Expect(requests).To(Equal([]directorRequest{
+			{path: "/deployments", ifNoneMatch: ""},
+			{path: "/deployments", ifNoneMatch: `"fake-etag"`},
+		}))
+	})
+
+	It("fetches the new body when the response changed", func() {
+		Expect(get("/configs")).To(Equal("fake-body /configs"))
+		etag = `"new-fake-etag"`
+		Expect(get("/configs")).To(Equal("fake-body /configs"))
+		Expect(get("/configs")).To(Equal("fake-body /configs"))
+
+		Expect(requests[2]).To(Equal(directorRequest{path: "/configs", ifNoneMatch: `"new-fake-etag"`}))
+	})
+
+	It("does not send conditional requests for other paths", func() {
+		get("/deployments/fake-deployment-name/vms")
+		get("/deployments/fake-deployment-name/vms")
+
+		Expect(requests[1].ifNoneMatch).To(BeEmpty())
+	})
+
+	Context("when the server does not support conditional requests", func() {
+		BeforeEach(func() {
+			etag = ""
+		})
+
+		It("does not send conditional requests", func() {
+			Expect(get("/deployments")).To(Equal("fake-body /deployments"))
+			Expect(get("/deployments")).To(Equal("fake-body /deployments"))
+
+			Expect(requests[1].ifNoneMatch).To(BeEmpty())
+		})
+	})
+})
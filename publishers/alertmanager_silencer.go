@@ -0,0 +1,231 @@
+package publishers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const alertmanagerDeploymentLabel = "bosh_deployment"
+
+type alertmanagerMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type alertmanagerSilence struct {
+	ID        string                `json:"id,omitempty"`
+	Matchers  []alertmanagerMatcher `json:"matchers"`
+	StartsAt  time.Time             `json:"startsAt"`
+	EndsAt    time.Time             `json:"endsAt"`
+	CreatedBy string                `json:"createdBy"`
+	Comment   string                `json:"comment"`
+}
+
+type alertmanagerGettableSilence struct {
+	alertmanagerSilence
+	Status struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+type alertmanagerSilenceResponse struct {
+	SilenceID string `json:"silenceID"`
+}
+
+// AlertmanagerSilencer silences the alerts of the deployments while a task
+// deploys, deletes, starts, stops or restarts them, and expires the silences
+// once the task is over. Silences last for the silence duration and are
+// extended while the task is in progress, so they expire on their own if
+// the exporter stops.
+type AlertmanagerSilencer struct {
+	alertmanagerURL string
+	createdBy       string
+	matchers        map[string]string
+	duration        time.Duration
+	httpClient      *http.Client
+	now             func() time.Time
+	mutex           sync.Mutex
+	initialized     bool
+	silences        map[string]alertmanagerSilence
+}
+
+// NewAlertmanagerSilencer returns a silencer matching the alerts on their
+// bosh_deployment label and on the extra matchers.
+func NewAlertmanagerSilencer(
+	alertmanagerURL string,
+	boshUUID string,
+	matchers map[string]string,
+	duration time.Duration,
+	httpClient *http.Client,
+) *AlertmanagerSilencer {
+	return &AlertmanagerSilencer{
+		alertmanagerURL: strings.TrimSuffix(alertmanagerURL, "/"),
+		createdBy:       "bosh_exporter/" + boshUUID,
+		matchers:        matchers,
+		duration:        duration,
+		httpClient:      httpClient,
+		now:             time.Now,
+		silences:        map[string]alertmanagerSilence{},
+	}
+}
+
+// ObserveDeployments creates or extends the silences of the deployments with
+// a task in progress, and expires the others. The silences of the deployments
+// whose tasks could not be read are kept as they are. The silences created by
+// a previous run of the exporter are taken over on the first call.
+func (s *AlertmanagerSilencer) ObserveDeployments(deploymentsInfo []deployments.DeploymentInfo) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.initialized {
+		if err := s.loadSilences(); err != nil {
+			return err
+		}
+		s.initialized = true
+	}
+
+	now := s.now()
+	errs := []string{}
+
+	inProgress := map[string]bool{}
+	for _, deployment := range deploymentsInfo {
+		if deployment.UnavailableDetails.Tasks {
+			inProgress[deployment.Name] = true
+			continue
+		}
+
+		task, ok := collectors.DeploymentTaskInProgress(deployment)
+		if !ok {
+			continue
+		}
+		inProgress[deployment.Name] = true
+
+		silence, silenced := s.silences[deployment.Name]
+		if silenced && silence.EndsAt.Sub(now) > s.duration/2 {
+			continue
+		}
+
+		silence = s.createSilence(silence.ID, deployment.Name, task, now)
+		id, err := s.postSilence(silence)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		silence.ID = id
+		s.silences[deployment.Name] = silence
+	}
+
+	for deploymentName, silence := range s.silences {
+		if inProgress[deploymentName] {
+			continue
+		}
+
+		if !silence.EndsAt.After(now) {
+			delete(s.silences, deploymentName)
+			continue
+		}
+
+		if err := s.expireSilence(silence.ID); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		delete(s.silences, deploymentName)
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (s *AlertmanagerSilencer) createSilence(id string, deploymentName string, task deployments.Task, now time.Time) alertmanagerSilence {
+	matchers := []alertmanagerMatcher{{Name: alertmanagerDeploymentLabel, Value: deploymentName, IsEqual: true}}
+	names := []string{}
+	for name := range s.matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		matchers = append(matchers, alertmanagerMatcher{Name: name, Value: s.matchers[name], IsEqual: true})
+	}
+
+	return alertmanagerSilence{
+		ID:        id,
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    now.Add(s.duration),
+		CreatedBy: s.createdBy,
+		Comment:   fmt.Sprintf("BOSH task %d `%s` in progress on deployment `%s`", task.ID, task.Description, deploymentName),
+	}
+}
+
+// loadSilences takes over the active silences created by this exporter.
+func (s *AlertmanagerSilencer) loadSilences() error {
+	body, err := doHTTPRequest(s.httpClient, "Alertmanager", "GET", s.alertmanagerURL+"/api/v2/silences", map[string]string{}, nil)
+	if err != nil {
+		return err
+	}
+
+	var silences []alertmanagerGettableSilence
+	if err = json.Unmarshal(body, &silences); err != nil {
+		return errors.New(fmt.Sprintf("Error unmarshalling Alertmanager silences: %v", err))
+	}
+
+	for _, silence := range silences {
+		if silence.CreatedBy != s.createdBy || silence.Status.State != "active" {
+			continue
+		}
+
+		for _, matcher := range silence.Matchers {
+			if matcher.Name == alertmanagerDeploymentLabel {
+				s.silences[matcher.Value] = silence.alertmanagerSilence
+			}
+		}
+	}
+
+	return nil
+}
+
+// postSilence creates the silence, or updates it if it has an ID, and
+// returns its ID.
+func (s *AlertmanagerSilencer) postSilence(silence alertmanagerSilence) (string, error) {
+	request, err := json.Marshal(silence)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error marshalling Alertmanager silence: %v", err))
+	}
+
+	body, err := doHTTPRequest(s.httpClient, "Alertmanager", "POST", s.alertmanagerURL+"/api/v2/silences", map[string]string{}, request)
+	if err != nil {
+		return "", err
+	}
+
+	var response alertmanagerSilenceResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return "", errors.New(fmt.Sprintf("Error unmarshalling Alertmanager silence response: %v", err))
+	}
+
+	return response.SilenceID, nil
+}
+
+// expireSilence expires the silence. Silences deleted by an operator are not
+// an error.
+func (s *AlertmanagerSilencer) expireSilence(id string) error {
+	_, err := doHTTPRequest(s.httpClient, "Alertmanager", "DELETE", s.alertmanagerURL+"/api/v2/silence/"+id, map[string]string{}, nil)
+	if statusErr, ok := err.(*httpStatusError); ok && statusErr.statusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
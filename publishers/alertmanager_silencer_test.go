@@ -0,0 +1,187 @@
+package publishers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeAlertmanagerSilence struct {
+	ID        string                   `json:"id"`
+	Matchers  []map[string]interface{} `json:"matchers"`
+	StartsAt  time.Time                `json:"startsAt"`
+	EndsAt    time.Time                `json:"endsAt"`
+	CreatedBy string                   `json:"createdBy"`
+	Comment   string                   `json:"comment"`
+	Status    map[string]string        `json:"status,omitempty"`
+}
+
+type fakeAlertmanager struct {
+	mu         sync.Mutex
+	silences   []fakeAlertmanagerSilence
+	posted     []fakeAlertmanagerSilence
+	expired    []string
+	statusCode int
+}
+
+func (a *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.statusCode != 0 {
+		w.WriteHeader(a.statusCode)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v2/silences":
+		json.NewEncoder(w).Encode(a.silences)
+	case r.Method == "POST" && r.URL.Path == "/api/v2/silences":
+		silence := fakeAlertmanagerSilence{}
+		json.NewDecoder(r.Body).Decode(&silence)
+		a.posted = append(a.posted, silence)
+		if silence.ID == "" {
+			silence.ID = fmt.Sprintf("fake-silence-%d", len(a.posted))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"silenceID": silence.ID})
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		a.expired = append(a.expired, strings.TrimPrefix(r.URL.Path, "/api/v2/silence/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("AlertmanagerSilencer", func() {
+	var (
+		now             time.Time
+		alertmanager    *fakeAlertmanager
+		server          *httptest.Server
+		deploymentsInfo []deployments.DeploymentInfo
+
+		alertmanagerSilencer *AlertmanagerSilencer
+	)
+
+	deployTask := deployments.Task{ID: 42, Description: "create deployment", State: "processing"}
+
+	BeforeEach(func() {
+		now = time.Unix(1500000000, 0).UTC()
+		alertmanager = &fakeAlertmanager{silences: []fakeAlertmanagerSilence{}}
+		server = httptest.NewServer(alertmanager)
+		deploymentsInfo = []deployments.DeploymentInfo{
+			{Name: "fake-deployment-1", Tasks: []deployments.Task{deployTask}},
+			{Name: "fake-deployment-2", Tasks: []deployments.Task{{ID: 41, Description: "create deployment", State: "done"}}},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		alertmanagerSilencer = NewAlertmanagerSilencer(server.URL, "fake-bosh-uuid", map[string]string{"environment": "fake-environment"}, time.Hour, &http.Client{Timeout: 5 * time.Second})
+		alertmanagerSilencer.SetNow(func() time.Time { return now })
+	})
+
+	It("silences the deployments with a deploy task in progress", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.posted).To(HaveLen(1))
+		Expect(alertmanager.posted[0].ID).To(BeEmpty())
+		Expect(alertmanager.posted[0].Matchers).To(Equal([]map[string]interface{}{
+			{"name": "bosh_deployment", "value": "fake-deployment-1", "isRegex": false, "isEqual": true},
+			{"name": "environment", "value": "fake-environment", "isRegex": false, "isEqual": true},
+		}))
+		Expect(alertmanager.posted[0].StartsAt).To(Equal(now))
+		Expect(alertmanager.posted[0].EndsAt).To(Equal(now.Add(time.Hour)))
+		Expect(alertmanager.posted[0].CreatedBy).To(Equal("bosh_exporter/fake-bosh-uuid"))
+		Expect(alertmanager.posted[0].Comment).To(Equal("BOSH task 42 `create deployment` in progress on deployment `fake-deployment-1`"))
+	})
+
+	It("does not create the silence again while the task is in progress", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		now = now.Add(10 * time.Minute)
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.posted).To(HaveLen(1))
+	})
+
+	It("extends the silence when the task lasts", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		now = now.Add(40 * time.Minute)
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.posted).To(HaveLen(2))
+		Expect(alertmanager.posted[1].ID).To(Equal("fake-silence-1"))
+		Expect(alertmanager.posted[1].EndsAt).To(Equal(now.Add(time.Hour)))
+	})
+
+	It("expires the silence once the task is over", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		deploymentsInfo[0].Tasks[0].State = "done"
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.expired).To(Equal([]string{"fake-silence-1"}))
+
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.expired).To(HaveLen(1))
+	})
+
+	It("keeps the silence when the tasks could not be read", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		deploymentsInfo[0].Tasks = nil
+		deploymentsInfo[0].UnavailableDetails.Tasks = true
+		now = now.Add(40 * time.Minute)
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanager.posted).To(HaveLen(1))
+		Expect(alertmanager.expired).To(BeEmpty())
+	})
+
+	It("expires the silence when the deployment is deleted", func() {
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+		Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo[1:])).To(Succeed())
+		Expect(alertmanager.expired).To(Equal([]string{"fake-silence-1"}))
+	})
+
+	Context("when silences were created by a previous run", func() {
+		BeforeEach(func() {
+			alertmanager.silences = []fakeAlertmanagerSilence{
+				{
+					ID:        "fake-previous-silence",
+					Matchers:  []map[string]interface{}{{"name": "bosh_deployment", "value": "fake-deployment-2", "isRegex": false, "isEqual": true}},
+					EndsAt:    now.Add(30 * time.Minute),
+					CreatedBy: "bosh_exporter/fake-bosh-uuid",
+					Status:    map[string]string{"state": "active"},
+				},
+				{
+					ID:        "fake-other-silence",
+					Matchers:  []map[string]interface{}{{"name": "bosh_deployment", "value": "fake-deployment-2", "isRegex": false, "isEqual": true}},
+					EndsAt:    now.Add(30 * time.Minute),
+					CreatedBy: "fake-operator",
+					Status:    map[string]string{"state": "active"},
+				},
+			}
+		})
+
+		It("expires the ones whose task is over", func() {
+			Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).To(Succeed())
+			Expect(alertmanager.expired).To(Equal([]string{"fake-previous-silence"}))
+		})
+	})
+
+	Context("when Alertmanager fails", func() {
+		BeforeEach(func() {
+			alertmanager.statusCode = http.StatusInternalServerError
+		})
+
+		It("returns an error", func() {
+			Expect(alertmanagerSilencer.ObserveDeployments(deploymentsInfo)).ToNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,184 @@
+package publishers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+)
+
+const (
+	auditDeploymentAdded      = "deployment_added"
+	auditDeploymentRemoved    = "deployment_removed"
+	auditInstanceAdded        = "instance_added"
+	auditInstanceRemoved      = "instance_removed"
+	auditInstanceStateChanged = "instance_state_changed"
+)
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Event         string    `json:"event"`
+	Deployment    string    `json:"deployment"`
+	Instance      string    `json:"instance,omitempty"`
+	State         string    `json:"state,omitempty"`
+	PreviousState string    `json:"previous_state,omitempty"`
+}
+
+// AuditLog appends to a JSON lines file the deployments and instances
+// added and removed, and the instances state changes, observed between two
+// collections. The file is opened for each write, so it can be rotated.
+type AuditLog struct {
+	filename    string
+	now         func() time.Time
+	mutex       sync.Mutex
+	initialized bool
+	deployments map[string]map[string]string
+}
+
+// NewAuditLog returns an audit log appending to the file, created if needed.
+func NewAuditLog(filename string) *AuditLog {
+	return &AuditLog{
+		filename:    filename,
+		now:         time.Now,
+		deployments: map[string]map[string]string{},
+	}
+}
+
+// ObserveDeployments appends the changes since the previous call to the
+// audit log. The first call only records the deployments. When the write
+// fails, the changes are written again, merged with the next ones.
+func (l *AuditLog) ObserveDeployments(deploymentsInfo []deployments.DeploymentInfo) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	observed := map[string]map[string]string{}
+	for _, deployment := range deploymentsInfo {
+		// The state of instances read in short format is unknown, so the
+		// deployment is assumed unchanged until read again in full format.
+		if previous, ok := l.deployments[deployment.Name]; ok && deployment.InstancesShortFormat {
+			observed[deployment.Name] = previous
+			continue
+		}
+
+		instances := map[string]string{}
+		for _, instance := range deployment.Instances {
+			instances[instance.Name+"/"+instance.ID] = instance.State
+		}
+		observed[deployment.Name] = instances
+	}
+
+	if !l.initialized {
+		l.initialized = true
+		l.deployments = observed
+		return nil
+	}
+
+	entries := l.diff(l.deployments, observed, l.now().UTC())
+	if len(entries) == 0 {
+		l.deployments = observed
+		return nil
+	}
+
+	if err := l.write(entries); err != nil {
+		return err
+	}
+	l.deployments = observed
+
+	return nil
+}
+
+func (l *AuditLog) diff(previous map[string]map[string]string, observed map[string]map[string]string, timestamp time.Time) []auditEntry {
+	entries := []auditEntry{}
+
+	for _, deploymentName := range auditDeploymentsNames(previous, observed) {
+		previousInstances, wasDeployed := previous[deploymentName]
+		instances, deployed := observed[deploymentName]
+
+		switch {
+		case !wasDeployed:
+			entries = append(entries, auditEntry{Timestamp: timestamp, Event: auditDeploymentAdded, Deployment: deploymentName})
+		case !deployed:
+			entries = append(entries, auditEntry{Timestamp: timestamp, Event: auditDeploymentRemoved, Deployment: deploymentName})
+		}
+
+		for _, name := range auditInstancesNames(previousInstances, instances) {
+			previousState, existed := previousInstances[name]
+			state, exists := instances[name]
+
+			switch {
+			case !existed:
+				entries = append(entries, auditEntry{Timestamp: timestamp, Event: auditInstanceAdded, Deployment: deploymentName, Instance: name, State: state})
+			case !exists:
+				entries = append(entries, auditEntry{Timestamp: timestamp, Event: auditInstanceRemoved, Deployment: deploymentName, Instance: name, PreviousState: previousState})
+			case previousState != state:
+				entries = append(entries, auditEntry{Timestamp: timestamp, Event: auditInstanceStateChanged, Deployment: deploymentName, Instance: name, State: state, PreviousState: previousState})
+			}
+		}
+	}
+
+	return entries
+}
+
+func (l *AuditLog) write(entries []auditEntry) error {
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return errors.New(fmt.Sprintf("Error marshalling audit log entry: %v", err))
+		}
+	}
+
+	f, err := os.OpenFile(l.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error opening audit log `%s`: %v", l.filename, err))
+	}
+
+	_, err = f.Write(lines.Bytes())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error writing audit log `%s`: %v", l.filename, err))
+	}
+
+	return nil
+}
+
+func auditDeploymentsNames(a map[string]map[string]string, b map[string]map[string]string) []string {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return auditSortedNames(names)
+}
+
+func auditInstancesNames(a map[string]string, b map[string]string) []string {
+	names := map[string]bool{}
+	for name := range a {
+		names[name] = true
+	}
+	for name := range b {
+		names[name] = true
+	}
+	return auditSortedNames(names)
+}
+
+func auditSortedNames(names map[string]bool) []string {
+	sortedNames := []string{}
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	return sortedNames
+}
@@ -0,0 +1,158 @@
+package publishers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-community/bosh_exporter/deployments"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeAuditEntry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Event         string    `json:"event"`
+	Deployment    string    `json:"deployment"`
+	Instance      string    `json:"instance"`
+	State         string    `json:"state"`
+	PreviousState string    `json:"previous_state"`
+}
+
+var _ = Describe("AuditLog", func() {
+	var (
+		err       error
+		directory string
+		filename  string
+		now       time.Time
+
+		auditLog *AuditLog
+	)
+
+	deployment := func(name string, states ...string) deployments.DeploymentInfo {
+		instances := []deployments.Instance{}
+		for i, state := range states {
+			instances = append(instances, deployments.Instance{Name: "fake-job", ID: string(rune('a' + i)), State: state})
+		}
+		return deployments.DeploymentInfo{Name: name, Instances: instances}
+	}
+
+	readEntries := func() []fakeAuditEntry {
+		content, err := ioutil.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+
+		entries := []fakeAuditEntry{}
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			entry := fakeAuditEntry{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	BeforeEach(func() {
+		directory, err = ioutil.TempDir("", "audit_log")
+		Expect(err).ToNot(HaveOccurred())
+		filename = filepath.Join(directory, "audit.log")
+		now = time.Date(2017, time.July, 14, 2, 40, 0, 0, time.UTC)
+
+		auditLog = NewAuditLog(filename)
+		auditLog.SetNow(func() time.Time { return now })
+
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "running")})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(directory)
+	})
+
+	It("does not write the first observed deployments", func() {
+		_, err = os.Stat(filename)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("does not write when nothing changed", func() {
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "running")})).To(Succeed())
+
+		_, err = os.Stat(filename)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("writes the added deployments and instances", func() {
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{
+			deployment("fake-deployment-1", "running", "running"),
+			deployment("fake-deployment-2", "starting"),
+		})).To(Succeed())
+
+		Expect(readEntries()).To(Equal([]fakeAuditEntry{
+			{Timestamp: now, Event: "deployment_added", Deployment: "fake-deployment-2"},
+			{Timestamp: now, Event: "instance_added", Deployment: "fake-deployment-2", Instance: "fake-job/a", State: "starting"},
+		}))
+	})
+
+	It("writes the removed deployments and instances", func() {
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{})).To(Succeed())
+
+		Expect(readEntries()).To(Equal([]fakeAuditEntry{
+			{Timestamp: now, Event: "deployment_removed", Deployment: "fake-deployment-1"},
+			{Timestamp: now, Event: "instance_removed", Deployment: "fake-deployment-1", Instance: "fake-job/a", PreviousState: "running"},
+			{Timestamp: now, Event: "instance_removed", Deployment: "fake-deployment-1", Instance: "fake-job/b", PreviousState: "running"},
+		}))
+	})
+
+	It("writes the instances state changes", func() {
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "failing")})).To(Succeed())
+
+		Expect(readEntries()).To(Equal([]fakeAuditEntry{
+			{Timestamp: now, Event: "instance_state_changed", Deployment: "fake-deployment-1", Instance: "fake-job/b", State: "failing", PreviousState: "running"},
+		}))
+	})
+
+	It("appends the changes of the successive observations", func() {
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "failing")})).To(Succeed())
+		now = now.Add(time.Minute)
+		Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "running")})).To(Succeed())
+
+		Expect(readEntries()).To(Equal([]fakeAuditEntry{
+			{Timestamp: now.Add(-time.Minute), Event: "instance_state_changed", Deployment: "fake-deployment-1", Instance: "fake-job/b", State: "failing", PreviousState: "running"},
+			{Timestamp: now, Event: "instance_state_changed", Deployment: "fake-deployment-1", Instance: "fake-job/b", State: "running", PreviousState: "failing"},
+		}))
+	})
+
+	Context("when the instances are read in short format", func() {
+		It("does not write the instances state changes", func() {
+			shortFormatDeployment := deployment("fake-deployment-1", "", "")
+			shortFormatDeployment.InstancesShortFormat = true
+			Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{shortFormatDeployment})).To(Succeed())
+			Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "failing")})).To(Succeed())
+
+			Expect(readEntries()).To(Equal([]fakeAuditEntry{
+				{Timestamp: now, Event: "instance_state_changed", Deployment: "fake-deployment-1", Instance: "fake-job/b", State: "failing", PreviousState: "running"},
+			}))
+		})
+	})
+
+	Context("when writing fails", func() {
+		BeforeEach(func() {
+			Expect(os.Mkdir(filename, 0755)).To(Succeed())
+		})
+
+		It("returns an error and writes the changes on the next observation", func() {
+			Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "failing")})).ToNot(Succeed())
+
+			Expect(os.Remove(filename)).To(Succeed())
+			Expect(auditLog.ObserveDeployments([]deployments.DeploymentInfo{deployment("fake-deployment-1", "running", "failing", "starting")})).To(Succeed())
+
+			Expect(readEntries()).To(Equal([]fakeAuditEntry{
+				{Timestamp: now, Event: "instance_state_changed", Deployment: "fake-deployment-1", Instance: "fake-job/b", State: "failing", PreviousState: "running"},
+				{Timestamp: now, Event: "instance_added", Deployment: "fake-deployment-1", Instance: "fake-job/c", State: "starting"},
+			}))
+		})
+	})
+})
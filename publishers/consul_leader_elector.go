@@ -0,0 +1,135 @@
+package publishers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/log"
+)
+
+type consulSessionRequest struct {
+	Name      string `json:"Name"`
+	TTL       string `json:"TTL"`
+	Behavior  string `json:"Behavior"`
+	LockDelay string `json:"LockDelay"`
+}
+
+type consulSessionResponse struct {
+	ID string `json:"ID"`
+}
+
+// ConsulLeaderElector elects a leader among the exporter replicas by
+// acquiring a Consul KV lock, so only one replica writes the Service
+// Discovery targets.
+type ConsulLeaderElector struct {
+	consulURL   string
+	consulToken string
+	key         string
+	ttl         time.Duration
+	httpClient  *http.Client
+	sessionID   string
+	leader      bool
+	mu          *sync.Mutex
+}
+
+func NewConsulLeaderElector(
+	consulURL string,
+	consulToken string,
+	key string,
+	ttl time.Duration,
+	httpClient *http.Client,
+) *ConsulLeaderElector {
+	return &ConsulLeaderElector{
+		consulURL:   strings.TrimSuffix(consulURL, "/"),
+		consulToken: consulToken,
+		key:         strings.TrimPrefix(key, "/"),
+		ttl:         ttl,
+		httpClient:  httpClient,
+		mu:          &sync.Mutex{},
+	}
+}
+
+// IsLeader renews the replica Consul session (creating a new one if it has
+// expired) and tries to acquire the lock. The lock is released by Consul when
+// the session is not renewed within its TTL.
+func (e *ConsulLeaderElector) IsLeader() (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.sessionID != "" {
+		if _, err := e.doRequest("PUT", "/v1/session/renew/"+e.sessionID, nil); err != nil {
+			log.Debugf("Consul session `%s` could not be renewed: %v", e.sessionID, err)
+			e.sessionID = ""
+		}
+	}
+
+	if e.sessionID == "" {
+		sessionID, err := e.createSession()
+		if err != nil {
+			e.setLeader(false)
+			return false, err
+		}
+		e.sessionID = sessionID
+	}
+
+	hostname, _ := os.Hostname()
+	response, err := e.doRequest("PUT", "/v1/kv/"+e.key+"?acquire="+url.QueryEscape(e.sessionID), []byte(hostname))
+	if err != nil {
+		e.setLeader(false)
+		return false, err
+	}
+
+	e.setLeader(strings.TrimSpace(string(response)) == "true")
+	return e.leader, nil
+}
+
+func (e *ConsulLeaderElector) setLeader(leader bool) {
+	if leader != e.leader {
+		if leader {
+			log.Infof("Acquired Consul lock `%s`, this replica is now the Service Discovery leader", e.key)
+		} else {
+			log.Infof("Lost Consul lock `%s`, this replica is no longer the Service Discovery leader", e.key)
+		}
+	}
+	e.leader = leader
+}
+
+func (e *ConsulLeaderElector) createSession() (string, error) {
+	request, err := json.Marshal(consulSessionRequest{
+		Name:      "bosh_exporter",
+		TTL:       e.ttl.String(),
+		Behavior:  "release",
+		LockDelay: "0s",
+	})
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error encoding Consul session: %v", err))
+	}
+
+	response, err := e.doRequest("PUT", "/v1/session/create", request)
+	if err != nil {
+		return "", err
+	}
+
+	var session consulSessionResponse
+	if err = json.Unmarshal(response, &session); err != nil {
+		return "", errors.New(fmt.Sprintf("Error decoding Consul session: %v", err))
+	}
+
+	return session.ID, nil
+}
+
+func (e *ConsulLeaderElector) doRequest(method string, path string, body []byte) ([]byte, error) {
+	headers := map[string]string{}
+	if e.consulToken != "" {
+		headers[consulTokenHeader] = e.consulToken
+	}
+
+	return doHTTPRequest(e.httpClient, "Consul", method, e.consulURL+path, headers, body)
+}
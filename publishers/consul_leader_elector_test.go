@@ -0,0 +1,140 @@
+package publishers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeConsulLocks struct {
+	mu       sync.Mutex
+	sessions map[string]bool
+	created  []map[string]interface{}
+	renewed  []string
+	holder   string
+	tokens   []string
+	nextID   int
+}
+
+func (c *fakeConsulLocks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens = append(c.tokens, r.Header.Get("X-Consul-Token"))
+	switch {
+	case r.Method == "PUT" && r.URL.Path == "/v1/session/create":
+		body, _ := ioutil.ReadAll(r.Body)
+		session := map[string]interface{}{}
+		json.Unmarshal(body, &session)
+		c.created = append(c.created, session)
+		c.nextID++
+		id := fmt.Sprintf("fake-session-%d", c.nextID)
+		c.sessions[id] = true
+		json.NewEncoder(w).Encode(map[string]string{"ID": id})
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/session/renew/")
+		if !c.sessions[id] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		c.renewed = append(c.renewed, id)
+	case r.Method == "PUT" && r.URL.Path == "/v1/kv/fake/leader":
+		session := r.URL.Query().Get("acquire")
+		if c.holder == "" || c.holder == session {
+			c.holder = session
+			w.Write([]byte("true"))
+		} else {
+			w.Write([]byte("false"))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("ConsulLeaderElector", func() {
+	var (
+		err          error
+		leader       bool
+		consulLocks  *fakeConsulLocks
+		consulServer *httptest.Server
+
+		consulLeaderElector *ConsulLeaderElector
+	)
+
+	BeforeEach(func() {
+		consulLocks = &fakeConsulLocks{sessions: map[string]bool{}}
+		consulServer = httptest.NewServer(consulLocks)
+	})
+
+	AfterEach(func() {
+		consulServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		consulLeaderElector = NewConsulLeaderElector(consulServer.URL, "fake-token", "/fake/leader", 30*time.Second, &http.Client{Timeout: 5 * time.Second})
+		leader, err = consulLeaderElector.IsLeader()
+	})
+
+	It("creates a session and acquires the lock", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(leader).To(BeTrue())
+		Expect(consulLocks.created).To(HaveLen(1))
+		Expect(consulLocks.created[0]).To(HaveKeyWithValue("TTL", "30s"))
+		Expect(consulLocks.created[0]).To(HaveKeyWithValue("Behavior", "release"))
+		Expect(consulLocks.tokens).To(ConsistOf("fake-token", "fake-token"))
+	})
+
+	It("renews the session on the next election", func() {
+		leader, err = consulLeaderElector.IsLeader()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(leader).To(BeTrue())
+		Expect(consulLocks.created).To(HaveLen(1))
+		Expect(consulLocks.renewed).To(Equal([]string{"fake-session-1"}))
+	})
+
+	Context("when the session has expired", func() {
+		It("creates a new session", func() {
+			consulLocks.mu.Lock()
+			consulLocks.sessions = map[string]bool{}
+			consulLocks.holder = ""
+			consulLocks.mu.Unlock()
+
+			leader, err = consulLeaderElector.IsLeader()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(leader).To(BeTrue())
+			Expect(consulLocks.created).To(HaveLen(2))
+		})
+	})
+
+	Context("when another replica holds the lock", func() {
+		BeforeEach(func() {
+			consulLocks.holder = "fake-other-session"
+		})
+
+		It("is not the leader", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(leader).To(BeFalse())
+		})
+	})
+
+	Context("when Consul fails", func() {
+		BeforeEach(func() {
+			consulServer.Close()
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(leader).To(BeFalse())
+		})
+	})
+})
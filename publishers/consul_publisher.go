@@ -0,0 +1,216 @@
+package publishers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+const (
+	consulTokenHeader  = "X-Consul-Token"
+	consulServiceLabel = model.MetaLabelPrefix + "bosh_job_process_name"
+)
+
+type consulService struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+type consulServiceRegistration struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+}
+
+type ConsulPublisher struct {
+	consulURL       string
+	consulToken     string
+	serviceIDPrefix string
+	httpClient      *http.Client
+}
+
+func NewConsulPublisher(
+	consulURL string,
+	consulToken string,
+	boshUUID string,
+	httpClient *http.Client,
+) *ConsulPublisher {
+	return &ConsulPublisher{
+		consulURL:       strings.TrimSuffix(consulURL, "/"),
+		consulToken:     consulToken,
+		serviceIDPrefix: "bosh-" + boshUUID + "-",
+		httpClient:      httpClient,
+	}
+}
+
+func (p *ConsulPublisher) Publish(targetGroups collectors.TargetGroups) error {
+	registeredServices, err := p.registeredServices()
+	if err != nil {
+		return err
+	}
+
+	services := p.createServices(targetGroups)
+	for serviceID, service := range services {
+		if registeredService, ok := registeredServices[serviceID]; ok && p.sameService(registeredService, service) {
+			continue
+		}
+
+		if err = p.registerService(service); err != nil {
+			return err
+		}
+	}
+
+	for serviceID := range registeredServices {
+		if _, ok := services[serviceID]; ok {
+			continue
+		}
+
+		if err = p.deregisterService(serviceID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *ConsulPublisher) createServices(targetGroups collectors.TargetGroups) map[string]consulService {
+	services := map[string]consulService{}
+
+	for _, targetGroup := range targetGroups {
+		for _, target := range targetGroup.Targets {
+			address, port := p.splitTarget(target)
+
+			service := consulService{
+				ID:      p.serviceID(targetGroup.Labels, target),
+				Service: string(targetGroup.Labels[consulServiceLabel]),
+				Tags:    p.serviceTags(targetGroup.Labels),
+				Address: address,
+				Port:    port,
+				Meta:    p.serviceMeta(targetGroup.Labels),
+			}
+			services[service.ID] = service
+		}
+	}
+
+	return services
+}
+
+// serviceID identifies the service by its target, as a target group may hold
+// several targets. Targets without a port may be shared by several processes,
+// so the process name is part of the ID too.
+func (p *ConsulPublisher) serviceID(labels model.LabelSet, target string) string {
+	return p.serviceIDPrefix + string(labels[consulServiceLabel]) + "-" + target
+}
+
+func (p *ConsulPublisher) serviceTags(labels model.LabelSet) []string {
+	tags := []string{}
+	for label, value := range labels {
+		tags = append(tags, strings.TrimPrefix(string(label), model.MetaLabelPrefix)+"="+string(value))
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+func (p *ConsulPublisher) serviceMeta(labels model.LabelSet) map[string]string {
+	meta := map[string]string{}
+	for label, value := range labels {
+		meta[strings.TrimPrefix(string(label), model.MetaLabelPrefix)] = string(value)
+	}
+
+	return meta
+}
+
+func (p *ConsulPublisher) splitTarget(target string) (string, int) {
+	host, portValue, err := net.SplitHostPort(target)
+	if err != nil {
+		return strings.Trim(target, "[]"), 0
+	}
+
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		return host, 0
+	}
+
+	return host, port
+}
+
+func (p *ConsulPublisher) sameService(registeredService consulService, service consulService) bool {
+	if len(registeredService.Meta) == 0 && len(service.Meta) == 0 {
+		registeredService.Meta = service.Meta
+	}
+
+	return reflect.DeepEqual(registeredService, service)
+}
+
+func (p *ConsulPublisher) registeredServices() (map[string]consulService, error) {
+	body, err := p.doRequest("GET", "/v1/agent/services", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	agentServices := map[string]consulService{}
+	if err = json.Unmarshal(body, &agentServices); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling Consul services: %v", err))
+	}
+
+	registeredServices := map[string]consulService{}
+	for serviceID, service := range agentServices {
+		if !strings.HasPrefix(serviceID, p.serviceIDPrefix) {
+			continue
+		}
+		sort.Strings(service.Tags)
+		registeredServices[serviceID] = service
+	}
+
+	return registeredServices, nil
+}
+
+func (p *ConsulPublisher) registerService(service consulService) error {
+	registration, err := json.Marshal(consulServiceRegistration{
+		ID:      service.ID,
+		Name:    service.Service,
+		Tags:    service.Tags,
+		Address: service.Address,
+		Port:    service.Port,
+		Meta:    service.Meta,
+	})
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error marshalling Consul service `%s`: %v", service.ID, err))
+	}
+
+	_, err = p.doRequest("PUT", "/v1/agent/service/register", registration)
+	return err
+}
+
+func (p *ConsulPublisher) deregisterService(serviceID string) error {
+	_, err := p.doRequest("PUT", "/v1/agent/service/deregister/"+url.PathEscape(serviceID), nil)
+	return err
+}
+
+func (p *ConsulPublisher) doRequest(method string, path string, body []byte) ([]byte, error) {
+	headers := map[string]string{}
+	if p.consulToken != "" {
+		headers[consulTokenHeader] = p.consulToken
+	}
+
+	return doHTTPRequest(p.httpClient, "Consul", method, p.consulURL+path, headers, body)
+}
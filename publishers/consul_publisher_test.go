@@ -0,0 +1,225 @@
+package publishers_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeConsulAgent struct {
+	mu           sync.Mutex
+	services     map[string]map[string]interface{}
+	registered   []map[string]interface{}
+	deregistered []string
+	tokens       []string
+	statusCode   int
+}
+
+func (a *fakeConsulAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tokens = append(a.tokens, r.Header.Get("X-Consul-Token"))
+	if a.statusCode != 0 {
+		w.WriteHeader(a.statusCode)
+		w.Write([]byte("fake-consul-error"))
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/agent/services":
+		json.NewEncoder(w).Encode(a.services)
+	case r.Method == "PUT" && r.URL.Path == "/v1/agent/service/register":
+		body, _ := ioutil.ReadAll(r.Body)
+		registration := map[string]interface{}{}
+		json.Unmarshal(body, &registration)
+		a.registered = append(a.registered, registration)
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/v1/agent/service/deregister/"):
+		a.deregistered = append(a.deregistered, strings.TrimPrefix(r.URL.Path, "/v1/agent/service/deregister/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("ConsulPublisher", func() {
+	var (
+		err          error
+		consulAgent  *fakeConsulAgent
+		consulServer *httptest.Server
+		consulToken  string
+		targetGroups collectors.TargetGroups
+
+		consulPublisher *ConsulPublisher
+
+		serviceID = "bosh-fake-bosh-uuid-fake-process-name-1.2.3.4:9100"
+		labels    = model.LabelSet{
+			model.LabelName("__meta_bosh_deployment"):       model.LabelValue("fake-deployment-name"),
+			model.LabelName("__meta_bosh_job_az"):           model.LabelValue("fake-job-az"),
+			model.LabelName("__meta_bosh_job_id"):           model.LabelValue("fake-job-id"),
+			model.LabelName("__meta_bosh_job_ip_index"):     model.LabelValue("0"),
+			model.LabelName("__meta_bosh_job_process_name"): model.LabelValue("fake-process-name"),
+		}
+		tags = []interface{}{
+			"bosh_deployment=fake-deployment-name",
+			"bosh_job_az=fake-job-az",
+			"bosh_job_id=fake-job-id",
+			"bosh_job_ip_index=0",
+			"bosh_job_process_name=fake-process-name",
+		}
+		meta = map[string]interface{}{
+			"bosh_deployment":       "fake-deployment-name",
+			"bosh_job_az":           "fake-job-az",
+			"bosh_job_id":           "fake-job-id",
+			"bosh_job_ip_index":     "0",
+			"bosh_job_process_name": "fake-process-name",
+		}
+	)
+
+	BeforeEach(func() {
+		consulAgent = &fakeConsulAgent{services: map[string]map[string]interface{}{}}
+		consulServer = httptest.NewServer(consulAgent)
+		consulToken = "fake-consul-token"
+		targetGroups = collectors.TargetGroups{
+			{
+				Targets: []string{"1.2.3.4:9100"},
+				Labels:  labels,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		consulServer.Close()
+	})
+
+	JustBeforeEach(func() {
+		consulPublisher = NewConsulPublisher(consulServer.URL+"/", consulToken, "fake-bosh-uuid", &http.Client{})
+		err = consulPublisher.Publish(targetGroups)
+	})
+
+	It("registers the targets as Consul services", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(consulAgent.registered).To(Equal([]map[string]interface{}{
+			{
+				"ID":      serviceID,
+				"Name":    "fake-process-name",
+				"Tags":    tags,
+				"Address": "1.2.3.4",
+				"Port":    float64(9100),
+				"Meta":    meta,
+			},
+		}))
+		Expect(consulAgent.deregistered).To(BeEmpty())
+	})
+
+	It("sends the Consul token", func() {
+		Expect(consulAgent.tokens).ToNot(BeEmpty())
+		for _, token := range consulAgent.tokens {
+			Expect(token).To(Equal(consulToken))
+		}
+	})
+
+	Context("when the target has no port", func() {
+		BeforeEach(func() {
+			targetGroups[0].Targets = []string{"[::1]"}
+		})
+
+		It("registers the service without a port", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consulAgent.registered).To(HaveLen(1))
+			Expect(consulAgent.registered[0]["Address"]).To(Equal("::1"))
+			Expect(consulAgent.registered[0]).ToNot(HaveKey("Port"))
+		})
+	})
+
+	Context("when the target group has several targets", func() {
+		BeforeEach(func() {
+			targetGroups[0].Targets = []string{"1.2.3.4:9100", "5.6.7.8:9100"}
+		})
+
+		It("registers a service per target", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consulAgent.registered).To(HaveLen(2))
+
+			ids := []interface{}{}
+			addresses := []interface{}{}
+			for _, registration := range consulAgent.registered {
+				ids = append(ids, registration["ID"])
+				addresses = append(addresses, registration["Address"])
+			}
+			Expect(ids).To(ConsistOf(serviceID, "bosh-fake-bosh-uuid-fake-process-name-5.6.7.8:9100"))
+			Expect(addresses).To(ConsistOf("1.2.3.4", "5.6.7.8"))
+		})
+	})
+
+	Context("when the service is already registered", func() {
+		BeforeEach(func() {
+			consulAgent.services[serviceID] = map[string]interface{}{
+				"ID":      serviceID,
+				"Service": "fake-process-name",
+				"Tags":    tags,
+				"Address": "1.2.3.4",
+				"Port":    9100,
+				"Meta":    meta,
+			}
+		})
+
+		It("does not register the service again", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consulAgent.registered).To(BeEmpty())
+			Expect(consulAgent.deregistered).To(BeEmpty())
+		})
+
+		Context("and it has changed", func() {
+			BeforeEach(func() {
+				consulAgent.services[serviceID]["Address"] = "5.6.7.8"
+			})
+
+			It("registers the service again", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(consulAgent.registered).To(HaveLen(1))
+				Expect(consulAgent.registered[0]["Address"]).To(Equal("1.2.3.4"))
+			})
+		})
+	})
+
+	Context("when a registered service is no longer discovered", func() {
+		BeforeEach(func() {
+			consulAgent.services["bosh-fake-bosh-uuid-fake-other-process-name-[::1]:9100"] = map[string]interface{}{
+				"ID":      "bosh-fake-bosh-uuid-fake-other-process-name-[::1]:9100",
+				"Service": "fake-other-process-name",
+			}
+			consulAgent.services["fake-unmanaged-service"] = map[string]interface{}{
+				"ID":      "fake-unmanaged-service",
+				"Service": "fake-unmanaged-service",
+			}
+		})
+
+		It("deregisters only the services registered by the exporter", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(consulAgent.deregistered).To(Equal([]string{"bosh-fake-bosh-uuid-fake-other-process-name-[::1]:9100"}))
+		})
+	})
+
+	Context("when Consul returns an error", func() {
+		BeforeEach(func() {
+			consulAgent.statusCode = http.StatusForbidden
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-consul-error"))
+		})
+	})
+})
@@ -0,0 +1,224 @@
+package publishers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+const (
+	etcdAuthorizationHeader = "Authorization"
+	etcdDeploymentLabel     = model.MetaLabelPrefix + "bosh_deployment"
+)
+
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+type etcdRangeRequest struct {
+	Key      string `json:"key"`
+	RangeEnd string `json:"range_end"`
+}
+
+type etcdRangeResponse struct {
+	Kvs []etcdKeyValue `json:"kvs"`
+}
+
+type etcdAuthenticateRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type etcdAuthenticateResponse struct {
+	Token string `json:"token"`
+}
+
+type EtcdPublisher struct {
+	etcdURL      string
+	etcdPrefix   string
+	etcdUsername string
+	etcdPassword string
+	httpClient   *http.Client
+}
+
+func NewEtcdPublisher(
+	etcdURL string,
+	etcdPrefix string,
+	etcdUsername string,
+	etcdPassword string,
+	httpClient *http.Client,
+) *EtcdPublisher {
+	return &EtcdPublisher{
+		etcdURL:      strings.TrimSuffix(etcdURL, "/"),
+		etcdPrefix:   strings.TrimSuffix(etcdPrefix, "/") + "/",
+		etcdUsername: etcdUsername,
+		etcdPassword: etcdPassword,
+		httpClient:   httpClient,
+	}
+}
+
+func (p *EtcdPublisher) Publish(targetGroups collectors.TargetGroups) error {
+	headers, err := p.authenticate()
+	if err != nil {
+		return err
+	}
+
+	storedValues, err := p.storedValues(headers)
+	if err != nil {
+		return err
+	}
+
+	values, err := p.createValues(targetGroups)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if storedValue, ok := storedValues[key]; ok && storedValue == values[key] {
+			continue
+		}
+
+		if err = p.putValue(headers, key, values[key]); err != nil {
+			return err
+		}
+	}
+
+	for key := range storedValues {
+		if _, ok := values[key]; ok {
+			continue
+		}
+
+		if err = p.deleteKey(headers, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *EtcdPublisher) createValues(targetGroups collectors.TargetGroups) (map[string]string, error) {
+	deploymentsTargetGroups := map[string]collectors.TargetGroups{}
+	for _, targetGroup := range targetGroups {
+		deploymentName := string(targetGroup.Labels[etcdDeploymentLabel])
+		deploymentsTargetGroups[deploymentName] = append(deploymentsTargetGroups[deploymentName], targetGroup)
+	}
+
+	values := map[string]string{}
+	for deploymentName, deploymentTargetGroups := range deploymentsTargetGroups {
+		value, err := json.Marshal(deploymentTargetGroups)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Error marshalling target groups for deployment `%s`: %v", deploymentName, err))
+		}
+		values[p.etcdPrefix+deploymentName] = string(value)
+	}
+
+	return values, nil
+}
+
+func (p *EtcdPublisher) authenticate() (map[string]string, error) {
+	headers := map[string]string{}
+	if p.etcdUsername == "" {
+		return headers, nil
+	}
+
+	body, err := p.doRequest(headers, "/v3/auth/authenticate", etcdAuthenticateRequest{Name: p.etcdUsername, Password: p.etcdPassword})
+	if err != nil {
+		return nil, err
+	}
+
+	var authenticateResponse etcdAuthenticateResponse
+	if err = json.Unmarshal(body, &authenticateResponse); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling etcd authentication response: %v", err))
+	}
+	headers[etcdAuthorizationHeader] = authenticateResponse.Token
+
+	return headers, nil
+}
+
+func (p *EtcdPublisher) storedValues(headers map[string]string) (map[string]string, error) {
+	rangeRequest := etcdRangeRequest{
+		Key:      p.encode(p.etcdPrefix),
+		RangeEnd: p.encode(p.prefixRangeEnd(p.etcdPrefix)),
+	}
+
+	body, err := p.doRequest(headers, "/v3/kv/range", rangeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var rangeResponse etcdRangeResponse
+	if err = json.Unmarshal(body, &rangeResponse); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error unmarshalling etcd range response: %v", err))
+	}
+
+	storedValues := map[string]string{}
+	for _, kv := range rangeResponse.Kvs {
+		key, err := p.decode(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.decode(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		storedValues[key] = value
+	}
+
+	return storedValues, nil
+}
+
+func (p *EtcdPublisher) putValue(headers map[string]string, key string, value string) error {
+	_, err := p.doRequest(headers, "/v3/kv/put", etcdKeyValue{Key: p.encode(key), Value: p.encode(value)})
+	return err
+}
+
+func (p *EtcdPublisher) deleteKey(headers map[string]string, key string) error {
+	_, err := p.doRequest(headers, "/v3/kv/deleterange", etcdKeyValue{Key: p.encode(key)})
+	return err
+}
+
+func (p *EtcdPublisher) prefixRangeEnd(prefix string) string {
+	rangeEnd := []byte(prefix)
+	rangeEnd[len(rangeEnd)-1]++
+
+	return string(rangeEnd)
+}
+
+func (p *EtcdPublisher) encode(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func (p *EtcdPublisher) decode(value string) (string, error) {
+	decodedValue, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error decoding etcd value `%s`: %v", value, err))
+	}
+
+	return string(decodedValue), nil
+}
+
+func (p *EtcdPublisher) doRequest(headers map[string]string, path string, request interface{}) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error marshalling etcd request `%s`: %v", path, err))
+	}
+
+	return doHTTPRequest(p.httpClient, "etcd", "POST", p.etcdURL+path, headers, body)
+}
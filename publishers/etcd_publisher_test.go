@@ -0,0 +1,187 @@
+package publishers_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeEtcdServer struct {
+	mu         sync.Mutex
+	values     map[string]string
+	puts       []string
+	deletes    []string
+	tokens     []string
+	statusCode int
+}
+
+func (s *fakeEtcdServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.statusCode != 0 {
+		w.WriteHeader(s.statusCode)
+		w.Write([]byte("fake-etcd-error"))
+		return
+	}
+
+	request := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&request)
+	decode := func(value string) string {
+		decodedValue, _ := base64.StdEncoding.DecodeString(value)
+		return string(decodedValue)
+	}
+	encode := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+
+	if r.URL.Path != "/v3/auth/authenticate" {
+		s.tokens = append(s.tokens, r.Header.Get("Authorization"))
+	}
+
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		if request["name"] != "fake-etcd-username" || request["password"] != "fake-etcd-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "fake-etcd-token"})
+	case "/v3/kv/range":
+		kvs := []map[string]string{}
+		for key, value := range s.values {
+			if key >= decode(request["key"]) && key < decode(request["range_end"]) {
+				kvs = append(kvs, map[string]string{"key": encode(key), "value": encode(value)})
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kvs": kvs})
+	case "/v3/kv/put":
+		s.values[decode(request["key"])] = decode(request["value"])
+		s.puts = append(s.puts, decode(request["key"]))
+		w.Write([]byte("{}"))
+	case "/v3/kv/deleterange":
+		delete(s.values, decode(request["key"]))
+		s.deletes = append(s.deletes, decode(request["key"]))
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("EtcdPublisher", func() {
+	var (
+		err          error
+		etcdServer   *fakeEtcdServer
+		server       *httptest.Server
+		etcdUsername string
+		etcdPassword string
+		targetGroups collectors.TargetGroups
+
+		etcdPublisher *EtcdPublisher
+
+		deploymentKey       = "/fake-prefix/fake-deployment-name"
+		targetGroupsContent = "[{\"targets\":[\"1.2.3.4\"],\"labels\":{\"__meta_bosh_deployment\":\"fake-deployment-name\",\"__meta_bosh_job_process_name\":\"fake-process-name\"}}]"
+	)
+
+	BeforeEach(func() {
+		etcdServer = &fakeEtcdServer{values: map[string]string{}}
+		server = httptest.NewServer(etcdServer)
+		etcdUsername = ""
+		etcdPassword = ""
+		targetGroups = collectors.TargetGroups{
+			{
+				Targets: []string{"1.2.3.4"},
+				Labels: model.LabelSet{
+					model.LabelName("__meta_bosh_deployment"):       model.LabelValue("fake-deployment-name"),
+					model.LabelName("__meta_bosh_job_process_name"): model.LabelValue("fake-process-name"),
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		etcdPublisher = NewEtcdPublisher(server.URL, "/fake-prefix", etcdUsername, etcdPassword, &http.Client{})
+		err = etcdPublisher.Publish(targetGroups)
+	})
+
+	It("puts the target groups of each deployment under the key prefix", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(etcdServer.values).To(Equal(map[string]string{deploymentKey: targetGroupsContent}))
+	})
+
+	Context("when the target groups have not changed", func() {
+		BeforeEach(func() {
+			etcdServer.values[deploymentKey] = targetGroupsContent
+		})
+
+		It("does not put the target groups again", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(etcdServer.puts).To(BeEmpty())
+		})
+	})
+
+	Context("when a stored deployment no longer exists", func() {
+		BeforeEach(func() {
+			etcdServer.values["/fake-prefix/fake-other-deployment-name"] = "[]"
+			etcdServer.values["/fake-other-prefix/fake-deployment-name"] = "[]"
+		})
+
+		It("deletes only the keys under the key prefix", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(etcdServer.deletes).To(Equal([]string{"/fake-prefix/fake-other-deployment-name"}))
+			Expect(etcdServer.values).To(HaveKey("/fake-other-prefix/fake-deployment-name"))
+		})
+	})
+
+	Context("when credentials are provided", func() {
+		BeforeEach(func() {
+			etcdUsername = "fake-etcd-username"
+			etcdPassword = "fake-etcd-password"
+		})
+
+		It("sends the authentication token", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(etcdServer.tokens).ToNot(BeEmpty())
+			for _, token := range etcdServer.tokens {
+				Expect(token).To(Equal("fake-etcd-token"))
+			}
+		})
+
+		Context("and they are not valid", func() {
+			BeforeEach(func() {
+				etcdPassword = "fake-wrong-password"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(etcdServer.puts).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when etcd returns an error", func() {
+		BeforeEach(func() {
+			etcdServer.statusCode = http.StatusInternalServerError
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(strings.Contains(err.Error(), "fake-etcd-error")).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,15 @@
+package publishers
+
+import (
+	"time"
+)
+
+// SetNow replaces the clock used to time the silences.
+func (s *AlertmanagerSilencer) SetNow(now func() time.Time) {
+	s.now = now
+}
+
+// SetNow replaces the clock used to timestamp the audit log entries.
+func (l *AuditLog) SetNow(now func() time.Time) {
+	l.now = now
+}
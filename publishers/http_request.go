@@ -0,0 +1,61 @@
+package publishers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// httpStatusError is returned when a request fails with a non 2xx status.
+type httpStatusError struct {
+	statusCode int
+	message    string
+}
+
+func (e *httpStatusError) Error() string {
+	return e.message
+}
+
+func doHTTPRequest(
+	httpClient *http.Client,
+	backend string,
+	method string,
+	url string,
+	headers map[string]string,
+	body []byte,
+) ([]byte, error) {
+	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error creating %s request `%s %s`: %v", backend, method, url, err))
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error sending %s request `%s %s`: %v", backend, method, url, err))
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading %s response `%s %s`: %v", backend, method, url, err))
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &httpStatusError{
+			statusCode: response.StatusCode,
+			message:    fmt.Sprintf("%s request `%s %s` failed with status %d: %s", backend, method, url, response.StatusCode, strings.TrimSpace(string(responseBody))),
+		}
+	}
+
+	return responseBody, nil
+}
@@ -0,0 +1,103 @@
+package publishers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	GraphiteLineFormat = "graphite"
+	InfluxLineFormat   = "influx"
+)
+
+var (
+	graphiteReplacer = strings.NewReplacer(";", "_", " ", "_", "=", "_", "~", "_")
+	influxReplacer   = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+type LinePublisher struct {
+	address string
+	format  string
+	timeout time.Duration
+}
+
+func NewLinePublisher(address string, format string, timeout time.Duration) (*LinePublisher, error) {
+	switch format {
+	case GraphiteLineFormat, InfluxLineFormat:
+	default:
+		return nil, errors.New(fmt.Sprintf("Line format `%s` is not supported", format))
+	}
+
+	return &LinePublisher{
+		address: address,
+		format:  format,
+		timeout: timeout,
+	}, nil
+}
+
+// Publish sends the metric families samples to the configured address using
+// either the Graphite plaintext or the InfluxDB line protocol.
+func (p *LinePublisher) Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error {
+	var lines bytes.Buffer
+	for _, s := range flattenMetricFamilies(metricFamilies, timestamp) {
+		if math.IsNaN(s.value) || math.IsInf(s.value, 0) {
+			continue
+		}
+
+		if p.format == GraphiteLineFormat {
+			lines.WriteString(graphiteLine(s))
+		} else {
+			lines.WriteString(influxLine(s))
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", p.address, p.timeout)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error connecting to %s `%s`: %v", p.format, p.address, err))
+	}
+	defer conn.Close()
+
+	if err = conn.SetWriteDeadline(time.Now().Add(p.timeout)); err != nil {
+		return errors.New(fmt.Sprintf("Error setting %s `%s` write deadline: %v", p.format, p.address, err))
+	}
+
+	if _, err = conn.Write(lines.Bytes()); err != nil {
+		return errors.New(fmt.Sprintf("Error writing to %s `%s`: %v", p.format, p.address, err))
+	}
+
+	return nil
+}
+
+// graphiteLine renders the sample as a tagged Graphite plaintext line.
+func graphiteLine(s sample) string {
+	line := graphiteReplacer.Replace(s.name())
+	for _, label := range s.labels {
+		if label.name == model.MetricNameLabel || label.value == "" {
+			continue
+		}
+		line += ";" + graphiteReplacer.Replace(label.name) + "=" + graphiteReplacer.Replace(label.value)
+	}
+
+	return fmt.Sprintf("%s %s %d\n", line, formatFloat(s.value), s.timestampMs/1000)
+}
+
+// influxLine renders the sample as an InfluxDB line protocol line.
+func influxLine(s sample) string {
+	line := influxReplacer.Replace(s.name())
+	for _, label := range s.labels {
+		if label.name == model.MetricNameLabel || label.value == "" {
+			continue
+		}
+		line += "," + influxReplacer.Replace(label.name) + "=" + influxReplacer.Replace(label.value)
+	}
+
+	return fmt.Sprintf("%s value=%s %d\n", line, formatFloat(s.value), s.timestampMs*int64(time.Millisecond))
+}
@@ -0,0 +1,109 @@
+package publishers_test
+
+import (
+	"io/ioutil"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+var _ = Describe("LinePublisher", func() {
+	var (
+		err            error
+		listener       net.Listener
+		address        string
+		received       chan string
+		format         string
+		metricFamilies []*dto.MetricFamily
+
+		linePublisher *LinePublisher
+
+		timestamp = time.Unix(1500000000, 0)
+	)
+
+	BeforeEach(func() {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		address = listener.Addr().String()
+
+		received = make(chan string, 1)
+		go func(listener net.Listener, received chan<- string) {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			content, _ := ioutil.ReadAll(conn)
+			received <- string(content)
+		}(listener, received)
+
+		format = GraphiteLineFormat
+		metricFamilies = []*dto.MetricFamily{
+			{
+				Name: proto.String("fake_gauge"),
+				Type: dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Label: []*dto.LabelPair{
+							{Name: proto.String("bosh_deployment"), Value: proto.String("fake deployment,name")},
+							{Name: proto.String("bosh_job_az"), Value: proto.String("")},
+						},
+						Gauge: &dto.Gauge{Value: proto.Float64(42)},
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	JustBeforeEach(func() {
+		linePublisher, err = NewLinePublisher(address, format, 5*time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		err = linePublisher.Publish(metricFamilies, timestamp)
+	})
+
+	Context("when the format is graphite", func() {
+		It("sends Graphite plaintext lines", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal("fake_gauge;bosh_deployment=fake_deployment,name 42 1500000000\n")))
+		})
+	})
+
+	Context("when the format is influx", func() {
+		BeforeEach(func() {
+			format = InfluxLineFormat
+		})
+
+		It("sends InfluxDB line protocol lines", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(received).Should(Receive(Equal(`fake_gauge,bosh_deployment=fake\ deployment\,name value=42 1500000000000000000` + "\n")))
+		})
+	})
+
+	Context("when the address is not reachable", func() {
+		BeforeEach(func() {
+			listener.Close()
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the format is not supported", func() {
+		It("returns an error", func() {
+			_, err = NewLinePublisher(address, "fake-format", 5*time.Second)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
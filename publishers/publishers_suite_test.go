@@ -0,0 +1,13 @@
+package publishers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPublishers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Publishers Suite")
+}
@@ -0,0 +1,48 @@
+package publishers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+type PushgatewayPublisher struct {
+	url        string
+	job        string
+	httpClient *http.Client
+}
+
+func NewPushgatewayPublisher(
+	url string,
+	job string,
+	httpClient *http.Client,
+) *PushgatewayPublisher {
+	return &PushgatewayPublisher{
+		url:        strings.TrimRight(url, "/"),
+		job:        job,
+		httpClient: httpClient,
+	}
+}
+
+// Publish replaces the metrics of the job grouping key at the Pushgateway
+// with the metric families. The Pushgateway does not accept client side
+// timestamps, so they are removed from the pushed metrics.
+func (p *PushgatewayPublisher) Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error {
+	body, err := encodeTextWithoutTimestamps(metricFamilies)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding Pushgateway metrics: %v", err))
+	}
+
+	headers := map[string]string{
+		"Content-Type": string(expfmt.FmtText),
+	}
+
+	_, err = doHTTPRequest(p.httpClient, "Pushgateway", "PUT", p.url+"/metrics/job/"+url.PathEscape(p.job), headers, body)
+	return err
+}
@@ -0,0 +1,90 @@
+package publishers_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+var _ = Describe("PushgatewayPublisher", func() {
+	var (
+		err            error
+		method         string
+		path           string
+		contentType    string
+		body           string
+		statusCode     int
+		server         *httptest.Server
+		metricFamilies []*dto.MetricFamily
+
+		pushgatewayPublisher *PushgatewayPublisher
+	)
+
+	BeforeEach(func() {
+		statusCode = http.StatusAccepted
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			content, _ := ioutil.ReadAll(r.Body)
+			method = r.Method
+			path = r.URL.EscapedPath()
+			contentType = r.Header.Get("Content-Type")
+			body = string(content)
+			w.WriteHeader(statusCode)
+		}))
+
+		metricFamilies = []*dto.MetricFamily{
+			{
+				Name: proto.String("fake_gauge"),
+				Help: proto.String("Fake gauge."),
+				Type: dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Label:       []*dto.LabelPair{{Name: proto.String("bosh_deployment"), Value: proto.String("fake-deployment-name")}},
+						Gauge:       &dto.Gauge{Value: proto.Float64(42)},
+						TimestampMs: proto.Int64(1500000000000),
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		pushgatewayPublisher = NewPushgatewayPublisher(server.URL+"/", "fake/job", &http.Client{Timeout: 5 * time.Second})
+		err = pushgatewayPublisher.Publish(metricFamilies, time.Now())
+	})
+
+	It("pushes the metrics to the job grouping key", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(method).To(Equal("PUT"))
+		Expect(path).To(Equal("/metrics/job/fake%2Fjob"))
+		Expect(contentType).To(Equal("text/plain; version=0.0.4"))
+		Expect(body).To(ContainSubstring("# TYPE fake_gauge gauge\n"))
+		Expect(body).To(ContainSubstring("fake_gauge{bosh_deployment=\"fake-deployment-name\"} 42\n"))
+	})
+
+	It("does not modify the metric families", func() {
+		Expect(metricFamilies[0].Metric[0].GetTimestampMs()).To(Equal(int64(1500000000000)))
+	})
+
+	Context("when the Pushgateway fails", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusBadRequest
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package publishers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const remoteWriteVersion = "0.1.0"
+
+type RemoteWritePublisher struct {
+	url         string
+	username    string
+	password    string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+func NewRemoteWritePublisher(
+	url string,
+	username string,
+	password string,
+	bearerToken string,
+	httpClient *http.Client,
+) *RemoteWritePublisher {
+	return &RemoteWritePublisher{
+		url:         url,
+		username:    username,
+		password:    password,
+		bearerToken: bearerToken,
+		httpClient:  httpClient,
+	}
+}
+
+// Publish pushes the metric families samples to the remote write endpoint.
+// Samples without an explicit timestamp are stamped with the given time.
+func (p *RemoteWritePublisher) Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error {
+	body, err := p.encode(metricFamilies, timestamp)
+	if err != nil {
+		return err
+	}
+
+	return p.send(body)
+}
+
+// encode returns the compressed remote write request body of the metric
+// families samples.
+func (p *RemoteWritePublisher) encode(metricFamilies []*dto.MetricFamily, timestamp time.Time) ([]byte, error) {
+	series := flattenMetricFamilies(metricFamilies, timestamp)
+
+	body, err := encodeWriteRequest(series)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error encoding remote write request: %v", err))
+	}
+
+	return snappy.Encode(nil, body), nil
+}
+
+// send pushes a compressed remote write request body to the remote write
+// endpoint.
+func (p *RemoteWritePublisher) send(body []byte) error {
+	headers := map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": remoteWriteVersion,
+	}
+	if p.bearerToken != "" {
+		headers["Authorization"] = "Bearer " + p.bearerToken
+	} else if p.username != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(p.username+":"+p.password))
+	}
+
+	_, err := doHTTPRequest(p.httpClient, "Remote Write", "POST", p.url, headers, body)
+	return err
+}
+
+// encodeWriteRequest encodes the series as a remote write `WriteRequest`
+// protobuf message.
+func encodeWriteRequest(series []sample) ([]byte, error) {
+	writeRequest := prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+	for _, s := range series {
+		labels := make([]prompb.Label, 0, len(s.labels))
+		for _, label := range s.labels {
+			labels = append(labels, prompb.Label{Name: label.name, Value: label.value})
+		}
+
+		writeRequest.Timeseries = append(writeRequest.Timeseries, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: s.value, Timestamp: s.timestampMs}},
+		})
+	}
+
+	return writeRequest.Marshal()
+}
@@ -0,0 +1,208 @@
+package publishers_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeRemoteWriteRequest struct {
+	headers http.Header
+	body    []byte
+}
+
+type fakeRemoteWriteSample struct {
+	labels      map[string]string
+	value       float64
+	timestampMs int64
+}
+
+func decodeWriteRequest(body []byte) []fakeRemoteWriteSample {
+	data, err := snappy.Decode(nil, body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var writeRequest prompb.WriteRequest
+	Expect(writeRequest.Unmarshal(data)).To(Succeed())
+
+	samples := []fakeRemoteWriteSample{}
+	for _, timeSeries := range writeRequest.Timeseries {
+		labels := map[string]string{}
+		for _, label := range timeSeries.Labels {
+			labels[label.Name] = label.Value
+		}
+
+		Expect(timeSeries.Samples).To(HaveLen(1))
+		samples = append(samples, fakeRemoteWriteSample{
+			labels:      labels,
+			value:       timeSeries.Samples[0].Value,
+			timestampMs: timeSeries.Samples[0].Timestamp,
+		})
+	}
+
+	return samples
+}
+
+var _ = Describe("RemoteWritePublisher", func() {
+	var (
+		err            error
+		requests       []fakeRemoteWriteRequest
+		statusCode     int
+		server         *httptest.Server
+		username       string
+		password       string
+		bearerToken    string
+		metricFamilies []*dto.MetricFamily
+
+		remoteWritePublisher *RemoteWritePublisher
+
+		timestamp       = time.Unix(1500000000, 0)
+		metricTimestamp = int64(1400000000000)
+	)
+
+	BeforeEach(func() {
+		requests = []fakeRemoteWriteRequest{}
+		statusCode = http.StatusNoContent
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, fakeRemoteWriteRequest{headers: r.Header, body: body})
+			w.WriteHeader(statusCode)
+		}))
+		username = ""
+		password = ""
+		bearerToken = ""
+
+		metricFamilies = []*dto.MetricFamily{
+			{
+				Name: proto.String("fake_gauge"),
+				Type: dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Label: []*dto.LabelPair{{Name: proto.String("bosh_deployment"), Value: proto.String("fake-deployment-name")}},
+						Gauge: &dto.Gauge{Value: proto.Float64(42)},
+					},
+				},
+			},
+			{
+				Name: proto.String("fake_counter"),
+				Type: dto.MetricType_COUNTER.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Counter:     &dto.Counter{Value: proto.Float64(3)},
+						TimestampMs: proto.Int64(metricTimestamp),
+					},
+				},
+			},
+			{
+				Name: proto.String("fake_histogram"),
+				Type: dto.MetricType_HISTOGRAM.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Histogram: &dto.Histogram{
+							SampleCount: proto.Uint64(2),
+							SampleSum:   proto.Float64(90),
+							Bucket:      []*dto.Bucket{{UpperBound: proto.Float64(60), CumulativeCount: proto.Uint64(1)}},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		remoteWritePublisher = NewRemoteWritePublisher(
+			server.URL+"/api/v1/write",
+			username,
+			password,
+			bearerToken,
+			&http.Client{Timeout: 5 * time.Second},
+		)
+		err = remoteWritePublisher.Publish(metricFamilies, timestamp)
+	})
+
+	It("pushes a snappy compressed protobuf write request", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].headers.Get("Content-Encoding")).To(Equal("snappy"))
+		Expect(requests[0].headers.Get("Content-Type")).To(Equal("application/x-protobuf"))
+		Expect(requests[0].headers.Get("X-Prometheus-Remote-Write-Version")).To(Equal("0.1.0"))
+		Expect(requests[0].headers.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("pushes the metrics samples", func() {
+		samples := decodeWriteRequest(requests[0].body)
+		Expect(samples).To(ConsistOf(
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_gauge", "bosh_deployment": "fake-deployment-name"}, value: 42, timestampMs: 1500000000000},
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_counter"}, value: 3, timestampMs: metricTimestamp},
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_histogram_bucket", "le": "60"}, value: 1, timestampMs: 1500000000000},
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_histogram_bucket", "le": "+Inf"}, value: 2, timestampMs: 1500000000000},
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_histogram_sum"}, value: 90, timestampMs: 1500000000000},
+			fakeRemoteWriteSample{labels: map[string]string{"__name__": "fake_histogram_count"}, value: 2, timestampMs: 1500000000000},
+		))
+	})
+
+	Context("when basic auth credentials are set", func() {
+		BeforeEach(func() {
+			username = "fake-username"
+			password = "fake-password"
+		})
+
+		It("authenticates the request", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests[0].headers.Get("Authorization")).To(Equal("Basic " + base64.StdEncoding.EncodeToString([]byte("fake-username:fake-password"))))
+		})
+	})
+
+	Context("when a bearer token is set", func() {
+		BeforeEach(func() {
+			bearerToken = "fake-token"
+		})
+
+		It("authenticates the request", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests[0].headers.Get("Authorization")).To(Equal("Bearer fake-token"))
+		})
+	})
+
+	Context("when the write request is large", func() {
+		BeforeEach(func() {
+			for i := 0; i < 2000; i++ {
+				metricFamilies[0].Metric = append(metricFamilies[0].Metric, &dto.Metric{
+					Label: []*dto.LabelPair{{Name: proto.String("bosh_deployment"), Value: proto.String(fmt.Sprintf("fake-deployment-name-%d", i))}},
+					Gauge: &dto.Gauge{Value: proto.Float64(float64(i))},
+				})
+			}
+		})
+
+		It("pushes all the metrics samples", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodeWriteRequest(requests[0].body)).To(HaveLen(2006))
+		})
+	})
+
+	Context("when the remote write endpoint fails", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusBadRequest
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
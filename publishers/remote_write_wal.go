@@ -0,0 +1,188 @@
+package publishers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/log"
+)
+
+const remoteWriteWALSegmentSuffix = ".wal"
+
+// RemoteWriteWAL queues the remote write requests in a directory before
+// sending them, so the metrics pushed while the remote write endpoint is
+// unreachable are sent, in order, once it is back, including after a restart
+// of the exporter. Each request is stored as a segment file holding its
+// compressed body, and removed once it has been accepted.
+type RemoteWriteWAL struct {
+	publisher   *RemoteWritePublisher
+	directory   string
+	maxSegments int
+	mutex       sync.Mutex
+}
+
+// NewRemoteWriteWAL returns a queue storing at most maxSegments requests in
+// the directory, dropping the oldest ones beyond (not limited if 0).
+func NewRemoteWriteWAL(publisher *RemoteWritePublisher, directory string, maxSegments int) (*RemoteWriteWAL, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error creating remote write WAL directory `%s`: %v", directory, err))
+	}
+
+	return &RemoteWriteWAL{
+		publisher:   publisher,
+		directory:   directory,
+		maxSegments: maxSegments,
+	}, nil
+}
+
+// Publish appends the metric families samples to the queue, and then sends
+// all the queued requests, including the ones left by a previous run.
+func (w *RemoteWriteWAL) Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error {
+	body, err := w.publisher.encode(metricFamilies, timestamp)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err = w.appendSegment(body, timestamp); err != nil {
+		return err
+	}
+
+	if err = w.truncate(); err != nil {
+		return err
+	}
+
+	return w.flush()
+}
+
+// flush sends the queued requests from the oldest one, stopping at the first
+// one failing to be sent. Requests rejected by the endpoint as invalid
+// would fail forever, so they are dropped instead of blocking the queue.
+func (w *RemoteWriteWAL) flush() error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for i, segment := range segments {
+		body, err := ioutil.ReadFile(segment)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error reading remote write WAL segment `%s`: %v", segment, err))
+		}
+
+		if err = w.publisher.send(body); err != nil {
+			if !remoteWriteRecoverable(err) {
+				log.Errorf("Dropping remote write WAL segment `%s`: %v", segment, err)
+				w.removeSegment(segment)
+				continue
+			}
+			return errors.New(fmt.Sprintf("Error sending remote write WAL segment `%s`, %d segments queued: %v", segment, len(segments)-i, err))
+		}
+
+		w.removeSegment(segment)
+	}
+
+	return nil
+}
+
+func (w *RemoteWriteWAL) appendSegment(body []byte, timestamp time.Time) error {
+	segment := filepath.Join(w.directory, fmt.Sprintf("%020d%s", timestamp.UnixNano(), remoteWriteWALSegmentSuffix))
+
+	tmpFile, err := ioutil.TempFile(w.directory, ".segment")
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error creating remote write WAL segment `%s`: %v", segment, err))
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(body); err != nil {
+		tmpFile.Close()
+		return errors.New(fmt.Sprintf("Error writing remote write WAL segment `%s`: %v", segment, err))
+	}
+
+	if err = tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return errors.New(fmt.Sprintf("Error writing remote write WAL segment `%s`: %v", segment, err))
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return errors.New(fmt.Sprintf("Error writing remote write WAL segment `%s`: %v", segment, err))
+	}
+
+	if err = os.Rename(tmpFile.Name(), segment); err != nil {
+		return errors.New(fmt.Sprintf("Error renaming remote write WAL segment `%s`: %v", segment, err))
+	}
+
+	return nil
+}
+
+// truncate drops the oldest segments beyond the maximum number of segments.
+func (w *RemoteWriteWAL) truncate() error {
+	if w.maxSegments <= 0 {
+		return nil
+	}
+
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	if len(segments) <= w.maxSegments {
+		return nil
+	}
+
+	dropped := segments[:len(segments)-w.maxSegments]
+	log.Errorf("Dropping %d remote write WAL segments exceeding the maximum of %d segments", len(dropped), w.maxSegments)
+	for _, segment := range dropped {
+		w.removeSegment(segment)
+	}
+
+	return nil
+}
+
+// segments returns the queued segments, from the oldest one. Segments names
+// are zero padded timestamps, so they sort chronologically.
+func (w *RemoteWriteWAL) segments() ([]string, error) {
+	files, err := ioutil.ReadDir(w.directory)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error reading remote write WAL directory `%s`: %v", w.directory, err))
+	}
+
+	segments := []string{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), remoteWriteWALSegmentSuffix) {
+			continue
+		}
+		segments = append(segments, filepath.Join(w.directory, file.Name()))
+	}
+	sort.Strings(segments)
+
+	return segments, nil
+}
+
+func (w *RemoteWriteWAL) removeSegment(segment string) {
+	if err := os.Remove(segment); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing remote write WAL segment `%s`: %v", segment, err)
+	}
+}
+
+// remoteWriteRecoverable returns whether sending a request may succeed when
+// retried: network errors, throttling and server errors are recoverable,
+// other client errors are not.
+func remoteWriteRecoverable(err error) bool {
+	statusErr, ok := err.(*httpStatusError)
+	if !ok {
+		return true
+	}
+	return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= 500
+}
@@ -0,0 +1,146 @@
+package publishers_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+var _ = Describe("RemoteWriteWAL", func() {
+	var (
+		err         error
+		requests    [][]byte
+		statusCode  int
+		server      *httptest.Server
+		tmpDir      string
+		directory   string
+		maxSegments int
+
+		remoteWriteWAL *RemoteWriteWAL
+	)
+
+	metricFamilies := func(value float64) []*dto.MetricFamily {
+		return []*dto.MetricFamily{
+			{
+				Name:   proto.String("fake_gauge"),
+				Type:   dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(value)}}},
+			},
+		}
+	}
+
+	pushedValues := func() []float64 {
+		values := []float64{}
+		for _, body := range requests {
+			for _, sample := range decodeWriteRequest(body) {
+				values = append(values, sample.value)
+			}
+		}
+		return values
+	}
+
+	segments := func() []string {
+		segments, err := filepath.Glob(filepath.Join(directory, "*.wal"))
+		Expect(err).ToNot(HaveOccurred())
+		return segments
+	}
+
+	BeforeEach(func() {
+		requests = [][]byte{}
+		statusCode = http.StatusNoContent
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if statusCode == http.StatusNoContent {
+				requests = append(requests, body)
+			}
+			w.WriteHeader(statusCode)
+		}))
+
+		tmpDir, err = ioutil.TempDir("", "remote_write_wal_test")
+		Expect(err).ToNot(HaveOccurred())
+		directory = filepath.Join(tmpDir, "wal")
+		maxSegments = 0
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tmpDir)
+	})
+
+	JustBeforeEach(func() {
+		remoteWritePublisher := NewRemoteWritePublisher(server.URL+"/api/v1/write", "", "", "", &http.Client{Timeout: 5 * time.Second})
+		remoteWriteWAL, err = NewRemoteWriteWAL(remoteWritePublisher, directory, maxSegments)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("pushes the metrics and empties the queue", func() {
+		Expect(remoteWriteWAL.Publish(metricFamilies(1), time.Unix(1500000000, 0))).To(Succeed())
+		Expect(pushedValues()).To(Equal([]float64{1}))
+		Expect(segments()).To(BeEmpty())
+	})
+
+	Context("when the remote write endpoint is unavailable", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusServiceUnavailable
+		})
+
+		It("queues the requests until the endpoint is back", func() {
+			Expect(remoteWriteWAL.Publish(metricFamilies(1), time.Unix(1500000000, 0))).ToNot(Succeed())
+			Expect(remoteWriteWAL.Publish(metricFamilies(2), time.Unix(1500000060, 0))).ToNot(Succeed())
+			Expect(segments()).To(HaveLen(2))
+
+			statusCode = http.StatusNoContent
+			Expect(remoteWriteWAL.Publish(metricFamilies(3), time.Unix(1500000120, 0))).To(Succeed())
+			Expect(pushedValues()).To(Equal([]float64{1, 2, 3}))
+			Expect(segments()).To(BeEmpty())
+		})
+
+		It("sends the requests queued by a previous run", func() {
+			Expect(remoteWriteWAL.Publish(metricFamilies(1), time.Unix(1500000000, 0))).ToNot(Succeed())
+
+			statusCode = http.StatusNoContent
+			remoteWritePublisher := NewRemoteWritePublisher(server.URL+"/api/v1/write", "", "", "", &http.Client{Timeout: 5 * time.Second})
+			remoteWriteWAL, err = NewRemoteWriteWAL(remoteWritePublisher, directory, maxSegments)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remoteWriteWAL.Publish(metricFamilies(2), time.Unix(1500000060, 0))).To(Succeed())
+			Expect(pushedValues()).To(Equal([]float64{1, 2}))
+		})
+
+		Context("and the queue is limited", func() {
+			BeforeEach(func() {
+				maxSegments = 2
+			})
+
+			It("drops the oldest requests", func() {
+				Expect(remoteWriteWAL.Publish(metricFamilies(1), time.Unix(1500000000, 0))).ToNot(Succeed())
+				Expect(remoteWriteWAL.Publish(metricFamilies(2), time.Unix(1500000060, 0))).ToNot(Succeed())
+
+				statusCode = http.StatusNoContent
+				Expect(remoteWriteWAL.Publish(metricFamilies(3), time.Unix(1500000120, 0))).To(Succeed())
+				Expect(pushedValues()).To(Equal([]float64{2, 3}))
+			})
+		})
+	})
+
+	Context("when the remote write endpoint rejects the requests", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusBadRequest
+		})
+
+		It("drops the rejected requests", func() {
+			Expect(remoteWriteWAL.Publish(metricFamilies(1), time.Unix(1500000000, 0))).To(Succeed())
+			Expect(segments()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,176 @@
+package publishers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+const (
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3Service          = "s3"
+	s3AmzDateFormat    = "20060102T150405Z"
+	s3DateFormat       = "20060102"
+)
+
+type S3Publisher struct {
+	endpoint        string
+	region          string
+	bucket          string
+	key             string
+	accessKeyID     string
+	secretAccessKey string
+	httpClient      *http.Client
+	lastPublished   string
+	now             func() time.Time
+}
+
+func NewS3Publisher(
+	endpoint string,
+	region string,
+	bucket string,
+	key string,
+	accessKeyID string,
+	secretAccessKey string,
+	httpClient *http.Client,
+) *S3Publisher {
+	return &S3Publisher{
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		region:          region,
+		bucket:          bucket,
+		key:             strings.TrimPrefix(key, "/"),
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		httpClient:      httpClient,
+		now:             time.Now,
+	}
+}
+
+func (p *S3Publisher) Publish(targetGroups collectors.TargetGroups) error {
+	body, err := json.Marshal(targetGroups)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error marshalling target groups: %v", err))
+	}
+
+	if string(body) == p.lastPublished {
+		return nil
+	}
+
+	endpointURL, err := url.Parse(p.endpoint)
+	if err != nil {
+		return errors.New(fmt.Sprintf("S3 endpoint `%s` is not valid: %v", p.endpoint, err))
+	}
+
+	objectPath := "/" + p.uriEncode(p.bucket, true) + "/" + p.uriEncode(p.key, false)
+	headers := p.signedHeaders("PUT", endpointURL.Host, objectPath, body)
+	objectURL := endpointURL.Scheme + "://" + endpointURL.Host + objectPath
+
+	if _, err = doHTTPRequest(p.httpClient, "S3", "PUT", objectURL, headers, body); err != nil {
+		return err
+	}
+	p.lastPublished = string(body)
+
+	return nil
+}
+
+func (p *S3Publisher) signedHeaders(method string, host string, path string, body []byte) map[string]string {
+	now := p.now().UTC()
+	amzDate := now.Format(s3AmzDateFormat)
+	scope := strings.Join([]string{now.Format(s3DateFormat), p.region, s3Service, "aws4_request"}, "/")
+	payloadHash := p.sha256Hex(body)
+
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		"Host":                 host,
+		"X-Amz-Content-Sha256": payloadHash,
+		"X-Amz-Date":           amzDate,
+	}
+
+	headerNames := []string{}
+	canonicalHeaders := map[string]string{}
+	for name, value := range headers {
+		headerName := strings.ToLower(name)
+		headerNames = append(headerNames, headerName)
+		canonicalHeaders[headerName] = strings.TrimSpace(value)
+	}
+	sort.Strings(headerNames)
+
+	canonicalHeadersList := ""
+	for _, headerName := range headerNames {
+		canonicalHeadersList += headerName + ":" + canonicalHeaders[headerName] + "\n"
+	}
+	signedHeadersList := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		"",
+		canonicalHeadersList,
+		signedHeadersList,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		p.sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := p.hmacSHA256([]byte("AWS4"+p.secretAccessKey), now.Format(s3DateFormat))
+	signingKey = p.hmacSHA256(signingKey, p.region)
+	signingKey = p.hmacSHA256(signingKey, s3Service)
+	signingKey = p.hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(p.hmacSHA256(signingKey, stringToSign))
+
+	headers["Authorization"] = fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm,
+		p.accessKeyID,
+		scope,
+		signedHeadersList,
+		signature,
+	)
+	delete(headers, "Host")
+
+	return headers
+}
+
+func (p *S3Publisher) uriEncode(value string, encodeSlash bool) string {
+	encoded := ""
+	for _, b := range []byte(value) {
+		switch {
+		case (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9'):
+			encoded += string(b)
+		case b == '-' || b == '_' || b == '.' || b == '~':
+			encoded += string(b)
+		case b == '/' && !encodeSlash:
+			encoded += string(b)
+		default:
+			encoded += fmt.Sprintf("%%%02X", b)
+		}
+	}
+
+	return encoded
+}
+
+func (p *S3Publisher) sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func (p *S3Publisher) hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
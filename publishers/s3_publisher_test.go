@@ -0,0 +1,118 @@
+package publishers_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeS3Request struct {
+	method  string
+	path    string
+	headers http.Header
+	body    string
+}
+
+var _ = Describe("S3Publisher", func() {
+	var (
+		err          error
+		requests     []fakeS3Request
+		statusCode   int
+		server       *httptest.Server
+		targetGroups collectors.TargetGroups
+
+		s3Publisher *S3Publisher
+
+		targetGroupsContent = "[{\"targets\":[\"1.2.3.4\"],\"labels\":{\"__meta_bosh_deployment\":\"fake-deployment-name\"}}]"
+	)
+
+	BeforeEach(func() {
+		requests = []fakeS3Request{}
+		statusCode = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, fakeS3Request{
+				method:  r.Method,
+				path:    r.URL.EscapedPath(),
+				headers: r.Header,
+				body:    string(body),
+			})
+			w.WriteHeader(statusCode)
+		}))
+		targetGroups = collectors.TargetGroups{
+			{
+				Targets: []string{"1.2.3.4"},
+				Labels: model.LabelSet{
+					model.LabelName("__meta_bosh_deployment"): model.LabelValue("fake-deployment-name"),
+				},
+			},
+		}
+		s3Publisher = NewS3Publisher(
+			server.URL,
+			"fake-region",
+			"fake-bucket",
+			"/fake path/bosh_target_groups.json",
+			"fake-access-key-id",
+			"fake-secret-access-key",
+			&http.Client{},
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		err = s3Publisher.Publish(targetGroups)
+	})
+
+	It("uploads the target groups object", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].method).To(Equal("PUT"))
+		Expect(requests[0].path).To(Equal("/fake-bucket/fake%20path/bosh_target_groups.json"))
+		Expect(requests[0].body).To(Equal(targetGroupsContent))
+		Expect(requests[0].headers.Get("Content-Type")).To(Equal("application/json"))
+	})
+
+	It("signs the request", func() {
+		Expect(requests[0].headers.Get("X-Amz-Content-Sha256")).To(HaveLen(64))
+		Expect(requests[0].headers.Get("X-Amz-Date")).To(MatchRegexp(`^\d{8}T\d{6}Z$`))
+		Expect(requests[0].headers.Get("Authorization")).To(MatchRegexp(
+			`^AWS4-HMAC-SHA256 Credential=fake-access-key-id/\d{8}/fake-region/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`,
+		))
+	})
+
+	Context("when the target groups have not changed", func() {
+		It("does not upload the target groups object again", func() {
+			err = s3Publisher.Publish(targetGroups)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
+	Context("when the upload fails", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusForbidden
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("uploads the target groups object again on next publish", func() {
+			err = s3Publisher.Publish(targetGroups)
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(HaveLen(2))
+		})
+	})
+})
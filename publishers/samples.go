@@ -0,0 +1,97 @@
+package publishers
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+type sampleLabel struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	labels      []sampleLabel
+	value       float64
+	timestampMs int64
+}
+
+// name returns the value of the `__name__` label of the sample.
+func (s sample) name() string {
+	for _, label := range s.labels {
+		if label.name == model.MetricNameLabel {
+			return label.value
+		}
+	}
+	return ""
+}
+
+// flattenMetricFamilies flattens the metric families into samples, expanding
+// summaries and histograms into their `_sum`, `_count` and quantiles or
+// buckets samples. Metrics without an explicit timestamp are stamped with the
+// given time.
+func flattenMetricFamilies(metricFamilies []*dto.MetricFamily, timestamp time.Time) []sample {
+	timestampMs := timestamp.UnixNano() / int64(time.Millisecond)
+	samples := []sample{}
+
+	for _, metricFamily := range metricFamilies {
+		name := metricFamily.GetName()
+		for _, metric := range metricFamily.Metric {
+			labels := []sampleLabel{}
+			for _, labelPair := range metric.Label {
+				labels = append(labels, sampleLabel{name: labelPair.GetName(), value: labelPair.GetValue()})
+			}
+
+			metricTimestampMs := timestampMs
+			if metric.TimestampMs != nil {
+				metricTimestampMs = metric.GetTimestampMs()
+			}
+
+			add := func(name string, value float64, extraLabels ...sampleLabel) {
+				sampleLabels := append([]sampleLabel{{name: model.MetricNameLabel, value: name}}, labels...)
+				sampleLabels = append(sampleLabels, extraLabels...)
+				sort.Slice(sampleLabels, func(i, j int) bool { return sampleLabels[i].name < sampleLabels[j].name })
+				samples = append(samples, sample{labels: sampleLabels, value: value, timestampMs: metricTimestampMs})
+			}
+
+			switch {
+			case metric.Gauge != nil:
+				add(name, metric.Gauge.GetValue())
+			case metric.Counter != nil:
+				add(name, metric.Counter.GetValue())
+			case metric.Untyped != nil:
+				add(name, metric.Untyped.GetValue())
+			case metric.Summary != nil:
+				for _, quantile := range metric.Summary.Quantile {
+					add(name, quantile.GetValue(), sampleLabel{name: model.QuantileLabel, value: formatFloat(quantile.GetQuantile())})
+				}
+				add(name+"_sum", metric.Summary.GetSampleSum())
+				add(name+"_count", float64(metric.Summary.GetSampleCount()))
+			case metric.Histogram != nil:
+				for _, bucket := range metric.Histogram.Bucket {
+					add(name+"_bucket", float64(bucket.GetCumulativeCount()), sampleLabel{name: model.BucketLabel, value: formatFloat(bucket.GetUpperBound())})
+				}
+				add(name+"_bucket", float64(metric.Histogram.GetSampleCount()), sampleLabel{name: model.BucketLabel, value: "+Inf"})
+				add(name+"_sum", metric.Histogram.GetSampleSum())
+				add(name+"_count", float64(metric.Histogram.GetSampleCount()))
+			}
+		}
+	}
+
+	return samples
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, +1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
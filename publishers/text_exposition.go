@@ -0,0 +1,28 @@
+package publishers
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// encodeTextWithoutTimestamps renders the metric families using the
+// Prometheus text exposition format, removing the metrics timestamps.
+func encodeTextWithoutTimestamps(metricFamilies []*dto.MetricFamily) ([]byte, error) {
+	var text bytes.Buffer
+	encoder := expfmt.NewEncoder(&text, expfmt.FmtText)
+	for _, metricFamily := range metricFamilies {
+		metricFamily = proto.Clone(metricFamily).(*dto.MetricFamily)
+		for _, metric := range metricFamily.Metric {
+			metric.TimestampMs = nil
+		}
+
+		if err := encoder.Encode(metricFamily); err != nil {
+			return nil, err
+		}
+	}
+
+	return text.Bytes(), nil
+}
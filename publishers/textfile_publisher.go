@@ -0,0 +1,57 @@
+package publishers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+type TextfilePublisher struct {
+	filename string
+}
+
+func NewTextfilePublisher(filename string) *TextfilePublisher {
+	return &TextfilePublisher{filename: filename}
+}
+
+// Publish writes the metric families to the file using the Prometheus text
+// exposition format. The file is written to a temporary file first and then
+// renamed, so readers (i.e. the node_exporter textfile collector) never see
+// a partial file. Timestamps are not supported by the node_exporter textfile
+// collector, so they are removed from the written metrics.
+func (p *TextfilePublisher) Publish(metricFamilies []*dto.MetricFamily, timestamp time.Time) error {
+	text, err := encodeTextWithoutTimestamps(metricFamilies)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error encoding textfile `%s` metrics: %v", p.filename, err))
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(p.filename), "."+filepath.Base(p.filename))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error creating textfile `%s`: %v", p.filename, err))
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.Write(text); err != nil {
+		tmpFile.Close()
+		return errors.New(fmt.Sprintf("Error writing textfile `%s`: %v", p.filename, err))
+	}
+
+	if err = tmpFile.Close(); err != nil {
+		return errors.New(fmt.Sprintf("Error writing textfile `%s`: %v", p.filename, err))
+	}
+
+	if err = os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return errors.New(fmt.Sprintf("Error writing textfile `%s`: %v", p.filename, err))
+	}
+
+	if err = os.Rename(tmpFile.Name(), p.filename); err != nil {
+		return errors.New(fmt.Sprintf("Error renaming textfile `%s`: %v", p.filename, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package publishers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+var _ = Describe("TextfilePublisher", func() {
+	var (
+		err            error
+		directory      string
+		filename       string
+		metricFamilies []*dto.MetricFamily
+
+		textfilePublisher *TextfilePublisher
+	)
+
+	BeforeEach(func() {
+		directory, err = ioutil.TempDir("", "textfile_publisher_test_")
+		Expect(err).ToNot(HaveOccurred())
+		filename = filepath.Join(directory, "bosh.prom")
+
+		metricFamilies = []*dto.MetricFamily{
+			{
+				Name: proto.String("fake_gauge"),
+				Help: proto.String("Fake gauge."),
+				Type: dto.MetricType_GAUGE.Enum(),
+				Metric: []*dto.Metric{
+					{
+						Label:       []*dto.LabelPair{{Name: proto.String("bosh_deployment"), Value: proto.String("fake-deployment-name")}},
+						Gauge:       &dto.Gauge{Value: proto.Float64(42)},
+						TimestampMs: proto.Int64(1500000000000),
+					},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(directory)
+	})
+
+	JustBeforeEach(func() {
+		textfilePublisher = NewTextfilePublisher(filename)
+		err = textfilePublisher.Publish(metricFamilies, time.Now())
+	})
+
+	It("writes the metrics without timestamps to the file", func() {
+		Expect(err).ToNot(HaveOccurred())
+
+		content, err := ioutil.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("# HELP fake_gauge Fake gauge.\n# TYPE fake_gauge gauge\nfake_gauge{bosh_deployment=\"fake-deployment-name\"} 42\n"))
+	})
+
+	It("does not leave temporary files", func() {
+		files, err := ioutil.ReadDir(directory)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+	})
+
+	Context("when the file already exists", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filename, []byte("old content"), 0644)).To(Succeed())
+		})
+
+		It("replaces the file", func() {
+			content, err := ioutil.ReadFile(filename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("fake_gauge"))
+			Expect(string(content)).ToNot(ContainSubstring("old content"))
+		})
+	})
+
+	Context("when the directory does not exist", func() {
+		BeforeEach(func() {
+			filename = filepath.Join(directory, "fake-directory", "bosh.prom")
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,92 @@
+package publishers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+)
+
+// webhookTopologyChange is the body posted to the webhook URL when the
+// Service Discovery target groups change.
+type webhookTopologyChange struct {
+	Added   collectors.TargetGroups `json:"added"`
+	Removed collectors.TargetGroups `json:"removed"`
+}
+
+type WebhookPublisher struct {
+	url          string
+	httpClient   *http.Client
+	initialized  bool
+	targetGroups map[string]collectors.TargetGroup
+}
+
+func NewWebhookPublisher(url string, httpClient *http.Client) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:          url,
+		httpClient:   httpClient,
+		targetGroups: map[string]collectors.TargetGroup{},
+	}
+}
+
+// Publish posts the target groups added and removed since the last
+// publication to the webhook URL. The first publication only records the
+// target groups, and nothing is posted when they did not change. When the
+// post fails, the changes are posted again with the next publication.
+func (p *WebhookPublisher) Publish(targetGroups collectors.TargetGroups) error {
+	current := map[string]collectors.TargetGroup{}
+	for _, targetGroup := range targetGroups {
+		key, err := json.Marshal(targetGroup)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Error marshalling webhook target group: %v", err))
+		}
+		current[string(key)] = targetGroup
+	}
+
+	if !p.initialized {
+		p.initialized = true
+		p.targetGroups = current
+		return nil
+	}
+
+	change := webhookTopologyChange{
+		Added:   p.difference(current, p.targetGroups),
+		Removed: p.difference(p.targetGroups, current),
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error marshalling webhook topology change: %v", err))
+	}
+
+	if _, err = doHTTPRequest(p.httpClient, "Webhook", "POST", p.url, map[string]string{}, body); err != nil {
+		return err
+	}
+	p.targetGroups = current
+
+	return nil
+}
+
+// difference returns the target groups of a missing from b, sorted by key.
+func (p *WebhookPublisher) difference(a map[string]collectors.TargetGroup, b map[string]collectors.TargetGroup) collectors.TargetGroups {
+	keys := []string{}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	targetGroups := collectors.TargetGroups{}
+	for _, key := range keys {
+		targetGroups = append(targetGroups, a[key])
+	}
+
+	return targetGroups
+}
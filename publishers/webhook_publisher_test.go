@@ -0,0 +1,106 @@
+package publishers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/publishers"
+)
+
+type fakeWebhookChange struct {
+	Added   collectors.TargetGroups `json:"added"`
+	Removed collectors.TargetGroups `json:"removed"`
+}
+
+var _ = Describe("WebhookPublisher", func() {
+	var (
+		changes    []fakeWebhookChange
+		statusCode int
+		server     *httptest.Server
+
+		webhookPublisher *WebhookPublisher
+	)
+
+	targetGroup := func(ip string, jobName string) collectors.TargetGroup {
+		return collectors.TargetGroup{
+			Targets: []string{ip},
+			Labels:  model.LabelSet{"__meta_bosh_job_name": model.LabelValue(jobName)},
+		}
+	}
+
+	BeforeEach(func() {
+		changes = []fakeWebhookChange{}
+		statusCode = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal("POST"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+			if statusCode == http.StatusOK {
+				change := fakeWebhookChange{}
+				Expect(json.NewDecoder(r.Body).Decode(&change)).To(Succeed())
+				changes = append(changes, change)
+			}
+			w.WriteHeader(statusCode)
+		}))
+
+		webhookPublisher = NewWebhookPublisher(server.URL+"/hook", &http.Client{Timeout: 5 * time.Second})
+		Expect(webhookPublisher.Publish(collectors.TargetGroups{targetGroup("1.2.3.4", "fake-job-1"), targetGroup("1.2.3.5", "fake-job-2")})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("does not post the initial target groups", func() {
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("does not post anything when the target groups do not change", func() {
+		Expect(webhookPublisher.Publish(collectors.TargetGroups{targetGroup("1.2.3.5", "fake-job-2"), targetGroup("1.2.3.4", "fake-job-1")})).To(Succeed())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("posts the target groups added and removed", func() {
+		Expect(webhookPublisher.Publish(collectors.TargetGroups{targetGroup("1.2.3.4", "fake-job-1"), targetGroup("1.2.3.6", "fake-job-3")})).To(Succeed())
+		Expect(changes).To(Equal([]fakeWebhookChange{
+			{
+				Added:   collectors.TargetGroups{targetGroup("1.2.3.6", "fake-job-3")},
+				Removed: collectors.TargetGroups{targetGroup("1.2.3.5", "fake-job-2")},
+			},
+		}))
+	})
+
+	It("posts a target group whose labels changed as removed and added", func() {
+		Expect(webhookPublisher.Publish(collectors.TargetGroups{targetGroup("1.2.3.4", "fake-job-1"), targetGroup("1.2.3.5", "fake-job-3")})).To(Succeed())
+		Expect(changes).To(HaveLen(1))
+		Expect(changes[0].Added).To(Equal(collectors.TargetGroups{targetGroup("1.2.3.5", "fake-job-3")}))
+		Expect(changes[0].Removed).To(Equal(collectors.TargetGroups{targetGroup("1.2.3.5", "fake-job-2")}))
+	})
+
+	Context("when the webhook fails", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusInternalServerError
+		})
+
+		It("posts the change again with the next publication", func() {
+			Expect(webhookPublisher.Publish(collectors.TargetGroups{targetGroup("1.2.3.4", "fake-job-1")})).ToNot(Succeed())
+
+			statusCode = http.StatusOK
+			Expect(webhookPublisher.Publish(collectors.TargetGroups{})).To(Succeed())
+			Expect(changes).To(Equal([]fakeWebhookChange{
+				{
+					Added:   collectors.TargetGroups{},
+					Removed: collectors.TargetGroups{targetGroup("1.2.3.4", "fake-job-1"), targetGroup("1.2.3.5", "fake-job-2")},
+				},
+			}))
+		})
+	})
+})
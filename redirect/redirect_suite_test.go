@@ -0,0 +1,13 @@
+package redirect_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Redirect Suite")
+}
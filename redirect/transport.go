@@ -0,0 +1,98 @@
+package redirect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/prometheus/common/log"
+)
+
+const maxRedirects = 10
+
+// Transport is an http.RoundTripper following the redirects of GET and HEAD
+// requests itself, to any host, scheme or port. The BOSH client redirects
+// every request back to the BOSH Director address, which breaks Directors
+// fronted by load balancers redirecting to an alternate address (or to UAA).
+//
+// Credentials are only sent along when redirected to the same host, and not
+// when downgraded from HTTPS to HTTP.
+type Transport struct {
+	transport http.RoundTripper
+}
+
+func NewTransport(transport http.RoundTripper) *Transport {
+	return &Transport{transport: transport}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return t.transport.RoundTrip(req)
+	}
+
+	for redirects := 0; ; redirects++ {
+		resp, err := t.transport.RoundTrip(req)
+		if err != nil || !isRedirect(resp.StatusCode) {
+			return resp, err
+		}
+
+		location := resp.Header.Get("Location")
+		if location == "" {
+			return resp, nil
+		}
+
+		redirectURL, err := req.URL.Parse(location)
+		if err != nil {
+			return resp, nil
+		}
+
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		if redirects >= maxRedirects {
+			return nil, errors.New(fmt.Sprintf("Error following redirects of `%s`: stopped after %d redirects", req.URL.Path, maxRedirects))
+		}
+
+		log.Debugf("Following redirect from `%s` to `%s`", req.URL.Path, redirectURL.Host+redirectURL.Path)
+		req = redirectRequest(req, redirectURL)
+	}
+}
+
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
+
+func redirectRequest(req *http.Request, redirectURL *url.URL) *http.Request {
+	redirected := new(http.Request)
+	*redirected = *req
+	redirected.URL = redirectURL
+	redirected.Host = ""
+	redirected.Header = http.Header{}
+	for name, values := range req.Header {
+		redirected.Header[name] = values
+	}
+	redirected.Header.Del("Referer")
+
+	if !credentialsAllowed(req.URL, redirectURL) {
+		redirected.Header.Del("Authorization")
+		redirected.Header.Del("Cookie")
+	}
+
+	return redirected
+}
+
+func credentialsAllowed(from *url.URL, to *url.URL) bool {
+	if from.Hostname() != to.Hostname() {
+		return false
+	}
+
+	return from.Scheme != "https" || to.Scheme == "https"
+}
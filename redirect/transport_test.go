@@ -0,0 +1,116 @@
+package redirect_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/redirect"
+)
+
+var _ = Describe("Transport", func() {
+	var (
+		authorization  string
+		targetServer   *httptest.Server
+		directorServer *httptest.Server
+		location       func() string
+		httpClient     *http.Client
+	)
+
+	BeforeEach(func() {
+		authorization = ""
+		targetServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.Write([]byte("fake-body " + r.URL.Path))
+		}))
+		location = func() string { return targetServer.URL + "/tasks/1" }
+		directorServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, location(), http.StatusFound)
+		}))
+
+		httpClient = &http.Client{
+			Transport: NewTransport(http.DefaultTransport),
+			// Like the BOSH client, redirect every request to the director.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				req.URL.Host = strings.TrimPrefix(directorServer.URL, "http://")
+				return nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		directorServer.Close()
+		targetServer.Close()
+	})
+
+	get := func() (int, string) {
+		req, err := http.NewRequest("GET", directorServer.URL+"/deployments/fake-deployment-name/instances", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "bearer fake-token")
+
+		resp, err := httpClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	It("follows redirects to an alternate port keeping the credentials", func() {
+		statusCode, body := get()
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("fake-body /tasks/1"))
+		Expect(authorization).To(Equal("bearer fake-token"))
+	})
+
+	Context("when redirected to another host", func() {
+		BeforeEach(func() {
+			location = func() string { return strings.Replace(targetServer.URL, "127.0.0.1", "localhost", 1) + "/tasks/1" }
+		})
+
+		It("follows the redirect without the credentials", func() {
+			statusCode, body := get()
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("fake-body /tasks/1"))
+			Expect(authorization).To(BeEmpty())
+		})
+	})
+
+	Context("when redirected to a relative location", func() {
+		BeforeEach(func() {
+			location = func() string { return "/tasks/1" }
+			directorServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/tasks/1" {
+					authorization = r.Header.Get("Authorization")
+					w.Write([]byte("fake-body " + r.URL.Path))
+					return
+				}
+				http.Redirect(w, r, location(), http.StatusFound)
+			})
+		})
+
+		It("follows the redirect on the same server", func() {
+			statusCode, body := get()
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("fake-body /tasks/1"))
+			Expect(authorization).To(Equal("bearer fake-token"))
+		})
+	})
+
+	Context("when redirected in a loop", func() {
+		BeforeEach(func() {
+			location = func() string { return "/loop" }
+		})
+
+		It("returns an error", func() {
+			_, err := httpClient.Get(directorServer.URL + "/loop")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("stopped after 10 redirects"))
+		})
+	})
+})
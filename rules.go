@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/rules"
+)
+
+const rulesCommand = "rules"
+
+// runRules prints the Prometheus alerting rules of the metrics described by
+// the BOSH collector.
+func runRules(
+	out io.Writer,
+	namespace string,
+	collectorsSubsystems collectors.CollectorsSubsystems,
+	constLabels prometheus.Labels,
+	boshCollector *collectors.BoshCollector,
+) error {
+	generator := rules.NewGenerator(
+		namespace,
+		collectorsSubsystems,
+		constLabels,
+		collectors.DescribedMetricsNames(boshCollector),
+	)
+
+	content, err := yaml.Marshal(generator.RuleGroups())
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error marshalling alerting rules: %v", err))
+	}
+
+	_, err = out.Write(content)
+	return err
+}
@@ -0,0 +1,3 @@
+// Package rules renders Prometheus alerting rules for the metrics exposed by
+// the exporter.
+package rules
@@ -0,0 +1,184 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+)
+
+// RuleGroups is a Prometheus rules file.
+type RuleGroups struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a Prometheus alerting rule.
+type Rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// metric is a metric exposed by a collector.
+type metric struct {
+	collector string
+	subsystem string
+	name      string
+}
+
+type ruleDefinition struct {
+	alert  string
+	metric metric
+	// expr is the query format, where %s is the metric selector.
+	expr        string
+	forDuration string
+	severity    string
+	summary     string
+	description string
+}
+
+const jobName = "{{ $labels.bosh_deployment }}/{{ $labels.bosh_job_name }}/{{ $labels.bosh_job_index }}"
+
+var rulesDefinitions = []ruleDefinition{
+	{
+		alert:       "BOSHJobUnhealthy",
+		metric:      metric{filters.JobsCollector, "job", "healthy"},
+		expr:        "%s == 0",
+		forDuration: "10m",
+		severity:    "warning",
+		summary:     "BOSH Job `" + jobName + "` is unhealthy",
+		description: "BOSH Job `" + jobName + "` at BOSH Director `{{ $labels.bosh_name }}` has been unhealthy for more than 10 minutes.",
+	},
+	{
+		alert:       "BOSHJobSystemDiskFull",
+		metric:      metric{filters.JobsCollector, "job", "system_disk_percent"},
+		expr:        "%s > 90",
+		forDuration: "30m",
+		severity:    "warning",
+		summary:     "BOSH Job `" + jobName + "` system disk is almost full",
+		description: "BOSH Job `" + jobName + "` system disk has been more than 90% full for more than 30 minutes (currently {{ $value }}%).",
+	},
+	{
+		alert:       "BOSHJobEphemeralDiskFull",
+		metric:      metric{filters.JobsCollector, "job", "ephemeral_disk_percent"},
+		expr:        "%s > 90",
+		forDuration: "30m",
+		severity:    "warning",
+		summary:     "BOSH Job `" + jobName + "` ephemeral disk is almost full",
+		description: "BOSH Job `" + jobName + "` ephemeral disk has been more than 90% full for more than 30 minutes (currently {{ $value }}%).",
+	},
+	{
+		alert:       "BOSHJobPersistentDiskFull",
+		metric:      metric{filters.JobsCollector, "job", "persistent_disk_percent"},
+		expr:        "%s > 90",
+		forDuration: "30m",
+		severity:    "critical",
+		summary:     "BOSH Job `" + jobName + "` persistent disk is almost full",
+		description: "BOSH Job `" + jobName + "` persistent disk has been more than 90% full for more than 30 minutes (currently {{ $value }}%).",
+	},
+	{
+		alert:       "BOSHExporterScrapeStale",
+		metric:      metric{collectors.ExporterMetrics, "", "last_scrape_timestamp"},
+		expr:        "time() - %s > 900",
+		forDuration: "5m",
+		severity:    "warning",
+		summary:     "BOSH Director `{{ $labels.bosh_name }}` metrics are stale",
+		description: "The exporter has not scraped BOSH Director `{{ $labels.bosh_name }}` for more than 15 minutes.",
+	},
+	{
+		alert:       "BOSHDirectorDown",
+		metric:      metric{collectors.ExporterMetrics, "", "last_scrape_error"},
+		expr:        "%s == 1",
+		forDuration: "10m",
+		severity:    "critical",
+		summary:     "BOSH Director `{{ $labels.bosh_name }}` is down",
+		description: "The exporter has failed to scrape BOSH Director `{{ $labels.bosh_name }}` for more than 10 minutes.",
+	},
+}
+
+// Generator renders the alerting rules of the metrics exposed by the
+// exporter.
+type Generator struct {
+	namespace    string
+	subsystems   collectors.CollectorsSubsystems
+	constLabels  prometheus.Labels
+	metricsNames map[string]bool
+}
+
+// NewGenerator returns a generator for the metrics named metricsNames,
+// exposed under the namespace and subsystems. Rules only match the metrics
+// with the constant labels.
+func NewGenerator(
+	namespace string,
+	subsystems collectors.CollectorsSubsystems,
+	constLabels prometheus.Labels,
+	metricsNames []string,
+) *Generator {
+	generator := &Generator{
+		namespace:    namespace,
+		subsystems:   subsystems,
+		constLabels:  constLabels,
+		metricsNames: map[string]bool{},
+	}
+	for _, name := range metricsNames {
+		generator.metricsNames[name] = true
+	}
+
+	return generator
+}
+
+// RuleGroups returns the alerting rules of the exposed metrics.
+func (g *Generator) RuleGroups() RuleGroups {
+	rules := []Rule{}
+	for _, definition := range rulesDefinitions {
+		name := prometheus.BuildFQName(g.subsystems.Namespace(g.namespace, definition.metric.collector), definition.metric.subsystem, definition.metric.name)
+		if !g.metricsNames[name] {
+			continue
+		}
+
+		rules = append(rules, Rule{
+			Alert:  definition.alert,
+			Expr:   fmt.Sprintf(definition.expr, name+g.selector()),
+			For:    definition.forDuration,
+			Labels: map[string]string{"severity": definition.severity},
+			Annotations: map[string]string{
+				"summary":     definition.summary,
+				"description": definition.description,
+			},
+		})
+	}
+
+	return RuleGroups{Groups: []RuleGroup{{Name: "bosh_exporter", Rules: rules}}}
+}
+
+// selector returns the label matchers of the constant labels, sorted by
+// label name.
+func (g *Generator) selector() string {
+	if len(g.constLabels) == 0 {
+		return ""
+	}
+
+	names := []string{}
+	for name := range g.constLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := []string{}
+	for _, name := range names {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, g.constLabels[name]))
+	}
+
+	return "{" + strings.Join(matchers, ",") + "}"
+}
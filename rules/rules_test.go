@@ -0,0 +1,114 @@
+package rules_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/cloudfoundry-community/bosh_exporter/collectors"
+	"github.com/cloudfoundry-community/bosh_exporter/filters"
+
+	. "github.com/cloudfoundry-community/bosh_exporter/rules"
+)
+
+var _ = Describe("Generator", func() {
+	var (
+		namespace            string
+		collectorsSubsystems collectors.CollectorsSubsystems
+		constLabels          prometheus.Labels
+		collectorsRegistry   *collectors.CollectorsRegistry
+
+		ruleGroups RuleGroups
+	)
+
+	rulesExprs := func() map[string]string {
+		exprs := map[string]string{}
+		for _, rule := range ruleGroups.Groups[0].Rules {
+			exprs[rule.Alert] = rule.Expr
+		}
+		return exprs
+	}
+
+	BeforeEach(func() {
+		namespace = "test_namespace"
+		collectorsSubsystems = collectors.CollectorsSubsystems{}
+		constLabels = prometheus.Labels{}
+		collectorsRegistry = collectors.NewCollectorsRegistry()
+	})
+
+	JustBeforeEach(func() {
+		boshCollector := collectors.NewBoshCollector(
+			nil,
+			nil,
+			collectors.BoshCollectorOptions{
+				Namespace:            namespace,
+				ConstLabels:          constLabels,
+				CollectorsSubsystems: collectorsSubsystems,
+				CollectorsRegistry:   collectorsRegistry,
+			},
+		)
+
+		generator := NewGenerator(namespace, collectorsSubsystems, constLabels, collectors.DescribedMetricsNames(boshCollector))
+		ruleGroups = generator.RuleGroups()
+	})
+
+	It("renders a rule for each rule metric", func() {
+		Expect(ruleGroups.Groups).To(HaveLen(1))
+		Expect(ruleGroups.Groups[0].Name).To(Equal("bosh_exporter"))
+		Expect(rulesExprs()).To(Equal(map[string]string{
+			"BOSHJobUnhealthy":          "test_namespace_job_healthy == 0",
+			"BOSHJobSystemDiskFull":     "test_namespace_job_system_disk_percent > 90",
+			"BOSHJobEphemeralDiskFull":  "test_namespace_job_ephemeral_disk_percent > 90",
+			"BOSHJobPersistentDiskFull": "test_namespace_job_persistent_disk_percent > 90",
+			"BOSHExporterScrapeStale":   "time() - test_namespace_last_scrape_timestamp > 900",
+			"BOSHDirectorDown":          "test_namespace_last_scrape_error == 1",
+		}))
+	})
+
+	It("renders the rules duration, severity and annotations", func() {
+		Expect(ruleGroups.Groups[0].Rules[0]).To(Equal(Rule{
+			Alert:  "BOSHJobUnhealthy",
+			Expr:   "test_namespace_job_healthy == 0",
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "BOSH Job `{{ $labels.bosh_deployment }}/{{ $labels.bosh_job_name }}/{{ $labels.bosh_job_index }}` is unhealthy",
+				"description": "BOSH Job `{{ $labels.bosh_deployment }}/{{ $labels.bosh_job_name }}/{{ $labels.bosh_job_index }}` at BOSH Director `{{ $labels.bosh_name }}` has been unhealthy for more than 10 minutes.",
+			},
+		}))
+	})
+
+	Context("when there are constant labels", func() {
+		BeforeEach(func() {
+			constLabels = prometheus.Labels{"team": "platform", "region": "eu-\"west\""}
+		})
+
+		It("matches the metrics with the constant labels", func() {
+			Expect(rulesExprs()).To(HaveKeyWithValue("BOSHDirectorDown", `test_namespace_last_scrape_error{region="eu-\"west\"",team="platform"} == 1`))
+		})
+	})
+
+	Context("when collectors have subsystems", func() {
+		BeforeEach(func() {
+			collectorsSubsystems = collectors.CollectorsSubsystems{filters.JobsCollector: "jobs"}
+		})
+
+		It("matches the metrics with their subsystem", func() {
+			Expect(rulesExprs()).To(HaveKeyWithValue("BOSHJobUnhealthy", "test_namespace_jobs_job_healthy == 0"))
+		})
+	})
+
+	Context("when a collector is disabled", func() {
+		BeforeEach(func() {
+			Expect(collectorsRegistry.SetEnabled(filters.JobsCollector, false)).To(Succeed())
+		})
+
+		It("does not render the rules of its metrics", func() {
+			Expect(rulesExprs()).To(Equal(map[string]string{
+				"BOSHExporterScrapeStale": "time() - test_namespace_last_scrape_timestamp > 900",
+				"BOSHDirectorDown":        "test_namespace_last_scrape_error == 1",
+			}))
+		})
+	})
+})